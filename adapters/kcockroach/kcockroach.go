@@ -0,0 +1,143 @@
+// Package kcockroach contains a KSQL adapter for CockroachDB.
+//
+// It works on top of the kpgx adapter, but it also retries the
+// transactions that fail with retryable errors, i.e. the ones with
+// the SQLSTATE 40001, since CockroachDB requires the clients to retry
+// them when they conflict with concurrent transactions.
+package kcockroach
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/vingarcia/ksql"
+	"github.com/vingarcia/ksql/adapters/kpgx"
+)
+
+// RetryConfig describes how the transactions
+// that fail with retryable errors are retried.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of times a transaction
+	// will run, including the first attempt, it defaults to 5
+	MaxAttempts int
+
+	// Backoff returns the duration to wait before the next attempt,
+	// it defaults to an exponential backoff starting at 10ms
+	Backoff func(attempt int) time.Duration
+}
+
+// SetDefaultValues sets the default values of the RetryConfig if unset
+func (r *RetryConfig) SetDefaultValues() {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = 5
+	}
+
+	if r.Backoff == nil {
+		r.Backoff = exponentialBackoff
+	}
+}
+
+func exponentialBackoff(attempt int) time.Duration {
+	return 10 * time.Millisecond << uint(attempt-1)
+}
+
+// CockroachAdapter adapts the pgxpool.Pool type to be compatible with
+// the `DBAdapter` interface and retries the transactions that
+// fail with retryable errors.
+type CockroachAdapter struct {
+	kpgx.PGXAdapter
+
+	retryConfig RetryConfig
+}
+
+// NewCockroachAdapter instantiates a new CockroachDB adapter
+func NewCockroachAdapter(pool *pgxpool.Pool, retryConfig RetryConfig) CockroachAdapter {
+	retryConfig.SetDefaultValues()
+	return CockroachAdapter{
+		PGXAdapter:  kpgx.NewPGXAdapter(pool),
+		retryConfig: retryConfig,
+	}
+}
+
+// RetryTx implements the ksql.TxRetrier interface
+func (c CockroachAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	if attempt >= c.retryConfig.MaxAttempts || !IsRetryableError(err) {
+		return false
+	}
+
+	timer := time.NewTimer(c.retryConfig.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// IsRetryableError checks if the input error is a CockroachDB
+// error asking for the transaction to be retried
+func IsRetryableError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001"
+	}
+
+	// Some of the errors returned by ksql are formatted as strings
+	// so we also need to check the error message:
+	return err != nil && strings.Contains(err.Error(), "(SQLSTATE 40001)")
+}
+
+// New instantiates a new ksql.Client for CockroachDB
+// using pgx as the backend driver
+func New(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+) (db ksql.DB, err error) {
+	return NewWithRetryConfig(ctx, connectionString, config, RetryConfig{})
+}
+
+// NewWithRetryConfig works like New but also allows the user to
+// configure how the transactions with retryable errors are retried
+func NewWithRetryConfig(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+	retryConfig RetryConfig,
+) (db ksql.DB, err error) {
+	config.SetDefaultValues()
+
+	pgxConf, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+
+	pgxConf.MaxConns = int32(config.MaxOpenConns)
+	if config.ConnMaxLifetime != 0 {
+		pgxConf.MaxConnLifetime = config.ConnMaxLifetime
+	}
+	if config.ConnMaxIdleTime != 0 {
+		pgxConf.MaxConnIdleTime = config.ConnMaxIdleTime
+	}
+	if config.StatementTimeout != 0 {
+		pgxConf.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, pgxConf)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+	if err = pool.Ping(ctx); err != nil {
+		return ksql.DB{}, err
+	}
+
+	db, err = ksql.NewWithConfig(NewCockroachAdapter(pool, retryConfig), "postgres", config)
+	return db, err
+}
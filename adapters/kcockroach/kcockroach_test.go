@@ -0,0 +1,107 @@
+package kcockroach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestRetryTx(t *testing.T) {
+	retryableErr := &pgconn.PgError{Code: "40001", Message: "restart transaction"}
+
+	adapter := CockroachAdapter{
+		retryConfig: RetryConfig{
+			MaxAttempts: 3,
+			Backoff: func(attempt int) time.Duration {
+				return time.Millisecond
+			},
+		},
+	}
+
+	tests := []struct {
+		desc          string
+		attempt       int
+		err           error
+		expectedRetry bool
+	}{
+		{
+			desc:          "should retry errors with SQLSTATE 40001",
+			attempt:       1,
+			err:           retryableErr,
+			expectedRetry: true,
+		},
+		{
+			desc:          "should retry errors wrapping errors with SQLSTATE 40001",
+			attempt:       2,
+			err:           fmt.Errorf("fake wrapper: %w", retryableErr),
+			expectedRetry: true,
+		},
+		{
+			desc:          "should retry errors formatted as strings",
+			attempt:       1,
+			err:           fmt.Errorf("error running query: %s", retryableErr),
+			expectedRetry: true,
+		},
+		{
+			desc:          "should not retry after the max number of attempts",
+			attempt:       3,
+			err:           retryableErr,
+			expectedRetry: false,
+		},
+		{
+			desc:          "should not retry other postgres errors",
+			attempt:       1,
+			err:           &pgconn.PgError{Code: "23505", Message: "duplicate key"},
+			expectedRetry: false,
+		},
+		{
+			desc:          "should not retry other errors",
+			attempt:       1,
+			err:           errors.New("fake error"),
+			expectedRetry: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			retry := adapter.RetryTx(context.Background(), test.attempt, test.err)
+			if retry != test.expectedRetry {
+				t.Fatalf("expected RetryTx to return %v, but got %v", test.expectedRetry, retry)
+			}
+		})
+	}
+
+	t.Run("should not retry if the context is canceled", func(t *testing.T) {
+		adapter := CockroachAdapter{
+			retryConfig: RetryConfig{
+				MaxAttempts: 3,
+				Backoff: func(attempt int) time.Duration {
+					return time.Hour
+				},
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		retry := adapter.RetryTx(ctx, 1, retryableErr)
+		if retry {
+			t.Fatalf("expected RetryTx to return false after the context was canceled")
+		}
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	for attempt, expected := range map[int]time.Duration{
+		1: 10 * time.Millisecond,
+		2: 20 * time.Millisecond,
+		3: 40 * time.Millisecond,
+	} {
+		if backoff := exponentialBackoff(attempt); backoff != expected {
+			t.Fatalf("expected backoff for attempt %d to be %v, but got %v", attempt, expected, backoff)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package kdataapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/vingarcia/ksql"
+)
+
+// DataAPIAdapter adapts the RDS Data API client to be
+// compatible with the `DBAdapter` interface
+type DataAPIAdapter struct {
+	client Client
+	config Config
+
+	// transactionID is only set for the adapters returned by BeginTx
+	transactionID *string
+}
+
+// NewDataAPIAdapter instantiates a new Data API adapter
+func NewDataAPIAdapter(client Client, config Config) DataAPIAdapter {
+	config.SetDefaultValues()
+	return DataAPIAdapter{
+		client: client,
+		config: config,
+	}
+}
+
+var _ ksql.DBAdapter = DataAPIAdapter{}
+
+// ExecContext implements the DBAdapter interface
+func (d DataAPIAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	output, err := d.executeStatement(ctx, query, args, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return DataAPIResult{output: output}, nil
+}
+
+// QueryContext implements the DBAdapter interface
+func (d DataAPIAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	output, err := d.executeStatement(ctx, query, args, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, col := range output.ColumnMetadata {
+		columns = append(columns, aws.ToString(col.Label))
+	}
+
+	return &DataAPIRows{
+		columns: columns,
+		records: output.Records,
+		idx:     -1,
+	}, nil
+}
+
+// BatchExecContext runs the same query once for each set of
+// params using the BatchExecuteStatement operation, which is
+// much faster than calling ExecContext once for each of them.
+func (d DataAPIAdapter) BatchExecContext(ctx context.Context, query string, paramSets [][]interface{}) error {
+	var sets [][]types.SqlParameter
+	for _, args := range paramSets {
+		params, err := buildParams(args)
+		if err != nil {
+			return err
+		}
+		sets = append(sets, params)
+	}
+
+	_, err := d.client.BatchExecuteStatement(ctx, &rdsdata.BatchExecuteStatementInput{
+		ResourceArn:   aws.String(d.config.ResourceARN),
+		SecretArn:     aws.String(d.config.SecretARN),
+		Database:      optionalString(d.config.Database),
+		Sql:           aws.String(rewritePlaceholders(d.config.Dialect, query)),
+		ParameterSets: sets,
+		TransactionId: d.transactionID,
+	})
+	return err
+}
+
+// BeginTx implements the Tx interface
+func (d DataAPIAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
+	if d.transactionID != nil {
+		return nil, fmt.Errorf("kdataapi: nested transactions are not supported")
+	}
+
+	output, err := d.client.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{
+		ResourceArn: aws.String(d.config.ResourceARN),
+		SecretArn:   aws.String(d.config.SecretARN),
+		Database:    optionalString(d.config.Database),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx := d
+	tx.transactionID = output.TransactionId
+	return DataAPITx{tx}, nil
+}
+
+func (d DataAPIAdapter) executeStatement(
+	ctx context.Context,
+	query string,
+	args []interface{},
+	includeMetadata bool,
+) (*rdsdata.ExecuteStatementOutput, error) {
+	params, err := buildParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.client.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
+		ResourceArn:           aws.String(d.config.ResourceARN),
+		SecretArn:             aws.String(d.config.SecretARN),
+		Database:              optionalString(d.config.Database),
+		Sql:                   aws.String(rewritePlaceholders(d.config.Dialect, query)),
+		Parameters:            params,
+		TransactionId:         d.transactionID,
+		IncludeResultMetadata: includeMetadata,
+	})
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// DataAPIResult is used to implement the DBAdapter interface and implements
+// the Result interface
+type DataAPIResult struct {
+	output *rdsdata.ExecuteStatementOutput
+}
+
+// RowsAffected implements the Result interface
+func (d DataAPIResult) RowsAffected() (int64, error) {
+	return d.output.NumberOfRecordsUpdated, nil
+}
+
+// LastInsertId implements the Result interface
+func (d DataAPIResult) LastInsertId() (int64, error) {
+	if len(d.output.GeneratedFields) == 0 {
+		return 0, fmt.Errorf("kdataapi: no generated fields were returned by the Data API")
+	}
+
+	id, ok := d.output.GeneratedFields[0].(*types.FieldMemberLongValue)
+	if !ok {
+		return 0, fmt.Errorf("kdataapi: expected the generated ID to be a long value but got: %T", d.output.GeneratedFields[0])
+	}
+	return id.Value, nil
+}
+
+// DataAPITx is used to implement the DBAdapter interface and implements
+// the Tx interface
+type DataAPITx struct {
+	DataAPIAdapter
+}
+
+var _ ksql.Tx = DataAPITx{}
+
+// Rollback implements the Tx interface
+func (d DataAPITx) Rollback(ctx context.Context) error {
+	_, err := d.client.RollbackTransaction(ctx, &rdsdata.RollbackTransactionInput{
+		ResourceArn:   aws.String(d.config.ResourceARN),
+		SecretArn:     aws.String(d.config.SecretARN),
+		TransactionId: d.transactionID,
+	})
+	return err
+}
+
+// Commit implements the Tx interface
+func (d DataAPITx) Commit(ctx context.Context) error {
+	_, err := d.client.CommitTransaction(ctx, &rdsdata.CommitTransactionInput{
+		ResourceArn:   aws.String(d.config.ResourceARN),
+		SecretArn:     aws.String(d.config.SecretARN),
+		TransactionId: d.transactionID,
+	})
+	return err
+}
+
+// DataAPIRows implements the Rows interface and is used to help
+// the DataAPIAdapter to implement the DBAdapter interface.
+//
+// Since the Data API returns all the records at once they
+// are kept in memory and iterated over by the Next method.
+type DataAPIRows struct {
+	columns []string
+	records [][]types.Field
+	idx     int
+}
+
+var _ ksql.Rows = &DataAPIRows{}
+
+// Columns implements the Rows interface
+func (d *DataAPIRows) Columns() ([]string, error) {
+	return d.columns, nil
+}
+
+// Next implements the Rows interface
+func (d *DataAPIRows) Next() bool {
+	if d.idx+1 >= len(d.records) {
+		return false
+	}
+	d.idx++
+	return true
+}
+
+// Scan implements the Rows interface
+func (d *DataAPIRows) Scan(args ...interface{}) error {
+	if d.idx < 0 || d.idx >= len(d.records) {
+		return fmt.Errorf("kdataapi: Scan called without calling Next")
+	}
+
+	record := d.records[d.idx]
+	if len(args) != len(record) {
+		return fmt.Errorf("kdataapi: expected %d destination arguments in Scan, not %d", len(record), len(args))
+	}
+
+	for i, field := range record {
+		value, err := fromField(field)
+		if err != nil {
+			return err
+		}
+
+		err = assignValue(args[i], value)
+		if err != nil {
+			return fmt.Errorf("kdataapi: error scanning column %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Err implements the Rows interface
+func (d *DataAPIRows) Err() error {
+	return nil
+}
+
+// Close implements the Rows interface
+func (d *DataAPIRows) Close() error {
+	return nil
+}
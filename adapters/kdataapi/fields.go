@@ -0,0 +1,104 @@
+package kdataapi
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// buildParams converts the query params into
+// the named parameters used by the Data API.
+func buildParams(args []interface{}) ([]types.SqlParameter, error) {
+	params := make([]types.SqlParameter, 0, len(args))
+	for i, arg := range args {
+		param, err := toSQLParameter(arg)
+		if err != nil {
+			return nil, fmt.Errorf("kdataapi: unable to convert param %d: %w", i+1, err)
+		}
+
+		param.Name = aws.String(paramName(i + 1))
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+func toSQLParameter(arg interface{}) (types.SqlParameter, error) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return types.SqlParameter{}, err
+		}
+		arg = value
+	}
+
+	switch v := arg.(type) {
+	case nil:
+		return types.SqlParameter{Value: &types.FieldMemberIsNull{Value: true}}, nil
+	case string:
+		return types.SqlParameter{Value: &types.FieldMemberStringValue{Value: v}}, nil
+	case []byte:
+		return types.SqlParameter{Value: &types.FieldMemberBlobValue{Value: v}}, nil
+	case bool:
+		return types.SqlParameter{Value: &types.FieldMemberBooleanValue{Value: v}}, nil
+	case int:
+		return longParam(int64(v)), nil
+	case int8:
+		return longParam(int64(v)), nil
+	case int16:
+		return longParam(int64(v)), nil
+	case int32:
+		return longParam(int64(v)), nil
+	case int64:
+		return longParam(v), nil
+	case uint:
+		return longParam(int64(v)), nil
+	case uint8:
+		return longParam(int64(v)), nil
+	case uint16:
+		return longParam(int64(v)), nil
+	case uint32:
+		return longParam(int64(v)), nil
+	case uint64:
+		return longParam(int64(v)), nil
+	case float32:
+		return types.SqlParameter{Value: &types.FieldMemberDoubleValue{Value: float64(v)}}, nil
+	case float64:
+		return types.SqlParameter{Value: &types.FieldMemberDoubleValue{Value: v}}, nil
+	case time.Time:
+		return types.SqlParameter{
+			Value:    &types.FieldMemberStringValue{Value: v.UTC().Format(timestampLayout)},
+			TypeHint: types.TypeHintTimestamp,
+		}, nil
+	}
+
+	return types.SqlParameter{}, fmt.Errorf("unsupported type %T", arg)
+}
+
+func longParam(v int64) types.SqlParameter {
+	return types.SqlParameter{Value: &types.FieldMemberLongValue{Value: v}}
+}
+
+// fromField converts the fields returned by the Data API into one
+// of the types used by the database/sql package when scanning, i.e.
+// nil, int64, float64, bool, []byte or string.
+func fromField(field types.Field) (interface{}, error) {
+	switch f := field.(type) {
+	case *types.FieldMemberIsNull:
+		return nil, nil
+	case *types.FieldMemberStringValue:
+		return f.Value, nil
+	case *types.FieldMemberLongValue:
+		return f.Value, nil
+	case *types.FieldMemberDoubleValue:
+		return f.Value, nil
+	case *types.FieldMemberBooleanValue:
+		return f.Value, nil
+	case *types.FieldMemberBlobValue:
+		return f.Value, nil
+	}
+
+	return nil, fmt.Errorf("kdataapi: unsupported field type %T", field)
+}
@@ -0,0 +1,80 @@
+// Package kdataapi contains a KSQL adapter for Aurora Serverless
+// that sends the queries through the RDS Data API, i.e. over HTTP,
+// instead of keeping a connection open with the database.
+//
+// This is useful on environments that can't hold persistent
+// connections, e.g. AWS Lambda functions.
+package kdataapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/vingarcia/ksql"
+)
+
+// Client is the subset of the methods of the *rdsdata.Client
+// used by this adapter, it is declared as an interface so the
+// client can be wrapped or replaced by a fake on tests.
+type Client interface {
+	ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
+	BatchExecuteStatement(ctx context.Context, params *rdsdata.BatchExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BatchExecuteStatementOutput, error)
+	BeginTransaction(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error)
+	CommitTransaction(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error)
+	RollbackTransaction(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error)
+}
+
+var _ Client = &rdsdata.Client{}
+
+// Config describes which Aurora cluster and database
+// the queries are sent to.
+type Config struct {
+	// ResourceARN is the ARN of the Aurora Serverless cluster
+	ResourceARN string
+
+	// SecretARN is the ARN of the Secrets Manager secret
+	// containing the credentials for accessing the database
+	SecretARN string
+
+	// Database is the name of the database, it is optional
+	// if the default database of the cluster should be used
+	Database string
+
+	// Dialect is the dialect of the database engine used by the
+	// cluster, i.e. "postgres" or "mysql", it defaults to "postgres"
+	Dialect string
+}
+
+// SetDefaultValues sets the default values of the Config if unset
+func (c *Config) SetDefaultValues() {
+	if c.Dialect == "" {
+		c.Dialect = "postgres"
+	}
+}
+
+// New instantiates a new ksql.Client that sends the
+// queries to Aurora Serverless using the RDS Data API
+//
+// The queries should be written with the placeholders of the
+// chosen dialect, e.g. `$1` for Postgres or `?` for MySQL, they
+// are converted to the named parameters used by the Data API.
+func New(
+	_ context.Context,
+	client Client,
+	config Config,
+) (ksql.DB, error) {
+	config.SetDefaultValues()
+
+	if config.ResourceARN == "" || config.SecretARN == "" {
+		return ksql.DB{}, fmt.Errorf("kdataapi: the ResourceARN and SecretARN are required")
+	}
+
+	switch config.Dialect {
+	case "postgres", "mysql":
+	default:
+		return ksql.DB{}, fmt.Errorf("kdataapi: unsupported dialect: '%s', use either 'postgres' or 'mysql'", config.Dialect)
+	}
+
+	return ksql.NewWithAdapter(NewDataAPIAdapter(client, config), config.Dialect)
+}
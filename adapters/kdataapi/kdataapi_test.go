@@ -0,0 +1,231 @@
+package kdataapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/vingarcia/ksql"
+)
+
+func TestRewritePlaceholders(t *testing.T) {
+	tests := []struct {
+		desc          string
+		dialect       string
+		query         string
+		expectedQuery string
+	}{
+		{
+			desc:          "should convert postgres placeholders",
+			dialect:       "postgres",
+			query:         `SELECT * FROM users WHERE id = $1 AND age > $12`,
+			expectedQuery: `SELECT * FROM users WHERE id = :p1 AND age > :p12`,
+		},
+		{
+			desc:          "should convert mysql placeholders",
+			dialect:       "mysql",
+			query:         "SELECT * FROM `users` WHERE id = ? AND age > ?",
+			expectedQuery: "SELECT * FROM `users` WHERE id = :p1 AND age > :p2",
+		},
+		{
+			desc:          "should ignore placeholders inside strings",
+			dialect:       "mysql",
+			query:         `SELECT * FROM users WHERE name = 'who?' AND "weird?col" = ?`,
+			expectedQuery: `SELECT * FROM users WHERE name = 'who?' AND "weird?col" = :p1`,
+		},
+		{
+			desc:          "should not change casts or question marks on postgres",
+			dialect:       "postgres",
+			query:         `SELECT $1::int, data ? 'key' FROM users`,
+			expectedQuery: `SELECT :p1::int, data ? 'key' FROM users`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			query := rewritePlaceholders(test.dialect, test.query)
+			if query != test.expectedQuery {
+				t.Fatalf("expected query to be `%s` but got `%s`", test.expectedQuery, query)
+			}
+		})
+	}
+}
+
+func TestAssignValue(t *testing.T) {
+	t.Run("should convert the values to the destination types", func(t *testing.T) {
+		var i int
+		var u uint32
+		var f float32
+		var s string
+		var b bool
+		var bs []byte
+		var ptr *int
+		var iface interface{}
+		var tm time.Time
+
+		for _, test := range []struct {
+			dest  interface{}
+			value interface{}
+		}{
+			{&i, int64(42)},
+			{&u, int64(43)},
+			{&f, float64(1.5)},
+			{&s, "foo"},
+			{&b, true},
+			{&bs, "bar"},
+			{&ptr, int64(44)},
+			{&iface, "baz"},
+			{&tm, "2022-01-02 03:04:05.123"},
+		} {
+			err := assignValue(test.dest, test.value)
+			if err != nil {
+				t.Fatalf("unexpected error assigning %v to %T: %s", test.value, test.dest, err)
+			}
+		}
+
+		expectedTime := time.Date(2022, 1, 2, 3, 4, 5, 123000000, time.UTC)
+		if i != 42 || u != 43 || f != 1.5 || s != "foo" || !b || string(bs) != "bar" ||
+			ptr == nil || *ptr != 44 || iface != "baz" || !tm.Equal(expectedTime) {
+			t.Fatalf("unexpected values: %v %v %v %v %v %v %v %v %v", i, u, f, s, b, bs, ptr, iface, tm)
+		}
+	})
+
+	t.Run("should set the destination to its zero value for NULLs", func(t *testing.T) {
+		i := 42
+		ptr := &i
+		err := assignValue(&ptr, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ptr != nil {
+			t.Fatalf("expected ptr to be nil but got: %v", *ptr)
+		}
+	})
+
+	t.Run("should report error for invalid conversions", func(t *testing.T) {
+		var i int
+		err := assignValue(&i, "not a number")
+		if err == nil {
+			t.Fatal("expected an error but got nil")
+		}
+	})
+}
+
+func TestAdapter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should send the params and scan the returned records", func(t *testing.T) {
+		client := &fakeClient{
+			executeOutput: &rdsdata.ExecuteStatementOutput{
+				ColumnMetadata: []types.ColumnMetadata{
+					{Label: aws.String("id")},
+					{Label: aws.String("name")},
+				},
+				Records: [][]types.Field{
+					{&types.FieldMemberLongValue{Value: 1}, &types.FieldMemberStringValue{Value: "Alice"}},
+					{&types.FieldMemberLongValue{Value: 2}, &types.FieldMemberIsNull{Value: true}},
+				},
+			},
+		}
+
+		db, err := New(ctx, client, Config{
+			ResourceARN: "fakeResourceARN",
+			SecretARN:   "fakeSecretARN",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var users []struct {
+			ID   int     `ksql:"id"`
+			Name *string `ksql:"name"`
+		}
+		err = db.Query(ctx, &users, "SELECT id, name FROM users WHERE age > $1", 18)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(users) != 2 || users[0].ID != 1 || *users[0].Name != "Alice" || users[1].ID != 2 || users[1].Name != nil {
+			t.Fatalf("unexpected users: %+v", users)
+		}
+
+		input := client.executeInputs[0]
+		if aws.ToString(input.Sql) != "SELECT id, name FROM users WHERE age > :p1" {
+			t.Fatalf("unexpected query: %s", aws.ToString(input.Sql))
+		}
+		expectedParams := []types.SqlParameter{
+			{Name: aws.String("p1"), Value: &types.FieldMemberLongValue{Value: 18}},
+		}
+		if !reflect.DeepEqual(input.Parameters, expectedParams) {
+			t.Fatalf("unexpected params: %#v", input.Parameters)
+		}
+	})
+
+	t.Run("should send the queries of a transaction with the transaction ID", func(t *testing.T) {
+		client := &fakeClient{
+			executeOutput: &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1},
+		}
+
+		db, err := New(ctx, client, Config{
+			ResourceARN: "fakeResourceARN",
+			SecretARN:   "fakeSecretARN",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		err = db.Transaction(ctx, func(db ksql.Provider) error {
+			_, err := db.Exec(ctx, "UPDATE users SET age = $1", 20)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if aws.ToString(client.executeInputs[0].TransactionId) != "fakeTransactionID" {
+			t.Fatalf("expected the query to be sent with the transaction ID")
+		}
+		if aws.ToString(client.committedTransactionID) != "fakeTransactionID" {
+			t.Fatalf("expected the transaction to be committed")
+		}
+	})
+
+	t.Run("should report error if the ARNs are missing", func(t *testing.T) {
+		_, err := New(ctx, &fakeClient{}, Config{})
+		if err == nil {
+			t.Fatal("expected an error but got nil")
+		}
+	})
+}
+
+type fakeClient struct {
+	executeOutput *rdsdata.ExecuteStatementOutput
+	executeInputs []*rdsdata.ExecuteStatementInput
+
+	committedTransactionID *string
+}
+
+func (f *fakeClient) ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	f.executeInputs = append(f.executeInputs, params)
+	return f.executeOutput, nil
+}
+
+func (f *fakeClient) BatchExecuteStatement(ctx context.Context, params *rdsdata.BatchExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BatchExecuteStatementOutput, error) {
+	return &rdsdata.BatchExecuteStatementOutput{}, nil
+}
+
+func (f *fakeClient) BeginTransaction(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("fakeTransactionID")}, nil
+}
+
+func (f *fakeClient) CommitTransaction(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	f.committedTransactionID = params.TransactionId
+	return &rdsdata.CommitTransactionOutput{}, nil
+}
+
+func (f *fakeClient) RollbackTransaction(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	return &rdsdata.RollbackTransactionOutput{}, nil
+}
@@ -0,0 +1,63 @@
+package kdataapi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// rewritePlaceholders converts the placeholders of the dialect, i.e.
+// `$1` for Postgres and `?` for MySQL, into the named parameters
+// expected by the Data API, i.e. `:p1`, `:p2`, etc.
+//
+// Placeholders inside quoted strings or identifiers are ignored.
+func rewritePlaceholders(dialect string, query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	var quote byte
+	var count int
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+
+		case c == '?' && dialect == "mysql":
+			count++
+			b.WriteString(":" + paramName(count))
+
+		case c == '$' && dialect == "postgres" && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			b.WriteString(":p" + query[i+1:j])
+			i = j - 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// paramName returns the name of the param on the input
+// position, starting from 1, without the `:` prefix
+func paramName(position int) string {
+	return "p" + strconv.Itoa(position)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
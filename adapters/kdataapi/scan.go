@@ -0,0 +1,131 @@
+package kdataapi
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// timestampLayout is the format used by the Data API for
+// sending and receiving dates and timestamps as strings
+const timestampLayout = "2006-01-02 15:04:05.999999"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// assignValue saves the value received from the Data API, i.e. one
+// of nil, int64, float64, bool, []byte or string, on the destination
+// pointer, similar to what the database/sql package does when scanning.
+func assignValue(dest interface{}, value interface{}) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("kdataapi: expected a non-nil pointer as scan destination but got: %T", dest)
+	}
+	v = v.Elem()
+
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Interface {
+		v.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		err := assignValue(ptr.Interface(), value)
+		if err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("kdataapi: can't scan value of type %T into time.Time", value)
+		}
+		t, err := time.Parse(timestampLayout, s)
+		if err != nil {
+			// Columns of type DATE don't have the time part:
+			t, err = time.Parse("2006-01-02", s)
+		}
+		if err != nil {
+			return fmt.Errorf("kdataapi: unable to parse '%s' as time.Time: %w", s, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(v.Type()) {
+		v.Set(rv)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(asString(value), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("kdataapi: can't scan value %v into %s: %w", value, v.Type(), err)
+		}
+		v.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(asString(value), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("kdataapi: can't scan value %v into %s: %w", value, v.Type(), err)
+		}
+		v.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(asString(value), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("kdataapi: can't scan value %v into %s: %w", value, v.Type(), err)
+		}
+		v.SetFloat(f)
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(asString(value))
+		if err != nil {
+			return fmt.Errorf("kdataapi: can't scan value %v into %s: %w", value, v.Type(), err)
+		}
+		v.SetBool(b)
+		return nil
+
+	case reflect.String:
+		v.SetString(asString(value))
+		return nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(asString(value)))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("kdataapi: can't scan value of type %T into %s", value, v.Type())
+}
+
+func asString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,101 @@
+package kduckdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/vingarcia/ksql/ksqltest"
+)
+
+// BulkInsert inserts a slice of records using the DuckDB appender API,
+// which is much faster than running one INSERT per record.
+//
+// The records argument must be a slice of structs or struct pointers
+// tagged with `ksql`, the table columns missing from the struct are
+// set to NULL and the attribute modifiers, e.g. `ksql:"foo,json"`,
+// are not applied, so the values are sent to DuckDB as they are.
+//
+// Note that the appender validates the Go types against the column
+// types, e.g. an `INTEGER` column expects an `int32` attribute.
+func BulkInsert(ctx context.Context, db *sql.DB, tableName string, records interface{}) error {
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("kduckdb: expected records to be a slice of structs but got: %T", records)
+	}
+
+	columns, err := getColumnNames(ctx, db, tableName)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]driver.Value, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		m, err := ksqltest.StructToMap(v.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("kduckdb: unable to read record %d: %w", i, err)
+		}
+
+		row := make([]driver.Value, len(columns))
+		for j, col := range columns {
+			row[j] = m[col]
+		}
+		rows = append(rows, row)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		appender, err := duckdb.NewAppenderFromConn(driverConn.(driver.Conn), "", tableName)
+		if err != nil {
+			return fmt.Errorf("kduckdb: unable to create appender for table '%s': %w", tableName, err)
+		}
+
+		for i, row := range rows {
+			err = appender.AppendRow(row...)
+			if err != nil {
+				appender.Close()
+				return fmt.Errorf("kduckdb: unable to append record %d: %w", i, err)
+			}
+		}
+
+		// Close also flushes the appended rows:
+		return appender.Close()
+	})
+}
+
+func getColumnNames(ctx context.Context, db *sql.DB, tableName string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT column_name FROM information_schema.columns WHERE table_name = $1 ORDER BY ordinal_position`,
+		tableName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("kduckdb: unable to read the columns of table '%s': %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("kduckdb: table '%s' not found", tableName)
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,17 @@
+module github.com/vingarcia/ksql/adapters/kduckdb
+
+go 1.19
+
+require (
+	github.com/marcboeker/go-duckdb v1.4.3
+	github.com/vingarcia/ksql v1.4.7
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
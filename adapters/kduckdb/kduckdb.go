@@ -0,0 +1,50 @@
+package kduckdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vingarcia/ksql"
+
+	// This is imported here so the user don't
+	// have to worry about it when he uses it.
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+// NewFromSQLDB builds a ksql.DB from a *sql.DB instance
+func NewFromSQLDB(db *sql.DB) (ksql.DB, error) {
+	return ksql.NewWithAdapter(NewSQLAdapter(db), "duckdb")
+}
+
+// New instantiates a new KissSQL client using the "duckdb" driver
+//
+// The connection string is the path to the database file,
+// or an empty string for using an in-memory database.
+func New(
+	_ context.Context,
+	connectionString string,
+	config ksql.Config,
+) (ksql.DB, error) {
+	config.SetDefaultValues()
+
+	db, err := sql.Open("duckdb", connectionString)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+	if err = db.Ping(); err != nil {
+		return ksql.DB{}, err
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	return ksql.NewWithConfig(NewSQLAdapter(db), "duckdb", config)
+}
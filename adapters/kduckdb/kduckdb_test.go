@@ -0,0 +1,71 @@
+package kduckdb
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/vingarcia/ksql"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAdapter(t *testing.T) {
+	os.Remove("/tmp/ksql.duckdb")
+	ksql.RunTestsForAdapter(t, "kduckdb", "duckdb", "/tmp/ksql.duckdb", func(t *testing.T) (ksql.DBAdapter, io.Closer) {
+		db, err := sql.Open("duckdb", "/tmp/ksql.duckdb")
+		tt.AssertNoErr(t, err)
+		return SQLAdapter{db}, db
+	})
+}
+
+func TestBulkInsert(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("duckdb", "")
+	tt.AssertNoErr(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE events (id INTEGER, name VARCHAR, score DOUBLE)`)
+	tt.AssertNoErr(t, err)
+
+	type event struct {
+		ID   int32  `ksql:"id"`
+		Name string `ksql:"name"`
+	}
+
+	t.Run("should insert all the records", func(t *testing.T) {
+		err := BulkInsert(ctx, db, "events", []event{
+			{ID: 1, Name: "first"},
+			{ID: 2, Name: "second"},
+		})
+		tt.AssertNoErr(t, err)
+
+		kdb, err := NewFromSQLDB(db)
+		tt.AssertNoErr(t, err)
+
+		var rows []struct {
+			ID    int32    `ksql:"id"`
+			Name  string   `ksql:"name"`
+			Score *float64 `ksql:"score"`
+		}
+		err = kdb.Query(ctx, &rows, "FROM events ORDER BY id")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(rows), 2)
+		tt.AssertEqual(t, rows[0].Name, "first")
+		tt.AssertEqual(t, rows[1].Name, "second")
+		tt.AssertEqual(t, rows[0].Score, (*float64)(nil))
+	})
+
+	t.Run("should report error if the table doesn't exist", func(t *testing.T) {
+		err := BulkInsert(ctx, db, "not_a_table", []event{{ID: 1}})
+		tt.AssertNotEqual(t, err, nil)
+	})
+
+	t.Run("should report error if records is not a slice", func(t *testing.T) {
+		err := BulkInsert(ctx, db, "events", event{ID: 1})
+		tt.AssertNotEqual(t, err, nil)
+	})
+}
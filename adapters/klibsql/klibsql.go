@@ -0,0 +1,167 @@
+// Package klibsql contains a KSQL adapter for libsql, the SQLite fork
+// used by Turso.
+//
+// It supports both connecting directly to a remote database, e.g.
+// `libsql://my-db.turso.io?authToken=...`, and the embedded replica
+// mode, where the reads are served from a local file that is kept in
+// sync with the remote database and the writes are sent to the remote one.
+package klibsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tursodatabase/go-libsql"
+	"github.com/vingarcia/ksql"
+)
+
+// Options contains the libsql specific options
+// that are not available on the ksql.Config struct.
+type Options struct {
+	// AuthToken is the token used for authenticating with the remote
+	// database, it can also be passed on the connection string as
+	// the `authToken` query param
+	AuthToken string
+
+	// ReplicaPath is the path of the local database file, if set the
+	// embedded replica mode is used instead of only connecting to the
+	// remote database
+	ReplicaPath string
+
+	// SyncInterval is the interval between the automatic syncs of the
+	// embedded replica, if unset the replica is only synced when created
+	SyncInterval time.Duration
+}
+
+// NewFromSQLDB builds a ksql.DB from a *sql.DB instance
+//
+// This is useful for users that need more control over the
+// embedded replica, e.g. for calling `connector.Sync()` manually,
+// since they can build the `*sql.DB` with `sql.OpenDB(connector)`.
+func NewFromSQLDB(db *sql.DB) (ksql.DB, error) {
+	return ksql.NewWithAdapter(NewSQLAdapter(db), "sqlite3")
+}
+
+// New instantiates a new KissSQL client connected to a remote
+// libsql database using the "libsql" driver
+func New(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+) (ksql.DB, error) {
+	return NewWithOptions(ctx, connectionString, config, Options{})
+}
+
+// NewWithOptions works like New but also allows the user to pass the
+// auth token separately and to use the embedded replica mode
+func NewWithOptions(
+	_ context.Context,
+	connectionString string,
+	config ksql.Config,
+	options Options,
+) (ksql.DB, error) {
+	config.SetDefaultValues()
+
+	if options.ReplicaPath != "" {
+		return newEmbeddedReplica(connectionString, config, options)
+	}
+
+	connectionString, err := addAuthToken(connectionString, options.AuthToken)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+
+	db, err := sql.Open("libsql", connectionString)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+	if err = db.Ping(); err != nil {
+		return ksql.DB{}, err
+	}
+
+	configurePool(db, config)
+
+	return ksql.NewWithConfig(NewSQLAdapter(db), "sqlite3", config)
+}
+
+func newEmbeddedReplica(primaryURL string, config ksql.Config, options Options) (ksql.DB, error) {
+	var connectorOptions []libsql.Option
+	if options.AuthToken != "" {
+		connectorOptions = append(connectorOptions, libsql.WithAuthToken(options.AuthToken))
+	}
+	if options.SyncInterval != 0 {
+		connectorOptions = append(connectorOptions, libsql.WithSyncInterval(options.SyncInterval))
+	}
+
+	connector, err := libsql.NewEmbeddedReplicaConnector(options.ReplicaPath, primaryURL, connectorOptions...)
+	if err != nil {
+		return ksql.DB{}, fmt.Errorf("klibsql: unable to create embedded replica: %w", err)
+	}
+
+	// Making sure the replica starts with the latest data:
+	if err = connector.Sync(); err != nil {
+		connector.Close()
+		return ksql.DB{}, fmt.Errorf("klibsql: unable to sync embedded replica: %w", err)
+	}
+
+	db := sql.OpenDB(connector)
+	configurePool(db, config)
+
+	return ksql.NewWithConfig(replicaAdapter{
+		SQLAdapter: NewSQLAdapter(db),
+		connector:  connector,
+	}, "sqlite3", config)
+}
+
+// configurePool applies the connection pool options of the ksql.Config
+func configurePool(db *sql.DB, config ksql.Config) {
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+}
+
+// replicaAdapter also closes the embedded replica connector
+// when the adapter is closed.
+type replicaAdapter struct {
+	SQLAdapter
+
+	connector *libsql.Connector
+}
+
+// Close implements the io.Closer interface
+func (r replicaAdapter) Close() error {
+	err := r.SQLAdapter.Close()
+	if closeErr := r.connector.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// addAuthToken adds the token to the connection string as the
+// `authToken` query param, which is how the driver expects it
+func addAuthToken(connectionString string, token string) (string, error) {
+	if token == "" {
+		return connectionString, nil
+	}
+
+	u, err := url.Parse(connectionString)
+	if err != nil {
+		return "", fmt.Errorf("klibsql: invalid connection string: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("authToken", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
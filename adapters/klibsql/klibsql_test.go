@@ -0,0 +1,76 @@
+package klibsql
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"testing"
+
+	_ "github.com/tursodatabase/go-libsql"
+	"github.com/vingarcia/ksql"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAdapter(t *testing.T) {
+	os.Remove("/tmp/ksql.libsql.db")
+	connStr := "file:/tmp/ksql.libsql.db"
+
+	registerAsSQLite3(t, connStr)
+
+	ksql.RunTestsForAdapter(t, "klibsql", "sqlite3", connStr, func(t *testing.T) (ksql.DBAdapter, io.Closer) {
+		db, err := sql.Open("libsql", connStr)
+		tt.AssertNoErr(t, err)
+		return SQLAdapter{db}, db
+	})
+}
+
+// registerAsSQLite3 registers the libsql driver with the "sqlite3" name,
+// since the shared tests create the tables using the name of the dialect.
+func registerAsSQLite3(t *testing.T, connStr string) {
+	db, err := sql.Open("libsql", connStr)
+	tt.AssertNoErr(t, err)
+	defer db.Close()
+
+	sql.Register("sqlite3", db.Driver())
+}
+
+func TestAddAuthToken(t *testing.T) {
+	tests := []struct {
+		desc             string
+		connectionString string
+		token            string
+		expectedConnStr  string
+	}{
+		{
+			desc:             "should not change the connection string if there is no token",
+			connectionString: "libsql://my-db.turso.io?authToken=fakeToken",
+			token:            "",
+			expectedConnStr:  "libsql://my-db.turso.io?authToken=fakeToken",
+		},
+		{
+			desc:             "should add the token as a query param",
+			connectionString: "libsql://my-db.turso.io",
+			token:            "fakeToken",
+			expectedConnStr:  "libsql://my-db.turso.io?authToken=fakeToken",
+		},
+		{
+			desc:             "should keep the other query params",
+			connectionString: "libsql://my-db.turso.io?tls=1",
+			token:            "fakeToken",
+			expectedConnStr:  "libsql://my-db.turso.io?authToken=fakeToken&tls=1",
+		},
+		{
+			desc:             "should override the token from the connection string",
+			connectionString: "libsql://my-db.turso.io?authToken=oldToken",
+			token:            "newToken",
+			expectedConnStr:  "libsql://my-db.turso.io?authToken=newToken",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			connStr, err := addAuthToken(test.connectionString, test.token)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, connStr, test.expectedConnStr)
+		})
+	}
+}
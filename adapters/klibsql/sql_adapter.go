@@ -0,0 +1,157 @@
+package klibsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vingarcia/ksql"
+)
+
+// SQLAdapter adapts the sql.DB type to be compatible with the `DBAdapter` interface
+type SQLAdapter struct {
+	*sql.DB
+}
+
+var _ ksql.DBAdapter = SQLAdapter{}
+
+// NewSQLAdapter returns a new instance of SQLAdapter with
+// the provided database instance.
+func NewSQLAdapter(db *sql.DB) SQLAdapter {
+	return SQLAdapter{
+		DB: db,
+	}
+}
+
+// ExecContext implements the DBAdapter interface
+func (s SQLAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	return s.DB.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements the DBAdapter interface
+func (s SQLAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{Rows: rows}, nil
+}
+
+// BeginTx implements the Tx interface
+func (s SQLAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	return SQLTx{Tx: tx}, err
+}
+
+// BeginTxWithOptions implements the ksql.TxOptionsBeginner interface
+func (s SQLAdapter) BeginTxWithOptions(ctx context.Context, opts ksql.TxOptions) (ksql.Tx, error) {
+	sqlOpts, err := opts.SQLTxOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// The SQLite transactions are always serializable,
+	// but the driver only accepts the default level:
+	if sqlOpts.Isolation == sql.LevelSerializable {
+		sqlOpts.Isolation = sql.LevelDefault
+	}
+
+	tx, err := s.DB.BeginTx(ctx, sqlOpts)
+	return SQLTx{Tx: tx}, err
+}
+
+// Close implements the io.Closer interface
+func (s SQLAdapter) Close() error {
+	return s.DB.Close()
+}
+
+// SQLTx is used to implement the DBAdapter interface and implements
+// the Tx interface
+type SQLTx struct {
+	*sql.Tx
+}
+
+// ExecContext implements the Tx interface
+func (s SQLTx) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	return s.Tx.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements the Tx interface
+func (s SQLTx) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	rows, err := s.Tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{Rows: rows}, nil
+}
+
+// Rollback implements the Tx interface
+func (s SQLTx) Rollback(ctx context.Context) error {
+	return s.Tx.Rollback()
+}
+
+// Commit implements the Tx interface
+func (s SQLTx) Commit(ctx context.Context) error {
+	return s.Tx.Commit()
+}
+
+var _ ksql.Tx = SQLTx{}
+
+// sqlRows fixes the scanning of the timestamps saved in UTC, the driver
+// saves them using the RFC3339 format, e.g. `2024-01-02T15:04:05Z`,
+// but returns them as strings since it doesn't parse the `Z` suffix.
+type sqlRows struct {
+	*sql.Rows
+}
+
+// Scan implements the ksql.Rows interface
+func (r sqlRows) Scan(dest ...interface{}) error {
+	fixedDest := make([]interface{}, len(dest))
+	for i, d := range dest {
+		switch d.(type) {
+		case *time.Time, **time.Time, *sql.NullTime:
+			fixedDest[i] = timeScanner{dest: d}
+		default:
+			fixedDest[i] = d
+		}
+	}
+	return r.Rows.Scan(fixedDest...)
+}
+
+type timeScanner struct {
+	dest interface{}
+}
+
+func (s timeScanner) Scan(value interface{}) error {
+	if str, ok := value.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return fmt.Errorf("klibsql: can't parse '%s' as a timestamp: %w", str, err)
+		}
+		value = t
+	}
+
+	switch dest := s.dest.(type) {
+	case *time.Time:
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("klibsql: can't scan value of type %T into a time.Time", value)
+		}
+		*dest = t
+	case **time.Time:
+		if value == nil {
+			*dest = nil
+			return nil
+		}
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("klibsql: can't scan value of type %T into a *time.Time", value)
+		}
+		*dest = &t
+	case *sql.NullTime:
+		return dest.Scan(value)
+	}
+
+	return nil
+}
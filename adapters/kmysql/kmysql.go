@@ -16,11 +16,40 @@ func NewFromSQLDB(db *sql.DB) (ksql.DB, error) {
 	return ksql.NewWithAdapter(NewSQLAdapter(db), "mysql")
 }
 
+// NewMariaDBFromSQLDB works like NewFromSQLDB but uses the
+// MariaDB dialect, see NewMariaDB() for more details
+func NewMariaDBFromSQLDB(db *sql.DB) (ksql.DB, error) {
+	return ksql.NewWithAdapter(NewSQLAdapter(db), "mariadb")
+}
+
 // New instantiates a new KissSQL client using the "mysql" driver
 func New(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+) (ksql.DB, error) {
+	return newWithDialect(ctx, connectionString, config, "mysql")
+}
+
+// NewMariaDB works like New but uses the MariaDB dialect, which
+// retrieves the IDs of the inserted records using `INSERT ... RETURNING`
+// instead of LastInsertId, so it also works for tables whose IDs are
+// not AUTO_INCREMENT.
+//
+// It requires MariaDB 10.5 or newer.
+func NewMariaDB(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+) (ksql.DB, error) {
+	return newWithDialect(ctx, connectionString, config, "mariadb")
+}
+
+func newWithDialect(
 	_ context.Context,
 	connectionString string,
 	config ksql.Config,
+	dialect string,
 ) (ksql.DB, error) {
 	config.SetDefaultValues()
 
@@ -33,6 +62,15 @@ func New(
 	}
 
 	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
 
-	return ksql.NewWithAdapter(NewSQLAdapter(db), "mysql")
+	return ksql.NewWithConfig(NewSQLAdapter(db), dialect, config)
 }
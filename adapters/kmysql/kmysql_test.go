@@ -24,6 +24,15 @@ func TestAdapter(t *testing.T) {
 		}
 		return SQLAdapter{db}, db
 	})
+
+	// The container runs MariaDB, so we also test the MariaDB dialect:
+	ksql.RunTestsForAdapter(t, "kmysql", "mariadb", mysqlURL, func(t *testing.T) (ksql.DBAdapter, io.Closer) {
+		db, err := sql.Open("mysql", mysqlURL)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return SQLAdapter{db}, db
+	})
 }
 
 func startMySQLDB(dbName string) (databaseURL string, closer func()) {
@@ -38,6 +38,17 @@ func (s SQLAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
 	return SQLTx{Tx: tx}, err
 }
 
+// BeginTxWithOptions implements the ksql.TxOptionsBeginner interface
+func (s SQLAdapter) BeginTxWithOptions(ctx context.Context, opts ksql.TxOptions) (ksql.Tx, error) {
+	sqlOpts, err := opts.SQLTxOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, sqlOpts)
+	return SQLTx{Tx: tx}, err
+}
+
 // Close implements the io.Closer interface
 func (s SQLAdapter) Close() error {
 	return s.DB.Close()
@@ -0,0 +1,8 @@
+module github.com/vingarcia/ksql/adapters/koracle
+
+go 1.14
+
+require (
+	github.com/godror/godror v0.36.0
+	github.com/vingarcia/ksql v1.4.7
+)
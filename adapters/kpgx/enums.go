@@ -0,0 +1,60 @@
+package kpgx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// RegisterEnumTypes registers all the ENUM types of the database, and the
+// arrays of these types, on the connection, so pgx encodes and decodes them
+// natively, e.g. for scanning a `status[]` column into a []string.
+//
+// It is called automatically for the pools created by `kpgx.New()`, and
+// for the pools built with `kpgx.NewFromPgxPool()` it can be set as the
+// AfterConnect hook of the pool config, e.g.:
+//
+//	pgxConf.AfterConnect = kpgx.RegisterEnumTypes
+//
+// Note that the types created after the connection is opened are
+// only registered on the new connections.
+func RegisterEnumTypes(ctx context.Context, conn *pgx.Conn) error {
+	rows, err := conn.Query(ctx, `
+		SELECT t.oid, t.typarray, t.typname, array_agg(e.enumlabel::text ORDER BY e.enumsortorder)
+		FROM pg_type t
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		GROUP BY t.oid, t.typarray, t.typname
+	`)
+	if err != nil {
+		return fmt.Errorf("kpgx: error loading the enum types: %s", err)
+	}
+	defer rows.Close()
+
+	connInfo := conn.ConnInfo()
+	for rows.Next() {
+		var oid, arrayOID uint32
+		var name string
+		var members []string
+		err := rows.Scan(&oid, &arrayOID, &name, &members)
+		if err != nil {
+			return fmt.Errorf("kpgx: error loading the enum types: %s", err)
+		}
+
+		connInfo.RegisterDataType(pgtype.DataType{
+			Value: pgtype.NewEnumType(name, members),
+			Name:  name,
+			OID:   oid,
+		})
+		connInfo.RegisterDataType(pgtype.DataType{
+			Value: pgtype.NewArrayType("_"+name, oid, func() pgtype.ValueTranscoder {
+				return pgtype.NewEnumType(name, members)
+			}),
+			Name: "_" + name,
+			OID:  arrayOID,
+		})
+	}
+
+	return rows.Err()
+}
@@ -2,7 +2,11 @@ package kpgx
 
 import (
 	"context"
+	"strconv"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/vingarcia/ksql"
 
@@ -12,6 +16,11 @@ import (
 )
 
 // NewFromPgxPool builds a ksql.DB from a *pgxpool.Pool instance
+//
+// This is useful for sharing a pool that is also used for other
+// purposes, e.g. LISTEN/NOTIFY or advisory locks, instead of opening
+// a second pool against the same database. Note that calling Close()
+// on the returned ksql.DB also closes the pool.
 func NewFromPgxPool(pool *pgxpool.Pool) (db ksql.DB, err error) {
 	return ksql.NewWithAdapter(NewPGXAdapter(pool), "postgres")
 }
@@ -30,6 +39,40 @@ func New(
 	}
 
 	pgxConf.MaxConns = int32(config.MaxOpenConns)
+	if config.ConnMaxLifetime != 0 {
+		pgxConf.MaxConnLifetime = config.ConnMaxLifetime
+	}
+	if config.ConnMaxIdleTime != 0 {
+		pgxConf.MaxConnIdleTime = config.ConnMaxIdleTime
+	}
+	if config.StatementTimeout != 0 {
+		pgxConf.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+	if config.PreparedStatements {
+		// This is already the pgx default, but it might
+		// have been disabled on the connection string:
+		pgxConf.ConnConfig.PreferSimpleProtocol = false
+		if pgxConf.ConnConfig.BuildStatementCache == nil {
+			pgxConf.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+				return stmtcache.New(conn, stmtcache.ModePrepare, 512)
+			}
+		}
+	}
+
+	// The enum and hstore types are registered so they can be used natively
+	// by pgx, without overriding the hook set on the connection string:
+	afterConnect := pgxConf.AfterConnect
+	pgxConf.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		if afterConnect != nil {
+			if err := afterConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if err := RegisterEnumTypes(ctx, conn); err != nil {
+			return err
+		}
+		return RegisterHstoreType(ctx, conn)
+	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, pgxConf)
 	if err != nil {
@@ -39,6 +82,6 @@ func New(
 		return ksql.DB{}, err
 	}
 
-	db, err = ksql.NewWithAdapter(NewPGXAdapter(pool), "postgres")
+	db, err = ksql.NewWithConfig(NewPGXAdapter(pool), "postgres", config)
 	return db, err
 }
@@ -3,9 +3,13 @@ package kpgx
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/netip"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,6 +32,242 @@ func TestAdapter(t *testing.T) {
 	})
 }
 
+func TestConvertValuers(t *testing.T) {
+	var nilValuer *fakeValuer
+	params := []interface{}{42, fakeValuer{value: "fake-value"}, nilValuer}
+
+	converted, err := convertValuers(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []interface{}{42, "fake-value", nil}
+	if fmt.Sprint(converted) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, but got %v", expected, converted)
+	}
+
+	// The original slice should not be modified:
+	if _, ok := params[1].(fakeValuer); !ok {
+		t.Fatalf("expected the input params not to be modified, but got: %v", params)
+	}
+}
+
+func TestScanWithSQLScanners(t *testing.T) {
+	var name sql.NullString
+	var age int
+	err := scanWithSQLScanners(
+		func(args ...interface{}) error {
+			if args[0] != nil {
+				t.Fatalf("expected the sql.Scanner destination to be skipped, but got: %T", args[0])
+			}
+			*args[1].(*int) = 42
+			return nil
+		},
+		func() ([]interface{}, error) {
+			return []interface{}{"fake-name", int32(42)}, nil
+		},
+		[]interface{}{&name, &age},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if name != (sql.NullString{String: "fake-name", Valid: true}) {
+		t.Fatalf("unexpected value for the sql.Scanner destination: %v", name)
+	}
+	if age != 42 {
+		t.Fatalf("unexpected value for the int destination: %d", age)
+	}
+}
+
+func TestNetIPTypes(t *testing.T) {
+	t.Run("should convert the netip params to text", func(t *testing.T) {
+		var nilAddr *netip.Addr
+		params := []interface{}{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParsePrefix("10.0.0.0/8"),
+			nilAddr,
+			netip.Addr{},
+		}
+
+		converted, err := convertValuers(params)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		expected := []interface{}{"10.0.0.1", "10.0.0.0/8", nil, nil}
+		if fmt.Sprint(converted) != fmt.Sprint(expected) {
+			t.Fatalf("expected %v, but got %v", expected, converted)
+		}
+	})
+
+	t.Run("should scan inet and cidr values into netip types", func(t *testing.T) {
+		var addr netip.Addr
+		var prefix netip.Prefix
+		var nullableAddr *netip.Addr
+		var nullPrefix = &netip.Prefix{}
+		err := scanWithSQLScanners(
+			func(args ...interface{}) error {
+				for i, arg := range args {
+					if arg != nil {
+						t.Fatalf("expected the netip destination %d to be skipped, but got: %T", i, arg)
+					}
+				}
+				return nil
+			},
+			func() ([]interface{}, error) {
+				_, ipNet, _ := net.ParseCIDR("192.168.0.0/16")
+				return []interface{}{
+					&net.IPNet{IP: net.ParseIP("10.0.0.1").To4(), Mask: net.CIDRMask(32, 32)},
+					ipNet,
+					"::1",
+					nil,
+				}, nil
+			},
+			[]interface{}{&addr, &prefix, &nullableAddr, &nullPrefix},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if addr != netip.MustParseAddr("10.0.0.1") {
+			t.Fatalf("unexpected value for the netip.Addr destination: %v", addr)
+		}
+		if prefix != netip.MustParsePrefix("192.168.0.0/16") {
+			t.Fatalf("unexpected value for the netip.Prefix destination: %v", prefix)
+		}
+		if nullableAddr == nil || *nullableAddr != netip.MustParseAddr("::1") {
+			t.Fatalf("unexpected value for the *netip.Addr destination: %v", nullableAddr)
+		}
+		if nullPrefix != nil {
+			t.Fatalf("expected the *netip.Prefix destination to be nil, but got: %v", nullPrefix)
+		}
+	})
+
+	t.Run("should report invalid and NULL values", func(t *testing.T) {
+		var addr netip.Addr
+		err := netIPScanner(&addr).Scan("not an ip")
+		if err == nil || !strings.Contains(err.Error(), "not an ip") {
+			t.Fatalf("expected an error mentioning the invalid value, but got: %v", err)
+		}
+
+		err = netIPScanner(&addr).Scan(nil)
+		if err == nil || !strings.Contains(err.Error(), "NULL") {
+			t.Fatalf("expected an error mentioning NULL, but got: %v", err)
+		}
+	})
+}
+
+func TestRange(t *testing.T) {
+	t.Run("should scan the text format of the ranges", func(t *testing.T) {
+		jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		tests := []struct {
+			desc     string
+			input    interface{}
+			expected Range[time.Time]
+		}{
+			{
+				desc:     "tstzrange written by Postgres",
+				input:    []byte(`["2024-01-01 00:00:00+00","2024-02-01 00:00:00+00")`),
+				expected: NewRange(jan, feb),
+			},
+			{
+				desc:     "tstzrange written by pgx",
+				input:    "[2024-01-01 00:00:00Z,2024-02-01 00:00:00Z)",
+				expected: NewRange(jan, feb),
+			},
+			{
+				desc:     "daterange with an inclusive upper bound",
+				input:    "(2024-01-01,2024-02-01]",
+				expected: Range[time.Time]{Lower: jan, Upper: feb, UpperInclusive: true},
+			},
+			{
+				desc:     "unbounded ranges",
+				input:    `["2024-01-01 00:00:00+00",)`,
+				expected: Range[time.Time]{Lower: jan, LowerInclusive: true, UpperUnbounded: true},
+			},
+			{
+				desc:     "empty ranges",
+				input:    "empty",
+				expected: Range[time.Time]{Empty: true},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				var r Range[time.Time]
+				err := r.Scan(test.input)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !r.Lower.Equal(test.expected.Lower) || !r.Upper.Equal(test.expected.Upper) {
+					t.Fatalf("expected %v, but got %v", test.expected, r)
+				}
+				r.Lower, r.Upper = test.expected.Lower, test.expected.Upper
+				if r != test.expected {
+					t.Fatalf("expected %v, but got %v", test.expected, r)
+				}
+			})
+		}
+
+		var numbers Range[int64]
+		err := numbers.Scan("[1,10)")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if numbers != NewRange[int64](1, 10) {
+			t.Fatalf("unexpected value for the int8range: %v", numbers)
+		}
+	})
+
+	t.Run("should write the text format of the ranges", func(t *testing.T) {
+		jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		value, err := NewRange(jan, jan.AddDate(0, 1, 0)).Value()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if value != `["2024-01-01T00:00:00Z","2024-02-01T00:00:00Z")` {
+			t.Fatalf("unexpected value for the tstzrange: %v", value)
+		}
+
+		value, err = Range[float64]{Upper: 2.5, UpperInclusive: true, LowerUnbounded: true}.Value()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if value != "(,2.5]" {
+			t.Fatalf("unexpected value for the numrange: %v", value)
+		}
+	})
+
+	t.Run("should report NULL and invalid values", func(t *testing.T) {
+		var r Range[int32]
+		err := r.Scan(nil)
+		if err == nil || !strings.Contains(err.Error(), "NULL") {
+			t.Fatalf("expected an error mentioning NULL, but got: %v", err)
+		}
+
+		err = r.Scan("[1,a)")
+		if err == nil || !strings.Contains(err.Error(), "invalid integer 'a'") {
+			t.Fatalf("expected an error mentioning the invalid bound, but got: %v", err)
+		}
+
+		err = r.Scan("1,2")
+		if err == nil || !strings.Contains(err.Error(), "[lower,upper)") {
+			t.Fatalf("expected an error mentioning the range format, but got: %v", err)
+		}
+	})
+}
+
+type fakeValuer struct {
+	value string
+}
+
+func (f fakeValuer) Value() (driver.Value, error) {
+	return f.value, nil
+}
+
 type closerAdapter struct {
 	close func()
 }
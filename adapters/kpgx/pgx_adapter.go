@@ -3,6 +3,7 @@ package kpgx
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
@@ -26,12 +27,22 @@ var _ ksql.DBAdapter = PGXAdapter{}
 
 // ExecContext implements the DBAdapter interface
 func (p PGXAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	args, err := convertValuers(args)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := p.db.Exec(ctx, query, args...)
 	return PGXResult{result}, err
 }
 
 // QueryContext implements the DBAdapter interface
 func (p PGXAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	args, err := convertValuers(args)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := p.db.Query(ctx, query, args...)
 	return PGXRows{rows}, err
 }
@@ -42,12 +53,80 @@ func (p PGXAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
 	return PGXTx{tx}, err
 }
 
+var pgxIsolationLevels = map[ksql.IsolationLevel]pgx.TxIsoLevel{
+	ksql.DefaultIsolation: "",
+	ksql.ReadUncommitted:  pgx.ReadUncommitted,
+	ksql.ReadCommitted:    pgx.ReadCommitted,
+	ksql.RepeatableRead:   pgx.RepeatableRead,
+	ksql.Serializable:     pgx.Serializable,
+}
+
+// BeginTxWithOptions implements the ksql.TxOptionsBeginner interface
+func (p PGXAdapter) BeginTxWithOptions(ctx context.Context, opts ksql.TxOptions) (ksql.Tx, error) {
+	isoLevel, ok := pgxIsolationLevels[opts.Isolation]
+	if !ok {
+		return nil, fmt.Errorf("kpgx: unsupported isolation level: %s", opts.Isolation)
+	}
+
+	pgxOpts := pgx.TxOptions{
+		IsoLevel: isoLevel,
+	}
+	if opts.ReadOnly {
+		pgxOpts.AccessMode = pgx.ReadOnly
+	}
+	if opts.Deferrable {
+		pgxOpts.DeferrableMode = pgx.Deferrable
+	}
+
+	tx, err := p.db.BeginTx(ctx, pgxOpts)
+	return PGXTx{tx}, err
+}
+
 // Close implements the io.Closer interface
 func (p PGXAdapter) Close() error {
 	p.db.Close()
 	return nil
 }
 
+// PoolStats implements the ksql.PoolStatsReporter interface
+func (p PGXAdapter) PoolStats() ksql.PoolStats {
+	stat := p.db.Stat()
+	return ksql.PoolStats{
+		MaxOpenConnections: int(stat.MaxConns()),
+		OpenConnections:    int(stat.TotalConns()),
+		InUse:              int(stat.AcquiredConns()),
+		Idle:               int(stat.IdleConns()),
+		WaitCount:          stat.EmptyAcquireCount(),
+		WaitDuration:       stat.AcquireDuration(),
+	}
+}
+
+// CopyFrom implements the ksql.BulkCopier interface
+// using the `COPY FROM` protocol of Postgres
+func (p PGXAdapter) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	rows, err := convertRowValuers(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.db.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+}
+
+var _ ksql.BulkCopier = PGXAdapter{}
+
+// convertRowValuers calls convertValuers for each row
+func convertRowValuers(rows [][]interface{}) ([][]interface{}, error) {
+	converted := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		var err error
+		converted[i], err = convertValuers(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
+
 // PGXResult is used to implement the DBAdapter interface and implements
 // the Result interface
 type PGXResult struct {
@@ -74,12 +153,22 @@ type PGXTx struct {
 
 // ExecContext implements the Tx interface
 func (p PGXTx) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	args, err := convertValuers(args)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := p.tx.Exec(ctx, query, args...)
 	return PGXResult{result}, err
 }
 
 // QueryContext implements the Tx interface
 func (p PGXTx) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	args, err := convertValuers(args)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := p.tx.Query(ctx, query, args...)
 	return PGXRows{rows}, err
 }
@@ -94,6 +183,17 @@ func (p PGXTx) Commit(ctx context.Context) error {
 	return p.tx.Commit(ctx)
 }
 
+// CopyFrom implements the ksql.BulkCopier interface
+// using the `COPY FROM` protocol of Postgres
+func (p PGXTx) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	rows, err := convertRowValuers(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.tx.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+}
+
 var _ ksql.Tx = PGXTx{}
 
 // PGXRows implements the Rows interface and is used to help
@@ -113,6 +213,13 @@ func (p PGXRows) Columns() ([]string, error) {
 	return names, nil
 }
 
+// Scan implements the Rows interface, making sure the destinations
+// implementing sql.Scanner receive the same values they would
+// receive from the database/sql package
+func (p PGXRows) Scan(args ...interface{}) error {
+	return scanWithSQLScanners(p.Rows.Scan, p.Rows.Values, args)
+}
+
 // Close implements the Rows interface
 func (p PGXRows) Close() error {
 	p.Rows.Close()
@@ -0,0 +1,246 @@
+package kpgx
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RangeBound lists the types of the bounds supported by Range:
+//
+//   - time.Time for tstzrange, tsrange and daterange columns
+//   - int32 for int4range columns
+//   - int64 for int8range columns
+//   - float64 for numrange columns
+type RangeBound interface {
+	time.Time | int32 | int64 | float64
+}
+
+// Range represents a Postgres range, e.g. a tstzrange column can be
+// read into a kpgx.Range[time.Time] attribute.
+//
+// It is written and read using the text format of the ranges, e.g.
+// `[2024-01-01 00:00:00+00,2024-02-01 00:00:00+00)`, so it also
+// works with the database/sql adapters.
+//
+// Use *kpgx.Range attributes for nullable columns,
+// since scanning NULL into a Range is an error.
+type Range[T RangeBound] struct {
+	Lower T
+	Upper T
+
+	// LowerInclusive and UpperInclusive tell if the bounds are part of
+	// the range, by default the range includes neither of them, e.g. `(1,5)`
+	LowerInclusive bool
+	UpperInclusive bool
+
+	// LowerUnbounded and UpperUnbounded tell the range has no lower or
+	// upper limit, in which case the Lower or Upper values are ignored
+	LowerUnbounded bool
+	UpperUnbounded bool
+
+	// Empty tells the range contains no values,
+	// in which case all the other fields are ignored
+	Empty bool
+}
+
+// NewRange returns the range `[lower,upper)`, which is
+// how Postgres normalizes the discrete ranges.
+func NewRange[T RangeBound](lower T, upper T) Range[T] {
+	return Range[T]{
+		Lower:          lower,
+		Upper:          upper,
+		LowerInclusive: true,
+	}
+}
+
+// Scan implements the sql.Scanner interface
+func (r *Range[T]) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return fmt.Errorf("kpgx: can't scan NULL into a %T, use a pointer instead", *r)
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("kpgx: can't scan value of type %T into a %T", value, *r)
+	}
+
+	parsed, err := parseRange[T](str)
+	if err != nil {
+		return fmt.Errorf("kpgx: can't scan '%s' into a %T: %s", str, *r, err)
+	}
+	*r = parsed
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (r Range[T]) Value() (driver.Value, error) {
+	if r.Empty {
+		return "empty", nil
+	}
+
+	var b strings.Builder
+	if r.LowerInclusive && !r.LowerUnbounded {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if !r.LowerUnbounded {
+		b.WriteString(formatRangeBound(r.Lower))
+	}
+	b.WriteByte(',')
+	if !r.UpperUnbounded {
+		b.WriteString(formatRangeBound(r.Upper))
+	}
+	if r.UpperInclusive && !r.UpperUnbounded {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+func formatRangeBound(bound interface{}) string {
+	switch v := bound.(type) {
+	case time.Time:
+		return `"` + v.Format(time.RFC3339Nano) + `"`
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func parseRange[T RangeBound](str string) (Range[T], error) {
+	var r Range[T]
+	str = strings.TrimSpace(str)
+	if strings.EqualFold(str, "empty") {
+		r.Empty = true
+		return r, nil
+	}
+
+	if len(str) < 3 || strings.IndexByte("[(", str[0]) == -1 || strings.IndexByte("])", str[len(str)-1]) == -1 {
+		return r, fmt.Errorf("expected a range like `[lower,upper)`")
+	}
+	r.LowerInclusive = str[0] == '['
+	r.UpperInclusive = str[len(str)-1] == ']'
+
+	lower, rest, err := readRangeBound(str[1 : len(str)-1])
+	if err != nil {
+		return r, err
+	}
+	if !strings.HasPrefix(rest, ",") {
+		return r, fmt.Errorf("expected ',' after the lower bound")
+	}
+	upper, rest, err := readRangeBound(rest[1:])
+	if err != nil {
+		return r, err
+	}
+	if rest != "" {
+		return r, fmt.Errorf("unexpected '%s' after the upper bound", rest)
+	}
+
+	r.LowerUnbounded = lower == nil
+	if lower != nil {
+		err = parseRangeBound(*lower, &r.Lower)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	r.UpperUnbounded = upper == nil
+	if upper != nil {
+		err = parseRangeBound(*upper, &r.Upper)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	return r, nil
+}
+
+// readRangeBound reads a bound that might be quoted, and might
+// contain escaped characters, it returns nil for missing bounds,
+// which is how Postgres writes the unbounded sides of the ranges.
+func readRangeBound(str string) (bound *string, rest string, err error) {
+	var b strings.Builder
+	quoted := false
+	i := 0
+	for ; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case c == '\\' && i+1 < len(str):
+			i++
+			b.WriteByte(str[i])
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ')' || c == ']'):
+			return finishRangeBound(b.String(), i > 0), str[i:], nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, "", fmt.Errorf("unterminated quoted bound")
+	}
+	return finishRangeBound(b.String(), i > 0), "", nil
+}
+
+func finishRangeBound(bound string, present bool) *string {
+	if !present {
+		return nil
+	}
+	return &bound
+}
+
+// These are the formats used by Postgres and by pgx
+// for writing the bounds of the time ranges:
+var rangeTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+func parseRangeBound(str string, dest interface{}) error {
+	switch d := dest.(type) {
+	case *time.Time:
+		for _, layout := range rangeTimeLayouts {
+			t, err := time.Parse(layout, str)
+			if err == nil {
+				*d = t
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time '%s'", str)
+	case *int32:
+		i, err := strconv.ParseInt(str, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid integer '%s'", str)
+		}
+		*d = int32(i)
+	case *int64:
+		i, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer '%s'", str)
+		}
+		*d = i
+	case *float64:
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number '%s'", str)
+		}
+		*d = f
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package kpgx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Since pgx doesn't use the database/sql package, the types implementing
+// the `sql.Scanner` and `driver.Valuer` interfaces are not always handled
+// the same way they would be by the sql adapters, e.g. a Scanner might
+// receive the binary encoding of a value instead of its text representation.
+//
+// The functions below make sure these types receive and produce the
+// same values they would when using the database/sql package, so
+// that the same structs work with both kinds of adapters.
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// convertValuers replaces all the params implementing the driver.Valuer
+// interface by the values they return, as the database/sql package would,
+// and the netip params by their text representation.
+func convertValuers(params []interface{}) ([]interface{}, error) {
+	var converted []interface{}
+	for i, param := range params {
+		value, ok := netIPParam(param)
+		if !ok {
+			valuer, isValuer := param.(driver.Valuer)
+			if !isValuer || isHandledByPgx(param) {
+				continue
+			}
+
+			var err error
+			value, err = callValuer(valuer)
+			if err != nil {
+				return nil, fmt.Errorf("kpgx: error calling Value() on param %d of type %T: %w", i+1, param, err)
+			}
+		}
+
+		if converted == nil {
+			// Copying so we don't modify the slice received from the caller:
+			converted = append([]interface{}{}, params...)
+		}
+		converted[i] = value
+	}
+
+	if converted == nil {
+		return params, nil
+	}
+	return converted, nil
+}
+
+// callValuer calls the Value() method unless it is a nil pointer
+// to a type implementing driver.Valuer with a value receiver,
+// which would panic, in this case it returns nil instead.
+func callValuer(valuer driver.Valuer) (driver.Value, error) {
+	v := reflect.ValueOf(valuer)
+	if v.Kind() == reflect.Ptr && v.IsNil() && v.Type().Elem().Implements(valuerType) {
+		return nil, nil
+	}
+
+	return valuer.Value()
+}
+
+// scanWithSQLScanners scans the current row using the input scanFn, but
+// replaces all the destinations implementing the sql.Scanner interface
+// so they receive the same values the database/sql package would send them.
+//
+// The netip destinations are handled the same way, since pgx can't decode
+// inet and cidr values into them.
+func scanWithSQLScanners(
+	scanFn func(args ...interface{}) error,
+	valuesFn func() ([]interface{}, error),
+	args []interface{},
+) error {
+	var scanners map[int]sql.Scanner
+	for i, arg := range args {
+		scanner := netIPScanner(arg)
+		if scanner == nil {
+			var ok bool
+			scanner, ok = arg.(sql.Scanner)
+			if !ok || isHandledByPgx(arg) {
+				continue
+			}
+		}
+
+		if scanners == nil {
+			scanners = map[int]sql.Scanner{}
+
+			// Copying so we don't modify the slice received from the caller:
+			args = append([]interface{}{}, args...)
+		}
+		scanners[i] = scanner
+
+		// pgx ignores nil destinations:
+		args[i] = nil
+	}
+
+	err := scanFn(args...)
+	if err != nil || scanners == nil {
+		return err
+	}
+
+	values, err := valuesFn()
+	if err != nil {
+		return err
+	}
+
+	for i, scanner := range scanners {
+		value, err := toDriverValue(values[i])
+		if err != nil {
+			return fmt.Errorf("kpgx: error scanning column %d into %T: %w", i+1, scanner, err)
+		}
+
+		err = scanner.Scan(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isHandledByPgx checks if the type belongs to pgx itself or to ksql,
+// since these types are already prepared for working with pgx.
+func isHandledByPgx(value interface{}) bool {
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return strings.HasPrefix(t.PkgPath(), "github.com/jackc/") ||
+		t.PkgPath() == "github.com/vingarcia/ksql"
+}
+
+// toDriverValue converts the values decoded by pgx into one of the
+// types the database/sql package sends to the sql.Scanner interface,
+// i.e. int64, float64, bool, []byte, string, time.Time or nil.
+func toDriverValue(value interface{}) (driver.Value, error) {
+	switch v := value.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case [16]byte:
+		// UUIDs are decoded as arrays by pgx:
+		return v[:], nil
+	case *net.IPNet:
+		// inet and cidr columns:
+		return v.String(), nil
+	case driver.Valuer:
+		// The pgtype types, e.g. pgtype.Numeric, return their text representation:
+		return callValuer(v)
+	case map[string]interface{}, []interface{}:
+		// JSON columns are decoded by pgx, so we encode them back:
+		return json.Marshal(v)
+	}
+
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
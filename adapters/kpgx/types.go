@@ -0,0 +1,157 @@
+package kpgx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// Besides the types supported natively by pgx, the kpgx adapter
+// maps a few of the Postgres extension types to Go types:
+//
+//   - hstore columns to map[string]string attributes, once the
+//     hstore type is registered with RegisterHstoreType()
+//   - inet and cidr columns to netip.Addr and netip.Prefix attributes
+//   - interval columns to time.Duration attributes, where months and
+//     days are converted assuming 30 days months and 24 hours days
+//   - range columns, e.g. tstzrange, to the kpgx.Range type
+//
+// Pointers to these types can be used for nullable columns.
+
+// RegisterHstoreType registers the hstore type on the connection, so pgx
+// can encode and decode it as a map[string]string, it does nothing if
+// the hstore extension is not installed on the database.
+//
+// It is called automatically for the pools created by `kpgx.New()`, and
+// for the pools built with `kpgx.NewFromPgxPool()` it can be called on
+// the AfterConnect hook of the pool config, e.g.:
+//
+//	pgxConf.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+//		return kpgx.RegisterHstoreType(ctx, conn)
+//	}
+func RegisterHstoreType(ctx context.Context, conn *pgx.Conn) error {
+	var oid uint32
+	err := conn.QueryRow(ctx, `SELECT oid FROM pg_type WHERE typname = 'hstore'`).Scan(&oid)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("kpgx: error loading the hstore type: %s", err)
+	}
+
+	conn.ConnInfo().RegisterDataType(pgtype.DataType{
+		Value: &pgtype.Hstore{},
+		Name:  "hstore",
+		OID:   oid,
+	})
+	return nil
+}
+
+// netIPParam converts the netip types, which pgx doesn't support,
+// to their text representation, which is accepted by Postgres
+// for both inet and cidr columns.
+func netIPParam(param interface{}) (value interface{}, ok bool) {
+	switch v := param.(type) {
+	case netip.Addr:
+		if !v.IsValid() {
+			return nil, true
+		}
+		return v.String(), true
+	case *netip.Addr:
+		if v == nil {
+			return nil, true
+		}
+		return netIPParam(*v)
+	case netip.Prefix:
+		if !v.IsValid() {
+			return nil, true
+		}
+		return v.String(), true
+	case *netip.Prefix:
+		if v == nil {
+			return nil, true
+		}
+		return netIPParam(*v)
+	}
+
+	return nil, false
+}
+
+// netIPScanner returns an sql.Scanner for the destinations
+// of the netip types or nil for the other destinations.
+func netIPScanner(arg interface{}) sql.Scanner {
+	switch dest := arg.(type) {
+	case *netip.Addr, **netip.Addr, *netip.Prefix, **netip.Prefix:
+		return netIPDest{dest: dest}
+	}
+	return nil
+}
+
+// netIPDest scans inet and cidr values into netip types, the values
+// might be written with or without the prefix length, e.g. `10.0.0.1`
+// or `10.0.0.1/8`, so both forms are accepted by both types.
+type netIPDest struct {
+	dest interface{}
+}
+
+// Scan implements the sql.Scanner interface
+func (n netIPDest) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return n.scanNull()
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("kpgx: can't scan value of type %T into %T", value, n.dest)
+	}
+
+	prefix, err := parsePrefix(str)
+	if err != nil {
+		return fmt.Errorf("kpgx: can't scan '%s' into %T: %s", str, n.dest, err)
+	}
+
+	switch dest := n.dest.(type) {
+	case *netip.Addr:
+		*dest = prefix.Addr()
+	case **netip.Addr:
+		addr := prefix.Addr()
+		*dest = &addr
+	case *netip.Prefix:
+		*dest = prefix
+	case **netip.Prefix:
+		*dest = &prefix
+	}
+	return nil
+}
+
+func (n netIPDest) scanNull() error {
+	switch dest := n.dest.(type) {
+	case **netip.Addr:
+		*dest = nil
+	case **netip.Prefix:
+		*dest = nil
+	default:
+		return fmt.Errorf("kpgx: can't scan NULL into %T, use a pointer instead", n.dest)
+	}
+	return nil
+}
+
+func parsePrefix(str string) (netip.Prefix, error) {
+	if strings.Contains(str, "/") {
+		return netip.ParsePrefix(str)
+	}
+
+	addr, err := netip.ParseAddr(str)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,112 @@
+package kpgx5
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vingarcia/ksql"
+)
+
+// PoolConfig contains the options of the pgxpool.Pool
+// that are not available on the ksql.Config struct.
+//
+// All the attributes are optional and if unset
+// the pgxpool defaults are used instead, when set they take
+// precedence over the equivalent options of the ksql.Config.
+type PoolConfig struct {
+	// MinConns is the minimum number of connections kept open by the pool
+	MinConns int32
+
+	// MaxConnLifetime is the duration after which a connection is closed
+	MaxConnLifetime time.Duration
+
+	// MaxConnIdleTime is the duration after which an idle connection is closed
+	MaxConnIdleTime time.Duration
+
+	// HealthCheckPeriod is the interval between the health checks of the idle connections
+	HealthCheckPeriod time.Duration
+}
+
+// NewFromPgxPool builds a ksql.DB from a *pgxpool.Pool instance
+//
+// This is useful for sharing a pool that is also used for other
+// purposes, e.g. LISTEN/NOTIFY or advisory locks, instead of opening
+// a second pool against the same database. Note that calling Close()
+// on the returned ksql.DB also closes the pool.
+func NewFromPgxPool(pool *pgxpool.Pool) (db ksql.DB, err error) {
+	return ksql.NewWithAdapter(NewPGXAdapter(pool), "postgres")
+}
+
+// New instantiates a new ksql.Client using pgx v5 as the backend driver
+func New(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+) (db ksql.DB, err error) {
+	return NewWithPoolConfig(ctx, connectionString, config, PoolConfig{})
+}
+
+// NewWithPoolConfig works like New but also allows the user
+// to configure the options that are specific to pgxpool
+func NewWithPoolConfig(
+	ctx context.Context,
+	connectionString string,
+	config ksql.Config,
+	poolConfig PoolConfig,
+) (db ksql.DB, err error) {
+	config.SetDefaultValues()
+
+	pgxConf, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+
+	pgxConf.MaxConns = int32(config.MaxOpenConns)
+	if config.ConnMaxLifetime != 0 {
+		pgxConf.MaxConnLifetime = config.ConnMaxLifetime
+	}
+	if config.ConnMaxIdleTime != 0 {
+		pgxConf.MaxConnIdleTime = config.ConnMaxIdleTime
+	}
+	if config.StatementTimeout != 0 {
+		pgxConf.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+	if config.TLSConfig != nil {
+		pgxConf.ConnConfig.TLSConfig = config.TLSConfig
+	}
+	if config.PreparedStatements {
+		// This is already the pgx default, but it might
+		// have been disabled on the connection string:
+		pgxConf.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		if pgxConf.ConnConfig.StatementCacheCapacity == 0 {
+			pgxConf.ConnConfig.StatementCacheCapacity = 512
+		}
+	}
+
+	if poolConfig.MinConns != 0 {
+		pgxConf.MinConns = poolConfig.MinConns
+	}
+	if poolConfig.MaxConnLifetime != 0 {
+		pgxConf.MaxConnLifetime = poolConfig.MaxConnLifetime
+	}
+	if poolConfig.MaxConnIdleTime != 0 {
+		pgxConf.MaxConnIdleTime = poolConfig.MaxConnIdleTime
+	}
+	if poolConfig.HealthCheckPeriod != 0 {
+		pgxConf.HealthCheckPeriod = poolConfig.HealthCheckPeriod
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgxConf)
+	if err != nil {
+		return ksql.DB{}, err
+	}
+	if err = pool.Ping(ctx); err != nil {
+		return ksql.DB{}, err
+	}
+
+	db, err = ksql.NewWithConfig(NewPGXAdapter(pool), "postgres", config)
+	return db, err
+}
@@ -0,0 +1,177 @@
+package kpgx5
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vingarcia/ksql"
+)
+
+// PGXAdapter adapts the pgxpool.Pool type to be compatible with the `DBAdapter` interface
+type PGXAdapter struct {
+	db *pgxpool.Pool
+}
+
+// NewPGXAdapter instantiates a new pgx adapter
+func NewPGXAdapter(db *pgxpool.Pool) PGXAdapter {
+	return PGXAdapter{
+		db: db,
+	}
+}
+
+var _ ksql.DBAdapter = PGXAdapter{}
+
+// ExecContext implements the DBAdapter interface
+func (p PGXAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	result, err := p.db.Exec(ctx, query, args...)
+	return PGXResult{result}, err
+}
+
+// QueryContext implements the DBAdapter interface
+func (p PGXAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	rows, err := p.db.Query(ctx, query, args...)
+	return PGXRows{rows}, err
+}
+
+// BeginTx implements the Tx interface
+func (p PGXAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
+	tx, err := p.db.Begin(ctx)
+	return PGXTx{tx}, err
+}
+
+var pgxIsolationLevels = map[ksql.IsolationLevel]pgx.TxIsoLevel{
+	ksql.DefaultIsolation: "",
+	ksql.ReadUncommitted:  pgx.ReadUncommitted,
+	ksql.ReadCommitted:    pgx.ReadCommitted,
+	ksql.RepeatableRead:   pgx.RepeatableRead,
+	ksql.Serializable:     pgx.Serializable,
+}
+
+// BeginTxWithOptions implements the ksql.TxOptionsBeginner interface
+func (p PGXAdapter) BeginTxWithOptions(ctx context.Context, opts ksql.TxOptions) (ksql.Tx, error) {
+	isoLevel, ok := pgxIsolationLevels[opts.Isolation]
+	if !ok {
+		return nil, fmt.Errorf("kpgx5: unsupported isolation level: %s", opts.Isolation)
+	}
+
+	pgxOpts := pgx.TxOptions{
+		IsoLevel: isoLevel,
+	}
+	if opts.ReadOnly {
+		pgxOpts.AccessMode = pgx.ReadOnly
+	}
+	if opts.Deferrable {
+		pgxOpts.DeferrableMode = pgx.Deferrable
+	}
+
+	tx, err := p.db.BeginTx(ctx, pgxOpts)
+	return PGXTx{tx}, err
+}
+
+// Close implements the io.Closer interface
+func (p PGXAdapter) Close() error {
+	p.db.Close()
+	return nil
+}
+
+// PoolStats implements the ksql.PoolStatsReporter interface
+func (p PGXAdapter) PoolStats() ksql.PoolStats {
+	stat := p.db.Stat()
+	return ksql.PoolStats{
+		MaxOpenConnections: int(stat.MaxConns()),
+		OpenConnections:    int(stat.TotalConns()),
+		InUse:              int(stat.AcquiredConns()),
+		Idle:               int(stat.IdleConns()),
+		WaitCount:          stat.EmptyAcquireCount(),
+		WaitDuration:       stat.AcquireDuration(),
+	}
+}
+
+// CopyFrom implements the ksql.BulkCopier interface
+// using the `COPY FROM` protocol of Postgres
+func (p PGXAdapter) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return p.db.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+}
+
+var _ ksql.BulkCopier = PGXAdapter{}
+
+// PGXResult is used to implement the DBAdapter interface and implements
+// the Result interface
+type PGXResult struct {
+	tag pgconn.CommandTag
+}
+
+// RowsAffected implements the Result interface
+func (p PGXResult) RowsAffected() (int64, error) {
+	return p.tag.RowsAffected(), nil
+}
+
+// LastInsertId implements the Result interface
+func (p PGXResult) LastInsertId() (int64, error) {
+	return 0, fmt.Errorf(
+		"LastInsertId is not implemented in the pgx adapter, use the `RETURNING` statement instead",
+	)
+}
+
+// PGXTx is used to implement the DBAdapter interface and implements
+// the Tx interface
+type PGXTx struct {
+	tx pgx.Tx
+}
+
+// ExecContext implements the Tx interface
+func (p PGXTx) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	result, err := p.tx.Exec(ctx, query, args...)
+	return PGXResult{result}, err
+}
+
+// QueryContext implements the Tx interface
+func (p PGXTx) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	rows, err := p.tx.Query(ctx, query, args...)
+	return PGXRows{rows}, err
+}
+
+// Rollback implements the Tx interface
+func (p PGXTx) Rollback(ctx context.Context) error {
+	return p.tx.Rollback(ctx)
+}
+
+// Commit implements the Tx interface
+func (p PGXTx) Commit(ctx context.Context) error {
+	return p.tx.Commit(ctx)
+}
+
+// CopyFrom implements the ksql.BulkCopier interface
+// using the `COPY FROM` protocol of Postgres
+func (p PGXTx) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return p.tx.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+}
+
+var _ ksql.Tx = PGXTx{}
+
+// PGXRows implements the Rows interface and is used to help
+// the PGXAdapter to implement the DBAdapter interface.
+type PGXRows struct {
+	pgx.Rows
+}
+
+var _ ksql.Rows = PGXRows{}
+
+// Columns implements the Rows interface
+func (p PGXRows) Columns() ([]string, error) {
+	var names []string
+	for _, desc := range p.Rows.FieldDescriptions() {
+		names = append(names, desc.Name)
+	}
+	return names, nil
+}
+
+// Close implements the Rows interface
+func (p PGXRows) Close() error {
+	p.Rows.Close()
+	return nil
+}
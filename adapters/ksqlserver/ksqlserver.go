@@ -33,6 +33,15 @@ func New(
 	}
 
 	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
 
-	return ksql.NewWithAdapter(NewSQLAdapter(db), "sqlserver")
+	return ksql.NewWithConfig(NewSQLAdapter(db), "sqlserver", config)
 }
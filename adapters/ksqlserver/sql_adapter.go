@@ -3,7 +3,10 @@ package ksqlserver
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
 
+	mssql "github.com/denisenkom/go-mssqldb"
 	"github.com/vingarcia/ksql"
 )
 
@@ -38,11 +41,42 @@ func (s SQLAdapter) BeginTx(ctx context.Context) (ksql.Tx, error) {
 	return SQLTx{Tx: tx}, err
 }
 
+// BeginTxWithOptions implements the ksql.TxOptionsBeginner interface
+func (s SQLAdapter) BeginTxWithOptions(ctx context.Context, opts ksql.TxOptions) (ksql.Tx, error) {
+	sqlOpts, err := opts.SQLTxOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, sqlOpts)
+	return SQLTx{Tx: tx}, err
+}
+
 // Close implements the io.Closer interface
 func (s SQLAdapter) Close() error {
 	return s.DB.Close()
 }
 
+// CopyFrom implements the ksql.BulkCopier interface using the bulk copy
+// of go-mssqldb, which needs a single connection, so the rows are copied
+// inside a transaction.
+func (s SQLAdapter) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := copyIn(ctx, tx, table, columns, rows)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+var _ ksql.BulkCopier = SQLAdapter{}
+
 // SQLTx is used to implement the DBAdapter interface and implements
 // the Tx interface
 type SQLTx struct {
@@ -69,4 +103,60 @@ func (s SQLTx) Commit(ctx context.Context) error {
 	return s.Tx.Commit()
 }
 
+// CopyFrom implements the ksql.BulkCopier interface
+// using the bulk copy of go-mssqldb
+func (s SQLTx) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return copyIn(ctx, s.Tx, table, columns, rows)
+}
+
 var _ ksql.Tx = SQLTx{}
+
+// copyIn sends the rows to SQL Server using the bulk copy statement
+// of go-mssqldb, where each row is buffered by one call to Exec and
+// the final call to Exec without arguments flushes all of them.
+func copyIn(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) (int64, error) {
+	stmt, err := tx.PrepareContext(ctx, mssql.CopyIn(table, mssql.BulkOptions{}, columns...))
+	if err != nil {
+		return 0, fmt.Errorf("ksqlserver: unable to prepare bulk copy for table '%s': %s", table, err)
+	}
+	defer stmt.Close()
+
+	for i, row := range rows {
+		values, err := convertValuers(row)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = stmt.ExecContext(ctx, values...)
+		if err != nil {
+			return 0, fmt.Errorf("ksqlserver: unable to copy the row at index %d: %s", i, err)
+		}
+	}
+
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// convertValuers is necessary because the bulk copy of
+// go-mssqldb only supports the basic types of values.
+func convertValuers(row []interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		valuer, ok := value.(driver.Valuer)
+		if !ok {
+			values[i] = value
+			continue
+		}
+
+		var err error
+		values[i], err = valuer.Value()
+		if err != nil {
+			return nil, fmt.Errorf("ksqlserver: error calling Value() on value %d of type %T: %s", i+1, value, err)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,199 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// Count returns the number of rows of the table that match the
+// input query, which should contain only the clauses that come after
+// the `FROM <table>` part of the query, e.g.:
+//
+//	count, err := db.Count(ctx, UsersTable, "WHERE age > ?", 42)
+//
+// The query can also be empty for counting all rows of the table.
+//
+// If a struct with the `softDelete` modifier was registered on the
+// table with `Table.WithStruct()` the soft deleted rows are not
+// counted unless the context is created with ksql.Unscoped, and
+// if it has the `tenant` modifier only the rows of the tenant
+// saved on the context by `ksql.WithTenant()` are counted.
+func (c DB) Count(
+	ctx context.Context,
+	table Table,
+	query string,
+	params ...interface{},
+) (count int64, err error) {
+	op := Operation{Method: "Count", Table: table.name, Query: query, Params: params}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.aggregate(ctx, &count, table, "COUNT(*)", op.Query, op.Params...)
+	})
+	return count, err
+}
+
+// Exists checks if the table has at least one row matching the input
+// query, which follows the same rules of the query used by Count, e.g.:
+//
+//	exists, err := db.Exists(ctx, UsersTable, "WHERE email = ?", email)
+func (c DB) Exists(
+	ctx context.Context,
+	table Table,
+	query string,
+	params ...interface{},
+) (exists bool, err error) {
+	op := Operation{Method: "Exists", Table: table.name, Query: query, Params: params}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		exists, err = c.exists(ctx, table, op.Query, op.Params...)
+		return err
+	})
+	return exists, err
+}
+
+// Sum saves on the result argument the sum of the input column for the
+// rows of the table that match the input query, e.g.:
+//
+//	var total int
+//	err := db.Sum(ctx, &total, OrdersTable, "amount", "WHERE user_id = ?", userID)
+//
+// The result must be a pointer to a scalar value, e.g. *int or *float64,
+// and it is set to zero if no rows match the query.
+func (c DB) Sum(
+	ctx context.Context,
+	result interface{},
+	table Table,
+	column string,
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "Sum", Table: table.name, Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.aggregate(ctx, result, table, "COALESCE(SUM("+c.dialect.Escape(column)+"), 0)", op.Query, op.Params...)
+	})
+}
+
+// Min saves on the result argument the smallest value of the input column
+// for the rows of the table that match the input query, e.g.:
+//
+//	var oldest *time.Time
+//	err := db.Min(ctx, &oldest, UsersTable, "created_at", "")
+//
+// The result must be a pointer to a scalar value, and since the minimum
+// of an empty set is NULL the result should be a pointer to a nullable
+// type, e.g. **time.Time or *sql.NullTime, if no rows might match the query.
+func (c DB) Min(
+	ctx context.Context,
+	result interface{},
+	table Table,
+	column string,
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "Min", Table: table.name, Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.aggregate(ctx, result, table, "MIN("+c.dialect.Escape(column)+")", op.Query, op.Params...)
+	})
+}
+
+// Max works like Min but saves the largest value of the input column
+func (c DB) Max(
+	ctx context.Context,
+	result interface{},
+	table Table,
+	column string,
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "Max", Table: table.name, Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.aggregate(ctx, result, table, "MAX("+c.dialect.Escape(column)+")", op.Query, op.Params...)
+	})
+}
+
+func (c DB) aggregate(
+	ctx context.Context,
+	result interface{},
+	table Table,
+	expression string,
+	query string,
+	params ...interface{},
+) error {
+	query, params, err := buildTableQuery(ctx, c.dialect, c.naming, "SELECT "+expression, table, query, params)
+	if err != nil {
+		return err
+	}
+
+	return c.queryOne(ctx, result, query, params...)
+}
+
+func (c DB) exists(
+	ctx context.Context,
+	table Table,
+	query string,
+	params ...interface{},
+) (bool, error) {
+	selectPart := "SELECT 1"
+	if c.dialect.DriverName() == "sqlserver" {
+		selectPart = "SELECT TOP 1 1"
+	}
+
+	query, params, err := buildTableQuery(ctx, c.dialect, c.naming, selectPart, table, query, params)
+	if err != nil {
+		return false, err
+	}
+
+	switch c.dialect.DriverName() {
+	case "sqlserver":
+	case "oracle":
+		query += " FETCH FIRST 1 ROWS ONLY"
+	default:
+		query += " LIMIT 1"
+	}
+
+	var found int
+	err = c.queryOne(ctx, &found, query, params...)
+	if errors.Is(err, ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// buildTableQuery builds a query of the form `<selectPart> FROM <table> <query>`
+// and adds the soft delete and the tenant filters if the table has a registered struct.
+func buildTableQuery(
+	ctx context.Context,
+	dialect Dialect,
+	naming *structs.Naming,
+	selectPart string,
+	table Table,
+	query string,
+	params []interface{},
+) (string, []interface{}, error) {
+	if err := table.validate(); err != nil {
+		return "", nil, fmt.Errorf("can't query ksql.Table: %s", err)
+	}
+
+	query = strings.TrimSpace(selectPart + " FROM " + dialect.Escape(table.name) + " " + query)
+	if table.structType == nil {
+		return query, params, nil
+	}
+
+	info, err := naming.GetTagInfo(table.structType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, err = addSoftDeleteFilter(ctx, dialect, query, table.structType, info)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return addTenantFilter(ctx, dialect, query, params, table.structType, info)
+}
@@ -0,0 +1,184 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type fakeScalarRows struct {
+	Rows
+
+	values []interface{}
+}
+
+func (f *fakeScalarRows) Next() bool {
+	return len(f.values) > 0
+}
+
+func (f *fakeScalarRows) Scan(args ...interface{}) error {
+	reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(f.values[0]))
+	f.values = f.values[1:]
+	return nil
+}
+
+func (f *fakeScalarRows) Columns() ([]string, error) {
+	return []string{"value"}, nil
+}
+
+func (f *fakeScalarRows) Err() error {
+	return nil
+}
+
+func (f *fakeScalarRows) Close() error {
+	return nil
+}
+
+func TestAggregates(t *testing.T) {
+	ctx := context.Background()
+
+	newDB := func(t *testing.T, driver string, queries *[]string, values ...interface{}) DB {
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				*queries = append(*queries, query)
+				return &fakeScalarRows{values: values}, nil
+			},
+		}, driver)
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("Count", func(t *testing.T) {
+		t.Run("should count the rows matching the query", func(t *testing.T) {
+			var queries []string
+			db := newDB(t, "postgres", &queries, int64(42))
+
+			count, err := db.Count(ctx, NewTable("users"), "WHERE age > $1", 18)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, count, int64(42))
+			tt.AssertEqual(t, queries, []string{`SELECT COUNT(*) FROM "users" WHERE age > $1`})
+		})
+
+		t.Run("should work with an empty query", func(t *testing.T) {
+			var queries []string
+			db := newDB(t, "mysql", &queries, int64(0))
+
+			count, err := db.Count(ctx, NewTable("users"), "")
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, count, int64(0))
+			tt.AssertEqual(t, queries, []string{"SELECT COUNT(*) FROM `users`"})
+		})
+
+		t.Run("should ignore the soft deleted rows of tables with a registered struct", func(t *testing.T) {
+			type softDeletedUser struct {
+				ID        int        `ksql:"id"`
+				DeletedAt *time.Time `ksql:"deleted_at,softDelete"`
+			}
+			table := NewTable("users").WithStruct(softDeletedUser{})
+
+			var queries []string
+			db := newDB(t, "sqlite3", &queries, int64(1), int64(2))
+
+			_, err := db.Count(ctx, table, "WHERE id > ?", 1)
+			tt.AssertNoErr(t, err)
+			_, err = db.Count(Unscoped(ctx), table, "WHERE id > ?", 1)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, queries, []string{
+				"SELECT COUNT(*) FROM `users` WHERE `deleted_at` IS NULL AND (id > ?)",
+				"SELECT COUNT(*) FROM `users` WHERE id > ?",
+			})
+		})
+
+		t.Run("should report invalid tables", func(t *testing.T) {
+			var queries []string
+			db := newDB(t, "postgres", &queries)
+
+			_, err := db.Count(ctx, NewTable(""), "")
+			tt.AssertErrContains(t, err, "table name cannot be an empty string")
+			tt.AssertEqual(t, len(queries), 0)
+		})
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		tests := []struct {
+			desc           string
+			driver         string
+			values         []interface{}
+			expectedQuery  string
+			expectedExists bool
+		}{
+			{
+				desc:           "should return true if a row is found",
+				driver:         "postgres",
+				values:         []interface{}{1},
+				expectedQuery:  `SELECT 1 FROM "users" WHERE email = $1 LIMIT 1`,
+				expectedExists: true,
+			},
+			{
+				desc:           "should return false if no rows are found",
+				driver:         "postgres",
+				values:         nil,
+				expectedQuery:  `SELECT 1 FROM "users" WHERE email = $1 LIMIT 1`,
+				expectedExists: false,
+			},
+			{
+				desc:           "should use TOP for sqlserver",
+				driver:         "sqlserver",
+				values:         []interface{}{1},
+				expectedQuery:  `SELECT TOP 1 1 FROM [users] WHERE email = @p1`,
+				expectedExists: true,
+			},
+			{
+				desc:           "should use FETCH FIRST for oracle",
+				driver:         "oracle",
+				values:         []interface{}{1},
+				expectedQuery:  `SELECT 1 FROM "USERS" WHERE email = :1 FETCH FIRST 1 ROWS ONLY`,
+				expectedExists: true,
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				var queries []string
+				db := newDB(t, test.driver, &queries, test.values...)
+
+				query := map[string]string{
+					"postgres":  "WHERE email = $1",
+					"sqlserver": "WHERE email = @p1",
+					"oracle":    "WHERE email = :1",
+				}[test.driver]
+
+				exists, err := db.Exists(ctx, NewTable("users"), query, "fake@example.com")
+				tt.AssertNoErr(t, err)
+
+				tt.AssertEqual(t, exists, test.expectedExists)
+				tt.AssertEqual(t, queries, []string{test.expectedQuery})
+			})
+		}
+	})
+
+	t.Run("Sum, Min and Max", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, "postgres", &queries, 30)
+
+		var total, min, max int
+		err := db.Sum(ctx, &total, NewTable("users"), "age", "WHERE name LIKE $1", "A%")
+		tt.AssertNoErr(t, err)
+		err = db.Min(ctx, &min, NewTable("users"), "age", "")
+		tt.AssertNoErr(t, err)
+		err = db.Max(ctx, &max, NewTable("users"), "age", "")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, []int{total, min, max}, []int{30, 30, 30})
+		tt.AssertEqual(t, queries, []string{
+			`SELECT COALESCE(SUM("age"), 0) FROM "users" WHERE name LIKE $1`,
+			`SELECT MIN("age") FROM "users"`,
+			`SELECT MAX("age") FROM "users"`,
+		})
+	})
+}
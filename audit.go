@@ -0,0 +1,234 @@
+package ksql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The operations saved on the `ksql.AuditEntry.Operation` attribute
+const (
+	AuditInsert = "INSERT"
+	AuditUpdate = "UPDATE"
+	AuditDelete = "DELETE"
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of the input context carrying the actor,
+// e.g. the ID of the logged user, that is saved on the audit entries
+// of the tables created with `ksql.NewTable().WithAudit()`:
+//
+//	ctx = ksql.WithActor(ctx, "user:42")
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor saved on the context by
+// `ksql.WithActor()` and true, or an empty string and false if there is none.
+func ActorFromContext(ctx context.Context) (actor string, found bool) {
+	actor, found = ctx.Value(actorKey{}).(string)
+	return actor, found
+}
+
+// AuditEntry describes the rows of the audit tables used by
+// `ksql.NewTable().WithAudit()`, and can be used for reading them, e.g.:
+//
+//	var entries []ksql.AuditEntry
+//	err := db.Query(ctx, &entries, "FROM audit_log WHERE table_name = ? ORDER BY id", "users")
+//
+// The Before and After attributes contain all the columns of the row
+// before and after the operation, and are nil for inserts and hard
+// deletes respectively. The RecordID contains the ID columns of the
+// row as a JSON object, e.g. `{"id":42}`.
+type AuditEntry struct {
+	ID        int64                  `ksql:"id"`
+	TableName string                 `ksql:"table_name"`
+	Operation string                 `ksql:"operation"`
+	RecordID  string                 `ksql:"record_id"`
+	Before    map[string]interface{} `ksql:"before_image,jsonNullable"`
+	After     map[string]interface{} `ksql:"after_image,jsonNullable"`
+	Actor     string                 `ksql:"actor"`
+	CreatedAt time.Time              `ksql:"created_at,timeNowUTC"`
+}
+
+// WithAudit returns a copy of the Table whose writes are recorded on the
+// input audit table, on the same transaction as the write itself, e.g.:
+//
+//	var UsersTable = ksql.NewTable("users").WithAudit("audit_log")
+//
+// The audit table must have the columns of the ksql.AuditEntry struct,
+// e.g. on Postgres:
+//
+//	CREATE TABLE audit_log (
+//		id BIGSERIAL PRIMARY KEY,
+//		table_name TEXT NOT NULL,
+//		operation TEXT NOT NULL,
+//		record_id TEXT NOT NULL,
+//		before_image JSONB,
+//		after_image JSONB,
+//		actor TEXT NOT NULL,
+//		created_at TIMESTAMPTZ NOT NULL
+//	);
+//
+// The Insert, Patch, Update, PatchWithResult, PatchMap, UpdateReturning,
+// Delete, DeleteWithResult, DeleteReturning, DequeueOne and DequeueMany
+// methods load the row before and after the write and save both images as
+// JSON together with the actor saved on the context by `ksql.WithActor()`.
+// The writes that don't find the row are not recorded.
+//
+// Since they need a transaction these methods return an error if the
+// DBAdapter doesn't implement the TxBeginner interface, and since there
+// is no way of loading the images of the rows efficiently the Upsert,
+// BulkInsert, CopyFrom, DeleteMany and UpdateMany methods return an
+// error for audited tables. Exec is never audited.
+func (t Table) WithAudit(auditTable string) Table {
+	t.auditTable = auditTable
+	return t
+}
+
+// assertNotAudited returns an error for the methods
+// that don't support the tables created with `Table.WithAudit()`
+func assertNotAudited(table Table, method string) error {
+	if table.auditTable == "" {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"ksql: %s doesn't support audited tables, but the table `%s` is audited on `%s`",
+		method, table.name, table.auditTable,
+	)
+}
+
+// auditWrite runs the input write on a transaction, or savepoint, saving
+// an audit entry with the images of the row identified by idOrRecord
+// before and after the write.
+//
+// The write receives the DB bound to the transaction and a copy of the
+// table without the audit option, and should return its result for
+// checking if the row was found, or nil if it returns an error instead.
+func (c DB) auditWrite(
+	ctx context.Context,
+	table Table,
+	operation string,
+	idOrRecord interface{},
+	write func(db DB, table Table) (Result, error),
+) (result Result, err error) {
+	auditTable := table.auditTable
+	table.auditTable = ""
+
+	err = c.startTransaction(ctx, func(tx Provider) (err error) {
+		db := tx.(DB)
+
+		var before map[string]interface{}
+		if operation != AuditInsert {
+			before, err = db.loadAuditImage(ctx, table, idOrRecord)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err = write(db, table)
+		if err != nil {
+			return err
+		}
+
+		if result != nil {
+			n, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("ksql: unable to check if the audited row was found: %s", err)
+			}
+			if n == 0 {
+				return nil
+			}
+		}
+
+		// For inserts the IDs are only known after the write:
+		idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+		if err != nil {
+			return err
+		}
+		ids := map[string]interface{}{}
+		for _, idName := range table.idColumns {
+			ids[idName] = idMap[idName]
+		}
+		recordID, err := json.Marshal(ids)
+		if err != nil {
+			return fmt.Errorf("ksql: unable to serialize the ID of the audited row: %s", err)
+		}
+
+		after, err := db.loadAuditImage(ctx, table, idOrRecord)
+		if err != nil {
+			return err
+		}
+
+		actor, _ := ActorFromContext(ctx)
+		return db.insert(ctx, NewTable(auditTable), &AuditEntry{
+			TableName: table.name,
+			Operation: operation,
+			RecordID:  string(recordID),
+			Before:    before,
+			After:     after,
+			Actor:     actor,
+		})
+	})
+	return result, err
+}
+
+// loadAuditImage loads all the columns of the row identified by
+// idOrRecord as a map, or returns nil if the row doesn't exist.
+func (c DB) loadAuditImage(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+) (map[string]interface{}, error) {
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantField, err := getTenantField(c.naming, table, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	table, idMap, err = scopeToTenant(ctx, table, tenantField, idMap)
+	if err != nil {
+		return nil, err
+	}
+
+	query, params := buildAuditImageQuery(c.dialect, table, idMap)
+
+	var rows []map[string]interface{}
+	err = c.queryMaps(ctx, &rows, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: unable to load the row of the audited table `%s`: %s", table.name, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rows[0], nil
+}
+
+func buildAuditImageQuery(
+	dialect Dialect,
+	table Table,
+	idMap map[string]interface{},
+) (query string, params []interface{}) {
+	whereQuery := []string{}
+	for i, idName := range table.idColumns {
+		whereQuery = append(whereQuery, fmt.Sprintf(
+			"%s = %s", dialect.Escape(idName), dialect.Placeholder(i),
+		))
+		params = append(params, idMap[idName])
+	}
+
+	return fmt.Sprintf(
+		"SELECT * FROM %s WHERE %s",
+		dialect.Escape(table.name),
+		strings.Join(whereQuery, " AND "),
+	), params
+}
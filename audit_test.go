@@ -0,0 +1,139 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+// fakeAuditRows returns the input rows, scanning each
+// value into the argument with the same position
+type fakeAuditRows struct {
+	Rows
+
+	columns []string
+	rows    [][]interface{}
+}
+
+func (f *fakeAuditRows) Next() bool {
+	return len(f.rows) > 0
+}
+
+func (f *fakeAuditRows) Scan(args ...interface{}) error {
+	for i, arg := range args {
+		reflect.ValueOf(arg).Elem().Set(reflect.ValueOf(f.rows[0][i]))
+	}
+	f.rows = f.rows[1:]
+	return nil
+}
+
+func (f *fakeAuditRows) Columns() ([]string, error) {
+	return f.columns, nil
+}
+
+func (f *fakeAuditRows) Err() error {
+	return nil
+}
+
+func (f *fakeAuditRows) Close() error {
+	return nil
+}
+
+func TestBuildAuditImageQuery(t *testing.T) {
+	query, params := buildAuditImageQuery(
+		supportedDialects["postgres"],
+		NewTable("user_permissions", "user_id", "perm_id"),
+		map[string]interface{}{"user_id": 1, "perm_id": 2, "type": "read"},
+	)
+	tt.AssertEqual(t, query, `SELECT * FROM "user_permissions" WHERE "user_id" = $1 AND "perm_id" = $2`)
+	tt.AssertEqual(t, params, []interface{}{1, 2})
+}
+
+func TestAuditedTables(t *testing.T) {
+	t.Run("should save the actor on the context", func(t *testing.T) {
+		_, found := ActorFromContext(context.Background())
+		tt.AssertEqual(t, found, false)
+
+		actor, found := ActorFromContext(WithActor(context.Background(), "user:42"))
+		tt.AssertEqual(t, found, true)
+		tt.AssertEqual(t, actor, "user:42")
+	})
+
+	t.Run("should report the methods that don't support audited tables", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		table := NewTable("users").WithAudit("audit_log")
+
+		_, err = db.UpdateMany(context.Background(), table, []user{{ID: 1}})
+		tt.AssertErrContains(t, err, "UpdateMany", "users", "audit_log")
+
+		_, err = db.BulkInsert(context.Background(), table, []user{{Name: "Jane"}})
+		tt.AssertErrContains(t, err, "BulkInsert", "users", "audit_log")
+	})
+
+	t.Run("should audit each job removed by DequeueMany", func(t *testing.T) {
+		type job struct {
+			ID   int    `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		deleted := map[interface{}]bool{}
+		var writes []string
+		adapter := mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				rows := &fakeAuditRows{columns: []string{"id", "name"}}
+				switch {
+				case strings.HasPrefix(query, "INSERT"):
+					writes = append(writes, strings.Join(strings.Fields(query)[:3], " "))
+					rows = &fakeAuditRows{columns: []string{"id"}, rows: [][]interface{}{{int64(1)}}}
+				case strings.Contains(query, "SKIP LOCKED"):
+					rows.rows = [][]interface{}{{1, "fake-job-1"}, {2, "fake-job-2"}}
+				case !deleted[args[0]]:
+					// The image of the job before it is deleted:
+					rows.rows = [][]interface{}{{args[0], "fake-job"}}
+				}
+				return rows, nil
+			},
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				if strings.HasPrefix(query, "DELETE") {
+					deleted[args[0]] = true
+				}
+				writes = append(writes, strings.Join(strings.Fields(query)[:3], " "))
+				return NewMockResult(0, 1), nil
+			},
+		}
+		db, err := NewWithAdapter(mockTxBeginner{
+			DBAdapter: adapter,
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{
+					DBAdapter: adapter,
+					CommitFn: func(ctx context.Context) error {
+						return nil
+					},
+				}, nil
+			},
+		}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		var jobs []job
+		err = db.DequeueMany(context.Background(), NewTable("jobs").WithAudit("audit_log"), "ORDER BY id", &jobs, 10)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(jobs), 2)
+		tt.AssertEqual(t, writes, []string{
+			`DELETE FROM "jobs"`, `INSERT INTO "audit_log"`,
+			`DELETE FROM "jobs"`, `INSERT INTO "audit_log"`,
+		})
+	})
+
+	t.Run("should require transactions", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		err = db.Insert(context.Background(), NewTable("users").WithAudit("audit_log"), &user{Name: "Jane"})
+		tt.AssertErrContains(t, err, "TxBeginner")
+	})
+}
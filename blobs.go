@@ -0,0 +1,393 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// blobChunkSize is the number of bytes sent or read on each
+// query by the methods that stream blobs and large objects
+var blobChunkSize = 1024 * 1024
+
+// The modes used for opening the Postgres large objects
+const (
+	pgInvWrite = 0x20000
+	pgInvRead  = 0x40000
+)
+
+// WriteBlob streams the content of the reader into a binary column of the
+// record identified by `idOrRecord`, so that large files never need to be
+// loaded in memory at once, e.g.:
+//
+//	file, err := os.Open("video.mp4")
+//	// ...
+//	err = db.WriteBlob(ctx, VideosTable, video.ID, "content", file)
+//
+// Just like on the Delete method the `idOrRecord` argument can be the ID
+// itself, or a struct or map containing all the ID columns.
+//
+// The content is sent in chunks of 1MB, the first one replacing the
+// current value of the column and the others appended to it, all on the
+// same transaction, so the column is only changed if the whole content
+// is written. It is supported on Postgres (bytea), MySQL, SQLite3 and
+// SQL Server (varbinary(max), using the `.WRITE` clause), and for the
+// Postgres large objects see `DB.WriteLargeObject()` instead.
+func (c DB) WriteBlob(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	column string,
+	r io.Reader,
+) error {
+	op := Operation{Method: "WriteBlob", Table: table.name, Record: idOrRecord}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.writeBlob(ctx, table, idOrRecord, column, r)
+	})
+}
+
+func (c DB) writeBlob(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	column string,
+	r io.Reader,
+) error {
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't write blob on ksql.Table: %s", err)
+	}
+
+	appendQuery, err := buildBlobAppendQuery(c.dialect, column)
+	if err != nil {
+		return err
+	}
+
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+	if err != nil {
+		return err
+	}
+	whereQuery, whereParams := buildBlobWhereQuery(c.dialect, table, idMap, 1)
+
+	setQuery := c.dialect.Escape(column) + " = " + c.dialect.Placeholder(0)
+	chunk := make([]byte, blobChunkSize)
+	return c.startTransaction(ctx, func(tx Provider) error {
+		db := tx.(DB)
+		for first := true; ; first = false {
+			n, readErr := io.ReadFull(r, chunk)
+			if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+				return readErr
+			}
+
+			// The first chunk is always written, even if it is empty,
+			// so that the previous content of the column is replaced:
+			if n == 0 && !first {
+				return nil
+			}
+
+			query := setQuery
+			if !first {
+				query = appendQuery
+			}
+			query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", db.dialect.Escape(table.name), query, whereQuery)
+
+			result, err := db.db.ExecContext(ctx, query, append([]interface{}{chunk[:n]}, whereParams...)...)
+			if err != nil {
+				return translateError(db.dialect.DriverName(), err)
+			}
+
+			if first {
+				rowsAffected, err := result.RowsAffected()
+				if err != nil {
+					return fmt.Errorf(
+						"unexpected error: unable to fetch how many rows were affected by the update: %s",
+						err,
+					)
+				}
+				if rowsAffected < 1 {
+					return notFoundError(ctx, NotFoundError{Table: table.name})
+				}
+			}
+
+			if readErr != nil {
+				return nil
+			}
+		}
+	})
+}
+
+// ReadBlob streams the content of a binary column of the record identified
+// by `idOrRecord` into the writer, returning the number of bytes written,
+// so that large files never need to be loaded in memory at once, e.g.:
+//
+//	n, err := db.ReadBlob(ctx, VideosTable, video.ID, "content", httpResponseWriter)
+//
+// The content is read in chunks of 1MB, each with its own query, so the
+// method should be called inside a transaction, e.g. with `ksql.InjectTx()`,
+// if the column might be changed while it is read. Nothing is written
+// if the column is NULL, and ksql.ErrRecordNotFound is returned if there
+// is no such record. The dialects supported are the same of `DB.WriteBlob()`.
+func (c DB) ReadBlob(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	column string,
+	w io.Writer,
+) (n int64, err error) {
+	op := Operation{Method: "ReadBlob", Table: table.name, Record: idOrRecord}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		n, err = c.readBlob(ctx, table, idOrRecord, column, w)
+		return err
+	})
+	return n, err
+}
+
+func (c DB) readBlob(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	column string,
+	w io.Writer,
+) (int64, error) {
+	c = c.contextTx(ctx)
+
+	if err := table.validate(); err != nil {
+		return 0, fmt.Errorf("can't read blob from ksql.Table: %s", err)
+	}
+
+	substrFunc := "substr"
+	switch c.dialect.DriverName() {
+	case "postgres", "sqlite3":
+	case "mysql", "mariadb", "sqlserver":
+		substrFunc = "SUBSTRING"
+	default:
+		return 0, fmt.Errorf("ksql: streaming blobs is not supported on driver `%s`", c.dialect.DriverName())
+	}
+
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+	if err != nil {
+		return 0, err
+	}
+	whereQuery, whereParams := buildBlobWhereQuery(c.dialect, table, idMap, 2)
+
+	query := fmt.Sprintf(
+		"SELECT %s(%s, %s, %s) FROM %s WHERE %s",
+		substrFunc,
+		c.dialect.Escape(column),
+		c.dialect.Placeholder(0),
+		c.dialect.Placeholder(1),
+		c.dialect.Escape(table.name),
+		whereQuery,
+	)
+
+	var total int64
+	for {
+		var chunk []byte
+		found, err := c.queryValue(ctx, &chunk, query, append([]interface{}{total + 1, blobChunkSize}, whereParams...)...)
+		if err != nil {
+			return total, err
+		}
+		if !found {
+			return total, notFoundError(ctx, NotFoundError{Table: table.name})
+		}
+
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+
+		if len(chunk) < blobChunkSize {
+			return total, nil
+		}
+	}
+}
+
+// WriteLargeObject creates a Postgres large object with the content of
+// the reader, which is sent in chunks of 1MB, and returns its OID, which
+// is usually saved on a column of type `oid`, e.g.:
+//
+//	oid, err := db.WriteLargeObject(ctx, file)
+//
+// The large object is written on a transaction, so it is discarded if the
+// content can't be written completely, and it should be deleted with
+// `SELECT lo_unlink(oid)` when it is no longer needed.
+func (c DB) WriteLargeObject(ctx context.Context, r io.Reader) (oid uint32, err error) {
+	op := Operation{Method: "WriteLargeObject"}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		oid, err = c.writeLargeObject(ctx, r)
+		return err
+	})
+	return oid, err
+}
+
+func (c DB) writeLargeObject(ctx context.Context, r io.Reader) (oid uint32, err error) {
+	if c.dialect.DriverName() != "postgres" {
+		return 0, fmt.Errorf("ksql: large objects are only supported on Postgres, but got driver `%s`", c.dialect.DriverName())
+	}
+
+	chunk := make([]byte, blobChunkSize)
+	err = c.startTransaction(ctx, func(tx Provider) error {
+		db := tx.(DB)
+
+		_, err := db.queryValue(ctx, &oid, "SELECT lo_create(0)")
+		if err != nil {
+			return err
+		}
+
+		fd, err := db.openLargeObject(ctx, oid, pgInvWrite)
+		if err != nil {
+			return err
+		}
+
+		for {
+			n, readErr := io.ReadFull(r, chunk)
+			if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+				return readErr
+			}
+
+			if n > 0 {
+				var written int
+				_, err := db.queryValue(ctx, &written, "SELECT lowrite($1, $2)", fd, chunk[:n])
+				if err != nil {
+					return err
+				}
+			}
+
+			if readErr != nil {
+				break
+			}
+		}
+
+		return db.closeLargeObject(ctx, fd)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return oid, nil
+}
+
+// ReadLargeObject streams the content of the Postgres large object
+// with the input OID into the writer, in chunks of 1MB, returning the
+// number of bytes written, e.g.:
+//
+//	n, err := db.ReadLargeObject(ctx, video.ContentOID, httpResponseWriter)
+func (c DB) ReadLargeObject(ctx context.Context, oid uint32, w io.Writer) (n int64, err error) {
+	op := Operation{Method: "ReadLargeObject"}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		n, err = c.readLargeObject(ctx, oid, w)
+		return err
+	})
+	return n, err
+}
+
+func (c DB) readLargeObject(ctx context.Context, oid uint32, w io.Writer) (total int64, err error) {
+	if c.dialect.DriverName() != "postgres" {
+		return 0, fmt.Errorf("ksql: large objects are only supported on Postgres, but got driver `%s`", c.dialect.DriverName())
+	}
+
+	// The descriptors of the large objects are only
+	// valid until the end of the current transaction:
+	err = c.startTransaction(ctx, func(tx Provider) error {
+		db := tx.(DB)
+
+		fd, err := db.openLargeObject(ctx, oid, pgInvRead)
+		if err != nil {
+			return err
+		}
+
+		for {
+			var chunk []byte
+			_, err := db.queryValue(ctx, &chunk, "SELECT loread($1, $2)", fd, blobChunkSize)
+			if err != nil {
+				return err
+			}
+
+			n, err := w.Write(chunk)
+			total += int64(n)
+			if err != nil {
+				return err
+			}
+
+			if len(chunk) < blobChunkSize {
+				return db.closeLargeObject(ctx, fd)
+			}
+		}
+	})
+
+	return total, err
+}
+
+func (c DB) openLargeObject(ctx context.Context, oid uint32, mode int) (fd int, err error) {
+	found, err := c.queryValue(ctx, &fd, "SELECT lo_open($1, $2)", oid, mode)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("ksql: unable to open the large object %d", oid)
+	}
+
+	return fd, nil
+}
+
+func (c DB) closeLargeObject(ctx context.Context, fd int) error {
+	var result int
+	_, err := c.queryValue(ctx, &result, "SELECT lo_close($1)", fd)
+	return err
+}
+
+// queryValue scans the only column of the first row returned by the
+// query into dest, returning false if the query returned no rows
+func (c DB) queryValue(ctx context.Context, dest interface{}, query string, params ...interface{}) (found bool, err error) {
+	rows, err := c.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return false, translateError(c.dialect.DriverName(), err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, translateError(c.dialect.DriverName(), rows.Err())
+	}
+
+	err = rows.Scan(dest)
+	if err != nil {
+		return false, translateError(c.dialect.DriverName(), err)
+	}
+
+	return true, rows.Close()
+}
+
+// buildBlobAppendQuery returns the SET clause used for appending
+// the first param to the end of the content of a binary column
+func buildBlobAppendQuery(dialect Dialect, column string) (string, error) {
+	escaped := dialect.Escape(column)
+	placeholder := dialect.Placeholder(0)
+
+	switch dialect.DriverName() {
+	case "postgres":
+		return escaped + " = " + escaped + " || " + placeholder, nil
+	case "sqlite3":
+		// Without the cast the result of `||` would be of type TEXT:
+		return escaped + " = CAST(" + escaped + " || " + placeholder + " AS BLOB)", nil
+	case "mysql", "mariadb":
+		return escaped + " = CONCAT(" + escaped + ", " + placeholder + ")", nil
+	case "sqlserver":
+		return escaped + ".WRITE(" + placeholder + ", NULL, NULL)", nil
+	default:
+		return "", fmt.Errorf("ksql: streaming blobs is not supported on driver `%s`", dialect.DriverName())
+	}
+}
+
+// buildBlobWhereQuery returns the WHERE clause for the ID columns,
+// with placeholders starting on the input position
+func buildBlobWhereQuery(dialect Dialect, table Table, idMap map[string]interface{}, firstPlaceholder int) (string, []interface{}) {
+	whereQuery := []string{}
+	params := []interface{}{}
+	for i, idName := range table.idColumns {
+		whereQuery = append(whereQuery, dialect.Escape(idName)+" = "+dialect.Placeholder(firstPlaceholder+i))
+		params = append(params, idMap[idName])
+	}
+
+	return strings.Join(whereQuery, " AND "), params
+}
@@ -0,0 +1,255 @@
+package ksql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+// fakeValueRows returns one row with a single
+// column for each of the values it contains
+type fakeValueRows struct {
+	Rows
+
+	values []interface{}
+}
+
+func (f *fakeValueRows) Next() bool {
+	return len(f.values) > 0
+}
+
+func (f *fakeValueRows) Scan(args ...interface{}) error {
+	reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(f.values[0]))
+	f.values = f.values[1:]
+	return nil
+}
+
+func (f *fakeValueRows) Err() error {
+	return nil
+}
+
+func (f *fakeValueRows) Close() error {
+	return nil
+}
+
+func TestWriteBlob(t *testing.T) {
+	ctx := context.Background()
+
+	defer func(size int) { blobChunkSize = size }(blobChunkSize)
+	blobChunkSize = 4
+
+	tests := []struct {
+		driver             string
+		expectedStatements []DryRunStatement
+	}{
+		{
+			driver: "postgres",
+			expectedStatements: []DryRunStatement{
+				{Query: `UPDATE "files" SET "content" = $1 WHERE "id" = $2`, Params: []interface{}{[]byte("0123"), 42}},
+				{Query: `UPDATE "files" SET "content" = "content" || $1 WHERE "id" = $2`, Params: []interface{}{[]byte("4567"), 42}},
+				{Query: `UPDATE "files" SET "content" = "content" || $1 WHERE "id" = $2`, Params: []interface{}{[]byte("89"), 42}},
+			},
+		},
+		{
+			driver: "sqlserver",
+			expectedStatements: []DryRunStatement{
+				{Query: `UPDATE [files] SET [content] = @p1 WHERE [id] = @p2`, Params: []interface{}{[]byte("0123"), 42}},
+				{Query: `UPDATE [files] SET [content].WRITE(@p1, NULL, NULL) WHERE [id] = @p2`, Params: []interface{}{[]byte("4567"), 42}},
+				{Query: `UPDATE [files] SET [content].WRITE(@p1, NULL, NULL) WHERE [id] = @p2`, Params: []interface{}{[]byte("89"), 42}},
+			},
+		},
+		{
+			driver: "mysql",
+			expectedStatements: []DryRunStatement{
+				{Query: "UPDATE `files` SET `content` = ? WHERE `id` = ?", Params: []interface{}{[]byte("0123"), 42}},
+				{Query: "UPDATE `files` SET `content` = CONCAT(`content`, ?) WHERE `id` = ?", Params: []interface{}{[]byte("4567"), 42}},
+				{Query: "UPDATE `files` SET `content` = CONCAT(`content`, ?) WHERE `id` = ?", Params: []interface{}{[]byte("89"), 42}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run("should send the content in chunks on "+test.driver, func(t *testing.T) {
+			dryRun, err := NewDryRun(test.driver, nil)
+			tt.AssertNoErr(t, err)
+
+			// The params are copied before each read
+			// since the chunks reuse the same buffer:
+			var statements []DryRunStatement
+			copyStatements := func() {
+				for _, s := range dryRun.Statements()[len(statements):] {
+					statements = append(statements, DryRunStatement{
+						Query:  s.Query,
+						Params: []interface{}{append([]byte{}, s.Params[0].([]byte)...), s.Params[1]},
+					})
+				}
+			}
+
+			err = dryRun.WriteBlob(ctx, NewTable("files"), 42, "content", &chunkRecorder{
+				Reader: strings.NewReader("0123456789"),
+				onRead: copyStatements,
+			})
+			copyStatements()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, statements, test.expectedStatements)
+		})
+	}
+
+	t.Run("should replace the content with an empty value for empty readers", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.WriteBlob(ctx, NewTable("files"), 42, "content", strings.NewReader(""))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{
+			{Query: `UPDATE "files" SET "content" = $1 WHERE "id" = $2`, Params: []interface{}{[]byte{}, 42}},
+		})
+	})
+
+	t.Run("should report unsupported drivers", func(t *testing.T) {
+		dryRun, err := NewDryRun("oracle", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.WriteBlob(ctx, NewTable("files"), 42, "content", strings.NewReader("0123"))
+		tt.AssertErrContains(t, err, "blobs", "not supported", "oracle")
+
+		_, err = dryRun.ReadBlob(ctx, NewTable("files"), 42, "content", &bytes.Buffer{})
+		tt.AssertErrContains(t, err, "blobs", "not supported", "oracle")
+	})
+}
+
+// chunkRecorder calls onRead before each read, i.e.
+// after the chunk of the previous read was sent
+type chunkRecorder struct {
+	*strings.Reader
+	onRead func()
+}
+
+func (c *chunkRecorder) Read(b []byte) (int, error) {
+	c.onRead()
+	return c.Reader.Read(b)
+}
+
+func TestReadBlob(t *testing.T) {
+	ctx := context.Background()
+
+	defer func(size int) { blobChunkSize = size }(blobChunkSize)
+	blobChunkSize = 4
+
+	t.Run("should read the content in chunks", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		chunks := []interface{}{[]byte("0123"), []byte("4567"), []byte("89")}
+		dryRun, err := NewDryRun("postgres", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				queries = append(queries, query)
+				params = append(params, args)
+				chunk := chunks[0]
+				chunks = chunks[1:]
+				return &fakeValueRows{values: []interface{}{chunk}}, nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		var buf bytes.Buffer
+		n, err := dryRun.ReadBlob(ctx, NewTable("files"), 42, "content", &buf)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, n, int64(10))
+		tt.AssertEqual(t, buf.String(), "0123456789")
+
+		tt.AssertEqual(t, len(queries), 3)
+		tt.AssertEqual(t, queries[0], `SELECT substr("content", $1, $2) FROM "files" WHERE "id" = $3`)
+		tt.AssertEqual(t, params, [][]interface{}{
+			{int64(1), 4, 42},
+			{int64(5), 4, 42},
+			{int64(9), 4, 42},
+		})
+	})
+
+	t.Run("should return ErrRecordNotFound for missing records", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.ReadBlob(ctx, NewTable("files"), 42, "content", &bytes.Buffer{})
+		tt.AssertEqual(t, IsNotFound(err), true)
+	})
+}
+
+func TestLargeObjects(t *testing.T) {
+	ctx := context.Background()
+
+	defer func(size int) { blobChunkSize = size }(blobChunkSize)
+	blobChunkSize = 4
+
+	t.Run("should write the large objects in chunks", func(t *testing.T) {
+		var queries []string
+		dryRun, err := NewDryRun("postgres", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				if strings.Contains(query, "lowrite") {
+					args[1] = string(args[1].([]byte))
+				}
+				queries = append(queries, fmt.Sprint(query, args))
+
+				switch {
+				case strings.Contains(query, "lo_create"):
+					return &fakeValueRows{values: []interface{}{uint32(1234)}}, nil
+				case strings.Contains(query, "lo_open"):
+					return &fakeValueRows{values: []interface{}{0}}, nil
+				default:
+					return &fakeValueRows{values: []interface{}{4}}, nil
+				}
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		oid, err := dryRun.WriteLargeObject(ctx, strings.NewReader("0123456789"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, oid, uint32(1234))
+		tt.AssertEqual(t, queries, []string{
+			"SELECT lo_create(0)[]",
+			"SELECT lo_open($1, $2)[1234 131072]",
+			"SELECT lowrite($1, $2)[0 0123]",
+			"SELECT lowrite($1, $2)[0 4567]",
+			"SELECT lowrite($1, $2)[0 89]",
+			"SELECT lo_close($1)[0]",
+		})
+	})
+
+	t.Run("should read the large objects in chunks", func(t *testing.T) {
+		chunks := []interface{}{[]byte("0123"), []byte("4567"), []byte("89")}
+		dryRun, err := NewDryRun("postgres", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				switch {
+				case strings.Contains(query, "loread"):
+					chunk := chunks[0]
+					chunks = chunks[1:]
+					return &fakeValueRows{values: []interface{}{chunk}}, nil
+				default:
+					return &fakeValueRows{values: []interface{}{0}}, nil
+				}
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		var buf bytes.Buffer
+		n, err := dryRun.ReadLargeObject(ctx, 1234, &buf)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, n, int64(10))
+		tt.AssertEqual(t, buf.String(), "0123456789")
+	})
+
+	t.Run("should report unsupported drivers", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.WriteLargeObject(ctx, strings.NewReader("0123"))
+		tt.AssertErrContains(t, err, "large objects", "Postgres", "sqlite3")
+
+		_, err = dryRun.ReadLargeObject(ctx, 1234, &bytes.Buffer{})
+		tt.AssertErrContains(t, err, "large objects", "Postgres", "sqlite3")
+	})
+}
@@ -0,0 +1,389 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// BulkCopier can be optionally implemented by the DBAdapter, and by the Tx
+// returned by it, for loading rows with the bulk load protocol of the
+// database, e.g. `COPY FROM` on Postgres or the bulk copy of SQL Server,
+// which is used by `DB.BulkInsert()` and `DB.CopyFrom()`.
+//
+// The values of each row are in the same order of the columns, and
+// it should return the number of rows that were written.
+type BulkCopier interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
+}
+
+// BulkInsert inserts all the records of the input slice using the fastest
+// strategy available for the database, which is much faster than running
+// one INSERT statement per record when loading large amounts of data, e.g.:
+//
+//	n, err := db.BulkInsert(ctx, UsersTable, &users)
+//
+// The adapters implementing the ksql.BulkCopier interface use the bulk load
+// protocol of the database, i.e. `COPY FROM` on kpgx and kpgx5 and the bulk
+// copy of go-mssqldb on ksqlserver. The other adapters fall back to
+// multi-row INSERT statements with as many records as the database allows
+// per statement, e.g. 65535 placeholders on MySQL and 999 on SQLite,
+// and these statements are not atomic unless BulkInsert runs inside a
+// transaction.
+//
+// The columns are read from the `ksql` tags of the records, and the ID
+// columns are only written if they are set on the records, in which
+// case they must be set on all the records.
+//
+// Since the bulk load protocols can't return the IDs generated by the
+// database they are not saved on the records, but the IDs of the tables
+// created with `Table.WithIDGenerator()` work as expected. The
+// `default=<value>` modifiers, the `timeNowUTC` modifiers, the Validator
+// and the insert hooks work just like on the Insert method.
+func (c DB) BulkInsert(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (rowsInserted int64, err error) {
+	op := Operation{Method: "BulkInsert", Table: table.name, Record: records}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		rowsInserted, err = c.bulkInsert(ctx, table, records)
+		return err
+	})
+	return rowsInserted, err
+}
+
+// CopyFrom works exactly like the BulkInsert method, and is named after
+// the `COPY FROM` protocol it uses on Postgres, e.g.:
+//
+//	n, err := db.CopyFrom(ctx, UsersTable, &users)
+func (c DB) CopyFrom(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (rowsCopied int64, err error) {
+	op := Operation{Method: "CopyFrom", Table: table.name, Record: records}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		rowsCopied, err = c.bulkInsert(ctx, table, records)
+		return err
+	})
+	return rowsCopied, err
+}
+
+func (c DB) bulkInsert(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (int64, error) {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := table.validate(); err != nil {
+		return 0, fmt.Errorf("can't insert in ksql.Table: %s", err)
+	}
+
+	if err := assertNotAudited(table, "BulkInsert"); err != nil {
+		return 0, err
+	}
+
+	recordList, columns, rows, err := c.prepareBulkRows(ctx, table, records)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	var n int64
+	if copier := getBulkCopier(c.db); copier != nil {
+		n, err = copier.CopyFrom(ctx, table.name, columns, rows)
+		if err != nil {
+			err = translateError(c.dialect.DriverName(), err)
+		}
+	} else {
+		n, err = c.insertBatches(ctx, table, columns, rows)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, record := range recordList {
+		if err := callAfterInsert(ctx, record); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// prepareBulkRows runs the same steps of the Insert method for each record,
+// i.e. the ID generation, the default values, the BeforeInsert hooks and
+// the validation, and then converts the records into rows of values.
+func (c DB) prepareBulkRows(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (recordList []interface{}, columns []string, rows [][]interface{}, err error) {
+	slice := reflect.ValueOf(records)
+	if slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice {
+		return nil, nil, nil, fmt.Errorf("ksql: expected to receive a slice of structs, but got: %T", records)
+	}
+
+	structType, isSliceOfPtrs, err := structs.DecodeAsSliceOfStructs(slice.Type())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	info, err := c.naming.GetTagInfo(structType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if info.IsNestedStruct {
+		return nil, nil, nil, fmt.Errorf("ksql: bulk inserts don't support nested structs")
+	}
+
+	now := time.Now().UTC()
+	recordList = make([]interface{}, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		v := slice.Index(i)
+		if !isSliceOfPtrs {
+			// The elements of slices are always addressable:
+			v = v.Addr()
+		}
+		if v.IsNil() {
+			return nil, nil, nil, fmt.Errorf("ksql: the record at index %d is a nil pointer", i)
+		}
+		recordList[i] = v.Interface()
+
+		if err := table.generateID(ctx, v, info); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := setTenantOnInsert(ctx, v.Elem(), info); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := setDefaultValues(ctx, v.Elem(), info, recordList[i]); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := callBeforeInsert(ctx, recordList[i]); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := c.validate(ctx, table, recordList[i]); err != nil {
+			return nil, nil, nil, err
+		}
+
+		setTimeNowOnInsert(v.Elem(), info, now)
+	}
+
+	fields, err := getBulkFields(table, info, recordList)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	columns = make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+
+	rows = make([][]interface{}, len(recordList))
+	for i, record := range recordList {
+		structValue := reflect.ValueOf(record).Elem()
+
+		row := make([]interface{}, len(fields))
+		for j, field := range fields {
+			var value interface{}
+			attr := structValue.FieldByIndex(field.Path)
+			if attr.Kind() != reflect.Ptr || !attr.IsNil() {
+				value = attr.Interface()
+			}
+
+			row[j], err = applyValueModifier(ctx, c.dialect, "Insert", field, value)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		rows[i] = row
+	}
+
+	return recordList, columns, rows, nil
+}
+
+// getBulkFields returns the fields written by the bulk inserts, omitting
+// the ID columns that are not set on any of the records so that the
+// database can generate them.
+func getBulkFields(table Table, info structs.StructInfo, recordList []interface{}) ([]*structs.FieldInfo, error) {
+	isID := map[string]bool{}
+	for _, idName := range table.idColumns {
+		isID[idName] = true
+	}
+
+	var fields []*structs.FieldInfo
+	for _, field := range info.Fields() {
+		if field.SkipOnInsert {
+			continue
+		}
+
+		if !isID[field.Name] || len(recordList) == 0 {
+			fields = append(fields, field)
+			continue
+		}
+
+		numSet := 0
+		for _, record := range recordList {
+			if !reflect.ValueOf(record).Elem().FieldByIndex(field.Path).IsZero() {
+				numSet++
+			}
+		}
+
+		switch numSet {
+		case 0:
+			// Omitted so the database generates the IDs
+		case len(recordList):
+			fields = append(fields, field)
+		default:
+			return nil, fmt.Errorf(
+				"ksql: the ID column `%s` must be either set on all records or on none of them for bulk inserts",
+				field.Name,
+			)
+		}
+	}
+
+	return fields, nil
+}
+
+// getBulkCopier returns the BulkCopier implemented by the
+// adapter, unwrapping the adapters added by KSQL itself,
+// or nil if the adapter doesn't implement it.
+func getBulkCopier(db DBAdapter) BulkCopier {
+	for {
+		switch adapter := db.(type) {
+		case BulkCopier:
+			return adapter
+		case tenantSessionAdapter:
+			// The bulk load protocols would bypass the session variable:
+			return nil
+		case interface{ unwrapAdapter() DBAdapter }:
+			db = adapter.unwrapAdapter()
+		default:
+			return nil
+		}
+	}
+}
+
+// maxParamsPerStatement is the maximum number of params supported
+// by each database on a single statement, for SQL Server we keep
+// some room below its limit of 2100 params and for SQLite we use
+// the limit of the versions before 3.32.0, which is the lowest.
+var maxParamsPerStatement = map[string]int{
+	"sqlserver": 2000,
+	"sqlite3":   999,
+	"mysql":     65535,
+	"mariadb":   65535,
+	"postgres":  65535,
+}
+
+const (
+	defaultMaxParamsPerStatement = 65535
+
+	// SQL Server also limits the VALUES clause to 1000 rows,
+	// and larger statements bring diminishing returns anyway:
+	maxRowsPerStatement = 1000
+)
+
+// insertBatches inserts the rows using multi-row INSERT statements,
+// each one with as many rows as the database allows.
+func (c DB) insertBatches(
+	ctx context.Context,
+	table Table,
+	columns []string,
+	rows [][]interface{},
+) (int64, error) {
+	maxParams, found := maxParamsPerStatement[c.dialect.DriverName()]
+	if !found {
+		maxParams = defaultMaxParamsPerStatement
+	}
+
+	batchSize := maxRowsPerStatement
+	if len(columns) > 0 && maxParams/len(columns) < batchSize {
+		batchSize = maxParams / len(columns)
+	}
+
+	var total int64
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		query, params := buildMultiRowInsertQuery(c.dialect, table, columns, rows[start:end])
+		result, err := c.db.ExecContext(ctx, query, params...)
+		if err != nil {
+			return total, translateError(c.dialect.DriverName(), err)
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf(
+				"unexpected error: unable to fetch how many rows were affected by the insert: %s",
+				err,
+			)
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// buildMultiRowInsertQuery builds an INSERT statement for all
+// the input rows, on Oracle it uses the `INSERT ALL` syntax
+// since it doesn't support multiple rows on the VALUES clause.
+func buildMultiRowInsertQuery(
+	dialect Dialect,
+	table Table,
+	columns []string,
+	rows [][]interface{},
+) (query string, params []interface{}) {
+	escapedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		escapedColumns[i] = dialect.Escape(column)
+	}
+	columnsQuery := strings.Join(escapedColumns, ", ")
+
+	valuesQuery := make([]string, len(rows))
+	for i, row := range rows {
+		placeholders := make([]string, len(row))
+		for j, value := range row {
+			params = append(params, value)
+			placeholders[j] = dialect.Placeholder(len(params) - 1)
+		}
+		valuesQuery[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	if dialect.DriverName() == "oracle" {
+		var b strings.Builder
+		b.WriteString("INSERT ALL")
+		for _, values := range valuesQuery {
+			b.WriteString(" INTO " + dialect.Escape(table.name) + " (" + columnsQuery + ") VALUES " + values)
+		}
+		b.WriteString(" SELECT 1 FROM DUAL")
+		return b.String(), params
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s",
+		dialect.Escape(table.name),
+		columnsQuery,
+		strings.Join(valuesQuery, ", "),
+	), params
+}
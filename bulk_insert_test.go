@@ -0,0 +1,151 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type copyUser struct {
+	ID   int     `ksql:"id"`
+	Name string  `ksql:"name"`
+	Nick *string `ksql:"nick"`
+}
+
+type mockBulkCopier struct {
+	mockDBAdapter
+
+	CopyFromFn func(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
+}
+
+func (m mockBulkCopier) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return m.CopyFromFn(ctx, table, columns, rows)
+}
+
+func TestBulkInsert(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should use the BulkCopier of the adapter", func(t *testing.T) {
+		var copiedTable string
+		var copiedColumns []string
+		var copiedRows [][]interface{}
+		db, err := NewWithConfig(mockBulkCopier{
+			CopyFromFn: func(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+				copiedTable, copiedColumns, copiedRows = table, columns, rows
+				return int64(len(rows)), nil
+			},
+		}, "postgres", Config{
+			// The adapters added by KSQL should be unwrapped:
+			Logger: newRecordingLogger(&[]logEntry{}),
+		})
+		tt.AssertNoErr(t, err)
+
+		nick := "bia"
+		n, err := db.BulkInsert(ctx, NewTable("users"), []copyUser{
+			{Name: "Ana"},
+			{Name: "Bia", Nick: &nick},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, n, int64(2))
+		tt.AssertEqual(t, copiedTable, "users")
+		tt.AssertEqual(t, copiedColumns, []string{"name", "nick"})
+		tt.AssertEqual(t, copiedRows, [][]interface{}{{"Ana", nil}, {"Bia", &nick}})
+	})
+
+	t.Run("should fall back to multi-row inserts", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		users := []*copyUser{
+			{ID: 1, Name: "Ana"},
+			{ID: 2, Name: "Bia"},
+		}
+		_, err = dryRun.BulkInsert(ctx, NewTable("users"), users)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `INSERT INTO "users" ("id", "name", "nick") VALUES ($1, $2, $3), ($4, $5, $6)`,
+			Params: []interface{}{1, "Ana", nil, 2, "Bia", nil},
+		}})
+	})
+
+	t.Run("should work the same way when called as CopyFrom", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		n, err := dryRun.CopyFrom(ctx, NewTable("users"), []copyUser{{Name: "Ana"}, {Name: "Bia"}})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, n, int64(1))
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  "INSERT INTO `users` (`name`, `nick`) VALUES (?, ?), (?, ?)",
+			Params: []interface{}{"Ana", nil, "Bia", nil},
+		}})
+	})
+
+	t.Run("should use INSERT ALL on oracle", func(t *testing.T) {
+		dryRun, err := NewDryRun("oracle", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.BulkInsert(ctx, NewTable("users"), []copyUser{{Name: "Ana"}, {Name: "Bia"}})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements()[0].Query,
+			`INSERT ALL INTO "USERS" ("NAME", "NICK") VALUES (:1, :2) INTO "USERS" ("NAME", "NICK") VALUES (:3, :4) SELECT 1 FROM DUAL`,
+		)
+	})
+
+	t.Run("should split the rows in batches respecting the params limit", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		users := make([]copyUser, 700)
+		for i := range users {
+			users[i].Name = "fake-name"
+		}
+
+		n, err := dryRun.BulkInsert(ctx, NewTable("users"), users)
+		tt.AssertNoErr(t, err)
+
+		// The DryRun reports one affected row per statement:
+		tt.AssertEqual(t, n, int64(2))
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 2)
+		tt.AssertEqual(t, len(statements[0].Params), 998)
+		tt.AssertEqual(t, len(statements[1].Params), 402)
+	})
+
+	t.Run("should call the insert hooks", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		users := []hookedUser{{Email: "A@Foo.com"}}
+		_, err = dryRun.BulkInsert(ctx, NewTable("users"), users)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, users[0].Calls, []string{"BeforeInsert", "AfterInsert"})
+		tt.AssertEqual(t, dryRun.Statements()[0].Params, []interface{}{"a@foo.com"})
+	})
+
+	t.Run("should report IDs set on only some of the records", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.BulkInsert(ctx, NewTable("users"), []copyUser{{ID: 1, Name: "Ana"}, {Name: "Bia"}})
+		tt.AssertErrContains(t, err, "ID column `id`", "all records")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should do nothing for empty slices", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		n, err := dryRun.BulkInsert(ctx, NewTable("users"), []copyUser{})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, n, int64(0))
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
@@ -0,0 +1,439 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// DeleteMany deletes all the rows identified by the input slice
+// using a single statement, e.g.:
+//
+//	result, err := db.DeleteMany(ctx, UsersTable, []int{1, 2, 3})
+//
+// Just like on the Delete method the elements of the slice can be the
+// IDs themselves, or structs or maps containing all the ID columns,
+// which is required for tables with composite keys.
+//
+// If the table or the records have an attribute with the `softDelete`
+// modifier the rows are soft deleted instead, and an empty slice is
+// a no-op. Unlike the Delete method no error is returned if some
+// of the rows are not found, so use `result.RowsAffected()`
+// for checking how many rows were deleted.
+func (c DB) DeleteMany(
+	ctx context.Context,
+	table Table,
+	idsOrRecords interface{},
+) (result Result, err error) {
+	op := Operation{Method: "DeleteMany", Table: table.name, Record: idsOrRecords}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		result, err = c.deleteMany(ctx, table, idsOrRecords)
+		return err
+	})
+	return result, err
+}
+
+func (c DB) deleteMany(
+	ctx context.Context,
+	table Table,
+	idsOrRecords interface{},
+) (Result, error) {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := table.validate(); err != nil {
+		return nil, fmt.Errorf("can't delete from ksql.Table: %s", err)
+	}
+
+	if err := assertNotAudited(table, "DeleteMany"); err != nil {
+		return nil, err
+	}
+
+	slice := reflect.ValueOf(idsOrRecords)
+	if slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ksql: expected to receive a slice of IDs or records, but got: %T", idsOrRecords)
+	}
+
+	if slice.Len() == 0 {
+		return NewMockResult(0, 0), nil
+	}
+
+	tenantField, err := getTenantField(c.naming, table, slice.Index(0).Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	scopedTable := table
+	idMaps := make([]map[string]interface{}, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, slice.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		scopedTable, idMaps[i], err = scopeToTenant(ctx, table, tenantField, idMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	table = scopedTable
+
+	softDeleteField, err := getSoftDeleteField(ctx, c.naming, table, slice.Index(0).Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	var params []interface{}
+	addParam := func(value interface{}) string {
+		params = append(params, value)
+		return c.dialect.Placeholder(len(params) - 1)
+	}
+
+	var query string
+	if softDeleteField != nil {
+		column := c.dialect.Escape(softDeleteField.Name)
+		query = fmt.Sprintf(
+			"UPDATE %s SET %s = %s WHERE (%s) AND %s IS NULL",
+			c.dialect.Escape(table.name),
+			column,
+			addParam(time.Now().UTC()),
+			buildIDsCondition(c.dialect, table.idColumns, idMaps, addParam),
+			column,
+		)
+	} else {
+		query = fmt.Sprintf(
+			"DELETE FROM %s WHERE %s",
+			c.dialect.Escape(table.name),
+			buildIDsCondition(c.dialect, table.idColumns, idMaps, addParam),
+		)
+	}
+
+	result, err := c.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return nil, translateError(c.dialect.DriverName(), err)
+	}
+
+	return result, nil
+}
+
+// buildIDsCondition builds a condition matching all the input IDs, i.e.
+// `id IN (...)` for tables with a single ID column and a sequence of
+// `(id1 = ? AND id2 = ?)` joined by `OR` for tables with composite keys.
+func buildIDsCondition(
+	dialect Dialect,
+	idColumns []string,
+	idMaps []map[string]interface{},
+	addParam func(value interface{}) string,
+) string {
+	if len(idColumns) == 1 {
+		placeholders := make([]string, len(idMaps))
+		for i, idMap := range idMaps {
+			placeholders[i] = addParam(idMap[idColumns[0]])
+		}
+		return dialect.Escape(idColumns[0]) + " IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	conds := make([]string, len(idMaps))
+	for i, idMap := range idMaps {
+		conds[i] = "(" + buildIDCondition(dialect, idColumns, idMap, addParam) + ")"
+	}
+	return strings.Join(conds, " OR ")
+}
+
+func buildIDCondition(
+	dialect Dialect,
+	idColumns []string,
+	idMap map[string]interface{},
+	addParam func(value interface{}) string,
+) string {
+	conds := make([]string, len(idColumns))
+	for i, idName := range idColumns {
+		conds[i] = dialect.Escape(idName) + " = " + addParam(idMap[idName])
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// UpdateMany applies a partial update to all the records of the input
+// slice using a single statement, which saves many round trips when
+// compared to calling Patch for each record, e.g.:
+//
+//	result, err := db.UpdateMany(ctx, UsersTable, &users)
+//
+// On Postgres it runs an `UPDATE ... FROM (...)` statement joining
+// the table with the values of the records, and on the other dialects
+// it sets each column with a `CASE` expression on the ID columns.
+//
+// Just like on Patch the nil pointer attributes are ignored, but since
+// a single statement is used all the records must ignore the same
+// attributes. The hooks, the timestamps and the Validator work just
+// like on Patch, but the `optimisticLock` modifier is not supported.
+//
+// Unlike the Patch method no error is returned if some of the records
+// are not found, so use `result.RowsAffected()` for checking how many
+// rows were updated, and an empty slice is a no-op.
+//
+// Note that databases limit the number of params of a statement, e.g.
+// 65535 on Postgres and 2100 on SQL Server, so large slices should be
+// split into batches.
+func (c DB) UpdateMany(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (result Result, err error) {
+	op := Operation{Method: "UpdateMany", Table: table.name, Record: records}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		result, err = c.updateMany(ctx, table, records)
+		return err
+	})
+	return result, err
+}
+
+func (c DB) updateMany(
+	ctx context.Context,
+	table Table,
+	records interface{},
+) (Result, error) {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := table.validate(); err != nil {
+		return nil, fmt.Errorf("can't update ksql.Table: %s", err)
+	}
+
+	if err := assertNotAudited(table, "UpdateMany"); err != nil {
+		return nil, err
+	}
+
+	slice := reflect.ValueOf(records)
+	if slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ksql: expected to receive a slice of structs, but got: %T", records)
+	}
+
+	structType, isSliceOfPtrs, err := structs.DecodeAsSliceOfStructs(slice.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.naming.GetTagInfo(structType)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsNestedStruct {
+		return nil, fmt.Errorf("ksql: UpdateMany doesn't support nested structs")
+	}
+	if info.OptimisticLockField != nil {
+		return nil, fmt.Errorf("ksql: UpdateMany doesn't support the optimisticLock modifier, use Patch instead")
+	}
+
+	if slice.Len() == 0 {
+		return NewMockResult(0, 0), nil
+	}
+
+	now := time.Now().UTC()
+	scopedTable := table
+	recordList := make([]interface{}, slice.Len())
+	recordMaps := make([]map[string]interface{}, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		record := slice.Index(i)
+		if !isSliceOfPtrs && record.CanAddr() {
+			// So the hooks with pointer receivers are called:
+			record = record.Addr()
+		}
+		recordList[i] = record.Interface()
+
+		if err := callBeforeUpdate(ctx, recordList[i]); err != nil {
+			return nil, err
+		}
+
+		if err := c.validate(ctx, table, recordList[i]); err != nil {
+			return nil, err
+		}
+
+		recordMap, err := c.naming.StructToMap(recordList[i])
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateIfAllIdsArePresent(table.idColumns, recordMap)
+		if err != nil {
+			return nil, err
+		}
+
+		scopedTable, recordMap, err = scopeToTenant(ctx, table, info.TenantField, recordMap)
+		if err != nil {
+			return nil, err
+		}
+
+		setTimeNowOnUpdate(recordMap, info, now)
+		recordMaps[i] = recordMap
+	}
+	table = scopedTable
+
+	columns := getUpdatedColumns(recordMaps[0], table.idColumns)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf(
+			"ksql: the input records have no attributes to update besides the ID columns: %v",
+			table.idColumns,
+		)
+	}
+
+	for i, recordMap := range recordMaps {
+		if i > 0 && !reflect.DeepEqual(getUpdatedColumns(recordMap, table.idColumns), columns) {
+			return nil, fmt.Errorf(
+				"ksql: all records passed to UpdateMany must update the same attributes, but the record at index %d has different nil pointer attributes than the first one",
+				i,
+			)
+		}
+
+		for _, column := range columns {
+			recordMap[column], err = applyValueModifier(ctx, c.dialect, "Update", info.ByName(column), recordMap[column])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var query string
+	var params []interface{}
+	if c.dialect.DriverName() == "postgres" {
+		query, params = buildUpdateManyJoinQuery(c.dialect, table, columns, recordMaps)
+	} else {
+		query, params = buildUpdateManyCaseQuery(c.dialect, table, columns, recordMaps)
+	}
+
+	result, err := c.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return nil, translateError(c.dialect.DriverName(), err)
+	}
+
+	for _, record := range recordList {
+		if err := callAfterUpdate(ctx, record); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// getUpdatedColumns returns the sorted names of the columns
+// of the record map that are not ID columns
+func getUpdatedColumns(recordMap map[string]interface{}, idColumns []string) []string {
+	isID := map[string]bool{}
+	for _, idName := range idColumns {
+		isID[idName] = true
+	}
+
+	columns := []string{}
+	for column := range recordMap {
+		if !isID[column] {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+
+	return columns
+}
+
+// buildUpdateManyJoinQuery builds an `UPDATE ... FROM (...)` query.
+//
+// The values are written as `SELECT $1, $2` instead of `VALUES ($1, $2)`
+// because Postgres would infer the type of the params as text on a VALUES
+// list, while the first SELECT, which returns no rows, makes Postgres infer
+// the types of the params from the types of the columns on the table.
+func buildUpdateManyJoinQuery(
+	dialect Dialect,
+	table Table,
+	columns []string,
+	recordMaps []map[string]interface{},
+) (query string, params []interface{}) {
+	addParam := func(value interface{}) string {
+		params = append(params, value)
+		return dialect.Placeholder(len(params) - 1)
+	}
+
+	allColumns := append(append([]string{}, table.idColumns...), columns...)
+	escapedColumns := make([]string, len(allColumns))
+	for i, column := range allColumns {
+		escapedColumns[i] = dialect.Escape(column)
+	}
+
+	tableName := dialect.Escape(table.name)
+	selects := []string{
+		"SELECT " + strings.Join(escapedColumns, ", ") + " FROM " + tableName + " WHERE false",
+	}
+	for _, recordMap := range recordMaps {
+		placeholders := make([]string, len(allColumns))
+		for i, column := range allColumns {
+			placeholders[i] = addParam(recordMap[column])
+		}
+		selects = append(selects, "SELECT "+strings.Join(placeholders, ", "))
+	}
+
+	setQuery := make([]string, len(columns))
+	for i, column := range columns {
+		setQuery[i] = dialect.Escape(column) + " = ksql_values." + dialect.Escape(column)
+	}
+
+	whereQuery := make([]string, len(table.idColumns))
+	for i, idName := range table.idColumns {
+		whereQuery[i] = tableName + "." + dialect.Escape(idName) + " = ksql_values." + dialect.Escape(idName)
+	}
+
+	query = fmt.Sprintf(
+		"UPDATE %s SET %s FROM (%s) ksql_values WHERE %s",
+		tableName,
+		strings.Join(setQuery, ", "),
+		strings.Join(selects, " UNION ALL "),
+		strings.Join(whereQuery, " AND "),
+	)
+
+	return query, params
+}
+
+// buildUpdateManyCaseQuery builds an UPDATE query that sets each column
+// with a `CASE WHEN <id> = ? THEN ? ... ELSE <column> END` expression.
+func buildUpdateManyCaseQuery(
+	dialect Dialect,
+	table Table,
+	columns []string,
+	recordMaps []map[string]interface{},
+) (query string, params []interface{}) {
+	addParam := func(value interface{}) string {
+		params = append(params, value)
+		return dialect.Placeholder(len(params) - 1)
+	}
+
+	setQuery := make([]string, len(columns))
+	for i, column := range columns {
+		var b strings.Builder
+		b.WriteString(dialect.Escape(column) + " = CASE")
+		for _, recordMap := range recordMaps {
+			b.WriteString(" WHEN " + buildIDCondition(dialect, table.idColumns, recordMap, addParam))
+			b.WriteString(" THEN " + addParam(recordMap[column]))
+		}
+		b.WriteString(" ELSE " + dialect.Escape(column) + " END")
+		setQuery[i] = b.String()
+	}
+
+	query = fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s",
+		dialect.Escape(table.name),
+		strings.Join(setQuery, ", "),
+		buildIDsCondition(dialect, table.idColumns, recordMaps, addParam),
+	)
+
+	return query, params
+}
@@ -0,0 +1,167 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type bulkUser struct {
+	ID   int    `ksql:"id"`
+	Name string `ksql:"name"`
+	Age  *int   `ksql:"age"`
+}
+
+func TestDeleteMany(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should delete all IDs with a single statement", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		result, err := dryRun.DeleteMany(ctx, NewTable("users"), []int{1, 2, 3})
+		tt.AssertNoErr(t, err)
+
+		n, err := result.RowsAffected()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, n, int64(1))
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `DELETE FROM "users" WHERE "id" IN ($1, $2, $3)`,
+			Params: []interface{}{1, 2, 3},
+		}})
+	})
+
+	t.Run("should support composite keys", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.DeleteMany(ctx, NewTable("user_permissions", "user_id", "perm_id"), []map[string]interface{}{
+			{"user_id": 1, "perm_id": 2},
+			{"user_id": 1, "perm_id": 3},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  "DELETE FROM `user_permissions` WHERE (`user_id` = ? AND `perm_id` = ?) OR (`user_id` = ? AND `perm_id` = ?)",
+			Params: []interface{}{1, 2, 1, 3},
+		}})
+	})
+
+	t.Run("should soft delete the records with the softDelete modifier", func(t *testing.T) {
+		type softDeletedUser struct {
+			ID        int        `ksql:"id"`
+			DeletedAt *time.Time `ksql:"deleted_at,softDelete"`
+		}
+
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.DeleteMany(ctx, NewTable("users"), []softDeletedUser{{ID: 1}, {ID: 2}})
+		tt.AssertNoErr(t, err)
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 1)
+		tt.AssertEqual(t, statements[0].Query, `UPDATE "users" SET "deleted_at" = $1 WHERE ("id" IN ($2, $3)) AND "deleted_at" IS NULL`)
+		tt.AssertEqual(t, statements[0].Params[0].(time.Time).Location(), time.UTC)
+		tt.AssertEqual(t, statements[0].Params[1:], []interface{}{1, 2})
+	})
+
+	t.Run("should do nothing for empty slices", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.DeleteMany(ctx, NewTable("users"), []int{})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should report invalid inputs", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.DeleteMany(ctx, NewTable("users"), 42)
+		tt.AssertErrContains(t, err, "expected to receive a slice", "int")
+
+		_, err = dryRun.DeleteMany(ctx, NewTable("users", "user_id", "perm_id"), []int{1})
+		tt.AssertErrContains(t, err, "composite keys")
+	})
+}
+
+func TestUpdateMany(t *testing.T) {
+	ctx := context.Background()
+	age := 42
+
+	t.Run("should use UPDATE FROM on postgres", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		users := []bulkUser{
+			{ID: 1, Name: "Ana", Age: &age},
+			{ID: 2, Name: "Bia", Age: &age},
+		}
+		_, err = dryRun.UpdateMany(ctx, NewTable("users"), &users)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query: `UPDATE "users" SET "age" = ksql_values."age", "name" = ksql_values."name"` +
+				` FROM (SELECT "id", "age", "name" FROM "users" WHERE false UNION ALL SELECT $1, $2, $3 UNION ALL SELECT $4, $5, $6) ksql_values` +
+				` WHERE "users"."id" = ksql_values."id"`,
+			Params: []interface{}{1, 42, "Ana", 2, 42, "Bia"},
+		}})
+	})
+
+	t.Run("should use CASE expressions on the other dialects", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.UpdateMany(ctx, NewTable("users"), []*bulkUser{
+			{ID: 1, Name: "Ana"},
+			{ID: 2, Name: "Bia"},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  "UPDATE `users` SET `name` = CASE WHEN `id` = ? THEN ? WHEN `id` = ? THEN ? ELSE `name` END WHERE `id` IN (?, ?)",
+			Params: []interface{}{1, "Ana", 2, "Bia", 1, 2},
+		}})
+	})
+
+	t.Run("should call the update hooks of all records", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		users := []hookedUser{
+			{ID: 1, Email: "A@Foo.com"},
+			{ID: 2, Email: "B@Bar.com"},
+		}
+		_, err = dryRun.UpdateMany(ctx, NewTable("users"), users)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, users[0].Calls, []string{"BeforeUpdate", "AfterUpdate"})
+		tt.AssertEqual(t, users[1].Calls, []string{"BeforeUpdate", "AfterUpdate"})
+		tt.AssertEqual(t, dryRun.Statements()[0].Params, []interface{}{uint(1), "a@foo.com", uint(2), "b@bar.com", uint(1), uint(2)})
+	})
+
+	t.Run("should report records updating different attributes", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.UpdateMany(ctx, NewTable("users"), []bulkUser{
+			{ID: 1, Name: "Ana", Age: &age},
+			{ID: 2, Name: "Bia"},
+		})
+		tt.AssertErrContains(t, err, "same attributes", "index 1")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should report records without IDs", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.UpdateMany(ctx, NewTable("users"), []bulkUser{{Name: "Ana"}})
+		tt.AssertErrContains(t, err, "id")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
@@ -0,0 +1,560 @@
+package ksql
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores the results cached by the `ksql.Config.Cache` option,
+// the ksql.NewLRUCacheStore() function returns an in-memory implementation,
+// and for sharing the cache between processes it can be implemented
+// on top of a shared store, e.g. Redis:
+//
+//	type redisStore struct {
+//		client *redis.Client
+//	}
+//
+//	func (r redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+//		value, err := r.client.Get(ctx, key).Bytes()
+//		if err == redis.Nil {
+//			return nil, false, nil
+//		}
+//		return value, err == nil, err
+//	}
+//
+//	func (r redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+//		return r.client.Set(ctx, key, value, ttl).Err()
+//	}
+//
+// A zero ttl means the value never expires.
+type CacheStore interface {
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheConfig configures the cache of the results of the Query and
+// QueryOne methods, it is enabled by setting the Store attribute.
+//
+// The results are cached by the query, the params, the type of the
+// destination and the tenant of the context, and a query is only cached
+// if all the tables following its FROM and JOIN keywords, including on
+// subqueries, are listed on the Tables attribute. The cached results of
+// a table are invalidated by the writes on it made with the Insert, Upsert,
+// Patch, Update, PatchMap, UpdateReturning, Delete, DeleteReturning,
+// DeleteMany, UpdateMany, BulkInsert, CopyFrom, DequeueOne and DequeueMany
+// methods and by the Exec statements that mention its name. The writes
+// made inside transactions only invalidate the results after the commit.
+//
+// Since the invalidation is based on a version of each table saved on the
+// Store, the processes sharing a Store also invalidate each other's results,
+// but the writes made by other means, e.g. by triggers, are only seen after
+// the TTL.
+//
+// The queries running inside transactions, the queries using
+// `ksql.WithLock()` and the queries using `ksql.SkipCache()` always
+// read from the database, and the results that can't be encoded with
+// the encoding/gob package are never cached.
+type CacheConfig struct {
+	Store CacheStore
+
+	// TTL is the maximum duration of the cached results,
+	// zero means they only expire when invalidated
+	TTL time.Duration
+
+	// Tables are the names of the tables whose queries can be cached
+	Tables []string
+
+	// OnError is called with the errors returned by the Store, which
+	// never fail the operations, if not set they are ignored
+	OnError func(ctx context.Context, err error)
+}
+
+type skipCacheKey struct{}
+
+// SkipCache returns a copy of the input context that makes
+// the Query and QueryOne methods read from the database even
+// if the cache is enabled with `ksql.Config.Cache`
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func isCacheSkipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skipped
+}
+
+// The writes that invalidate the cached results of their tables
+var cacheInvalidatingMethods = map[string]bool{
+	"Insert":           true,
+	"InsertFields":     true,
+	"Upsert":           true,
+	"Patch":            true,
+	"PatchWithResult":  true,
+	"UpdateFields":     true,
+	"Update":           true,
+	"PatchMap":         true,
+	"UpdateReturning":  true,
+	"Delete":           true,
+	"DeleteWithResult": true,
+	"DeleteReturning":  true,
+	"DeleteMany":       true,
+	"UpdateMany":       true,
+	"BulkInsert":       true,
+	"CopyFrom":         true,
+	"DequeueOne":       true,
+	"DequeueMany":      true,
+	"WriteBlob":        true,
+}
+
+type queryCache struct {
+	store   CacheStore
+	ttl     time.Duration
+	tables  map[string]bool
+	onError func(ctx context.Context, err error)
+}
+
+func newQueryCache(config CacheConfig) *queryCache {
+	tables := map[string]bool{}
+	for _, table := range config.Tables {
+		tables[strings.ToLower(table)] = true
+	}
+
+	return &queryCache{
+		store:   config.Store,
+		ttl:     config.TTL,
+		tables:  tables,
+		onError: config.OnError,
+	}
+}
+
+// useCache checks if a query can use the cache
+func (c DB) useCache(ctx context.Context) bool {
+	return c.cache != nil && !isCacheSkipped(ctx) && c.isShareableRead(ctx)
+}
+
+// isShareableRead checks if the result of a query can be shared with other
+// callers, which is never the case inside transactions, since they might
+// see uncommitted rows, or for the queries locking rows.
+func (c DB) isShareableRead(ctx context.Context) bool {
+	if _, isTx := c.db.(Tx); isTx {
+		return false
+	}
+
+	_, locked := ctx.Value(lockKey{}).(LockMode)
+	return !locked
+}
+
+// read saves the result of the query on the dest argument from the cache
+// or, if it is not cached, by running the input query function, whose
+// result is then cached.
+func (q *queryCache) read(
+	ctx context.Context,
+	method string,
+	dest interface{},
+	query string,
+	params []interface{},
+	runQuery func(ctx context.Context) error,
+) error {
+	ctx = SkipCache(ctx)
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return runQuery(ctx)
+	}
+
+	key, cacheable := q.buildKey(ctx, method, dest, query, params)
+	if !cacheable {
+		return runQuery(ctx)
+	}
+
+	value, found, err := q.store.Get(ctx, key)
+	if err != nil {
+		q.reportError(ctx, err)
+	} else if found {
+		err := decodeReadResult(value, destValue)
+		if err == nil {
+			return nil
+		}
+		q.reportError(ctx, fmt.Errorf("unable to decode the cached result: %s", err))
+	}
+
+	err = runQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	value, err = encodeReadResult(destValue)
+	if err != nil {
+		// The types gob can't encode are never cached:
+		return nil
+	}
+
+	err = q.store.Set(ctx, key, value, q.ttl)
+	if err != nil {
+		q.reportError(ctx, err)
+	}
+
+	return nil
+}
+
+// buildKey returns the key of the cached result of a query, which
+// includes the current versions of the tables it reads, or false if
+// the query can't be cached.
+func (q *queryCache) buildKey(
+	ctx context.Context,
+	method string,
+	dest interface{},
+	query string,
+	params []interface{},
+) (key string, cacheable bool) {
+	tables := queryTables(query)
+	if len(tables) == 0 {
+		return "", false
+	}
+	for _, table := range tables {
+		if !q.tables[table] {
+			return "", false
+		}
+	}
+
+	readKey, ok := buildReadKey(ctx, method, dest, query, params)
+	if !ok {
+		return "", false
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00", readKey)
+
+	for _, table := range tables {
+		version, err := q.tableVersion(ctx, table)
+		if err != nil {
+			q.reportError(ctx, err)
+			return "", false
+		}
+		fmt.Fprintf(hash, "%s=%s\x00", table, version)
+	}
+
+	return "ksql:query:" + hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// tableVersion returns the current version of the table,
+// creating a new one if it is not on the store
+func (q *queryCache) tableVersion(ctx context.Context, table string) (string, error) {
+	value, found, err := q.store.Get(ctx, tableVersionKey(table))
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return string(value), nil
+	}
+
+	// A new version is created even if the old one was only evicted,
+	// so the results cached with the old version are never used again:
+	return q.newTableVersion(ctx, table)
+}
+
+func (q *queryCache) newTableVersion(ctx context.Context, table string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	version := hex.EncodeToString(b[:])
+
+	return version, q.store.Set(ctx, tableVersionKey(table), []byte(version), 0)
+}
+
+// buildReadKey returns a hash identifying the result of a query, i.e. of
+// the query, its params, the type of its destination and the options of
+// the context that change the query, or false if the params can't be
+// serialized.
+func buildReadKey(
+	ctx context.Context,
+	method string,
+	dest interface{},
+	query string,
+	params []interface{},
+) (key string, ok bool) {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+
+	tenantID, _ := TenantFromContext(ctx)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%T\x00%s\x00%s\x00", method, dest, strings.Join(strings.Fields(query), " "), encodedParams)
+	fmt.Fprintf(hash, "%#v\x00%t\x00%t\x00", tenantID, isCrossTenant(ctx), isUnscoped(ctx))
+
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// encodeReadResult serializes the value pointed by
+// the destination of a query with encoding/gob
+func encodeReadResult(destValue reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).EncodeValue(destValue.Elem())
+	return buf.Bytes(), err
+}
+
+// decodeReadResult decodes a value serialized by encodeReadResult on
+// a new value before saving it on the destination, since gob doesn't
+// overwrite the attributes that are zero on the serialized value.
+func decodeReadResult(value []byte, destValue reflect.Value) error {
+	decoded := reflect.New(destValue.Type().Elem())
+	err := gob.NewDecoder(bytes.NewReader(value)).DecodeValue(decoded)
+	if err != nil {
+		return err
+	}
+
+	destValue.Elem().Set(decoded.Elem())
+	return nil
+}
+
+func tableVersionKey(table string) string {
+	return "ksql:table:" + table
+}
+
+// invalidateCache invalidates the cached results of the tables written
+// by the input operation, if it is a write, after the current
+// transaction commits or immediately if there is no transaction.
+func (c DB) invalidateCache(ctx context.Context, op Operation) {
+	if c.cache == nil {
+		return
+	}
+
+	var tables []string
+	switch {
+	case op.Method == "Exec":
+		for _, token := range tokenizeQuery(op.Query) {
+			if token.isIdentifier && c.cache.tables[token.tableName()] {
+				tables = append(tables, token.tableName())
+			}
+		}
+	case cacheInvalidatingMethods[op.Method]:
+		if c.cache.tables[strings.ToLower(op.Table)] {
+			tables = append(tables, strings.ToLower(op.Table))
+		}
+	}
+	if len(tables) == 0 {
+		return
+	}
+
+	invalidate := func(ctx context.Context) {
+		for _, table := range tables {
+			if _, err := c.cache.newTableVersion(ctx, table); err != nil {
+				c.cache.reportError(ctx, err)
+			}
+		}
+	}
+
+	if err := c.contextTx(ctx).OnCommit(invalidate); err != nil {
+		invalidate(ctx)
+	}
+}
+
+func (q *queryCache) reportError(ctx context.Context, err error) {
+	if q.onError != nil {
+		q.onError(ctx, fmt.Errorf("ksql: cache error: %s", err))
+	}
+}
+
+// queryTables returns the lowercase names of the tables following the
+// FROM and JOIN keywords of the query, including the ones of subqueries
+// and of the comma separated lists of tables, without their schemas.
+func queryTables(query string) []string {
+	var tables []string
+
+	// inFrom tracks if the FROM clause of each level of parentheses
+	// is still open, i.e. if a comma would introduce another table:
+	inFrom := []bool{false}
+	expectTable := false
+	for _, token := range tokenizeQuery(query) {
+		depth := len(inFrom) - 1
+		switch {
+		case token.text == "(":
+			inFrom = append(inFrom, false)
+			expectTable = false
+		case token.text == ")":
+			if depth > 0 {
+				inFrom = inFrom[:depth]
+			}
+			expectTable = false
+		case token.text == ",":
+			expectTable = inFrom[depth]
+		case !token.isIdentifier:
+		case expectTable:
+			tables = append(tables, token.tableName())
+			expectTable = false
+		default:
+			switch strings.ToUpper(token.text) {
+			case "FROM":
+				inFrom[depth] = true
+				expectTable = true
+			case "JOIN":
+				expectTable = true
+			case "SELECT", "WHERE", "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET",
+				"FETCH", "FOR", "UNION", "INTERSECT", "EXCEPT", "RETURNING", "SET", "VALUES":
+				inFrom[depth] = false
+			}
+		}
+	}
+
+	return tables
+}
+
+type queryToken struct {
+	text         string
+	isIdentifier bool
+}
+
+// tableName returns the token as a lowercase
+// table name without quotes and without schema
+func (t queryToken) tableName() string {
+	name := t.text
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "\"`[]"))
+}
+
+// tokenizeQuery splits the query into identifiers, which might be quoted
+// and contain dots, and the `(`, `)` and `,` characters, ignoring the
+// string literals, the comments and the other characters.
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'':
+			end := strings.IndexByte(query[i+1:], '\'')
+			if end == -1 {
+				return tokens
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return tokens
+			}
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end == -1 {
+				return tokens
+			}
+			i += end + 1
+
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, queryToken{text: string(c)})
+
+		case c == '"' || c == '`' || c == '[' || isIdentifierChar(c):
+			start := i
+			for i < len(query) {
+				if quote := query[i]; quote == '"' || quote == '`' || quote == '[' {
+					closing := quote
+					if quote == '[' {
+						closing = ']'
+					}
+					end := strings.IndexByte(query[i+1:], closing)
+					if end == -1 {
+						return tokens
+					}
+					i += end + 2
+				} else if isIdentifierChar(query[i]) || query[i] == '.' {
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, queryToken{text: query[start:i], isIdentifier: true})
+			i--
+		}
+	}
+
+	return tokens
+}
+
+// NewLRUCacheStore returns an in-memory ksql.CacheStore that keeps up to
+// maxEntries values, evicting the least recently used ones when it is full.
+func NewLRUCacheStore(maxEntries int) CacheStore {
+	return &lruCacheStore{
+		maxEntries: maxEntries,
+		entries:    list.New(),
+		index:      map[string]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+type lruCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    *list.List
+	index      map[string]*list.Element
+	now        func() time.Time
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Get implements the ksql.CacheStore interface
+func (s *lruCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, found := s.index[key]
+	if !found {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !s.now().Before(entry.expiresAt) {
+		s.entries.Remove(element)
+		delete(s.index, key)
+		return nil, false, nil
+	}
+
+	s.entries.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set implements the ksql.CacheStore interface
+func (s *lruCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.now().Add(ttl)
+	}
+
+	if element, found := s.index[key]; found {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		s.entries.MoveToFront(element)
+		return nil
+	}
+
+	s.index[key] = s.entries.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for s.maxEntries > 0 && s.entries.Len() > s.maxEntries {
+		oldest := s.entries.Back()
+		s.entries.Remove(oldest)
+		delete(s.index, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestQueryTables(t *testing.T) {
+	tests := []struct {
+		desc           string
+		query          string
+		expectedTables []string
+	}{
+		{
+			desc:           "should find the table of the FROM clause",
+			query:          "FROM users WHERE age > ?",
+			expectedTables: []string{"users"},
+		},
+		{
+			desc:           "should find the joined tables",
+			query:          `SELECT u.id FROM "public"."Users" AS u JOIN posts p ON p.user_id = u.id LEFT JOIN [dbo].[tags] t ON t.id = p.tag_id`,
+			expectedTables: []string{"users", "posts", "tags"},
+		},
+		{
+			desc:           "should find the comma separated tables",
+			query:          "SELECT a.id, b.id FROM a_table a, b_table b WHERE a.id = b.id",
+			expectedTables: []string{"a_table", "b_table"},
+		},
+		{
+			desc:           "should find the tables of subqueries",
+			query:          "FROM users WHERE id IN (SELECT user_id FROM posts WHERE title = 'FROM fake') ORDER BY id",
+			expectedTables: []string{"users", "posts"},
+		},
+		{
+			desc:           "should ignore the comments",
+			query:          "FROM users -- JOIN posts\n/* JOIN tags */ WHERE id = 1",
+			expectedTables: []string{"users"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tt.AssertEqual(t, queryTables(test.query), test.expectedTables)
+		})
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	type user struct {
+		ID   int    `ksql:"id"`
+		Name string `ksql:"name"`
+	}
+
+	newDB := func(t *testing.T, queries *[]string) DB {
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, params ...interface{}) (Result, error) {
+				return NewMockResult(1, 1), nil
+			},
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				*queries = append(*queries, query)
+				return &fakeScalarRows{values: []interface{}{1, 2}}, nil
+			},
+		}, "sqlite3", Config{
+			Cache: CacheConfig{
+				Store:  NewLRUCacheStore(100),
+				Tables: []string{"users"},
+			},
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	ctx := context.Background()
+
+	t.Run("should read the cached results", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries)
+
+		for i := 0; i < 2; i++ {
+			var ids []int
+			err := db.Query(ctx, &ids, "SELECT id FROM users WHERE age > ?", 18)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, ids, []int{1, 2})
+		}
+		tt.AssertEqual(t, len(queries), 1)
+
+		var ids []int
+		err := db.Query(ctx, &ids, "SELECT id FROM users WHERE age > ?", 21)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(queries), 2)
+
+		var id int
+		err = db.QueryOne(WithTenant(ctx, 42), &id, "SELECT id FROM users WHERE age > ?", 18)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, id, 1)
+		tt.AssertEqual(t, len(queries), 3)
+	})
+
+	t.Run("should invalidate the results after the writes", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries)
+
+		query := func() {
+			var ids []int
+			err := db.Query(ctx, &ids, "SELECT id FROM users")
+			tt.AssertNoErr(t, err)
+		}
+
+		query()
+		err := db.Insert(ctx, NewTable("users"), &user{Name: "Jane"})
+		tt.AssertNoErr(t, err)
+		query()
+		tt.AssertEqual(t, len(queries), 2)
+
+		_, err = db.Exec(ctx, "UPDATE users SET name = 'John'")
+		tt.AssertNoErr(t, err)
+		query()
+		tt.AssertEqual(t, len(queries), 3)
+
+		_, err = db.Exec(ctx, "UPDATE posts SET title = 'Title'")
+		tt.AssertNoErr(t, err)
+		query()
+		tt.AssertEqual(t, len(queries), 3)
+	})
+
+	t.Run("should not cache the queries of unregistered tables", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries)
+
+		for i := 0; i < 2; i++ {
+			var ids []int
+			err := db.Query(ctx, &ids, "SELECT u.id FROM users u JOIN posts p ON p.user_id = u.id")
+			tt.AssertNoErr(t, err)
+		}
+		tt.AssertEqual(t, len(queries), 2)
+	})
+
+	t.Run("should skip the cache when requested", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries)
+
+		for i := 0; i < 2; i++ {
+			var ids []int
+			err := db.Query(SkipCache(ctx), &ids, "SELECT id FROM users")
+			tt.AssertNoErr(t, err)
+		}
+		tt.AssertEqual(t, len(queries), 2)
+	})
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should evict the least recently used entries", func(t *testing.T) {
+		store := NewLRUCacheStore(2)
+
+		tt.AssertNoErr(t, store.Set(ctx, "a", []byte("1"), 0))
+		tt.AssertNoErr(t, store.Set(ctx, "b", []byte("2"), 0))
+
+		_, found, _ := store.Get(ctx, "a")
+		tt.AssertEqual(t, found, true)
+
+		tt.AssertNoErr(t, store.Set(ctx, "c", []byte("3"), 0))
+
+		_, found, _ = store.Get(ctx, "b")
+		tt.AssertEqual(t, found, false)
+
+		value, found, _ := store.Get(ctx, "a")
+		tt.AssertEqual(t, found, true)
+		tt.AssertEqual(t, string(value), "1")
+	})
+
+	t.Run("should expire the entries after the ttl", func(t *testing.T) {
+		now := time.Now()
+		store := NewLRUCacheStore(10).(*lruCacheStore)
+		store.now = func() time.Time { return now }
+
+		tt.AssertNoErr(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+
+		_, found, _ := store.Get(ctx, "a")
+		tt.AssertEqual(t, found, true)
+
+		now = now.Add(time.Minute)
+		_, found, _ = store.Get(ctx, "a")
+		tt.AssertEqual(t, found, false)
+	})
+}
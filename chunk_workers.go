@@ -0,0 +1,164 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// chunkWorkers is a bounded pool of goroutines used by
+// QueryChunks for calling the ForEachChunk callback
+// in parallel when the ChunkParser.Workers is set.
+type chunkWorkers struct {
+	fn     reflect.Value
+	chunks chan reflect.Value
+	cancel context.CancelFunc
+
+	// free contains the chunks already processed by the workers,
+	// so they can be reused instead of allocating new ones:
+	free      chan reflect.Value
+	chunkType reflect.Type
+	chunkSize int
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	// done is closed as soon as any of the callbacks fail
+	// so that we stop dispatching new chunks:
+	done    chan struct{}
+	errOnce sync.Once
+	err     error
+}
+
+func startChunkWorkers(
+	numWorkers int,
+	fn reflect.Value,
+	cancel context.CancelFunc,
+	chunkType reflect.Type,
+	chunkSize int,
+) *chunkWorkers {
+	w := &chunkWorkers{
+		fn:     fn,
+		chunks: make(chan reflect.Value),
+		cancel: cancel,
+		done:   make(chan struct{}),
+
+		// There are never more than one chunk per worker
+		// plus the one being filled with the next rows:
+		free:      make(chan reflect.Value, numWorkers+1),
+		chunkType: chunkType,
+		chunkSize: chunkSize,
+	}
+
+	w.wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go w.run()
+	}
+
+	return w
+}
+
+func (w *chunkWorkers) run() {
+	defer w.wg.Done()
+	for chunk := range w.chunks {
+		select {
+		case <-w.done:
+			// Skip the remaining chunks after an error:
+			continue
+		default:
+		}
+
+		err, _ := w.fn.Call([]reflect.Value{chunk})[0].Interface().(error)
+		if err != nil {
+			w.fail(err)
+		}
+
+		select {
+		case w.free <- chunk:
+		default:
+		}
+	}
+}
+
+// newChunk returns one of the chunks already processed by
+// the workers if there is one, or allocates a new one.
+func (w *chunkWorkers) newChunk() reflect.Value {
+	select {
+	case chunk := <-w.free:
+		return chunk
+	default:
+		return reflect.MakeSlice(w.chunkType, 0, w.chunkSize)
+	}
+}
+
+func (w *chunkWorkers) fail(err error) {
+	w.errOnce.Do(func() {
+		w.err = err
+		close(w.done)
+
+		// Cancelling the context will interrupt the query
+		// that is still loading new chunks from the database:
+		w.cancel()
+	})
+}
+
+// send dispatches a chunk to one of the workers, it returns false
+// if the chunk was not sent because one of the workers failed.
+func (w *chunkWorkers) send(chunk reflect.Value) bool {
+	select {
+	case w.chunks <- chunk:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// wait stops the workers and waits for the chunks already
+// dispatched to be processed, returning the first error
+// returned by the ForEachChunk callback if any.
+//
+// It is safe to call wait more than once.
+func (w *chunkWorkers) wait() error {
+	w.closeOnce.Do(func() {
+		close(w.chunks)
+	})
+	w.wg.Wait()
+	return w.err
+}
+
+// finishChunkWorkers dispatches the last partially filled chunk
+// if necessary and then waits for all the workers to finish.
+//
+// Errors returned by the ForEachChunk callback take precedence over
+// the errors from the rows, since these are probably caused by the
+// cancellation of the context after the callback failed.
+func finishChunkWorkers(workers *chunkWorkers, rows Rows, chunk reflect.Value, idx int) error {
+	closeErr := rows.Close()
+	rowsErr := rows.Err()
+
+	// If no rows were found or idx was reset to 0
+	// on the last iteration skip this last chunk:
+	if closeErr == nil && rowsErr == nil && idx > 0 {
+		workers.send(chunk.Slice(0, idx))
+	}
+
+	if closeErr != nil {
+		return workers.waitOr(closeErr)
+	}
+
+	return workers.waitOr(rowsErr)
+}
+
+// waitOr waits for the workers to finish and returns the error
+// from the ForEachChunk callbacks if there is one, or the
+// input error otherwise.
+func (w *chunkWorkers) waitOr(err error) error {
+	if workerErr := w.wait(); workerErr != nil {
+		if workerErr == ErrAbortIteration {
+			return nil
+		}
+		return workerErr
+	}
+
+	return err
+}
@@ -0,0 +1,209 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by all the operations while the circuit
+// breaker is open, i.e. after too many consecutive failures, so the
+// database has some time to recover instead of receiving more requests.
+var ErrCircuitOpen = errors.New("ksql: circuit breaker is open")
+
+// CircuitBreakerConfig describes when the circuit breaker opens,
+// rejecting all operations, and when it starts letting them through again.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures
+	// that opens the circuit, zero disables the circuit breaker
+	FailureThreshold int
+
+	// OpenTimeout is how long the circuit stays open before letting
+	// a single probe operation through, if it succeeds the circuit
+	// closes, otherwise it opens again, it defaults to 10s
+	OpenTimeout time.Duration
+
+	// IsFailure optionally replaces the default classification of
+	// which errors count as failures, by default only the errors
+	// caused by unreachable databases or broken connections count,
+	// so errors like constraint violations never open the circuit
+	IsFailure func(err error) bool
+}
+
+// SetDefaultValues sets the default values of the CircuitBreakerConfig if unset
+func (c *CircuitBreakerConfig) SetDefaultValues() {
+	if c.OpenTimeout == 0 {
+		c.OpenTimeout = 10 * time.Second
+	}
+
+	if c.IsFailure == nil {
+		c.IsFailure = isConnectionError
+	}
+}
+
+// The possible states of the circuit breaker,
+// as reported by DB.HealthCheck()
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// circuitBreaker holds the state shared by all
+// the copies of the DB using the same breaker.
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+
+	// now is only replaced on tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	config.SetDefaultValues()
+	return &circuitBreaker{
+		config: config,
+		state:  CircuitClosed,
+		now:    time.Now,
+	}
+}
+
+// allow checks if the operation can run, moving the circuit
+// to half-open if the open timeout has passed, in which case
+// only the first caller is allowed to run as a probe.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// A probe is already running:
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// done records the result of an operation allowed by allow()
+func (b *circuitBreaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || !b.config.IsFailure(err) {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *circuitBreaker) currentState() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// circuitBreakerAdapter wraps a DBAdapter rejecting all the
+// operations while the circuit breaker is open.
+type circuitBreakerAdapter struct {
+	DBAdapter
+
+	breaker *circuitBreaker
+}
+
+// ExecContext implements the DBAdapter interface
+func (c circuitBreakerAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := c.DBAdapter.ExecContext(ctx, query, args...)
+	c.breaker.done(err)
+	return result, err
+}
+
+// QueryContext implements the DBAdapter interface
+func (c circuitBreakerAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.DBAdapter.QueryContext(ctx, query, args...)
+	c.breaker.done(err)
+	return rows, err
+}
+
+// BeginTx implements the TxBeginner interface
+func (c circuitBreakerAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := c.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	c.breaker.done(err)
+	return tx, err
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (c circuitBreakerAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := c.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	tx, err := txBeginner.BeginTxWithOptions(ctx, opts)
+	c.breaker.done(err)
+	return tx, err
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (c circuitBreakerAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := c.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (c circuitBreakerAdapter) Close() error {
+	closer, ok := c.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func (c circuitBreakerAdapter) unwrapAdapter() DBAdapter {
+	return c.DBAdapter
+}
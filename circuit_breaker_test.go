@@ -0,0 +1,103 @@
+package ksql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	newDB := func(t *testing.T, errs *[]error, calls *int) (DB, *time.Time) {
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				*calls++
+				if len(*errs) == 0 {
+					return nil, nil
+				}
+				return nil, shiftErrSlice(errs)
+			},
+		}, "postgres", Config{
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 2,
+				OpenTimeout:      time.Minute,
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		now := time.Now()
+		db.breaker.now = func() time.Time {
+			return now
+		}
+		return db, &now
+	}
+
+	connErr := fmt.Errorf("fake wrapper: %w", driver.ErrBadConn)
+
+	t.Run("should open after the failure threshold and close after a successful probe", func(t *testing.T) {
+		errs := []error{connErr, connErr}
+		var calls int
+		db, now := newDB(t, &errs, &calls)
+		ctx := context.Background()
+
+		_, err := db.Exec(ctx, "fake query")
+		tt.AssertErrContains(t, err, "bad connection")
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitClosed)
+
+		_, err = db.Exec(ctx, "fake query")
+		tt.AssertErrContains(t, err, "bad connection")
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitOpen)
+
+		_, err = db.Exec(ctx, "fake query")
+		tt.AssertEqual(t, err, ErrCircuitOpen)
+		tt.AssertEqual(t, calls, 2)
+
+		*now = now.Add(2 * time.Minute)
+
+		_, err = db.Exec(ctx, "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, 3)
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitClosed)
+	})
+
+	t.Run("should open again if the probe fails", func(t *testing.T) {
+		errs := []error{connErr, connErr, connErr}
+		var calls int
+		db, now := newDB(t, &errs, &calls)
+		ctx := context.Background()
+
+		db.Exec(ctx, "fake query")
+		db.Exec(ctx, "fake query")
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitOpen)
+
+		*now = now.Add(2 * time.Minute)
+
+		_, err := db.Exec(ctx, "fake query")
+		tt.AssertErrContains(t, err, "bad connection")
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitOpen)
+
+		_, err = db.Exec(ctx, "fake query")
+		tt.AssertEqual(t, err, ErrCircuitOpen)
+		tt.AssertEqual(t, calls, 3)
+	})
+
+	t.Run("should not count errors that are not connection errors", func(t *testing.T) {
+		errs := []error{
+			fmt.Errorf("fake constraint violation"),
+			fmt.Errorf("fake constraint violation"),
+			fmt.Errorf("fake constraint violation"),
+		}
+		var calls int
+		db, _ := newDB(t, &errs, &calls)
+		ctx := context.Background()
+
+		for i := 0; i < 3; i++ {
+			_, err := db.Exec(ctx, "fake query")
+			tt.AssertErrContains(t, err, "fake constraint violation")
+		}
+		tt.AssertEqual(t, db.breaker.currentState(), CircuitClosed)
+	})
+}
@@ -0,0 +1,180 @@
+package ksql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Clauses is a minimal helper for composing the WHERE, ORDER BY,
+// LIMIT and OFFSET clauses of a query when some of the filters are
+// optional, it is meant to be appended to a raw query, e.g.:
+//
+//	clauses := ksql.Where("age > ?", 18).
+//		AndIf(name != "", "name = ?", name).
+//		OrderBy("name").
+//		Limit(10)
+//
+//	query, params, err := clauses.Build(db.Dialect())
+//	err = db.Query(ctx, &users, "FROM users "+query, params...)
+//
+// The conditions use `?` as the placeholder for all databases, and it is
+// converted to the placeholder of the dialect on Build, so question marks
+// that are not placeholders should be sent as params instead.
+//
+// The zero value is valid and renders an empty string,
+// and all the methods return copies of the Clauses.
+type Clauses struct {
+	conditions []condition
+	orderBy    string
+	limit      int
+	offset     int
+}
+
+type condition struct {
+	operator string
+	cond     string
+	params   []interface{}
+}
+
+// Where starts a new Clauses with the input condition
+func Where(cond string, params ...interface{}) Clauses {
+	return Clauses{}.And(cond, params...)
+}
+
+// And adds a condition to the WHERE clause joined with `AND`
+func (c Clauses) And(cond string, params ...interface{}) Clauses {
+	return c.add("AND", cond, params)
+}
+
+// AndIf works like And but only adds the condition if `ok` is true,
+// which is useful for optional filters
+func (c Clauses) AndIf(ok bool, cond string, params ...interface{}) Clauses {
+	if !ok {
+		return c
+	}
+	return c.And(cond, params...)
+}
+
+// Or adds a condition to the WHERE clause joined with `OR`,
+// each condition is wrapped in parentheses, but note that
+// `AND` still has precedence over `OR`
+func (c Clauses) Or(cond string, params ...interface{}) Clauses {
+	return c.add("OR", cond, params)
+}
+
+func (c Clauses) add(operator string, cond string, params []interface{}) Clauses {
+	c.conditions = append(append([]condition{}, c.conditions...), condition{
+		operator: operator,
+		cond:     cond,
+		params:   params,
+	})
+	return c
+}
+
+// OrderBy sets the ORDER BY clause, e.g. `OrderBy("name, id DESC")`
+func (c Clauses) OrderBy(orderBy string) Clauses {
+	c.orderBy = orderBy
+	return c
+}
+
+// Limit sets the maximum number of rows returned
+func (c Clauses) Limit(limit int) Clauses {
+	c.limit = limit
+	return c
+}
+
+// Offset sets the number of rows skipped
+func (c Clauses) Offset(offset int) Clauses {
+	c.offset = offset
+	return c
+}
+
+// Build renders the clauses using the placeholders and the LIMIT syntax of
+// the input dialect, i.e. `OFFSET ... ROWS FETCH NEXT ... ROWS ONLY`
+// for SQL Server and Oracle and `LIMIT ... OFFSET ...` for the others.
+func (c Clauses) Build(dialect Dialect) (query string, params []interface{}, err error) {
+	return c.buildWithOffset(dialect, 0)
+}
+
+// buildWithOffset works like Build but the placeholders start
+// from `offset`, which is necessary for appending the clauses
+// to a query that already has params.
+func (c Clauses) buildWithOffset(dialect Dialect, offset int) (query string, params []interface{}, err error) {
+	var parts []string
+
+	if len(c.conditions) > 0 {
+		var b strings.Builder
+		b.WriteString("WHERE ")
+		for i, cond := range c.conditions {
+			if i > 0 {
+				b.WriteString(" " + cond.operator + " ")
+			}
+
+			rendered, err := replacePlaceholders(dialect, cond.cond, offset+len(params), len(cond.params))
+			if err != nil {
+				return "", nil, err
+			}
+			b.WriteString("(" + rendered + ")")
+			params = append(params, cond.params...)
+		}
+		parts = append(parts, b.String())
+	}
+
+	if c.orderBy != "" {
+		parts = append(parts, "ORDER BY "+c.orderBy)
+	}
+
+	switch dialect.DriverName() {
+	case "sqlserver", "oracle":
+		if c.limit > 0 || c.offset > 0 {
+			if c.orderBy == "" && dialect.DriverName() == "sqlserver" {
+				return "", nil, fmt.Errorf("ksql: SQL Server requires an ORDER BY clause for using LIMIT or OFFSET")
+			}
+			parts = append(parts, "OFFSET "+strconv.Itoa(c.offset)+" ROWS")
+		}
+		if c.limit > 0 {
+			parts = append(parts, "FETCH NEXT "+strconv.Itoa(c.limit)+" ROWS ONLY")
+		}
+	default:
+		if c.limit > 0 {
+			parts = append(parts, "LIMIT "+strconv.Itoa(c.limit))
+		}
+		if c.offset > 0 {
+			parts = append(parts, "OFFSET "+strconv.Itoa(c.offset))
+		}
+	}
+
+	return strings.Join(parts, " "), params, nil
+}
+
+// replacePlaceholders converts the `?` placeholders of the
+// condition to the ones of the dialect, starting from `offset`
+func replacePlaceholders(dialect Dialect, cond string, offset int, numParams int) (string, error) {
+	var b strings.Builder
+	count := 0
+	for _, r := range cond {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		b.WriteString(dialect.Placeholder(offset + count))
+		count++
+	}
+
+	if count != numParams {
+		return "", fmt.Errorf(
+			"ksql: the condition `%s` has %d placeholders but received %d params",
+			cond, count, numParams,
+		)
+	}
+
+	return b.String(), nil
+}
+
+// Dialect returns the Dialect used by the DB, which
+// is useful for building queries, e.g. with ksql.Clauses
+func (c DB) Dialect() Dialect {
+	return c.dialect
+}
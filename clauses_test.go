@@ -0,0 +1,97 @@
+package ksql
+
+import (
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestClauses(t *testing.T) {
+	tests := []struct {
+		desc           string
+		driver         string
+		clauses        Clauses
+		expectedQuery  string
+		expectedParams []interface{}
+		expectedErr    []string
+	}{
+		{
+			desc:   "should build all clauses for postgres",
+			driver: "postgres",
+			clauses: Where("age > ?", 18).
+				And("name LIKE ?", "B%").
+				AndIf(false, "ignored = ?", 42).
+				Or("id IN (?)", []int{1, 2}).
+				OrderBy("name, id DESC").
+				Limit(10).
+				Offset(20),
+			expectedQuery:  `WHERE (age > $1) AND (name LIKE $2) OR (id IN ($3)) ORDER BY name, id DESC LIMIT 10 OFFSET 20`,
+			expectedParams: []interface{}{18, "B%", []int{1, 2}},
+		},
+		{
+			desc:           "should use the question mark placeholders for sqlite",
+			driver:         "sqlite3",
+			clauses:        Clauses{}.AndIf(true, "age BETWEEN ? AND ?", 18, 30).Limit(5),
+			expectedQuery:  `WHERE (age BETWEEN ? AND ?) LIMIT 5`,
+			expectedParams: []interface{}{18, 30},
+		},
+		{
+			desc:           "should use the FETCH syntax for sqlserver",
+			driver:         "sqlserver",
+			clauses:        Where("age > ?", 18).OrderBy("id").Limit(10).Offset(20),
+			expectedQuery:  `WHERE (age > @p1) ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+			expectedParams: []interface{}{18},
+		},
+		{
+			desc:          "should use the FETCH syntax for oracle",
+			driver:        "oracle",
+			clauses:       Clauses{}.Limit(10),
+			expectedQuery: `OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY`,
+		},
+		{
+			desc:          "should render an empty string for empty clauses",
+			driver:        "postgres",
+			clauses:       Clauses{}.AndIf(false, "age > ?", 18),
+			expectedQuery: ``,
+		},
+		{
+			desc:        "should report placeholders that don't match the params",
+			driver:      "postgres",
+			clauses:     Where("age > ? AND age < ?", 18),
+			expectedErr: []string{"2 placeholders", "1 params"},
+		},
+		{
+			desc:        "should require ORDER BY for paginating on sqlserver",
+			driver:      "sqlserver",
+			clauses:     Clauses{}.Limit(10),
+			expectedErr: []string{"ORDER BY"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dialect, err := GetDriverDialect(test.driver)
+			tt.AssertNoErr(t, err)
+
+			query, params, err := test.clauses.Build(dialect)
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+			tt.AssertEqual(t, params, test.expectedParams)
+		})
+	}
+
+	t.Run("should not modify the original clauses", func(t *testing.T) {
+		base := Where("age > ?", 18)
+		_ = base.And("name = ?", "fake")
+
+		dialect, err := GetDriverDialect("sqlite3")
+		tt.AssertNoErr(t, err)
+
+		query, _, err := base.Build(dialect)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, `WHERE (age > ?)`)
+	})
+}
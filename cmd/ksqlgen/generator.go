@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// generatorArgs are the options of a single ksqlgen run
+type generatorArgs struct {
+	// Dir is the directory of the package containing the struct
+	Dir string
+
+	// TypeName is the name of the struct the code is generated for
+	TypeName string
+
+	// Table is optional, if set a ksql.Table is generated for it
+	Table string
+
+	// IDColumns are the ID columns of the table, the default is "id"
+	IDColumns []string
+}
+
+// column describes one of the attributes of the struct with a `ksql` tag
+type column struct {
+	Name      string
+	FieldName string
+	ConstName string
+	IsPtr     bool
+}
+
+type templateData struct {
+	Package   string
+	TypeName  string
+	Receiver  string
+	Table     string
+	TableVar  string
+	IDColumns []string
+	Columns   []column
+}
+
+// generate parses the Go files of the package and returns
+// the formatted source code generated for the struct
+func generate(args generatorArgs) ([]byte, error) {
+	fset := token.NewFileSet()
+	filenames, err := filepath.Glob(filepath.Join(args.Dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+
+	var pkgName string
+	var structType *ast.StructType
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		pkgName = file.Name.Name
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok || spec.Name.Name != args.TypeName {
+				return structType == nil
+			}
+
+			structType, _ = spec.Type.(*ast.StructType)
+			return false
+		})
+		if structType != nil {
+			break
+		}
+	}
+	if structType == nil {
+		return nil, fmt.Errorf("ksqlgen: struct %s not found on directory %s", args.TypeName, args.Dir)
+	}
+
+	columns, err := parseColumns(args.TypeName, structType)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("ksqlgen: struct %s has no attributes with the `ksql` tag", args.TypeName)
+	}
+
+	idColumns := args.IDColumns
+	if len(idColumns) == 0 {
+		idColumns = []string{"id"}
+	}
+
+	data := templateData{
+		Package:  pkgName,
+		TypeName: args.TypeName,
+		Receiver: strings.ToLower(args.TypeName[:1]),
+		Table:    args.Table,
+		TableVar: toCamelCase(args.Table) + "Table",
+		Columns:  columns,
+	}
+	// The default ID column is omitted for readability:
+	if len(idColumns) != 1 || idColumns[0] != "id" {
+		data.IDColumns = idColumns
+	}
+
+	var buf bytes.Buffer
+	err = codeTemplate.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("ksqlgen: unable to format the generated code: %s", err)
+	}
+
+	return code, nil
+}
+
+// parseColumns reads the `ksql` tags of the struct following the same
+// rules of the ksql runtime, and returns an error for the features
+// that can't be generated, i.e. flattened and nested structs.
+func parseColumns(typeName string, structType *ast.StructType) ([]column, error) {
+	var columns []column
+	names := map[string]bool{}
+	for _, field := range structType.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(value)
+		}
+
+		if _, found := tag.Lookup("tablename"); found {
+			return nil, fmt.Errorf("ksqlgen: struct %s has nested structs, which are not supported", typeName)
+		}
+
+		name, found := tag.Lookup("ksql")
+		if !found && len(field.Names) == 0 {
+			return nil, fmt.Errorf("ksqlgen: struct %s has embedded structs, which are not supported", typeName)
+		}
+		if name == "" {
+			continue
+		}
+
+		if len(field.Names) != 1 {
+			return nil, fmt.Errorf("ksqlgen: the `ksql` tag of struct %s must be used on a single named attribute", typeName)
+		}
+		fieldName := field.Names[0].Name
+		if !ast.IsExported(fieldName) {
+			return nil, fmt.Errorf("ksqlgen: all fields using the ksql tags must be exported, but %s.%s is unexported", typeName, fieldName)
+		}
+
+		tags := strings.Split(name, ",")
+		for _, modifier := range tags[1:] {
+			if modifier == "flatten" {
+				return nil, fmt.Errorf("ksqlgen: attribute %s.%s uses the flatten modifier, which is not supported", typeName, fieldName)
+			}
+		}
+
+		if names[tags[0]] {
+			return nil, fmt.Errorf("ksqlgen: struct %s contains multiple attributes with the same ksql tag name: '%s'", typeName, tags[0])
+		}
+		names[tags[0]] = true
+
+		_, isPtr := field.Type.(*ast.StarExpr)
+		columns = append(columns, column{
+			Name:      tags[0],
+			FieldName: fieldName,
+			ConstName: typeName + "Column" + fieldName,
+			IsPtr:     isPtr,
+		})
+	}
+
+	return columns, nil
+}
+
+// toCamelCase converts table names such as `user_permissions`
+// into `UserPermissions` for naming the generated variables.
+func toCamelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var codeTemplate = template.Must(template.New("code").Parse(`// Code generated by ksqlgen; DO NOT EDIT.
+
+package {{ .Package }}
+
+import "github.com/vingarcia/ksql"
+{{ if .Table }}
+// {{ .TableVar }} is the ksql.Table used for the {{ .TypeName }} records
+var {{ .TableVar }} = ksql.NewTable({{ printf "%q" .Table }}{{ range .IDColumns }}, {{ printf "%q" . }}{{ end }})
+{{ end }}
+// The column names of the {{ .TypeName }} struct
+const (
+{{- range .Columns }}
+	{{ .ConstName }} = {{ printf "%q" .Name }}
+{{- end }}
+)
+
+// KSQLScanTarget implements the ksql.StaticScanner interface
+func ({{ .Receiver }} *{{ .TypeName }}) KSQLScanTarget(column string) interface{} {
+	switch column {
+{{- range .Columns }}
+	case {{ .ConstName }}:
+		return &{{ $.Receiver }}.{{ .FieldName }}
+{{- end }}
+	}
+	return nil
+}
+
+// KSQLValues implements the ksql.StaticValuer interface
+func ({{ .Receiver }} {{ .TypeName }}) KSQLValues() map[string]interface{} {
+	values := make(map[string]interface{}, {{ len .Columns }})
+{{- range .Columns }}
+{{- if .IsPtr }}
+	if {{ $.Receiver }}.{{ .FieldName }} != nil {
+		values[{{ .ConstName }}] = *{{ $.Receiver }}.{{ .FieldName }}
+	}
+{{- else }}
+	values[{{ .ConstName }}] = {{ $.Receiver }}.{{ .FieldName }}
+{{- end }}
+{{- end }}
+	return values
+}
+
+var _ ksql.StaticScanner = &{{ .TypeName }}{}
+var _ ksql.StaticValuer = {{ .TypeName }}{}
+`))
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("should generate the code for the struct", func(t *testing.T) {
+		dir := writePackage(t, `package models
+
+type UserPermission struct {
+	ID      int     `+"`ksql:\"id\"`"+`
+	Name    *string `+"`ksql:\"name\"`"+`
+	Address Address `+"`ksql:\"address,json\"`"+`
+	Ignored string
+}
+
+type Address struct {
+	Street string
+}
+`)
+
+		code, err := generate(generatorArgs{
+			Dir:       dir,
+			TypeName:  "UserPermission",
+			Table:     "user_permissions",
+			IDColumns: []string{"user_id", "perm_id"},
+		})
+		tt.AssertNoErr(t, err)
+
+		assertContains(t, string(code),
+			"// Code generated by ksqlgen; DO NOT EDIT.",
+			"package models",
+			`var UserPermissionsTable = ksql.NewTable("user_permissions", "user_id", "perm_id")`,
+			`UserPermissionColumnID      = "id"`,
+			`UserPermissionColumnAddress = "address"`,
+			"case UserPermissionColumnName:\n\t\treturn &u.Name",
+			"if u.Name != nil {\n\t\tvalues[UserPermissionColumnName] = *u.Name",
+			"values[UserPermissionColumnID] = u.ID",
+			"var _ ksql.StaticScanner = &UserPermission{}",
+		)
+		tt.AssertEqual(t, strings.Contains(string(code), "Ignored"), false)
+	})
+
+	t.Run("should omit the table and the default ID column", func(t *testing.T) {
+		dir := writePackage(t, "package models\n\ntype User struct {\n\tID int `ksql:\"id\"`\n}\n")
+
+		code, err := generate(generatorArgs{Dir: dir, TypeName: "User"})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, strings.Contains(string(code), "NewTable"), false)
+
+		code, err = generate(generatorArgs{Dir: dir, TypeName: "User", Table: "users"})
+		tt.AssertNoErr(t, err)
+		assertContains(t, string(code), `var UsersTable = ksql.NewTable("users")`)
+	})
+
+	t.Run("should report errors for unsupported structs", func(t *testing.T) {
+		tests := []struct {
+			desc               string
+			structDecl         string
+			expectErrToContain []string
+		}{
+			{
+				desc:               "missing struct",
+				structDecl:         "type Other struct{}",
+				expectErrToContain: []string{"struct User not found"},
+			},
+			{
+				desc:               "no ksql tags",
+				structDecl:         "type User struct{ ID int }",
+				expectErrToContain: []string{"no attributes with the `ksql` tag"},
+			},
+			{
+				desc:               "nested struct",
+				structDecl:         "type User struct{ Other struct{} `tablename:\"o\"` }",
+				expectErrToContain: []string{"nested structs"},
+			},
+			{
+				desc:               "flatten modifier",
+				structDecl:         "type User struct{ Other struct{} `ksql:\"other,flatten\"` }",
+				expectErrToContain: []string{"User.Other", "flatten"},
+			},
+			{
+				desc:               "unexported attribute",
+				structDecl:         "type User struct{ id int `ksql:\"id\"` }",
+				expectErrToContain: []string{"User.id", "unexported"},
+			},
+			{
+				desc:               "duplicated names",
+				structDecl:         "type User struct{ ID int `ksql:\"id\"`; ID2 int `ksql:\"id\"` }",
+				expectErrToContain: []string{"same ksql tag name", "id"},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				dir := writePackage(t, "package models\n\n"+test.structDecl+"\n")
+
+				_, err := generate(generatorArgs{Dir: dir, TypeName: "User"})
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+			})
+		}
+	})
+}
+
+func TestToSnakeCase(t *testing.T) {
+	tt.AssertEqual(t, toSnakeCase("User"), "user")
+	tt.AssertEqual(t, toSnakeCase("UserPermission"), "user_permission")
+	tt.AssertEqual(t, toSnakeCase("HTTPRequest"), "http_request")
+}
+
+func assertContains(t *testing.T, code string, substrs ...string) {
+	for _, substr := range substrs {
+		if !strings.Contains(code, substr) {
+			t.Fatalf("expected the generated code to contain:\n%s\n\ngot:\n%s", substr, code)
+		}
+	}
+}
+
+func writePackage(t *testing.T, code string) string {
+	dir, err := ioutil.TempDir("", "ksqlgen")
+	tt.AssertNoErr(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	err = ioutil.WriteFile(filepath.Join(dir, "models.go"), []byte(code), 0644)
+	tt.AssertNoErr(t, err)
+	return dir
+}
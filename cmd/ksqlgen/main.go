@@ -0,0 +1,86 @@
+// Command ksqlgen generates static, reflection-free code for the
+// structs with `ksql` tags, it is meant to be used with go:generate:
+//
+//	//go:generate go run github.com/vingarcia/ksql/cmd/ksqlgen -type User -table users
+//
+// For each struct it generates:
+//
+//   - A constant with the name of each column, e.g. `UserColumnName`
+//   - The `KSQLScanTarget` method implementing the ksql.StaticScanner interface
+//   - The `KSQLValues` method implementing the ksql.StaticValuer interface
+//   - A ksql.Table variable named after the table, e.g. `UsersTable`,
+//     if the `-table` argument is provided
+//
+// The generated file is written to `<type_name>_ksql.go` by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+func main() {
+	typeName := flag.String("type", "", "the name of the struct the code is generated for (required)")
+	table := flag.String("table", "", "the name of the table, if set a ksql.Table variable is generated")
+	ids := flag.String("ids", "", "the comma separated list of ID columns of the table (default \"id\")")
+	output := flag.String("output", "", "the output file (default \"<type_name>_ksql.go\")")
+	dir := flag.String("dir", ".", "the directory of the package containing the struct")
+	flag.Parse()
+
+	if *typeName == "" {
+		fmt.Fprintln(os.Stderr, "ksqlgen: the -type argument is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var idColumns []string
+	if *ids != "" {
+		idColumns = strings.Split(*ids, ",")
+	}
+
+	code, err := generate(generatorArgs{
+		Dir:       *dir,
+		TypeName:  *typeName,
+		Table:     *table,
+		IDColumns: idColumns,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	filename := *output
+	if filename == "" {
+		filename = filepath.Join(*dir, toSnakeCase(*typeName)+"_ksql.go")
+	}
+
+	err = ioutil.WriteFile(filename, code, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ksqlgen: unable to write the output file: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// toSnakeCase converts type names such as `UserPermission`
+// into `user_permission` for naming the generated files.
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			isWordStart := i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if isWordStart {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+// Command ksqlvet checks the queries passed to the ksql methods against
+// the schema of the database, reporting the tables and columns that don't
+// exist and the selected columns that don't match the destination structs:
+//
+//	ksqlvet -schema schema.sql ./...
+//
+// The schema is read from a dump containing the CREATE TABLE statements,
+// e.g. the output of `pg_dump --schema-only`, `mysqldump --no-data` or
+// `sqlite3 database.db .schema`. For checking against a live database
+// use the `kvet.LoadSchema()` and `kvet.Check()` functions instead.
+//
+// The command exits with status 1 if any issues are found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/vingarcia/ksql/kvet"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "the file with the schema dump (required)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ksqlvet -schema <file> [directories]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *schemaFile == "" {
+		fmt.Fprintln(os.Stderr, "ksqlvet: the -schema argument is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ddl, err := ioutil.ReadFile(*schemaFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ksqlvet: unable to read the schema file: %s\n", err)
+		os.Exit(1)
+	}
+
+	schema, err := kvet.ParseSchema(string(ddl))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"./..."}
+	}
+
+	issues, err := kvet.Check(schema, dirs...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ksqlvet: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+package ksql
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var concreteTypes = struct {
+	sync.RWMutex
+	byInterface map[reflect.Type]reflect.Type
+}{
+	byInterface: map[reflect.Type]reflect.Type{},
+}
+
+// RegisterConcreteType registers the type used for scanning the attributes
+// declared with an interface type, which is passed as a nil pointer, e.g.:
+//
+//	type Money interface {
+//		Cents() int64
+//	}
+//
+//	ksql.RegisterConcreteType((*Money)(nil), DecimalMoney{})
+//
+//	type Product struct {
+//		ID    int   `ksql:"id"`
+//		Price Money `ksql:"price"`
+//	}
+//
+// The columns are scanned into a new instance of the concrete type, which
+// should be scannable from the column, e.g. by implementing sql.Scanner,
+// and the attribute is set to nil when the column is NULL. The values
+// saved on these attributes are sent to the database as they are.
+//
+// Attributes of the empty interface type work without registering a type,
+// since the value of the column is saved directly on them.
+//
+// It should be called before the interface is used for the first time,
+// usually on an `init()` function, and it panics if the concrete type
+// doesn't implement the interface or if the interface is already registered.
+func RegisterConcreteType(interfacePtr interface{}, concrete interface{}) {
+	ptrType := reflect.TypeOf(interfacePtr)
+	if ptrType == nil || ptrType.Kind() != reflect.Ptr || ptrType.Elem().Kind() != reflect.Interface {
+		panic(fmt.Errorf("ksql: RegisterConcreteType expects a nil pointer to an interface type, e.g. (*MyInterface)(nil), but got: %T", interfacePtr))
+	}
+	interfaceType := ptrType.Elem()
+
+	concreteType := reflect.TypeOf(concrete)
+	if concreteType == nil || !concreteType.Implements(interfaceType) {
+		panic(fmt.Errorf("ksql: the type %T doesn't implement the interface %v", concrete, interfaceType))
+	}
+
+	concreteTypes.Lock()
+	defer concreteTypes.Unlock()
+
+	if _, found := concreteTypes.byInterface[interfaceType]; found {
+		panic(fmt.Errorf("ksql: the interface %v already has a registered concrete type", interfaceType))
+	}
+	concreteTypes.byInterface[interfaceType] = concreteType
+}
+
+// getConcreteType returns the type registered for the interface type of
+// an attribute, or nil if the attribute doesn't need one, i.e. if it is
+// not of a non-empty interface type.
+func getConcreteType(fieldName string, fieldType reflect.Type) (reflect.Type, error) {
+	if fieldType.Kind() != reflect.Interface || fieldType.NumMethod() == 0 {
+		return nil, nil
+	}
+
+	concreteTypes.RLock()
+	concreteType, found := concreteTypes.byInterface[fieldType]
+	concreteTypes.RUnlock()
+	if !found {
+		return nil, fmt.Errorf(
+			"ksql: can't scan the attribute `%s` of interface type %v, please register a concrete type for it with `ksql.RegisterConcreteType()`",
+			fieldName, fieldType,
+		)
+	}
+
+	return concreteType, nil
+}
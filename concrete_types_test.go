@@ -0,0 +1,92 @@
+package ksql
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type fakeConcreteTypeInterface interface {
+	Fake() string
+}
+
+type fakeConcreteType struct{}
+
+func (fakeConcreteType) Fake() string { return "fake" }
+
+func TestRegisterConcreteType(t *testing.T) {
+	t.Run("should make the concrete type available for the interface", func(t *testing.T) {
+		RegisterConcreteType((*fakeConcreteTypeInterface)(nil), fakeConcreteType{})
+
+		concreteType, err := getConcreteType("fake", reflect.TypeOf((*fakeConcreteTypeInterface)(nil)).Elem())
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, concreteType, reflect.TypeOf(fakeConcreteType{}))
+	})
+
+	t.Run("should panic for invalid arguments", func(t *testing.T) {
+		tests := []struct {
+			desc               string
+			interfacePtr       interface{}
+			concrete           interface{}
+			expectErrToContain []string
+		}{
+			{
+				desc:               "interface is not a pointer",
+				interfacePtr:       fakeConcreteType{},
+				concrete:           fakeConcreteType{},
+				expectErrToContain: []string{"pointer to an interface", "ksql.fakeConcreteType"},
+			},
+			{
+				desc:               "interface is nil",
+				interfacePtr:       nil,
+				concrete:           fakeConcreteType{},
+				expectErrToContain: []string{"pointer to an interface"},
+			},
+			{
+				desc:               "concrete type doesn't implement the interface",
+				interfacePtr:       (*fmt.Stringer)(nil),
+				concrete:           fakeConcreteType{},
+				expectErrToContain: []string{"ksql.fakeConcreteType", "doesn't implement", "fmt.Stringer"},
+			},
+			{
+				desc:               "interface is already registered",
+				interfacePtr:       (*fakeConcreteTypeInterface)(nil),
+				concrete:           fakeConcreteType{},
+				expectErrToContain: []string{"ksql.fakeConcreteTypeInterface", "already"},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				panicPayload := tt.PanicHandler(func() {
+					RegisterConcreteType(test.interfacePtr, test.concrete)
+				})
+
+				err, ok := panicPayload.(error)
+				tt.AssertEqual(t, ok, true)
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+			})
+		}
+	})
+}
+
+func TestGetConcreteType(t *testing.T) {
+	t.Run("should not require concrete types for other types", func(t *testing.T) {
+		for _, fieldType := range []reflect.Type{
+			reflect.TypeOf(""),
+			reflect.TypeOf(fakeConcreteType{}),
+			reflect.TypeOf((*interface{})(nil)).Elem(),
+		} {
+			concreteType, err := getConcreteType("fake", fieldType)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, concreteType, nil)
+		}
+	})
+
+	t.Run("should report interfaces without a registered concrete type", func(t *testing.T) {
+		_, err := getConcreteType("fake", reflect.TypeOf((*fmt.Stringer)(nil)).Elem())
+		tt.AssertErrContains(t, err, "fake", "fmt.Stringer", "RegisterConcreteType")
+	})
+}
@@ -0,0 +1,275 @@
+package ksql
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUniqueViolation is returned when an operation violates a unique
+// or primary key constraint, it can be checked with `errors.As()`:
+//
+//	var uniqueErr ksql.ErrUniqueViolation
+//	if errors.As(err, &uniqueErr) {
+//		fmt.Println("duplicated value on column:", uniqueErr.Column)
+//	}
+//
+// The Constraint and Column attributes are only
+// set if they are reported by the database.
+type ErrUniqueViolation struct {
+	Constraint string
+	Column     string
+
+	// Err is the original error returned by the driver
+	Err error
+}
+
+func (e ErrUniqueViolation) Error() string { return e.Err.Error() }
+
+// Unwrap returns the original error returned by the driver
+func (e ErrUniqueViolation) Unwrap() error { return e.Err }
+
+// ErrForeignKeyViolation is returned when an operation
+// violates a foreign key constraint.
+//
+// The Constraint and Column attributes are only
+// set if they are reported by the database.
+type ErrForeignKeyViolation struct {
+	Constraint string
+	Column     string
+
+	// Err is the original error returned by the driver
+	Err error
+}
+
+func (e ErrForeignKeyViolation) Error() string { return e.Err.Error() }
+
+// Unwrap returns the original error returned by the driver
+func (e ErrForeignKeyViolation) Unwrap() error { return e.Err }
+
+// ErrCheckViolation is returned when an operation
+// violates a check constraint.
+//
+// The Constraint and Column attributes are only
+// set if they are reported by the database.
+type ErrCheckViolation struct {
+	Constraint string
+	Column     string
+
+	// Err is the original error returned by the driver
+	Err error
+}
+
+func (e ErrCheckViolation) Error() string { return e.Err.Error() }
+
+// Unwrap returns the original error returned by the driver
+func (e ErrCheckViolation) Unwrap() error { return e.Err }
+
+// ErrNotNullViolation is returned when an operation
+// saves a NULL value on a NOT NULL column.
+//
+// The Column attribute is only set if it is reported by the database.
+type ErrNotNullViolation struct {
+	Column string
+
+	// Err is the original error returned by the driver
+	Err error
+}
+
+func (e ErrNotNullViolation) Error() string { return e.Err.Error() }
+
+// Unwrap returns the original error returned by the driver
+func (e ErrNotNullViolation) Unwrap() error { return e.Err }
+
+type violationKind int
+
+const (
+	noViolation violationKind = iota
+	uniqueViolation
+	foreignKeyViolation
+	checkViolation
+	notNullViolation
+)
+
+// violationPattern matches the message of the errors caused by
+// constraint violations, the messages are used instead of the error
+// types since the drivers are imported by the adapters on separate modules.
+type violationPattern struct {
+	kind    violationKind
+	pattern *regexp.Regexp
+
+	// constraintGroup and columnGroup are the indexes of the
+	// submatches containing the names of the constraint
+	// and of the column, or 0 if they are not available.
+	constraintGroup int
+	columnGroup     int
+}
+
+var mysqlViolationPatterns = []violationPattern{
+	{uniqueViolation, regexp.MustCompile(`Error 1062\b.*Duplicate entry .* for key '([^']*)'`), 1, 0},
+	{foreignKeyViolation, regexp.MustCompile("Error 145[12]\\b.*CONSTRAINT `([^`]*)` FOREIGN KEY \\(`([^`]*)`\\)"), 1, 2},
+	{foreignKeyViolation, regexp.MustCompile(`Error 145[12]\b`), 0, 0},
+	{checkViolation, regexp.MustCompile(`Error 3819\b.*Check constraint '([^']*)'`), 1, 0},
+	{notNullViolation, regexp.MustCompile(`Error 1048\b.*Column '([^']*)' cannot be null`), 0, 1},
+	{notNullViolation, regexp.MustCompile(`Error 1364\b.*Field '([^']*)' doesn't have a default value`), 0, 1},
+}
+
+var violationPatterns = map[string][]violationPattern{
+	"postgres": {
+		{uniqueViolation, regexp.MustCompile(`violates unique constraint "([^"]*)"`), 1, 0},
+		{foreignKeyViolation, regexp.MustCompile(`violates foreign key constraint "([^"]*)"`), 1, 0},
+		{checkViolation, regexp.MustCompile(`violates check constraint "([^"]*)"`), 1, 0},
+		{notNullViolation, regexp.MustCompile(`null value in column "([^"]*)".* violates not-null constraint`), 0, 1},
+	},
+	"mysql":   mysqlViolationPatterns,
+	"mariadb": mysqlViolationPatterns,
+	"sqlite3": {
+		{uniqueViolation, regexp.MustCompile(`UNIQUE constraint failed: ([^\s,]+(?:, [^\s,]+)*)`), 0, 1},
+		{foreignKeyViolation, regexp.MustCompile(`FOREIGN KEY constraint failed`), 0, 0},
+		{checkViolation, regexp.MustCompile(`CHECK constraint failed: (\S+)`), 1, 0},
+		{notNullViolation, regexp.MustCompile(`NOT NULL constraint failed: (\S+)`), 0, 1},
+	},
+	"sqlserver": {
+		{uniqueViolation, regexp.MustCompile(`Violation of (?:PRIMARY|UNIQUE) KEY constraint '([^']*)'`), 1, 0},
+		{uniqueViolation, regexp.MustCompile(`Cannot insert duplicate key row in object '[^']*' with unique index '([^']*)'`), 1, 0},
+		{foreignKeyViolation, regexp.MustCompile(`conflicted with the (?:FOREIGN KEY|REFERENCE) constraint "([^"]*)".*column '([^']*)'`), 1, 2},
+		{foreignKeyViolation, regexp.MustCompile(`conflicted with the (?:FOREIGN KEY|REFERENCE) constraint "([^"]*)"`), 1, 0},
+		{checkViolation, regexp.MustCompile(`conflicted with the CHECK constraint "([^"]*)".*column '([^']*)'`), 1, 2},
+		{checkViolation, regexp.MustCompile(`conflicted with the CHECK constraint "([^"]*)"`), 1, 0},
+		{notNullViolation, regexp.MustCompile(`Cannot insert the value NULL into column '([^']*)'`), 0, 1},
+	},
+	"oracle": {
+		{uniqueViolation, regexp.MustCompile(`ORA-00001: unique constraint \(([^)]*)\)`), 1, 0},
+		{foreignKeyViolation, regexp.MustCompile(`ORA-0229[12]: integrity constraint \(([^)]*)\)`), 1, 0},
+		{checkViolation, regexp.MustCompile(`ORA-02290: check constraint \(([^)]*)\)`), 1, 0},
+		{notNullViolation, regexp.MustCompile(`ORA-01400: cannot insert NULL into \((\S+)\)`), 0, 1},
+	},
+	"duckdb": {
+		{uniqueViolation, regexp.MustCompile(`violates (?:primary key|unique) constraint`), 0, 0},
+		{foreignKeyViolation, regexp.MustCompile(`(?i)violates foreign key constraint`), 0, 0},
+		{checkViolation, regexp.MustCompile(`CHECK constraint failed: (\S+)`), 1, 0},
+		{notNullViolation, regexp.MustCompile(`NOT NULL constraint failed: (\S+)`), 0, 1},
+	},
+}
+
+// postgresDetailKey extracts the column names from the detail message
+// of Postgres errors, e.g. `Key (email)=(a@b.com) already exists.`
+var postgresDetailKey = regexp.MustCompile(`Key \(([^)]*)\)=`)
+
+// translateError converts the errors caused by constraint violations
+// into one of the typed errors above, other errors are returned unchanged.
+func translateError(driverName string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err.(type) {
+	case ErrUniqueViolation, ErrForeignKeyViolation, ErrCheckViolation, ErrNotNullViolation:
+		return err
+	}
+
+	kind, constraint, column := classifyError(driverName, err)
+	switch kind {
+	case uniqueViolation:
+		return ErrUniqueViolation{Constraint: constraint, Column: column, Err: err}
+	case foreignKeyViolation:
+		return ErrForeignKeyViolation{Constraint: constraint, Column: column, Err: err}
+	case checkViolation:
+		return ErrCheckViolation{Constraint: constraint, Column: column, Err: err}
+	case notNullViolation:
+		return ErrNotNullViolation{Column: column, Err: err}
+	}
+
+	return err
+}
+
+func classifyError(driverName string, err error) (kind violationKind, constraint string, column string) {
+	msg := err.Error()
+	for _, p := range violationPatterns[driverName] {
+		match := p.pattern.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+
+		kind = p.kind
+		if p.constraintGroup != 0 {
+			constraint = unqualifyName(match[p.constraintGroup])
+		}
+		if p.columnGroup != 0 {
+			column = unqualifyColumns(match[p.columnGroup])
+		}
+		break
+	}
+
+	if driverName == "postgres" {
+		// Both pgx and lib/pq errors contain more details
+		// than their messages, so we check them too:
+		if kind == noViolation {
+			kind = postgresViolationCodes[errorField(err, "Code")]
+		}
+		if name := errorField(err, "ConstraintName", "Constraint"); name != "" {
+			constraint = name
+		}
+		if name := errorField(err, "ColumnName", "Column"); name != "" {
+			column = name
+		}
+		if match := postgresDetailKey.FindStringSubmatch(errorField(err, "Detail")); column == "" && match != nil {
+			column = match[1]
+		}
+	}
+
+	return kind, constraint, column
+}
+
+var postgresViolationCodes = map[string]violationKind{
+	"23505": uniqueViolation,
+	"23503": foreignKeyViolation,
+	"23514": checkViolation,
+	"23502": notNullViolation,
+}
+
+// errorField returns the first non empty string attribute
+// with one of the input names on any of the errors wrapped
+// by the input error, or an empty string if there is none.
+func errorField(err error, names ...string) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		for _, name := range names {
+			field := v.FieldByName(name)
+			if field.IsValid() && field.Kind() == reflect.String && field.String() != "" {
+				return field.String()
+			}
+		}
+	}
+
+	return ""
+}
+
+// unqualifyName removes the schema or table prefixes from a name,
+// e.g. `users.email` becomes `email`.
+func unqualifyName(name string) string {
+	name = strings.Trim(name, `"`)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = strings.Trim(name[i+1:], `"`)
+	}
+	return name
+}
+
+// unqualifyColumns works like unqualifyName but for a
+// list of column names, e.g. `users.a, users.b` becomes `a, b`.
+func unqualifyColumns(names string) string {
+	columns := strings.Split(names, ",")
+	for i, column := range columns {
+		columns[i] = unqualifyName(strings.TrimSpace(column))
+	}
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,169 @@
+package ksql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+// fakePgError mimics the attributes of the pgconn.PgError type
+type fakePgError struct {
+	Code           string
+	Message        string
+	Detail         string
+	ConstraintName string
+}
+
+func (e *fakePgError) Error() string {
+	return fmt.Sprintf("ERROR: %s (SQLSTATE %s)", e.Message, e.Code)
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		desc        string
+		driverName  string
+		err         error
+		expectedErr error
+	}{
+		{
+			desc:       "should translate postgres unique violations",
+			driverName: "postgres",
+			err: &fakePgError{
+				Code:           "23505",
+				Message:        `duplicate key value violates unique constraint "users_email_key"`,
+				Detail:         "Key (email)=(a@b.com) already exists.",
+				ConstraintName: "users_email_key",
+			},
+			expectedErr: ErrUniqueViolation{Constraint: "users_email_key", Column: "email"},
+		},
+		{
+			desc:        "should translate postgres errors using only the message",
+			driverName:  "postgres",
+			err:         errors.New(`pq: insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey"`),
+			expectedErr: ErrForeignKeyViolation{Constraint: "posts_user_id_fkey"},
+		},
+		{
+			desc:        "should translate postgres errors using only the code",
+			driverName:  "postgres",
+			err:         &fakePgError{Code: "23514", Message: "fake message", ConstraintName: "age_check"},
+			expectedErr: ErrCheckViolation{Constraint: "age_check"},
+		},
+		{
+			desc:        "should translate postgres not null violations",
+			driverName:  "postgres",
+			err:         errors.New(`ERROR: null value in column "name" of relation "users" violates not-null constraint (SQLSTATE 23502)`),
+			expectedErr: ErrNotNullViolation{Column: "name"},
+		},
+		{
+			desc:        "should translate mysql unique violations",
+			driverName:  "mysql",
+			err:         errors.New("Error 1062 (23000): Duplicate entry 'a@b.com' for key 'users.email'"),
+			expectedErr: ErrUniqueViolation{Constraint: "email"},
+		},
+		{
+			desc:        "should translate mysql foreign key violations",
+			driverName:  "mariadb",
+			err:         errors.New("Error 1452: Cannot add or update a child row: a foreign key constraint fails (`db`.`posts`, CONSTRAINT `posts_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"),
+			expectedErr: ErrForeignKeyViolation{Constraint: "posts_fk", Column: "user_id"},
+		},
+		{
+			desc:        "should translate mysql check violations",
+			driverName:  "mysql",
+			err:         errors.New("Error 3819 (HY000): Check constraint 'age_check' is violated."),
+			expectedErr: ErrCheckViolation{Constraint: "age_check"},
+		},
+		{
+			desc:        "should translate mysql not null violations",
+			driverName:  "mysql",
+			err:         errors.New("Error 1048 (23000): Column 'name' cannot be null"),
+			expectedErr: ErrNotNullViolation{Column: "name"},
+		},
+		{
+			desc:        "should translate sqlite3 unique violations",
+			driverName:  "sqlite3",
+			err:         errors.New("UNIQUE constraint failed: user_permissions.user_id, user_permissions.perm_id"),
+			expectedErr: ErrUniqueViolation{Column: "user_id, perm_id"},
+		},
+		{
+			desc:        "should translate sqlite3 foreign key violations",
+			driverName:  "sqlite3",
+			err:         errors.New("FOREIGN KEY constraint failed"),
+			expectedErr: ErrForeignKeyViolation{},
+		},
+		{
+			desc:        "should translate sqlite3 not null violations",
+			driverName:  "sqlite3",
+			err:         errors.New("NOT NULL constraint failed: users.name"),
+			expectedErr: ErrNotNullViolation{Column: "name"},
+		},
+		{
+			desc:        "should translate sqlserver unique violations",
+			driverName:  "sqlserver",
+			err:         errors.New("mssql: Violation of UNIQUE KEY constraint 'unique_1'. Cannot insert duplicate key in object 'dbo.user_permissions'. The duplicate key value is (1, 42)."),
+			expectedErr: ErrUniqueViolation{Constraint: "unique_1"},
+		},
+		{
+			desc:        "should translate sqlserver check violations",
+			driverName:  "sqlserver",
+			err:         errors.New(`mssql: The INSERT statement conflicted with the CHECK constraint "age_check". The conflict occurred in database "ksql", table "dbo.users", column 'age'.`),
+			expectedErr: ErrCheckViolation{Constraint: "age_check", Column: "age"},
+		},
+		{
+			desc:        "should translate oracle unique violations",
+			driverName:  "oracle",
+			err:         errors.New("ORA-00001: unique constraint (KSQL.USERS_EMAIL_UK) violated"),
+			expectedErr: ErrUniqueViolation{Constraint: "USERS_EMAIL_UK"},
+		},
+		{
+			desc:        "should translate oracle not null violations",
+			driverName:  "oracle",
+			err:         errors.New(`ORA-01400: cannot insert NULL into ("KSQL"."USERS"."NAME")`),
+			expectedErr: ErrNotNullViolation{Column: "NAME"},
+		},
+		{
+			desc:        "should not translate other errors",
+			driverName:  "postgres",
+			err:         errors.New("fake error"),
+			expectedErr: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := translateError(test.driverName, test.err)
+
+			// The original error should always be preserved:
+			tt.AssertEqual(t, errors.Is(err, test.err), true)
+			tt.AssertEqual(t, err.Error(), test.err.Error())
+
+			switch expected := test.expectedErr.(type) {
+			case ErrUniqueViolation:
+				expected.Err = test.err
+				tt.AssertEqual(t, err, expected)
+			case ErrForeignKeyViolation:
+				expected.Err = test.err
+				tt.AssertEqual(t, err, expected)
+			case ErrCheckViolation:
+				expected.Err = test.err
+				tt.AssertEqual(t, err, expected)
+			case ErrNotNullViolation:
+				expected.Err = test.err
+				tt.AssertEqual(t, err, expected)
+			default:
+				tt.AssertEqual(t, err, test.err)
+			}
+		})
+	}
+
+	t.Run("should work with errors.As on wrapped errors", func(t *testing.T) {
+		err := errors.Wrap(
+			translateError("sqlite3", errors.New("UNIQUE constraint failed: users.email")),
+			"fake wrapper",
+		)
+
+		var uniqueErr ErrUniqueViolation
+		tt.AssertEqual(t, errors.As(err, &uniqueErr), true)
+		tt.AssertEqual(t, uniqueErr.Column, "email")
+	})
+}
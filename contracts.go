@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 
 	"github.com/pkg/errors"
 )
@@ -11,6 +12,20 @@ import (
 // ErrRecordNotFound ...
 var ErrRecordNotFound error = errors.Wrap(sql.ErrNoRows, "ksql: the query returned no results")
 
+// ErrVersionConflict is returned when updating a record whose
+// `optimisticLock` attribute doesn't match the version saved on
+// the database, i.e. when the record was changed by someone else
+var ErrVersionConflict error = fmt.Errorf("ksql: the record was modified concurrently, its version doesn't match the database")
+
+// ErrMissingTenant is returned when a struct with the `tenant` modifier
+// is used with a context that was neither created with `ksql.WithTenant()`
+// nor with `ksql.CrossTenant()`
+var ErrMissingTenant error = fmt.Errorf("ksql: the context has no tenant, use ksql.WithTenant(ctx, tenantID) or ksql.CrossTenant(ctx)")
+
+// ErrNoRowsAffected is returned by `ksql.ExpectRowsAffected()`
+// when the operation didn't change any rows
+var ErrNoRowsAffected error = fmt.Errorf("ksql: the operation didn't affect any rows")
+
 // ErrAbortIteration ...
 var ErrAbortIteration error = fmt.Errorf("ksql: abort iteration, should only be used inside QueryChunks function")
 
@@ -45,6 +60,24 @@ type Table struct {
 
 	// IDColumns defaults to []string{"id"} if unset
 	idColumns []string
+
+	// conflictColumns are used by the Upsert function
+	// to detect conflicts, it defaults to the idColumns if unset
+	conflictColumns []string
+
+	// structType is used by the PatchMap function
+	// to validate the names of the columns being updated
+	structType reflect.Type
+
+	// idGenerator is set with the `Table.WithIDGenerator()` method
+	idGenerator IDGenerator
+
+	// auditTable is set with the `Table.WithAudit()` method
+	auditTable string
+
+	// fields is set by the InsertFields and UpdateFields
+	// methods with the only columns they should write
+	fields []string
 }
 
 // NewTable returns a Table instance that stores
@@ -75,6 +108,44 @@ func NewTable(tableName string, ids ...string) Table {
 	}
 }
 
+// WithConflictColumns returns a copy of the Table that will use
+// the input columns as the conflict target of the Upsert method,
+// by default the ID columns are used for this purpose.
+//
+// The columns informed here should be covered by a unique
+// constraint or index on the database, e.g.:
+//
+//	var UsersByEmailTable = ksql.NewTable("users").WithConflictColumns("email")
+//
+// Note that MySQL doesn't support choosing a conflict target,
+// so on MySQL any unique key might trigger the update.
+func (t Table) WithConflictColumns(columns ...string) Table {
+	t.conflictColumns = columns
+	return t
+}
+
+// WithStruct returns a copy of the Table that has the input struct
+// registered as the representation of its rows, e.g.:
+//
+//	var UsersTable = ksql.NewTable("users").WithStruct(User{})
+//
+// The registered struct is used by the PatchMap method for checking
+// that only the columns declared on its `ksql` tags are updated.
+func (t Table) WithStruct(record interface{}) Table {
+	t.structType = reflect.TypeOf(record)
+	if t.structType != nil && t.structType.Kind() == reflect.Ptr {
+		t.structType = t.structType.Elem()
+	}
+	return t
+}
+
+func (t Table) getConflictColumns() []string {
+	if len(t.conflictColumns) == 0 {
+		return t.idColumns
+	}
+	return t.conflictColumns
+}
+
 func (t Table) validate() error {
 	if t.name == "" {
 		return fmt.Errorf("table name cannot be an empty string")
@@ -86,10 +157,25 @@ func (t Table) validate() error {
 		}
 	}
 
+	for _, fieldName := range t.conflictColumns {
+		if fieldName == "" {
+			return fmt.Errorf("conflict columns cannot be empty strings")
+		}
+	}
+
+	if t.structType != nil && t.structType.Kind() != reflect.Struct {
+		return fmt.Errorf("expected the registered struct to be a struct, but got: %v", t.structType)
+	}
+
 	return nil
 }
 
 func (t Table) insertMethodFor(dialect Dialect) insertMethod {
+	// The generated IDs are saved on the record before the insertion:
+	if t.idGenerator != nil {
+		return insertWithNoIDRetrieval
+	}
+
 	if len(t.idColumns) == 1 {
 		return dialect.InsertMethod()
 	}
@@ -117,5 +203,23 @@ type ChunkParser struct {
 	//
 	// Where the actual Record type should be of a struct
 	// representing the rows you are expecting to receive.
+	//
+	// The chunk and its records are reused for loading the next
+	// rows after the callback returns, which avoids allocating new
+	// records for each chunk, so the callback must copy the records
+	// it needs to keep.
 	ForEachChunk interface{}
+
+	// Workers is optional, if set to a number greater than 1 the
+	// ForEachChunk callback will be called concurrently by up to this
+	// number of goroutines, each one receiving a different chunk.
+	//
+	// If any of the callbacks return an error no new chunks are
+	// dispatched, the query is cancelled and QueryChunks returns
+	// the first error it got.
+	//
+	// Note that when using this option the chunks might be
+	// processed out of order and the ForEachChunk function
+	// must be safe for concurrent use.
+	Workers int
 }
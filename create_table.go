@@ -0,0 +1,437 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// CreateTableSQL returns the CREATE TABLE statement of the input table
+// on the dialect of the input driver, e.g. "postgres" or "sqlite3", with
+// one column for each attribute of the record, e.g.:
+//
+//	type User struct {
+//		ID    int    `ksql:"id"`
+//		Name  string `ksql:"name,type=varchar(50),notnull"`
+//		Email string `ksql:"email,notnull,unique"`
+//		Age   *int   `ksql:"age"`
+//	}
+//
+//	query, err := ksql.CreateTableSQL("postgres", ksql.NewTable("users"), &User{})
+//
+// The type of each column is chosen based on the type of the attribute
+// unless it is declared with the `type=<sql type>` option, which is
+// required for struct, slice and map attributes that don't use the `json`
+// modifier. The columns are nullable unless they are tagged with `notnull`
+// or are part of the primary key, which is built from the ID columns of
+// the table, and the `unique` option adds a unique constraint to the column.
+//
+// The attributes using the `enum` modifier, see `ksql.RegisterEnum()`, use
+// native ENUM types on Postgres and MySQL, where the Postgres type is created
+// before the table, and a CHECK constraint on the other databases.
+//
+// Tables with a single integer ID column and no IDGenerator have this
+// column auto incremented, on DuckDB this requires a sequence
+// so in this case the query also creates it.
+//
+// If the record is nil the struct registered with `Table.WithStruct()` is used.
+//
+// This function is meant for tests and prototypes, for production
+// databases a proper migration tool should be used instead.
+func CreateTableSQL(driver string, table Table, record interface{}) (string, error) {
+	dialect, err := GetDriverDialect(driver)
+	if err != nil {
+		return "", fmt.Errorf("ksql: %s", err)
+	}
+
+	return buildCreateTableQuery(dialect, nil, table, record, false)
+}
+
+// EnsureTable creates the input table, as described on `ksql.CreateTableSQL()`,
+// if it doesn't exist yet, existing tables are never changed, so it won't add
+// new columns or update the types of the existing ones.
+func EnsureTable(ctx context.Context, db DB, table Table, record interface{}) error {
+	query, err := buildCreateTableQuery(db.dialect, db.naming, table, record, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("ksql: error creating table `%s`: %s", table.name, err)
+	}
+
+	return nil
+}
+
+func buildCreateTableQuery(dialect Dialect, naming *structs.Naming, table Table, record interface{}, ifNotExists bool) (string, error) {
+	if err := table.validate(); err != nil {
+		return "", fmt.Errorf("ksql: can't create table: %s", err)
+	}
+
+	structType := table.structType
+	if record != nil {
+		structType = reflect.TypeOf(record)
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("ksql: expected record to be a struct or a pointer to struct, but got: %T", record)
+	}
+
+	info, err := naming.GetTagInfo(structType)
+	if err != nil {
+		return "", err
+	}
+	if info.IsNestedStruct {
+		return "", fmt.Errorf("ksql: can't create a table from a struct with nested structs: %v", structType)
+	}
+
+	isID := map[string]bool{}
+	for _, idName := range table.idColumns {
+		if !info.ByName(idName).Valid {
+			return "", fmt.Errorf("ksql: the struct %v has no attribute for the ID column `%s`", structType, idName)
+		}
+		isID[idName] = true
+	}
+
+	driver := dialect.DriverName()
+	autoIncrement := len(table.idColumns) == 1 && table.idGenerator == nil
+
+	var prefix string
+	createdEnums := map[string]bool{}
+	columns := []string{}
+	for _, field := range info.Fields() {
+		column := []string{escapeColumn(dialect, info, field.Name)}
+
+		fieldType := structType.FieldByIndex(field.Path).Type
+		kind, err := columnKind(fieldType, field)
+		if err != nil {
+			return "", fmt.Errorf("ksql: can't create table `%s`: %s", table.name, err)
+		}
+
+		sqlType := field.SQLType
+		if sqlType == "" {
+			sqlType = columnTypes[driver][kind]
+		}
+
+		var check string
+		if field.ModifierName == "enum" && field.SQLType == "" {
+			enum, err := getEnum(fieldType)
+			if err != nil {
+				return "", err
+			}
+
+			var createType string
+			sqlType, createType, check = enumColumnType(dialect, enum, sqlType, column[0], ifNotExists)
+			if createType != "" && !createdEnums[enum.name] {
+				prefix += createType
+				createdEnums[enum.name] = true
+			}
+		}
+
+		switch {
+		case isID[field.Name] && autoIncrement && isIntegerKind(kind) && field.SQLType == "":
+			column = append(column, autoIncrementColumn(driver, kind, sqlType))
+			if driver == "duckdb" {
+				sequence := table.name + "_id_seq"
+				prefix += "CREATE SEQUENCE IF NOT EXISTS " + sequence + ";\n"
+				column = append(column, "PRIMARY KEY DEFAULT nextval('"+sequence+"')")
+			}
+		case isID[field.Name] && len(table.idColumns) == 1:
+			column = append(column, sqlType, "PRIMARY KEY")
+		default:
+			column = append(column, sqlType)
+			if field.NotNull || isID[field.Name] {
+				column = append(column, "NOT NULL")
+			}
+		}
+
+		if field.Unique {
+			column = append(column, "UNIQUE")
+		}
+
+		if check != "" {
+			column = append(column, check)
+		}
+
+		columns = append(columns, strings.Join(column, " "))
+	}
+
+	if len(table.idColumns) > 1 {
+		idColumns := make([]string, len(table.idColumns))
+		for i, idName := range table.idColumns {
+			idColumns[i] = dialect.Escape(idName)
+		}
+		columns = append(columns, "PRIMARY KEY ("+strings.Join(idColumns, ", ")+")")
+	}
+
+	query := fmt.Sprintf(
+		"CREATE TABLE %s (\n\t%s\n)",
+		dialect.Escape(table.name),
+		strings.Join(columns, ",\n\t"),
+	)
+	if !ifNotExists {
+		return prefix + query, nil
+	}
+
+	switch driver {
+	case "sqlserver":
+		return fmt.Sprintf(
+			"IF OBJECT_ID(N'%s', N'U') IS NULL %s",
+			strings.ReplaceAll(table.name, "'", "''"), query,
+		), nil
+	case "oracle":
+		// Oracle has no `IF NOT EXISTS`, so we ignore the
+		// error ORA-00955 of tables that already exist:
+		return fmt.Sprintf(
+			"BEGIN EXECUTE IMMEDIATE '%s'; EXCEPTION WHEN OTHERS THEN IF SQLCODE != -955 THEN RAISE; END IF; END;",
+			strings.ReplaceAll(query, "'", "''"),
+		), nil
+	default:
+		return prefix + strings.Replace(query, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1), nil
+	}
+}
+
+// enumColumnType returns the column type of the attributes using the
+// `enum` modifier, which is a native ENUM type on Postgres and MySQL,
+// and the default type with a CHECK constraint on the other databases.
+//
+// On Postgres the type must be created before the table, so
+// the statement for creating it is also returned.
+func enumColumnType(
+	dialect Dialect,
+	enum *enumInfo,
+	defaultType string,
+	escapedColumn string,
+	ifNotExists bool,
+) (sqlType string, createType string, check string) {
+	values := strings.Join(enum.sqlValues(), ", ")
+	if enum.isInteger() {
+		return defaultType, "", "CHECK (" + escapedColumn + " IN (" + values + "))"
+	}
+
+	// The Go type might be an integer saved as a string:
+	defaultType = columnTypes[dialect.DriverName()][stringColumn]
+
+	switch dialect.DriverName() {
+	case "postgres":
+		createType = "CREATE TYPE " + dialect.Escape(enum.name) + " AS ENUM (" + values + ")"
+		if ifNotExists {
+			// Postgres has no `CREATE TYPE IF NOT EXISTS`:
+			createType = "DO $$ BEGIN " + createType + "; EXCEPTION WHEN duplicate_object THEN NULL; END $$"
+		}
+		return dialect.Escape(enum.name), createType + ";\n", ""
+	case "mysql", "mariadb":
+		return "ENUM(" + values + ")", "", ""
+	default:
+		return defaultType, "", "CHECK (" + escapedColumn + " IN (" + values + "))"
+	}
+}
+
+type columnKindType string
+
+const (
+	smallintColumn columnKindType = "smallint"
+	intColumn      columnKindType = "int"
+	bigintColumn   columnKindType = "bigint"
+	floatColumn    columnKindType = "float"
+	doubleColumn   columnKindType = "double"
+	boolColumn     columnKindType = "bool"
+	stringColumn   columnKindType = "string"
+	timeColumn     columnKindType = "time"
+	bytesColumn    columnKindType = "bytes"
+	jsonColumn     columnKindType = "json"
+	vectorColumn   columnKindType = "vector"
+)
+
+var columnTypes = map[string]map[columnKindType]string{
+	"postgres": {
+		smallintColumn: "SMALLINT",
+		intColumn:      "INTEGER",
+		bigintColumn:   "BIGINT",
+		floatColumn:    "REAL",
+		doubleColumn:   "DOUBLE PRECISION",
+		boolColumn:     "BOOLEAN",
+		stringColumn:   "TEXT",
+		timeColumn:     "TIMESTAMP",
+		bytesColumn:    "BYTEA",
+		jsonColumn:     "JSONB",
+		vectorColumn:   "vector",
+	},
+	"sqlite3": {
+		smallintColumn: "INTEGER",
+		intColumn:      "INTEGER",
+		bigintColumn:   "INTEGER",
+		floatColumn:    "REAL",
+		doubleColumn:   "REAL",
+		boolColumn:     "BOOLEAN",
+		stringColumn:   "TEXT",
+		timeColumn:     "DATETIME",
+		bytesColumn:    "BLOB",
+		jsonColumn:     "TEXT",
+		vectorColumn:   "TEXT",
+	},
+	"duckdb": {
+		smallintColumn: "SMALLINT",
+		intColumn:      "INTEGER",
+		bigintColumn:   "BIGINT",
+		floatColumn:    "REAL",
+		doubleColumn:   "DOUBLE",
+		boolColumn:     "BOOLEAN",
+		stringColumn:   "VARCHAR",
+		timeColumn:     "TIMESTAMP",
+		bytesColumn:    "BLOB",
+		jsonColumn:     "VARCHAR",
+		vectorColumn:   "VARCHAR",
+	},
+	"mysql": {
+		smallintColumn: "SMALLINT",
+		intColumn:      "INT",
+		bigintColumn:   "BIGINT",
+		floatColumn:    "FLOAT",
+		doubleColumn:   "DOUBLE",
+		boolColumn:     "BOOLEAN",
+		stringColumn:   "VARCHAR(255)",
+		timeColumn:     "DATETIME(6)",
+		bytesColumn:    "LONGBLOB",
+		jsonColumn:     "JSON",
+		vectorColumn:   "LONGTEXT",
+	},
+	"sqlserver": {
+		smallintColumn: "SMALLINT",
+		intColumn:      "INT",
+		bigintColumn:   "BIGINT",
+		floatColumn:    "REAL",
+		doubleColumn:   "FLOAT",
+		boolColumn:     "BIT",
+		stringColumn:   "NVARCHAR(255)",
+		timeColumn:     "DATETIME2",
+		bytesColumn:    "VARBINARY(MAX)",
+		jsonColumn:     "NVARCHAR(MAX)",
+		vectorColumn:   "NVARCHAR(MAX)",
+	},
+	"oracle": {
+		smallintColumn: "NUMBER(5)",
+		intColumn:      "NUMBER(10)",
+		bigintColumn:   "NUMBER(19)",
+		floatColumn:    "BINARY_FLOAT",
+		doubleColumn:   "BINARY_DOUBLE",
+		boolColumn:     "NUMBER(1)",
+		stringColumn:   "VARCHAR2(255)",
+		timeColumn:     "TIMESTAMP",
+		bytesColumn:    "BLOB",
+		jsonColumn:     "CLOB",
+		vectorColumn:   "CLOB",
+	},
+}
+
+func init() {
+	columnTypes["mariadb"] = columnTypes["mysql"]
+}
+
+var (
+	bytesType  = reflect.TypeOf([]byte{})
+	vectorType = reflect.TypeOf(Vector{})
+
+	nullTypes = map[reflect.Type]columnKindType{
+		reflect.TypeOf(sql.NullString{}):  stringColumn,
+		reflect.TypeOf(sql.NullInt64{}):   bigintColumn,
+		reflect.TypeOf(sql.NullInt32{}):   intColumn,
+		reflect.TypeOf(sql.NullFloat64{}): doubleColumn,
+		reflect.TypeOf(sql.NullBool{}):    boolColumn,
+		reflect.TypeOf(sql.NullTime{}):    timeColumn,
+	}
+)
+
+// columnKind returns the kind of column used for storing
+// the attribute if it has no `type=<sql type>` option
+func columnKind(t reflect.Type, field *structs.FieldInfo) (columnKindType, error) {
+	switch field.ModifierName {
+	case "json", "jsonNullable":
+		return jsonColumn, nil
+	case "encrypted":
+		return bytesColumn, nil
+	case "vector":
+		return vectorColumn, nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if kind, found := nullTypes[t]; found {
+		return kind, nil
+	}
+
+	switch {
+	case t == timeType:
+		return timeColumn, nil
+	case t == vectorType:
+		return vectorColumn, nil
+	case t == bytesType:
+		return bytesColumn, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return smallintColumn, nil
+	case reflect.Int32, reflect.Uint16:
+		return intColumn, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return bigintColumn, nil
+	case reflect.Float32:
+		return floatColumn, nil
+	case reflect.Float64:
+		return doubleColumn, nil
+	case reflect.Bool:
+		return boolColumn, nil
+	case reflect.String:
+		return stringColumn, nil
+	}
+
+	if field.SQLType != "" {
+		return "", nil
+	}
+
+	return "", fmt.Errorf(
+		"can't infer the column type of attribute '%s' of type %v, please declare it with the `type=<sql type>` option",
+		field.Name, t,
+	)
+}
+
+func isIntegerKind(kind columnKindType) bool {
+	return kind == smallintColumn || kind == intColumn || kind == bigintColumn
+}
+
+// autoIncrementColumn returns the type and constraints of
+// an auto incremented primary key on each of the dialects
+func autoIncrementColumn(driver string, kind columnKindType, sqlType string) string {
+	switch driver {
+	case "postgres":
+		switch kind {
+		case smallintColumn:
+			return "SMALLSERIAL PRIMARY KEY"
+		case intColumn:
+			return "SERIAL PRIMARY KEY"
+		default:
+			return "BIGSERIAL PRIMARY KEY"
+		}
+	case "sqlite3":
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	case "mysql", "mariadb":
+		return sqlType + " AUTO_INCREMENT PRIMARY KEY"
+	case "sqlserver":
+		return sqlType + " IDENTITY(1,1) PRIMARY KEY"
+	case "oracle":
+		return sqlType + " GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"
+	default:
+		// DuckDB uses a sequence, which is added by the caller:
+		return sqlType
+	}
+}
@@ -0,0 +1,145 @@
+package ksql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	type user struct {
+		ID        int            `ksql:"id"`
+		Name      string         `ksql:"name,type=varchar(50),notnull"`
+		Email     string         `ksql:"email,notnull,unique"`
+		Age       *int           `ksql:"age"`
+		Balance   float64        `ksql:"balance,type=decimal(10,2)"`
+		Nickname  sql.NullString `ksql:"nickname"`
+		Address   address        `ksql:"address,json"`
+		Avatar    []byte         `ksql:"avatar"`
+		Active    bool           `ksql:"active"`
+		CreatedAt time.Time      `ksql:"created_at,timeNowUTC"`
+	}
+
+	t.Run("should build the query for each dialect", func(t *testing.T) {
+		tests := []struct {
+			driver        string
+			expectedQuery string
+		}{
+			{
+				driver: "postgres",
+				expectedQuery: `CREATE TABLE "users" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"name" varchar(50) NOT NULL,
+	"email" TEXT NOT NULL UNIQUE,
+	"age" BIGINT,
+	"balance" decimal(10,2),
+	"nickname" TEXT,
+	"address" JSONB,
+	"avatar" BYTEA,
+	"active" BOOLEAN,
+	"created_at" TIMESTAMP
+)`,
+			},
+			{
+				driver: "sqlite3",
+				expectedQuery: "CREATE TABLE `users` (\n" +
+					"\t`id` INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+					"\t`name` varchar(50) NOT NULL,\n" +
+					"\t`email` TEXT NOT NULL UNIQUE,\n" +
+					"\t`age` INTEGER,\n" +
+					"\t`balance` decimal(10,2),\n" +
+					"\t`nickname` TEXT,\n" +
+					"\t`address` TEXT,\n" +
+					"\t`avatar` BLOB,\n" +
+					"\t`active` BOOLEAN,\n" +
+					"\t`created_at` DATETIME\n" +
+					")",
+			},
+			{
+				driver: "sqlserver",
+				expectedQuery: `CREATE TABLE [users] (
+	[id] BIGINT IDENTITY(1,1) PRIMARY KEY,
+	[name] varchar(50) NOT NULL,
+	[email] NVARCHAR(255) NOT NULL UNIQUE,
+	[age] BIGINT,
+	[balance] decimal(10,2),
+	[nickname] NVARCHAR(255),
+	[address] NVARCHAR(MAX),
+	[avatar] VARBINARY(MAX),
+	[active] BIT,
+	[created_at] DATETIME2
+)`,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.driver, func(t *testing.T) {
+				query, err := CreateTableSQL(test.driver, NewTable("users"), &user{})
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, query, test.expectedQuery)
+			})
+		}
+	})
+
+	t.Run("should create the sequence of the ID on duckdb", func(t *testing.T) {
+		type post struct {
+			ID    int    `ksql:"id"`
+			Title string `ksql:"title"`
+		}
+
+		query, err := CreateTableSQL("duckdb", NewTable("posts").WithStruct(post{}), nil)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, `CREATE SEQUENCE IF NOT EXISTS posts_id_seq;
+CREATE TABLE "posts" (
+	"id" BIGINT PRIMARY KEY DEFAULT nextval('posts_id_seq'),
+	"title" VARCHAR
+)`)
+	})
+
+	t.Run("should use composite primary keys without auto increment", func(t *testing.T) {
+		type userPermission struct {
+			UserID int    `ksql:"user_id"`
+			PermID int    `ksql:"perm_id"`
+			Type   string `ksql:"type"`
+		}
+
+		query, err := CreateTableSQL("mysql", NewTable("user_permissions", "user_id", "perm_id"), &userPermission{})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, "CREATE TABLE `user_permissions` (\n"+
+			"\t`user_id` BIGINT NOT NULL,\n"+
+			"\t`perm_id` BIGINT NOT NULL,\n"+
+			"\t`type` VARCHAR(255),\n"+
+			"\tPRIMARY KEY (`user_id`, `perm_id`)\n"+
+			")")
+	})
+
+	t.Run("should report attributes with unknown types", func(t *testing.T) {
+		type user struct {
+			ID   int      `ksql:"id"`
+			Tags []string `ksql:"tags"`
+		}
+
+		_, err := CreateTableSQL("postgres", NewTable("users"), &user{})
+		tt.AssertErrContains(t, err, "tags", "type=<sql type>")
+	})
+
+	t.Run("should report missing ID attributes", func(t *testing.T) {
+		type user struct {
+			Name string `ksql:"name"`
+		}
+
+		_, err := CreateTableSQL("postgres", NewTable("users"), &user{})
+		tt.AssertErrContains(t, err, "ID column", "id")
+	})
+
+	t.Run("should report unsupported drivers", func(t *testing.T) {
+		_, err := CreateTableSQL("fakedriver", NewTable("users"), &user{})
+		tt.AssertErrContains(t, err, "unsupported driver", "fakedriver")
+	})
+}
@@ -0,0 +1,231 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// Cursor allows the user to iterate over the results of
+// a query one row at a time, which is useful for processing
+// more rows than would fit in memory without using callbacks.
+//
+// It is created by the `DB.QueryIter()` method and should be
+// used as in the example below:
+//
+//	cursor := db.QueryIter(ctx, "FROM users WHERE age > ?", 42)
+//	defer cursor.Close()
+//
+//	var u User
+//	for cursor.Next(&u) {
+//		fmt.Println(u.Name)
+//	}
+//	if err := cursor.Err(); err != nil {
+//		return err
+//	}
+//
+// A Cursor is not safe for concurrent use.
+type Cursor struct {
+	ctx     context.Context
+	db      DB
+	query   string
+	params  []interface{}
+	rows    Rows
+	err     error
+	closed  bool
+	recType reflect.Type
+	info    structs.StructInfo
+	scanner *rowScanner
+
+	// cancel releases the resources of the timeout
+	// applied to the query when the cursor is closed
+	cancel context.CancelFunc
+}
+
+// QueryIter returns a Cursor for iterating over the results
+// of the input query.
+//
+// The query is only sent to the database on the first call
+// to Cursor.Next(), this allows KSQL to build the SELECT part
+// of the query based on the type of the record if the query
+// starts with `FROM`, just like on the Query method.
+//
+// Any errors, including errors from running the query,
+// are reported by the Cursor.Err() method after
+// Cursor.Next() returns false.
+//
+// The cursor must be closed after use, otherwise
+// the database connection won't be released.
+func (c DB) QueryIter(
+	ctx context.Context,
+	query string,
+	params ...interface{},
+) *Cursor {
+	return &Cursor{
+		ctx:    c.withTimeLocation(c.withEncryptor(ctx)),
+		db:     c.contextTx(ctx),
+		query:  query,
+		params: params,
+	}
+}
+
+// Next loads the next row from the database into the
+// input record, which must be a pointer to struct.
+//
+// It returns false when there are no more rows
+// or if an error occurs, in which case the error
+// is available on the Err() method.
+func (c *Cursor) Next(record interface{}) bool {
+	if c.closed {
+		return false
+	}
+
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	if err := assertStructPtr(t); err != nil {
+		return c.fail(fmt.Errorf("ksql: expected record to be a pointer to struct, but got: %T", record))
+	}
+	if v.IsNil() {
+		return c.fail(fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record))
+	}
+
+	if c.rows == nil {
+		err := c.start(t.Elem())
+		if err != nil {
+			return c.fail(err)
+		}
+	} else if c.recType != t.Elem() {
+		return c.fail(fmt.Errorf(
+			"ksql: all calls to Cursor.Next() must use the same record type, expected %v but got %v",
+			reflect.PtrTo(c.recType), t,
+		))
+	}
+
+	if !c.rows.Next() {
+		return c.fail(c.rows.Err())
+	}
+
+	if c.scanner == nil {
+		scanner, err := newRowScanner(c.ctx, c.db.dialect, c.rows, c.recType, c.info)
+		if err != nil {
+			return c.fail(err)
+		}
+		c.scanner = scanner
+	}
+
+	err := c.scanner.scan(v)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	return true
+}
+
+// start runs the query of the cursor through the middlewares of the
+// DB, which might rewrite it just like on the other query methods
+func (c *Cursor) start(structType reflect.Type) error {
+	op := Operation{Method: "QueryIter", Query: c.query, Params: c.params}
+	return c.db.intercept(c.ctx, op, func(ctx context.Context, op Operation) error {
+		c.ctx, c.query, c.params = ctx, op.Query, op.Params
+		return c.runQuery(structType)
+	})
+}
+
+func (c *Cursor) runQuery(structType reflect.Type) error {
+	info, err := c.db.naming.GetTagInfo(structType)
+	if err != nil {
+		return err
+	}
+
+	firstToken := strings.ToUpper(getFirstToken(c.query))
+	if info.IsNestedStruct && firstToken == "SELECT" {
+		// This error check is necessary, since if we can't build the select part of the query this feature won't work.
+		return fmt.Errorf("can't generate SELECT query for nested struct: when using this feature omit the SELECT part of the query")
+	}
+
+	query := c.query
+	if firstToken == "FROM" {
+		selectPrefix, err := buildSelectQuery(c.db.dialect, structType, info, selectQueryCache[c.db.dialect.DriverName()])
+		if err != nil {
+			return err
+		}
+		query = selectPrefix + query
+	}
+
+	query, err = addSoftDeleteFilter(c.ctx, c.db.dialect, query, structType, info)
+	if err != nil {
+		return err
+	}
+
+	query, params, err := addTenantFilter(c.ctx, c.db.dialect, query, c.params, structType, info)
+	if err != nil {
+		return err
+	}
+
+	query, err = addLockClause(c.ctx, c.db.dialect, query)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = expandSliceParams(c.db.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	c.ctx, c.cancel = c.db.withTimeout(c.ctx)
+
+	rows, err := c.db.readDB(c.ctx).QueryContext(c.ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("error running query: %s", err)
+	}
+
+	c.rows = rows
+	c.recType = structType
+	c.info = info
+	return nil
+}
+
+// fail saves the error (if any) and closes the
+// cursor, it always returns false for convenience.
+func (c *Cursor) fail(err error) bool {
+	if c.err == nil {
+		c.err = err
+	}
+
+	closeErr := c.Close()
+	if c.err == nil {
+		c.err = closeErr
+	}
+
+	return false
+}
+
+// Err returns the error, if any, that happened during
+// the iteration, it should be checked after Next() returns false.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close releases the resources held by the Cursor.
+//
+// It is safe to call Close more than once and it is
+// called automatically when Next() returns false.
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if c.cancel != nil {
+		defer c.cancel()
+	}
+
+	if c.rows == nil {
+		return nil
+	}
+
+	return c.rows.Close()
+}
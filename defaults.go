@@ -0,0 +1,53 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// Defaulter can be implemented by the records passed to Insert and
+// Upsert for filling default values that can't be declared with the
+// `default=<value>` modifier, e.g. values computed from other attributes:
+//
+//	func (u *User) SetDefaults(ctx context.Context) error {
+//		if u.DisplayName == "" {
+//			u.DisplayName = u.FirstName + " " + u.LastName
+//		}
+//		return nil
+//	}
+//
+// It is called after the `default=<value>` modifiers are applied.
+type Defaulter interface {
+	SetDefaults(ctx context.Context) error
+}
+
+// setDefaultValues sets the attributes with the `default=<value>` modifier
+// that have zero values to their default values, and then calls the
+// SetDefaults method if the record implements the Defaulter interface.
+func setDefaultValues(ctx context.Context, structValue reflect.Value, info structs.StructInfo, record interface{}) error {
+	for _, field := range info.Fields() {
+		if !field.Default.IsValid() {
+			continue
+		}
+
+		attr := structValue.FieldByIndex(field.Path)
+		if !attr.IsZero() {
+			continue
+		}
+
+		if attr.Kind() == reflect.Ptr {
+			// Allocate a new pointer so the records don't share the default value:
+			attr.Set(reflect.New(attr.Type().Elem()))
+			attr.Elem().Set(field.Default.Elem())
+			continue
+		}
+		attr.Set(field.Default)
+	}
+
+	if defaulter, ok := record.(Defaulter); ok {
+		return defaulter.SetDefaults(ctx)
+	}
+	return nil
+}
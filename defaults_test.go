@@ -0,0 +1,96 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type userWithDefaults struct {
+	ID       uint    `ksql:"id"`
+	Status   string  `ksql:"status,default=pending"`
+	Score    *int    `ksql:"score,default=10"`
+	Active   bool    `ksql:"active,default=true"`
+	Ratio    float64 `ksql:"ratio,default=0.5"`
+	Nickname string  `ksql:"nickname"`
+}
+
+func (u *userWithDefaults) SetDefaults(ctx context.Context) error {
+	if u.Nickname == "" {
+		u.Nickname = u.Status + "-user"
+	}
+	return nil
+}
+
+func TestDefaultValues(t *testing.T) {
+	t.Run("should set the default values of the empty attributes on insert", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		var u1, u2 userWithDefaults
+		err = dryRun.Insert(context.Background(), usersTable, &u1)
+		tt.AssertNoErr(t, err)
+		err = dryRun.Insert(context.Background(), usersTable, &u2)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u1.Status, "pending")
+		tt.AssertEqual(t, *u1.Score, 10)
+		tt.AssertEqual(t, u1.Active, true)
+		tt.AssertEqual(t, u1.Ratio, 0.5)
+		tt.AssertEqual(t, u1.Nickname, "pending-user")
+
+		// The records should not share the default pointers:
+		*u1.Score = 42
+		tt.AssertEqual(t, *u2.Score, 10)
+	})
+
+	t.Run("should not overwrite the attributes that are set", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		score := 0
+		u := userWithDefaults{Status: "active", Score: &score, Nickname: "bia"}
+		err = dryRun.Upsert(context.Background(), usersTable, &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.Status, "active")
+		tt.AssertEqual(t, *u.Score, 0)
+		tt.AssertEqual(t, u.Nickname, "bia")
+	})
+
+	t.Run("should report invalid default values", func(t *testing.T) {
+		type invalidDefault struct {
+			Age int `ksql:"age,default=abc"`
+		}
+		_, err := structs.GetTagInfo(reflect.TypeOf(invalidDefault{}))
+		tt.AssertErrContains(t, err, "age", "invalid default value")
+
+		type unsupportedDefault struct {
+			Tags []string `ksql:"tags,default=a"`
+		}
+		_, err = structs.GetTagInfo(reflect.TypeOf(unsupportedDefault{}))
+		tt.AssertErrContains(t, err, "tags", "not supported")
+	})
+
+	t.Run("should report errors from the Defaulter", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Insert(context.Background(), usersTable, &failingDefaulter{})
+		tt.AssertErrContains(t, err, "fake error")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
+
+type failingDefaulter struct {
+	ID   uint   `ksql:"id"`
+	Name string `ksql:"name"`
+}
+
+func (f *failingDefaulter) SetDefaults(ctx context.Context) error {
+	return errors.New("fake error")
+}
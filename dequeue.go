@@ -0,0 +1,208 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// DequeueOne removes one row of a table used as a work queue and saves
+// it on the job argument, which must be a pointer to struct, e.g.:
+//
+//	var job Job
+//	err := db.DequeueOne(ctx, JobsTable, "WHERE queue = ? ORDER BY id", &job, "emails")
+//	if ksql.IsNotFound(err) {
+//		// The queue is empty
+//	}
+//
+// The where argument contains the clauses that come after the
+// `FROM <table>` part of the query, and can be empty for any row.
+//
+// The row is selected with `FOR UPDATE SKIP LOCKED` on Postgres, MySQL 8
+// and MariaDB 10.6+ and with the `UPDLOCK, ROWLOCK, READPAST` hints on
+// SQL Server, so concurrent workers never receive the same job and never
+// wait for each other, and then it is deleted on the same transaction.
+// The other dialects are not supported.
+//
+// When called inside a transaction the row is only removed when the
+// transaction commits, so if the job is processed on the same transaction
+// and the processing fails the job returns to the queue, e.g.:
+//
+//	err := db.Transaction(ctx, func(tx ksql.Provider) error {
+//		ctx := ksql.InjectTx(ctx, tx)
+//
+//		var job Job
+//		err := db.DequeueOne(ctx, JobsTable, "ORDER BY id", &job)
+//		if err != nil {
+//			return err
+//		}
+//		return process(ctx, job)
+//	})
+//
+// If the struct has an attribute with the `softDelete` modifier
+// the row is soft deleted instead, just like on the Delete method,
+// and on tables created with `WithAudit()` the deletion is audited.
+func (c DB) DequeueOne(
+	ctx context.Context,
+	table Table,
+	where string,
+	job interface{},
+	params ...interface{},
+) error {
+	op := Operation{Method: "DequeueOne", Table: table.name, Query: where, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.dequeueOne(ctx, table, op.Query, job, op.Params...)
+	})
+}
+
+func (c DB) dequeueOne(
+	ctx context.Context,
+	table Table,
+	where string,
+	job interface{},
+	params ...interface{},
+) error {
+	t := reflect.TypeOf(job)
+	if err := assertStructPtr(t); err != nil {
+		return fmt.Errorf("ksql: expected job to be a pointer to struct, but got: %T", job)
+	}
+
+	query, err := buildDequeueQuery(c.dialect, c.naming, table, t.Elem(), where, 1)
+	if err != nil {
+		return err
+	}
+
+	return c.startTransaction(ctx, func(tx Provider) error {
+		db := tx.(DB)
+		err := db.queryOne(WithLock(ctx, ForUpdate.SkipLocked()), job, query, params...)
+		if err != nil {
+			return err
+		}
+
+		return db.delete(ctx, table, job)
+	})
+}
+
+// DequeueMany works like DequeueOne but removes up to limit rows
+// at once, saving them on the jobs argument, which must be a
+// pointer to a slice of structs, e.g.:
+//
+//	var jobs []Job
+//	err := db.DequeueMany(ctx, JobsTable, "WHERE queue = ? ORDER BY id", &jobs, 100, "emails")
+//
+// If the queue is empty the slice is left empty and no error is returned.
+//
+// On tables created with `WithAudit()` the jobs are deleted one
+// at a time, so each of them is saved on its own audit entry.
+func (c DB) DequeueMany(
+	ctx context.Context,
+	table Table,
+	where string,
+	jobs interface{},
+	limit int,
+	params ...interface{},
+) error {
+	op := Operation{Method: "DequeueMany", Table: table.name, Query: where, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.dequeueMany(ctx, table, op.Query, jobs, limit, op.Params...)
+	})
+}
+
+func (c DB) dequeueMany(
+	ctx context.Context,
+	table Table,
+	where string,
+	jobs interface{},
+	limit int,
+	params ...interface{},
+) error {
+	if limit < 1 {
+		return fmt.Errorf("ksql: the limit of DequeueMany must be positive, but got: %d", limit)
+	}
+
+	slicePtr := reflect.ValueOf(jobs)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.IsNil() || slicePtr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ksql: expected jobs to be a pointer to a slice of structs, but got: %T", jobs)
+	}
+
+	structType, _, err := structs.DecodeAsSliceOfStructs(slicePtr.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	query, err := buildDequeueQuery(c.dialect, c.naming, table, structType, where, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.startTransaction(ctx, func(tx Provider) error {
+		db := tx.(DB)
+		err := db.query(WithLock(ctx, ForUpdate.SkipLocked()), jobs, query, params...)
+		if err != nil {
+			return err
+		}
+
+		slice := slicePtr.Elem()
+		if slice.Len() == 0 {
+			return nil
+		}
+
+		if table.auditTable == "" {
+			_, err = db.deleteMany(ctx, table, jobs)
+			return err
+		}
+
+		// DeleteMany doesn't support audited tables:
+		for i := 0; i < slice.Len(); i++ {
+			job := slice.Index(i)
+			if job.Kind() != reflect.Ptr {
+				job = job.Addr()
+			}
+
+			err := db.delete(ctx, table, job.Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// buildDequeueQuery builds the query selecting up to limit rows of the
+// table, the lock clause is added later by the Query methods.
+func buildDequeueQuery(
+	dialect Dialect,
+	naming *structs.Naming,
+	table Table,
+	structType reflect.Type,
+	where string,
+	limit int,
+) (string, error) {
+	if err := table.validate(); err != nil {
+		return "", fmt.Errorf("can't dequeue from ksql.Table: %s", err)
+	}
+
+	info, err := naming.GetTagInfo(structType)
+	if err != nil {
+		return "", err
+	}
+	if info.IsNestedStruct {
+		return "", fmt.Errorf("ksql: the dequeue methods don't support nested structs")
+	}
+
+	fromQuery := strings.TrimSpace("FROM " + dialect.Escape(table.name) + " " + strings.TrimSpace(where))
+
+	switch dialect.DriverName() {
+	case "postgres", "mysql", "mariadb":
+		return fromQuery + " LIMIT " + strconv.Itoa(limit), nil
+	case "sqlserver":
+		selectQuery := buildSelectQueryForPlainStructs(dialect, structType, info)
+		return "SELECT TOP " + strconv.Itoa(limit) + " " + strings.TrimPrefix(selectQuery, "SELECT ") + fromQuery, nil
+	default:
+		return "", fmt.Errorf("ksql: the dequeue methods are not supported on driver `%s`", dialect.DriverName())
+	}
+}
@@ -0,0 +1,46 @@
+package ksql
+
+import (
+	"reflect"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestBuildDequeueQuery(t *testing.T) {
+	type job struct {
+		ID    int    `ksql:"id"`
+		Queue string `ksql:"queue"`
+	}
+
+	tests := []struct {
+		driver        string
+		expectedQuery string
+	}{
+		{
+			driver:        "postgres",
+			expectedQuery: `FROM "jobs" WHERE queue = $1 ORDER BY id LIMIT 10`,
+		},
+		{
+			driver:        "mysql",
+			expectedQuery: "FROM `jobs` WHERE queue = $1 ORDER BY id LIMIT 10",
+		},
+		{
+			driver:        "sqlserver",
+			expectedQuery: `SELECT TOP 10 [id], [queue] FROM [jobs] WHERE queue = $1 ORDER BY id`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			query, err := buildDequeueQuery(supportedDialects[test.driver], nil, NewTable("jobs"), reflect.TypeOf(job{}), " WHERE queue = $1 ORDER BY id ", 10)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+		})
+	}
+
+	t.Run("should report unsupported drivers", func(t *testing.T) {
+		_, err := buildDequeueQuery(supportedDialects["sqlite3"], nil, NewTable("jobs"), reflect.TypeOf(job{}), "", 1)
+		tt.AssertErrContains(t, err, "not supported", "sqlite3")
+	})
+}
@@ -0,0 +1,237 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TableSchema describes the columns and the indexes of a table,
+// see `DB.DescribeTable()` for more details
+type TableSchema struct {
+	Name    string
+	Columns []ColumnSchema
+
+	// PrimaryKey contains the columns of the primary
+	// key in order, or nil if the table has none
+	PrimaryKey []string
+
+	// Indexes contains the indexes of the table sorted by name, including the
+	// ones created for unique constraints, but not the primary key index
+	Indexes []IndexSchema
+}
+
+// ColumnSchema describes a single column of a table
+type ColumnSchema struct {
+	Name string
+
+	// Type is the lowercased type reported by the database, so the
+	// same Go type might have different types on each dialect, e.g.
+	// "character varying(50)" on Postgres and "varchar(50)" on MySQL.
+	Type string
+
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// IndexSchema describes an index of a table
+type IndexSchema struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// Column returns the column with the input name and true,
+// or a zero ColumnSchema and false if there is no such column
+func (s TableSchema) Column(name string) (ColumnSchema, bool) {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// DescribeTable loads the columns, the primary key and the indexes of
+// the input table from the catalog of the database, e.g. the
+// information_schema on MySQL or the pragma functions on SQLite.
+//
+// The columns are returned in the order they were declared and if the
+// table doesn't exist an error wrapping `ksql.ErrRecordNotFound` is returned.
+//
+// On Postgres the table is looked up using the `search_path`, on Oracle
+// the names of the table, columns and indexes are lowercased, and on DuckDB
+// the indexes are not loaded, since its catalog doesn't list their columns.
+func (c DB) DescribeTable(ctx context.Context, tableName string) (TableSchema, error) {
+	queries, found := describeTableQueries[c.dialect.DriverName()]
+	if !found {
+		return TableSchema{}, fmt.Errorf("ksql: DescribeTable is not supported on driver `%s`", c.dialect.DriverName())
+	}
+
+	var columns []describedColumn
+	err := c.Query(ctx, &columns, queries.columns, tableName)
+	if err != nil {
+		return TableSchema{}, fmt.Errorf("ksql: error loading the columns of table `%s`: %s", tableName, err)
+	}
+	if len(columns) == 0 {
+		return TableSchema{}, notFoundError(ctx, NotFoundError{Table: tableName})
+	}
+
+	schema := TableSchema{
+		Name: tableName,
+	}
+
+	var indexColumns []describedIndexColumn
+	if queries.indexes != "" {
+		err = c.Query(ctx, &indexColumns, queries.indexes, tableName)
+		if err != nil {
+			return TableSchema{}, fmt.Errorf("ksql: error loading the indexes of table `%s`: %s", tableName, err)
+		}
+	}
+
+	// The rows are sorted by index name and then by position:
+	for _, row := range indexColumns {
+		if row.Primary != 0 {
+			schema.PrimaryKey = append(schema.PrimaryKey, row.ColumnName)
+			continue
+		}
+
+		last := len(schema.Indexes) - 1
+		if last < 0 || schema.Indexes[last].Name != row.IndexName {
+			schema.Indexes = append(schema.Indexes, IndexSchema{
+				Name:   row.IndexName,
+				Unique: row.Unique != 0,
+			})
+			last++
+		}
+		schema.Indexes[last].Columns = append(schema.Indexes[last].Columns, row.ColumnName)
+	}
+
+	if schema.PrimaryKey == nil {
+		// On SQLite and DuckDB the primary key is reported by the
+		// columns query, with its position on the pk attribute:
+		var pkColumns []describedColumn
+		for _, column := range columns {
+			if column.PrimaryKey > 0 {
+				pkColumns = append(pkColumns, column)
+			}
+		}
+		sort.SliceStable(pkColumns, func(i, j int) bool {
+			return pkColumns[i].PrimaryKey < pkColumns[j].PrimaryKey
+		})
+		for _, column := range pkColumns {
+			schema.PrimaryKey = append(schema.PrimaryKey, column.Name)
+		}
+	}
+
+	isPrimaryKey := map[string]bool{}
+	for _, name := range schema.PrimaryKey {
+		isPrimaryKey[name] = true
+	}
+
+	for _, column := range columns {
+		schema.Columns = append(schema.Columns, ColumnSchema{
+			Name:       column.Name,
+			Type:       strings.ToLower(column.Type),
+			Nullable:   column.Nullable != 0,
+			PrimaryKey: isPrimaryKey[column.Name],
+		})
+	}
+
+	return schema, nil
+}
+
+// describedColumn and describedIndexColumn are the rows returned by the
+// describeTableQueries, the booleans are returned as integers because
+// not all drivers can scan the boolean values of all databases into bools.
+type describedColumn struct {
+	Name       string `ksql:"name"`
+	Type       string `ksql:"type"`
+	Nullable   int    `ksql:"nullable"`
+	PrimaryKey int    `ksql:"pk"`
+}
+
+type describedIndexColumn struct {
+	IndexName  string `ksql:"index_name"`
+	ColumnName string `ksql:"column_name"`
+	Unique     int    `ksql:"is_unique"`
+	Primary    int    `ksql:"is_primary"`
+}
+
+var describeTableQueries = map[string]struct {
+	columns string
+	indexes string
+}{
+	"sqlite3": {
+		columns: `SELECT name, type, CASE WHEN "notnull" THEN 0 ELSE 1 END AS nullable, pk
+			FROM pragma_table_info(?) ORDER BY cid`,
+		indexes: `SELECT il.name AS index_name, COALESCE(ii.name, '') AS column_name, il."unique" AS is_unique, 0 AS is_primary
+			FROM pragma_index_list(?) il JOIN pragma_index_info(il.name) ii
+			WHERE il.origin != 'pk'
+			ORDER BY il.name, ii.seqno`,
+	},
+	"duckdb": {
+		// The catalog functions are used instead of `pragma_table_info()`
+		// since it fails with a catalog error for the missing tables:
+		columns: `SELECT c.column_name AS name, c.data_type AS type, CASE WHEN c.is_nullable THEN 1 ELSE 0 END AS nullable,
+				COALESCE(list_position(k.constraint_column_names, c.column_name), 0) AS pk
+			FROM duckdb_columns() c
+			LEFT JOIN duckdb_constraints() k ON k.table_oid = c.table_oid AND k.constraint_type = 'PRIMARY KEY'
+			WHERE c.table_name = ? AND c.schema_name = current_schema()
+			ORDER BY c.column_index`,
+	},
+	"postgres": {
+		columns: `SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, (NOT a.attnotnull)::int AS nullable, 0 AS pk
+			FROM pg_attribute a
+			WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`,
+		indexes: `SELECT i.relname AS index_name, a.attname AS column_name, ix.indisunique::int AS is_unique, ix.indisprimary::int AS is_primary
+			FROM pg_index ix
+			JOIN pg_class i ON i.oid = ix.indexrelid
+			JOIN unnest(ix.indkey) WITH ORDINALITY AS k(attnum, position) ON true
+			JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+			WHERE ix.indrelid = to_regclass($1)
+			ORDER BY i.relname, k.position`,
+	},
+	"mysql": {
+		columns: `SELECT column_name AS name, column_type AS type, is_nullable = 'YES' AS nullable, 0 AS pk
+			FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY ordinal_position`,
+		indexes: `SELECT index_name AS index_name, column_name AS column_name, non_unique = 0 AS is_unique, index_name = 'PRIMARY' AS is_primary
+			FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY index_name, seq_in_index`,
+	},
+	"sqlserver": {
+		columns: `SELECT c.name AS name, TYPE_NAME(c.user_type_id) AS type, CAST(c.is_nullable AS INT) AS nullable, 0 AS pk
+			FROM sys.columns c
+			WHERE c.object_id = OBJECT_ID(@p1)
+			ORDER BY c.column_id`,
+		indexes: `SELECT i.name AS index_name, c.name AS column_name, CAST(i.is_unique AS INT) AS is_unique, CAST(i.is_primary_key AS INT) AS is_primary
+			FROM sys.indexes i
+			JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+			JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+			WHERE i.object_id = OBJECT_ID(@p1) AND ic.is_included_column = 0
+			ORDER BY i.name, ic.key_ordinal`,
+	},
+	"oracle": {
+		columns: `SELECT LOWER(column_name) AS name, LOWER(data_type) AS type, CASE nullable WHEN 'Y' THEN 1 ELSE 0 END AS nullable, 0 AS pk
+			FROM user_tab_columns
+			WHERE table_name = UPPER(:1)
+			ORDER BY column_id`,
+		indexes: `SELECT LOWER(i.index_name) AS index_name, LOWER(ic.column_name) AS column_name,
+				CASE i.uniqueness WHEN 'UNIQUE' THEN 1 ELSE 0 END AS is_unique,
+				CASE WHEN c.constraint_type = 'P' THEN 1 ELSE 0 END AS is_primary
+			FROM user_indexes i
+			JOIN user_ind_columns ic ON ic.index_name = i.index_name
+			LEFT JOIN user_constraints c ON c.index_name = i.index_name AND c.constraint_type = 'P'
+			WHERE i.table_name = UPPER(:1)
+			ORDER BY i.index_name, ic.column_position`,
+	},
+}
+
+func init() {
+	describeTableQueries["mariadb"] = describeTableQueries["mysql"]
+}
@@ -3,6 +3,9 @@ package ksql
 import (
 	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
 )
 
 type insertMethod int
@@ -12,6 +15,7 @@ const (
 	insertWithOutput
 	insertWithLastInsertID
 	insertWithNoIDRetrieval
+	insertWithReturningInto
 )
 
 var supportedDialects = map[string]Dialect{
@@ -19,6 +23,9 @@ var supportedDialects = map[string]Dialect{
 	"sqlite3":   &sqlite3Dialect{},
 	"mysql":     &mysqlDialect{},
 	"sqlserver": &sqlserverDialect{},
+	"oracle":    &oracleDialect{},
+	"duckdb":    &duckdbDialect{},
+	"mariadb":   &mariadbDialect{},
 }
 
 // Dialect is used to represent the different ways
@@ -41,7 +48,7 @@ func (postgresDialect) InsertMethod() insertMethod {
 }
 
 func (postgresDialect) Escape(str string) string {
-	return `"` + str + `"`
+	return `"` + strings.ReplaceAll(str, `"`, `""`) + `"`
 }
 
 func (postgresDialect) Placeholder(idx int) string {
@@ -59,13 +66,41 @@ func (sqlite3Dialect) InsertMethod() insertMethod {
 }
 
 func (sqlite3Dialect) Escape(str string) string {
-	return "`" + str + "`"
+	return "`" + strings.ReplaceAll(str, "`", "``") + "`"
 }
 
 func (sqlite3Dialect) Placeholder(idx int) string {
 	return "?"
 }
 
+type duckdbDialect struct{}
+
+func (duckdbDialect) DriverName() string {
+	return "duckdb"
+}
+
+func (duckdbDialect) InsertMethod() insertMethod {
+	return insertWithReturning
+}
+
+func (duckdbDialect) Escape(str string) string {
+	return `"` + strings.ReplaceAll(str, `"`, `""`) + `"`
+}
+
+func (duckdbDialect) Placeholder(idx int) string {
+	return "$" + strconv.Itoa(idx+1)
+}
+
+// escapeColumn escapes the column name of a struct attribute, keeping the
+// exact case of the attributes with the `quoted` option on the dialects
+// that would convert their names to upper case, i.e. Oracle.
+func escapeColumn(dialect Dialect, info structs.StructInfo, column string) string {
+	if d, ok := dialect.(interface{ escapeExact(string) string }); ok && info.ByName(column).Quoted {
+		return d.escapeExact(column)
+	}
+	return dialect.Escape(column)
+}
+
 // GetDriverDialect instantiantes the dialect for the
 // provided driver string, if the drive is not supported
 // it returns an error
@@ -89,13 +124,29 @@ func (mysqlDialect) InsertMethod() insertMethod {
 }
 
 func (mysqlDialect) Escape(str string) string {
-	return "`" + str + "`"
+	return "`" + strings.ReplaceAll(str, "`", "``") + "`"
 }
 
 func (mysqlDialect) Placeholder(idx int) string {
 	return "?"
 }
 
+// mariadbDialect works like the mysqlDialect except that
+// it retrieves the IDs using `INSERT ... RETURNING`, which
+// is available since MariaDB 10.5 and also works for tables
+// whose IDs are not AUTO_INCREMENT, e.g. UUIDs generated by default.
+type mariadbDialect struct {
+	mysqlDialect
+}
+
+func (mariadbDialect) DriverName() string {
+	return "mariadb"
+}
+
+func (mariadbDialect) InsertMethod() insertMethod {
+	return insertWithReturning
+}
+
 type sqlserverDialect struct{}
 
 func (sqlserverDialect) DriverName() string {
@@ -107,9 +158,39 @@ func (sqlserverDialect) InsertMethod() insertMethod {
 }
 
 func (sqlserverDialect) Escape(str string) string {
-	return `[` + str + `]`
+	return `[` + strings.ReplaceAll(str, `]`, `]]`) + `]`
 }
 
 func (sqlserverDialect) Placeholder(idx int) string {
 	return "@p" + strconv.Itoa(idx+1)
 }
+
+type oracleDialect struct{}
+
+func (oracleDialect) DriverName() string {
+	return "oracle"
+}
+
+func (oracleDialect) InsertMethod() insertMethod {
+	return insertWithReturningInto
+}
+
+// Escape converts the names to upper case since this is how Oracle
+// saves the identifiers that are not quoted, otherwise the users
+// would have to quote all the names used on their own queries.
+//
+// The columns created with mixed-case names can be used by declaring
+// their attributes with the `quoted` option, e.g. `ksql:"userName,quoted"`.
+func (oracleDialect) Escape(str string) string {
+	return oracleDialect{}.escapeExact(strings.ToUpper(str))
+}
+
+// escapeExact escapes the name keeping its case, which is used
+// for the attributes declared with the `quoted` option
+func (oracleDialect) escapeExact(str string) string {
+	return `"` + strings.ReplaceAll(str, `"`, `""`) + `"`
+}
+
+func (oracleDialect) Placeholder(idx int) string {
+	return ":" + strconv.Itoa(idx+1)
+}
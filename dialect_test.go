@@ -1,8 +1,12 @@
 package ksql
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/vingarcia/ksql/internal/structs"
 	tt "github.com/vingarcia/ksql/internal/testtools"
 )
 
@@ -22,3 +26,85 @@ func TestGetDriverDialect(t *testing.T) {
 		tt.AssertErrContains(t, err, "unsupported driver", "non-existing-driver")
 	})
 }
+
+func TestDialectEscape(t *testing.T) {
+	tests := []struct {
+		driver         string
+		name           string
+		expectedOutput string
+	}{
+		{driver: "postgres", name: "order", expectedOutput: `"order"`},
+		{driver: "postgres", name: "Group", expectedOutput: `"Group"`},
+		{driver: "postgres", name: `weird"name`, expectedOutput: `"weird""name"`},
+		{driver: "duckdb", name: `weird"name`, expectedOutput: `"weird""name"`},
+		{driver: "sqlite3", name: "order", expectedOutput: "`order`"},
+		{driver: "sqlite3", name: "weird`name", expectedOutput: "`weird``name`"},
+		{driver: "mysql", name: "order", expectedOutput: "`order`"},
+		{driver: "mysql", name: "weird`name", expectedOutput: "`weird``name`"},
+		{driver: "mariadb", name: "Group", expectedOutput: "`Group`"},
+		{driver: "sqlserver", name: "order", expectedOutput: "[order]"},
+		{driver: "sqlserver", name: "weird]name", expectedOutput: "[weird]]name]"},
+		{driver: "oracle", name: "Group", expectedOutput: `"GROUP"`},
+		{driver: "oracle", name: `weird"name`, expectedOutput: `"WEIRD""NAME"`},
+	}
+	for _, test := range tests {
+		t.Run(test.driver+"/"+test.name, func(t *testing.T) {
+			tt.AssertEqual(t, supportedDialects[test.driver].Escape(test.name), test.expectedOutput)
+		})
+	}
+}
+
+func TestEscapeColumn(t *testing.T) {
+	type record struct {
+		Order string `ksql:"order"`
+		Group string `ksql:"Group,quoted"`
+	}
+	info, err := structs.GetTagInfo(reflect.TypeOf(record{}))
+	tt.AssertNoErr(t, err)
+
+	t.Run("should keep the case of quoted columns on oracle", func(t *testing.T) {
+		dialect := supportedDialects["oracle"]
+		tt.AssertEqual(t, escapeColumn(dialect, info, "order"), `"ORDER"`)
+		tt.AssertEqual(t, escapeColumn(dialect, info, "Group"), `"Group"`)
+	})
+
+	t.Run("should escape quoted columns normally on the other dialects", func(t *testing.T) {
+		tt.AssertEqual(t, escapeColumn(supportedDialects["postgres"], info, "Group"), `"Group"`)
+		tt.AssertEqual(t, escapeColumn(supportedDialects["mysql"], info, "Group"), "`Group`")
+		tt.AssertEqual(t, escapeColumn(supportedDialects["sqlserver"], info, "Group"), "[Group]")
+	})
+	t.Run("should keep the case of quoted columns on the delete queries on oracle", func(t *testing.T) {
+		type quotedRecord struct {
+			ID int `ksql:"Id,quoted"`
+		}
+		type softDeletedRecord struct {
+			ID        int        `ksql:"Id,quoted"`
+			DeletedAt *time.Time `ksql:"DeletedAt,quoted,softDelete"`
+		}
+
+		var readQueries []string
+		ctx := context.Background()
+		dryRun, err := NewDryRun("oracle", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				readQueries = append(readQueries, query)
+				return &fakeUserRows{}, nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Delete(ctx, NewTable("records", "Id"), &quotedRecord{ID: 42})
+		tt.AssertNoErr(t, err)
+		err = dryRun.Delete(ctx, NewTable("records", "Id"), &softDeletedRecord{ID: 43})
+		tt.AssertNoErr(t, err)
+		var records []softDeletedRecord
+		err = dryRun.Query(ctx, &records, `SELECT "Id" FROM records`)
+		tt.AssertNoErr(t, err)
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 2)
+		tt.AssertEqual(t, statements[0].Query, `DELETE FROM "RECORDS" WHERE "Id" = :1`)
+		tt.AssertEqual(t, statements[1].Query, `UPDATE "RECORDS" SET "DeletedAt" = :1 WHERE "Id" = :2 AND "DeletedAt" IS NULL`)
+		tt.AssertEqual(t, statements[1].Params[0].(time.Time).Location(), time.UTC)
+		tt.AssertEqual(t, readQueries, []string{`SELECT "Id" FROM records WHERE "DeletedAt" IS NULL`})
+	})
+}
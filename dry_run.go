@@ -0,0 +1,164 @@
+package ksql
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// DryRunStatement is a statement recorded by a DryRun instead
+// of being sent to the database
+type DryRunStatement struct {
+	Query  string
+	Params []interface{}
+}
+
+// DryRun is a ksql.DB that records the statements that would write
+// to the database instead of sending them, which is useful for writing
+// golden tests of the generated SQL or for previewing batch jobs, e.g.:
+//
+//	dryRun, err := ksql.NewDryRun("postgres", nil)
+//	err = dryRun.Insert(ctx, usersTable, &user)
+//	fmt.Println(dryRun.Statements())
+//
+// All the statements sent with Exec and the INSERT, UPDATE and DELETE
+// statements are recorded and reported as affecting a single row,
+// so the records are not updated with the generated IDs.
+//
+// The other queries are sent to the reads adapter, or, if it is nil,
+// they return no rows.
+type DryRun struct {
+	DB
+
+	adapter *dryRunAdapter
+}
+
+// NewDryRun instantiates a new DryRun for the input dialect,
+// the reads adapter is optional.
+func NewDryRun(dialectName string, reads DBAdapter) (DryRun, error) {
+	adapter := &dryRunAdapter{
+		reads: reads,
+		mu:    &sync.Mutex{},
+	}
+
+	db, err := NewWithAdapter(adapter, dialectName)
+	if err != nil {
+		return DryRun{}, err
+	}
+
+	return DryRun{
+		DB:      db,
+		adapter: adapter,
+	}, nil
+}
+
+// Statements returns the statements recorded so far
+func (d DryRun) Statements() []DryRunStatement {
+	d.adapter.mu.Lock()
+	defer d.adapter.mu.Unlock()
+
+	return append([]DryRunStatement(nil), d.adapter.statements...)
+}
+
+// Reset discards the statements recorded so far
+func (d DryRun) Reset() {
+	d.adapter.mu.Lock()
+	defer d.adapter.mu.Unlock()
+
+	d.adapter.statements = nil
+}
+
+type dryRunAdapter struct {
+	reads DBAdapter
+
+	mu         *sync.Mutex
+	statements []DryRunStatement
+}
+
+func (d *dryRunAdapter) record(query string, params []interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.statements = append(d.statements, DryRunStatement{
+		Query:  query,
+		Params: params,
+	})
+}
+
+// ExecContext implements the DBAdapter interface
+func (d *dryRunAdapter) ExecContext(ctx context.Context, query string, params ...interface{}) (Result, error) {
+	d.record(query, params)
+	return NewMockResult(0, 1), nil
+}
+
+// QueryContext implements the DBAdapter interface
+func (d *dryRunAdapter) QueryContext(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+	switch strings.ToUpper(getFirstToken(query)) {
+	case "INSERT", "UPDATE", "DELETE":
+		d.record(query, params)
+
+		// The statements with a RETURNING clause expect to read a row:
+		return &dryRunRows{remaining: 1}, nil
+	}
+
+	if d.reads == nil {
+		return &dryRunRows{}, nil
+	}
+
+	return d.reads.QueryContext(ctx, query, params...)
+}
+
+// BeginTx implements the TxBeginner interface, the
+// transactions of a DryRun are never sent to the database
+func (d *dryRunAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	return dryRunTx{dryRunAdapter: d}, nil
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (d *dryRunAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	return dryRunTx{dryRunAdapter: d}, nil
+}
+
+type dryRunTx struct {
+	*dryRunAdapter
+}
+
+// Rollback implements the Tx interface
+func (dryRunTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+// Commit implements the Tx interface
+func (dryRunTx) Commit(ctx context.Context) error {
+	return nil
+}
+
+// dryRunRows returns the configured number of
+// rows without changing the scanned values
+type dryRunRows struct {
+	remaining int
+}
+
+func (r *dryRunRows) Scan(...interface{}) error {
+	return nil
+}
+
+func (r *dryRunRows) Close() error {
+	return nil
+}
+
+func (r *dryRunRows) Next() bool {
+	if r.remaining == 0 {
+		return false
+	}
+	r.remaining--
+	return true
+}
+
+func (r *dryRunRows) Err() error {
+	return nil
+}
+
+func (r *dryRunRows) Columns() ([]string, error) {
+	return []string{}, nil
+}
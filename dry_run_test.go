@@ -0,0 +1,92 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Run("should record the write statements without sending them", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		// A single column is used since the
+		// order of the columns is not deterministic:
+		type dryRunUser struct {
+			ID   uint   `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		ctx := context.Background()
+		err = dryRun.Insert(ctx, usersTable, &dryRunUser{Name: "Bia"})
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Delete(ctx, usersTable, 42)
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.Exec(ctx, "UPDATE users SET age = $1", 30)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{
+			{
+				Query:  `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`,
+				Params: []interface{}{"Bia"},
+			},
+			{
+				Query:  `DELETE FROM "users" WHERE "id" = $1`,
+				Params: []interface{}{42},
+			},
+			{
+				Query:  `UPDATE users SET age = $1`,
+				Params: []interface{}{30},
+			},
+		})
+
+		dryRun.Reset()
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should record the statements made inside transactions", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Transaction(context.Background(), func(db Provider) error {
+			_, err := db.Exec(context.Background(), "DELETE FROM users")
+			return err
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{
+			{Query: "DELETE FROM users"},
+		})
+	})
+
+	t.Run("should send the read queries to the reads adapter", func(t *testing.T) {
+		var readQueries []string
+		dryRun, err := NewDryRun("sqlite3", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				readQueries = append(readQueries, query)
+				return &dryRunRows{}, nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		var users []user
+		err = dryRun.Query(context.Background(), &users, "SELECT id FROM users")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, readQueries, []string{"SELECT id FROM users"})
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should return no rows for read queries if there is no reads adapter", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		var u user
+		err = dryRun.QueryOne(context.Background(), &u, "FROM users WHERE id = ?", 42)
+		tt.AssertEqual(t, IsNotFound(err), true)
+	})
+}
@@ -0,0 +1,185 @@
+package ksql
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+// Encryptor is used by the `encrypted` modifier for encrypting the
+// attributes before saving them and for decrypting them when they
+// are loaded from the database, e.g.:
+//
+//	type User struct {
+//		ID  int    `ksql:"id"`
+//		SSN string `ksql:"ssn,encrypted"`
+//	}
+//
+// The `ksql.NewAESGCMEncryptor()` function returns a default
+// implementation, and other implementations can delegate
+// to a KMS or to Vault.
+//
+// The Encryptor is configured with the `ksql.Config.Encryptor`
+// attribute or on the context with `ksql.WithEncryptor()`.
+type Encryptor interface {
+	Encrypt(ctx context.Context, plaintext []byte) (ciphertext []byte, err error)
+	Decrypt(ctx context.Context, ciphertext []byte) (plaintext []byte, err error)
+}
+
+type encryptorKey struct{}
+
+// WithEncryptor returns a context whose operations use the input
+// Encryptor for the `encrypted` attributes, overriding the one
+// configured on the ksql.Config, which is useful e.g. for using
+// a different key for each tenant.
+func WithEncryptor(ctx context.Context, encryptor Encryptor) context.Context {
+	return context.WithValue(ctx, encryptorKey{}, encryptor)
+}
+
+// withEncryptor adds the Encryptor of the DB to the
+// context unless the context already has one
+func (c DB) withEncryptor(ctx context.Context) context.Context {
+	if c.encryptor == nil || ctx.Value(encryptorKey{}) != nil {
+		return ctx
+	}
+	return WithEncryptor(ctx, c.encryptor)
+}
+
+func init() {
+	structs.RegisterAttrModifier("encrypted", encryptedModifier)
+}
+
+// encryptedModifier encrypts string and []byte attributes, and pointers
+// to them, the encrypted values are saved as []byte so the columns
+// should have a binary type, e.g. BYTEA on Postgres or BLOB on MySQL.
+var encryptedModifier = ksqlmodifiers.AttrModifier{
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		encryptor, err := encryptorFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		v := reflect.ValueOf(inputValue)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+
+		var plaintext []byte
+		switch {
+		case v.Kind() == reflect.String:
+			plaintext = []byte(v.String())
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			plaintext = v.Bytes()
+		default:
+			return nil, fmt.Errorf("ksql: the encrypted modifier only supports string and []byte attributes, but got: %T", inputValue)
+		}
+
+		return encryptor.Encrypt(ctx, plaintext)
+	},
+
+	Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+		encryptor, err := encryptorFromContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		v := reflect.ValueOf(attrPtr).Elem()
+		if dbValue == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		var ciphertext []byte
+		switch value := dbValue.(type) {
+		case []byte:
+			ciphertext = value
+		case string:
+			ciphertext = []byte(value)
+		default:
+			return fmt.Errorf("ksql: unexpected type %T for an encrypted column, expected []byte", dbValue)
+		}
+
+		plaintext, err := encryptor.Decrypt(ctx, ciphertext)
+		if err != nil {
+			return fmt.Errorf("ksql: error decrypting attribute: %s", err)
+		}
+
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.New(v.Type().Elem()))
+			v = v.Elem()
+		}
+
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(plaintext))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(plaintext)
+		default:
+			return fmt.Errorf("ksql: the encrypted modifier only supports string and []byte attributes, but got: %T", attrPtr)
+		}
+
+		return nil
+	},
+}
+
+func encryptorFromContext(ctx context.Context) (Encryptor, error) {
+	encryptor, _ := ctx.Value(encryptorKey{}).(Encryptor)
+	if encryptor == nil {
+		return nil, fmt.Errorf("ksql: the encrypted modifier requires an Encryptor, please set it on the ksql.Config or with ksql.WithEncryptor()")
+	}
+	return encryptor, nil
+}
+
+// NewAESGCMEncryptor returns an Encryptor that uses AES-GCM with the
+// input key, which must have 16, 24 or 32 bytes for AES-128, AES-192
+// or AES-256 respectively.
+//
+// The random nonce used on each encryption is prepended to the ciphertext.
+func NewAESGCMEncryptor(key []byte) (Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: invalid AES key: %s", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: error creating AES-GCM cipher: %s", err)
+	}
+
+	return aesGCMEncryptor{gcm: gcm}, nil
+}
+
+type aesGCMEncryptor struct {
+	gcm cipher.AEAD
+}
+
+// Encrypt implements the Encryptor interface
+func (e aesGCMEncryptor) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("ksql: error generating nonce: %s", err)
+	}
+
+	return e.gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt implements the Encryptor interface
+func (e aesGCMEncryptor) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	nonceSize := e.gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	return e.gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,158 @@
+package ksql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type userWithSecrets struct {
+	ID     uint    `ksql:"id"`
+	SSN    string  `ksql:"ssn,encrypted"`
+	Notes  *string `ksql:"notes,encrypted"`
+	Secret []byte  `ksql:"secret,encrypted"`
+}
+
+type fakeEncryptedRows struct {
+	Rows
+
+	values []interface{}
+	done   bool
+}
+
+func (f *fakeEncryptedRows) Next() bool {
+	if f.done {
+		return false
+	}
+	f.done = true
+	return true
+}
+
+func (f *fakeEncryptedRows) Scan(args ...interface{}) error {
+	*(args[0].(*uint)) = 1
+	for i, value := range f.values {
+		if err := args[i+1].(modifierScanner).Scan(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeEncryptedRows) Columns() ([]string, error) {
+	return []string{"id", "ssn", "notes", "secret"}, nil
+}
+
+func (f *fakeEncryptedRows) Err() error {
+	return nil
+}
+
+func (f *fakeEncryptedRows) Close() error {
+	return nil
+}
+
+func TestEncryptedModifier(t *testing.T) {
+	encryptor, err := NewAESGCMEncryptor(bytes.Repeat([]byte("k"), 32))
+	tt.AssertNoErr(t, err)
+
+	t.Run("should encrypt the attributes on insert", func(t *testing.T) {
+		ctx := context.Background()
+
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		notes := "fake notes"
+		err = dryRun.Insert(WithEncryptor(ctx, encryptor), NewTable("users"), &userWithSecrets{
+			SSN:    "123-45-6789",
+			Notes:  &notes,
+			Secret: []byte("fake secret"),
+		})
+		tt.AssertNoErr(t, err)
+
+		plaintexts := map[string]bool{}
+		for _, param := range dryRun.Statements()[0].Params {
+			ciphertext, ok := param.([]byte)
+			tt.AssertEqual(t, ok, true)
+
+			plaintext, err := encryptor.Decrypt(ctx, ciphertext)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, bytes.Equal(plaintext, ciphertext), false)
+			plaintexts[string(plaintext)] = true
+		}
+		tt.AssertEqual(t, plaintexts, map[string]bool{
+			"123-45-6789": true,
+			"fake notes":  true,
+			"fake secret": true,
+		})
+	})
+
+	t.Run("should decrypt the attributes on queries", func(t *testing.T) {
+		ctx := context.Background()
+
+		var values []interface{}
+		for _, plaintext := range []string{"123-45-6789", "fake notes", "fake secret"} {
+			ciphertext, err := encryptor.Encrypt(ctx, []byte(plaintext))
+			tt.AssertNoErr(t, err)
+			values = append(values, ciphertext)
+		}
+
+		db, err := NewWithConfig(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return &fakeEncryptedRows{values: values}, nil
+			},
+		}, "sqlite3", Config{
+			Encryptor: encryptor,
+		})
+		tt.AssertNoErr(t, err)
+
+		var u userWithSecrets
+		err = db.QueryOne(ctx, &u, "FROM users WHERE id = 1")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, u.SSN, "123-45-6789")
+		tt.AssertEqual(t, *u.Notes, "fake notes")
+		tt.AssertEqual(t, u.Secret, []byte("fake secret"))
+	})
+
+	t.Run("should keep NULL values as nil", func(t *testing.T) {
+		db, err := NewWithConfig(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return &fakeEncryptedRows{values: []interface{}{nil, nil, nil}}, nil
+			},
+		}, "sqlite3", Config{
+			Encryptor: encryptor,
+		})
+		tt.AssertNoErr(t, err)
+
+		notes := "should be overwritten"
+		u := userWithSecrets{Notes: &notes}
+		err = db.QueryOne(context.Background(), &u, "FROM users WHERE id = 1")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, u.SSN, "")
+		tt.AssertEqual(t, u.Notes, (*string)(nil))
+	})
+
+	t.Run("should report an error if there is no Encryptor", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Insert(context.Background(), NewTable("users"), &userWithSecrets{SSN: "fake"})
+		tt.AssertErrContains(t, err, "encrypted", "Encryptor")
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		_, err := NewAESGCMEncryptor([]byte("short"))
+		tt.AssertErrContains(t, err, "invalid AES key")
+	})
+
+	t.Run("should fail to decrypt values encrypted with another key", func(t *testing.T) {
+		otherEncryptor, err := NewAESGCMEncryptor(bytes.Repeat([]byte("x"), 32))
+		tt.AssertNoErr(t, err)
+
+		ciphertext, err := encryptor.Encrypt(context.Background(), []byte("fake"))
+		tt.AssertNoErr(t, err)
+
+		_, err = otherEncryptor.Decrypt(context.Background(), ciphertext)
+		tt.AssertNotEqual(t, err, nil)
+	})
+}
@@ -0,0 +1,274 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+// enumInfo describes an enum registered with `ksql.RegisterEnum()`
+type enumInfo struct {
+	name   string
+	goType reflect.Type
+
+	// dbValues are the values allowed on the database, in the
+	// order they were declared, they are either all strings or
+	// all int64 values.
+	dbValues []interface{}
+
+	toDB   map[interface{}]interface{}
+	fromDB map[interface{}]reflect.Value
+}
+
+var enums = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*enumInfo
+}{
+	byType: map[reflect.Type]*enumInfo{},
+}
+
+// RegisterEnum registers the values allowed for the attributes tagged
+// with the `enum` modifier, the name is used for the type of the
+// column on `ksql.CreateTableSQL()` and the values are either a slice
+// with the values of a string or integer type, which are saved as they
+// are, or a map from these values to the values saved on the database, e.g.:
+//
+//	type OrderStatus string
+//
+//	type Priority int
+//
+//	func init() {
+//		ksql.RegisterEnum("order_status", []OrderStatus{"pending", "paid", "shipped"})
+//		ksql.RegisterEnum("priority", map[Priority]string{
+//			Low:  "low",
+//			High: "high",
+//		})
+//	}
+//
+//	type Order struct {
+//		ID       int         `ksql:"id"`
+//		Status   OrderStatus `ksql:"status,enum"`
+//		Priority *Priority   `ksql:"priority,enum"`
+//	}
+//
+// Writing a value that is not registered returns an error before
+// the query is sent, and so does reading an unknown value from the
+// database. The database values of a map are sorted for building
+// the column type since Go maps have no order.
+//
+// It should be called before the type is used for the first time,
+// usually on an `init()` function, and it panics if the values are
+// invalid or if the type is already registered.
+func RegisterEnum(name string, values interface{}) {
+	v := reflect.ValueOf(values)
+	if name == "" || (v.Kind() != reflect.Slice && v.Kind() != reflect.Map) {
+		panic(fmt.Errorf("ksql: RegisterEnum expects a name and a slice or a map of values, but got: %q and %T", name, values))
+	}
+
+	enum := &enumInfo{
+		name:   name,
+		goType: v.Type().Elem(),
+		toDB:   map[interface{}]interface{}{},
+		fromDB: map[interface{}]reflect.Value{},
+	}
+
+	var pairs [][2]reflect.Value
+	if v.Kind() == reflect.Map {
+		enum.goType = v.Type().Key()
+		for _, key := range v.MapKeys() {
+			pairs = append(pairs, [2]reflect.Value{key, v.MapIndex(key)})
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			return fmt.Sprint(pairs[i][1].Interface()) < fmt.Sprint(pairs[j][1].Interface())
+		})
+	} else {
+		for i := 0; i < v.Len(); i++ {
+			value := reflect.ValueOf(v.Index(i).Interface())
+			pairs = append(pairs, [2]reflect.Value{value, value})
+		}
+	}
+
+	if enumKind(enum.goType) == reflect.Invalid {
+		panic(fmt.Errorf("ksql: the enum `%s` should use a string or integer type, but got: %v", name, enum.goType))
+	}
+
+	for _, pair := range pairs {
+		dbValue, ok := normalizeEnumValue(pair[1].Interface())
+		if !ok {
+			panic(fmt.Errorf("ksql: the enum `%s` should be saved as strings or integers, but got: %T", name, pair[1].Interface()))
+		}
+
+		if len(enum.dbValues) > 0 && reflect.TypeOf(dbValue) != reflect.TypeOf(enum.dbValues[0]) {
+			panic(fmt.Errorf("ksql: the values of the enum `%s` should all be strings or all be integers", name))
+		}
+
+		if _, found := enum.fromDB[dbValue]; found {
+			panic(fmt.Errorf("ksql: the enum `%s` has the value %v more than once", name, dbValue))
+		}
+
+		enum.dbValues = append(enum.dbValues, dbValue)
+		enum.toDB[pair[0].Interface()] = dbValue
+		enum.fromDB[dbValue] = pair[0]
+	}
+
+	if len(enum.dbValues) == 0 {
+		panic(fmt.Errorf("ksql: the enum `%s` should have at least one value", name))
+	}
+
+	enums.Lock()
+	defer enums.Unlock()
+
+	if _, found := enums.byType[enum.goType]; found {
+		panic(fmt.Errorf("ksql: the type %v is already registered as an enum", enum.goType))
+	}
+	enums.byType[enum.goType] = enum
+}
+
+func getEnum(t reflect.Type) (*enumInfo, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	enums.RLock()
+	defer enums.RUnlock()
+
+	enum, found := enums.byType[t]
+	if !found {
+		return nil, fmt.Errorf("ksql: the type %v is used with the enum modifier but it was not registered with ksql.RegisterEnum()", t)
+	}
+	return enum, nil
+}
+
+// enumKind returns reflect.String or reflect.Int64 for
+// the types that can be used as enums and reflect.Invalid
+// for the other types
+func enumKind(t reflect.Type) reflect.Kind {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Int64
+	}
+	return reflect.Invalid
+}
+
+// normalizeEnumValue converts the values of the enums to
+// either string or int64, so they can be compared with the
+// values returned by the drivers
+func normalizeEnumValue(value interface{}) (interface{}, bool) {
+	if b, ok := value.([]byte); ok {
+		return string(b), true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return nil, false
+}
+
+func (e *enumInfo) isInteger() bool {
+	_, isInt := e.dbValues[0].(int64)
+	return isInt
+}
+
+// sqlValues returns the allowed values formatted as SQL literals
+func (e *enumInfo) sqlValues() []string {
+	values := make([]string, len(e.dbValues))
+	for i, value := range e.dbValues {
+		if str, isString := value.(string); isString {
+			values[i] = "'" + strings.ReplaceAll(str, "'", "''") + "'"
+			continue
+		}
+		values[i] = strconv.FormatInt(value.(int64), 10)
+	}
+	return values
+}
+
+func (e *enumInfo) invalidValueErr(value interface{}) error {
+	return fmt.Errorf(
+		"ksql: invalid value %v for the enum `%s`, expected one of: %s",
+		value, e.name, strings.Join(e.sqlValues(), ", "),
+	)
+}
+
+func init() {
+	structs.RegisterAttrModifier("enum", enumModifier)
+}
+
+// enumModifier validates and converts the values of the
+// types registered with `ksql.RegisterEnum()`
+var enumModifier = ksqlmodifiers.AttrModifier{
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		v := reflect.ValueOf(inputValue)
+		if !v.IsValid() {
+			return nil, nil
+		}
+
+		enum, err := getEnum(v.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+
+		dbValue, found := enum.toDB[v.Interface()]
+		if !found {
+			return nil, enum.invalidValueErr(v.Interface())
+		}
+		return dbValue, nil
+	},
+
+	Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+		v := reflect.ValueOf(attrPtr).Elem()
+		enum, err := getEnum(v.Type())
+		if err != nil {
+			return err
+		}
+
+		if dbValue == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		value, ok := normalizeEnumValue(dbValue)
+		if str, isString := value.(string); isString && enum.isInteger() {
+			// Some drivers return the integers as text:
+			value, err = strconv.ParseInt(str, 10, 64)
+			ok = err == nil
+		}
+		if !ok {
+			return fmt.Errorf("ksql: unexpected type %T for the enum `%s`", dbValue, enum.name)
+		}
+
+		goValue, found := enum.fromDB[value]
+		if !found {
+			return enum.invalidValueErr(value)
+		}
+
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.New(v.Type().Elem()))
+			v = v.Elem()
+		}
+		v.Set(goValue)
+		return nil
+	},
+}
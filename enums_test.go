@@ -0,0 +1,205 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+type orderStatus string
+
+type orderPriority int
+
+const (
+	lowPriority orderPriority = iota + 1
+	highPriority
+)
+
+func init() {
+	RegisterEnum("order_status", []orderStatus{"pending", "paid", "shipped"})
+	RegisterEnum("order_priority", map[orderPriority]string{
+		lowPriority:  "low",
+		highPriority: "high",
+	})
+}
+
+type order struct {
+	ID       int            `ksql:"id"`
+	Status   orderStatus    `ksql:"status,enum"`
+	Priority *orderPriority `ksql:"priority,enum"`
+}
+
+func TestRegisterEnum(t *testing.T) {
+	tests := []struct {
+		desc               string
+		name               string
+		values             interface{}
+		expectErrToContain []string
+	}{
+		{
+			desc:               "should panic for values that are not slices or maps",
+			name:               "color",
+			values:             "red",
+			expectErrToContain: []string{"slice or a map", "string"},
+		},
+		{
+			desc:               "should panic for types that are not strings or integers",
+			name:               "weight",
+			values:             []float64{1.5},
+			expectErrToContain: []string{"weight", "string or integer", "float64"},
+		},
+		{
+			desc:               "should panic for repeated values",
+			name:               "color",
+			values:             map[int]string{1: "red", 2: "red"},
+			expectErrToContain: []string{"color", "red", "more than once"},
+		},
+		{
+			desc:               "should panic for empty enums",
+			name:               "color",
+			values:             []int{},
+			expectErrToContain: []string{"color", "at least one value"},
+		},
+		{
+			desc:               "should panic for types that are already registered",
+			name:               "status",
+			values:             []orderStatus{"pending"},
+			expectErrToContain: []string{"orderStatus", "already registered"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			panicPayload := tt.PanicHandler(func() {
+				RegisterEnum(test.name, test.values)
+			})
+
+			err, ok := panicPayload.(error)
+			tt.AssertEqual(t, ok, true)
+			tt.AssertErrContains(t, err, test.expectErrToContain...)
+		})
+	}
+}
+
+func TestEnumModifier(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should convert the values written to the database", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		priority := highPriority
+		err = dryRun.Patch(ctx, NewTable("orders"), &order{
+			ID:       1,
+			Status:   "paid",
+			Priority: &priority,
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, paramsByColumn(dryRun.Statements()[0]), map[string]interface{}{
+			"status":   "paid",
+			"priority": "high",
+			"id":       1,
+		})
+	})
+
+	t.Run("should reject values that were not registered", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Patch(ctx, NewTable("orders"), &order{
+			ID:     1,
+			Status: "lost",
+		})
+		tt.AssertErrContains(t, err, "lost", "order_status", "'pending', 'paid', 'shipped'")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should scan the values read from the database", func(t *testing.T) {
+		opInfo := ksqlmodifiers.OpInfo{DriverName: "postgres", Method: "Query"}
+
+		var status orderStatus
+		err := enumModifier.Scan(ctx, opInfo, &status, []byte("shipped"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, status, orderStatus("shipped"))
+
+		var priority *orderPriority
+		err = enumModifier.Scan(ctx, opInfo, &priority, "low")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, *priority, lowPriority)
+
+		err = enumModifier.Scan(ctx, opInfo, &priority, nil)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, priority == nil, true)
+
+		err = enumModifier.Scan(ctx, opInfo, &status, "lost")
+		tt.AssertErrContains(t, err, "lost", "order_status")
+	})
+
+	t.Run("should report types that were not registered", func(t *testing.T) {
+		opInfo := ksqlmodifiers.OpInfo{DriverName: "postgres", Method: "Query"}
+
+		var notAnEnum string
+		err := enumModifier.Scan(ctx, opInfo, &notAnEnum, "paid")
+		tt.AssertErrContains(t, err, "string", "ksql.RegisterEnum()")
+
+		_, err = enumModifier.Value(ctx, opInfo, notAnEnum)
+		tt.AssertErrContains(t, err, "string", "ksql.RegisterEnum()")
+	})
+}
+
+func TestEnumColumns(t *testing.T) {
+	tests := []struct {
+		driver        string
+		ifNotExists   bool
+		expectedQuery string
+	}{
+		{
+			driver: "postgres",
+			expectedQuery: `CREATE TYPE "order_status" AS ENUM ('pending', 'paid', 'shipped');
+CREATE TYPE "order_priority" AS ENUM ('high', 'low');
+CREATE TABLE "orders" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"status" "order_status",
+	"priority" "order_priority"
+)`,
+		},
+		{
+			driver:      "postgres",
+			ifNotExists: true,
+			expectedQuery: `DO $$ BEGIN CREATE TYPE "order_status" AS ENUM ('pending', 'paid', 'shipped'); EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN CREATE TYPE "order_priority" AS ENUM ('high', 'low'); EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+CREATE TABLE IF NOT EXISTS "orders" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"status" "order_status",
+	"priority" "order_priority"
+)`,
+		},
+		{
+			driver: "mysql",
+			expectedQuery: "CREATE TABLE `orders` (\n" +
+				"\t`id` BIGINT AUTO_INCREMENT PRIMARY KEY,\n" +
+				"\t`status` ENUM('pending', 'paid', 'shipped'),\n" +
+				"\t`priority` ENUM('high', 'low')\n" +
+				")",
+		},
+		{
+			driver: "sqlite3",
+			expectedQuery: "CREATE TABLE `orders` (\n" +
+				"\t`id` INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+				"\t`status` TEXT CHECK (`status` IN ('pending', 'paid', 'shipped')),\n" +
+				"\t`priority` TEXT CHECK (`priority` IN ('high', 'low'))\n" +
+				")",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			dialect, err := GetDriverDialect(test.driver)
+			tt.AssertNoErr(t, err)
+
+			query, err := buildCreateTableQuery(dialect, nil, NewTable("orders"), &order{}, test.ifNotExists)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+		})
+	}
+}
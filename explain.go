@@ -0,0 +1,157 @@
+package ksql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// QueryPlan contains the execution plan of a query
+// as returned by the `ksql.DB.Explain()` method
+type QueryPlan struct {
+	// Raw is the plan as returned by the database, i.e. a JSON
+	// document for Postgres and MySQL and one line per step of
+	// the plan for SQLite
+	Raw string
+}
+
+// Decode parses the JSON plans returned by Postgres
+// and MySQL into the input target, e.g.:
+//
+//	var nodes []struct {
+//		Plan struct {
+//			NodeType   string  `json:"Node Type"`
+//			ActualRows int     `json:"Actual Rows"`
+//			TotalCost  float64 `json:"Total Cost"`
+//		} `json:"Plan"`
+//	}
+//	err := plan.Decode(&nodes)
+func (p QueryPlan) Decode(target interface{}) error {
+	err := json.Unmarshal([]byte(p.Raw), target)
+	if err != nil {
+		return fmt.Errorf("ksql: error decoding query plan: %s", err)
+	}
+	return nil
+}
+
+// Explain returns the execution plan of the input query by prefixing
+// it with the EXPLAIN statement appropriate for the database, i.e.:
+//
+//   - Postgres: `EXPLAIN (ANALYZE, FORMAT JSON)`
+//   - MySQL and MariaDB: `EXPLAIN FORMAT=JSON`
+//   - SQLite: `EXPLAIN QUERY PLAN`
+//
+// Note that on Postgres the ANALYZE option actually executes the
+// query, so when explaining statements that write to the database
+// make sure to run it inside a transaction that is rolled back.
+func (c DB) Explain(
+	ctx context.Context,
+	plan *QueryPlan,
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "Explain", Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.explain(ctx, plan, op.Query, op.Params...)
+	})
+}
+
+func (c DB) explain(
+	ctx context.Context,
+	plan *QueryPlan,
+	query string,
+	params ...interface{},
+) error {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if plan == nil {
+		return fmt.Errorf("ksql: expected to receive a pointer to a QueryPlan, but got nil")
+	}
+
+	query, params, err := expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	raw, err := explainQuery(ctx, c.db, c.dialect.DriverName(), true, query, params)
+	if err != nil {
+		return err
+	}
+
+	plan.Raw = raw
+	return nil
+}
+
+func explainPrefix(driverName string, analyze bool) (string, error) {
+	switch driverName {
+	case "postgres":
+		if analyze {
+			return "EXPLAIN (ANALYZE, FORMAT JSON) ", nil
+		}
+		return "EXPLAIN (FORMAT JSON) ", nil
+	case "mysql", "mariadb":
+		return "EXPLAIN FORMAT=JSON ", nil
+	case "sqlite3":
+		return "EXPLAIN QUERY PLAN ", nil
+	}
+
+	return "", fmt.Errorf("ksql: EXPLAIN is not supported for the `%s` driver", driverName)
+}
+
+// explainQuery runs the EXPLAIN statement and returns the plan as
+// a string, since some databases return the plan as several rows
+// the last column of each row is read and the rows are joined by
+// new lines.
+func explainQuery(
+	ctx context.Context,
+	db DBAdapter,
+	driverName string,
+	analyze bool,
+	query string,
+	params []interface{},
+) (string, error) {
+	prefix, err := explainPrefix(driverName, analyze)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := db.QueryContext(ctx, prefix+query, params...)
+	if err != nil {
+		return "", fmt.Errorf("error running EXPLAIN: %s", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("ksql: the EXPLAIN statement returned no columns")
+	}
+
+	var lines []string
+	for rows.Next() {
+		scanArgs := make([]interface{}, len(columns))
+		for i := range scanArgs {
+			scanArgs[i] = new(interface{})
+		}
+		var line string
+		scanArgs[len(columns)-1] = &line
+
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			return "", fmt.Errorf("error scanning EXPLAIN results: %s", err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error reading EXPLAIN results: %s", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,142 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type fakePlanRows struct {
+	Rows
+
+	columns []string
+	lines   []string
+}
+
+func (f *fakePlanRows) Next() bool {
+	return len(f.lines) > 0
+}
+
+func (f *fakePlanRows) Scan(args ...interface{}) error {
+	*(args[len(args)-1].(*string)) = f.lines[0]
+	f.lines = f.lines[1:]
+	return nil
+}
+
+func (f *fakePlanRows) Columns() ([]string, error) {
+	return f.columns, nil
+}
+
+func (f *fakePlanRows) Err() error {
+	return nil
+}
+
+func (f *fakePlanRows) Close() error {
+	return nil
+}
+
+func TestExplain(t *testing.T) {
+	t.Run("should use the EXPLAIN syntax of each dialect", func(t *testing.T) {
+		tests := []struct {
+			driver         string
+			expectedPrefix string
+		}{
+			{driver: "postgres", expectedPrefix: "EXPLAIN (ANALYZE, FORMAT JSON) "},
+			{driver: "mysql", expectedPrefix: "EXPLAIN FORMAT=JSON "},
+			{driver: "mariadb", expectedPrefix: "EXPLAIN FORMAT=JSON "},
+			{driver: "sqlite3", expectedPrefix: "EXPLAIN QUERY PLAN "},
+		}
+		for _, test := range tests {
+			t.Run(test.driver, func(t *testing.T) {
+				var receivedQuery string
+				db, err := NewWithAdapter(mockDBAdapter{
+					QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+						receivedQuery = query
+						return &fakePlanRows{columns: []string{"plan"}, lines: []string{`[{"Plan":{}}]`}}, nil
+					},
+				}, test.driver)
+				tt.AssertNoErr(t, err)
+
+				var plan QueryPlan
+				err = db.Explain(context.Background(), &plan, "SELECT * FROM users")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, receivedQuery, test.expectedPrefix+"SELECT * FROM users")
+				tt.AssertEqual(t, plan.Raw, `[{"Plan":{}}]`)
+			})
+		}
+	})
+
+	t.Run("should join plans returned as multiple rows", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return &fakePlanRows{
+					columns: []string{"id", "parent", "notused", "detail"},
+					lines:   []string{"SCAN users", "USE TEMP B-TREE FOR ORDER BY"},
+				}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		var plan QueryPlan
+		err = db.Explain(context.Background(), &plan, "SELECT * FROM users ORDER BY name")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, plan.Raw, "SCAN users\nUSE TEMP B-TREE FOR ORDER BY")
+	})
+
+	t.Run("should decode JSON plans", func(t *testing.T) {
+		plan := QueryPlan{Raw: `[{"Plan": {"Node Type": "Seq Scan", "Actual Rows": 42}}]`}
+
+		var nodes []struct {
+			Plan struct {
+				NodeType   string `json:"Node Type"`
+				ActualRows int    `json:"Actual Rows"`
+			} `json:"Plan"`
+		}
+		err := plan.Decode(&nodes)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(nodes), 1)
+		tt.AssertEqual(t, nodes[0].Plan.NodeType, "Seq Scan")
+		tt.AssertEqual(t, nodes[0].Plan.ActualRows, 42)
+	})
+
+	t.Run("should report an error for unsupported drivers", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "sqlserver")
+		tt.AssertNoErr(t, err)
+
+		var plan QueryPlan
+		err = db.Explain(context.Background(), &plan, "SELECT * FROM users")
+		tt.AssertErrContains(t, err, "EXPLAIN", "not supported", "sqlserver")
+	})
+
+	t.Run("should explain slow queries when ExplainSlowQueries is set", func(t *testing.T) {
+		var explainQueries []string
+		var slowQueries []SlowQuery
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				time.Sleep(2 * time.Millisecond)
+				return NewMockResult(0, 1), nil
+			},
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				explainQueries = append(explainQueries, query)
+				return &fakePlanRows{columns: []string{"plan"}, lines: []string{`[{"Plan":{}}]`}}, nil
+			},
+		}, "postgres", Config{
+			SlowQueryThreshold: time.Millisecond,
+			ExplainSlowQueries: true,
+			OnSlowQuery: func(ctx context.Context, q SlowQuery) {
+				slowQueries = append(slowQueries, q)
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "UPDATE users SET age = $1", 42)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, explainQueries, []string{"EXPLAIN (FORMAT JSON) UPDATE users SET age = $1"})
+		tt.AssertEqual(t, len(slowQueries), 1)
+		tt.AssertEqual(t, slowQueries[0].Plan, `[{"Plan":{}}]`)
+		tt.AssertNoErr(t, slowQueries[0].PlanErr)
+	})
+}
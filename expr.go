@@ -0,0 +1,38 @@
+package ksql
+
+// Expr is a raw SQL expression that can be written on a column instead of a
+// value, which is useful for updating counters and server-side timestamps
+// without a separate Exec, e.g.:
+//
+//	err := db.PatchMap(ctx, PostsTable, post.ID, map[string]interface{}{
+//		"views":      ksql.Expr("views + 1"),
+//		"updated_at": ksql.Expr("CURRENT_TIMESTAMP"),
+//	})
+//
+// The expressions are accepted as values on the PatchMap method and on
+// attributes of the interface{} type on the Insert, Patch, Upsert and
+// UpdateReturning methods, the last one being useful for reading the
+// values computed by the expressions after the update, and
+// they are written on the query as they are, without placeholders and
+// without running the modifiers of the attributes, so they should never
+// contain user input.
+type Expr string
+
+// buildValuesQuery returns the placeholders for the params of an INSERT
+// query, writing the expressions directly on the query instead, together
+// with the params that are left after removing these expressions.
+func buildValuesQuery(dialect Dialect, params []interface{}) (valuesQuery []string, remainingParams []interface{}) {
+	valuesQuery = make([]string, len(params))
+	remainingParams = make([]interface{}, 0, len(params))
+	for i, param := range params {
+		if expr, ok := param.(Expr); ok {
+			valuesQuery[i] = string(expr)
+			continue
+		}
+
+		valuesQuery[i] = dialect.Placeholder(len(remainingParams))
+		remainingParams = append(remainingParams, param)
+	}
+
+	return valuesQuery, remainingParams
+}
@@ -0,0 +1,69 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestExpr(t *testing.T) {
+	ctx := context.Background()
+
+	type counterPost struct {
+		ID    int         `ksql:"id"`
+		Title string      `ksql:"title"`
+		Views interface{} `ksql:"views"`
+	}
+
+	t.Run("should write the expressions of PatchMap on the query", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.PatchMap(ctx, NewTable("posts").WithStruct(counterPost{}), 1, map[string]interface{}{
+			"views": Expr("views + 1"),
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `UPDATE "posts" SET "views" = views + 1 WHERE "id" = $1`,
+			Params: []interface{}{1},
+		}})
+	})
+
+	t.Run("should number the placeholders around the expressions", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Patch(ctx, NewTable("posts"), counterPost{ID: 1, Title: "title", Views: Expr("views + 1")})
+		tt.AssertNoErr(t, err)
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 1)
+		tt.AssertEqual(t, statements[0].Params, []interface{}{"title", 1})
+
+		query := statements[0].Query
+		if query != `UPDATE "posts" SET "title" = $1, "views" = views + 1 WHERE "id" = $2` &&
+			query != `UPDATE "posts" SET "views" = views + 1, "title" = $1 WHERE "id" = $2` {
+			t.Fatalf("unexpected query: %s", query)
+		}
+	})
+
+	t.Run("should write the expressions of inserts on the query", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.InsertFields(ctx, NewTable("posts"), &counterPost{Title: "title", Views: Expr("0")}, "views", "title")
+		tt.AssertNoErr(t, err)
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 1)
+		tt.AssertEqual(t, statements[0].Params, []interface{}{"title"})
+
+		query := statements[0].Query
+		if query != "INSERT INTO `posts` (`views`, `title`) VALUES (0, ?)" &&
+			query != "INSERT INTO `posts` (`title`, `views`) VALUES (?, 0)" {
+			t.Fatalf("unexpected query: %s", query)
+		}
+	})
+}
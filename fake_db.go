@@ -0,0 +1,760 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqltest"
+)
+
+// FakeDB is an in-memory implementation of the Provider interface
+// meant for unit tests of code that uses ksql, so that these tests
+// don't need a real database, e.g.:
+//
+//	db := ksql.NewFakeDB()
+//	err := db.Insert(ctx, UsersTable, &User{Name: "Ana", Age: 20})
+//
+//	var users []User
+//	err = db.Query(ctx, &users, "FROM users WHERE age >= $1 ORDER BY name", 18)
+//
+// The tables are created when the first record is inserted and
+// the IDs of tables with a single integer ID are auto incremented.
+//
+// Only a small subset of SQL is supported: queries on a single table with
+// WHERE clauses using AND, OR, NOT, comparisons, IN, LIKE and IS NULL, and
+// ORDER BY, LIMIT and OFFSET clauses. Exec supports the same subset for
+// simple INSERT, UPDATE and DELETE statements. Everything else, e.g. JOINs,
+// GROUP BY and function calls, returns an ErrFakeDBUnsupported error, in
+// which case the code should be tested with a real database instead.
+//
+// NOTE: FakeDB lives in this package instead of the ksqltest package
+// because the ksql package imports ksqltest and not the other way around.
+type FakeDB struct {
+	state *fakeDBState
+}
+
+type fakeDBState struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+}
+
+type fakeTable struct {
+	rows   []map[string]interface{}
+	lastID int64
+}
+
+var _ Provider = FakeDB{}
+
+// ErrFakeDBUnsupported is returned by the FakeDB when a
+// query uses a feature not supported by its SQL engine.
+type ErrFakeDBUnsupported struct {
+	Feature string
+	Query   string
+}
+
+func (e ErrFakeDBUnsupported) Error() string {
+	return fmt.Sprintf("ksql.FakeDB: %s is not supported by the fake database, test this code with a real database instead: %s", e.Feature, e.Query)
+}
+
+// NewFakeDB instantiates an empty FakeDB
+func NewFakeDB() FakeDB {
+	return FakeDB{
+		state: &fakeDBState{
+			tables: map[string]*fakeTable{},
+		},
+	}
+}
+
+// Rows returns a copy of the rows saved on the input table, which
+// is useful for checking the changes made by the code being tested.
+func (f FakeDB) Rows(tableName string) []map[string]interface{} {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	table, ok := f.state.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return copyFakeRows(table.rows)
+}
+
+func copyFakeRows(rows []map[string]interface{}) []map[string]interface{} {
+	copies := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		copies = append(copies, copyFakeRow(row))
+	}
+	return copies
+}
+
+func copyFakeRow(row map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+// table returns the table with the input name creating
+// it if necessary, the caller must hold the lock.
+func (s *fakeDBState) table(name string) *fakeTable {
+	table, ok := s.tables[name]
+	if !ok {
+		table = &fakeTable{}
+		s.tables[name] = table
+	}
+	return table
+}
+
+// Insert saves a copy of the record on the in-memory table
+func (f FakeDB) Insert(ctx context.Context, table Table, record interface{}) error {
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	if err := assertStructPtr(t); err != nil {
+		return fmt.Errorf(
+			"ksql: expected record to be a pointer to struct, but got: %T",
+			record,
+		)
+	}
+
+	if v.IsNil() {
+		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+	}
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't insert in ksql.Table: %s", err)
+	}
+
+	info, err := structs.GetTagInfo(t.Elem())
+	if err != nil {
+		return err
+	}
+
+	if err := table.generateID(ctx, v, info); err != nil {
+		return err
+	}
+
+	if err := setDefaultValues(ctx, v.Elem(), info, record); err != nil {
+		return err
+	}
+
+	if err := callBeforeInsert(ctx, record); err != nil {
+		return err
+	}
+
+	setTimeNowOnInsert(v.Elem(), info, time.Now().UTC())
+
+	f.state.mu.Lock()
+	err = f.insert(table, v, info)
+	f.state.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return callAfterInsert(ctx, record)
+}
+
+func (f FakeDB) insert(table Table, v reflect.Value, info structs.StructInfo) error {
+	row, err := ksqltest.StructToMap(v.Interface())
+	if err != nil {
+		return err
+	}
+	for _, field := range info.Fields() {
+		if field.SkipOnInsert {
+			delete(row, field.Name)
+		}
+	}
+
+	t := f.state.table(table.name)
+	if len(table.idColumns) == 1 {
+		idName := table.idColumns[0]
+		fieldInfo := info.ByName(idName)
+		if fieldInfo.Valid && isFakeAutoIncrementField(v.Elem().FieldByIndex(fieldInfo.Path)) {
+			t.lastID++
+			err := ksqltest.FillStructWith(v.Interface(), map[string]interface{}{idName: t.lastID})
+			if err != nil {
+				return err
+			}
+
+			// Saving the ID with the type of the attribute keeps the
+			// values consistent with the ones saved by Patch:
+			row[idName] = reflect.Indirect(v.Elem().FieldByIndex(fieldInfo.Path)).Interface()
+		}
+
+		if id, ok := normalizeFakeValue(row[idName]).(int64); ok && id > t.lastID {
+			t.lastID = id
+		}
+	}
+
+	for _, existing := range t.rows {
+		if matchesFakeIDs(existing, row, table.idColumns) {
+			return ErrUniqueViolation{
+				Column: table.idColumns[0],
+				Err:    fmt.Errorf("ksql.FakeDB: duplicate ID on table `%s`: %v", table.name, row[table.idColumns[0]]),
+			}
+		}
+	}
+
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+// isFakeAutoIncrementField checks if the ID attribute is an empty
+// integer, which is the case where the database would generate it
+func isFakeAutoIncrementField(field reflect.Value) bool {
+	if field.Kind() == reflect.Ptr {
+		if !field.IsNil() {
+			return false
+		}
+		field = reflect.New(field.Type().Elem()).Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.IsZero()
+	}
+	return false
+}
+
+func matchesFakeIDs(row map[string]interface{}, idMap map[string]interface{}, idColumns []string) bool {
+	for _, idName := range idColumns {
+		cmp, err := compareFakeValues(row[idName], idMap[idName])
+		if err != nil || cmp == nil || *cmp != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Patch updates the non nil attributes of the record on the in-memory
+// table, returning a NotFoundError if there is no record with its ID
+func (f FakeDB) Patch(ctx context.Context, table Table, record interface{}) error {
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	tStruct := t
+	if t.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+		}
+		tStruct = t.Elem()
+	}
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't update ksql.Table: %s", err)
+	}
+
+	info, err := structs.GetTagInfo(tStruct)
+	if err != nil {
+		return err
+	}
+
+	if err := callBeforeUpdate(ctx, record); err != nil {
+		return err
+	}
+
+	recordMap, err := ksqltest.StructToMap(record)
+	if err != nil {
+		return err
+	}
+	setTimeNowOnUpdate(recordMap, info, time.Now().UTC())
+
+	idMap, err := normalizeIDsAsMap(nil, table.idColumns, recordMap)
+	if err != nil {
+		return err
+	}
+
+	f.state.mu.Lock()
+	found := false
+	for _, row := range f.state.table(table.name).rows {
+		if !matchesFakeIDs(row, idMap, table.idColumns) {
+			continue
+		}
+
+		for k, v := range recordMap {
+			row[k] = v
+		}
+		found = true
+	}
+	f.state.mu.Unlock()
+
+	if !found {
+		return notFoundError(ctx, NotFoundError{Table: table.name})
+	}
+
+	return callAfterUpdate(ctx, record)
+}
+
+// Update works exactly like Patch
+//
+// Deprecated: Use the Patch method instead
+func (f FakeDB) Update(ctx context.Context, table Table, record interface{}) error {
+	return f.Patch(ctx, table, record)
+}
+
+// Delete removes the record with the input ID from the in-memory
+// table, or sets its deleted at attribute if the record has an
+// attribute with the `softDelete` modifier.
+func (f FakeDB) Delete(ctx context.Context, table Table, idOrRecord interface{}) error {
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't delete from ksql.Table: %s", err)
+	}
+
+	idMap, err := normalizeIDsAsMap(nil, table.idColumns, idOrRecord)
+	if err != nil {
+		return err
+	}
+
+	softDeleteField, err := getSoftDeleteField(ctx, nil, table, idOrRecord)
+	if err != nil {
+		return err
+	}
+
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	t := f.state.table(table.name)
+	found := false
+	rows := t.rows[:0]
+	for _, row := range t.rows {
+		if !matchesFakeIDs(row, idMap, table.idColumns) {
+			rows = append(rows, row)
+			continue
+		}
+
+		if softDeleteField != nil {
+			if row[softDeleteField.Name] != nil {
+				rows = append(rows, row)
+				continue
+			}
+			row[softDeleteField.Name] = time.Now()
+			rows = append(rows, row)
+		}
+		found = true
+	}
+	t.rows = rows
+
+	if !found {
+		return notFoundError(ctx, NotFoundError{Table: table.name})
+	}
+
+	return nil
+}
+
+// Query loads the rows returned by the query into the records slice
+func (f FakeDB) Query(ctx context.Context, records interface{}, query string, params ...interface{}) error {
+	slicePtr := reflect.ValueOf(records)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.IsNil() {
+		return fmt.Errorf("ksql: expected to receive a pointer to slice of structs, but got: %T", records)
+	}
+
+	structType, isSliceOfPtrs, err := structs.DecodeAsSliceOfStructs(slicePtr.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	rows, err := f.query(ctx, structType, query, params)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(slicePtr.Type().Elem(), 0, len(rows))
+	for _, row := range rows {
+		record := reflect.New(structType)
+		err = ksqltest.FillStructWith(record.Interface(), row)
+		if err != nil {
+			return err
+		}
+
+		err = callAfterQuery(ctx, record.Interface())
+		if err != nil {
+			return err
+		}
+
+		if !isSliceOfPtrs {
+			record = record.Elem()
+		}
+		slice = reflect.Append(slice, record)
+	}
+	slicePtr.Elem().Set(slice)
+
+	return nil
+}
+
+// QueryOne loads the first row returned by the query into the record,
+// returning a NotFoundError if the query returns no rows.
+//
+// As with the real database the record can also be a pointer to a
+// scalar value if the query selects a single column.
+func (f FakeDB) QueryOne(ctx context.Context, record interface{}, query string, params ...interface{}) error {
+	v := reflect.ValueOf(record)
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("ksql: expected to receive a pointer to struct, but got: %T", record)
+	}
+	if v.IsNil() {
+		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+	}
+
+	t := v.Type().Elem()
+	isStruct := t.Kind() == reflect.Struct
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		isStruct = true
+		if v.Elem().IsNil() {
+			v.Elem().Set(reflect.New(t.Elem()))
+		}
+		record = v.Elem().Interface()
+		t = t.Elem()
+	}
+
+	var structType reflect.Type
+	if isStruct {
+		structType = t
+	}
+
+	rows, err := f.query(ctx, structType, query, params)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return notFoundError(ctx, NotFoundError{Query: query})
+	}
+
+	if !isStruct {
+		if len(rows[0]) != 1 {
+			return fmt.Errorf("ksql: expected the query to return a single column for scanning into %T, but got %d columns", record, len(rows[0]))
+		}
+		for _, value := range rows[0] {
+			converted, err := structs.NewPtrConverter(value).Convert(t)
+			if err != nil {
+				return fmt.Errorf("ksql.FakeDB: %s", err)
+			}
+			v.Elem().Set(converted)
+		}
+		return nil
+	}
+
+	err = ksqltest.FillStructWith(record, rows[0])
+	if err != nil {
+		return err
+	}
+
+	return callAfterQuery(ctx, record)
+}
+
+// QueryChunks calls the ForEachChunk callback with the
+// rows returned by the query split in chunks of ChunkSize
+func (f FakeDB) QueryChunks(ctx context.Context, parser ChunkParser) error {
+	chunkType, err := structs.ParseInputFunc(parser.ForEachChunk)
+	if err != nil {
+		return err
+	}
+
+	structType, _, err := structs.DecodeAsSliceOfStructs(chunkType)
+	if err != nil {
+		return err
+	}
+
+	rows, err := f.query(ctx, structType, parser.Query, parser.Params)
+	if err != nil {
+		return err
+	}
+
+	chunkSize := parser.ChunkSize
+	if chunkSize < 1 {
+		chunkSize = len(rows)
+	}
+
+	for len(rows) > 0 {
+		n := chunkSize
+		if n > len(rows) {
+			n = len(rows)
+		}
+
+		err = ksqltest.CallFunctionWithRows(parser.ForEachChunk, rows[:n])
+		if err == ErrAbortIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+
+	return nil
+}
+
+// query runs a SELECT statement returning copies of the selected rows,
+// the structType is used for filtering soft deleted records and might
+// be nil when the result is not scanned into a struct.
+func (f FakeDB) query(ctx context.Context, structType reflect.Type, query string, params []interface{}) ([]map[string]interface{}, error) {
+	stmt, err := parseFakeSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.kind != "SELECT" {
+		return nil, fmt.Errorf("ksql.FakeDB: expected a SELECT query but got: %s", query)
+	}
+
+	var softDeleteColumn string
+	if structType != nil {
+		info, err := structs.GetTagInfo(structType)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsNestedStruct {
+			return nil, ErrFakeDBUnsupported{Feature: "scanning into nested structs", Query: query}
+		}
+		if info.SoftDeleteField != nil && !isUnscoped(ctx) {
+			softDeleteColumn = info.SoftDeleteField.Name
+		}
+	}
+
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	var rows []map[string]interface{}
+	if table, ok := f.state.tables[stmt.table]; ok {
+		rows, err = filterFakeRows(table.rows, stmt.where, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if softDeleteColumn != "" {
+		var notDeleted []map[string]interface{}
+		for _, row := range rows {
+			if row[softDeleteColumn] == nil {
+				notDeleted = append(notDeleted, row)
+			}
+		}
+		rows = notDeleted
+	}
+
+	err = sortFakeRows(rows, stmt.orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = paginateFakeRows(rows, stmt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if len(stmt.columns) == 0 {
+			results = append(results, copyFakeRow(row))
+			continue
+		}
+
+		result := map[string]interface{}{}
+		for _, column := range stmt.columns {
+			result[column.alias] = row[column.name]
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func filterFakeRows(rows []map[string]interface{}, where fakeExpr, params []interface{}) ([]map[string]interface{}, error) {
+	var filtered []map[string]interface{}
+	for _, row := range rows {
+		if where != nil {
+			matches, err := evalFakeBool(where, row, params)
+			if err != nil {
+				return nil, err
+			}
+			if matches != fakeTrue {
+				continue
+			}
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered, nil
+}
+
+func sortFakeRows(rows []map[string]interface{}, orderBy []fakeOrder) (err error) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, order := range orderBy {
+			a, b := normalizeFakeValue(rows[i][order.column]), normalizeFakeValue(rows[j][order.column])
+			if a == nil || b == nil {
+				if (a == nil) == (b == nil) {
+					continue
+				}
+				// NULLs come last in ascending order, as on Postgres:
+				return (b == nil) != order.desc
+			}
+
+			cmp, cmpErr := compareFakeValues(a, b)
+			if cmpErr != nil {
+				err = cmpErr
+				return false
+			}
+			if *cmp != 0 {
+				return (*cmp < 0) != order.desc
+			}
+		}
+		return false
+	})
+	return err
+}
+
+func paginateFakeRows(rows []map[string]interface{}, stmt fakeStatement, params []interface{}) ([]map[string]interface{}, error) {
+	if stmt.offset != nil {
+		offset, err := evalFakeInt(stmt.offset, params)
+		if err != nil {
+			return nil, err
+		}
+		if offset > len(rows) {
+			offset = len(rows)
+		}
+		rows = rows[offset:]
+	}
+
+	if stmt.limit != nil {
+		limit, err := evalFakeInt(stmt.limit, params)
+		if err != nil {
+			return nil, err
+		}
+		if limit < len(rows) {
+			rows = rows[:limit]
+		}
+	}
+
+	return rows, nil
+}
+
+func evalFakeInt(expr fakeExpr, params []interface{}) (int, error) {
+	v, err := expr.eval(nil, params)
+	if err != nil {
+		return 0, err
+	}
+
+	i, ok := normalizeFakeValue(v).(int64)
+	if !ok || i < 0 {
+		return 0, fmt.Errorf("ksql.FakeDB: expected LIMIT and OFFSET to be non negative integers, but got: %v", v)
+	}
+	return int(i), nil
+}
+
+// Exec runs simple INSERT, UPDATE and DELETE statements on the in-memory
+// tables, the Result reports the number of affected rows and, for INSERT
+// statements, the auto incremented ID of the inserted row.
+func (f FakeDB) Exec(ctx context.Context, query string, params ...interface{}) (Result, error) {
+	stmt, err := parseFakeSQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	t := f.state.table(stmt.table)
+	switch stmt.kind {
+	case "INSERT":
+		row := map[string]interface{}{}
+		for i, column := range stmt.columns {
+			row[column.name], err = stmt.values[i].eval(nil, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Since the tables default to the `id` column it is used
+		// for returning the last insert ID as MySQL and SQLite do:
+		if id, ok := normalizeFakeValue(row["id"]).(int64); ok && id > t.lastID {
+			t.lastID = id
+		} else if _, ok := row["id"]; !ok {
+			t.lastID++
+			row["id"] = t.lastID
+		}
+		t.rows = append(t.rows, row)
+		return NewMockResult(t.lastID, 1), nil
+
+	case "UPDATE":
+		rows, err := filterFakeRows(t.rows, stmt.where, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// The values are evaluated before the changes so that
+		// the assignments don't affect each other, as in SQL:
+		updates := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			updates[i] = map[string]interface{}{}
+			for _, assignment := range stmt.set {
+				updates[i][assignment.column], err = assignment.value.eval(row, params)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for i, row := range rows {
+			for k, v := range updates[i] {
+				row[k] = v
+			}
+		}
+		return NewMockResult(0, int64(len(rows))), nil
+
+	case "DELETE":
+		var kept []map[string]interface{}
+		var deleted int64
+		for _, row := range t.rows {
+			if stmt.where != nil {
+				matches, err := evalFakeBool(stmt.where, row, params)
+				if err != nil {
+					return nil, err
+				}
+				if matches != fakeTrue {
+					kept = append(kept, row)
+					continue
+				}
+			}
+			deleted++
+		}
+		t.rows = kept
+		return NewMockResult(0, deleted), nil
+	}
+
+	return nil, ErrFakeDBUnsupported{Feature: "running SELECT queries with Exec", Query: query}
+}
+
+// Transaction calls fn with the same FakeDB restoring the
+// state of all tables if fn returns an error or panics
+func (f FakeDB) Transaction(ctx context.Context, fn func(Provider) error) (err error) {
+	f.state.mu.Lock()
+	snapshot := make(map[string]*fakeTable, len(f.state.tables))
+	for name, table := range f.state.tables {
+		snapshot[name] = &fakeTable{
+			rows:   copyFakeRows(table.rows),
+			lastID: table.lastID,
+		}
+	}
+	f.state.mu.Unlock()
+
+	rollback := func() {
+		f.state.mu.Lock()
+		f.state.tables = snapshot
+		f.state.mu.Unlock()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	err = fn(f)
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	return nil
+}
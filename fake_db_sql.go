@@ -0,0 +1,1099 @@
+package ksql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file contains the minimal SQL engine used by the FakeDB,
+// it only supports single table queries with simple WHERE clauses
+// and returns an ErrFakeDBUnsupported error for everything else.
+
+type fakeTokenKind int
+
+const (
+	fakeWord fakeTokenKind = iota
+	fakeQuotedName
+	fakeString
+	fakeNumber
+	fakeParam
+	fakeSymbol
+	fakeEOF
+)
+
+type fakeToken struct {
+	kind fakeTokenKind
+	text string
+}
+
+func tokenizeFakeSQL(query string) ([]fakeToken, error) {
+	var tokens []fakeToken
+	nextParam := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				end = len(query) - i
+			}
+			i += end
+
+		case c == '\'':
+			var s strings.Builder
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == '\'' {
+					if j+1 < len(query) && query[j+1] == '\'' {
+						s.WriteByte('\'')
+						j++
+						continue
+					}
+					break
+				}
+				s.WriteByte(query[j])
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("ksql.FakeDB: unterminated string in query: %s", query)
+			}
+			tokens = append(tokens, fakeToken{kind: fakeString, text: s.String()})
+			i = j + 1
+
+		case c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("ksql.FakeDB: unterminated quoted name in query: %s", query)
+			}
+			tokens = append(tokens, fakeToken{kind: fakeQuotedName, text: query[i+1 : i+1+end]})
+			i += end + 2
+
+		case c == '?':
+			nextParam++
+			tokens = append(tokens, fakeToken{kind: fakeParam, text: strconv.Itoa(nextParam)})
+			i++
+
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			tokens = append(tokens, fakeToken{kind: fakeParam, text: query[i+1 : j]})
+			i = j
+
+		case isDigit(c):
+			j := i
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, fakeToken{kind: fakeNumber, text: query[i:j]})
+			i = j
+
+		case isNameChar(c):
+			j := i
+			for j < len(query) && (isNameChar(query[j]) || isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, fakeToken{kind: fakeWord, text: query[i:j]})
+			i = j
+
+		default:
+			symbol := string(c)
+			if i+1 < len(query) {
+				switch query[i : i+2] {
+				case "<=", ">=", "<>", "!=":
+					symbol = query[i : i+2]
+				}
+			}
+			if !strings.Contains("(),.*=<>;", symbol[:1]) && symbol != "!=" {
+				return nil, ErrFakeDBUnsupported{Feature: fmt.Sprintf("the symbol `%s`", symbol), Query: query}
+			}
+			tokens = append(tokens, fakeToken{kind: fakeSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+
+	return append(tokens, fakeToken{kind: fakeEOF}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// fakeStatement is the parsed version of the
+// queries and commands supported by the FakeDB
+type fakeStatement struct {
+	kind  string
+	table string
+
+	// columns are the selected columns for SELECT statements
+	// (empty for `SELECT *` or queries starting with FROM)
+	// or the inserted columns for INSERT statements
+	columns []fakeSelectedColumn
+	values  []fakeExpr
+
+	// set contains the assignments of UPDATE statements
+	set []fakeAssignment
+
+	where   fakeExpr
+	orderBy []fakeOrder
+	limit   fakeExpr
+	offset  fakeExpr
+}
+
+type fakeSelectedColumn struct {
+	name  string
+	alias string
+}
+
+type fakeAssignment struct {
+	column string
+	value  fakeExpr
+}
+
+type fakeOrder struct {
+	column string
+	desc   bool
+}
+
+type fakeParser struct {
+	query  string
+	tokens []fakeToken
+	pos    int
+}
+
+func parseFakeSQL(query string) (fakeStatement, error) {
+	tokens, err := tokenizeFakeSQL(query)
+	if err != nil {
+		return fakeStatement{}, err
+	}
+
+	p := &fakeParser{query: query, tokens: tokens}
+
+	var stmt fakeStatement
+	switch {
+	case p.peekKeyword("SELECT"), p.peekKeyword("FROM"):
+		stmt, err = p.parseSelect()
+	case p.peekKeyword("DELETE"):
+		stmt, err = p.parseDelete()
+	case p.peekKeyword("UPDATE"):
+		stmt, err = p.parseUpdate()
+	case p.peekKeyword("INSERT"):
+		stmt, err = p.parseInsert()
+	default:
+		return fakeStatement{}, p.unsupported()
+	}
+	if err != nil {
+		return fakeStatement{}, err
+	}
+
+	p.acceptSymbol(";")
+	if p.peek().kind != fakeEOF {
+		return fakeStatement{}, p.unsupported()
+	}
+
+	return stmt, nil
+}
+
+func (p *fakeParser) peek() fakeToken {
+	return p.tokens[p.pos]
+}
+
+func (p *fakeParser) next() fakeToken {
+	t := p.tokens[p.pos]
+	if t.kind != fakeEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *fakeParser) peekKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == fakeWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *fakeParser) acceptKeyword(keyword string) bool {
+	if p.peekKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *fakeParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *fakeParser) acceptSymbol(symbol string) bool {
+	t := p.peek()
+	if t.kind == fakeSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *fakeParser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.unexpected(symbol)
+	}
+	return nil
+}
+
+func (p *fakeParser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == fakeEOF {
+		return fmt.Errorf("ksql.FakeDB: expected `%s` but the query ended: %s", expected, p.query)
+	}
+	return fmt.Errorf("ksql.FakeDB: expected `%s` but got `%s` in query: %s", expected, t.text, p.query)
+}
+
+// unsupported reports the next token as an unsupported feature,
+// since most syntax errors are actually valid SQL not supported
+// by the FakeDB, e.g. JOINs, GROUP BY and function calls.
+func (p *fakeParser) unsupported() error {
+	t := p.peek()
+	if t.kind == fakeEOF {
+		return fmt.Errorf("ksql.FakeDB: unexpected end of query: %s", p.query)
+	}
+	return ErrFakeDBUnsupported{Feature: "`" + t.text + "`", Query: p.query}
+}
+
+var fakeReservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "ORDER": true, "GROUP": true,
+	"LIMIT": true, "OFFSET": true, "JOIN": true, "LEFT": true, "RIGHT": true,
+	"INNER": true, "OUTER": true, "CROSS": true, "ON": true, "AND": true,
+	"OR": true, "NOT": true, "SET": true, "VALUES": true, "HAVING": true,
+	"UNION": true, "RETURNING": true, "FOR": true, "AS": true,
+}
+
+func (p *fakeParser) parseName() (string, error) {
+	t := p.peek()
+	switch {
+	case t.kind == fakeQuotedName:
+		p.pos++
+		return t.text, nil
+	case t.kind == fakeWord && !fakeReservedWords[strings.ToUpper(t.text)]:
+		p.pos++
+		return t.text, nil
+	}
+	return "", p.unsupported()
+}
+
+// parseColumn parses a column name removing the alias or table
+// name prefix, e.g. `u.name`, `users.name` or `"u"."name"`
+func (p *fakeParser) parseColumn() (string, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return "", err
+	}
+
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		name = name[i+1:]
+	}
+
+	// Quoted names are tokenized separately from the dot:
+	for p.acceptSymbol(".") {
+		name, err = p.parseName()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return name, nil
+}
+
+// parseTable parses the table name skipping its optional alias,
+// schema prefixes like `public.users` are ignored.
+func (p *fakeParser) parseTable() (string, error) {
+	name, err := p.parseColumn()
+	if err != nil {
+		return "", err
+	}
+
+	p.acceptKeyword("AS")
+	t := p.peek()
+	if t.kind == fakeQuotedName || (t.kind == fakeWord && !fakeReservedWords[strings.ToUpper(t.text)]) {
+		p.next()
+	}
+
+	return name, nil
+}
+
+func (p *fakeParser) parseSelect() (fakeStatement, error) {
+	stmt := fakeStatement{kind: "SELECT"}
+	if p.acceptKeyword("SELECT") {
+		if p.peekKeyword("DISTINCT") {
+			return stmt, p.unsupported()
+		}
+
+		if !p.acceptSymbol("*") {
+			for {
+				name, err := p.parseColumn()
+				if err != nil {
+					return stmt, err
+				}
+				if p.peek().kind == fakeSymbol && p.peek().text == "(" {
+					// Function calls, e.g. count(*), are not supported:
+					p.pos--
+					return stmt, p.unsupported()
+				}
+
+				column := fakeSelectedColumn{name: name, alias: name}
+				if p.acceptKeyword("AS") || p.peek().kind == fakeQuotedName ||
+					(p.peek().kind == fakeWord && !fakeReservedWords[strings.ToUpper(p.peek().text)]) {
+					column.alias, err = p.parseName()
+					if err != nil {
+						return stmt, err
+					}
+				}
+				stmt.columns = append(stmt.columns, column)
+
+				if !p.acceptSymbol(",") {
+					break
+				}
+			}
+		}
+	}
+
+	err := p.expectKeyword("FROM")
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.table, err = p.parseTable()
+	if err != nil {
+		return stmt, err
+	}
+
+	err = p.parseWhere(&stmt)
+	if err != nil {
+		return stmt, err
+	}
+
+	if p.acceptKeyword("ORDER") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return stmt, err
+		}
+
+		for {
+			column, err := p.parseColumn()
+			if err != nil {
+				return stmt, err
+			}
+
+			order := fakeOrder{column: column}
+			if p.acceptKeyword("DESC") {
+				order.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			stmt.orderBy = append(stmt.orderBy, order)
+
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("LIMIT") {
+		stmt.limit, err = p.parseOperand()
+		if err != nil {
+			return stmt, err
+		}
+	}
+
+	if p.acceptKeyword("OFFSET") {
+		stmt.offset, err = p.parseOperand()
+		if err != nil {
+			return stmt, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *fakeParser) parseDelete() (stmt fakeStatement, err error) {
+	stmt.kind = "DELETE"
+	p.next()
+
+	err = p.expectKeyword("FROM")
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.table, err = p.parseTable()
+	if err != nil {
+		return stmt, err
+	}
+
+	return stmt, p.parseWhere(&stmt)
+}
+
+func (p *fakeParser) parseUpdate() (stmt fakeStatement, err error) {
+	stmt.kind = "UPDATE"
+	p.next()
+
+	stmt.table, err = p.parseTable()
+	if err != nil {
+		return stmt, err
+	}
+
+	err = p.expectKeyword("SET")
+	if err != nil {
+		return stmt, err
+	}
+
+	for {
+		column, err := p.parseColumn()
+		if err != nil {
+			return stmt, err
+		}
+
+		err = p.expectSymbol("=")
+		if err != nil {
+			return stmt, err
+		}
+
+		value, err := p.parseOperand()
+		if err != nil {
+			return stmt, err
+		}
+		stmt.set = append(stmt.set, fakeAssignment{column: column, value: value})
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	return stmt, p.parseWhere(&stmt)
+}
+
+func (p *fakeParser) parseInsert() (stmt fakeStatement, err error) {
+	stmt.kind = "INSERT"
+	p.next()
+
+	err = p.expectKeyword("INTO")
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.table, err = p.parseTable()
+	if err != nil {
+		return stmt, err
+	}
+
+	err = p.expectSymbol("(")
+	if err != nil {
+		return stmt, err
+	}
+	for {
+		column, err := p.parseColumn()
+		if err != nil {
+			return stmt, err
+		}
+		stmt.columns = append(stmt.columns, fakeSelectedColumn{name: column, alias: column})
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	err = p.expectSymbol(")")
+	if err != nil {
+		return stmt, err
+	}
+
+	err = p.expectKeyword("VALUES")
+	if err != nil {
+		return stmt, err
+	}
+
+	err = p.expectSymbol("(")
+	if err != nil {
+		return stmt, err
+	}
+	for {
+		value, err := p.parseOperand()
+		if err != nil {
+			return stmt, err
+		}
+		stmt.values = append(stmt.values, value)
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	err = p.expectSymbol(")")
+	if err != nil {
+		return stmt, err
+	}
+
+	if len(stmt.values) != len(stmt.columns) {
+		return stmt, fmt.Errorf("ksql.FakeDB: got %d columns and %d values in query: %s", len(stmt.columns), len(stmt.values), p.query)
+	}
+
+	return stmt, nil
+}
+
+func (p *fakeParser) parseWhere(stmt *fakeStatement) (err error) {
+	if p.acceptKeyword("WHERE") {
+		stmt.where, err = p.parseOr()
+	}
+	return err
+}
+
+// fakeExpr is a node of the parsed WHERE clauses,
+// operands evaluate to values and the other nodes to
+// fakeBool values, which are used for representing the
+// three valued logic of SQL, i.e. true, false and NULL.
+type fakeExpr interface {
+	eval(row map[string]interface{}, params []interface{}) (interface{}, error)
+}
+
+type fakeBool int
+
+const (
+	fakeFalse fakeBool = iota
+	fakeTrue
+	fakeUnknown
+)
+
+func toFakeBool(b bool) fakeBool {
+	if b {
+		return fakeTrue
+	}
+	return fakeFalse
+}
+
+type fakeColumnExpr struct{ name string }
+
+func (e fakeColumnExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	return row[e.name], nil
+}
+
+type fakeLiteralExpr struct{ value interface{} }
+
+func (e fakeLiteralExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	return e.value, nil
+}
+
+type fakeParamExpr struct{ index int }
+
+func (e fakeParamExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	if e.index < 1 || e.index > len(params) {
+		return nil, fmt.Errorf("ksql.FakeDB: the query references the param %d but only %d params were received", e.index, len(params))
+	}
+	return params[e.index-1], nil
+}
+
+type fakeAndExpr struct{ left, right fakeExpr }
+
+func (e fakeAndExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	left, err := evalFakeBool(e.left, row, params)
+	if err != nil || left == fakeFalse {
+		return left, err
+	}
+
+	right, err := evalFakeBool(e.right, row, params)
+	if err != nil || right == fakeFalse {
+		return right, err
+	}
+
+	if left == fakeUnknown || right == fakeUnknown {
+		return fakeUnknown, nil
+	}
+	return fakeTrue, nil
+}
+
+type fakeOrExpr struct{ left, right fakeExpr }
+
+func (e fakeOrExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	left, err := evalFakeBool(e.left, row, params)
+	if err != nil || left == fakeTrue {
+		return left, err
+	}
+
+	right, err := evalFakeBool(e.right, row, params)
+	if err != nil || right == fakeTrue {
+		return right, err
+	}
+
+	if left == fakeUnknown || right == fakeUnknown {
+		return fakeUnknown, nil
+	}
+	return fakeFalse, nil
+}
+
+type fakeNotExpr struct{ expr fakeExpr }
+
+func (e fakeNotExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	b, err := evalFakeBool(e.expr, row, params)
+	switch b {
+	case fakeTrue:
+		return fakeFalse, err
+	case fakeFalse:
+		return fakeTrue, err
+	}
+	return b, err
+}
+
+type fakeIsNullExpr struct {
+	expr fakeExpr
+}
+
+func (e fakeIsNullExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	v, err := e.expr.eval(row, params)
+	return toFakeBool(normalizeFakeValue(v) == nil), err
+}
+
+type fakeCompareExpr struct {
+	op          string
+	left, right fakeExpr
+}
+
+func (e fakeCompareExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, params)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "LIKE" {
+		return fakeLike(left, right)
+	}
+
+	cmp, err := compareFakeValues(left, right)
+	if err != nil || cmp == nil {
+		return fakeUnknown, err
+	}
+
+	switch e.op {
+	case "=":
+		return toFakeBool(*cmp == 0), nil
+	case "!=", "<>":
+		return toFakeBool(*cmp != 0), nil
+	case "<":
+		return toFakeBool(*cmp < 0), nil
+	case "<=":
+		return toFakeBool(*cmp <= 0), nil
+	case ">":
+		return toFakeBool(*cmp > 0), nil
+	}
+	return toFakeBool(*cmp >= 0), nil
+}
+
+type fakeInExpr struct {
+	expr   fakeExpr
+	values []fakeExpr
+}
+
+func (e fakeInExpr) eval(row map[string]interface{}, params []interface{}) (interface{}, error) {
+	v, err := e.expr.eval(row, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	for _, valueExpr := range e.values {
+		value, err := valueExpr.eval(row, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// Slices are expanded just like ksql does with `IN (?)`:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < rv.Len(); i++ {
+				values = append(values, rv.Index(i).Interface())
+			}
+			continue
+		}
+		values = append(values, value)
+	}
+
+	result := fakeFalse
+	for _, value := range values {
+		cmp, err := compareFakeValues(v, value)
+		if err != nil {
+			return nil, err
+		}
+		if cmp == nil {
+			result = fakeUnknown
+			continue
+		}
+		if *cmp == 0 {
+			return fakeTrue, nil
+		}
+	}
+	return result, nil
+}
+
+func evalFakeBool(expr fakeExpr, row map[string]interface{}, params []interface{}) (fakeBool, error) {
+	v, err := expr.eval(row, params)
+	if err != nil {
+		return fakeUnknown, err
+	}
+
+	switch v := normalizeFakeValue(v).(type) {
+	case fakeBool:
+		return v, nil
+	case bool:
+		return toFakeBool(v), nil
+	case nil:
+		return fakeUnknown, nil
+	}
+	return fakeUnknown, fmt.Errorf("ksql.FakeDB: expected a boolean expression but got: %v", v)
+}
+
+func (p *fakeParser) parseOr() (fakeExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeOrExpr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *fakeParser) parseAnd() (fakeExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeAndExpr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *fakeParser) parseNot() (fakeExpr, error) {
+	if p.acceptKeyword("NOT") {
+		expr, err := p.parseNot()
+		return fakeNotExpr{expr: expr}, err
+	}
+	return p.parsePredicate()
+}
+
+func (p *fakeParser) parsePredicate() (fakeExpr, error) {
+	if p.acceptSymbol("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expectSymbol(")")
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		err = p.expectKeyword("NULL")
+		if err != nil {
+			return nil, err
+		}
+
+		var expr fakeExpr = fakeIsNullExpr{expr: left}
+		if not {
+			expr = fakeNotExpr{expr: expr}
+		}
+		return expr, nil
+	}
+
+	not := p.acceptKeyword("NOT")
+	if p.acceptKeyword("IN") {
+		err = p.expectSymbol("(")
+		if err != nil {
+			return nil, err
+		}
+
+		in := fakeInExpr{expr: left}
+		for {
+			value, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			in.values = append(in.values, value)
+
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return nil, err
+		}
+
+		if not {
+			return fakeNotExpr{expr: in}, nil
+		}
+		return in, nil
+	}
+
+	if p.acceptKeyword("LIKE") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		var expr fakeExpr = fakeCompareExpr{op: "LIKE", left: left, right: right}
+		if not {
+			expr = fakeNotExpr{expr: expr}
+		}
+		return expr, nil
+	}
+
+	if not {
+		return nil, p.unsupported()
+	}
+
+	t := p.peek()
+	if t.kind != fakeSymbol || !fakeComparisonOps[t.text] {
+		// Boolean columns and params can be used as predicates:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeCompareExpr{op: t.text, left: left, right: right}, nil
+}
+
+var fakeComparisonOps = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+func (p *fakeParser) parseOperand() (fakeExpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case fakeParam:
+		p.next()
+		index, _ := strconv.Atoi(t.text)
+		return fakeParamExpr{index: index}, nil
+
+	case fakeString:
+		p.next()
+		return fakeLiteralExpr{value: t.text}, nil
+
+	case fakeNumber:
+		p.next()
+		if strings.Contains(t.text, ".") {
+			f, err := strconv.ParseFloat(t.text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ksql.FakeDB: invalid number `%s` in query: %s", t.text, p.query)
+			}
+			return fakeLiteralExpr{value: f}, nil
+		}
+		i, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ksql.FakeDB: invalid number `%s` in query: %s", t.text, p.query)
+		}
+		return fakeLiteralExpr{value: i}, nil
+
+	case fakeWord:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			p.next()
+			return fakeLiteralExpr{value: nil}, nil
+		case "TRUE":
+			p.next()
+			return fakeLiteralExpr{value: true}, nil
+		case "FALSE":
+			p.next()
+			return fakeLiteralExpr{value: false}, nil
+		}
+	}
+
+	name, err := p.parseColumn()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == fakeSymbol && p.peek().text == "(" {
+		// Function calls, e.g. lower(name), are not supported:
+		p.pos--
+		return nil, p.unsupported()
+	}
+
+	return fakeColumnExpr{name: name}, nil
+}
+
+// normalizeFakeValue dereferences pointers and converts the
+// values to a few basic types so they can be compared, e.g.
+// all integer types are converted to int64.
+func normalizeFakeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, fakeBool, time.Time:
+		return v
+	case []byte:
+		return string(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+
+	if t, ok := rv.Interface().(time.Time); ok {
+		return t
+	}
+	return rv.Interface()
+}
+
+// compareFakeValues returns nil if any of the values is NULL
+func compareFakeValues(a interface{}, b interface{}) (*int, error) {
+	a = normalizeFakeValue(a)
+	b = normalizeFakeValue(b)
+	if a == nil || b == nil {
+		return nil, nil
+	}
+
+	cmp := 0
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
+			}
+			return &cmp, nil
+		case float64:
+			cmp = compareOrdered(float64(a), b)
+			return &cmp, nil
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			cmp = compareOrdered(a, float64(b))
+			return &cmp, nil
+		case float64:
+			cmp = compareOrdered(a, b)
+			return &cmp, nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			cmp = strings.Compare(a, b)
+			return &cmp, nil
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			if a != b {
+				cmp = 1
+				if !a {
+					cmp = -1
+				}
+			}
+			return &cmp, nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			if a.Before(b) {
+				cmp = -1
+			} else if a.After(b) {
+				cmp = 1
+			}
+			return &cmp, nil
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return &cmp, nil
+	}
+
+	return nil, fmt.Errorf("ksql.FakeDB: can't compare values of types %T and %T", a, b)
+}
+
+func compareOrdered(a float64, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func fakeLike(value interface{}, pattern interface{}) (fakeBool, error) {
+	value = normalizeFakeValue(value)
+	pattern = normalizeFakeValue(pattern)
+	if value == nil || pattern == nil {
+		return fakeUnknown, nil
+	}
+
+	s, ok1 := value.(string)
+	p, ok2 := pattern.(string)
+	if !ok1 || !ok2 {
+		return fakeUnknown, fmt.Errorf("ksql.FakeDB: LIKE expects strings but got %T and %T", value, pattern)
+	}
+
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	for _, c := range p {
+		switch c {
+		case '%':
+			re.WriteString(".*")
+		case '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	matched, err := regexp.MatchString(re.String(), s)
+	return toFakeBool(matched), err
+}
@@ -0,0 +1,204 @@
+package ksql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vingarcia/ksql"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestFakeDB(t *testing.T) {
+	ctx := context.Background()
+	usersTable := ksql.NewTable("users", "id")
+	type User struct {
+		ID   int    `ksql:"id"`
+		Name string `ksql:"name"`
+		Age  *int   `ksql:"age"`
+	}
+
+	intPtr := func(i int) *int { return &i }
+
+	newFakeDB := func(t *testing.T) ksql.FakeDB {
+		db := ksql.NewFakeDB()
+		for _, user := range []User{
+			{Name: "Ana", Age: intPtr(20)},
+			{Name: "Bia", Age: intPtr(17)},
+			{Name: "Cris", Age: intPtr(35)},
+			{Name: "Dani"},
+		} {
+			err := db.Insert(ctx, usersTable, &user)
+			tt.AssertNoErr(t, err)
+		}
+		return db
+	}
+
+	t.Run("should auto increment the IDs and reject duplicated IDs", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		user := User{Name: "Eva"}
+		err := db.Insert(ctx, usersTable, &user)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, user.ID, 5)
+
+		err = db.Insert(ctx, usersTable, &User{ID: 5, Name: "Fabi"})
+		var uniqueErr ksql.ErrUniqueViolation
+		tt.AssertEqual(t, errors.As(err, &uniqueErr), true)
+		tt.AssertEqual(t, uniqueErr.Column, "id")
+	})
+
+	t.Run("should filter, sort and paginate the rows", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		var users []User
+		err := db.Query(ctx, &users, "FROM users WHERE age >= $1 OR name LIKE 'D%' ORDER BY age DESC", 18)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, users, []User{
+			{ID: 4, Name: "Dani"},
+			{ID: 3, Name: "Cris", Age: intPtr(35)},
+			{ID: 1, Name: "Ana", Age: intPtr(20)},
+		})
+
+		var ptrUsers []*User
+		err = db.Query(ctx, &ptrUsers, "SELECT u.id, u.name FROM users u WHERE u.id IN (?) AND age IS NOT NULL ORDER BY name LIMIT ? OFFSET 1", []int{1, 2, 3}, 1)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, ptrUsers, []*User{{ID: 2, Name: "Bia"}})
+
+		var user User
+		err = db.QueryOne(ctx, &user, "FROM users WHERE name = $1", "Cris")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, user, User{ID: 3, Name: "Cris", Age: intPtr(35)})
+
+		var name string
+		err = db.QueryOne(ctx, &name, "SELECT name FROM users WHERE id = $1", 2)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, name, "Bia")
+
+		err = db.QueryOne(ctx, &user, "FROM users WHERE NOT (age < 100)")
+		tt.AssertEqual(t, ksql.IsNotFound(err), true)
+	})
+
+	t.Run("should patch and delete records by ID", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		err := db.Patch(ctx, usersTable, struct {
+			ID  int  `ksql:"id"`
+			Age *int `ksql:"age"`
+		}{ID: 4, Age: intPtr(40)})
+		tt.AssertNoErr(t, err)
+
+		err = db.Delete(ctx, usersTable, 1)
+		tt.AssertNoErr(t, err)
+
+		err = db.Delete(ctx, usersTable, 1)
+		tt.AssertEqual(t, ksql.IsNotFound(err), true)
+
+		err = db.Patch(ctx, usersTable, &User{ID: 42, Name: "Nobody"})
+		tt.AssertEqual(t, ksql.IsNotFound(err), true)
+
+		tt.AssertEqual(t, db.Rows("users"), []map[string]interface{}{
+			{"id": 2, "name": "Bia", "age": 17},
+			{"id": 3, "name": "Cris", "age": 35},
+			{"id": 4, "name": "Dani", "age": 40},
+		})
+	})
+
+	t.Run("should filter soft deleted records", func(t *testing.T) {
+		type Post struct {
+			ID        int        `ksql:"id"`
+			Title     string     `ksql:"title"`
+			DeletedAt *time.Time `ksql:"deleted_at,softDelete"`
+		}
+		postsTable := ksql.NewTable("posts")
+
+		db := ksql.NewFakeDB()
+		tt.AssertNoErr(t, db.Insert(ctx, postsTable, &Post{Title: "first"}))
+		tt.AssertNoErr(t, db.Insert(ctx, postsTable, &Post{Title: "second"}))
+		tt.AssertNoErr(t, db.Delete(ctx, postsTable, &Post{ID: 1}))
+
+		var posts []Post
+		err := db.Query(ctx, &posts, "FROM posts")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(posts), 1)
+		tt.AssertEqual(t, posts[0].Title, "second")
+
+		err = db.Query(ksql.Unscoped(ctx), &posts, "FROM posts")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(posts), 2)
+	})
+
+	t.Run("should run simple statements with Exec", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		result, err := db.Exec(ctx, "UPDATE users SET age = $1, name = 'Teen' WHERE age < $2", 18, 18)
+		tt.AssertNoErr(t, err)
+		rowsAffected, _ := result.RowsAffected()
+		tt.AssertEqual(t, rowsAffected, int64(1))
+
+		result, err = db.Exec(ctx, "DELETE FROM users WHERE age IS NULL")
+		tt.AssertNoErr(t, err)
+		rowsAffected, _ = result.RowsAffected()
+		tt.AssertEqual(t, rowsAffected, int64(1))
+
+		result, err = db.Exec(ctx, `INSERT INTO "users" ("name", "age") VALUES ($1, $2)`, "Eva", 28)
+		tt.AssertNoErr(t, err)
+		lastInsertID, _ := result.LastInsertId()
+		tt.AssertEqual(t, lastInsertID, int64(5))
+
+		var users []User
+		err = db.Query(ctx, &users, "SELECT * FROM users ORDER BY id")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, users, []User{
+			{ID: 1, Name: "Ana", Age: intPtr(20)},
+			{ID: 2, Name: "Teen", Age: intPtr(18)},
+			{ID: 3, Name: "Cris", Age: intPtr(35)},
+			{ID: 5, Name: "Eva", Age: intPtr(28)},
+		})
+	})
+
+	t.Run("should call ForEachChunk with the rows split in chunks", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		var chunks [][]User
+		err := db.QueryChunks(ctx, ksql.ChunkParser{
+			Query:     "SELECT id FROM users WHERE id > $1",
+			Params:    []interface{}{1},
+			ChunkSize: 2,
+			ForEachChunk: func(users []User) error {
+				chunks = append(chunks, users)
+				return nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, chunks, [][]User{{{ID: 2}, {ID: 3}}, {{ID: 4}}})
+	})
+
+	t.Run("should rollback the changes of failed transactions", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		err := db.Transaction(ctx, func(db ksql.Provider) error {
+			err := db.Delete(ctx, usersTable, 1)
+			tt.AssertNoErr(t, err)
+			return errors.New("fake error")
+		})
+		tt.AssertErrContains(t, err, "fake error")
+
+		tt.AssertEqual(t, len(db.Rows("users")), 4)
+	})
+
+	t.Run("should report unsupported features", func(t *testing.T) {
+		db := newFakeDB(t)
+
+		var users []User
+		err := db.Query(ctx, &users, "FROM users u JOIN posts p ON p.user_id = u.id")
+		tt.AssertErrContains(t, err, "`JOIN` is not supported by the fake database")
+
+		var count int
+		err = db.QueryOne(ctx, &count, "SELECT count(*) FROM users")
+		var unsupportedErr ksql.ErrFakeDBUnsupported
+		tt.AssertEqual(t, errors.As(err, &unsupportedErr), true)
+		tt.AssertEqual(t, unsupportedErr.Feature, "`count`")
+	})
+}
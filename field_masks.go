@@ -0,0 +1,104 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// InsertFields works like the Insert method but only writes the input
+// columns of the record, leaving the other columns with their default
+// values on the database, e.g.:
+//
+//	err := db.InsertFields(ctx, UsersTable, &user, "name", "age")
+//
+// The ID columns, when set, and the `tenant` attribute are always written,
+// and the listed attributes are written even if they are nil pointers.
+func (c DB) InsertFields(
+	ctx context.Context,
+	table Table,
+	record interface{},
+	columns ...string,
+) error {
+	op := Operation{Method: "InsertFields", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		if len(columns) == 0 {
+			return fmt.Errorf("ksql: InsertFields requires at least one column to be written")
+		}
+
+		table.fields = columns
+		return c.insert(ctx, table, record)
+	})
+}
+
+// UpdateFields works like the Patch method but only updates the input
+// columns of the record, which is useful for avoiding overwriting
+// columns owned by other parts of the system, e.g.:
+//
+//	err := db.UpdateFields(ctx, UsersTable, &user, "name", "age")
+//
+// Unlike Patch the listed attributes are updated even if they are nil
+// pointers, in which case the columns are set to NULL. The ID columns are
+// only used for finding the record, and the attributes with the
+// `timeNowUTC` and `optimisticLock` modifiers keep working as usual.
+func (c DB) UpdateFields(
+	ctx context.Context,
+	table Table,
+	record interface{},
+	columns ...string,
+) error {
+	op := Operation{Method: "UpdateFields", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		if len(columns) == 0 {
+			return fmt.Errorf("ksql: UpdateFields requires at least one column to be updated")
+		}
+
+		table.fields = columns
+		return c.patch(ctx, table, record)
+	})
+}
+
+// applyFieldMask returns the columns of the recordMap that should be
+// written according to the `Table.fields` mask, reading the masked
+// attributes directly from the record so that nil pointers are included.
+//
+// The ID, tenant and version columns are kept even if they are not
+// listed, since they are needed for identifying the record.
+func applyFieldMask(
+	table Table,
+	info structs.StructInfo,
+	record interface{},
+	recordMap map[string]interface{},
+) (map[string]interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(record))
+
+	masked := map[string]interface{}{}
+	for _, column := range table.fields {
+		field := info.ByName(column)
+		if !field.Valid {
+			return nil, fmt.Errorf(
+				"ksql: the column `%s` is not declared on the struct %v",
+				column, v.Type(),
+			)
+		}
+
+		masked[column] = v.FieldByIndex(field.Path).Interface()
+	}
+
+	keep := append([]string{}, table.idColumns...)
+	if info.TenantField != nil {
+		keep = append(keep, info.TenantField.Name)
+	}
+	if info.OptimisticLockField != nil {
+		keep = append(keep, info.OptimisticLockField.Name)
+	}
+	for _, column := range keep {
+		if value, found := recordMap[column]; found {
+			masked[column] = value
+		}
+	}
+
+	return masked, nil
+}
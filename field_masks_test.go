@@ -0,0 +1,97 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestInsertFields(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should only write the listed columns", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		age := 42
+		err = dryRun.InsertFields(ctx, NewTable("users"), &bulkUser{Name: "Jane", Age: &age}, "name")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  "INSERT INTO `users` (`name`) VALUES (?)",
+			Params: []interface{}{"Jane"},
+		}})
+	})
+
+	t.Run("should write the listed nil pointers as NULL", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.InsertFields(ctx, NewTable("users"), &bulkUser{Name: "Jane"}, "age")
+		tt.AssertNoErr(t, err)
+
+		statements := dryRun.Statements()
+		tt.AssertEqual(t, len(statements), 1)
+		tt.AssertEqual(t, statements[0].Query, "INSERT INTO `users` (`age`) VALUES (?)")
+		tt.AssertEqual(t, statements[0].Params, []interface{}{(*int)(nil)})
+	})
+
+	t.Run("should report invalid columns", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.InsertFields(ctx, NewTable("users"), &bulkUser{Name: "Jane"}, "not_a_column")
+		tt.AssertErrContains(t, err, "not_a_column", "ksql.bulkUser")
+
+		err = dryRun.InsertFields(ctx, NewTable("users"), &bulkUser{Name: "Jane"})
+		tt.AssertErrContains(t, err, "InsertFields", "at least one column")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
+
+func TestUpdateFields(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should only update the listed columns", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		age := 42
+		err = dryRun.UpdateFields(ctx, NewTable("users"), &bulkUser{ID: 1, Name: "Jane", Age: &age}, "age")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `UPDATE "users" SET "age" = $1 WHERE "id" = $2`,
+			Params: []interface{}{&age, 1},
+		}})
+	})
+
+	t.Run("should set the listed nil pointers to NULL", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.UpdateFields(ctx, NewTable("users"), &bulkUser{ID: 1, Name: "Jane"}, "age")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `UPDATE "users" SET "age" = $1 WHERE "id" = $2`,
+			Params: []interface{}{(*int)(nil), 1},
+		}})
+	})
+
+	t.Run("should report invalid columns", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.UpdateFields(ctx, NewTable("users"), &bulkUser{ID: 1, Name: "Jane"}, "not_a_column")
+		tt.AssertErrContains(t, err, "not_a_column", "ksql.bulkUser")
+
+		err = dryRun.UpdateFields(ctx, NewTable("users"), &bulkUser{ID: 1, Name: "Jane"})
+		tt.AssertErrContains(t, err, "UpdateFields", "at least one column")
+
+		err = dryRun.UpdateFields(ctx, NewTable("users"), &bulkUser{ID: 1, Name: "Jane"}, "id")
+		tt.AssertErrContains(t, err, "no attributes to update")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
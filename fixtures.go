@@ -0,0 +1,480 @@
+package ksql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// FixturesEnvVar is the environment variable that forces the FixtureDB
+// to record the fixtures again when set to "record", or to fail
+// instead of recording missing fixtures when set to "replay".
+const FixturesEnvVar = "KSQL_FIXTURES"
+
+// FixtureDB is a Provider for tests that records the calls made to a real
+// database on a fixture file, including the queries, their params and the
+// returned records, and replays them on the next runs without a database,
+// which allows most tests to run on CI without starting the database, e.g.:
+//
+//	db, err := ksql.NewFixtureDB(t, "testdata/users_repo.json", func() (ksql.Provider, error) {
+//		return kpgx.New(ctx, os.Getenv("DATABASE_URL"), ksql.Config{})
+//	})
+//
+// The fixture file is recorded if it doesn't exist yet, and it can be
+// recorded again by setting the KSQL_FIXTURES environment variable to
+// "record", e.g. after changing the queries of the code being tested.
+// When replaying, the calls must happen in the same order they were
+// recorded and with the same queries and params, otherwise the test fails.
+//
+// The records are saved as JSON, so only the attributes that survive
+// a round trip with the encoding/json package are replayed.
+type FixtureDB struct {
+	state *fixtureState
+
+	// db is only set when recording
+	db Provider
+}
+
+type fixtureState struct {
+	t         MockT
+	path      string
+	recording bool
+
+	mu      sync.Mutex
+	entries []fixtureEntry
+	next    int
+}
+
+type fixtureFile struct {
+	Entries []fixtureEntry `json:"entries"`
+}
+
+// fixtureEntry is a single call recorded on the fixture file
+type fixtureEntry struct {
+	Method string          `json:"method"`
+	Table  string          `json:"table,omitempty"`
+	Query  string          `json:"query,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+
+	// Result contains the records returned by the queries or
+	// the record of Insert with the IDs set by the database
+	Result json.RawMessage   `json:"result,omitempty"`
+	Chunks []json.RawMessage `json:"chunks,omitempty"`
+
+	RowsAffected int64  `json:"rows_affected,omitempty"`
+	LastInsertID int64  `json:"last_insert_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+var _ Provider = FixtureDB{}
+
+// NewFixtureDB creates a FixtureDB that records the calls on the
+// file at the input path, the connect function is only called
+// when recording, so it is not called when replaying the fixtures.
+//
+// The fixture file is written when the test ends, and when
+// replaying the test fails if not all recorded calls were made.
+func NewFixtureDB(t MockT, path string, connect func() (Provider, error)) (FixtureDB, error) {
+	mode := os.Getenv(FixturesEnvVar)
+	if mode != "" && mode != "record" && mode != "replay" {
+		return FixtureDB{}, fmt.Errorf("ksql: invalid value for the %s environment variable: '%s', expected 'record' or 'replay'", FixturesEnvVar, mode)
+	}
+
+	state := &fixtureState{
+		t:    t,
+		path: path,
+	}
+
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil && mode != "record":
+		var file fixtureFile
+		err = json.Unmarshal(content, &file)
+		if err != nil {
+			return FixtureDB{}, fmt.Errorf("ksql: unable to parse the fixture file '%s': %s", path, err)
+		}
+		state.entries = file.Entries
+
+		t.Cleanup(func() {
+			t.Helper()
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			if state.next < len(state.entries) {
+				t.Errorf("ksql.FixtureDB: %d of the calls recorded on '%s' were not made, the next one is: %s", len(state.entries)-state.next, path, state.entries[state.next])
+			}
+		})
+		return FixtureDB{state: state}, nil
+
+	case err == nil || os.IsNotExist(err):
+		if mode == "replay" {
+			return FixtureDB{}, fmt.Errorf("ksql: the fixture file '%s' doesn't exist and %s is set to 'replay'", path, FixturesEnvVar)
+		}
+
+	default:
+		return FixtureDB{}, fmt.Errorf("ksql: unable to read the fixture file '%s': %s", path, err)
+	}
+
+	db, err := connect()
+	if err != nil {
+		return FixtureDB{}, fmt.Errorf("ksql: unable to connect to the database for recording the fixtures: %s", err)
+	}
+
+	state.recording = true
+	t.Cleanup(func() {
+		t.Helper()
+		if err := state.save(); err != nil {
+			t.Errorf("%s", err)
+		}
+	})
+
+	return FixtureDB{state: state, db: db}, nil
+}
+
+func (s *fixtureState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.MarshalIndent(fixtureFile{Entries: s.entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ksql.FixtureDB: unable to encode the fixtures: %s", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err != nil {
+		return fmt.Errorf("ksql.FixtureDB: unable to create the directory of the fixture file: %s", err)
+	}
+
+	err = ioutil.WriteFile(s.path, append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("ksql.FixtureDB: unable to write the fixture file: %s", err)
+	}
+
+	return nil
+}
+
+func (e fixtureEntry) String() string {
+	s := e.Method + "("
+	if e.Table != "" {
+		s += fmt.Sprintf("table %q", e.Table)
+	} else if e.Query != "" {
+		s += fmt.Sprintf("%q", e.Query)
+	}
+	if len(e.Params) > 0 {
+		s += ", " + string(e.Params)
+	}
+	return s + ")"
+}
+
+// record appends the entry returning its position so
+// it can be updated after the call is completed
+func (s *fixtureState) record(entry fixtureEntry) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	return len(s.entries) - 1
+}
+
+func (s *fixtureState) update(i int, fn func(entry *fixtureEntry) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&s.entries[i])
+}
+
+// replay returns the next recorded entry if it matches the received call
+func (s *fixtureState) replay(received fixtureEntry) (fixtureEntry, error) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.next >= len(s.entries) {
+		err = fmt.Errorf("ksql.FixtureDB: received %s but all the calls recorded on '%s' were already replayed, record the fixtures again with %s=record", received, s.path, FixturesEnvVar)
+	} else if next := s.entries[s.next]; next.Method != received.Method ||
+		next.Table != received.Table ||
+		next.Query != received.Query ||
+		!jsonEqual(next.Params, received.Params) {
+		err = fmt.Errorf("ksql.FixtureDB: received %s but the next call recorded on '%s' is %s, record the fixtures again with %s=record", received, s.path, next, FixturesEnvVar)
+	}
+	if err != nil {
+		s.t.Errorf("%s", err)
+		return fixtureEntry{}, err
+	}
+
+	entry := s.entries[s.next]
+	s.next++
+	return entry, nil
+}
+
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+
+	var aValue, bValue interface{}
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+func newFixtureEntry(method string, table string, query string, params []interface{}) (fixtureEntry, error) {
+	entry := fixtureEntry{
+		Method: method,
+		Table:  table,
+		Query:  query,
+	}
+
+	if len(params) > 0 {
+		var err error
+		entry.Params, err = json.Marshal(params)
+		if err != nil {
+			return fixtureEntry{}, fmt.Errorf("ksql.FixtureDB: unable to encode the params of the %s call: %s", method, err)
+		}
+	}
+
+	return entry, nil
+}
+
+func fixtureError(msg string) error {
+	switch msg {
+	case "":
+		return nil
+	case ErrRecordNotFound.Error():
+		return ErrRecordNotFound
+	case ErrAbortIteration.Error():
+		return ErrAbortIteration
+	}
+	return errors.New(msg)
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// call records or replays a call whose result, if any, is
+// loaded into the dest argument, e.g. Query and Insert.
+func (f FixtureDB) call(
+	method string,
+	table string,
+	query string,
+	params []interface{},
+	dest interface{},
+	run func(db Provider) error,
+) error {
+	entry, err := newFixtureEntry(method, table, query, params)
+	if err != nil {
+		return err
+	}
+
+	if !f.state.recording {
+		entry, err = f.state.replay(entry)
+		if err != nil {
+			return err
+		}
+		if dest != nil && len(entry.Result) > 0 {
+			err = json.Unmarshal(entry.Result, dest)
+			if err != nil {
+				return fmt.Errorf("ksql.FixtureDB: unable to decode the result of %s: %s", entry, err)
+			}
+		}
+		return fixtureError(entry.Error)
+	}
+
+	i := f.state.record(entry)
+	runErr := run(f.db)
+	return f.state.update(i, func(entry *fixtureEntry) error {
+		entry.Error = errorMessage(runErr)
+		if dest != nil && runErr == nil {
+			entry.Result, err = json.Marshal(dest)
+			if err != nil {
+				return fmt.Errorf("ksql.FixtureDB: unable to encode the result of %s: %s", entry, err)
+			}
+		}
+		return runErr
+	})
+}
+
+// Insert records or replays a call to Insert, the record
+// is updated with the values set by the database, e.g. the IDs
+func (f FixtureDB) Insert(ctx context.Context, table Table, record interface{}) error {
+	return f.call("Insert", table.name, "", []interface{}{record}, record, func(db Provider) error {
+		return db.Insert(ctx, table, record)
+	})
+}
+
+// Patch records or replays a call to Patch
+func (f FixtureDB) Patch(ctx context.Context, table Table, record interface{}) error {
+	return f.call("Patch", table.name, "", []interface{}{record}, nil, func(db Provider) error {
+		return db.Patch(ctx, table, record)
+	})
+}
+
+// Delete records or replays a call to Delete
+func (f FixtureDB) Delete(ctx context.Context, table Table, idOrRecord interface{}) error {
+	return f.call("Delete", table.name, "", []interface{}{idOrRecord}, nil, func(db Provider) error {
+		return db.Delete(ctx, table, idOrRecord)
+	})
+}
+
+// Update records or replays a call to Update
+func (f FixtureDB) Update(ctx context.Context, table Table, record interface{}) error {
+	return f.call("Update", table.name, "", []interface{}{record}, nil, func(db Provider) error {
+		return db.Update(ctx, table, record)
+	})
+}
+
+// Query records or replays a call to Query
+func (f FixtureDB) Query(ctx context.Context, records interface{}, query string, params ...interface{}) error {
+	return f.call("Query", "", query, params, records, func(db Provider) error {
+		return db.Query(ctx, records, query, params...)
+	})
+}
+
+// QueryOne records or replays a call to QueryOne
+func (f FixtureDB) QueryOne(ctx context.Context, record interface{}, query string, params ...interface{}) error {
+	return f.call("QueryOne", "", query, params, record, func(db Provider) error {
+		return db.QueryOne(ctx, record, query, params...)
+	})
+}
+
+// QueryChunks records or replays a call to QueryChunks, the chunks
+// are recorded as they are received by the ForEachChunk callback
+func (f FixtureDB) QueryChunks(ctx context.Context, parser ChunkParser) error {
+	entry, err := newFixtureEntry("QueryChunks", "", parser.Query, parser.Params)
+	if err != nil {
+		return err
+	}
+
+	fn := reflect.ValueOf(parser.ForEachChunk)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 || fn.Type().In(0).Kind() != reflect.Slice {
+		return fmt.Errorf("ksql.FixtureDB: the ForEachChunk attribute must be a function receiving a slice, but got: %T", parser.ForEachChunk)
+	}
+
+	if !f.state.recording {
+		entry, err = f.state.replay(entry)
+		if err != nil {
+			return err
+		}
+
+		for _, rawChunk := range entry.Chunks {
+			chunk := reflect.New(fn.Type().In(0))
+			err = json.Unmarshal(rawChunk, chunk.Interface())
+			if err != nil {
+				return fmt.Errorf("ksql.FixtureDB: unable to decode the chunks of %s: %s", entry, err)
+			}
+
+			err, _ = fn.Call([]reflect.Value{chunk.Elem()})[0].Interface().(error)
+			if err == ErrAbortIteration {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return fixtureError(entry.Error)
+	}
+
+	i := f.state.record(entry)
+	var encodeErr error
+	recordingParser := parser
+	recordingParser.ForEachChunk = reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		rawChunk, err := json.Marshal(args[0].Interface())
+		if err != nil {
+			encodeErr = err
+		}
+		_ = f.state.update(i, func(entry *fixtureEntry) error {
+			entry.Chunks = append(entry.Chunks, rawChunk)
+			return nil
+		})
+		return fn.Call(args)
+	}).Interface()
+
+	err = f.db.QueryChunks(ctx, recordingParser)
+	if encodeErr != nil {
+		return fmt.Errorf("ksql.FixtureDB: unable to encode the chunks of %s: %s", entry, encodeErr)
+	}
+	return f.state.update(i, func(entry *fixtureEntry) error {
+		entry.Error = errorMessage(err)
+		return err
+	})
+}
+
+// Exec records or replays a call to Exec
+func (f FixtureDB) Exec(ctx context.Context, query string, params ...interface{}) (Result, error) {
+	entry, err := newFixtureEntry("Exec", "", query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.state.recording {
+		entry, err = f.state.replay(entry)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Error != "" {
+			return nil, fixtureError(entry.Error)
+		}
+		return NewMockResult(entry.LastInsertID, entry.RowsAffected), nil
+	}
+
+	i := f.state.record(entry)
+	result, err := f.db.Exec(ctx, query, params...)
+	updateErr := f.state.update(i, func(entry *fixtureEntry) error {
+		entry.Error = errorMessage(err)
+		if err != nil {
+			return err
+		}
+
+		// Not all drivers support both values:
+		entry.RowsAffected, _ = result.RowsAffected()
+		entry.LastInsertID, _ = result.LastInsertId()
+		return nil
+	})
+	if updateErr != nil {
+		return nil, updateErr
+	}
+
+	return result, nil
+}
+
+// Transaction records or replays a call to Transaction,
+// the calls made inside the transaction are also recorded
+func (f FixtureDB) Transaction(ctx context.Context, fn func(Provider) error) error {
+	entry, err := newFixtureEntry("Transaction", "", "", nil)
+	if err != nil {
+		return err
+	}
+
+	if !f.state.recording {
+		entry, err = f.state.replay(entry)
+		if err != nil {
+			return err
+		}
+
+		err = fn(f)
+		if err != nil {
+			return err
+		}
+
+		// The recorded error might have happened on the commit:
+		return fixtureError(entry.Error)
+	}
+
+	i := f.state.record(entry)
+	err = f.db.Transaction(ctx, func(tx Provider) error {
+		return fn(FixtureDB{state: f.state, db: tx})
+	})
+	return f.state.update(i, func(entry *fixtureEntry) error {
+		entry.Error = errorMessage(err)
+		return err
+	})
+}
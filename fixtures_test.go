@@ -0,0 +1,155 @@
+package ksql_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vingarcia/ksql"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestFixtureDB(t *testing.T) {
+	ctx := context.Background()
+	usersTable := ksql.NewTable("users", "id")
+	type User struct {
+		ID   int    `ksql:"id" json:"id"`
+		Name string `ksql:"name" json:"name"`
+	}
+
+	fakeDB := ksql.Mock{
+		InsertFn: func(ctx context.Context, table ksql.Table, record interface{}) error {
+			record.(*User).ID = 42
+			return nil
+		},
+		QueryFn: func(ctx context.Context, records interface{}, query string, params ...interface{}) error {
+			*records.(*[]User) = []User{{ID: 1, Name: "Ana"}, {ID: 2, Name: "Bia"}}
+			return nil
+		},
+		QueryOneFn: func(ctx context.Context, record interface{}, query string, params ...interface{}) error {
+			return ksql.ErrRecordNotFound
+		},
+		QueryChunksFn: func(ctx context.Context, parser ksql.ChunkParser) error {
+			forEachChunk := parser.ForEachChunk.(func([]User) error)
+			err := forEachChunk([]User{{ID: 1}, {ID: 2}})
+			if err != nil {
+				return err
+			}
+			return forEachChunk([]User{{ID: 3}})
+		},
+		ExecFn: func(ctx context.Context, query string, params ...interface{}) (ksql.Result, error) {
+			return ksql.NewMockResult(0, 3), nil
+		},
+		TransactionFn: func(ctx context.Context, fn func(db ksql.Provider) error) error {
+			return fn(ksql.Mock{
+				DeleteFn: func(ctx context.Context, table ksql.Table, idOrRecord interface{}) error {
+					return errors.New("fake delete error")
+				},
+			})
+		},
+	}
+
+	// runCalls makes the same calls both when recording and replaying
+	runCalls := func(t *testing.T, db ksql.Provider) {
+		user := User{Name: "Cris"}
+		err := db.Insert(ctx, usersTable, &user)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, user, User{ID: 42, Name: "Cris"})
+
+		var users []User
+		err = db.Query(ctx, &users, "FROM users WHERE name LIKE $1", "%a")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, users, []User{{ID: 1, Name: "Ana"}, {ID: 2, Name: "Bia"}})
+
+		err = db.QueryOne(ctx, &user, "FROM users WHERE id = $1", 7)
+		tt.AssertEqual(t, err, ksql.ErrRecordNotFound)
+
+		var chunks [][]User
+		err = db.QueryChunks(ctx, ksql.ChunkParser{
+			Query:     "FROM users",
+			ChunkSize: 2,
+			ForEachChunk: func(users []User) error {
+				chunks = append(chunks, users)
+				return nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, chunks, [][]User{{{ID: 1}, {ID: 2}}, {{ID: 3}}})
+
+		result, err := db.Exec(ctx, "DELETE FROM users WHERE age < $1", 18)
+		tt.AssertNoErr(t, err)
+		rowsAffected, err := result.RowsAffected()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, rowsAffected, int64(3))
+
+		err = db.Transaction(ctx, func(db ksql.Provider) error {
+			return db.Delete(ctx, usersTable, 1)
+		})
+		tt.AssertErrContains(t, err, "fake delete error")
+	}
+
+	t.Run("should replay the recorded calls without connecting to the database", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ksql-fixtures")
+		tt.AssertNoErr(t, err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "testdata", "users.json")
+
+		fakeT := &fakeMockT{}
+		db, err := ksql.NewFixtureDB(fakeT, path, func() (ksql.Provider, error) {
+			return fakeDB, nil
+		})
+		tt.AssertNoErr(t, err)
+		runCalls(t, db)
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+
+		fakeT = &fakeMockT{}
+		db, err = ksql.NewFixtureDB(fakeT, path, func() (ksql.Provider, error) {
+			t.Fatal("connect should not be called when replaying")
+			return nil, nil
+		})
+		tt.AssertNoErr(t, err)
+		runCalls(t, db)
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+
+	t.Run("should fail when the calls don't match the fixtures", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ksql-fixtures")
+		tt.AssertNoErr(t, err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "users.json")
+
+		fakeT := &fakeMockT{}
+		db, err := ksql.NewFixtureDB(fakeT, path, func() (ksql.Provider, error) {
+			return fakeDB, nil
+		})
+		tt.AssertNoErr(t, err)
+		_, err = db.Exec(ctx, "DELETE FROM users WHERE age < $1", 18)
+		tt.AssertNoErr(t, err)
+		_, err = db.Exec(ctx, "DELETE FROM users WHERE age < $1", 21)
+		tt.AssertNoErr(t, err)
+		fakeT.finish()
+
+		fakeT = &fakeMockT{}
+		db, err = ksql.NewFixtureDB(fakeT, path, nil)
+		tt.AssertNoErr(t, err)
+		_, err = db.Exec(ctx, "DELETE FROM users WHERE age < $1", 16)
+		tt.AssertErrContains(t, err, "received Exec(\"DELETE FROM users WHERE age < $1\", [16])", "KSQL_FIXTURES=record")
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 2)
+		tt.AssertErrContains(t, errors.New(fakeT.errs[1]), "2 of the calls recorded", "were not made")
+	})
+
+	t.Run("should not record missing fixtures when replay is forced", func(t *testing.T) {
+		os.Setenv(ksql.FixturesEnvVar, "replay")
+		defer os.Unsetenv(ksql.FixturesEnvVar)
+
+		_, err := ksql.NewFixtureDB(&fakeMockT{}, "nonexistent/users.json", nil)
+		tt.AssertErrContains(t, err, "doesn't exist", "replay")
+	})
+}
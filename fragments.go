@@ -0,0 +1,117 @@
+package ksql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fragment is a piece of SQL together with its params, which can be
+// composed into larger queries without ever writing the params on the
+// query itself, which makes it a safe replacement for building the
+// optional filters of a query with fmt.Sprintf, e.g.:
+//
+//	query, params, err := ksql.SQL("SELECT * FROM users WHERE 1=1").
+//		AppendIf(minAge > 0, "AND age > ?", minAge).
+//		AppendIf(name != "", "AND name = ?", name).
+//		Append("ORDER BY id").
+//		Build(db.Dialect())
+//
+//	err = db.Query(ctx, &users, query, params...)
+//
+// Like on ksql.Clauses the fragments use `?` as the placeholder for all
+// databases, and they are converted to the placeholders of the dialect
+// on Build, numbered in the order they appear on the final query.
+//
+// Fragments can also be used as params of other fragments, in which
+// case they are written in the place of the `?`, e.g. for subqueries:
+//
+//	admins := ksql.SQL("SELECT user_id FROM admins WHERE active = ?", true)
+//	query := ksql.SQL("SELECT * FROM users WHERE id IN (?)", admins)
+//
+// The zero value is valid and renders an empty string,
+// and all the methods return copies of the Fragment.
+type Fragment struct {
+	parts []fragmentPart
+}
+
+type fragmentPart struct {
+	query  string
+	params []interface{}
+}
+
+// SQL starts a new Fragment with the input query and params
+func SQL(query string, params ...interface{}) Fragment {
+	return Fragment{}.Append(query, params...)
+}
+
+// Append adds a query and its params to the end of the
+// fragment, separated from the previous query by a space
+func (f Fragment) Append(query string, params ...interface{}) Fragment {
+	f.parts = append(append([]fragmentPart{}, f.parts...), fragmentPart{
+		query:  query,
+		params: params,
+	})
+	return f
+}
+
+// AppendIf works like Append but only adds the query if `ok` is true,
+// which is useful for optional filters
+func (f Fragment) AppendIf(ok bool, query string, params ...interface{}) Fragment {
+	if !ok {
+		return f
+	}
+	return f.Append(query, params...)
+}
+
+// Build renders the fragment using the placeholders of the input
+// dialect, returning the final query and the params in the
+// same order as their placeholders.
+func (f Fragment) Build(dialect Dialect) (query string, params []interface{}, err error) {
+	var b strings.Builder
+	params, err = f.render(dialect, &b, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return b.String(), params, nil
+}
+
+// render writes the fragment to the builder, appending its
+// params to the params of the enclosing fragments, which are
+// needed for numbering the placeholders
+func (f Fragment) render(dialect Dialect, b *strings.Builder, params []interface{}) ([]interface{}, error) {
+	for i, part := range f.parts {
+		numPlaceholders := strings.Count(part.query, "?")
+		if numPlaceholders != len(part.params) {
+			return nil, fmt.Errorf(
+				"ksql: the fragment `%s` has %d placeholders but received %d params",
+				part.query, numPlaceholders, len(part.params),
+			)
+		}
+
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		query := part.query
+		for _, param := range part.params {
+			idx := strings.IndexByte(query, '?')
+			b.WriteString(query[:idx])
+			query = query[idx+1:]
+
+			if nested, ok := param.(Fragment); ok {
+				var err error
+				params, err = nested.render(dialect, b, params)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			b.WriteString(dialect.Placeholder(len(params)))
+			params = append(params, param)
+		}
+		b.WriteString(query)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,96 @@
+package ksql
+
+import (
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestFragment(t *testing.T) {
+	minAge := 18
+	name := ""
+
+	tests := []struct {
+		desc           string
+		driver         string
+		fragment       Fragment
+		expectedQuery  string
+		expectedParams []interface{}
+		expectedErr    []string
+	}{
+		{
+			desc:   "should skip the conditional fragments",
+			driver: "postgres",
+			fragment: SQL("SELECT * FROM users WHERE 1=1").
+				AppendIf(minAge > 0, "AND age > ?", minAge).
+				AppendIf(name != "", "AND name = ?", name).
+				Append("AND age < ? ORDER BY id", 60),
+			expectedQuery:  `SELECT * FROM users WHERE 1=1 AND age > $1 AND age < $2 ORDER BY id`,
+			expectedParams: []interface{}{18, 60},
+		},
+		{
+			desc:   "should write nested fragments in the place of their placeholders",
+			driver: "sqlserver",
+			fragment: SQL("SELECT * FROM users WHERE name = ?", "Bia").
+				Append("AND id IN (?) AND age > ?",
+					SQL("SELECT user_id FROM admins WHERE level = ?", 2),
+					30,
+				),
+			expectedQuery:  `SELECT * FROM users WHERE name = @p1 AND id IN (SELECT user_id FROM admins WHERE level = @p2) AND age > @p3`,
+			expectedParams: []interface{}{"Bia", 2, 30},
+		},
+		{
+			desc:           "should keep the question marks for sqlite",
+			driver:         "sqlite3",
+			fragment:       SQL("SELECT * FROM users WHERE age BETWEEN ? AND ?", 18, 30),
+			expectedQuery:  `SELECT * FROM users WHERE age BETWEEN ? AND ?`,
+			expectedParams: []interface{}{18, 30},
+		},
+		{
+			desc:          "should render an empty string for empty fragments",
+			driver:        "postgres",
+			fragment:      Fragment{}.AppendIf(false, "age > ?", 18),
+			expectedQuery: ``,
+		},
+		{
+			desc:        "should report placeholders that don't match the params",
+			driver:      "postgres",
+			fragment:    SQL("SELECT * FROM users").Append("WHERE age > ? AND age < ?", 18),
+			expectedErr: []string{"WHERE age > ? AND age < ?", "2 placeholders", "1 params"},
+		},
+		{
+			desc:        "should report errors on nested fragments",
+			driver:      "postgres",
+			fragment:    SQL("SELECT * FROM users WHERE id IN (?)", SQL("SELECT user_id FROM admins WHERE level = ?")),
+			expectedErr: []string{"1 placeholders", "0 params"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dialect, err := GetDriverDialect(test.driver)
+			tt.AssertNoErr(t, err)
+
+			query, params, err := test.fragment.Build(dialect)
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+			tt.AssertEqual(t, params, test.expectedParams)
+		})
+	}
+
+	t.Run("should not modify the original fragment", func(t *testing.T) {
+		base := SQL("SELECT * FROM users WHERE age > ?", 18)
+		_ = base.Append("AND name = ?", "fake")
+
+		dialect, err := GetDriverDialect("postgres")
+		tt.AssertNoErr(t, err)
+
+		query, params, err := base.Build(dialect)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, `SELECT * FROM users WHERE age > $1`)
+		tt.AssertEqual(t, params, []interface{}{18})
+	})
+}
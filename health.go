@@ -0,0 +1,121 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PoolStats contains the stats of the connection pool of an adapter
+type PoolStats struct {
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+
+	// WaitCount is the number of times a connection had to be waited for
+	WaitCount int64
+
+	// WaitDuration is the total time spent waiting for connections
+	WaitDuration time.Duration
+}
+
+// PoolStatsReporter can be optionally implemented by the DBAdapter
+// for reporting the stats of its connection pool on `DB.HealthCheck()`.
+//
+// The adapters built on top of `database/sql` don't need to implement
+// it, since the stats are read from their `Stats()` method instead.
+type PoolStatsReporter interface {
+	PoolStats() PoolStats
+}
+
+// HealthStatus is the result of a health check,
+// see `DB.HealthCheck()` for more details
+type HealthStatus struct {
+	// Latency is how long the ping query took to run
+	Latency time.Duration
+
+	// CircuitState is one of CircuitClosed, CircuitOpen or CircuitHalfOpen,
+	// or an empty string if the circuit breaker is disabled
+	CircuitState string
+
+	// PoolStats is nil if the adapter doesn't report them
+	PoolStats *PoolStats
+}
+
+// HealthCheck runs a simple query, e.g. `SELECT 1`, for checking if
+// the database is reachable and reports the state of the circuit
+// breaker and the stats of the connection pool.
+//
+// The returned status is filled even when an error is returned,
+// so it can be used for reporting why the database is unhealthy.
+func (c DB) HealthCheck(ctx context.Context) (HealthStatus, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var status HealthStatus
+	if c.breaker != nil {
+		status.CircuitState = c.breaker.currentState()
+	}
+	status.PoolStats = getPoolStats(c.db)
+
+	start := time.Now()
+	err := c.ping(ctx)
+	status.Latency = time.Since(start)
+
+	if c.breaker != nil {
+		// The ping might have changed the state of the circuit:
+		status.CircuitState = c.breaker.currentState()
+	}
+
+	return status, err
+}
+
+func (c DB) ping(ctx context.Context) error {
+	query := "SELECT 1"
+	if c.dialect.DriverName() == "oracle" {
+		query = "SELECT 1 FROM DUAL"
+	}
+
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("ksql: the health check query returned no rows")
+	}
+
+	return rows.Close()
+}
+
+// getPoolStats reads the pool stats from the adapter,
+// unwrapping the adapters added by KSQL itself
+func getPoolStats(db DBAdapter) *PoolStats {
+	for {
+		switch adapter := db.(type) {
+		case PoolStatsReporter:
+			stats := adapter.PoolStats()
+			return &stats
+		case interface{ Stats() sql.DBStats }:
+			stats := adapter.Stats()
+			return &PoolStats{
+				MaxOpenConnections: stats.MaxOpenConnections,
+				OpenConnections:    stats.OpenConnections,
+				InUse:              stats.InUse,
+				Idle:               stats.Idle,
+				WaitCount:          stats.WaitCount,
+				WaitDuration:       stats.WaitDuration,
+			}
+		case interface{ unwrapAdapter() DBAdapter }:
+			db = adapter.unwrapAdapter()
+		default:
+			return nil
+		}
+	}
+}
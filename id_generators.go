@@ -0,0 +1,163 @@
+package ksql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// IDGenerator generates the ID of the records inserted on a Table
+// created with `ksql.NewTable().WithIDGenerator()`, the built-in
+// generators are ksql.UUIDv4, ksql.UUIDv7 and ksql.ULID.
+type IDGenerator func(ctx context.Context) (interface{}, error)
+
+// WithIDGenerator returns a copy of the Table whose IDs are generated by
+// the input function on Insert and Upsert instead of by the database, e.g.:
+//
+//	var UsersTable = ksql.NewTable("users").WithIDGenerator(ksql.UUIDv7)
+//
+// The ID is only generated if the ID attribute of the record is empty,
+// and since the ID is known beforehand it is saved on the record without
+// using the `RETURNING` clause or equivalent, saving a round-trip.
+//
+// It only works for tables with a single ID column and the ID attribute
+// must be a string, a [16]byte array, e.g. uuid.UUID, for the UUID
+// generators, or a type the generated value can be converted to.
+func (t Table) WithIDGenerator(generator IDGenerator) Table {
+	t.idGenerator = generator
+	return t
+}
+
+// generateID fills the ID of the record if the table has an
+// IDGenerator and the ID attribute of the record is empty.
+func (t Table) generateID(ctx context.Context, v reflect.Value, info structs.StructInfo) error {
+	if t.idGenerator == nil {
+		return nil
+	}
+
+	if len(t.idColumns) != 1 {
+		return fmt.Errorf("ksql: ID generators are only supported for tables with a single ID column, but got: %v", t.idColumns)
+	}
+
+	fieldInfo := info.ByName(t.idColumns[0])
+	if !fieldInfo.Valid {
+		return fmt.Errorf("ksql: the record has no attribute for the ID column `%s`", t.idColumns[0])
+	}
+
+	field := v.Elem().FieldByIndex(fieldInfo.Path)
+	if !field.IsZero() {
+		return nil
+	}
+
+	id, err := t.idGenerator(ctx)
+	if err != nil {
+		return fmt.Errorf("ksql: error generating ID: %s", err)
+	}
+
+	idValue := reflect.ValueOf(id)
+	switch {
+	case idValue.Type().AssignableTo(field.Type()):
+		field.Set(idValue)
+	case isUUIDArray(field.Type()) && idValue.Kind() == reflect.String:
+		uuid, err := parseUUID(idValue.String())
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(uuid).Convert(field.Type()))
+	case idValue.Type().ConvertibleTo(field.Type()):
+		field.Set(idValue.Convert(field.Type()))
+	default:
+		return fmt.Errorf("ksql: can't save the generated ID of type %T on the attribute of type %v", id, field.Type())
+	}
+
+	return nil
+}
+
+func isUUIDArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+func parseUUID(s string) ([16]byte, error) {
+	var uuid [16]byte
+	hexStr := strings.Replace(s, "-", "", 4)
+	if len(s) != 36 || len(hexStr) != 32 {
+		return uuid, fmt.Errorf("ksql: invalid UUID: '%s'", s)
+	}
+
+	_, err := hex.Decode(uuid[:], []byte(hexStr))
+	if err != nil {
+		return uuid, fmt.Errorf("ksql: invalid UUID: '%s'", s)
+	}
+	return uuid, nil
+}
+
+// UUIDv4 is an IDGenerator that generates random
+// UUIDs in the canonical format, as described by RFC 9562
+func UUIDv4(ctx context.Context) (interface{}, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return nil, err
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return formatUUID(uuid), nil
+}
+
+// UUIDv7 is an IDGenerator that generates time-ordered UUIDs
+// in the canonical format, as described by RFC 9562, which
+// are better suited for indexes than the random ones.
+func UUIDv7(ctx context.Context) (interface{}, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[6:]); err != nil {
+		return nil, err
+	}
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(uuid[:6], ms[2:])
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x70
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return formatUUID(uuid), nil
+}
+
+func formatUUID(uuid [16]byte) string {
+	s := hex.EncodeToString(uuid[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID is an IDGenerator that generates time-ordered IDs in the ULID
+// format, i.e. 26 characters encoded with Crockford's base32.
+func ULID(ctx context.Context) (interface{}, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[6:]); err != nil {
+		return nil, err
+	}
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(id[:6], ms[2:])
+
+	// The 128 bits are encoded in groups of 5 bits, starting
+	// with 2 padding bits so that the total is 130 bits:
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = (lo >> 5) | (hi << 59)
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
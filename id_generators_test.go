@@ -0,0 +1,138 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestIDGenerators(t *testing.T) {
+	ctx := context.Background()
+	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	t.Run("should generate UUIDv4 IDs", func(t *testing.T) {
+		id, err := UUIDv4(ctx)
+		tt.AssertNoErr(t, err)
+
+		match := uuidRegex.FindStringSubmatch(id.(string))
+		tt.AssertEqual(t, len(match), 2)
+		tt.AssertEqual(t, match[1], "4")
+	})
+
+	t.Run("should generate time ordered UUIDv7 IDs", func(t *testing.T) {
+		id1, err := UUIDv7(ctx)
+		tt.AssertNoErr(t, err)
+		time.Sleep(2 * time.Millisecond)
+		id2, err := UUIDv7(ctx)
+		tt.AssertNoErr(t, err)
+
+		match := uuidRegex.FindStringSubmatch(id1.(string))
+		tt.AssertEqual(t, len(match), 2)
+		tt.AssertEqual(t, match[1], "7")
+		tt.AssertEqual(t, id1.(string) < id2.(string), true)
+	})
+
+	t.Run("should generate time ordered ULIDs", func(t *testing.T) {
+		id1, err := ULID(ctx)
+		tt.AssertNoErr(t, err)
+		time.Sleep(2 * time.Millisecond)
+		id2, err := ULID(ctx)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(id1.(string)), true)
+		tt.AssertEqual(t, id1.(string) < id2.(string), true)
+	})
+
+	t.Run("should generate the ID on insert without retrieving it from the database", func(t *testing.T) {
+		type uuidUser struct {
+			ID   string `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		u := uuidUser{Name: "Bia"}
+		err = dryRun.Insert(ctx, NewTable("users").WithIDGenerator(UUIDv7), &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, uuidRegex.MatchString(u.ID), true)
+
+		statement := dryRun.Statements()[0]
+		tt.AssertEqual(t, strings.Contains(statement.Query, "RETURNING"), false)
+		tt.AssertEqual(t, len(statement.Params), 2)
+		tt.AssertEqual(t, statement.Params[0] == u.ID || statement.Params[1] == u.ID, true)
+	})
+
+	t.Run("should generate the ID on upsert", func(t *testing.T) {
+		type uuidUser struct {
+			ID   string `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		u := uuidUser{Name: "Bia"}
+		err = dryRun.Upsert(ctx, NewTable("users").WithIDGenerator(UUIDv7), &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, uuidRegex.MatchString(u.ID), true)
+
+		statement := dryRun.Statements()[0]
+		tt.AssertEqual(t, statement.Params[0] == u.ID || statement.Params[1] == u.ID, true)
+	})
+
+	t.Run("should not overwrite IDs that are already set", func(t *testing.T) {
+		type uuidUser struct {
+			ID   string `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		u := uuidUser{ID: "fake-id", Name: "Bia"}
+		err = dryRun.Insert(ctx, NewTable("users").WithIDGenerator(UUIDv4), &u)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, u.ID, "fake-id")
+	})
+
+	t.Run("should save UUIDs on [16]byte attributes", func(t *testing.T) {
+		type fakeUUID [16]byte
+		type uuidUser struct {
+			ID   fakeUUID `ksql:"id"`
+			Name string   `ksql:"name"`
+		}
+
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		u := uuidUser{Name: "Bia"}
+		err = dryRun.Insert(ctx, NewTable("users").WithIDGenerator(UUIDv4), &u)
+		tt.AssertNoErr(t, err)
+		tt.AssertNotEqual(t, u.ID, fakeUUID{})
+		tt.AssertEqual(t, u.ID[6]>>4, byte(4))
+	})
+
+	t.Run("should report errors from the generator", func(t *testing.T) {
+		type uuidUser struct {
+			ID   string `ksql:"id"`
+			Name string `ksql:"name"`
+		}
+
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Insert(ctx, NewTable("users").WithIDGenerator(func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("fake error")
+		}), &uuidUser{Name: "Bia"})
+		tt.AssertErrContains(t, err, "generating ID", "fake error")
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+}
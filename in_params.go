@@ -0,0 +1,242 @@
+package ksql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// placeholderRef describes a single placeholder found on a query
+type placeholderRef struct {
+	start, end int
+	paramIdx   int
+	inClause   bool
+}
+
+// expandSliceParams expands the placeholders used inside `IN (...)`
+// clauses whose corresponding params are slices into one placeholder
+// for each element of the slice, e.g.:
+//
+//	"SELECT * FROM users WHERE id IN (?)", []int{1, 2, 3}
+//
+// Becomes:
+//
+//	"SELECT * FROM users WHERE id IN (?, ?, ?)", 1, 2, 3
+//
+// Placeholders on all other positions as well as []byte and
+// driver.Valuer params are never expanded, so it is still possible
+// to send arrays to the database, e.g. `WHERE id = ANY($1)` on Postgres.
+func expandSliceParams(dialect Dialect, query string, params []interface{}) (string, []interface{}, error) {
+	expand := make([]bool, len(params))
+	hasSlices := false
+	for i, param := range params {
+		expand[i] = isExpandableSlice(param)
+		hasSlices = hasSlices || expand[i]
+	}
+	if !hasSlices {
+		return query, params, nil
+	}
+
+	refs := findPlaceholders(dialect, query)
+
+	// If the same param is used more than once (which is only possible
+	// on dialects with numbered placeholders) we only expand it if all
+	// its references are inside `IN` clauses:
+	isReferenced := make([]bool, len(params))
+	for _, ref := range refs {
+		if ref.paramIdx >= len(params) {
+			continue
+		}
+		isReferenced[ref.paramIdx] = true
+		if !ref.inClause {
+			expand[ref.paramIdx] = false
+		}
+	}
+
+	var newParams []interface{}
+	offsets := make([]int, len(params))
+	for i, param := range params {
+		offsets[i] = len(newParams)
+		if !expand[i] || !isReferenced[i] {
+			newParams = append(newParams, param)
+			continue
+		}
+
+		v := reflect.ValueOf(param)
+		if v.Len() == 0 {
+			return "", nil, fmt.Errorf(
+				"ksql: can't expand an empty slice into the `IN` clause, received for param %d: %v",
+				i+1, param,
+			)
+		}
+
+		for j := 0; j < v.Len(); j++ {
+			newParams = append(newParams, v.Index(j).Interface())
+		}
+	}
+
+	var b strings.Builder
+	lastEnd := 0
+	for _, ref := range refs {
+		b.WriteString(query[lastEnd:ref.start])
+		lastEnd = ref.end
+
+		if ref.paramIdx >= len(params) {
+			// Let the database report the error for missing params:
+			b.WriteString(query[ref.start:ref.end])
+			continue
+		}
+
+		numPlaceholders := 1
+		if expand[ref.paramIdx] {
+			numPlaceholders = reflect.ValueOf(params[ref.paramIdx]).Len()
+		}
+
+		placeholders := make([]string, numPlaceholders)
+		for i := range placeholders {
+			placeholders[i] = dialect.Placeholder(offsets[ref.paramIdx] + i)
+		}
+		b.WriteString(strings.Join(placeholders, ", "))
+	}
+	b.WriteString(query[lastEnd:])
+
+	return b.String(), newParams, nil
+}
+
+func isExpandableSlice(param interface{}) bool {
+	if param == nil {
+		return false
+	}
+
+	if _, ok := param.(driver.Valuer); ok {
+		return false
+	}
+
+	t := reflect.TypeOf(param)
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+
+	// Byte slices are sent to the database as blobs:
+	return t.Elem().Kind() != reflect.Uint8
+}
+
+// Rebind converts the `?` placeholders of the query to the placeholders of
+// the input dialect, e.g. `$1` and `$2` on Postgres or `@p1` and `@p2` on
+// SQL Server, so the same query can run on all the databases, e.g.:
+//
+//	query := ksql.Rebind(db.Dialect(), "SELECT * FROM users WHERE age > ? AND name = ?")
+//
+// The question marks inside quotes and comments are kept as they are.
+//
+// The queries received by the ksql.DB methods can also be rebound
+// automatically with the `ksql.Config.RebindPlaceholders` option.
+func Rebind(dialect Dialect, query string) string {
+	if dialect.Placeholder(0) == "?" {
+		return query
+	}
+
+	refs := findPlaceholders(sqlite3Dialect{}, query)
+	if len(refs) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	lastEnd := 0
+	for _, ref := range refs {
+		b.WriteString(query[lastEnd:ref.start])
+		b.WriteString(dialect.Placeholder(ref.paramIdx))
+		lastEnd = ref.end
+	}
+	b.WriteString(query[lastEnd:])
+
+	return b.String()
+}
+
+// findPlaceholders returns all placeholders of the query
+// that are not inside quotes or comments.
+func findPlaceholders(dialect Dialect, query string) (refs []placeholderRef) {
+	// Dialects that use the same placeholder for all params are positional,
+	// the others are numbered like `$1` or `@p1`:
+	isPositional := dialect.Placeholder(0) == dialect.Placeholder(1)
+	prefix := dialect.Placeholder(0)
+	if !isPositional {
+		prefix = strings.TrimSuffix(prefix, "1")
+	}
+
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end == -1 {
+				return refs
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return refs
+			}
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end == -1 {
+				return refs
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], prefix):
+			ref := placeholderRef{
+				start: i,
+				end:   i + len(prefix),
+			}
+
+			if isPositional {
+				ref.paramIdx = len(refs)
+			} else {
+				for ref.end < len(query) && query[ref.end] >= '0' && query[ref.end] <= '9' {
+					ref.end++
+				}
+				n, err := strconv.Atoi(query[i+len(prefix) : ref.end])
+				if err != nil {
+					// Not a placeholder, e.g. a `$` with no number after it:
+					continue
+				}
+				ref.paramIdx = n - 1
+			}
+
+			ref.inClause = isInsideInClause(query, ref.start, ref.end)
+			refs = append(refs, ref)
+			i = ref.end - 1
+		}
+	}
+
+	return refs
+}
+
+// isInsideInClause checks if the placeholder between start and end
+// is the only element inside an `IN (...)` clause.
+func isInsideInClause(query string, start int, end int) bool {
+	before := strings.TrimRight(query[:start], " \t\r\n")
+	if !strings.HasSuffix(before, "(") {
+		return false
+	}
+	before = strings.TrimRight(before[:len(before)-1], " \t\r\n")
+	if len(before) < 2 || !strings.EqualFold(before[len(before)-2:], "IN") {
+		return false
+	}
+	if len(before) > 2 && isIdentifierChar(before[len(before)-3]) {
+		return false
+	}
+
+	after := strings.TrimLeft(query[end:], " \t\r\n")
+	return strings.HasPrefix(after, ")")
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
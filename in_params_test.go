@@ -0,0 +1,226 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/nullable"
+)
+
+func TestExpandSliceParams(t *testing.T) {
+	tests := []struct {
+		desc           string
+		dialect        string
+		query          string
+		params         []interface{}
+		expectedQuery  string
+		expectedParams []interface{}
+		expectedErr    []string
+	}{
+		{
+			desc:           "should not change queries without slice params",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE id IN ($1)`,
+			params:         []interface{}{42},
+			expectedQuery:  `SELECT * FROM users WHERE id IN ($1)`,
+			expectedParams: []interface{}{42},
+		},
+		{
+			desc:           "should expand slices on postgres",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE age > $1 AND id IN ($2) AND name = $3`,
+			params:         []interface{}{18, []int{1, 2, 3}, "foo"},
+			expectedQuery:  `SELECT * FROM users WHERE age > $1 AND id IN ($2, $3, $4) AND name = $5`,
+			expectedParams: []interface{}{18, 1, 2, 3, "foo"},
+		},
+		{
+			desc:           "should expand slices on sqlserver",
+			dialect:        "sqlserver",
+			query:          `SELECT * FROM users WHERE id IN ( @p1 ) AND name = @p2`,
+			params:         []interface{}{[]int{1, 2}, "foo"},
+			expectedQuery:  `SELECT * FROM users WHERE id IN ( @p1, @p2 ) AND name = @p3`,
+			expectedParams: []interface{}{1, 2, "foo"},
+		},
+		{
+			desc:           "should expand slices on mysql",
+			dialect:        "mysql",
+			query:          `SELECT * FROM users WHERE id in (?) AND name = ?`,
+			params:         []interface{}{[]uint{1, 2}, "foo"},
+			expectedQuery:  `SELECT * FROM users WHERE id in (?, ?) AND name = ?`,
+			expectedParams: []interface{}{uint(1), uint(2), "foo"},
+		},
+		{
+			desc:           "should expand slices on oracle",
+			dialect:        "oracle",
+			query:          `SELECT * FROM users WHERE id IN (:1) AND name = :2`,
+			params:         []interface{}{[]int{1, 2}, "foo"},
+			expectedQuery:  `SELECT * FROM users WHERE id IN (:1, :2) AND name = :3`,
+			expectedParams: []interface{}{1, 2, "foo"},
+		},
+		{
+			desc:           "should expand slices on sqlite3",
+			dialect:        "sqlite3",
+			query:          `SELECT * FROM users WHERE name = ? AND id NOT IN (?)`,
+			params:         []interface{}{"foo", []string{"a", "b"}},
+			expectedQuery:  `SELECT * FROM users WHERE name = ? AND id NOT IN (?, ?)`,
+			expectedParams: []interface{}{"foo", "a", "b"},
+		},
+		{
+			desc:           "should expand arrays",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE id IN ($1)`,
+			params:         []interface{}{[2]int{1, 2}},
+			expectedQuery:  `SELECT * FROM users WHERE id IN ($1, $2)`,
+			expectedParams: []interface{}{1, 2},
+		},
+		{
+			desc:           "should expand params that are reused inside IN clauses",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE id IN ($1) OR parent_id IN ($1)`,
+			params:         []interface{}{[]int{1, 2}},
+			expectedQuery:  `SELECT * FROM users WHERE id IN ($1, $2) OR parent_id IN ($1, $2)`,
+			expectedParams: []interface{}{1, 2},
+		},
+		{
+			desc:           "should not expand slices used outside of IN clauses",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE id = ANY($1)`,
+			params:         []interface{}{[]int{1, 2}},
+			expectedQuery:  `SELECT * FROM users WHERE id = ANY($1)`,
+			expectedParams: []interface{}{[]int{1, 2}},
+		},
+		{
+			desc:           "should not expand slices reused outside of IN clauses",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE id IN ($1) OR id = ANY($1)`,
+			params:         []interface{}{[]int{1, 2}},
+			expectedQuery:  `SELECT * FROM users WHERE id IN ($1) OR id = ANY($1)`,
+			expectedParams: []interface{}{[]int{1, 2}},
+		},
+		{
+			desc:           "should not expand placeholders inside functions that end with IN",
+			dialect:        "sqlite3",
+			query:          `SELECT * FROM users WHERE MIN(?) > 0`,
+			params:         []interface{}{[]int{1, 2}},
+			expectedQuery:  `SELECT * FROM users WHERE MIN(?) > 0`,
+			expectedParams: []interface{}{[]int{1, 2}},
+		},
+		{
+			desc:           "should not expand byte slices",
+			dialect:        "sqlite3",
+			query:          `SELECT * FROM users WHERE data IN (?)`,
+			params:         []interface{}{[]byte("foo")},
+			expectedQuery:  `SELECT * FROM users WHERE data IN (?)`,
+			expectedParams: []interface{}{[]byte("foo")},
+		},
+		{
+			desc:           "should ignore placeholders inside strings and comments",
+			dialect:        "sqlite3",
+			query:          "SELECT '?', \"?\", `?` FROM users -- ?\n WHERE /* ? */ id IN (?)",
+			params:         []interface{}{[]int{1, 2}},
+			expectedQuery:  "SELECT '?', \"?\", `?` FROM users -- ?\n WHERE /* ? */ id IN (?, ?)",
+			expectedParams: []interface{}{1, 2},
+		},
+		{
+			desc:           "should keep pointer params unchanged",
+			dialect:        "postgres",
+			query:          `SELECT * FROM users WHERE name = $1 AND id IN ($2)`,
+			params:         []interface{}{nullable.String("foo"), []int{1}},
+			expectedQuery:  `SELECT * FROM users WHERE name = $1 AND id IN ($2)`,
+			expectedParams: []interface{}{nullable.String("foo"), 1},
+		},
+
+		/* * * * * Testing error cases: * * * * */
+		{
+			desc:        "should report error for empty slices",
+			dialect:     "postgres",
+			query:       `SELECT * FROM users WHERE id IN ($1)`,
+			params:      []interface{}{[]int{}},
+			expectedErr: []string{"ksql", "empty slice", "IN"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			query, params, err := expandSliceParams(supportedDialects[test.dialect], test.query, test.params)
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+			tt.AssertEqual(t, params, test.expectedParams)
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		desc          string
+		dialect       string
+		query         string
+		expectedQuery string
+	}{
+		{
+			desc:          "should number the placeholders on postgres",
+			dialect:       "postgres",
+			query:         `SELECT * FROM users WHERE age > ? AND name = ?`,
+			expectedQuery: `SELECT * FROM users WHERE age > $1 AND name = $2`,
+		},
+		{
+			desc:          "should number the placeholders on sqlserver",
+			dialect:       "sqlserver",
+			query:         `SELECT * FROM users WHERE age > ? AND name = ?`,
+			expectedQuery: `SELECT * FROM users WHERE age > @p1 AND name = @p2`,
+		},
+		{
+			desc:          "should number the placeholders on oracle",
+			dialect:       "oracle",
+			query:         `SELECT * FROM users WHERE id IN (?)`,
+			expectedQuery: `SELECT * FROM users WHERE id IN (:1)`,
+		},
+		{
+			desc:          "should keep the question marks on sqlite3",
+			dialect:       "sqlite3",
+			query:         `SELECT * FROM users WHERE age > ?`,
+			expectedQuery: `SELECT * FROM users WHERE age > ?`,
+		},
+		{
+			desc:    "should ignore question marks inside quotes and comments",
+			dialect: "postgres",
+			query: `SELECT * FROM users WHERE name = '?' AND "why?" = ? -- why?
+				AND age > ? /* ? */`,
+			expectedQuery: `SELECT * FROM users WHERE name = '?' AND "why?" = $1 -- why?
+				AND age > $2 /* ? */`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dialect, err := GetDriverDialect(test.dialect)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, Rebind(dialect, test.query), test.expectedQuery)
+		})
+	}
+
+	t.Run("should rebind the queries with the RebindPlaceholders option", func(t *testing.T) {
+		ctx := context.Background()
+
+		var queries []string
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, params ...interface{}) (Result, error) {
+				queries = append(queries, query)
+				return NewMockResult(0, 1), nil
+			},
+		}, "postgres", Config{
+			RebindPlaceholders: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(ctx, "UPDATE users SET age = ? WHERE id = ?", 42, 1)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"UPDATE users SET age = $1 WHERE id = $2"})
+	})
+}
@@ -0,0 +1,47 @@
+package structs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+// These modifiers are handled directly by KSQL
+// so they can't be registered by the users:
+var builtinModifiers = map[string]bool{
+	"flatten":                true,
+	"softDelete":             true,
+	"optimisticLock":         true,
+	"tenant":                 true,
+	"timeNowUTC":             true,
+	"timeNowUTCSkipOnUpdate": true,
+	"notnull":                true,
+	"unique":                 true,
+	"quoted":                 true,
+	"omitempty":              true,
+	"readOnly":               true,
+}
+
+var modifiers = &sync.Map{}
+
+// RegisterAttrModifier makes a modifier available to be used on
+// the `ksql` tags, it panics if the name is already in use.
+func RegisterAttrModifier(name string, modifier ksqlmodifiers.AttrModifier) {
+	if name == "" || builtinModifiers[name] {
+		panic(fmt.Errorf("ksql: cannot register modifier '%s', this name is reserved", name))
+	}
+
+	if _, found := modifiers.LoadOrStore(name, modifier); found {
+		panic(fmt.Errorf("ksql: cannot register modifier '%s', this name is already in use", name))
+	}
+}
+
+// LoadAttrModifier returns the modifier registered with the input name
+func LoadAttrModifier(name string) (ksqlmodifiers.AttrModifier, bool) {
+	data, found := modifiers.Load(name)
+	if !found {
+		return ksqlmodifiers.AttrModifier{}, false
+	}
+	return data.(ksqlmodifiers.AttrModifier), true
+}
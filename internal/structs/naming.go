@@ -0,0 +1,43 @@
+package structs
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Naming works like the GetTagInfo and StructToMap functions but also
+// maps the exported attributes without the `ksql` tag to the columns
+// named by its naming function, e.g. `UserID` to `user_id`.
+//
+// Each Naming keeps its own cache, and a nil *Naming works exactly
+// like the GetTagInfo and StructToMap functions.
+type Naming struct {
+	naming func(fieldName string) string
+	cache  *sync.Map
+}
+
+// NewNaming instantiates a Naming using the input naming function
+func NewNaming(naming func(fieldName string) string) *Naming {
+	return &Naming{
+		naming: naming,
+		cache:  &sync.Map{},
+	}
+}
+
+// GetTagInfo returns the type information of the struct,
+// including the attributes named by the naming function
+func (n *Naming) GetTagInfo(key reflect.Type) (StructInfo, error) {
+	if n == nil {
+		return GetTagInfo(key)
+	}
+	return getCachedTagInfo(n.cache, key, n)
+}
+
+// StructToMap converts the struct to a map including
+// the attributes named by the naming function
+func (n *Naming) StructToMap(obj interface{}) (map[string]interface{}, error) {
+	if n == nil {
+		return StructToMap(obj)
+	}
+	return structToMap(n.cache, obj, n)
+}
@@ -3,8 +3,12 @@ package structs
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/vingarcia/ksql/ksqlmodifiers"
 )
 
 // StructInfo stores metainformation of the struct
@@ -12,18 +16,89 @@ import (
 // efectively and efficiently with reflection.
 type StructInfo struct {
 	IsNestedStruct bool
+	fields         []*FieldInfo
+	tables         []*FieldInfo
 	byIndex        map[int]*FieldInfo
 	byName         map[string]*FieldInfo
+
+	// SoftDeleteField is the field with the `softDelete`
+	// modifier or nil if the struct has no such field
+	SoftDeleteField *FieldInfo
+
+	// OptimisticLockField is the field with the `optimisticLock`
+	// modifier or nil if the struct has no such field
+	OptimisticLockField *FieldInfo
+
+	// TenantField is the field with the `tenant`
+	// modifier or nil if the struct has no such field
+	TenantField *FieldInfo
+
+	// Naming is the Naming used for building this StructInfo, or nil
+	// if only the tagged attributes are mapped, it should also be used
+	// for the nested structs and as part of the keys of the caches
+	// derived from the StructInfo.
+	Naming *Naming
 }
 
 // FieldInfo contains reflection and tags
 // information regarding a specific field
 // of a struct.
 type FieldInfo struct {
-	Name            string
-	Index           int
-	Valid           bool
-	SerializeAsJSON bool
+	Name  string
+	Index int
+	Valid bool
+
+	// Path is the sequence of indexes for reaching this attribute
+	// with `reflect.Value.FieldByIndex()`, for most attributes it
+	// only contains the Index, but for attributes of flattened
+	// structs it starts with the index of the flattened struct.
+	Path []int
+
+	SoftDelete     bool
+	OptimisticLock bool
+	Tenant         bool
+
+	// TimeNowUTC attributes are set to the current time
+	// in UTC when the record is inserted or updated
+	TimeNowUTC bool
+
+	// SkipOnInsert attributes are never inserted and
+	// SkipOnUpdate attributes are never updated, both
+	// are set for the attributes with the `readOnly` option
+	SkipOnInsert bool
+	SkipOnUpdate bool
+
+	// Modifier is the registered modifier used by the
+	// attribute, e.g. `json`, or nil if there is none
+	Modifier     *ksqlmodifiers.AttrModifier
+	ModifierName string
+
+	// SQLType, NotNull and Unique are declared with the `type=<sql type>`,
+	// `notnull` and `unique` options, they are only used when generating
+	// the schema of the table, e.g. with `ksql.CreateTableSQL()`.
+	SQLType string
+	NotNull bool
+	Unique  bool
+
+	// Quoted attributes are declared with the `quoted` option, and
+	// their column names are always escaped with their exact case,
+	// even on databases whose names are converted to upper case
+	Quoted bool
+
+	// OmitEmpty attributes are declared with the `omitempty` option, and
+	// are ignored on writes when they have the zero value of their type,
+	// just like nil pointers are
+	OmitEmpty bool
+
+	// Default is the value declared with the `default=<value>`
+	// modifier, already converted to the type of the attribute,
+	// or an invalid reflect.Value if there is no such modifier
+	Default reflect.Value
+
+	// Separator is only used by attributes tagged with `tablename`
+	// and it is placed between the name of this table and the names
+	// of the tables nested inside it for building their aliases.
+	Separator string
 }
 
 // ByIndex returns either the *FieldInfo of a valid
@@ -40,15 +115,27 @@ func (s StructInfo) ByIndex(idx int) *FieldInfo {
 // empty struct with Valid set to false
 func (s StructInfo) ByName(name string) *FieldInfo {
 	field, found := s.byName[name]
+	if !found {
+		// Some databases, e.g. Oracle, return the
+		// column names in upper case:
+		field, found = s.byName[strings.ToLower(name)]
+	}
 	if !found {
 		return &FieldInfo{}
 	}
 	return field
 }
 
-func (s StructInfo) add(field FieldInfo) {
+func (s *StructInfo) add(field FieldInfo) {
 	field.Valid = true
-	s.byIndex[field.Index] = &field
+	if field.Path == nil {
+		field.Path = []int{field.Index}
+	}
+
+	s.fields = append(s.fields, &field)
+	if len(field.Path) == 1 {
+		s.byIndex[field.Index] = &field
+	}
 	s.byName[field.Name] = &field
 
 	// Make sure to save a lowercased version because
@@ -56,11 +143,35 @@ func (s StructInfo) add(field FieldInfo) {
 	if _, found := s.byName[strings.ToLower(field.Name)]; !found {
 		s.byName[strings.ToLower(field.Name)] = &field
 	}
+
+	if field.SoftDelete {
+		s.SoftDeleteField = &field
+	}
+
+	if field.OptimisticLock {
+		s.OptimisticLockField = &field
+	}
+
+	if field.Tenant {
+		s.TenantField = &field
+	}
+}
+
+// Fields returns the info of all the valid fields in the order
+// they are declared, including the fields of flattened structs
+func (s StructInfo) Fields() []*FieldInfo {
+	return s.fields
+}
+
+// Tables returns the info of all the attributes tagged with
+// `tablename`, i.e. the structs representing the tables of a JOIN
+func (s StructInfo) Tables() []*FieldInfo {
+	return s.tables
 }
 
 // NumFields ...
 func (s StructInfo) NumFields() int {
-	return len(s.byIndex)
+	return len(s.fields)
 }
 
 // This cache is kept as a pkg variable
@@ -76,10 +187,10 @@ var tagInfoCache = &sync.Map{}
 // a struct, but for now this accessor is the one
 // we are using
 func GetTagInfo(key reflect.Type) (StructInfo, error) {
-	return getCachedTagInfo(tagInfoCache, key)
+	return getCachedTagInfo(tagInfoCache, key, nil)
 }
 
-func getCachedTagInfo(tagInfoCache *sync.Map, key reflect.Type) (StructInfo, error) {
+func getCachedTagInfo(tagInfoCache *sync.Map, key reflect.Type, naming *Naming) (StructInfo, error) {
 	if data, found := tagInfoCache.Load(key); found {
 		if info, ok := data.(StructInfo); !ok {
 			return StructInfo{}, fmt.Errorf("invalid cache entry, expected type StructInfo, found %T", data)
@@ -88,7 +199,7 @@ func getCachedTagInfo(tagInfoCache *sync.Map, key reflect.Type) (StructInfo, err
 		}
 	}
 
-	info, err := getTagNames(key)
+	info, err := getTagNames(key, naming)
 	if err != nil {
 		return StructInfo{}, err
 	}
@@ -107,6 +218,15 @@ func getCachedTagInfo(tagInfoCache *sync.Map, key reflect.Type) (StructInfo, err
 // the slower steps of the reflection required to perform
 // this task.
 func StructToMap(obj interface{}) (map[string]interface{}, error) {
+	return structToMap(tagInfoCache, obj, nil)
+}
+
+func structToMap(tagInfoCache *sync.Map, obj interface{}, naming *Naming) (map[string]interface{}, error) {
+	// This interface is exported as ksql.StaticValuer:
+	if valuer, ok := obj.(interface{ KSQLValues() map[string]interface{} }); ok {
+		return valuer.KSQLValues(), nil
+	}
+
 	v := reflect.ValueOf(obj)
 	t := v.Type()
 
@@ -118,19 +238,14 @@ func StructToMap(obj interface{}) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("input must be a struct or struct pointer")
 	}
 
-	info, err := getCachedTagInfo(tagInfoCache, t)
+	info, err := getCachedTagInfo(tagInfoCache, t, naming)
 	if err != nil {
 		return nil, err
 	}
 
 	m := map[string]interface{}{}
-	for i := 0; i < v.NumField(); i++ {
-		fieldInfo := info.ByIndex(i)
-		if !fieldInfo.Valid {
-			continue
-		}
-
-		field := v.Field(i)
+	for _, fieldInfo := range info.Fields() {
+		field := v.FieldByIndex(fieldInfo.Path)
 		ft := field.Type()
 		if ft.Kind() == reflect.Ptr {
 			if field.IsNil() {
@@ -138,6 +253,8 @@ func StructToMap(obj interface{}) (map[string]interface{}, error) {
 			}
 
 			field = field.Elem()
+		} else if fieldInfo.OmitEmpty && field.IsZero() {
+			continue
 		}
 
 		m[fieldInfo.Name] = field.Interface()
@@ -227,68 +344,70 @@ func (p PtrConverter) Convert(destType reflect.Type) (reflect.Value, error) {
 	return destValue, nil
 }
 
+var timeType = reflect.TypeOf(time.Time{})
+
 // This function collects only the names
 // that will be used from the input type.
 //
 // This should save several calls to `Field(i).Tag.Get("foo")`
 // which improves performance by a lot.
-func getTagNames(t reflect.Type) (StructInfo, error) {
+func getTagNames(t reflect.Type, naming *Naming) (StructInfo, error) {
 	info := StructInfo{
 		byIndex: map[int]*FieldInfo{},
 		byName:  map[string]*FieldInfo{},
+		Naming:  naming,
+	}
+
+	var namingFn func(string) string
+	if naming != nil {
+		namingFn = naming.naming
+	}
+	err := addTaggedFields(&info, t, nil, "", namingFn)
+	if err != nil {
+		return StructInfo{}, err
 	}
-	for i := 0; i < t.NumField(); i++ {
-		// If this field is private:
-		if t.Field(i).PkgPath != "" {
-			return StructInfo{}, fmt.Errorf("all fields using the ksql tags must be exported, but %v is unexported", t)
-		}
 
-		name := t.Field(i).Tag.Get("ksql")
-		if name == "" {
+	// The attributes tagged with `tablename` represent the tables
+	// of a JOIN, and are only used when scanning nested structs:
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("tablename")
+		if tag == "" {
 			continue
 		}
 
-		tags := strings.Split(name, ",")
-		serializeAsJSON := false
-		if len(tags) > 1 {
-			name = tags[0]
-			serializeAsJSON = tags[1] == "json"
+		options := strings.Split(tag, ",")
+		table := FieldInfo{
+			Name:      options[0],
+			Index:     i,
+			Path:      []int{i},
+			Valid:     true,
+			Separator: "_",
 		}
-
-		if _, found := info.byName[name]; found {
-			return StructInfo{}, fmt.Errorf(
-				"struct contains multiple attributes with the same ksql tag name: '%s'",
-				name,
-			)
+		for _, option := range options[1:] {
+			if !strings.HasPrefix(option, "separator=") {
+				return StructInfo{}, fmt.Errorf(
+					"the tablename attribute '%s' of struct %v uses an unknown option: '%s'",
+					table.Name, t, option,
+				)
+			}
+			table.Separator = strings.TrimPrefix(option, "separator=")
 		}
 
-		info.add(FieldInfo{
-			Name:            name,
-			Index:           i,
-			SerializeAsJSON: serializeAsJSON,
-		})
+		info.tables = append(info.tables, &table)
 	}
 
 	// If there were `ksql` tags present, then we are finished:
-	if len(info.byIndex) > 0 {
+	if len(info.fields) > 0 {
 		return info, nil
 	}
 
 	// If there are no `ksql` tags in the struct, lets assume
 	// it is a struct tagged with `tablename` for allowing JOINs
-	for i := 0; i < t.NumField(); i++ {
-		name := t.Field(i).Tag.Get("tablename")
-		if name == "" {
-			continue
-		}
-
-		info.add(FieldInfo{
-			Name:  name,
-			Index: i,
-		})
+	for _, table := range info.tables {
+		info.add(*table)
 	}
 
-	if len(info.byIndex) == 0 {
+	if len(info.fields) == 0 {
 		return StructInfo{}, fmt.Errorf("the struct must contain at least one attribute with the ksql tag")
 	}
 
@@ -331,3 +450,325 @@ func DecodeAsSliceOfStructs(slice reflect.Type) (
 
 	return elemType, isPtr, nil
 }
+
+// addTaggedFields adds all the attributes of the input type that
+// have the `ksql` tag to the StructInfo, the attributes of flattened
+// structs are added recursively with their names prefixed by `prefix`.
+//
+// If the naming function is not nil the exported attributes without the
+// `ksql` tag are also added, named by converting their Go names with it.
+func addTaggedFields(info *StructInfo, t reflect.Type, parentPath []int, prefix string, naming func(string) string) error {
+	for i := 0; i < t.NumField(); i++ {
+		path := append(append([]int{}, parentPath...), i)
+		name, found := t.Field(i).Tag.Lookup("ksql")
+
+		// Embedded structs without the `ksql` tag are flattened
+		// as long as they declare attributes with the `ksql` tag,
+		// and since only their attributes are used, their own
+		// types are allowed to be private:
+		if !found && t.Field(i).Anonymous && (hasTaggedFields(t.Field(i).Type) || naming != nil && t.Field(i).Type.Kind() == reflect.Struct) {
+			err := addFlattenedFields(info, t.Field(i), path, prefix, naming)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The attributes tagged with `tablename` are never named automatically
+		// since they represent the tables of a JOIN:
+		if _, isTable := t.Field(i).Tag.Lookup("tablename"); !found && naming != nil && !isTable && t.Field(i).PkgPath == "" {
+			name = naming(t.Field(i).Name)
+		}
+
+		// If this field is private:
+		if t.Field(i).PkgPath != "" {
+			return fmt.Errorf("all fields using the ksql tags must be exported, but %v is unexported", t)
+		}
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		tags := splitTagOptions(name)
+		name = prefix + tags[0]
+		var modifier *ksqlmodifiers.AttrModifier
+		var modifierName string
+		var sqlType string
+		notNull := false
+		unique := false
+		quoted := false
+		omitEmpty := false
+		readOnly := false
+		flatten := false
+		softDelete := false
+		optimisticLock := false
+		tenant := false
+		timeNowUTC := false
+		skipOnUpdate := false
+		var defaultValue reflect.Value
+		for _, option := range tags[1:] {
+			if strings.HasPrefix(option, "default=") {
+				var err error
+				defaultValue, err = parseDefaultValue(t.Field(i).Type, strings.TrimPrefix(option, "default="))
+				if err != nil {
+					return fmt.Errorf(
+						"attribute '%s' of struct %v has an invalid default value: %s",
+						name, t, err,
+					)
+				}
+				continue
+			}
+
+			if strings.HasPrefix(option, "type=") {
+				sqlType = strings.TrimPrefix(option, "type=")
+				continue
+			}
+
+			switch option {
+			case "":
+				continue
+			case "notnull":
+				notNull = true
+			case "unique":
+				unique = true
+			case "quoted":
+				quoted = true
+			case "omitempty":
+				omitEmpty = true
+			case "readOnly":
+				readOnly = true
+			case "flatten":
+				flatten = true
+			case "softDelete":
+				softDelete = true
+			case "optimisticLock":
+				optimisticLock = true
+			case "tenant":
+				tenant = true
+			case "timeNowUTC":
+				timeNowUTC = true
+			case "timeNowUTCSkipOnUpdate":
+				timeNowUTC = true
+				skipOnUpdate = true
+			default:
+				registeredModifier, found := LoadAttrModifier(option)
+				if !found {
+					return fmt.Errorf(
+						"attribute '%s' of struct %v uses an unknown modifier: '%s'",
+						name, t, option,
+					)
+				}
+
+				if modifier != nil {
+					return fmt.Errorf(
+						"attribute '%s' of struct %v can't have more than one registered modifier",
+						name, t,
+					)
+				}
+				modifier = &registeredModifier
+				modifierName = option
+			}
+		}
+
+		if flatten {
+			if len(tags) > 2 {
+				return fmt.Errorf(
+					"attribute '%s' of struct %v can't use other modifiers together with the flatten modifier",
+					t.Field(i).Name, t,
+				)
+			}
+
+			err := addFlattenedFields(info, t.Field(i), path, name, naming)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, found := info.byName[name]; found {
+			return fmt.Errorf(
+				"struct contains multiple attributes with the same ksql tag name: '%s'",
+				name,
+			)
+		}
+
+		if softDelete && info.SoftDeleteField != nil {
+			return fmt.Errorf(
+				"struct contains multiple attributes with the softDelete modifier: '%s' and '%s'",
+				info.SoftDeleteField.Name, name,
+			)
+		}
+
+		if timeNowUTC {
+			fieldType := t.Field(i).Type
+			if fieldType != timeType && fieldType != reflect.PtrTo(timeType) {
+				return fmt.Errorf(
+					"the timeNowUTC modifiers can only be used on attributes of type time.Time or *time.Time, but '%s' is of type %v",
+					name, fieldType,
+				)
+			}
+		}
+
+		if optimisticLock && info.OptimisticLockField != nil {
+			return fmt.Errorf(
+				"struct contains multiple attributes with the optimisticLock modifier: '%s' and '%s'",
+				info.OptimisticLockField.Name, name,
+			)
+		}
+
+		if tenant && info.TenantField != nil {
+			return fmt.Errorf(
+				"struct contains multiple attributes with the tenant modifier: '%s' and '%s'",
+				info.TenantField.Name, name,
+			)
+		}
+
+		// Read only attributes are only scanned, e.g. for generated columns:
+		skipOnInsert := readOnly
+		skipOnUpdate = skipOnUpdate || readOnly
+		if modifier != nil {
+			skipOnInsert = skipOnInsert || modifier.SkipOnInsert
+			skipOnUpdate = skipOnUpdate || modifier.SkipOnUpdate
+		}
+
+		info.add(FieldInfo{
+			Name:           name,
+			Index:          path[0],
+			Path:           path,
+			SoftDelete:     softDelete,
+			OptimisticLock: optimisticLock,
+			Tenant:         tenant,
+			TimeNowUTC:     timeNowUTC,
+			SkipOnInsert:   skipOnInsert,
+			SkipOnUpdate:   skipOnUpdate,
+			Modifier:       modifier,
+			ModifierName:   modifierName,
+			Default:        defaultValue,
+			SQLType:        sqlType,
+			NotNull:        notNull,
+			Unique:         unique,
+			Quoted:         quoted,
+			OmitEmpty:      omitEmpty,
+		})
+	}
+
+	return nil
+}
+
+func addFlattenedFields(info *StructInfo, field reflect.StructField, path []int, prefix string, naming func(string) string) error {
+	if field.Type.Kind() != reflect.Struct {
+		return fmt.Errorf(
+			"only struct attributes can be flattened, but '%s' is of type %v",
+			field.Name, field.Type,
+		)
+	}
+
+	numFields := len(info.fields)
+	err := addTaggedFields(info, field.Type, path, prefix, naming)
+	if err != nil {
+		return err
+	}
+
+	if len(info.fields) == numFields {
+		return fmt.Errorf(
+			"the flattened struct %v must contain at least one attribute with the ksql tag",
+			field.Type,
+		)
+	}
+
+	return nil
+}
+
+// splitTagOptions splits the ksql tag on the commas that are not
+// inside parentheses, so that options like `type=decimal(10,2)`
+// are kept as a single option.
+func splitTagOptions(tag string) []string {
+	var options []string
+	depth := 0
+	start := 0
+	for i, c := range tag {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(options, tag[start:])
+}
+
+// hasTaggedFields checks if the input type is a struct with at
+// least one attribute with the `ksql` tag, including the
+// attributes of its embedded structs.
+func hasTaggedFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if _, found := t.Field(i).Tag.Lookup("ksql"); found {
+			return true
+		}
+
+		if t.Field(i).Anonymous && hasTaggedFields(t.Field(i).Type) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDefaultValue converts the value of a `default=<value>`
+// modifier to the type of the attribute, only strings, booleans
+// and numbers, or pointers to them, are supported.
+func parseDefaultValue(t reflect.Type, s string) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		elem, err := parseDefaultValue(t.Elem(), s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("default values are not supported for attributes of type %v", t)
+	}
+
+	return v, nil
+}
@@ -12,6 +12,16 @@ func (b mockTxBeginner) BeginTx(ctx context.Context) (Tx, error) {
 	return b.BeginTxFn(ctx)
 }
 
+// mockTxRetrier mocks the ksql.TxRetrier interface
+type mockTxRetrier struct {
+	mockTxBeginner
+	RetryTxFn func(ctx context.Context, attempt int, err error) bool
+}
+
+func (r mockTxRetrier) RetryTx(ctx context.Context, attempt int, err error) bool {
+	return r.RetryTxFn(ctx, attempt, err)
+}
+
 // mockDBAdapter mocks the ksql.DBAdapter interface
 type mockDBAdapter struct {
 	ExecContextFn  func(ctx context.Context, query string, args ...interface{}) (Result, error)
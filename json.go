@@ -1,12 +1,46 @@
 package ksql
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"reflect"
+
+	"github.com/vingarcia/ksql/ksqlmodifiers"
 )
 
+// jsonModifier implements the `json` modifier, which saves
+// the attribute on the database serialized as JSON
+var jsonModifier = ksqlmodifiers.AttrModifier{
+	Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+		return (&jsonSerializable{
+			DriverName: opInfo.DriverName,
+			Attr:       attrPtr,
+		}).Scan(dbValue)
+	},
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		return jsonSerializable{
+			DriverName: opInfo.DriverName,
+			Attr:       inputValue,
+		}, nil
+	},
+}
+
+// jsonNullableModifier implements the `jsonNullable` modifier, which works
+// like the `json` modifier except that zero values, e.g. empty structs
+// or nil maps and slices, are saved as NULL instead of as JSON
+var jsonNullableModifier = ksqlmodifiers.AttrModifier{
+	Scan: jsonModifier.Scan,
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		if inputValue == nil || reflect.ValueOf(inputValue).IsZero() {
+			return nil, nil
+		}
+
+		return jsonModifier.Value(ctx, opInfo, inputValue)
+	},
+}
+
 // This type was created to make it easier to adapt
 // input attributes to be convertible to and from JSON
 // before sending or receiving it from the database.
@@ -18,7 +52,21 @@ type jsonSerializable struct {
 // Scan Implements the Scanner interface in order to load
 // this field from the JSON stored in the database
 func (j *jsonSerializable) Scan(value interface{}) error {
-	if value == nil {
+	var rawJSON []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		rawJSON = v
+	case json.RawMessage:
+		rawJSON = v
+	case string:
+		// Required since sqlite3 and sqlserver might return strings not bytes
+		rawJSON = []byte(v)
+	default:
+		return fmt.Errorf("unexpected type received to Scan: %T", value)
+	}
+
+	if len(rawJSON) == 0 {
 		v := reflect.ValueOf(j.Attr)
 		// Set the struct to its 0 value just like json.Unmarshal
 		// does for nil attributes:
@@ -26,23 +74,18 @@ func (j *jsonSerializable) Scan(value interface{}) error {
 		return nil
 	}
 
-	// Required since sqlite3 returns strings not bytes
-	if v, ok := value.(string); ok {
-		value = []byte(v)
-	}
-
-	rawJSON, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("unexpected type received to Scan: %T", value)
-	}
 	return json.Unmarshal(rawJSON, j.Attr)
 }
 
 // Value Implements the Valuer interface in order to save
 // this field as JSON on the database.
+//
+// MySQL, MariaDB, SQLServer and DuckDB receive it as a string since they
+// reject binary values on JSON and VARCHAR columns.
 func (j jsonSerializable) Value() (driver.Value, error) {
 	b, err := json.Marshal(j.Attr)
-	if j.DriverName == "sqlserver" {
+	switch j.DriverName {
+	case "sqlserver", "mysql", "mariadb", "duckdb":
 		return string(b), err
 	}
 	return b, err
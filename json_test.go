@@ -0,0 +1,93 @@
+package ksql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+func TestJSONSerializable(t *testing.T) {
+	t.Run("Scan should accept all the representations used by the drivers", func(t *testing.T) {
+		tests := []struct {
+			desc     string
+			dbValue  interface{}
+			expected address
+		}{
+			{
+				desc:     "bytes",
+				dbValue:  []byte(`{"country":"BR"}`),
+				expected: address{Country: "BR"},
+			},
+			{
+				desc:     "strings",
+				dbValue:  `{"country":"US"}`,
+				expected: address{Country: "US"},
+			},
+			{
+				desc:     "raw json",
+				dbValue:  json.RawMessage(`{"country":"PT"}`),
+				expected: address{Country: "PT"},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				var addr address
+				err := (&jsonSerializable{Attr: &addr}).Scan(test.dbValue)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, addr, test.expected)
+			})
+		}
+	})
+
+	t.Run("Scan should reset the attribute for NULL and empty values", func(t *testing.T) {
+		for _, dbValue := range []interface{}{nil, "", []byte{}} {
+			addr := address{City: "should be overwritten"}
+			err := (&jsonSerializable{Attr: &addr}).Scan(dbValue)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, addr, address{})
+		}
+	})
+
+	t.Run("Scan should report error for unexpected types", func(t *testing.T) {
+		var addr address
+		err := (&jsonSerializable{Attr: &addr}).Scan(42)
+		tt.AssertErrContains(t, err, "unexpected type", "int")
+	})
+
+	t.Run("Value should use strings only for drivers that require it", func(t *testing.T) {
+		for _, driver := range []string{"postgres", "sqlite3"} {
+			value, err := jsonSerializable{DriverName: driver, Attr: map[string]string{"country": "BR"}}.Value()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, value, []byte(`{"country":"BR"}`))
+		}
+
+		for _, driver := range []string{"mysql", "sqlserver"} {
+			value, err := jsonSerializable{DriverName: driver, Attr: map[string]string{"country": "BR"}}.Value()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, value, `{"country":"BR"}`)
+		}
+	})
+}
+
+func TestJSONNullableModifier(t *testing.T) {
+	ctx := context.Background()
+	opInfo := ksqlmodifiers.OpInfo{DriverName: "postgres", Method: "Insert"}
+
+	t.Run("should save zero values as NULL", func(t *testing.T) {
+		for _, zeroValue := range []interface{}{nil, address{}, map[string]interface{}(nil), []string(nil)} {
+			value, err := jsonNullableModifier.Value(ctx, opInfo, zeroValue)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, value, nil)
+		}
+	})
+
+	t.Run("should save other values as JSON", func(t *testing.T) {
+		value, err := jsonNullableModifier.Value(ctx, opInfo, []string{})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, jsonSerializable{DriverName: "postgres", Attr: []string{}})
+	})
+}
@@ -0,0 +1,13 @@
+// Command kanalyzer runs the ksql Analyzer, it is meant to be used with go vet:
+//
+//	go vet -vettool=$(which kanalyzer) ./...
+package main
+
+import (
+	"github.com/vingarcia/ksql/kanalyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(kanalyzer.Analyzer)
+}
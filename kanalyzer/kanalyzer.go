@@ -0,0 +1,416 @@
+// Package kanalyzer contains a go/analysis Analyzer that reports the
+// mistakes on the structs and arguments passed to ksql that would
+// otherwise only be noticed at runtime, namely:
+//
+//   - Structs with multiple attributes using the same `ksql` tag
+//   - Unexported attributes with the `ksql` tag
+//   - Records missing the ID columns of the ksql.Table on
+//     the methods that need them, e.g. Patch and Delete
+//   - Records and destinations that are not pointers
+//   - Slices passed as the destination of QueryOne
+//
+// It can be run with `go vet` using the command on `cmd/kanalyzer`:
+//
+//	go install github.com/vingarcia/ksql/kanalyzer/cmd/kanalyzer@latest
+//	go vet -vettool=$(which kanalyzer) ./...
+//
+// The ID columns are only known for the tables created by calling
+// `ksql.NewTable()` with constant arguments, which is the usual case.
+package kanalyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const ksqlPackagePath = "github.com/vingarcia/ksql"
+
+// Analyzer reports the incorrect uses of the ksql structs and methods
+var Analyzer = &analysis.Analyzer{
+	Name:      "ksql",
+	Doc:       "check the structs and arguments passed to the ksql methods",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(tableIDsFact)},
+}
+
+// tableIDsFact records the ID columns of the package level ksql.Table
+// variables so they are also known when used by other packages.
+type tableIDsFact struct {
+	IDs []string
+}
+
+func (*tableIDsFact) AFact() {}
+
+func (f *tableIDsFact) String() string {
+	return fmt.Sprintf("ksql.Table IDs: %s", strings.Join(f.IDs, ", "))
+}
+
+type argKind int
+
+const (
+	// recordArg must be a pointer to struct
+	recordArg argKind = iota
+
+	// idOrRecordArg can be either an ID value or a struct
+	idOrRecordArg
+
+	// sliceDestArg must be a pointer to slice
+	sliceDestArg
+
+	// singleDestArg must be a pointer but not to a slice
+	singleDestArg
+)
+
+type methodInfo struct {
+	argPos     int
+	kind       argKind
+	requireIDs bool
+}
+
+var methods = map[string]methodInfo{
+	"Insert":           {argPos: 2, kind: recordArg},
+	"InsertFields":     {argPos: 2, kind: recordArg},
+	"Upsert":           {argPos: 2, kind: recordArg, requireIDs: true},
+	"Update":           {argPos: 2, kind: recordArg, requireIDs: true},
+	"UpdateFields":     {argPos: 2, kind: recordArg, requireIDs: true},
+	"UpdateReturning":  {argPos: 2, kind: recordArg, requireIDs: true},
+	"Patch":            {argPos: 2, kind: recordArg, requireIDs: true},
+	"PatchWithResult":  {argPos: 2, kind: recordArg, requireIDs: true},
+	"DeleteReturning":  {argPos: 2, kind: recordArg, requireIDs: true},
+	"Delete":           {argPos: 2, kind: idOrRecordArg, requireIDs: true},
+	"DeleteWithResult": {argPos: 2, kind: idOrRecordArg, requireIDs: true},
+	"PatchMap":         {argPos: 2, kind: idOrRecordArg, requireIDs: true},
+	"Query":            {argPos: 1, kind: sliceDestArg},
+	"QueryOne":         {argPos: 1, kind: singleDestArg},
+}
+
+type checker struct {
+	pass *analysis.Pass
+
+	// reported avoids reporting the same struct more than once
+	reported map[types.Type]bool
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	c := checker{
+		pass:     pass,
+		reported: map[types.Type]bool{},
+	}
+
+	exportTableFacts(pass)
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+
+		method, found := methods[sel.Sel.Name]
+		if !found || len(call.Args) <= method.argPos {
+			return
+		}
+
+		fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != ksqlPackagePath {
+			return
+		}
+
+		c.checkCall(call, sel.Sel.Name, method)
+	})
+
+	return nil, nil
+}
+
+// exportTableFacts exports the ID columns of the package level tables
+func exportTableFacts(pass *analysis.Pass) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok || len(valueSpec.Names) != len(valueSpec.Values) {
+					continue
+				}
+				for i, name := range valueSpec.Names {
+					ids, ok := getNewTableIDs(pass.TypesInfo, valueSpec.Values[i])
+					if !ok {
+						continue
+					}
+					if obj := pass.TypesInfo.Defs[name]; obj != nil {
+						pass.ExportObjectFact(obj, &tableIDsFact{IDs: ids})
+					}
+				}
+			}
+		}
+	}
+}
+
+// getNewTableIDs returns the ID columns of expressions such as
+// `ksql.NewTable("users", "id")` or `ksql.NewTable("users").WithStruct(User{})`
+func getNewTableIDs(info *types.Info, expr ast.Expr) ([]string, bool) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return nil, false
+	}
+
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != ksqlPackagePath {
+		return nil, false
+	}
+
+	// Methods of the Table, e.g. `WithConflictColumns()`, don't change the IDs:
+	if sig := fn.Type().(*types.Signature); sig.Recv() != nil {
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil, false
+		}
+		return getNewTableIDs(info, sel.X)
+	}
+
+	if fn.Name() != "NewTable" || call.Ellipsis.IsValid() {
+		return nil, false
+	}
+
+	var ids []string
+	for _, arg := range call.Args[1:] {
+		value := info.Types[arg].Value
+		if value == nil || value.Kind() != constant.String {
+			return nil, false
+		}
+		ids = append(ids, constant.StringVal(value))
+	}
+	if len(ids) == 0 {
+		ids = []string{"id"}
+	}
+
+	return ids, true
+}
+
+// getTableIDs returns the ID columns of the table argument if they are known
+func (c checker) getTableIDs(expr ast.Expr) ([]string, bool) {
+	if ids, ok := getNewTableIDs(c.pass.TypesInfo, expr); ok {
+		return ids, true
+	}
+
+	var ident *ast.Ident
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return nil, false
+	}
+
+	obj, ok := c.pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok {
+		return nil, false
+	}
+
+	var fact tableIDsFact
+	if !c.pass.ImportObjectFact(obj, &fact) {
+		return nil, false
+	}
+	return fact.IDs, true
+}
+
+func (c checker) checkCall(call *ast.CallExpr, methodName string, method methodInfo) {
+	arg := call.Args[method.argPos]
+	t := c.pass.TypesInfo.TypeOf(arg)
+	if t == nil {
+		return
+	}
+
+	// Arguments of type interface{} can't be checked:
+	if _, isInterface := t.Underlying().(*types.Interface); isInterface {
+		return
+	}
+
+	ptr, isPtr := t.Underlying().(*types.Pointer)
+	var elem types.Type = t
+	if isPtr {
+		elem = ptr.Elem()
+	}
+
+	var structType types.Type
+	switch method.kind {
+	case recordArg:
+		if !isPtr {
+			c.pass.Reportf(arg.Pos(), "ksql.%s expects a pointer to struct, but got %s", methodName, c.typeString(t))
+			return
+		}
+		structType = elem
+
+	case idOrRecordArg:
+		structType = elem
+
+	case sliceDestArg:
+		if !isPtr {
+			c.pass.Reportf(arg.Pos(), "ksql.%s expects a pointer to a slice, but got %s", methodName, c.typeString(t))
+			return
+		}
+		slice, ok := elem.Underlying().(*types.Slice)
+		if !ok {
+			c.pass.Reportf(arg.Pos(), "ksql.%s expects a pointer to a slice, but got %s, use QueryOne for loading a single record", methodName, c.typeString(t))
+			return
+		}
+		structType = slice.Elem()
+		if ptr, ok := structType.Underlying().(*types.Pointer); ok {
+			structType = ptr.Elem()
+		}
+
+	case singleDestArg:
+		if !isPtr {
+			c.pass.Reportf(arg.Pos(), "ksql.%s expects a pointer, but got %s", methodName, c.typeString(t))
+			return
+		}
+		// []byte is a valid destination for single columns:
+		if slice, ok := elem.Underlying().(*types.Slice); ok && !isByte(slice.Elem()) {
+			c.pass.Reportf(arg.Pos(), "ksql.%s expects a pointer to a single record, but got %s, use Query for loading multiple records", methodName, c.typeString(t))
+			return
+		}
+		structType = elem
+	}
+
+	st, ok := structType.Underlying().(*types.Struct)
+	if !ok || isScalarStruct(structType) {
+		return
+	}
+
+	columns := c.checkStruct(arg, structType, st)
+
+	if !method.requireIDs || columns == nil {
+		return
+	}
+
+	ids, ok := c.getTableIDs(call.Args[1])
+	if !ok {
+		return
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !columns[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		c.pass.Reportf(arg.Pos(), "ksql.%s requires the ID columns of the table but %s has no attributes tagged with: %s", methodName, c.typeString(structType), strings.Join(missing, ", "))
+	}
+}
+
+// checkStruct reports the problems on the `ksql` tags of the struct
+// and returns its column names, or nil if it is a nested struct.
+func (c checker) checkStruct(arg ast.Expr, t types.Type, st *types.Struct) map[string]bool {
+	columns := map[string]bool{}
+	var msgs []string
+	isNested := collectColumns(st, columns, &msgs)
+
+	if len(msgs) > 0 && !c.reported[t] {
+		c.reported[t] = true
+		for _, msg := range msgs {
+			c.pass.Reportf(arg.Pos(), "%s %s", c.typeString(t), msg)
+		}
+	}
+
+	if isNested {
+		return nil
+	}
+	return columns
+}
+
+// collectColumns adds the column names of the struct to the map
+// including the flattened attributes and reports its problems
+// on msgs, it returns true if it is a nested struct.
+func collectColumns(st *types.Struct, columns map[string]bool, msgs *[]string) (isNested bool) {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		if _, found := tag.Lookup("tablename"); found {
+			isNested = true
+			continue
+		}
+
+		name := tag.Get("ksql")
+		if name == "" {
+			continue
+		}
+
+		if !field.Exported() {
+			*msgs = append(*msgs, fmt.Sprintf("has the `ksql` tag on the unexported attribute %s", field.Name()))
+			continue
+		}
+
+		tags := strings.Split(name, ",")
+		if hasModifier(tags[1:], "flatten") {
+			fieldType := field.Type()
+			if ptr, ok := fieldType.Underlying().(*types.Pointer); ok {
+				fieldType = ptr.Elem()
+			}
+			if embedded, ok := fieldType.Underlying().(*types.Struct); ok {
+				collectColumns(embedded, columns, msgs)
+			}
+			continue
+		}
+
+		if columns[tags[0]] {
+			*msgs = append(*msgs, fmt.Sprintf("has multiple attributes with the same `ksql` tag name: %q", tags[0]))
+			continue
+		}
+		columns[tags[0]] = true
+	}
+
+	return isNested
+}
+
+func hasModifier(modifiers []string, name string) bool {
+	for _, modifier := range modifiers {
+		if modifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isByte(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Byte
+}
+
+// isScalarStruct returns true for the structs that are scanned as a
+// single value, i.e. time.Time and the ones implementing sql.Scanner
+func isScalarStruct(t types.Type) bool {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" {
+		return true
+	}
+	methods := types.NewMethodSet(types.NewPointer(t))
+	return methods.Lookup(nil, "Scan") != nil
+}
+
+func (c checker) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(c.pass.Pkg))
+}
@@ -0,0 +1,93 @@
+package a
+
+import (
+	"context"
+	"time"
+
+	"github.com/vingarcia/ksql"
+
+	"models"
+)
+
+var usersTable = ksql.NewTable("users") // want usersTable:"ksql.Table IDs: id"
+
+type User struct {
+	ID   int    `ksql:"id"`
+	Name string `ksql:"name"`
+}
+
+type DuplicatedTags struct {
+	ID       int    `ksql:"id"`
+	Name     string `ksql:"name"`
+	FullName string `ksql:"name"`
+}
+
+type UnexportedTag struct {
+	ID   int    `ksql:"id"`
+	name string `ksql:"name"`
+}
+
+type Address struct {
+	Street string `ksql:"street"`
+}
+
+type UserWithAddress struct {
+	ID      int     `ksql:"id"`
+	Address Address `ksql:"address,flatten"`
+	Street  string  `ksql:"street"`
+}
+
+type UserWithoutID struct {
+	Name string `ksql:"name"`
+}
+
+type Membership struct {
+	UserID int `ksql:"user_id"`
+}
+
+type UserAndPost struct {
+	User User `tablename:"u"`
+}
+
+func queries(ctx context.Context, db ksql.Provider) {
+	var user User
+	var users []User
+	var userPtrs []*User
+
+	_ = db.Insert(ctx, usersTable, &user)
+	_ = db.Insert(ctx, usersTable, user) // want `ksql.Insert expects a pointer to struct, but got User`
+	_ = db.Patch(ctx, usersTable, &user)
+	_ = db.Delete(ctx, usersTable, 42)
+	_ = db.Delete(ctx, usersTable, user)
+
+	_ = db.Insert(ctx, usersTable, &DuplicatedTags{}) // want `DuplicatedTags has multiple attributes with the same .ksql. tag name: "name"`
+	_ = db.Insert(ctx, usersTable, &DuplicatedTags{})
+	_ = db.Insert(ctx, usersTable, &UnexportedTag{})   // want `UnexportedTag has the .ksql. tag on the unexported attribute name`
+	_ = db.Insert(ctx, usersTable, &UserWithAddress{}) // want `UserWithAddress has multiple attributes with the same .ksql. tag name: "street"`
+
+	_ = db.Insert(ctx, usersTable, &UserWithoutID{})
+	_ = db.Patch(ctx, usersTable, &UserWithoutID{})           // want `ksql.Patch requires the ID columns of the table but UserWithoutID has no attributes tagged with: id`
+	_ = db.Delete(ctx, ksql.NewTable("users", "uid"), User{}) // want `ksql.Delete requires the ID columns of the table but User has no attributes tagged with: uid`
+	_ = db.Patch(ctx, models.PermissionsTable, &Membership{}) // want `ksql.Patch requires the ID columns of the table but Membership has no attributes tagged with: perm_id`
+	_ = db.Patch(ctx, models.PermissionsTable, &models.Permission{})
+
+	_ = db.Query(ctx, &users, "FROM users")
+	_ = db.Query(ctx, &userPtrs, "FROM users")
+	_ = db.Query(ctx, users, "FROM users") // want `ksql.Query expects a pointer to a slice, but got \[\]User`
+	_ = db.Query(ctx, &user, "FROM users") // want `ksql.Query expects a pointer to a slice, but got \*User, use QueryOne for loading a single record`
+
+	var count int
+	var data []byte
+	var createdAt time.Time
+	var rows []UserAndPost
+	_ = db.QueryOne(ctx, &user, "FROM users")
+	_ = db.QueryOne(ctx, &count, "SELECT count(*) FROM users")
+	_ = db.QueryOne(ctx, &data, "SELECT data FROM users")
+	_ = db.QueryOne(ctx, &createdAt, "SELECT created_at FROM users")
+	_ = db.Query(ctx, &rows, "FROM users u")
+	_ = db.QueryOne(ctx, user, "FROM users")   // want `ksql.QueryOne expects a pointer, but got User`
+	_ = db.QueryOne(ctx, &users, "FROM users") // want `ksql.QueryOne expects a pointer to a single record, but got \*\[\]User, use Query for loading multiple records`
+
+	var record interface{} = &user
+	_ = db.Insert(ctx, usersTable, record)
+}
@@ -0,0 +1,26 @@
+// Package ksql is a stub of the ksql package with the
+// declarations used by the tests of the analyzer
+package ksql
+
+import "context"
+
+type Table struct {
+	name string
+	ids  []string
+}
+
+func NewTable(name string, ids ...string) Table {
+	return Table{name: name, ids: ids}
+}
+
+func (t Table) WithConflictColumns(columns ...string) Table {
+	return t
+}
+
+type Provider interface {
+	Insert(ctx context.Context, table Table, record interface{}) error
+	Patch(ctx context.Context, table Table, record interface{}) error
+	Delete(ctx context.Context, table Table, idOrRecord interface{}) error
+	Query(ctx context.Context, records interface{}, query string, params ...interface{}) error
+	QueryOne(ctx context.Context, record interface{}, query string, params ...interface{}) error
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vingarcia/ksql"
+
+var PermissionsTable = ksql.NewTable("permissions", "user_id", "perm_id").WithConflictColumns("user_id") // want PermissionsTable:"ksql.Table IDs: user_id, perm_id"
+
+type Permission struct {
+	UserID int `ksql:"user_id"`
+	PermID int `ksql:"perm_id"`
+}
@@ -80,9 +80,8 @@ func (i Insert) BuildQuery(dialect ksql.Dialect) (sqlQuery string, params []inte
 
 	b.WriteString(" (")
 	var escapedNames []string
-	for i := 0; i < info.NumFields(); i++ {
-		name := info.ByIndex(i).Name
-		escapedNames = append(escapedNames, dialect.Escape(name))
+	for _, fieldInfo := range info.Fields() {
+		escapedNames = append(escapedNames, dialect.Escape(fieldInfo.Name))
 	}
 	b.WriteString(strings.Join(escapedNames, ", "))
 	b.WriteString(") VALUES ")
@@ -96,9 +95,9 @@ func (i Insert) BuildQuery(dialect ksql.Dialect) (sqlQuery string, params []inte
 		}
 
 		placeholders := []string{}
-		for j := 0; j < info.NumFields(); j++ {
+		for _, fieldInfo := range info.Fields() {
 			placeholders = append(placeholders, dialect.Placeholder(len(params)))
-			params = append(params, record.Field(j).Interface())
+			params = append(params, record.FieldByIndex(fieldInfo.Path).Interface())
 		}
 		values = append(values, "("+strings.Join(placeholders, ", ")+")")
 	}
@@ -215,9 +215,8 @@ func buildSelectQuery(obj interface{}, dialect ksql.Dialect) (string, error) {
 	}
 
 	var escapedNames []string
-	for i := 0; i < info.NumFields(); i++ {
-		name := info.ByIndex(i).Name
-		escapedNames = append(escapedNames, dialect.Escape(name))
+	for _, fieldInfo := range info.Fields() {
+		escapedNames = append(escapedNames, dialect.Escape(fieldInfo.Name))
 	}
 
 	query := strings.Join(escapedNames, ", ")
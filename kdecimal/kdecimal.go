@@ -0,0 +1,393 @@
+// Package kdecimal contains an arbitrary precision decimal type that
+// can be scanned from and written to NUMERIC and DECIMAL columns on all
+// the adapters without losing precision, e.g. for monetary values:
+//
+//	type Invoice struct {
+//		ID    int              `ksql:"id"`
+//		Total kdecimal.Decimal `ksql:"total"`
+//
+//		// Pointers can be used for nullable columns:
+//		Discount *kdecimal.Decimal `ksql:"discount"`
+//	}
+//
+// The values are sent to the database as strings, which the databases
+// convert to their decimal types, and scanned from the text representation
+// of the columns, including the Postgres MONEY type on the `en_US` locale,
+// e.g. `$1,234.56`. Note that SQLite has no decimal type, so the values
+// should be saved on TEXT columns there in order to avoid losing precision.
+package kdecimal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an immutable decimal number represented by an
+// arbitrary precision integer and the number of digits after
+// the decimal point, e.g. 12.50 has value 1250 and scale 2.
+//
+// The zero value of Decimal is the number 0.
+type Decimal struct {
+	value *big.Int
+	scale int32
+}
+
+// New returns the decimal `value * 10^-scale`, e.g.
+// `kdecimal.New(1250, 2)` is the number 12.50.
+func New(value int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{
+			value: new(big.Int).Mul(big.NewInt(value), pow10(-scale)),
+		}
+	}
+
+	return Decimal{
+		value: big.NewInt(value),
+		scale: scale,
+	}
+}
+
+// NewFromInt returns the decimal representation of the input integer
+func NewFromInt(value int64) Decimal {
+	return New(value, 0)
+}
+
+// NewFromString parses decimal numbers written in plain notation, e.g.
+// `-1234.5678`, or in scientific notation, e.g. `1.5e-3`.
+func NewFromString(str string) (Decimal, error) {
+	original := str
+	if str == "" {
+		return Decimal{}, fmt.Errorf("kdecimal: can't parse an empty string as a decimal")
+	}
+
+	var exp int64
+	if i := strings.IndexAny(str, "eE"); i != -1 {
+		var err error
+		exp, err = strconv.ParseInt(str[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("kdecimal: invalid exponent on decimal '%s'", original)
+		}
+		str = str[:i]
+	}
+
+	var scale int64
+	if i := strings.IndexByte(str, '.'); i != -1 {
+		scale = int64(len(str) - i - 1)
+		str = str[:i] + str[i+1:]
+	}
+
+	digits := strings.TrimLeft(str, "+-")
+	if digits == "" || len(str)-len(digits) > 1 || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("kdecimal: can't parse '%s' as a decimal", original)
+	}
+
+	value, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("kdecimal: can't parse '%s' as a decimal", original)
+	}
+
+	scale -= exp
+	if scale < 0 {
+		value.Mul(value, pow10(int32(-scale)))
+		scale = 0
+	}
+
+	return Decimal{value: value, scale: int32(scale)}, nil
+}
+
+// RequireFromString works like NewFromString but panics if the
+// string is not a valid decimal, which is useful for constants.
+func RequireFromString(str string) Decimal {
+	d, err := NewFromString(str)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewFromFloat converts a float64 to a decimal using the
+// shortest representation that converts back to the same float,
+// e.g. 0.1 is converted to 0.1 instead of 0.1000000000000000055.
+func NewFromFloat(f float64) Decimal {
+	d, err := NewFromString(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		// Only NaN and the infinities can't be converted:
+		panic(fmt.Errorf("kdecimal: can't convert %v to a decimal", f))
+	}
+	return d
+}
+
+func (d Decimal) unscaled() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// rescale returns the unscaled value of the decimal
+// with the input scale, which must not be smaller
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.unscaled()
+	}
+	return new(big.Int).Mul(d.unscaled(), pow10(scale-d.scale))
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := maxScale(d, other)
+	return Decimal{
+		value: new(big.Int).Add(d.rescale(scale), other.rescale(scale)),
+		scale: scale,
+	}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d.Add(other.Neg())
+}
+
+// Mul returns d * other
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{
+		value: new(big.Int).Mul(d.unscaled(), other.unscaled()),
+		scale: d.scale + other.scale,
+	}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{
+		value: new(big.Int).Neg(d.unscaled()),
+		scale: d.scale,
+	}
+}
+
+// Round rounds the decimal to the input number of digits after the
+// decimal point, rounding half away from zero, e.g. 2.345 is rounded
+// to 2.35 and -2.345 to -2.35 with 2 places.
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.scale {
+		return Decimal{value: d.rescale(places), scale: places}
+	}
+
+	divisor := pow10(d.scale - places)
+	quotient, remainder := new(big.Int).QuoRem(d.unscaled(), divisor, new(big.Int))
+
+	// Rounds half away from zero by comparing twice the remainder with the divisor:
+	remainder.Abs(remainder).Lsh(remainder, 1)
+	if remainder.Cmp(divisor) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(d.Sign())))
+	}
+
+	if places < 0 {
+		return Decimal{value: quotient.Mul(quotient, pow10(-places))}
+	}
+	return Decimal{value: quotient, scale: places}
+}
+
+// Cmp compares the decimals and returns -1 if d < other,
+// 0 if d == other and +1 if d > other.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := maxScale(d, other)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal checks if both decimals represent the same number,
+// regardless of their scales, e.g. 1.5 is equal to 1.50
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0 and +1 if d > 0
+func (d Decimal) Sign() int {
+	return d.unscaled().Sign()
+}
+
+// IsZero checks if the decimal is equal to 0
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Rat returns the decimal as a *big.Rat, which can be
+// used for the operations not supported by Decimal, e.g.
+// divisions, before converting back with `Decimal.Round()`.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled(), pow10(d.scale))
+}
+
+// Float64 returns the nearest float64 to the decimal,
+// which might lose precision
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns the decimal in plain notation
+// keeping its scale, e.g. 12.50 or -0.001
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled()).String()
+
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.scale <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= int(d.scale) {
+		digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Scan implements the sql.Scanner interface, it accepts the
+// text representations of the decimals as well as numbers,
+// and the decimal types of the drivers, e.g. duckdb.Decimal.
+//
+// Use *kdecimal.Decimal attributes for nullable columns,
+// since scanning NULL into a Decimal is an error.
+func (d *Decimal) Scan(value interface{}) (err error) {
+	switch v := value.(type) {
+	case nil:
+		return fmt.Errorf("kdecimal: can't scan NULL into a Decimal, use a *kdecimal.Decimal instead")
+	case string:
+		*d, err = parseDatabaseString(v)
+	case []byte:
+		*d, err = parseDatabaseString(string(v))
+	case int64:
+		*d = NewFromInt(v)
+	case float64:
+		*d = NewFromFloat(v)
+	case float32:
+		*d = NewFromFloat(float64(v))
+	case driver.Valuer:
+		driverValue, err := v.Value()
+		if err != nil {
+			return fmt.Errorf("kdecimal: error reading value of type %T: %s", value, err)
+		}
+		if _, isValuer := driverValue.(driver.Valuer); isValuer {
+			return fmt.Errorf("kdecimal: can't scan value of type %T into a Decimal", value)
+		}
+		return d.Scan(driverValue)
+	case fmt.Stringer:
+		*d, err = parseDatabaseString(v.String())
+	default:
+		var ok bool
+		*d, ok = fromScaledInt(value)
+		if !ok {
+			return fmt.Errorf("kdecimal: can't scan value of type %T into a Decimal", value)
+		}
+	}
+
+	return err
+}
+
+// fromScaledInt converts the decimal types of the drivers that
+// have no methods for converting them, but are represented as
+// structs with the `Value *big.Int` and the `Scale` attributes,
+// e.g. the duckdb.Decimal type.
+func fromScaledInt(value interface{}) (Decimal, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Struct {
+		return Decimal{}, false
+	}
+
+	unscaled := v.FieldByName("Value")
+	if !unscaled.IsValid() || !unscaled.CanInterface() {
+		return Decimal{}, false
+	}
+	bigInt, ok := unscaled.Interface().(*big.Int)
+	if !ok || bigInt == nil {
+		return Decimal{}, false
+	}
+
+	var scale int64
+	switch scaleValue := v.FieldByName("Scale"); scaleValue.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		scale = int64(scaleValue.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		scale = scaleValue.Int()
+	default:
+		return Decimal{}, false
+	}
+
+	return Decimal{
+		value: new(big.Int).Set(bigInt),
+		scale: int32(scale),
+	}, true
+}
+
+// parseDatabaseString parses the decimals returned by the
+// databases, which might be formatted as money, e.g. $1,234.56
+func parseDatabaseString(str string) (Decimal, error) {
+	d, err := NewFromString(str)
+	if err == nil {
+		return d, nil
+	}
+
+	negative := strings.HasPrefix(str, "-") || strings.HasPrefix(str, "(")
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' {
+			return r
+		}
+		return -1
+	}, str)
+	if negative {
+		cleaned = "-" + cleaned
+	}
+
+	d, cleanErr := NewFromString(cleaned)
+	if cleanErr != nil {
+		return Decimal{}, err
+	}
+
+	return d, nil
+}
+
+// Value implements the driver.Valuer interface,
+// sending the decimal as a string to the database
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes the decimal as a JSON string, since
+// JSON numbers are usually decoded as floats
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON decodes the decimal from either
+// a JSON string or a JSON number
+func (d *Decimal) UnmarshalJSON(data []byte) (err error) {
+	str := string(data)
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		str = unquoted
+	}
+
+	*d, err = NewFromString(str)
+	return err
+}
+
+func maxScale(a Decimal, b Decimal) int32 {
+	if a.scale > b.scale {
+		return a.scale
+	}
+	return b.scale
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,160 @@
+package kdecimal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestNewFromString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "0", expected: "0"},
+		{input: "12.50", expected: "12.50"},
+		{input: "-0.001", expected: "-0.001"},
+		{input: "+42", expected: "42"},
+		{input: ".5", expected: "0.5"},
+		{input: "1.5e-3", expected: "0.0015"},
+		{input: "1.5E3", expected: "1500"},
+		{input: "123456789012345678901234567890.123456789", expected: "123456789012345678901234567890.123456789"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			d, err := NewFromString(test.input)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, d.String(), test.expected)
+		})
+	}
+
+	t.Run("should report invalid decimals", func(t *testing.T) {
+		for _, input := range []string{"", "abc", "1.2.3", "--1", "1e", "1,5", "."} {
+			_, err := NewFromString(input)
+			tt.AssertErrContains(t, err, "kdecimal")
+		}
+	})
+}
+
+func TestArithmetic(t *testing.T) {
+	a := RequireFromString("10.25")
+	b := RequireFromString("0.125")
+
+	tt.AssertEqual(t, a.Add(b).String(), "10.375")
+	tt.AssertEqual(t, a.Sub(b).String(), "10.125")
+	tt.AssertEqual(t, a.Mul(b).String(), "1.28125")
+	tt.AssertEqual(t, a.Neg().String(), "-10.25")
+
+	// The classic float64 problem:
+	tt.AssertEqual(t, NewFromFloat(0.1).Add(NewFromFloat(0.2)).String(), "0.3")
+
+	var zero Decimal
+	tt.AssertEqual(t, zero.String(), "0")
+	tt.AssertEqual(t, zero.IsZero(), true)
+	tt.AssertEqual(t, zero.Add(a).String(), "10.25")
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int32
+		expected string
+	}{
+		{input: "2.345", places: 2, expected: "2.35"},
+		{input: "-2.345", places: 2, expected: "-2.35"},
+		{input: "2.344", places: 2, expected: "2.34"},
+		{input: "2.5", places: 0, expected: "3"},
+		{input: "2.5", places: 3, expected: "2.500"},
+		{input: "1251", places: -2, expected: "1300"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tt.AssertEqual(t, RequireFromString(test.input).Round(test.places).String(), test.expected)
+		})
+	}
+}
+
+func TestCmp(t *testing.T) {
+	tt.AssertEqual(t, RequireFromString("1.5").Equal(RequireFromString("1.50")), true)
+	tt.AssertEqual(t, RequireFromString("1.5").Cmp(RequireFromString("1.49")), 1)
+	tt.AssertEqual(t, RequireFromString("-1.5").Cmp(RequireFromString("1")), -1)
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		desc     string
+		input    interface{}
+		expected string
+	}{
+		{desc: "string", input: "12.50", expected: "12.50"},
+		{desc: "bytes", input: []byte("-0.10"), expected: "-0.10"},
+		{desc: "int64", input: int64(42), expected: "42"},
+		{desc: "float64", input: float64(12.5), expected: "12.5"},
+		{desc: "money", input: "$1,234.56", expected: "1234.56"},
+		{desc: "negative money", input: "-$1,234.56", expected: "-1234.56"},
+		{desc: "scaled big.Int", input: fakeDriverDecimal{Width: 5, Scale: 2, Value: big.NewInt(-12345)}, expected: "-123.45"},
+		{desc: "driver.Valuer", input: fakeValuer{value: "12.50"}, expected: "12.50"},
+		{desc: "fmt.Stringer", input: big.NewFloat(1.5), expected: "1.5"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var d Decimal
+			err := d.Scan(test.input)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, d.String(), test.expected)
+		})
+	}
+
+	t.Run("should report NULL and invalid values", func(t *testing.T) {
+		var d Decimal
+		err := d.Scan(nil)
+		tt.AssertErrContains(t, err, "NULL", "*kdecimal.Decimal")
+
+		err = d.Scan("not a number")
+		tt.AssertErrContains(t, err, "not a number")
+
+		err = d.Scan(true)
+		tt.AssertErrContains(t, err, "bool")
+	})
+}
+
+// fakeDriverDecimal has the same attributes as the duckdb.Decimal type
+type fakeDriverDecimal struct {
+	Width uint8
+	Scale uint8
+	Value *big.Int
+}
+
+type fakeValuer struct {
+	value driver.Value
+}
+
+func (f fakeValuer) Value() (driver.Value, error) {
+	return f.value, nil
+}
+
+func TestValue(t *testing.T) {
+	value, err := RequireFromString("12.50").Value()
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, value, "12.50")
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Total Decimal `json:"total"`
+	}{Total: RequireFromString("12.50")})
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, string(data), `{"total":"12.50"}`)
+
+	var decoded struct {
+		Total    Decimal `json:"total"`
+		Discount Decimal `json:"discount"`
+	}
+	err = json.Unmarshal([]byte(`{"total":"12.50","discount":0.25}`), &decoded)
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, decoded.Total.String(), "12.50")
+	tt.AssertEqual(t, decoded.Discount.String(), "0.25")
+}
@@ -0,0 +1,8 @@
+module github.com/vingarcia/ksql/kmigrate
+
+go 1.16
+
+require (
+	github.com/vingarcia/ksql v1.4.7
+	github.com/vingarcia/ksql/adapters/ksqlite3 v1.4.7
+)
@@ -0,0 +1,449 @@
+// Package kmigrate runs versioned schema migrations using a ksql.DB,
+// so that the migrations share the same connection and configuration
+// used by the rest of the application, e.g.:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	func main() {
+//		// ...
+//		err = kmigrate.Migrate(ctx, db, migrations)
+//	}
+//
+// The migrations are SQL files named as `<version>_<name>.up.sql` and
+// `<version>_<name>.down.sql`, e.g. `0001_create_users.up.sql`, which can
+// be in any directory of the file system, and Go functions registered with
+// `Config.Migrations`. The applied versions are saved on the
+// `schema_migrations` table, which is created if it doesn't exist.
+package kmigrate
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vingarcia/ksql"
+)
+
+// DefaultTableName is the table used for saving
+// the applied migrations if none is configured
+const DefaultTableName = "schema_migrations"
+
+// Migration describes a single version of the schema, the Up function
+// applies it and the Down function, which is optional, reverts it.
+//
+// The functions receive a ksql.Provider bound to the
+// transaction used for running the migrations.
+type Migration struct {
+	Version int64
+	Name    string
+
+	Up   func(ctx context.Context, db ksql.Provider) error
+	Down func(ctx context.Context, db ksql.Provider) error
+}
+
+// Config contains the optional configurations for the Migrator
+type Config struct {
+	// TableName is the table used for saving the applied
+	// migrations, it defaults to "schema_migrations".
+	TableName string
+
+	// Migrations are applied along with the SQL files, they
+	// can't have the same version as any of the files.
+	Migrations []Migration
+}
+
+// MigrationStatus describes a migration and when it was applied,
+// AppliedAt is nil for the migrations that are still pending.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts the migrations of a database
+type Migrator struct {
+	db         ksql.DB
+	tableName  string
+	migrations []Migration
+}
+
+// Migrate applies all the pending migrations, it is a shortcut for:
+//
+//	migrator, err := kmigrate.New(db, fsys, kmigrate.Config{})
+//	// ...
+//	err = migrator.Up(ctx)
+func Migrate(ctx context.Context, db ksql.DB, fsys fs.FS) error {
+	migrator, err := New(db, fsys, Config{})
+	if err != nil {
+		return err
+	}
+
+	return migrator.Up(ctx)
+}
+
+// New loads the SQL migrations of the file system, which might be nil
+// if all the migrations are configured as Go functions, and returns a
+// Migrator for applying them to the database.
+func New(db ksql.DB, fsys fs.FS, config Config) (*Migrator, error) {
+	if config.TableName == "" {
+		config.TableName = DefaultTableName
+	}
+
+	migrations := map[int64]Migration{}
+	if fsys != nil {
+		var err error
+		migrations, err = readMigrations(fsys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, migration := range config.Migrations {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("kmigrate: missing the Up function of migration %d", migration.Version)
+		}
+		if _, found := migrations[migration.Version]; found {
+			return nil, fmt.Errorf("kmigrate: found more than one migration with version %d", migration.Version)
+		}
+		migrations[migration.Version] = migration
+	}
+
+	sorted := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		sorted = append(sorted, migration)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		tableName:  config.TableName,
+		migrations: sorted,
+	}, nil
+}
+
+// Up applies all the pending migrations in the order of their versions,
+// including the migrations with versions older than the last applied one.
+//
+// All the migrations run in a single transaction along with the lock that
+// prevents other Migrators from running at the same time, so if one of them
+// fails none of them is applied, except on databases that commit schema
+// changes implicitly, like MySQL.
+//
+// On MySQL the `multiStatements=true` parameter of the connection string
+// is required for running the SQL files with more than one statement.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(db ksql.Provider, applied map[int64]migrationRecord) error {
+		for _, migration := range m.migrations {
+			if _, found := applied[migration.Version]; found {
+				continue
+			}
+
+			err := migration.Up(ctx, db)
+			if err != nil {
+				return fmt.Errorf("kmigrate: error applying migration %d (%s): %s", migration.Version, migration.Name, err)
+			}
+
+			_, err = db.Exec(ctx, fmt.Sprintf(
+				"INSERT INTO %s (%s, %s, %s) VALUES (%s, %s, %s)",
+				m.escape(m.tableName), m.escape("version"), m.escape("name"), m.escape("applied_at"),
+				m.placeholder(0), m.placeholder(1), m.placeholder(2),
+			), migration.Version, migration.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("kmigrate: error saving migration %d as applied: %s", migration.Version, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the `steps` applied migrations with the highest versions,
+// starting from the highest one, which is also the reverse order they
+// were applied by `Up()`, and it runs in a single transaction just like it.
+//
+// It returns an error if one of the migrations being
+// reverted has no Down function or is not known by the Migrator.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	migrations := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		migrations[migration.Version] = migration
+	}
+
+	return m.run(ctx, func(db ksql.Provider, applied map[int64]migrationRecord) error {
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i] > versions[j]
+		})
+
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration, found := migrations[version]
+			if !found {
+				return fmt.Errorf("kmigrate: can't revert migration %d (%s) because it was not found", version, applied[version].Name)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("kmigrate: can't revert migration %d (%s) because it has no Down function", version, migration.Name)
+			}
+
+			err := migration.Down(ctx, db)
+			if err != nil {
+				return fmt.Errorf("kmigrate: error reverting migration %d (%s): %s", version, migration.Name, err)
+			}
+
+			_, err = db.Exec(ctx, fmt.Sprintf(
+				"DELETE FROM %s WHERE %s = %s",
+				m.escape(m.tableName), m.escape("version"), m.placeholder(0),
+			), version)
+			if err != nil {
+				return fmt.Errorf("kmigrate: error saving migration %d as reverted: %s", version, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status returns all the known and applied migrations sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.run(ctx, func(db ksql.Provider, applied map[int64]migrationRecord) error {
+		for _, migration := range m.migrations {
+			status := MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+			if record, found := applied[migration.Version]; found {
+				appliedAt := record.AppliedAt
+				status.AppliedAt = &appliedAt
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+
+		// The migrations applied by other versions of the application:
+		for _, record := range applied {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, MigrationStatus{
+				Version:   record.Version,
+				Name:      record.Name,
+				AppliedAt: &appliedAt,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+type migrationRecord struct {
+	Version   int64     `ksql:"version"`
+	Name      string    `ksql:"name"`
+	AppliedAt time.Time `ksql:"applied_at"`
+}
+
+// run creates the migrations table if necessary and calls fn with
+// the applied migrations inside a transaction holding the lock
+func (m *Migrator) run(ctx context.Context, fn func(db ksql.Provider, applied map[int64]migrationRecord) error) error {
+	_, err := m.db.Exec(ctx, m.createTableQuery())
+	if err != nil {
+		return fmt.Errorf("kmigrate: error creating the migrations table: %s", err)
+	}
+
+	return m.db.Transaction(ctx, func(db ksql.Provider) (err error) {
+		unlock, err := m.lock(ctx, db)
+		if err != nil {
+			return fmt.Errorf("kmigrate: error acquiring the migrations lock: %s", err)
+		}
+		defer func() {
+			if unlockErr := unlock(); unlockErr != nil && err == nil {
+				err = fmt.Errorf("kmigrate: error releasing the migrations lock: %s", unlockErr)
+			}
+		}()
+
+		var records []migrationRecord
+		err = db.Query(ctx, &records, "FROM "+m.escape(m.tableName))
+		if err != nil {
+			return fmt.Errorf("kmigrate: error loading the applied migrations: %s", err)
+		}
+
+		applied := map[int64]migrationRecord{}
+		for _, record := range records {
+			applied[record.Version] = record
+		}
+
+		return fn(db, applied)
+	})
+}
+
+// lock prevents other Migrators from running concurrently until the end of
+// the current transaction, the returned function must be called afterwards
+// for releasing the locks that are not bound to the transaction.
+func (m *Migrator) lock(ctx context.Context, db ksql.Provider) (unlock func() error, err error) {
+	noop := func() error { return nil }
+
+	h := fnv.New64a()
+	h.Write([]byte("kmigrate:" + m.tableName))
+	lockKey := int64(h.Sum64())
+	lockName := "kmigrate_" + strconv.FormatInt(lockKey, 16)
+
+	switch m.db.Dialect().DriverName() {
+	case "postgres":
+		_, err = db.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey)
+		return noop, err
+	case "mysql", "mariadb":
+		// GET_LOCK is bound to the connection instead of the transaction:
+		var acquired int
+		err = db.QueryOne(ctx, &acquired, "SELECT GET_LOCK(?, -1)", lockName)
+		if err == nil && acquired != 1 {
+			err = fmt.Errorf("GET_LOCK returned %d", acquired)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := db.Exec(ctx, "DO RELEASE_LOCK(?)", lockName)
+			return err
+		}, nil
+	case "sqlserver":
+		_, err = db.Exec(ctx, "EXEC sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Transaction', @LockTimeout = -1", lockName)
+		return noop, err
+	case "oracle":
+		_, err = db.Exec(ctx, "LOCK TABLE "+m.escape(m.tableName)+" IN EXCLUSIVE MODE")
+		return noop, err
+	default:
+		// SQLite and DuckDB lock the whole database on the first write of the
+		// transaction, so we write to the table before reading it:
+		_, err = db.Exec(ctx, "DELETE FROM "+m.escape(m.tableName)+" WHERE 1 = 0")
+		return noop, err
+	}
+}
+
+func (m *Migrator) createTableQuery() string {
+	table := m.escape(m.tableName)
+	version, name, appliedAt := m.escape("version"), m.escape("name"), m.escape("applied_at")
+
+	switch m.db.Dialect().DriverName() {
+	case "mysql", "mariadb":
+		return fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s (%s BIGINT PRIMARY KEY, %s VARCHAR(255) NOT NULL, %s DATETIME(6) NOT NULL)",
+			table, version, name, appliedAt,
+		)
+	case "sqlserver":
+		return fmt.Sprintf(
+			"IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (%s BIGINT PRIMARY KEY, %s NVARCHAR(255) NOT NULL, %s DATETIME2 NOT NULL)",
+			m.tableName, table, version, name, appliedAt,
+		)
+	case "oracle":
+		// Oracle has no `IF NOT EXISTS`, so we ignore the
+		// error ORA-00955 of tables that already exist:
+		return fmt.Sprintf(
+			"BEGIN EXECUTE IMMEDIATE 'CREATE TABLE %s (%s NUMBER(19) PRIMARY KEY, %s VARCHAR2(255) NOT NULL, %s TIMESTAMP NOT NULL)'; "+
+				"EXCEPTION WHEN OTHERS THEN IF SQLCODE != -955 THEN RAISE; END IF; END;",
+			table, version, name, appliedAt,
+		)
+	default:
+		return fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s (%s BIGINT PRIMARY KEY, %s VARCHAR(255) NOT NULL, %s TIMESTAMP NOT NULL)",
+			table, version, name, appliedAt,
+		)
+	}
+}
+
+func (m *Migrator) escape(name string) string {
+	return m.db.Dialect().Escape(name)
+}
+
+func (m *Migrator) placeholder(idx int) string {
+	return m.db.Dialect().Placeholder(idx)
+}
+
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// readMigrations loads the SQL migrations from all
+// the directories of the file system by version
+func readMigrations(fsys fs.FS) (map[int64]Migration, error) {
+	migrations := map[int64]Migration{}
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		match := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version on file '%s': %s", path, err)
+		}
+
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		migration, found := migrations[version]
+		if found && migration.Name != match[2] {
+			return fmt.Errorf("found more than one migration with version %d: '%s' and '%s'", version, migration.Name, match[2])
+		}
+		migration.Version = version
+		migration.Name = match[2]
+
+		fn := execFunc(string(content))
+		if match[3] == "up" {
+			if migration.Up != nil {
+				return fmt.Errorf("found more than one up migration with version %d", version)
+			}
+			migration.Up = fn
+		} else {
+			if migration.Down != nil {
+				return fmt.Errorf("found more than one down migration with version %d", version)
+			}
+			migration.Down = fn
+		}
+
+		migrations[version] = migration
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kmigrate: error reading the migration files: %s", err)
+	}
+
+	for version, migration := range migrations {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("kmigrate: missing the up file of migration %d (%s)", version, migration.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+func execFunc(query string) func(ctx context.Context, db ksql.Provider) error {
+	return func(ctx context.Context, db ksql.Provider) error {
+		_, err := db.Exec(ctx, query)
+		return err
+	}
+}
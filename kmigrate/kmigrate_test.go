@@ -0,0 +1,193 @@
+package kmigrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/vingarcia/ksql"
+	"github.com/vingarcia/ksql/adapters/ksqlite3"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.up.sql": {
+			Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`),
+		},
+		"migrations/0001_create_users.down.sql": {
+			Data: []byte(`DROP TABLE users`),
+		},
+		"migrations/0002_add_age.up.sql": {
+			Data: []byte(`ALTER TABLE users ADD COLUMN age INTEGER; CREATE INDEX users_age ON users (age)`),
+		},
+		"migrations/0002_add_age.down.sql": {
+			Data: []byte(`DROP INDEX users_age; ALTER TABLE users DROP COLUMN age`),
+		},
+		"migrations/README.md": {
+			Data: []byte(`ignored`),
+		},
+	}
+
+	t.Run("should apply and revert the migrations", func(t *testing.T) {
+		db := newTestDB(t)
+
+		err := Migrate(ctx, db, fsys)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		_, err = db.Exec(ctx, `INSERT INTO users (name, age) VALUES ('Ana', 22)`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Running it again should do nothing:
+		err = Migrate(ctx, db, fsys)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		migrator, err := New(db, fsys, Config{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(statuses) != 2 || statuses[0].Name != "create_users" || statuses[1].AppliedAt == nil {
+			t.Fatalf("unexpected statuses: %+v", statuses)
+		}
+
+		err = migrator.Down(ctx, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		_, err = db.Exec(ctx, `INSERT INTO users (name, age) VALUES ('Bia', 23)`)
+		if err == nil || !strings.Contains(err.Error(), "age") {
+			t.Fatalf("expected an error about the age column, but got: %v", err)
+		}
+
+		statuses, err = migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+			t.Fatalf("unexpected statuses: %+v", statuses)
+		}
+	})
+
+	t.Run("should run the Go migrations along with the SQL files", func(t *testing.T) {
+		db := newTestDB(t)
+
+		migrator, err := New(db, fsys, Config{
+			TableName: "custom_migrations",
+			Migrations: []Migration{{
+				Version: 3,
+				Name:    "seed_users",
+				Up: func(ctx context.Context, db ksql.Provider) error {
+					_, err := db.Exec(ctx, `INSERT INTO users (name, age) VALUES ('Ana', 22)`)
+					return err
+				},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		err = migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var count int
+		err = db.QueryOne(ctx, &count, `SELECT count(*) FROM users`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if count != 1 {
+			t.Fatalf("expected 1 user, but got %d", count)
+		}
+
+		err = db.QueryOne(ctx, &count, `SELECT count(*) FROM custom_migrations`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if count != 3 {
+			t.Fatalf("expected 3 applied migrations, but got %d", count)
+		}
+
+		err = migrator.Down(ctx, 1)
+		if err == nil || !strings.Contains(err.Error(), "no Down function") {
+			t.Fatalf("expected an error about the missing Down function, but got: %v", err)
+		}
+	})
+
+	t.Run("should rollback all the migrations if one of them fails", func(t *testing.T) {
+		db := newTestDB(t)
+
+		err := Migrate(ctx, db, fstest.MapFS{
+			"1_create_users.up.sql": {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY)`)},
+			"2_invalid.up.sql":      {Data: []byte(`not valid sql`)},
+		})
+		if err == nil || !strings.Contains(err.Error(), "migration 2 (invalid)") {
+			t.Fatalf("expected an error about migration 2, but got: %v", err)
+		}
+
+		var count int
+		err = db.QueryOne(ctx, &count, `SELECT count(*) FROM schema_migrations`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if count != 0 {
+			t.Fatalf("expected no applied migrations, but got %d", count)
+		}
+	})
+
+	t.Run("should report invalid migration files", func(t *testing.T) {
+		db := newTestDB(t)
+
+		_, err := New(db, fstest.MapFS{
+			"1_create_users.down.sql": {Data: []byte(`DROP TABLE users`)},
+		}, Config{})
+		if err == nil || !strings.Contains(err.Error(), "missing the up file") {
+			t.Fatalf("expected an error about the missing up file, but got: %v", err)
+		}
+
+		_, err = New(db, fstest.MapFS{
+			"1_create_users.up.sql": {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY)`)},
+			"1_create_posts.up.sql": {Data: []byte(`CREATE TABLE posts (id INTEGER PRIMARY KEY)`)},
+		}, Config{})
+		if err == nil || !strings.Contains(err.Error(), "more than one migration with version 1") {
+			t.Fatalf("expected an error about the duplicated version, but got: %v", err)
+		}
+	})
+}
+
+func newTestDB(t *testing.T) ksql.DB {
+	dir, err := ioutil.TempDir("", "kmigrate")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	db, err := ksqlite3.New(context.Background(), filepath.Join(dir, "kmigrate.db"), ksql.Config{})
+	if err != nil {
+		t.Fatalf("unable to open the database: %s", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
@@ -0,0 +1,93 @@
+// Package kprometheus contains a ksql.MetricsHook that exports
+// the latency and the errors of the KSQL operations to Prometheus.
+package kprometheus
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vingarcia/ksql"
+)
+
+// Options contains the optional configurations of the Collector
+type Options struct {
+	// Namespace is prepended to the metric names, e.g. "myapp"
+	// produces the metric "myapp_ksql_operation_duration_seconds"
+	Namespace string
+
+	// Buckets are the buckets of the duration histogram,
+	// it defaults to prometheus.DefBuckets
+	Buckets []float64
+
+	// ConstLabels are added to all the metrics, which is useful
+	// for telling apart the metrics of different databases
+	ConstLabels prometheus.Labels
+}
+
+// Collector implements both the ksql.MetricsHook and the
+// prometheus.Collector interfaces, so it should be registered
+// on a prometheus.Registerer and set on the ksql.Config, e.g.:
+//
+//	collector := kprometheus.NewCollector(kprometheus.Options{})
+//	prometheus.MustRegister(collector)
+//
+//	db, err := kpgx.New(ctx, connStr, ksql.Config{
+//		MetricsHook: collector,
+//	})
+type Collector struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+var _ ksql.MetricsHook = &Collector{}
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector instantiates a new Collector
+func NewCollector(opts Options) *Collector {
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	return &Collector{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   "ksql",
+			Name:        "operation_duration_seconds",
+			Help:        "The duration of the KSQL operations.",
+			Buckets:     opts.Buckets,
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "table"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   "ksql",
+			Name:        "operation_errors_total",
+			Help:        "The number of KSQL operations that returned an error.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "table", "error_class"}),
+	}
+}
+
+// OnQueryStart implements the ksql.MetricsHook interface
+func (c *Collector) OnQueryStart(ctx context.Context, op ksql.Operation) context.Context {
+	return ctx
+}
+
+// OnQueryEnd implements the ksql.MetricsHook interface
+func (c *Collector) OnQueryEnd(ctx context.Context, op ksql.Operation, metrics ksql.QueryMetrics) {
+	c.duration.WithLabelValues(op.Method, op.Table).Observe(metrics.Duration.Seconds())
+	if metrics.Err != nil {
+		c.errors.WithLabelValues(op.Method, op.Table, metrics.ErrorClass).Inc()
+	}
+}
+
+// Describe implements the prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+}
@@ -0,0 +1,42 @@
+package kprometheus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vingarcia/ksql"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector(Options{Namespace: "fake"})
+
+	ctx := context.Background()
+	op := ksql.Operation{Method: "Insert", Table: "users"}
+
+	collector.OnQueryEnd(collector.OnQueryStart(ctx, op), op, ksql.QueryMetrics{
+		Duration: 10 * time.Millisecond,
+	})
+
+	notFoundErr := ksql.NotFoundError{Table: "users"}
+	collector.OnQueryEnd(collector.OnQueryStart(ctx, op), op, ksql.QueryMetrics{
+		Duration:   20 * time.Millisecond,
+		Err:        notFoundErr,
+		ErrorClass: ksql.ErrorClass(notFoundErr),
+	})
+
+	if count := testutil.CollectAndCount(collector, "fake_ksql_operation_duration_seconds"); count != 1 {
+		t.Fatalf("expected 1 histogram, but got %d", count)
+	}
+
+	expected := `
+# HELP fake_ksql_operation_errors_total The number of KSQL operations that returned an error.
+# TYPE fake_ksql_operation_errors_total counter
+fake_ksql_operation_errors_total{error_class="not_found",operation="Insert",table="users"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "fake_ksql_operation_errors_total"); err != nil {
+		t.Fatalf("unexpected metrics: %s", err)
+	}
+}
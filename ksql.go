@@ -3,16 +3,17 @@ package ksql
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
 	"fmt"
 	"io"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/pkg/errors"
 	"github.com/vingarcia/ksql/internal/structs"
-	"github.com/vingarcia/ksql/ksqltest"
 )
 
 var selectQueryCache = initializeQueryCache()
@@ -26,6 +27,13 @@ func initializeQueryCache() map[string]*sync.Map {
 	return cache
 }
 
+// selectQueryKey is the key of the selectQueryCache, the query
+// also depends on the Naming used for building the StructInfo
+type selectQueryKey struct {
+	structType reflect.Type
+	naming     *structs.Naming
+}
+
 // DB represents the KSQL client responsible for
 // interfacing with the "database/sql" package implementing
 // the KSQL interface `ksql.Provider`.
@@ -33,6 +41,53 @@ type DB struct {
 	driver  string
 	dialect Dialect
 	db      DBAdapter
+
+	// identity is shared by all the copies of the DB, including the ones
+	// bound to its transactions, and is used for making sure the
+	// transactions injected on the context are only used by this DB
+	identity *dbIdentity
+
+	// replicas is only set for the instances
+	// created with `ksql.NewWithReplicas()`
+	replicas *replicaSet
+
+	defaultTimeout time.Duration
+
+	// breaker is only set if the circuit breaker is enabled
+	breaker *circuitBreaker
+
+	// txHooks is only set for the instances bound to a transaction
+	txHooks *txHooks
+
+	// savepoints is only set for the instances created by
+	// `ksql.RunInRollbackTx()`, it counts the savepoints used
+	// for running the nested transactions
+	savepoints *int32
+
+	// middlewares are registered with the `DB.With()` method
+	middlewares []Middleware
+
+	// validator is set with the `ksql.Config.Validator` attribute
+	validator func(ctx context.Context, record interface{}) error
+
+	// encryptor is set with the `ksql.Config.Encryptor` attribute
+	encryptor Encryptor
+
+	// timeLocation is set with the `ksql.Config.TimeLocation` attribute
+	timeLocation *time.Location
+
+	// rebindPlaceholders is set with the `ksql.Config.RebindPlaceholders` attribute
+	rebindPlaceholders bool
+
+	// cache is only set if the `ksql.Config.Cache` is enabled
+	cache *queryCache
+
+	// flights is only set if the `ksql.Config.SingleFlight` is enabled
+	flights *flightGroup
+
+	// naming is only set if the `ksql.Config.MapUntaggedFields`
+	// is enabled, otherwise only the tagged attributes are mapped
+	naming *structs.Naming
 }
 
 // DBAdapter is minimalistic interface to decouple our implementation
@@ -51,6 +106,17 @@ type TxBeginner interface {
 	BeginTx(ctx context.Context) (Tx, error)
 }
 
+// TxRetrier can be optionally implemented by the DBAdapter for databases
+// that require the client to retry the transactions that failed due to
+// conflicts with concurrent transactions, e.g. CockroachDB.
+//
+// RetryTx is called after each failed attempt, starting with attempt 1,
+// and should return true if the transaction should run again.
+// It may also block for a while for implementing a backoff.
+type TxRetrier interface {
+	RetryTx(ctx context.Context, attempt int, err error) bool
+}
+
 // Result stores information about the result of an Exec query
 type Result interface {
 	LastInsertId() (int64, error)
@@ -80,8 +146,170 @@ type Config struct {
 	// MaxOpenCons defaults to 1 if not set
 	MaxOpenConns int
 
+	// MaxIdleConns is the maximum number of idle connections kept
+	// by the pool, if not set the default of each driver is used.
+	//
+	// It is ignored by the pgx adapters, since pgxpool has no equivalent option
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum duration a connection is reused,
+	// if not set the connections are reused according to the driver defaults
+	ConnMaxLifetime time.Duration
+
+	// ConnMaxIdleTime is the maximum duration a connection can stay idle,
+	// if not set the connections are closed according to the driver defaults
+	ConnMaxIdleTime time.Duration
+
 	// Used by some adapters (such as kpgx) where nil disables TLS
 	TLSConfig *tls.Config
+
+	// DefaultQueryTimeout is the maximum duration of each operation
+	// whose context has no deadline, zero means no timeout.
+	//
+	// It can be overridden on each call with `ksql.WithTimeout(ctx, d)`
+	DefaultQueryTimeout time.Duration
+
+	// Used by some adapters (such as kpgx) for setting the
+	// `statement_timeout` of the Postgres sessions, zero means no timeout
+	StatementTimeout time.Duration
+
+	// PreparedStatements runs all the statements as prepared statements,
+	// saving the parsing and planning of the queries that run often.
+	//
+	// On the adapters based on database/sql the statements are kept on
+	// a cache of the 512 most recently used queries, and on kpgx and kpgx5
+	// the statement cache of pgx is used instead. The queries are cached
+	// by their exact text, so the params should always be passed as
+	// arguments instead of being formatted into the query, and the
+	// QueryComments should not contain values that change on each
+	// request, e.g. trace IDs.
+	PreparedStatements bool
+
+	// RetryPolicy configures the retries of the operations
+	// that fail with transient errors, they are disabled by default
+	RetryPolicy RetryPolicy
+
+	// CircuitBreaker configures the circuit breaker that
+	// rejects all operations after too many consecutive
+	// failures, it is disabled by default
+	CircuitBreaker CircuitBreakerConfig
+
+	// MetricsHook is called before and after each operation,
+	// see `ksql.MetricsHook` for more details
+	MetricsHook MetricsHook
+
+	// Logger receives a log entry for each statement sent
+	// to the database, see `ksql.Logger` for more details
+	Logger Logger
+
+	// RedactLogParams replaces the params of the statements
+	// by "[REDACTED]" on the logs sent to the Logger
+	RedactLogParams bool
+
+	// SlowQueryThreshold is the duration above which the statements
+	// are logged as warnings by the Logger and reported to the
+	// OnSlowQuery callback, zero disables it
+	SlowQueryThreshold time.Duration
+
+	// OnSlowQuery is called for each statement slower
+	// than the SlowQueryThreshold, it is optional
+	OnSlowQuery func(ctx context.Context, q SlowQuery)
+
+	// ExplainSlowQueries runs EXPLAIN on the successful slow statements
+	// and attaches the plan to the logs and to the `ksql.SlowQuery`.
+	//
+	// Since it sends an extra statement for each slow statement it
+	// is meant for development and performance tests only.
+	ExplainSlowQueries bool
+
+	// QueryComments are appended to all the statements following the
+	// sqlcommenter format, see `ksql.WithQueryComments()` for more details
+	QueryComments map[string]string
+
+	// Validator is called with the records passed to Insert, Upsert, Patch
+	// and Update before building the query, if it returns an error the
+	// operation is aborted and the error is returned wrapped in a
+	// ksql.ErrValidation.
+	//
+	// The `kvalidator` module contains an implementation
+	// based on the `go-playground/validator` struct tags.
+	Validator func(ctx context.Context, record interface{}) error
+
+	// Encryptor is used by the `encrypted` modifier, see
+	// the `ksql.Encryptor` interface for more details
+	Encryptor Encryptor
+
+	// TimeLocation is the location used for all the time.Time and *time.Time
+	// attributes, e.g. `time.UTC`, they are converted to it before they are
+	// written and after they are read, so the same data produces the same
+	// times on all the drivers. If not set the times keep the zone
+	// returned by each driver.
+	//
+	// When it is set the times saved as text or as unix timestamps are also
+	// decoded, e.g. on SQLite or on MySQL without the `parseTime=true`
+	// option, and the texts without zones are interpreted as UTC.
+	//
+	// The attributes of DATE columns should use the `date` modifier, e.g.
+	// `ksql:"birthday,date"`, so they are read as the midnight in UTC of
+	// the saved date instead of being converted to another day.
+	TimeLocation *time.Location
+
+	// TenantSessionVariable is the name of a Postgres setting, e.g.
+	// "app.tenant_id", that is set to the tenant saved on the context by
+	// `ksql.WithTenant()` before each statement, so that it can be read by
+	// Row Level Security policies with `current_setting('app.tenant_id')`.
+	//
+	// The setting is set with `set_config(name, value, true)`, which works
+	// like `SET LOCAL`, so the statements running outside transactions run
+	// on a transaction of their own, and the transactions use the tenant of
+	// the context received by the Transaction method. The statements whose
+	// context has no tenant run unchanged, and BulkInsert uses INSERT
+	// statements instead of the `COPY FROM` protocol.
+	//
+	// It is only supported on Postgres, e.g. on the kpgx and kpgx5 adapters.
+	TenantSessionVariable string
+
+	// Cache enables the caching of the results of the Query and QueryOne
+	// methods, see `ksql.CacheConfig` for more details
+	Cache CacheConfig
+
+	// SingleFlight makes the identical calls to Query and QueryOne that
+	// run concurrently, i.e. with the same query, params, type of
+	// destination and tenant, share a single round trip to the database.
+	//
+	// The result is copied to the destination of each caller with the
+	// encoding/gob package, so the results that can't be encoded are not
+	// shared. The queries running inside transactions or using
+	// `ksql.WithLock()` are never shared. When the cache is also enabled
+	// only the queries missing the cache are deduplicated.
+	SingleFlight bool
+
+	// MapUntaggedFields maps the exported attributes without the `ksql`
+	// tag to the columns named by the NamingStrategy, so that large
+	// structs don't need to tag every attribute.
+	//
+	// The tags still take precedence, so they can be used for renaming
+	// columns or adding modifiers, and the attributes tagged with
+	// `ksql:"-"` are ignored. Embedded structs without the tag are
+	// flattened, and the attributes tagged with `tablename` still
+	// represent the tables of a JOIN.
+	MapUntaggedFields bool
+
+	// NamingStrategy converts the names of the untagged attributes to
+	// column names when MapUntaggedFields is enabled, it defaults to
+	// ksql.SnakeCase, e.g. `UserID` is mapped to `user_id`.
+	NamingStrategy func(fieldName string) string
+
+	// RebindPlaceholders allows the queries of all the databases to use `?`
+	// as the placeholder, converting them to the placeholders of the dialect
+	// with `ksql.Rebind()` before running the queries, so that the same
+	// query works on Postgres in production and on SQLite on the tests.
+	//
+	// The queries are rebound before they reach the middlewares, and the
+	// question marks inside quotes and comments are kept, but note that
+	// the `?` operators of the Postgres JSONB type can't be used
+	// with this option, e.g. `jsonb_exists()` should be used instead.
+	RebindPlaceholders bool
 }
 
 // SetDefaultValues should be called by all adapters
@@ -104,12 +332,109 @@ func NewWithAdapter(
 	}
 
 	return DB{
-		dialect: dialect,
-		driver:  dialectName,
-		db:      db,
+		dialect:  dialect,
+		driver:   dialectName,
+		db:       db,
+		identity: &dbIdentity{},
 	}, nil
 }
 
+// NewWithConfig works like NewWithAdapter but also applies the
+// options of the ksql.Config that are handled by KSQL itself
+// instead of by the adapters, i.e. the DefaultQueryTimeout,
+// the RetryPolicy, the CircuitBreaker, the MetricsHook, the Logger,
+// the QueryComments, the Validator, the Encryptor, the TimeLocation, the
+// PreparedStatements, the TenantSessionVariable, the Cache,
+// the SingleFlight, the MapUntaggedFields and the RebindPlaceholders options.
+func NewWithConfig(
+	db DBAdapter,
+	dialectName string,
+	config Config,
+) (DB, error) {
+	c, err := NewWithAdapter(db, dialectName)
+	if err != nil {
+		return DB{}, err
+	}
+
+	c.defaultTimeout = config.DefaultQueryTimeout
+	c.validator = config.Validator
+	c.encryptor = config.Encryptor
+	c.timeLocation = config.TimeLocation
+	c.rebindPlaceholders = config.RebindPlaceholders
+
+	if config.Cache.Store != nil {
+		c.cache = newQueryCache(config.Cache)
+	}
+
+	if config.SingleFlight {
+		c.flights = newFlightGroup()
+	}
+
+	if config.MapUntaggedFields {
+		namingStrategy := config.NamingStrategy
+		if namingStrategy == nil {
+			namingStrategy = SnakeCase
+		}
+		c.naming = structs.NewNaming(namingStrategy)
+	}
+
+	// The statements are prepared after the comments
+	// are added since they are part of the query:
+	if config.PreparedStatements {
+		c.db = newPreparedStatementsAdapter(c.db)
+	}
+
+	if config.TenantSessionVariable != "" {
+		if dialectName != "postgres" {
+			return DB{}, fmt.Errorf("ksql: the TenantSessionVariable option is only supported on Postgres, but got driver `%s`", dialectName)
+		}
+		c.db = newTenantSessionAdapter(c.db, config.TenantSessionVariable)
+	}
+
+	// The comments are always enabled since
+	// they can also be set on the context:
+	c.db = commentAdapter{
+		DBAdapter: c.db,
+		commenter: commenter{globalComments: config.QueryComments},
+	}
+
+	// The logger is the innermost wrapper so that
+	// each retry attempt is logged separately:
+	if config.Logger != nil || (config.SlowQueryThreshold > 0 && config.OnSlowQuery != nil) {
+		c.db = loggingAdapter{
+			DBAdapter: c.db,
+			logger: statementLogger{
+				logger:        config.Logger,
+				redactParams:  config.RedactLogParams,
+				slowThreshold: config.SlowQueryThreshold,
+				onSlowQuery:   config.OnSlowQuery,
+				explainSlow:   config.ExplainSlowQueries,
+				driverName:    dialectName,
+			},
+		}
+	}
+
+	if config.RetryPolicy.MaxAttempts > 1 {
+		c.db = newRetryAdapter(c.db, dialectName, config.RetryPolicy)
+	}
+
+	// The circuit breaker wraps the retries so
+	// that each operation counts as a single failure:
+	if config.CircuitBreaker.FailureThreshold > 0 {
+		c.breaker = newCircuitBreaker(config.CircuitBreaker)
+		c.db = circuitBreakerAdapter{
+			DBAdapter: c.db,
+			breaker:   c.breaker,
+		}
+	}
+
+	if config.MetricsHook != nil {
+		c = c.With(NewMetricsMiddleware(config.MetricsHook))
+	}
+
+	return c, nil
+}
+
 // Query queries several rows from the database,
 // the input should be a slice of structs (or *struct) passed
 // by reference and it will be filled with all the results.
@@ -117,18 +442,64 @@ func NewWithAdapter(
 // Note: it is very important to make sure the query will
 // return a small known number of results, otherwise you risk
 // of overloading the available memory.
+//
+// Slice params used as the only element of an `IN (...)` clause are
+// automatically expanded into one param for each element of the slice, e.g.:
+//
+//	err := db.Query(ctx, &users, "FROM users WHERE id IN (?)", []int{1, 2, 3})
+//
+// This also applies to the QueryOne, QueryChunks, QueryIter and Exec methods.
+//
+// The input can also be a slice of scalar values, e.g. []int or []string,
+// if the query returns a single column, in this case the SELECT part of
+// the query can't be omitted:
+//
+//	var ids []int
+//	err := db.Query(ctx, &ids, "SELECT id FROM users WHERE age > ?", 18)
 func (c DB) Query(
 	ctx context.Context,
 	records interface{},
 	query string,
 	params ...interface{},
 ) error {
+	op := Operation{Method: "Query", Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.query(ctx, records, op.Query, op.Params...)
+	})
+}
+
+func (c DB) query(
+	ctx context.Context,
+	records interface{},
+	query string,
+	params ...interface{},
+) error {
+	c = c.contextTx(ctx)
+	if c.useCache(ctx) {
+		return c.cache.read(ctx, "Query", records, query, params, func(ctx context.Context) error {
+			return c.query(ctx, records, query, params...)
+		})
+	}
+
+	if c.useSingleFlight(ctx) {
+		return c.flights.do(ctx, "Query", records, query, params, func(ctx context.Context) error {
+			return c.query(ctx, records, query, params...)
+		})
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	slicePtr := reflect.ValueOf(records)
 	slicePtrType := slicePtr.Type()
 	if slicePtrType.Kind() != reflect.Ptr {
 		return fmt.Errorf("ksql: expected to receive a pointer to slice of structs, but got: %T", records)
 	}
 	sliceType := slicePtrType.Elem()
+	if sliceType.Kind() == reflect.Slice && isScalarType(sliceType.Elem()) {
+		return c.queryScalars(ctx, slicePtr, query, params)
+	}
+
 	slice := slicePtr.Elem()
 	structType, isSliceOfPtrs, err := structs.DecodeAsSliceOfStructs(sliceType)
 	if err != nil {
@@ -142,7 +513,7 @@ func (c DB) Query(
 		slice = slice.Slice(0, 0)
 	}
 
-	info, err := structs.GetTagInfo(structType)
+	info, err := c.naming.GetTagInfo(structType)
 	if err != nil {
 		return err
 	}
@@ -161,12 +532,33 @@ func (c DB) Query(
 		query = selectPrefix + query
 	}
 
-	rows, err := c.db.QueryContext(ctx, query, params...)
+	query, err = addSoftDeleteFilter(ctx, c.dialect, query, structType, info)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = addTenantFilter(ctx, c.dialect, query, params, structType, info)
+	if err != nil {
+		return err
+	}
+
+	query, err = addLockClause(ctx, c.dialect, query)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, query, params...)
 	if err != nil {
 		return fmt.Errorf("error running query: %s", err)
 	}
 	defer rows.Close()
 
+	var scanner *rowScanner
 	for idx := 0; rows.Next(); idx++ {
 		// Allocate new slice elements
 		// only if they are not already allocated:
@@ -181,11 +573,18 @@ func (c DB) Query(
 
 		elemPtr := slice.Index(idx).Addr()
 		if isSliceOfPtrs {
-			// This is necessary since scanRows expects a *record not a **record
+			// This is necessary since the scanner expects a *record not a **record
 			elemPtr = elemPtr.Elem()
 		}
 
-		err = scanRows(c.dialect, rows, elemPtr.Interface())
+		if scanner == nil {
+			scanner, err = newRowScanner(ctx, c.dialect, rows, structType, info)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = scanner.scan(elemPtr)
 		if err != nil {
 			return err
 		}
@@ -210,13 +609,50 @@ func (c DB) Query(
 // and the query should return only one result.
 //
 // QueryOne returns a ErrRecordNotFound if
-// the query returns no results.
+// the query returns no results, use `ksql.QueryOneOrZero()`
+// for handling this case without checking the error.
+//
+// The record can also be a pointer to a scalar value, e.g. *int, or
+// to a type implementing the sql.Scanner interface, e.g. *sql.NullTime,
+// if the query returns a single column, which is useful for aggregate
+// queries:
+//
+//	var count int
+//	err := db.QueryOne(ctx, &count, "SELECT count(*) FROM users")
 func (c DB) QueryOne(
 	ctx context.Context,
 	record interface{},
 	query string,
 	params ...interface{},
 ) error {
+	op := Operation{Method: "QueryOne", Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.queryOne(ctx, record, op.Query, op.Params...)
+	})
+}
+
+func (c DB) queryOne(
+	ctx context.Context,
+	record interface{},
+	query string,
+	params ...interface{},
+) error {
+	c = c.contextTx(ctx)
+	if c.useCache(ctx) {
+		return c.cache.read(ctx, "QueryOne", record, query, params, func(ctx context.Context) error {
+			return c.queryOne(ctx, record, query, params...)
+		})
+	}
+
+	if c.useSingleFlight(ctx) {
+		return c.flights.do(ctx, "QueryOne", record, query, params, func(ctx context.Context) error {
+			return c.queryOne(ctx, record, query, params...)
+		})
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	v := reflect.ValueOf(record)
 	t := v.Type()
 	if t.Kind() != reflect.Ptr {
@@ -227,12 +663,16 @@ func (c DB) QueryOne(
 		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
 	}
 
+	if isScalarType(t.Elem()) {
+		return c.queryOneScalar(ctx, record, query, params)
+	}
+
 	tStruct := t.Elem()
 	if tStruct.Kind() != reflect.Struct {
 		return fmt.Errorf("ksql: expected to receive a pointer to struct, but got: %T", record)
 	}
 
-	info, err := structs.GetTagInfo(tStruct)
+	info, err := c.naming.GetTagInfo(tStruct)
 	if err != nil {
 		return err
 	}
@@ -251,7 +691,27 @@ func (c DB) QueryOne(
 		query = selectPrefix + query
 	}
 
-	rows, err := c.db.QueryContext(ctx, query, params...)
+	query, err = addSoftDeleteFilter(ctx, c.dialect, query, tStruct, info)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = addTenantFilter(ctx, c.dialect, query, params, tStruct, info)
+	if err != nil {
+		return err
+	}
+
+	query, err = addLockClause(ctx, c.dialect, query)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, query, params...)
 	if err != nil {
 		return fmt.Errorf("error running query: %s", err)
 	}
@@ -261,10 +721,10 @@ func (c DB) QueryOne(
 		if rows.Err() != nil {
 			return rows.Err()
 		}
-		return ErrRecordNotFound
+		return notFoundError(ctx, NotFoundError{Query: query})
 	}
 
-	err = scanRowsFromType(c.dialect, rows, record, t, v)
+	err = scanRowsFromType(ctx, c.dialect, c.naming, rows, record, t, v)
 	if err != nil {
 		return err
 	}
@@ -276,22 +736,42 @@ func (c DB) QueryOne(
 // more results than would normally fit on memory,
 // for others cases the Query and QueryOne functions are indicated.
 //
-// The ChunkParser argument has 4 attributes:
+// The ChunkParser argument has 5 attributes:
 // (1) The Query;
 // (2) The query args;
 // (3) The chunk size;
 // (4) A callback function called ForEachChunk, that will be called
-// to process each chunk loaded from the database.
+// to process each chunk loaded from the database;
+// (5) Optionally, the number of Workers that will call
+// the ForEachChunk callback concurrently.
 //
 // Note that the signature of the ForEachChunk callback can be
 // any function that receives a slice of structs or a slice of
 // pointers to struct as its only argument and that reflection
 // will be used to instantiate this argument and to fill it
 // with the database rows.
+//
+// The same chunks are reused for all the rows, so the records
+// received by the callback are only valid until it returns.
 func (c DB) QueryChunks(
 	ctx context.Context,
 	parser ChunkParser,
 ) error {
+	op := Operation{Method: "QueryChunks", Query: parser.Query, Params: parser.Params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		parser.Query, parser.Params = op.Query, op.Params
+		return c.queryChunks(ctx, parser)
+	})
+}
+
+func (c DB) queryChunks(
+	ctx context.Context,
+	parser ChunkParser,
+) error {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	fnValue := reflect.ValueOf(parser.ForEachChunk)
 	chunkType, err := structs.ParseInputFunc(parser.ForEachChunk)
 	if err != nil {
@@ -305,7 +785,7 @@ func (c DB) QueryChunks(
 		return err
 	}
 
-	info, err := structs.GetTagInfo(structType)
+	info, err := c.naming.GetTagInfo(structType)
 	if err != nil {
 		return err
 	}
@@ -324,12 +804,50 @@ func (c DB) QueryChunks(
 		parser.Query = selectPrefix + parser.Query
 	}
 
-	rows, err := c.db.QueryContext(ctx, parser.Query, parser.Params...)
+	if parser.Workers < 0 {
+		return fmt.Errorf("ksql: the ChunkParser.Workers attribute cannot be negative, but got: %d", parser.Workers)
+	}
+
+	var workers *chunkWorkers
+	if parser.Workers > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		workers = startChunkWorkers(parser.Workers, fnValue, cancel, chunkType, parser.ChunkSize)
+		// Make sure no goroutines are left behind on early returns:
+		defer workers.wait()
+	}
+
+	parser.Query, err = addSoftDeleteFilter(ctx, c.dialect, parser.Query, structType, info)
+	if err != nil {
+		return err
+	}
+
+	parser.Query, parser.Params, err = addTenantFilter(ctx, c.dialect, parser.Query, parser.Params, structType, info)
+	if err != nil {
+		return err
+	}
+
+	parser.Query, err = addLockClause(ctx, c.dialect, parser.Query)
+	if err != nil {
+		return err
+	}
+
+	parser.Query, parser.Params, err = expandSliceParams(c.dialect, parser.Query, parser.Params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, parser.Query, parser.Params...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
+	zeroValue := reflect.Zero(structType)
+
+	var scanner *rowScanner
 	var idx = 0
 	for rows.Next() {
 		// Allocate new slice elements
@@ -341,10 +859,33 @@ func (c DB) QueryChunks(
 				elemValue = elemValue.Elem()
 			}
 			chunk = reflect.Append(chunk, elemValue)
+		} else if isSliceOfPtrs {
+			// Reset the records of the previous chunks:
+			chunk.Index(idx).Elem().Set(zeroValue)
+		} else {
+			chunk.Index(idx).Set(zeroValue)
+		}
+
+		if scanner == nil {
+			scanner, err = newRowScanner(ctx, c.dialect, rows, structType, info)
+			if err != nil {
+				if workers != nil {
+					return workers.waitOr(err)
+				}
+				return err
+			}
+		}
+
+		elemPtr := chunk.Index(idx).Addr()
+		if isSliceOfPtrs {
+			elemPtr = elemPtr.Elem()
 		}
 
-		err = scanRows(c.dialect, rows, chunk.Index(idx).Addr().Interface())
+		err = scanner.scan(elemPtr)
 		if err != nil {
+			if workers != nil {
+				return workers.waitOr(err)
+			}
 			return err
 		}
 
@@ -354,6 +895,17 @@ func (c DB) QueryChunks(
 		}
 
 		idx = 0
+		if workers != nil {
+			if !workers.send(chunk) {
+				break
+			}
+
+			// The chunk now belongs to one of the workers,
+			// so we need another one for the next rows:
+			chunk = workers.newChunk()
+			continue
+		}
+
 		err, _ = fnValue.Call([]reflect.Value{chunk})[0].Interface().(error)
 		if err != nil {
 			if err == ErrAbortIteration {
@@ -363,6 +915,10 @@ func (c DB) QueryChunks(
 		}
 	}
 
+	if workers != nil {
+		return finishChunkWorkers(workers, rows, chunk, idx)
+	}
+
 	if err := rows.Close(); err != nil {
 		return err
 	}
@@ -393,11 +949,50 @@ func (c DB) QueryChunks(
 //
 // If the original instances have been passed by reference
 // the ID is automatically updated after insertion is completed.
+//
+// Attributes with the `timeNowUTC` or `timeNowUTCSkipOnUpdate` modifiers,
+// e.g. `ksql:"created_at,timeNowUTC"`, are set to the current time in UTC
+// before the insertion, both on the database and on the record.
+//
+// Attributes with the `default=<value>` modifier, e.g.
+// `ksql:"status,default=pending"`, are set to the default
+// value if they are empty, and if the record implements the
+// ksql.Defaulter interface its SetDefaults method is called.
+//
+// Attributes with the `readOnly` option, e.g. `ksql:"search,readOnly"`,
+// are never inserted nor updated, only read by the queries, which is
+// useful for generated columns and columns maintained by the database.
+//
+// If the record implements the ksql.BeforeInsertHook or the
+// ksql.AfterInsertHook interfaces they are called before and
+// after the insertion respectively.
 func (c DB) Insert(
 	ctx context.Context,
 	table Table,
 	record interface{},
 ) error {
+	op := Operation{Method: "Insert", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.insert(ctx, table, record)
+	})
+}
+
+func (c DB) insert(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	if table.auditTable != "" {
+		_, err := c.auditWrite(ctx, table, AuditInsert, record, func(db DB, table Table) (Result, error) {
+			return nil, db.insert(ctx, table, record)
+		})
+		return err
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	v := reflect.ValueOf(record)
 	t := v.Type()
 	if err := assertStructPtr(t); err != nil {
@@ -415,12 +1010,34 @@ func (c DB) Insert(
 		return fmt.Errorf("can't insert in ksql.Table: %s", err)
 	}
 
-	info, err := structs.GetTagInfo(t.Elem())
+	info, err := c.naming.GetTagInfo(t.Elem())
 	if err != nil {
 		return err
 	}
 
-	query, params, scanValues, err := buildInsertQuery(c.dialect, table, t, v, info, record)
+	if err := table.generateID(ctx, v, info); err != nil {
+		return err
+	}
+
+	if err := setTenantOnInsert(ctx, v.Elem(), info); err != nil {
+		return err
+	}
+
+	if err := setDefaultValues(ctx, v.Elem(), info, record); err != nil {
+		return err
+	}
+
+	if err := callBeforeInsert(ctx, record); err != nil {
+		return err
+	}
+
+	if err := c.validate(ctx, table, record); err != nil {
+		return err
+	}
+
+	setTimeNowOnInsert(v.Elem(), info, time.Now().UTC())
+
+	query, params, scanValues, err := buildInsertQuery(ctx, c.dialect, c.naming, table, t, v, info, record)
 	if err != nil {
 		return err
 	}
@@ -428,6 +1045,9 @@ func (c DB) Insert(
 	switch table.insertMethodFor(c.dialect) {
 	case insertWithReturning, insertWithOutput:
 		err = c.insertReturningIDs(ctx, query, params, scanValues, table.idColumns)
+	case insertWithReturningInto:
+		// The IDs are saved on the record by the `sql.Out` params:
+		err = c.insertWithNoIDRetrieval(ctx, query, params)
 	case insertWithLastInsertID:
 		err = c.insertWithLastInsertID(ctx, t, v, info, record, query, params, table.idColumns[0])
 	case insertWithNoIDRetrieval:
@@ -437,8 +1057,11 @@ func (c DB) Insert(
 		// So we don't expect the code to ever get into this default case.
 		err = fmt.Errorf("code error: unsupported driver `%s`", c.driver)
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	return callAfterInsert(ctx, record)
 }
 
 func (c DB) insertReturningIDs(
@@ -450,14 +1073,14 @@ func (c DB) insertReturningIDs(
 ) error {
 	rows, err := c.db.QueryContext(ctx, query, params...)
 	if err != nil {
-		return err
+		return translateError(c.dialect.DriverName(), err)
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
 		err := fmt.Errorf("unexpected error when retrieving the id columns from the database")
 		if rows.Err() != nil {
-			err = rows.Err()
+			err = translateError(c.dialect.DriverName(), rows.Err())
 		}
 
 		return err
@@ -465,7 +1088,7 @@ func (c DB) insertReturningIDs(
 
 	err = rows.Scan(scanValues...)
 	if err != nil {
-		return err
+		return translateError(c.dialect.DriverName(), err)
 	}
 
 	return rows.Close()
@@ -483,7 +1106,7 @@ func (c DB) insertWithLastInsertID(
 ) error {
 	result, err := c.db.ExecContext(ctx, query, params...)
 	if err != nil {
-		return err
+		return translateError(c.dialect.DriverName(), err)
 	}
 
 	id, err := result.LastInsertId()
@@ -491,10 +1114,16 @@ func (c DB) insertWithLastInsertID(
 		return err
 	}
 
+	fieldInfo := info.ByName(idName)
+	if !fieldInfo.Valid {
+		// There is no attribute for saving the ID on the record:
+		return nil
+	}
+
 	vID := reflect.ValueOf(id)
 	tID := vID.Type()
 
-	fieldAddr := v.Elem().Field(info.ByName(idName).Index).Addr()
+	fieldAddr := v.Elem().FieldByIndex(fieldInfo.Path).Addr()
 	fieldType := fieldAddr.Type().Elem()
 
 	if !tID.ConvertibleTo(fieldType) {
@@ -515,61 +1144,223 @@ func (c DB) insertWithNoIDRetrieval(
 	params []interface{},
 ) error {
 	_, err := c.db.ExecContext(ctx, query, params...)
-	return err
-}
-
-func assertStructPtr(t reflect.Type) error {
-	if t.Kind() != reflect.Ptr {
-		return fmt.Errorf("expected a Kind of Ptr but got: %s", t)
-	}
-	if t.Elem().Kind() != reflect.Struct {
-		return fmt.Errorf("expected a Kind of Ptr to Struct but got: %s", t)
-	}
-	return nil
+	return translateError(c.dialect.DriverName(), err)
 }
 
-// Delete deletes one record from the database using the ID or IDs
-// defined on the `ksql.Table` passed as second argument.
-//
-// For tables with a single ID column you can pass the record
-// to be deleted as a struct, as a map or just pass the ID itself.
+// Upsert inserts a record on the database or updates it if
+// it conflicts with an existing one.
 //
-// For tables with composite keys you must pass the record
-// as a struct or a map so that KSQL can read all the composite keys
-// from it.
-//
-// The examples below should work for both types of tables:
-//
-//     err := c.Delete(ctx, UsersTable, user)
-//
-//     err := c.Delete(ctx, UserPostsTable, map[string]interface{}{
-//         "user_id": user.ID,
-//         "post_id": post.ID,
-//     })
+// By default the conflict is detected using the ID columns of the
+// table, but other unique columns can be used instead by
+// creating the table with `ksql.NewTable().WithConflictColumns()`.
 //
-// The example below is shorter but will only work for tables with a single primary key:
-//
-//     err := c.Delete(ctx, UsersTable, user.ID)
+// On conflict all the columns present on the record, except the
+// conflict columns, are updated, and just like with Patch nil
+// pointer attributes are ignored.
 //
-func (c DB) Delete(
+// If the record was passed by reference the ID is automatically
+// updated after the operation is completed, but only for databases
+// that support the `RETURNING` or `OUTPUT` clauses, i.e. Postgres
+// and SQLServer.
+func (c DB) Upsert(
 	ctx context.Context,
 	table Table,
-	idOrRecord interface{},
+	record interface{},
 ) error {
-	if err := table.validate(); err != nil {
-		return fmt.Errorf("can't delete from ksql.Table: %s", err)
-	}
-
-	idMap, err := normalizeIDsAsMap(table.idColumns, idOrRecord)
-	if err != nil {
-		return err
-	}
+	op := Operation{Method: "Upsert", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.upsert(ctx, table, record)
+	})
+}
 
-	var query string
-	var params []interface{}
-	query, params = buildDeleteQuery(c.dialect, table, idMap)
+func (c DB) upsert(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
-	result, err := c.db.ExecContext(ctx, query, params...)
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	if err := assertStructPtr(t); err != nil {
+		return fmt.Errorf(
+			"ksql: expected record to be a pointer to struct, but got: %T",
+			record,
+		)
+	}
+
+	if v.IsNil() {
+		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+	}
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't upsert in ksql.Table: %s", err)
+	}
+
+	if err := assertNotAudited(table, "Upsert"); err != nil {
+		return err
+	}
+
+	info, err := c.naming.GetTagInfo(t.Elem())
+	if err != nil {
+		return err
+	}
+
+	if err := assertTenantInConflictColumns(ctx, table, info); err != nil {
+		return err
+	}
+
+	if err := table.generateID(ctx, v, info); err != nil {
+		return err
+	}
+
+	if err := setTenantOnInsert(ctx, v.Elem(), info); err != nil {
+		return err
+	}
+
+	if err := setDefaultValues(ctx, v.Elem(), info, record); err != nil {
+		return err
+	}
+
+	if err := callBeforeInsert(ctx, record); err != nil {
+		return err
+	}
+
+	if err := c.validate(ctx, table, record); err != nil {
+		return err
+	}
+
+	setTimeNowOnInsert(v.Elem(), info, time.Now().UTC())
+
+	query, params, scanValues, err := buildUpsertQuery(ctx, c.dialect, c.naming, table, v, info, record)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(scanValues) == 0:
+		err = c.insertWithNoIDRetrieval(ctx, query, params)
+	case c.dialect.DriverName() == "duckdb":
+		err = c.upsertReturningIDsOnDuckDB(ctx, table, info, record, query, params, scanValues)
+	default:
+		err = c.insertReturningIDs(ctx, query, params, scanValues, table.idColumns)
+	}
+	if err != nil {
+		return err
+	}
+
+	return callAfterInsert(ctx, record)
+}
+
+// upsertReturningIDsOnDuckDB works like insertReturningIDs, except that
+// DuckDB returns no rows when the `ON CONFLICT` clause updates an existing
+// row, in which case the IDs are loaded using the conflict columns.
+func (c DB) upsertReturningIDsOnDuckDB(
+	ctx context.Context,
+	table Table,
+	info structs.StructInfo,
+	record interface{},
+	query string,
+	params []interface{},
+	scanValues []interface{},
+) error {
+	rows, err := c.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return translateError(c.dialect.DriverName(), err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return translateError(c.dialect.DriverName(), rows.Err())
+		}
+		rows.Close()
+
+		recordMap, err := c.naming.StructToMap(record)
+		if err != nil {
+			return err
+		}
+
+		var conditions []string
+		var conflictParams []interface{}
+		for i, col := range table.getConflictColumns() {
+			conditions = append(conditions, escapeColumn(c.dialect, info, col)+" = "+c.dialect.Placeholder(i))
+			conflictParams = append(conflictParams, recordMap[col])
+		}
+
+		return c.insertReturningIDs(ctx, fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s",
+			strings.Join(escapeIDColumns(c.dialect, table, info, ""), ", "),
+			c.dialect.Escape(table.name),
+			strings.Join(conditions, " AND "),
+		), conflictParams, scanValues, table.idColumns)
+	}
+
+	err = rows.Scan(scanValues...)
+	if err != nil {
+		return translateError(c.dialect.DriverName(), err)
+	}
+
+	return rows.Close()
+}
+
+func assertStructPtr(t reflect.Type) error {
+	if t.Kind() != reflect.Ptr {
+		return fmt.Errorf("expected a Kind of Ptr but got: %s", t)
+	}
+	if t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a Kind of Ptr to Struct but got: %s", t)
+	}
+	return nil
+}
+
+// Delete deletes one record from the database using the ID or IDs
+// defined on the `ksql.Table` passed as second argument.
+//
+// For tables with a single ID column you can pass the record
+// to be deleted as a struct, as a map or just pass the ID itself.
+//
+// For tables with composite keys you must pass the record
+// as a struct or a map so that KSQL can read all the composite keys
+// from it.
+//
+// The examples below should work for both types of tables:
+//
+//     err := c.Delete(ctx, UsersTable, user)
+//
+//     err := c.Delete(ctx, UserPostsTable, map[string]interface{}{
+//         "user_id": user.ID,
+//         "post_id": post.ID,
+//     })
+//
+// The example below is shorter but will only work for tables with a single primary key:
+//
+//     err := c.Delete(ctx, UsersTable, user.ID)
+//
+// If the struct registered on the table with `ksql.NewTable().WithStruct()`
+// or the record itself has an attribute with the `softDelete` modifier, e.g.
+// `ksql:"deleted_at,softDelete"`, the record is not removed, instead
+// this attribute is set to the current time. To actually remove it
+// use a context created with `ksql.Unscoped(ctx)`.
+func (c DB) Delete(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+) error {
+	op := Operation{Method: "Delete", Table: table.name, Record: idOrRecord}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.delete(ctx, table, idOrRecord)
+	})
+}
+
+func (c DB) delete(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+) error {
+	result, err := c.deleteWithResult(ctx, table, idOrRecord)
 	if err != nil {
 		return err
 	}
@@ -580,13 +1371,85 @@ func (c DB) Delete(
 	}
 
 	if n == 0 {
-		return ErrRecordNotFound
+		return notFoundError(ctx, NotFoundError{Table: table.name})
 	}
 
-	return err
+	return nil
 }
 
-func normalizeIDsAsMap(idNames []string, idOrMap interface{}) (idMap map[string]interface{}, err error) {
+// DeleteWithResult works like Delete but returns the ksql.Result
+// of the operation instead of returning an error when no rows are
+// deleted, which allows the caller to check `result.RowsAffected()`
+// or to opt into an error with `ksql.ExpectRowsAffected()`.
+func (c DB) DeleteWithResult(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+) (result Result, err error) {
+	op := Operation{Method: "DeleteWithResult", Table: table.name, Record: idOrRecord}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		result, err = c.deleteWithResult(ctx, table, idOrRecord)
+		return err
+	})
+	return result, err
+}
+
+func (c DB) deleteWithResult(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+) (Result, error) {
+	if table.auditTable != "" {
+		return c.auditWrite(ctx, table, AuditDelete, idOrRecord, func(db DB, table Table) (Result, error) {
+			return db.deleteWithResult(ctx, table, idOrRecord)
+		})
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := table.validate(); err != nil {
+		return nil, fmt.Errorf("can't delete from ksql.Table: %s", err)
+	}
+
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantField, err := getTenantField(c.naming, table, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	table, idMap, err = scopeToTenant(ctx, table, tenantField, idMap)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := getRecordInfo(c.naming, table, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	var query string
+	var params []interface{}
+	if info.SoftDeleteField != nil && !isUnscoped(ctx) {
+		query, params = buildSoftDeleteQuery(c.dialect, table, info, idMap, info.SoftDeleteField.Name, time.Now().UTC(), "", "")
+	} else {
+		query, params = buildDeleteQuery(c.dialect, table, info, idMap, "", "")
+	}
+
+	result, err := c.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return nil, translateError(c.dialect.DriverName(), err)
+	}
+
+	return result, nil
+}
+
+func normalizeIDsAsMap(naming *structs.Naming, idNames []string, idOrMap interface{}) (idMap map[string]interface{}, err error) {
 	if len(idNames) == 0 {
 		return nil, fmt.Errorf("internal ksql error: missing idNames")
 	}
@@ -602,7 +1465,7 @@ func normalizeIDsAsMap(idNames []string, idOrMap interface{}) (idMap map[string]
 
 	switch t.Kind() {
 	case reflect.Struct:
-		idMap, err = ksqltest.StructToMap(idOrMap)
+		idMap, err = naming.StructToMap(idOrMap)
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not get ID(s) from input record")
 		}
@@ -613,6 +1476,13 @@ func normalizeIDsAsMap(idNames []string, idOrMap interface{}) (idMap map[string]
 			return nil, fmt.Errorf("expected map[string]interface{} but got %T", idOrMap)
 		}
 	default:
+		if len(idNames) > 1 {
+			return nil, fmt.Errorf(
+				"ksql: tables with composite keys %v require the IDs to be passed as a struct or a map, but got: %T",
+				idNames, idOrMap,
+			)
+		}
+
 		idMap = map[string]interface{}{
 			idNames[0]: idOrMap,
 		}
@@ -638,35 +1508,273 @@ func (c DB) Update(
 //
 // Partial updates will ignore any nil pointer attributes from the struct, updating only
 // the non nil pointers and non pointer attributes.
+//
+// So non pointer attributes are always updated, even when they have their zero
+// value, unless they are declared with the `omitempty` option, e.g.
+// `ksql:"age,omitempty"`, in which case their zero values are ignored just
+// like nil pointers, and a pointer to the zero value should be used for
+// setting the column to zero. The same applies for the Insert method.
+//
+// If the struct has an attribute with the `optimisticLock` modifier, e.g.
+// `ksql:"version,optimisticLock"`, the record is only updated if its version
+// matches the one saved on the database, otherwise ksql.ErrVersionConflict
+// is returned. On success the version is incremented on the database and,
+// if the record was passed by reference, on the record as well.
+//
+// Attributes with the `timeNowUTC` modifier are always set to the current
+// time in UTC, and the ones with the `timeNowUTCSkipOnUpdate` modifier
+// are never updated.
+//
+// If the record implements the ksql.BeforeUpdateHook or the
+// ksql.AfterUpdateHook interfaces they are called before and
+// after the update respectively.
 func (c DB) Patch(
 	ctx context.Context,
 	table Table,
 	record interface{},
 ) error {
+	op := Operation{Method: "Patch", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.patch(ctx, table, record)
+	})
+}
+
+func (c DB) patch(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	result, err := c.patchWithResult(ctx, table, record)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf(
+			"unexpected error: unable to fetch how many rows were affected by the update: %s",
+			err,
+		)
+	}
+	if n < 1 {
+		return notFoundError(ctx, NotFoundError{Table: table.name})
+	}
+
+	return nil
+}
+
+// PatchWithResult works like Patch but returns the ksql.Result
+// of the operation instead of returning an error when no rows are
+// updated, which allows the caller to check `result.RowsAffected()`
+// or to opt into an error with `ksql.ExpectRowsAffected()`.
+//
+// If the record has an attribute with the `optimisticLock` modifier
+// and its version doesn't match the database ksql.ErrVersionConflict
+// is still returned.
+func (c DB) PatchWithResult(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) (result Result, err error) {
+	op := Operation{Method: "PatchWithResult", Table: table.name, Record: record}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		result, err = c.patchWithResult(ctx, table, record)
+		return err
+	})
+	return result, err
+}
+
+func (c DB) patchWithResult(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) (Result, error) {
+	if table.auditTable != "" {
+		return c.auditWrite(ctx, table, AuditUpdate, record, func(db DB, table Table) (Result, error) {
+			return db.patchWithResult(ctx, table, record)
+		})
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	v := reflect.ValueOf(record)
 	t := v.Type()
 	tStruct := t
 	if t.Kind() == reflect.Ptr {
 		if v.IsNil() {
-			return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+			return nil, fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
 		}
 		tStruct = t.Elem()
 	}
-	info, err := structs.GetTagInfo(tStruct)
+
+	if err := table.validate(); err != nil {
+		return nil, fmt.Errorf("can't update ksql.Table: %s", err)
+	}
+
+	info, err := c.naming.GetTagInfo(tStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := callBeforeUpdate(ctx, record); err != nil {
+		return nil, err
+	}
+
+	if err := c.validate(ctx, table, record); err != nil {
+		return nil, err
+	}
+
+	recordMap, err := c.naming.StructToMap(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if table.fields != nil {
+		recordMap, err = applyFieldMask(table, info, record, recordMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scopedTable, recordMap, err := scopeToTenant(ctx, table, info.TenantField, recordMap)
+	if err != nil {
+		return nil, err
+	}
+
+	query, params, err := buildUpdateQueryFromMap(ctx, c.dialect, table.name, info, recordMap, "", "", scopedTable.idColumns...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return nil, translateError(c.dialect.DriverName(), err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unexpected error: unable to fetch how many rows were affected by the update: %s",
+			err,
+		)
+	}
+	if n < 1 {
+		if info.OptimisticLockField != nil {
+			err := c.checkVersionConflict(ctx, table, record)
+			if !IsNotFound(err) {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		incrementVersion(v.Elem(), info.OptimisticLockField)
+	}
+
+	return result, callAfterUpdate(ctx, record)
+}
+
+// PatchMap updates only the columns present on the `changes` map
+// of the record identified by `idOrRecord` on the database.
+//
+// Just like on the Delete method the `idOrRecord` argument can be the
+// ID itself, or a struct or map containing all the ID columns, e.g.:
+//
+//     err := c.PatchMap(ctx, UsersTable, user.ID, map[string]interface{}{
+//         "name": "Jane",
+//     })
+//
+// For safety the table must have a struct registered with
+// `ksql.NewTable().WithStruct()`, and all the keys of the `changes`
+// map must match the names declared on its `ksql` tags.
+func (c DB) PatchMap(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	changes map[string]interface{},
+) error {
+	op := Operation{Method: "PatchMap", Table: table.name, Record: idOrRecord}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.patchMap(ctx, table, idOrRecord, changes)
+	})
+}
+
+func (c DB) patchMap(
+	ctx context.Context,
+	table Table,
+	idOrRecord interface{},
+	changes map[string]interface{},
+) error {
+	if table.auditTable != "" {
+		_, err := c.auditWrite(ctx, table, AuditUpdate, idOrRecord, func(db DB, table Table) (Result, error) {
+			return nil, db.patchMap(ctx, table, idOrRecord, changes)
+		})
+		return err
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't update ksql.Table: %s", err)
+	}
+
+	if table.structType == nil {
+		return fmt.Errorf(
+			"ksql: PatchMap requires a struct registered on the ksql.Table, please create it with: ksql.NewTable(%q).WithStruct(MyStruct{})",
+			table.name,
+		)
+	}
+
+	info, err := c.naming.GetTagInfo(table.structType)
 	if err != nil {
 		return err
 	}
 
-	query, params, err := buildUpdateQuery(c.dialect, table.name, info, record, table.idColumns...)
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
 	if err != nil {
 		return err
 	}
 
-	result, err := c.db.ExecContext(ctx, query, params...)
+	recordMap := map[string]interface{}{}
+	for column, value := range changes {
+		if !info.ByName(column).Valid {
+			return fmt.Errorf(
+				"ksql: the column `%s` is not declared on the struct %v registered for the table `%s`",
+				column, table.structType, table.name,
+			)
+		}
+
+		if _, isID := findString(table.idColumns, column); isID {
+			return fmt.Errorf("ksql: PatchMap can't update the ID column `%s`", column)
+		}
+
+		recordMap[column] = value
+	}
+
+	for _, idName := range table.idColumns {
+		recordMap[idName] = idMap[idName]
+	}
+
+	scopedTable, recordMap, err := scopeToTenant(ctx, table, info.TenantField, recordMap)
+	if err != nil {
+		return err
+	}
+
+	query, params, err := buildUpdateQueryFromMap(ctx, c.dialect, table.name, info, recordMap, "", "", scopedTable.idColumns...)
 	if err != nil {
 		return err
 	}
 
+	result, err := c.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return translateError(c.dialect.DriverName(), err)
+	}
+
 	n, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf(
@@ -675,23 +1783,308 @@ func (c DB) Patch(
 		)
 	}
 	if n < 1 {
-		return ErrRecordNotFound
+		if info.OptimisticLockField != nil {
+			return c.checkVersionConflict(ctx, table, idMap)
+		}
+		return notFoundError(ctx, NotFoundError{Table: table.name})
 	}
 
 	return nil
 }
 
+// UpdateReturning applies a partial update to the given record just like
+// Patch does, and then fills the record with the values of the row as it
+// is saved on the database after the update, i.e. including any values
+// computed by the database itself like defaults and triggers.
+//
+// The record must be passed as a pointer to struct, and this method is only
+// supported on databases with the `RETURNING` or `OUTPUT` clauses, i.e.
+// Postgres, SQLite3 and SQLServer.
+func (c DB) UpdateReturning(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	op := Operation{Method: "UpdateReturning", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.updateReturning(ctx, table, record)
+	})
+}
+
+func (c DB) updateReturning(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	if table.auditTable != "" {
+		_, err := c.auditWrite(ctx, table, AuditUpdate, record, func(db DB, table Table) (Result, error) {
+			return nil, db.updateReturning(ctx, table, record)
+		})
+		return err
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	if err := assertStructPtr(t); err != nil {
+		return fmt.Errorf(
+			"ksql: expected record to be a pointer to struct, but got: %T",
+			record,
+		)
+	}
+
+	if v.IsNil() {
+		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+	}
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't update ksql.Table: %s", err)
+	}
+
+	info, err := c.naming.GetTagInfo(t.Elem())
+	if err != nil {
+		return err
+	}
+
+	if err := callBeforeUpdate(ctx, record); err != nil {
+		return err
+	}
+
+	if err := c.validate(ctx, table, record); err != nil {
+		return err
+	}
+
+	outputQuery, returningQuery, err := buildUpdateReturningQuery(c.dialect, t.Elem(), info)
+	if err != nil {
+		return err
+	}
+
+	recordMap, err := c.naming.StructToMap(record)
+	if err != nil {
+		return err
+	}
+
+	scopedTable, recordMap, err := scopeToTenant(ctx, table, info.TenantField, recordMap)
+	if err != nil {
+		return err
+	}
+
+	query, params, err := buildUpdateQueryFromMap(ctx, c.dialect, table.name, info, recordMap, outputQuery, returningQuery, scopedTable.idColumns...)
+	if err != nil {
+		return err
+	}
+
+	err = c.scanReturnedRow(ctx, table.name, query, params, record)
+	if IsNotFound(err) && info.OptimisticLockField != nil {
+		return c.checkVersionConflict(ctx, table, record)
+	}
+	if err != nil {
+		return err
+	}
+
+	return callAfterUpdate(ctx, record)
+}
+
+// DeleteReturning deletes one record from the database using the ID
+// or IDs read from the input record, and then fills the record with
+// the values the deleted row had on the database.
+//
+// Just like on the Delete method records with the `softDelete`
+// modifier are only marked as deleted.
+//
+// The record must be passed as a pointer to struct, and this method is only
+// supported on databases with the `RETURNING` or `OUTPUT` clauses, i.e.
+// Postgres, SQLite3 and SQLServer, and on DuckDB for the records
+// without the `softDelete` modifier.
+func (c DB) DeleteReturning(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	op := Operation{Method: "DeleteReturning", Table: table.name, Record: record}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.deleteReturning(ctx, table, record)
+	})
+}
+
+func (c DB) deleteReturning(
+	ctx context.Context,
+	table Table,
+	record interface{},
+) error {
+	if table.auditTable != "" {
+		_, err := c.auditWrite(ctx, table, AuditDelete, record, func(db DB, table Table) (Result, error) {
+			return nil, db.deleteReturning(ctx, table, record)
+		})
+		return err
+	}
+
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	v := reflect.ValueOf(record)
+	t := v.Type()
+	if err := assertStructPtr(t); err != nil {
+		return fmt.Errorf(
+			"ksql: expected record to be a pointer to struct, but got: %T",
+			record,
+		)
+	}
+
+	if v.IsNil() {
+		return fmt.Errorf("ksql: expected a valid pointer to struct as argument but received a nil pointer: %v", record)
+	}
+
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't delete from ksql.Table: %s", err)
+	}
+
+	info, err := c.naming.GetTagInfo(t.Elem())
+	if err != nil {
+		return err
+	}
+
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, record)
+	if err != nil {
+		return err
+	}
+
+	table, idMap, err = scopeToTenant(ctx, table, info.TenantField, idMap)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	var params []interface{}
+	if info.SoftDeleteField != nil && !isUnscoped(ctx) {
+		outputQuery, returningQuery, err := buildUpdateReturningQuery(c.dialect, t.Elem(), info)
+		if err != nil {
+			return err
+		}
+
+		query, params = buildSoftDeleteQuery(c.dialect, table, info, idMap, info.SoftDeleteField.Name, time.Now().UTC(), outputQuery, returningQuery)
+	} else {
+		outputQuery, returningQuery, err := buildReturningQuery(c.dialect, t.Elem(), info, "DELETED.")
+		if err != nil {
+			return err
+		}
+
+		query, params = buildDeleteQuery(c.dialect, table, info, idMap, outputQuery, returningQuery)
+	}
+
+	return c.scanReturnedRow(ctx, table.name, query, params, record)
+}
+
+func (c DB) scanReturnedRow(
+	ctx context.Context,
+	tableName string,
+	query string,
+	params []interface{},
+	record interface{},
+) error {
+	rows, err := c.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return translateError(c.dialect.DriverName(), err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return translateError(c.dialect.DriverName(), rows.Err())
+		}
+		return notFoundError(ctx, NotFoundError{Table: tableName})
+	}
+
+	err = scanRowsFromType(ctx, c.dialect, c.naming, rows, record, reflect.TypeOf(record), reflect.ValueOf(record))
+	if err != nil {
+		return translateError(c.dialect.DriverName(), err)
+	}
+
+	return rows.Close()
+}
+
 func buildInsertQuery(
+	ctx context.Context,
+	dialect Dialect,
+	naming *structs.Naming,
+	table Table,
+	t reflect.Type,
+	v reflect.Value,
+	info structs.StructInfo,
+	record interface{},
+) (query string, params []interface{}, scanValues []interface{}, err error) {
+	columnNames, params, err := buildInsertColumns(ctx, dialect, naming, table, info, record)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	valuesQuery, params := buildValuesQuery(dialect, params)
+
+	// Escape all cols to be sure they will be interpreted as column names:
+	escapedColumnNames := []string{}
+	for _, col := range columnNames {
+		escapedColumnNames = append(escapedColumnNames, escapeColumn(dialect, info, col))
+	}
+
+	var returningQuery, outputQuery string
+	switch table.insertMethodFor(dialect) {
+	case insertWithReturning:
+		returningQuery = " RETURNING " + strings.Join(escapeIDColumns(dialect, table, info, ""), ", ")
+		scanValues = getIDScanValues(table, v, info)
+	case insertWithOutput:
+		outputQuery = " OUTPUT " + strings.Join(escapeIDColumns(dialect, table, info, "INSERTED."), ", ")
+		scanValues = getIDScanValues(table, v, info)
+	case insertWithReturningInto:
+		var intoPlaceholders []string
+		for i := range table.idColumns {
+			intoPlaceholders = append(intoPlaceholders, dialect.Placeholder(len(params)+i))
+		}
+		returningQuery = " RETURNING " + strings.Join(escapeIDColumns(dialect, table, info, ""), ", ") +
+			" INTO " + strings.Join(intoPlaceholders, ", ")
+		params = append(params, getIDOutParams(table, v, info)...)
+	}
+
+	// Note that the outputQuery and the returningQuery depend
+	// on the selected driver, thus, they might be empty strings.
+	query = fmt.Sprintf(
+		"INSERT INTO %s (%s)%s VALUES (%s)%s",
+		dialect.Escape(table.name),
+		strings.Join(escapedColumnNames, ", "),
+		outputQuery,
+		strings.Join(valuesQuery, ", "),
+		returningQuery,
+	)
+
+	return query, params, scanValues, nil
+}
+
+// buildInsertColumns returns the names of the columns that should
+// be written on an INSERT query and their respective params.
+//
+// ID columns that were not set are omitted so that the
+// database can generate them.
+func buildInsertColumns(
+	ctx context.Context,
 	dialect Dialect,
+	naming *structs.Naming,
 	table Table,
-	t reflect.Type,
-	v reflect.Value,
 	info structs.StructInfo,
 	record interface{},
-) (query string, params []interface{}, scanValues []interface{}, err error) {
-	recordMap, err := ksqltest.StructToMap(record)
+) (columnNames []string, params []interface{}, err error) {
+	recordMap, err := naming.StructToMap(record)
 	if err != nil {
-		return "", nil, nil, err
+		return nil, nil, err
+	}
+
+	if table.fields != nil {
+		recordMap, err = applyFieldMask(table, info, record, recordMap)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	for _, fieldName := range table.idColumns {
@@ -706,106 +2099,256 @@ func buildInsertQuery(
 		}
 	}
 
-	columnNames := []string{}
 	for col := range recordMap {
+		if info.ByName(col).SkipOnInsert {
+			continue
+		}
 		columnNames = append(columnNames, col)
 	}
 
-	params = make([]interface{}, len(recordMap))
-	valuesQuery := make([]string, len(recordMap))
+	params = make([]interface{}, len(columnNames))
 	for i, col := range columnNames {
-		recordValue := recordMap[col]
-		params[i] = recordValue
-		if info.ByName(col).SerializeAsJSON {
-			params[i] = jsonSerializable{
-				DriverName: dialect.DriverName(),
-				Attr:       recordValue,
-			}
+		params[i], err = applyValueModifier(ctx, dialect, "Insert", info.ByName(col), recordMap[col])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return columnNames, params, nil
+}
+
+func escapeIDColumns(dialect Dialect, table Table, info structs.StructInfo, prefix string) []string {
+	escapedIDNames := []string{}
+	for _, id := range table.idColumns {
+		escapedIDNames = append(escapedIDNames, prefix+escapeColumn(dialect, info, id))
+	}
+	return escapedIDNames
+}
+
+func getIDScanValues(table Table, v reflect.Value, info structs.StructInfo) (scanValues []interface{}) {
+	for _, id := range table.idColumns {
+		fieldInfo := info.ByName(id)
+		if !fieldInfo.Valid {
+			// There is no attribute for saving this ID on the record:
+			scanValues = append(scanValues, nopScannerValue)
+			continue
 		}
 
-		valuesQuery[i] = dialect.Placeholder(i)
+		scanValues = append(
+			scanValues,
+			v.Elem().FieldByIndex(fieldInfo.Path).Addr().Interface(),
+		)
 	}
+	return scanValues
+}
 
-	// Escape all cols to be sure they will be interpreted as column names:
-	escapedColumnNames := []string{}
+// getIDOutParams returns the `sql.Out` params used for saving
+// the IDs returned by the `RETURNING ... INTO` clause on the record.
+func getIDOutParams(table Table, v reflect.Value, info structs.StructInfo) (params []interface{}) {
+	for _, id := range table.idColumns {
+		// There might be no attribute for saving this ID on the record:
+		var dest interface{} = new(interface{})
+
+		fieldInfo := info.ByName(id)
+		if fieldInfo.Valid {
+			dest = v.Elem().FieldByIndex(fieldInfo.Path).Addr().Interface()
+		}
+
+		params = append(params, sql.Out{Dest: dest})
+	}
+	return params
+}
+
+func buildUpsertQuery(
+	ctx context.Context,
+	dialect Dialect,
+	naming *structs.Naming,
+	table Table,
+	v reflect.Value,
+	info structs.StructInfo,
+	record interface{},
+) (query string, params []interface{}, scanValues []interface{}, err error) {
+	columnNames, params, err := buildInsertColumns(ctx, dialect, naming, table, info, record)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	conflictColumns := table.getConflictColumns()
+	isConflictColumn := map[string]bool{}
+	for _, col := range conflictColumns {
+		isConflictColumn[col] = true
+	}
+
+	var updateColumns []string
 	for _, col := range columnNames {
-		escapedColumnNames = append(escapedColumnNames, dialect.Escape(col))
+		if !isConflictColumn[col] && !info.ByName(col).SkipOnUpdate {
+			updateColumns = append(updateColumns, col)
+		}
 	}
 
-	var returningQuery, outputQuery string
-	switch dialect.InsertMethod() {
-	case insertWithReturning:
-		escapedIDNames := []string{}
-		for _, id := range table.idColumns {
-			escapedIDNames = append(escapedIDNames, dialect.Escape(id))
+	valuesQuery, params := buildValuesQuery(dialect, params)
+	escapedColumnNames := make([]string, len(columnNames))
+	for i, col := range columnNames {
+		escapedColumnNames[i] = escapeColumn(dialect, info, col)
+	}
+
+	switch dialect.DriverName() {
+	case "postgres", "sqlite3", "duckdb":
+		escapedConflictColumns := []string{}
+		for _, col := range conflictColumns {
+			escapedConflictColumns = append(escapedConflictColumns, escapeColumn(dialect, info, col))
 		}
-		returningQuery = " RETURNING " + strings.Join(escapedIDNames, ", ")
 
-		for _, id := range table.idColumns {
-			scanValues = append(
-				scanValues,
-				v.Elem().Field(info.ByName(id).Index).Addr().Interface(),
-			)
+		// When there is nothing else to update we update one of the conflict
+		// columns to its own value, so that `RETURNING` still returns the row:
+		if len(updateColumns) == 0 {
+			updateColumns = conflictColumns[:1]
 		}
-	case insertWithOutput:
-		escapedIDNames := []string{}
-		for _, id := range table.idColumns {
-			escapedIDNames = append(escapedIDNames, "INSERTED."+dialect.Escape(id))
+
+		setQuery := []string{}
+		for _, col := range updateColumns {
+			setQuery = append(setQuery, escapeColumn(dialect, info, col)+" = EXCLUDED."+escapeColumn(dialect, info, col))
 		}
-		outputQuery = " OUTPUT " + strings.Join(escapedIDNames, ", ")
 
-		for _, id := range table.idColumns {
-			scanValues = append(
-				scanValues,
-				v.Elem().Field(info.ByName(id).Index).Addr().Interface(),
-			)
+		var returningQuery string
+		if dialect.InsertMethod() == insertWithReturning {
+			returningQuery = " RETURNING " + strings.Join(escapeIDColumns(dialect, table, info, ""), ", ")
+			scanValues = getIDScanValues(table, v, info)
 		}
-	}
 
-	// Note that the outputQuery and the returningQuery depend
-	// on the selected driver, thus, they might be empty strings.
-	query = fmt.Sprintf(
-		"INSERT INTO %s (%s)%s VALUES (%s)%s",
-		dialect.Escape(table.name),
-		strings.Join(escapedColumnNames, ", "),
-		outputQuery,
-		strings.Join(valuesQuery, ", "),
-		returningQuery,
-	)
+		query = fmt.Sprintf(
+			"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s%s",
+			dialect.Escape(table.name),
+			strings.Join(escapedColumnNames, ", "),
+			strings.Join(valuesQuery, ", "),
+			strings.Join(escapedConflictColumns, ", "),
+			strings.Join(setQuery, ", "),
+			returningQuery,
+		)
+
+	case "mysql", "mariadb":
+		// MySQL requires at least one assignment on the update clause:
+		if len(updateColumns) == 0 {
+			updateColumns = conflictColumns[:1]
+		}
+
+		setQuery := []string{}
+		for _, col := range updateColumns {
+			setQuery = append(setQuery, escapeColumn(dialect, info, col)+" = VALUES("+escapeColumn(dialect, info, col)+")")
+		}
+
+		query = fmt.Sprintf(
+			"INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+			dialect.Escape(table.name),
+			strings.Join(escapedColumnNames, ", "),
+			strings.Join(valuesQuery, ", "),
+			strings.Join(setQuery, ", "),
+		)
+
+	case "sqlserver":
+		// Only the conflict columns present on the record can be compared,
+		// if none is present the MERGE will always insert a new row:
+		onQuery := []string{}
+		for _, col := range conflictColumns {
+			if _, found := findString(columnNames, col); found {
+				onQuery = append(onQuery, "target."+escapeColumn(dialect, info, col)+" = source."+escapeColumn(dialect, info, col))
+			}
+		}
+		if len(onQuery) == 0 {
+			onQuery = []string{"1 = 0"}
+		}
+
+		var matchedQuery string
+		if len(updateColumns) > 0 {
+			setQuery := []string{}
+			for _, col := range updateColumns {
+				setQuery = append(setQuery, "target."+escapeColumn(dialect, info, col)+" = source."+escapeColumn(dialect, info, col))
+			}
+			matchedQuery = " WHEN MATCHED THEN UPDATE SET " + strings.Join(setQuery, ", ")
+
+			// Without the WHEN MATCHED clause no rows would be
+			// returned for existing records, so we only retrieve
+			// the IDs when it is present:
+			scanValues = getIDScanValues(table, v, info)
+		}
+
+		sourceColumns := []string{}
+		for _, col := range columnNames {
+			sourceColumns = append(sourceColumns, "source."+escapeColumn(dialect, info, col))
+		}
+
+		var outputQuery string
+		if scanValues != nil {
+			outputQuery = " OUTPUT " + strings.Join(escapeIDColumns(dialect, table, info, "INSERTED."), ", ")
+		}
+
+		query = fmt.Sprintf(
+			"MERGE INTO %s AS target USING (VALUES (%s)) AS source (%s) ON %s%s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)%s;",
+			dialect.Escape(table.name),
+			strings.Join(valuesQuery, ", "),
+			strings.Join(escapedColumnNames, ", "),
+			strings.Join(onQuery, " AND "),
+			matchedQuery,
+			strings.Join(escapedColumnNames, ", "),
+			strings.Join(sourceColumns, ", "),
+			outputQuery,
+		)
+
+	default:
+		return "", nil, nil, fmt.Errorf("ksql: Upsert is not supported for the driver `%s`", dialect.DriverName())
+	}
 
 	return query, params, scanValues, nil
 }
 
-func buildUpdateQuery(
+func findString(slice []string, str string) (idx int, found bool) {
+	for i, s := range slice {
+		if s == str {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func buildUpdateQueryFromMap(
+	ctx context.Context,
 	dialect Dialect,
 	tableName string,
 	info structs.StructInfo,
-	record interface{},
+	recordMap map[string]interface{},
+	outputQuery string,
+	returningQuery string,
 	idFieldNames ...string,
 ) (query string, args []interface{}, err error) {
-	recordMap, err := ksqltest.StructToMap(record)
+	// This validation must happen before we compute the number of
+	// args, otherwise missing ID attributes would break the math below:
+	err = validateIfAllIdsArePresent(idFieldNames, recordMap)
 	if err != nil {
 		return "", nil, err
 	}
-	numAttrs := len(recordMap)
-	args = make([]interface{}, numAttrs)
-	numNonIDArgs := numAttrs - len(idFieldNames)
-	whereArgs := args[numNonIDArgs:]
 
-	err = validateIfAllIdsArePresent(idFieldNames, recordMap)
-	if err != nil {
-		return "", nil, err
+	setTimeNowOnUpdate(recordMap, info, time.Now().UTC())
+
+	// The optimistic lock attribute is never set directly, instead it is
+	// compared with the version saved on the database and then incremented:
+	lockField := info.OptimisticLockField
+	var version interface{}
+	hasVersion := false
+	if lockField != nil {
+		version, hasVersion = recordMap[lockField.Name]
+		delete(recordMap, lockField.Name)
 	}
 
-	whereQuery := make([]string, len(idFieldNames))
-	for i, fieldName := range idFieldNames {
-		whereArgs[i] = recordMap[fieldName]
-		whereQuery[i] = fmt.Sprintf(
-			"%s = %s",
-			dialect.Escape(fieldName),
-			dialect.Placeholder(i+numNonIDArgs),
+	if len(recordMap) == len(idFieldNames) {
+		return "", nil, fmt.Errorf(
+			"ksql: the input record has no attributes to update besides the ID columns: %v",
+			idFieldNames,
 		)
+	}
 
+	ids := make([]interface{}, len(idFieldNames))
+	for i, fieldName := range idFieldNames {
+		ids[i] = recordMap[fieldName]
 		delete(recordMap, fieldName)
 	}
 
@@ -815,27 +2358,51 @@ func buildUpdateQuery(
 	}
 
 	var setQuery []string
-	for i, k := range keys {
-		recordValue := recordMap[k]
-		if info.ByName(k).SerializeAsJSON {
-			recordValue = jsonSerializable{
-				DriverName: dialect.DriverName(),
-				Attr:       recordValue,
-			}
+	for _, k := range keys {
+		// Expressions are written on the query instead of the params:
+		if expr, ok := recordMap[k].(Expr); ok {
+			setQuery = append(setQuery, escapeColumn(dialect, info, k)+" = "+string(expr))
+			continue
+		}
+
+		arg, err := applyValueModifier(ctx, dialect, "Update", info.ByName(k), recordMap[k])
+		if err != nil {
+			return "", nil, err
 		}
-		args[i] = recordValue
 		setQuery = append(setQuery, fmt.Sprintf(
 			"%s = %s",
-			dialect.Escape(k),
-			dialect.Placeholder(i),
+			escapeColumn(dialect, info, k),
+			dialect.Placeholder(len(args)),
 		))
+		args = append(args, arg)
+	}
+
+	whereQuery := make([]string, len(idFieldNames))
+	for i, fieldName := range idFieldNames {
+		whereQuery[i] = fmt.Sprintf(
+			"%s = %s",
+			escapeColumn(dialect, info, fieldName),
+			dialect.Placeholder(len(args)),
+		)
+		args = append(args, ids[i])
+	}
+
+	if lockField != nil {
+		escapedName := escapeColumn(dialect, info, lockField.Name)
+		setQuery = append(setQuery, escapedName+" = "+escapedName+" + 1")
+		if hasVersion {
+			whereQuery = append(whereQuery, escapedName+" = "+dialect.Placeholder(len(args)))
+			args = append(args, version)
+		}
 	}
 
 	query = fmt.Sprintf(
-		"UPDATE %s SET %s WHERE %s",
+		"UPDATE %s SET %s%s WHERE %s%s",
 		dialect.Escape(tableName),
 		strings.Join(setQuery, ", "),
+		outputQuery,
 		strings.Join(whereQuery, " AND "),
+		returningQuery,
 	)
 
 	return query, args, nil
@@ -857,8 +2424,30 @@ func validateIfAllIdsArePresent(idNames []string, idMap map[string]interface{})
 }
 
 // Exec just runs an SQL command on the database returning no rows.
-func (c DB) Exec(ctx context.Context, query string, params ...interface{}) (Result, error) {
-	return c.db.ExecContext(ctx, query, params...)
+//
+// Just like on the Query method, slice params used inside
+// `IN (...)` clauses are expanded into one param per element.
+func (c DB) Exec(ctx context.Context, query string, params ...interface{}) (result Result, err error) {
+	op := Operation{Method: "Exec", Query: query, Params: params}
+	err = c.intercept(ctx, op, func(ctx context.Context, op Operation) (err error) {
+		result, err = c.exec(ctx, op.Query, op.Params...)
+		return err
+	})
+	return result, err
+}
+
+func (c DB) exec(ctx context.Context, query string, params ...interface{}) (Result, error) {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	query, params, err := expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.db.ExecContext(ctx, query, params...)
+	return result, translateError(c.dialect.DriverName(), err)
 }
 
 // Transaction encapsulates several queries into a single transaction.
@@ -870,55 +2459,101 @@ func (c DB) Exec(ctx context.Context, query string, params ...interface{}) (Resu
 //
 // If it happens that a second transaction is started inside a transaction
 // callback the same transaction will be reused with no errors.
+//
+// If the DBAdapter implements the TxRetrier interface the whole
+// transaction, including the callback, might run more than once,
+// so the callback should not have side effects outside of it.
 func (c DB) Transaction(ctx context.Context, fn func(Provider) error) error {
+	return c.intercept(ctx, Operation{Method: "Transaction"}, func(ctx context.Context, op Operation) error {
+		return c.startTransaction(ctx, fn)
+	})
+}
+
+func (c DB) startTransaction(ctx context.Context, fn func(Provider) error) error {
+	c = c.contextTx(ctx)
 	switch txBeginner := c.db.(type) {
 	case Tx:
+		if c.savepoints != nil {
+			return c.runTransaction(ctx, c.beginSavepoint, fn)
+		}
 		return fn(c)
 	case TxBeginner:
-		tx, err := txBeginner.BeginTx(ctx)
-		if err != nil {
-			return fmt.Errorf("KSQL: error starting transaction: %s", err)
-		}
-		defer func() {
-			if r := recover(); r != nil {
-				rollbackErr := tx.Rollback(ctx)
-				if rollbackErr != nil {
-					r = errors.Wrap(rollbackErr,
-						fmt.Sprintf("KSQL: unable to rollback after panic with value: %v", r),
-					)
-				}
-				panic(r)
-			}
-		}()
+		return c.transaction(ctx, txBeginner.BeginTx, fn)
+	default:
+		return fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+}
 
-		dbCopy := c
-		dbCopy.db = tx
+// transaction runs the transaction, retrying it if
+// the DBAdapter implements the TxRetrier interface.
+func (c DB) transaction(
+	ctx context.Context,
+	beginTx func(ctx context.Context) (Tx, error),
+	fn func(Provider) error,
+) error {
+	retrier, ok := c.db.(TxRetrier)
+	if !ok {
+		return c.runTransaction(ctx, beginTx, fn)
+	}
 
-		err = fn(dbCopy)
-		if err != nil {
-			rollbackErr := tx.Rollback(ctx)
+	for attempt := 1; ; attempt++ {
+		err := c.runTransaction(ctx, beginTx, fn)
+		if err == nil || !retrier.RetryTx(ctx, attempt, err) {
+			return err
+		}
+	}
+}
+
+func (c DB) runTransaction(ctx context.Context, beginTx func(ctx context.Context) (Tx, error), fn func(Provider) error) error {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("KSQL: error starting transaction: %s", err)
+	}
+	hooks := &txHooks{}
+	defer func() {
+		if r := recover(); r != nil {
+			rollbackErr := hooks.rollback(ctx, tx)
 			if rollbackErr != nil {
-				err = errors.Wrap(rollbackErr,
-					fmt.Sprintf("KSQL: unable to rollback after error: %s", err.Error()),
+				r = errors.Wrap(rollbackErr,
+					fmt.Sprintf("KSQL: unable to rollback after panic with value: %v", r),
 				)
 			}
-			return err
+			panic(r)
 		}
+	}()
 
-		return tx.Commit(ctx)
+	dbCopy := c
+	dbCopy.db = tx
 
-	default:
-		return fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	// All the queries of a transaction must run on the primary:
+	dbCopy.replicas = nil
+	dbCopy.txHooks = hooks
+
+	err = fn(dbCopy)
+	if err != nil {
+		rollbackErr := hooks.rollback(ctx, tx)
+		if rollbackErr != nil {
+			err = errors.Wrap(rollbackErr,
+				fmt.Sprintf("KSQL: unable to rollback after error: %s", err.Error()),
+			)
+		}
+		return err
 	}
+
+	return hooks.commit(ctx, tx)
 }
 
 // Close implements the io.Closer interface
 func (c DB) Close() error {
+	replicasErr := c.closeReplicas()
+
 	closer, ok := c.db.(io.Closer)
 	if ok {
-		return closer.Close()
+		if err := closer.Close(); err != nil {
+			return err
+		}
 	}
-	return nil
+	return replicasErr
 }
 
 type nopScanner struct{}
@@ -929,14 +2564,16 @@ func (nopScanner) Scan(value interface{}) error {
 	return nil
 }
 
-func scanRows(dialect Dialect, rows Rows, record interface{}) error {
+func scanRows(ctx context.Context, dialect Dialect, rows Rows, record interface{}) error {
 	v := reflect.ValueOf(record)
 	t := v.Type()
-	return scanRowsFromType(dialect, rows, record, t, v)
+	return scanRowsFromType(ctx, dialect, nil, rows, record, t, v)
 }
 
 func scanRowsFromType(
+	ctx context.Context,
 	dialect Dialect,
+	naming *structs.Naming,
 	rows Rows,
 	record interface{},
 	t reflect.Type,
@@ -946,120 +2583,105 @@ func scanRowsFromType(
 		return fmt.Errorf("ksql: expected record to be a pointer to struct, but got: %T", record)
 	}
 
-	v = v.Elem()
-	t = t.Elem()
-
-	if t.Kind() != reflect.Struct {
+	if t.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("ksql: expected record to be a pointer to struct, but got: %T", record)
 	}
 
-	info, err := structs.GetTagInfo(t)
+	info, err := naming.GetTagInfo(t.Elem())
 	if err != nil {
 		return err
 	}
 
-	var scanArgs []interface{}
-	if info.IsNestedStruct {
-		// This version is positional meaning that it expect the arguments
-		// to follow an specific order. It's ok because we don't allow the
-		// user to type the "SELECT" part of the query for nested structs.
-		scanArgs, err = getScanArgsForNestedStructs(dialect, rows, t, v, info)
-		if err != nil {
-			return err
-		}
-	} else {
-		names, err := rows.Columns()
-		if err != nil {
-			return err
-		}
-		// Since this version uses the names of the columns it works
-		// with any order of attributes/columns.
-		scanArgs = getScanArgsFromNames(dialect, names, v, info)
-	}
-
-	return rows.Scan(scanArgs...)
-}
-
-func getScanArgsForNestedStructs(dialect Dialect, rows Rows, t reflect.Type, v reflect.Value, info structs.StructInfo) ([]interface{}, error) {
-	scanArgs := []interface{}{}
-	for i := 0; i < v.NumField(); i++ {
-		if !info.ByIndex(i).Valid {
-			continue
-		}
-
-		// TODO(vingarcia00): Handle case where type is pointer
-		nestedStructInfo, err := structs.GetTagInfo(t.Field(i).Type)
-		if err != nil {
-			return nil, err
-		}
-
-		nestedStructValue := v.Field(i)
-		for j := 0; j < nestedStructValue.NumField(); j++ {
-			fieldInfo := nestedStructInfo.ByIndex(j)
-			if !fieldInfo.Valid {
-				continue
-			}
-
-			valueScanner := nopScannerValue
-			if fieldInfo.Valid {
-				valueScanner = nestedStructValue.Field(fieldInfo.Index).Addr().Interface()
-				if fieldInfo.SerializeAsJSON {
-					valueScanner = &jsonSerializable{
-						DriverName: dialect.DriverName(),
-						Attr:       valueScanner,
-					}
-				}
-			}
-
-			scanArgs = append(scanArgs, valueScanner)
-		}
-	}
-
-	return scanArgs, nil
-}
-
-func getScanArgsFromNames(dialect Dialect, names []string, v reflect.Value, info structs.StructInfo) []interface{} {
-	scanArgs := []interface{}{}
-	for _, name := range names {
-		fieldInfo := info.ByName(name)
-
-		valueScanner := nopScannerValue
-		if fieldInfo.Valid {
-			valueScanner = v.Field(fieldInfo.Index).Addr().Interface()
-			if fieldInfo.SerializeAsJSON {
-				valueScanner = &jsonSerializable{
-					DriverName: dialect.DriverName(),
-					Attr:       valueScanner,
-				}
-			}
-		}
-
-		scanArgs = append(scanArgs, valueScanner)
+	scanner, err := newRowScanner(ctx, dialect, rows, t.Elem(), info)
+	if err != nil {
+		return err
 	}
 
-	return scanArgs
+	return scanner.scan(v)
 }
 
 func buildDeleteQuery(
 	dialect Dialect,
 	table Table,
+	info structs.StructInfo,
 	idMap map[string]interface{},
+	outputQuery string,
+	returningQuery string,
 ) (query string, params []interface{}) {
 	whereQuery := []string{}
 	for i, idName := range table.idColumns {
 		whereQuery = append(whereQuery, fmt.Sprintf(
-			"%s = %s", dialect.Escape(idName), dialect.Placeholder(i),
+			"%s = %s", escapeColumn(dialect, info, idName), dialect.Placeholder(i),
 		))
 		params = append(params, idMap[idName])
 	}
 
 	return fmt.Sprintf(
-		"DELETE FROM %s WHERE %s",
+		"DELETE FROM %s%s WHERE %s%s",
 		dialect.Escape(table.name),
+		outputQuery,
 		strings.Join(whereQuery, " AND "),
+		returningQuery,
 	), params
 }
 
+// buildReturningQuery builds the clause used for retrieving all the
+// columns of the struct after a write, for SQLServer it is an `OUTPUT`
+// clause and for the other dialects a `RETURNING` clause.
+//
+// The outputPrefix is used only for SQLServer and should be
+// either "INSERTED." or "DELETED.".
+func buildReturningQuery(
+	dialect Dialect,
+	structType reflect.Type,
+	info structs.StructInfo,
+	outputPrefix string,
+) (outputQuery string, returningQuery string, err error) {
+	if info.IsNestedStruct {
+		return "", "", fmt.Errorf("ksql: can't scan the returned row into a struct with nested structs: %v", structType)
+	}
+
+	var fields []string
+	for _, fieldInfo := range info.Fields() {
+		fields = append(fields, escapeColumn(dialect, info, fieldInfo.Name))
+	}
+
+	switch dialect.DriverName() {
+	case "postgres", "sqlite3", "duckdb":
+		returningQuery = " RETURNING " + strings.Join(fields, ", ")
+	case "sqlserver":
+		for i := range fields {
+			fields[i] = outputPrefix + fields[i]
+		}
+		outputQuery = " OUTPUT " + strings.Join(fields, ", ")
+	default:
+		return "", "", fmt.Errorf(
+			"ksql: retrieving the affected row is not supported for the driver `%s`",
+			dialect.DriverName(),
+		)
+	}
+
+	return outputQuery, returningQuery, nil
+}
+
+// buildUpdateReturningQuery works like buildReturningQuery for the UPDATE
+// statements, which can't return the updated row on DuckDB since it
+// rejects `UPDATE ... RETURNING` on tables with primary keys.
+func buildUpdateReturningQuery(
+	dialect Dialect,
+	structType reflect.Type,
+	info structs.StructInfo,
+) (outputQuery string, returningQuery string, err error) {
+	if dialect.DriverName() == "duckdb" {
+		return "", "", fmt.Errorf(
+			"ksql: retrieving the updated row is not supported for the driver `%s`",
+			dialect.DriverName(),
+		)
+	}
+
+	return buildReturningQuery(dialect, structType, info, "INSERTED.")
+}
+
 // We implemented this function instead of using
 // a regex or strings.Fields because we wanted
 // to preserve the performance of the package.
@@ -1082,7 +2704,8 @@ func buildSelectQuery(
 	info structs.StructInfo,
 	selectQueryCache *sync.Map,
 ) (query string, err error) {
-	if data, found := selectQueryCache.Load(structType); found {
+	key := selectQueryKey{structType: structType, naming: info.Naming}
+	if data, found := selectQueryCache.Load(key); found {
 		if selectQuery, ok := data.(string); !ok {
 			return "", fmt.Errorf("invalid cache entry, expected type string, found %T", data)
 		} else {
@@ -1099,7 +2722,7 @@ func buildSelectQuery(
 		query = buildSelectQueryForPlainStructs(dialect, structType, info)
 	}
 
-	selectQueryCache.Store(structType, query)
+	selectQueryCache.Store(key, query)
 	return query, nil
 }
 
@@ -1109,13 +2732,8 @@ func buildSelectQueryForPlainStructs(
 	info structs.StructInfo,
 ) string {
 	var fields []string
-	for i := 0; i < structType.NumField(); i++ {
-		fieldInfo := info.ByIndex(i)
-		if !fieldInfo.Valid {
-			continue
-		}
-
-		fields = append(fields, dialect.Escape(fieldInfo.Name))
+	for _, fieldInfo := range info.Fields() {
+		fields = append(fields, escapeColumn(dialect, info, fieldInfo.Name))
 	}
 
 	return "SELECT " + strings.Join(fields, ", ") + " "
@@ -1126,36 +2744,17 @@ func buildSelectQueryForNestedStructs(
 	structType reflect.Type,
 	info structs.StructInfo,
 ) (string, error) {
-	var fields []string
-	for i := 0; i < structType.NumField(); i++ {
-		nestedStructInfo := info.ByIndex(i)
-		if !nestedStructInfo.Valid {
-			continue
-		}
-
-		nestedStructName := nestedStructInfo.Name
-		nestedStructType := structType.Field(i).Type
-		if nestedStructType.Kind() != reflect.Struct {
-			return "", fmt.Errorf(
-				"expected nested struct with `tablename:\"%s\"` to be a kind of Struct, but got %v",
-				nestedStructName, nestedStructType,
-			)
-		}
-
-		nestedStructTagInfo, err := structs.GetTagInfo(nestedStructType)
-		if err != nil {
-			return "", err
-		}
-
-		for j := 0; j < structType.Field(i).Type.NumField(); j++ {
-			fieldInfo := nestedStructTagInfo.ByIndex(j)
-			if !fieldInfo.Valid {
-				continue
-			}
+	tables, err := getNestedTables(structType, info)
+	if err != nil {
+		return "", err
+	}
 
+	var fields []string
+	for _, table := range tables {
+		for _, fieldInfo := range table.info.Fields() {
 			fields = append(
 				fields,
-				dialect.Escape(nestedStructName)+"."+dialect.Escape(fieldInfo.Name),
+				dialect.Escape(table.alias)+"."+escapeColumn(dialect, table.info, fieldInfo.Name),
 			)
 		}
 	}
@@ -0,0 +1,181 @@
+// Package ksqlgeo contains types for reading and writing the geometry
+// and geography columns of PostGIS, with both the pq and pgx adapters,
+// without selecting them with `ST_AsBinary()` and decoding them by hand:
+//
+//	type Place struct {
+//		ID       int              `ksql:"id"`
+//		Location ksqlgeo.Geometry `ksql:"location"`
+//
+//		// Pointers can be used for nullable columns:
+//		Area *ksqlgeo.Geometry `ksql:"area"`
+//	}
+//
+//	err := db.Insert(ctx, placesTable, &Place{
+//		Location: ksqlgeo.Geometry{SRID: 4326, Shape: ksqlgeo.Point{X: -43.2, Y: -22.9}},
+//	})
+//
+// The values are scanned from the hex encoded EWKB returned by PostGIS
+// for these columns, and also from binary WKB and from WKT, e.g. the
+// results of `ST_AsBinary()` and `ST_AsText()`, and they are written as
+// hex encoded EWKB, which PostGIS accepts for both column types.
+//
+// Only 2D shapes are supported, i.e. the Z and M coordinates are rejected.
+package ksqlgeo
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Shape is implemented by the Point, LineString, Polygon,
+// MultiPoint, MultiLineString and MultiPolygon types
+type Shape interface {
+	wkbType() uint32
+}
+
+// Point is a position with the X and Y coordinates, which
+// are the longitude and the latitude on geographic SRIDs
+type Point struct {
+	X float64
+	Y float64
+}
+
+// LineString is a sequence of points
+type LineString []Point
+
+// Polygon is a list of linear rings, i.e. closed LineStrings, where
+// the first ring is the exterior and the others are the holes
+type Polygon []LineString
+
+// MultiPoint is a collection of points
+type MultiPoint []Point
+
+// MultiLineString is a collection of LineStrings
+type MultiLineString []LineString
+
+// MultiPolygon is a collection of polygons
+type MultiPolygon []Polygon
+
+const (
+	pointType           uint32 = 1
+	lineStringType      uint32 = 2
+	polygonType         uint32 = 3
+	multiPointType      uint32 = 4
+	multiLineStringType uint32 = 5
+	multiPolygonType    uint32 = 6
+)
+
+func (Point) wkbType() uint32           { return pointType }
+func (LineString) wkbType() uint32      { return lineStringType }
+func (Polygon) wkbType() uint32         { return polygonType }
+func (MultiPoint) wkbType() uint32      { return multiPointType }
+func (MultiLineString) wkbType() uint32 { return multiLineStringType }
+func (MultiPolygon) wkbType() uint32    { return multiPolygonType }
+
+// Geometry is a Shape and its Spatial Reference System Identifier,
+// e.g. 4326 for WGS 84, which is 0 if the shape has no SRID.
+//
+// It can be used for attributes of both geometry and geography columns.
+type Geometry struct {
+	SRID  int
+	Shape Shape
+}
+
+// Scan implements the sql.Scanner interface, it accepts hex encoded
+// EWKB or WKB, binary EWKB or WKB and EWKT or WKT texts.
+//
+// Use *ksqlgeo.Geometry attributes for nullable columns,
+// since scanning NULL into a Geometry is an error.
+func (g *Geometry) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return fmt.Errorf("ksqlgeo: can't scan NULL into a Geometry, use a *ksqlgeo.Geometry instead")
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("ksqlgeo: can't scan value of type %T into a Geometry", value)
+	}
+
+	geometry, err := parse(data)
+	if err != nil {
+		return err
+	}
+
+	*g = geometry
+	return nil
+}
+
+// parse detects the format of the input, which is either
+// binary WKB, hex encoded WKB or WKT
+func parse(data []byte) (Geometry, error) {
+	if len(data) == 0 {
+		return Geometry{}, fmt.Errorf("ksqlgeo: can't parse an empty value as a geometry")
+	}
+
+	// Binary WKB starts with the byte order, which is 0 or 1:
+	if data[0] == 0 || data[0] == 1 {
+		return decodeWKB(data)
+	}
+
+	if isHex(data) {
+		decoded := make([]byte, hex.DecodedLen(len(data)))
+		if _, err := hex.Decode(decoded, data); err != nil {
+			return Geometry{}, fmt.Errorf("ksqlgeo: invalid hex encoded WKB: %s", err)
+		}
+		return decodeWKB(decoded)
+	}
+
+	return parseWKT(string(data))
+}
+
+func isHex(data []byte) bool {
+	if len(data)%2 != 0 {
+		return false
+	}
+	for _, c := range data {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Value implements the driver.Valuer interface,
+// sending the geometry as hex encoded EWKB
+func (g Geometry) Value() (driver.Value, error) {
+	if g.Shape == nil {
+		return nil, fmt.Errorf("ksqlgeo: can't write a Geometry without a Shape, use a nil *ksqlgeo.Geometry for NULL values")
+	}
+	return strings.ToUpper(hex.EncodeToString(g.EWKB())), nil
+}
+
+// EWKB returns the geometry encoded as the Extended Well-Known Binary
+// used by PostGIS, which is the same as WKB if the SRID is 0.
+func (g Geometry) EWKB() []byte {
+	return encodeWKB(g.Shape, g.SRID)
+}
+
+// WKB returns the shape encoded as Well-Known Binary,
+// e.g. for `ST_GeomFromWKB()`, ignoring the SRID
+func (g Geometry) WKB() []byte {
+	return encodeWKB(g.Shape, 0)
+}
+
+// String returns the geometry as EWKT, e.g. `SRID=4326;POINT(1 2)`,
+// or as WKT if the SRID is 0
+func (g Geometry) String() string {
+	if g.Shape == nil {
+		return ""
+	}
+
+	wkt := formatWKT(g.Shape)
+	if g.SRID != 0 {
+		return fmt.Sprintf("SRID=%d;%s", g.SRID, wkt)
+	}
+	return wkt
+}
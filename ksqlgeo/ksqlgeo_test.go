@@ -0,0 +1,155 @@
+package ksqlgeo
+
+import (
+	"encoding/hex"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestScan(t *testing.T) {
+	point := Geometry{SRID: 4326, Shape: Point{X: 1, Y: 2}}
+
+	tests := []struct {
+		desc     string
+		input    interface{}
+		expected Geometry
+	}{
+		{
+			desc:     "hex EWKB returned by pq",
+			input:    []byte("0101000020E6100000000000000000F03F0000000000000040"),
+			expected: point,
+		},
+		{
+			desc:     "hex EWKB returned by pgx",
+			input:    "0101000020e6100000000000000000f03f0000000000000040",
+			expected: point,
+		},
+		{
+			desc:     "binary WKB returned by ST_AsBinary",
+			input:    mustDecodeHex("0101000000000000000000F03F0000000000000040"),
+			expected: Geometry{Shape: Point{X: 1, Y: 2}},
+		},
+		{
+			desc:     "big endian WKB",
+			input:    mustDecodeHex("00000000013FF00000000000004000000000000000"),
+			expected: Geometry{Shape: Point{X: 1, Y: 2}},
+		},
+		{
+			desc:     "WKT returned by ST_AsText",
+			input:    "POINT(1 2)",
+			expected: Geometry{Shape: Point{X: 1, Y: 2}},
+		},
+		{
+			desc:     "EWKT",
+			input:    "SRID=4326;point (1 2)",
+			expected: point,
+		},
+		{
+			desc:  "polygon",
+			input: "POLYGON((0 0,4 0,4 4,0 0),(1 1,2 1,1 2,1 1))",
+			expected: Geometry{Shape: Polygon{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 0}},
+				{{1, 1}, {2, 1}, {1, 2}, {1, 1}},
+			}},
+		},
+		{
+			desc:     "multi point without parentheses",
+			input:    "MULTIPOINT(1 2, -3.5 4e2)",
+			expected: Geometry{Shape: MultiPoint{{1, 2}, {-3.5, 400}}},
+		},
+		{
+			desc:     "empty line string",
+			input:    "LINESTRING EMPTY",
+			expected: Geometry{Shape: LineString{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var g Geometry
+			err := g.Scan(test.input)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, g, test.expected)
+		})
+	}
+
+	t.Run("should report NULL and invalid values", func(t *testing.T) {
+		var g Geometry
+		err := g.Scan(nil)
+		tt.AssertErrContains(t, err, "NULL", "*ksqlgeo.Geometry")
+
+		err = g.Scan(42)
+		tt.AssertErrContains(t, err, "int")
+
+		err = g.Scan("POINT(1)")
+		tt.AssertErrContains(t, err, "POINT(1)", "number")
+
+		err = g.Scan("POINT Z (1 2 3)")
+		tt.AssertErrContains(t, err, "2D")
+
+		err = g.Scan("GEOMETRYCOLLECTION(POINT(1 2))")
+		tt.AssertErrContains(t, err, "unsupported", "GEOMETRYCOLLECTION")
+
+		err = g.Scan("0101000000000000000000F03F")
+		tt.AssertErrContains(t, err, "WKB", "end of data")
+
+		// A POINT Z with the EWKB Z flag:
+		err = g.Scan("0101000080000000000000F03F00000000000000400000000000000840")
+		tt.AssertErrContains(t, err, "WKB", "2D")
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	shapes := []Shape{
+		Point{X: -43.2, Y: -22.9},
+		LineString{{0, 0}, {1, 1}, {2, 0.5}},
+		Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 0}}},
+		MultiPoint{{1, 2}, {3, 4}},
+		MultiLineString{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}},
+		MultiPolygon{
+			{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}},
+			{{{2, 2}, {3, 2}, {3, 3}, {2, 2}}},
+		},
+	}
+	for _, shape := range shapes {
+		g := Geometry{SRID: 4326, Shape: shape}
+		t.Run(g.String(), func(t *testing.T) {
+			value, err := g.Value()
+			tt.AssertNoErr(t, err)
+
+			var fromEWKB Geometry
+			err = fromEWKB.Scan(value)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, fromEWKB, g)
+
+			var fromEWKT Geometry
+			err = fromEWKT.Scan(g.String())
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, fromEWKT, g)
+
+			var fromWKB Geometry
+			err = fromWKB.Scan(g.WKB())
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, fromWKB, Geometry{Shape: shape})
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	value, err := Geometry{SRID: 4326, Shape: Point{X: 1, Y: 2}}.Value()
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, value, "0101000020E6100000000000000000F03F0000000000000040")
+
+	tt.AssertEqual(t, Geometry{SRID: 4326, Shape: Point{X: 1, Y: 2}}.String(), "SRID=4326;POINT(1 2)")
+
+	_, err = Geometry{}.Value()
+	tt.AssertErrContains(t, err, "Shape", "nil *ksqlgeo.Geometry")
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,259 @@
+package ksqlgeo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// These flags are added by EWKB to the geometry type:
+const (
+	ewkbZFlag    uint32 = 0x80000000
+	ewkbMFlag    uint32 = 0x40000000
+	ewkbSRIDFlag uint32 = 0x20000000
+)
+
+func encodeWKB(shape Shape, srid int) []byte {
+	buf := []byte{1}
+	typ := shape.wkbType()
+	if srid != 0 {
+		buf = appendUint32(buf, typ|ewkbSRIDFlag)
+		buf = appendUint32(buf, uint32(srid))
+	} else {
+		buf = appendUint32(buf, typ)
+	}
+
+	switch s := shape.(type) {
+	case Point:
+		return appendPoint(buf, s)
+	case LineString:
+		return appendPoints(buf, s)
+	case Polygon:
+		return appendRings(buf, s)
+	case MultiPoint:
+		buf = appendUint32(buf, uint32(len(s)))
+		for _, point := range s {
+			buf = append(buf, encodeWKB(point, 0)...)
+		}
+	case MultiLineString:
+		buf = appendUint32(buf, uint32(len(s)))
+		for _, line := range s {
+			buf = append(buf, encodeWKB(line, 0)...)
+		}
+	case MultiPolygon:
+		buf = appendUint32(buf, uint32(len(s)))
+		for _, polygon := range s {
+			buf = append(buf, encodeWKB(polygon, 0)...)
+		}
+	}
+	return buf
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendPoint(buf []byte, p Point) []byte {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], math.Float64bits(p.X))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(p.Y))
+	return append(buf, b[:]...)
+}
+
+func appendPoints(buf []byte, points []Point) []byte {
+	buf = appendUint32(buf, uint32(len(points)))
+	for _, p := range points {
+		buf = appendPoint(buf, p)
+	}
+	return buf
+}
+
+func appendRings(buf []byte, rings []LineString) []byte {
+	buf = appendUint32(buf, uint32(len(rings)))
+	for _, ring := range rings {
+		buf = appendPoints(buf, ring)
+	}
+	return buf
+}
+
+// wkbReader decodes WKB and EWKB, keeping track of
+// the byte order of the geometry being decoded
+type wkbReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func decodeWKB(data []byte) (Geometry, error) {
+	r := &wkbReader{data: data}
+	shape, srid, err := r.readShape()
+	if err != nil {
+		return Geometry{}, fmt.Errorf("ksqlgeo: invalid WKB: %s", err)
+	}
+	if len(r.data) > 0 {
+		return Geometry{}, fmt.Errorf("ksqlgeo: invalid WKB: %d unexpected bytes after the geometry", len(r.data))
+	}
+	return Geometry{SRID: srid, Shape: shape}, nil
+}
+
+func (r *wkbReader) readShape() (shape Shape, srid int, err error) {
+	if len(r.data) < 1 {
+		return nil, 0, fmt.Errorf("unexpected end of data")
+	}
+	switch r.data[0] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid byte order %d", r.data[0])
+	}
+	r.data = r.data[1:]
+
+	typ, err := r.readUint32()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ&ewkbSRIDFlag != 0 {
+		v, err := r.readUint32()
+		if err != nil {
+			return nil, 0, err
+		}
+		srid = int(v)
+	}
+
+	// The ISO WKB adds 1000, 2000 or 3000 to the
+	// type of the shapes with Z, M or ZM coordinates:
+	if typ&(ewkbZFlag|ewkbMFlag) != 0 || typ&0x0FFFFFFF > 1000 {
+		return nil, 0, fmt.Errorf("only 2D geometries are supported")
+	}
+
+	switch typ & 0x0FFFFFFF {
+	case pointType:
+		shape, err = r.readPoint()
+	case lineStringType:
+		shape, err = r.readPoints()
+	case polygonType:
+		shape, err = r.readRings()
+	case multiPointType:
+		var multi MultiPoint
+		err = r.readMulti(func(s Shape) bool {
+			p, ok := s.(Point)
+			multi = append(multi, p)
+			return ok
+		})
+		shape = multi
+	case multiLineStringType:
+		var multi MultiLineString
+		err = r.readMulti(func(s Shape) bool {
+			line, ok := s.(LineString)
+			multi = append(multi, line)
+			return ok
+		})
+		shape = multi
+	case multiPolygonType:
+		var multi MultiPolygon
+		err = r.readMulti(func(s Shape) bool {
+			polygon, ok := s.(Polygon)
+			multi = append(multi, polygon)
+			return ok
+		})
+		shape = multi
+	default:
+		return nil, 0, fmt.Errorf("unsupported geometry type %d", typ&0x0FFFFFFF)
+	}
+
+	return shape, srid, err
+}
+
+func (r *wkbReader) readUint32() (uint32, error) {
+	if len(r.data) < 4 {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	v := r.order.Uint32(r.data)
+	r.data = r.data[4:]
+	return v, nil
+}
+
+// readCount reads the number of elements of a shape, checking
+// it against the remaining data so that corrupted values
+// can't cause huge allocations
+func (r *wkbReader) readCount(minElemSize int) (int, error) {
+	n, err := r.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	if int64(n)*int64(minElemSize) > int64(len(r.data)) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	return int(n), nil
+}
+
+func (r *wkbReader) readPoint() (Point, error) {
+	if len(r.data) < 16 {
+		return Point{}, fmt.Errorf("unexpected end of data")
+	}
+	p := Point{
+		X: math.Float64frombits(r.order.Uint64(r.data)),
+		Y: math.Float64frombits(r.order.Uint64(r.data[8:])),
+	}
+	r.data = r.data[16:]
+	return p, nil
+}
+
+func (r *wkbReader) readPoints() (LineString, error) {
+	n, err := r.readCount(16)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(LineString, n)
+	for i := range points {
+		points[i], err = r.readPoint()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (r *wkbReader) readRings() (Polygon, error) {
+	n, err := r.readCount(4)
+	if err != nil {
+		return nil, err
+	}
+
+	rings := make(Polygon, n)
+	for i := range rings {
+		rings[i], err = r.readPoints()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rings, nil
+}
+
+// readMulti reads the shapes of a multi shape, each with its own
+// header, and calls add for each of them, which returns false if
+// the shape has the wrong type
+func (r *wkbReader) readMulti(add func(Shape) bool) error {
+	n, err := r.readCount(5)
+	if err != nil {
+		return err
+	}
+
+	order := r.order
+	for i := 0; i < n; i++ {
+		shape, _, err := r.readShape()
+		if err != nil {
+			return err
+		}
+		if !add(shape) {
+			return fmt.Errorf("unexpected %T inside a multi geometry", shape)
+		}
+	}
+	r.order = order
+	return nil
+}
@@ -0,0 +1,284 @@
+package ksqlgeo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func formatWKT(shape Shape) string {
+	switch s := shape.(type) {
+	case Point:
+		return "POINT(" + formatPoint(s) + ")"
+	case LineString:
+		return "LINESTRING" + formatPoints(s)
+	case Polygon:
+		return "POLYGON" + formatRings(s)
+	case MultiPoint:
+		if len(s) == 0 {
+			return "MULTIPOINT EMPTY"
+		}
+		points := make([]string, len(s))
+		for i, p := range s {
+			points[i] = "(" + formatPoint(p) + ")"
+		}
+		return "MULTIPOINT(" + strings.Join(points, ",") + ")"
+	case MultiLineString:
+		return "MULTILINESTRING" + formatRings(s)
+	case MultiPolygon:
+		if len(s) == 0 {
+			return "MULTIPOLYGON EMPTY"
+		}
+		polygons := make([]string, len(s))
+		for i, polygon := range s {
+			polygons[i] = formatRings(polygon)
+		}
+		return "MULTIPOLYGON(" + strings.Join(polygons, ",") + ")"
+	}
+	return ""
+}
+
+func formatPoint(p Point) string {
+	return strconv.FormatFloat(p.X, 'f', -1, 64) + " " + strconv.FormatFloat(p.Y, 'f', -1, 64)
+}
+
+func formatPoints(points []Point) string {
+	if len(points) == 0 {
+		return " EMPTY"
+	}
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = formatPoint(p)
+	}
+	return "(" + strings.Join(coords, ",") + ")"
+}
+
+func formatRings(rings []LineString) string {
+	if len(rings) == 0 {
+		return " EMPTY"
+	}
+	formatted := make([]string, len(rings))
+	for i, ring := range rings {
+		formatted[i] = formatPoints(ring)
+	}
+	return "(" + strings.Join(formatted, ",") + ")"
+}
+
+// wktParser is a recursive descent parser for the
+// 2D shapes written as WKT, e.g. `POINT(1 2)`
+type wktParser struct {
+	input string
+	pos   int
+}
+
+func parseWKT(wkt string) (Geometry, error) {
+	var g Geometry
+	str := strings.TrimSpace(wkt)
+	if strings.HasPrefix(strings.ToUpper(str), "SRID=") {
+		i := strings.IndexByte(str, ';')
+		if i == -1 {
+			return Geometry{}, fmt.Errorf("ksqlgeo: invalid EWKT '%s': missing ';' after the SRID", wkt)
+		}
+
+		srid, err := strconv.Atoi(str[len("SRID="):i])
+		if err != nil {
+			return Geometry{}, fmt.Errorf("ksqlgeo: invalid EWKT '%s': invalid SRID", wkt)
+		}
+		g.SRID = srid
+		str = str[i+1:]
+	}
+
+	p := &wktParser{input: str}
+	shape, err := p.parseShape()
+	if err == nil && p.skipSpaces() < len(p.input) {
+		err = fmt.Errorf("unexpected '%s' after the geometry", p.input[p.pos:])
+	}
+	if err != nil {
+		return Geometry{}, fmt.Errorf("ksqlgeo: invalid WKT '%s': %s", wkt, err)
+	}
+
+	g.Shape = shape
+	return g, nil
+}
+
+func (p *wktParser) skipSpaces() int {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.pos]) != -1 {
+		p.pos++
+	}
+	return p.pos
+}
+
+// word reads the next sequence of letters, in upper case
+func (p *wktParser) word() string {
+	start := p.skipSpaces()
+	for p.pos < len(p.input) {
+		c := p.input[p.pos] | 0x20
+		if c < 'a' || c > 'z' {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToUpper(p.input[start:p.pos])
+}
+
+// consume skips the input char if it is the next one
+func (p *wktParser) consume(c byte) bool {
+	if p.skipSpaces() < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *wktParser) expect(c byte) error {
+	if !p.consume(c) {
+		return fmt.Errorf("expected '%c' at position %d", c, p.pos)
+	}
+	return nil
+}
+
+// isEmpty checks if the shape is declared as EMPTY, otherwise
+// it reads the opening parenthesis of the shape
+func (p *wktParser) isEmpty() (bool, error) {
+	start := p.pos
+	if p.word() == "EMPTY" {
+		return true, nil
+	}
+	p.pos = start
+	return false, p.expect('(')
+}
+
+func (p *wktParser) parseShape() (Shape, error) {
+	typ := p.word()
+
+	start := p.pos
+	if dims := p.word(); dims != "" {
+		if dims == "Z" || dims == "M" || dims == "ZM" {
+			return nil, fmt.Errorf("only 2D geometries are supported")
+		}
+		p.pos = start
+	}
+
+	switch typ {
+	case "POINT":
+		empty, err := p.isEmpty()
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			return nil, fmt.Errorf("empty points are not supported")
+		}
+		point, err := p.parsePoint()
+		if err != nil {
+			return nil, err
+		}
+		return point, p.expect(')')
+	case "LINESTRING":
+		return p.parsePoints()
+	case "POLYGON":
+		rings, err := p.parseList(func() (interface{}, error) { return p.parsePoints() })
+		polygon := make(Polygon, len(rings))
+		for i, ring := range rings {
+			polygon[i] = ring.(LineString)
+		}
+		return polygon, err
+	case "MULTIPOINT":
+		points, err := p.parseList(func() (interface{}, error) {
+			// The points might be inside parentheses or not:
+			if !p.consume('(') {
+				return p.parsePoint()
+			}
+			point, err := p.parsePoint()
+			if err != nil {
+				return nil, err
+			}
+			return point, p.expect(')')
+		})
+		multi := make(MultiPoint, len(points))
+		for i, point := range points {
+			multi[i] = point.(Point)
+		}
+		return multi, err
+	case "MULTILINESTRING":
+		lines, err := p.parseList(func() (interface{}, error) { return p.parsePoints() })
+		multi := make(MultiLineString, len(lines))
+		for i, line := range lines {
+			multi[i] = line.(LineString)
+		}
+		return multi, err
+	case "MULTIPOLYGON":
+		polygons, err := p.parseList(func() (interface{}, error) {
+			rings, err := p.parseList(func() (interface{}, error) { return p.parsePoints() })
+			polygon := make(Polygon, len(rings))
+			for i, ring := range rings {
+				polygon[i] = ring.(LineString)
+			}
+			return polygon, err
+		})
+		multi := make(MultiPolygon, len(polygons))
+		for i, polygon := range polygons {
+			multi[i] = polygon.(Polygon)
+		}
+		return multi, err
+	case "":
+		return nil, fmt.Errorf("missing the geometry type")
+	}
+
+	return nil, fmt.Errorf("unsupported geometry type %s", typ)
+}
+
+// parseList parses a comma separated list of elements inside
+// parentheses, or the EMPTY keyword for empty lists
+func (p *wktParser) parseList(parseElem func() (interface{}, error)) ([]interface{}, error) {
+	empty, err := p.isEmpty()
+	if empty || err != nil {
+		return nil, err
+	}
+
+	var elems []interface{}
+	for {
+		elem, err := parseElem()
+		if err != nil {
+			return elems, err
+		}
+		elems = append(elems, elem)
+
+		if !p.consume(',') {
+			return elems, p.expect(')')
+		}
+	}
+}
+
+func (p *wktParser) parsePoints() (LineString, error) {
+	elems, err := p.parseList(func() (interface{}, error) { return p.parsePoint() })
+	points := make(LineString, len(elems))
+	for i, elem := range elems {
+		points[i] = elem.(Point)
+	}
+	return points, err
+}
+
+func (p *wktParser) parsePoint() (Point, error) {
+	x, err := p.parseNumber()
+	if err != nil {
+		return Point{}, err
+	}
+	y, err := p.parseNumber()
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{X: x, Y: y}, nil
+}
+
+func (p *wktParser) parseNumber() (float64, error) {
+	start := p.skipSpaces()
+	for p.pos < len(p.input) && strings.IndexByte("0123456789+-.eE", p.input[p.pos]) != -1 {
+		p.pos++
+	}
+
+	f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number at position %d", start)
+	}
+	return f, nil
+}
@@ -0,0 +1,46 @@
+// Package ksqlmodifiers contains the types used for creating custom
+// attribute modifiers, i.e. the options that can be added to the
+// `ksql` tags after the column name, e.g. `ksql:"tags,csv"`.
+//
+// New modifiers should be registered using `ksql.RegisterModifier()`.
+package ksqlmodifiers
+
+import "context"
+
+// AttrModifier describes how KSQL should handle an attribute
+// tagged with the modifier when reading it from the database
+// and when writing it to the database.
+//
+// All the attributes are optional.
+type AttrModifier struct {
+	// SkipOnInsert and SkipOnUpdate make KSQL ignore the attribute
+	// on Insert and on Update/Patch operations respectively.
+	SkipOnInsert bool
+	SkipOnUpdate bool
+
+	// Scan is called with a pointer to the attribute and the value
+	// read from the database, and it should decode this value
+	// into the attribute, like a `sql.Scanner` would.
+	Scan AttrScanner
+
+	// Value is called with the value of the attribute before
+	// it is sent to the database, and it should return the
+	// value that will actually be written, like a `driver.Valuer` would.
+	Value AttrValuer
+}
+
+// AttrScanner is the signature of the AttrModifier.Scan function
+type AttrScanner func(ctx context.Context, opInfo OpInfo, attrPtr interface{}, dbValue interface{}) error
+
+// AttrValuer is the signature of the AttrModifier.Value function
+type AttrValuer func(ctx context.Context, opInfo OpInfo, inputValue interface{}) (outputValue interface{}, err error)
+
+// OpInfo contains information about the operation
+// that is using the modifier.
+type OpInfo struct {
+	// DriverName is the name of the driver being used, e.g. "postgres"
+	DriverName string
+
+	// Method is either "Insert", "Update" or "Query"
+	Method string
+}
@@ -0,0 +1,253 @@
+// Package containers starts disposable databases for integration tests
+// using testcontainers-go, so that the same test can run against each of
+// the databases supported by ksql with a single line of setup, e.g.:
+//
+//	func TestUsersRepository(t *testing.T) {
+//		for _, dialect := range []string{"postgres", "mysql", "sqlserver", "sqlite3"} {
+//			t.Run(dialect, func(t *testing.T) {
+//				db := containers.NewDB(t, dialect, containers.Config{
+//					Schema: schemas[dialect],
+//				})
+//
+//				// ... test the repository using db ...
+//			})
+//		}
+//	}
+//
+// A Docker daemon is required for all dialects but sqlite3,
+// which uses a temporary file instead of a container.
+package containers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vingarcia/ksql"
+	"github.com/vingarcia/ksql/adapters/kmysql"
+	"github.com/vingarcia/ksql/adapters/kpgx"
+	"github.com/vingarcia/ksql/adapters/ksqlite3"
+	"github.com/vingarcia/ksql/adapters/ksqlserver"
+)
+
+// Config contains the optional configurations for starting a database
+type Config struct {
+	// Image overrides the default Docker image of the dialect,
+	// e.g. "postgres:16" or "mariadb:10.8" for the mysql dialect.
+	Image string
+
+	// Schema is executed as a single statement once the database is
+	// ready, so it can contain several statements separated by `;`.
+	Schema string
+
+	// StartupTimeout is the maximum time waiting for the database
+	// to accept connections, it defaults to 1 minute.
+	StartupTimeout time.Duration
+
+	// KSQLConfig is used for instantiating the ksql.DB
+	KSQLConfig ksql.Config
+}
+
+// T is the subset of the testing.TB interface used by NewDB
+type T interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// NewDB starts a database for the input dialect and returns a ksql.DB
+// connected to it, the test fails if the database can't be started and
+// the database is removed when the test ends.
+//
+// The supported dialects are "postgres", "mysql", "sqlserver" and "sqlite3".
+func NewDB(t T, dialect string, config Config) ksql.DB {
+	t.Helper()
+
+	db, closer, err := New(context.Background(), dialect, config)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	t.Cleanup(func() {
+		t.Helper()
+		if err := closer(); err != nil {
+			t.Fatalf("%s", err)
+		}
+	})
+
+	return db
+}
+
+// New starts a database for the input dialect and returns a ksql.DB
+// connected to it along with a function that closes the connection
+// and removes the database, which must be called by the caller.
+//
+// It is meant to be used on a TestMain function, for sharing the same
+// database between several tests, otherwise the NewDB function is simpler.
+func New(ctx context.Context, dialect string, config Config) (_ ksql.DB, closer func() error, err error) {
+	if config.StartupTimeout == 0 {
+		config.StartupTimeout = time.Minute
+	}
+
+	var db ksql.DB
+	switch dialect {
+	case "sqlite3":
+		db, closer, err = startSQLite(ctx, config)
+	case "postgres", "mysql", "sqlserver":
+		db, closer, err = startContainer(ctx, dialects[dialect], config)
+	default:
+		return ksql.DB{}, nil, fmt.Errorf("containers: unsupported dialect '%s', expected one of: postgres, mysql, sqlserver or sqlite3", dialect)
+	}
+	if err != nil {
+		return ksql.DB{}, nil, err
+	}
+
+	if config.Schema != "" {
+		_, err = db.Exec(ctx, config.Schema)
+		if err != nil {
+			closer()
+			return ksql.DB{}, nil, fmt.Errorf("containers: error applying the schema on %s: %s", dialect, err)
+		}
+	}
+
+	return db, closer, nil
+}
+
+type dialectContainer struct {
+	image string
+	port  nat.Port
+	env   map[string]string
+
+	// url builds the connection string from
+	// the host and port of the container
+	url func(hostAndPort string) string
+
+	connect func(ctx context.Context, url string, config ksql.Config) (ksql.DB, error)
+}
+
+var dialects = map[string]dialectContainer{
+	"postgres": {
+		image: "postgres:14.0",
+		port:  "5432/tcp",
+		env: map[string]string{
+			"POSTGRES_PASSWORD": "postgres",
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_DB":       "ksql",
+		},
+		url: func(hostAndPort string) string {
+			return fmt.Sprintf("postgres://postgres:postgres@%s/ksql?sslmode=disable", hostAndPort)
+		},
+		connect: kpgx.New,
+	},
+	"mysql": {
+		image: "mysql:8.0",
+		port:  "3306/tcp",
+		env: map[string]string{
+			"MYSQL_ROOT_PASSWORD": "mysql",
+			"MYSQL_DATABASE":      "ksql",
+		},
+		url: func(hostAndPort string) string {
+			// multiStatements is required for applying the schema with a single Exec:
+			return fmt.Sprintf("root:mysql@(%s)/ksql?timeout=30s&parseTime=true&multiStatements=true", hostAndPort)
+		},
+		connect: kmysql.New,
+	},
+	"sqlserver": {
+		image: "mcr.microsoft.com/mssql/server:2022-latest",
+		port:  "1433/tcp",
+		env: map[string]string{
+			"SA_PASSWORD": "Sqls3rv3r",
+			"ACCEPT_EULA": "Y",
+		},
+		url: func(hostAndPort string) string {
+			return fmt.Sprintf("sqlserver://sa:Sqls3rv3r@%s?databaseName=master", hostAndPort)
+		},
+		connect: ksqlserver.New,
+	},
+}
+
+func startContainer(ctx context.Context, d dialectContainer, config Config) (_ ksql.DB, closer func() error, err error) {
+	image := d.image
+	if config.Image != "" {
+		image = config.Image
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        image,
+			ExposedPorts: []string{string(d.port)},
+			Env:          d.env,
+			WaitingFor:   wait.ForListeningPort(d.port).WithStartupTimeout(config.StartupTimeout),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return ksql.DB{}, nil, fmt.Errorf("containers: unable to start the container for image '%s': %s", image, err)
+	}
+	terminate := func() error {
+		return container.Terminate(context.Background())
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		terminate()
+		return ksql.DB{}, nil, fmt.Errorf("containers: unable to get the host of the container: %s", err)
+	}
+
+	port, err := container.MappedPort(ctx, d.port)
+	if err != nil {
+		terminate()
+		return ksql.DB{}, nil, fmt.Errorf("containers: unable to get the port of the container: %s", err)
+	}
+
+	url := d.url(fmt.Sprintf("%s:%s", host, port.Port()))
+
+	// The port might be open before the database is ready to accept connections,
+	// e.g. postgres restarts after running its initialization scripts:
+	var db ksql.DB
+	deadline := time.Now().Add(config.StartupTimeout)
+	for {
+		db, err = d.connect(ctx, url, config.KSQLConfig)
+		if err == nil {
+			_, err = db.Exec(ctx, "SELECT 1")
+			if err == nil {
+				break
+			}
+			db.Close()
+		}
+
+		if time.Now().After(deadline) {
+			terminate()
+			return ksql.DB{}, nil, fmt.Errorf("containers: the database on image '%s' was not ready after %s: %s", image, config.StartupTimeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return db, func() error {
+		db.Close()
+		return terminate()
+	}, nil
+}
+
+func startSQLite(ctx context.Context, config Config) (_ ksql.DB, closer func() error, err error) {
+	dir, err := ioutil.TempDir("", "ksql-containers")
+	if err != nil {
+		return ksql.DB{}, nil, fmt.Errorf("containers: unable to create the directory for the sqlite3 database: %s", err)
+	}
+
+	db, err := ksqlite3.New(ctx, filepath.Join(dir, "ksql.db"), config.KSQLConfig)
+	if err != nil {
+		os.RemoveAll(dir)
+		return ksql.DB{}, nil, fmt.Errorf("containers: unable to open the sqlite3 database: %s", err)
+	}
+
+	return db, func() error {
+		db.Close()
+		return os.RemoveAll(dir)
+	}, nil
+}
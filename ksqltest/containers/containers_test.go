@@ -0,0 +1,59 @@
+package containers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vingarcia/ksql"
+)
+
+type user struct {
+	ID   int    `ksql:"id"`
+	Name string `ksql:"name"`
+}
+
+var schemas = map[string]string{
+	"sqlite3":   `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`,
+	"postgres":  `CREATE TABLE users (id serial PRIMARY KEY, name TEXT)`,
+	"mysql":     `CREATE TABLE users (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(50))`,
+	"sqlserver": `CREATE TABLE users (id INT IDENTITY(1,1) PRIMARY KEY, name VARCHAR(50))`,
+}
+
+func TestNewDB(t *testing.T) {
+	ctx := context.Background()
+	usersTable := ksql.NewTable("users")
+
+	for _, dialect := range []string{"sqlite3", "postgres", "mysql", "sqlserver"} {
+		t.Run(dialect, func(t *testing.T) {
+			if dialect != "sqlite3" && testing.Short() {
+				t.Skip("skipping the tests that require Docker in short mode")
+			}
+
+			db := NewDB(t, dialect, Config{
+				Schema: schemas[dialect],
+			})
+
+			u := user{Name: "Ana"}
+			err := db.Insert(ctx, usersTable, &u)
+			if err != nil {
+				t.Fatalf("unexpected error inserting the user: %s", err)
+			}
+
+			var users []user
+			err = db.Query(ctx, &users, "FROM users")
+			if err != nil {
+				t.Fatalf("unexpected error querying the users: %s", err)
+			}
+			if len(users) != 1 || users[0] != u {
+				t.Fatalf("expected to find the user %v, but got: %v", u, users)
+			}
+		})
+	}
+
+	t.Run("should report unsupported dialects", func(t *testing.T) {
+		_, _, err := New(ctx, "oracle", Config{})
+		if err == nil {
+			t.Fatal("expected an error for the oracle dialect")
+		}
+	})
+}
@@ -62,8 +62,8 @@ func FillStructWith(record interface{}, dbRow map[string]interface{}) error {
 		}
 
 		src := structs.NewPtrConverter(rawSrc)
-		dest := v.Field(fieldInfo.Index)
-		destType := t.Field(fieldInfo.Index).Type
+		dest := v.FieldByIndex(fieldInfo.Path)
+		destType := dest.Type()
 
 		destValue, err := src.Convert(destType)
 		if err != nil {
@@ -112,6 +112,62 @@ func TestStructToMap(t *testing.T) {
 
 		tt.AssertNotEqual(t, err, nil)
 	})
+
+	t.Run("should flatten embedded structs and structs with the flatten modifier", func(t *testing.T) {
+		type Address struct {
+			Street string `ksql:"street"`
+			City   string `ksql:"city"`
+		}
+		type Timestamps struct {
+			CreatedAt string `ksql:"created_at"`
+		}
+		m, err := StructToMap(struct {
+			Timestamps
+			Name    string  `ksql:"name"`
+			Home    Address `ksql:"home_,flatten"`
+			Work    Address `ksql:"work_,flatten"`
+			Ignored Address
+		}{
+			Timestamps: Timestamps{CreatedAt: "fake-date"},
+			Name:       "fake-name",
+			Home:       Address{Street: "home-street", City: "home-city"},
+			Work:       Address{Street: "work-street", City: "work-city"},
+		})
+
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, m, map[string]interface{}{
+			"created_at":  "fake-date",
+			"name":        "fake-name",
+			"home_street": "home-street",
+			"home_city":   "home-city",
+			"work_street": "work-street",
+			"work_city":   "work-city",
+		})
+	})
+
+	t.Run("should return error for name conflicts between flattened attributes", func(t *testing.T) {
+		type Address struct {
+			Street string `ksql:"street"`
+		}
+		_, err := StructToMap(struct {
+			Street string  `ksql:"street"`
+			Home   Address `ksql:",flatten"`
+		}{})
+
+		tt.AssertErrContains(t, err, "multiple attributes", "street")
+	})
+
+	t.Run("should return error when flattening attributes that are not structs", func(t *testing.T) {
+		type Address struct {
+			Street string `ksql:"street"`
+		}
+		_, err := StructToMap(struct {
+			Name string   `ksql:"name"`
+			Home *Address `ksql:"home_,flatten"`
+		}{})
+
+		tt.AssertErrContains(t, err, "only struct attributes can be flattened", "Home")
+	})
 }
 
 func TestFillStructWith(t *testing.T) {
@@ -130,6 +186,24 @@ func TestFillStructWith(t *testing.T) {
 		tt.AssertEqual(t, user.Age, 22)
 	})
 
+	t.Run("should fill the attributes of flattened structs", func(t *testing.T) {
+		type Address struct {
+			Street string `ksql:"street"`
+		}
+		var user struct {
+			Name string  `ksql:"name"`
+			Home Address `ksql:"home_,flatten"`
+		}
+		err := FillStructWith(&user, map[string]interface{}{
+			"name":        "Breno",
+			"home_street": "Av. Brasil",
+		})
+
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, user.Name, "Breno")
+		tt.AssertEqual(t, user.Home.Street, "Av. Brasil")
+	})
+
 	t.Run("should fill ptr fields with ptr values", func(t *testing.T) {
 		var user struct {
 			Name *string `ksql:"name"`
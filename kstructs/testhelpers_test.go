@@ -69,6 +69,26 @@ func TestStructToMap(t *testing.T) {
 		tt.AssertEqual(t, m, map[string]interface{}{})
 	})
 
+	t.Run("should ignore zero value attrs with the omitempty option", func(t *testing.T) {
+		type S3 struct {
+			Name   string `ksql:"name,omitempty"`
+			Age    int    `ksql:"age,omitempty"`
+			Active bool   `ksql:"active"`
+		}
+
+		m, err := StructToMap(S3{
+			Name:   "my name",
+			Age:    0,
+			Active: false,
+		})
+
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, m, map[string]interface{}{
+			"name":   "my name",
+			"active": false,
+		})
+	})
+
 	t.Run("should ignore fields not tagged with ksql", func(t *testing.T) {
 		m, err := StructToMap(struct {
 			Name              string `ksql:"name_attr"`
@@ -0,0 +1,32 @@
+// Package kvalidator contains a ksql.Config.Validator that validates
+// the records using the `validate` struct tags of the
+// github.com/go-playground/validator package.
+package kvalidator
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// New returns a function that can be used as the ksql.Config.Validator,
+// if the input validator is nil a new one is created, e.g.:
+//
+//	db, err := kpgx.New(ctx, connStr, ksql.Config{
+//		Validator: kvalidator.New(nil),
+//	})
+//
+// The errors returned by the validator are wrapped in a ksql.ErrValidation,
+// so the validator.ValidationErrors can still be retrieved with `errors.As()`.
+//
+// Note that on partial updates with Patch the nil pointer attributes
+// are not updated, so they should be tagged with `omitempty`.
+func New(v *validator.Validate) func(ctx context.Context, record interface{}) error {
+	if v == nil {
+		v = validator.New(validator.WithRequiredStructEnabled())
+	}
+
+	return func(ctx context.Context, record interface{}) error {
+		return v.StructCtx(ctx, record)
+	}
+}
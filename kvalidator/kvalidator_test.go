@@ -0,0 +1,64 @@
+package kvalidator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/vingarcia/ksql"
+)
+
+type user struct {
+	ID    int    `ksql:"id"`
+	Name  string `ksql:"name" validate:"required"`
+	Email string `ksql:"email" validate:"required,email"`
+}
+
+type mockAdapter struct {
+	statements int
+}
+
+func (m *mockAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (ksql.Result, error) {
+	m.statements++
+	return ksql.NewMockResult(1, 1), nil
+}
+
+func (m *mockAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (ksql.Rows, error) {
+	m.statements++
+	return nil, errors.New("unexpected call to QueryContext")
+}
+
+func TestNew(t *testing.T) {
+	adapter := &mockAdapter{}
+	db, err := ksql.NewWithConfig(adapter, "mysql", ksql.Config{
+		Validator: New(nil),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	usersTable := ksql.NewTable("users")
+
+	err = db.Insert(context.Background(), usersTable, &user{Name: "Bia", Email: "not an email"})
+
+	var validationErr ksql.ErrValidation
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ksql.ErrValidation, but got: %v", err)
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) || fieldErrs[0].Field() != "Email" {
+		t.Fatalf("expected a validation error on the Email field, but got: %v", err)
+	}
+	if adapter.statements != 0 {
+		t.Fatalf("expected no statements to be sent, but got %d", adapter.statements)
+	}
+
+	err = db.Insert(context.Background(), usersTable, &user{Name: "Bia", Email: "bia@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if adapter.statements != 1 {
+		t.Fatalf("expected 1 statement to be sent, but got %d", adapter.statements)
+	}
+}
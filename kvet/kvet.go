@@ -0,0 +1,466 @@
+// Package kvet checks the queries passed to the ksql methods against the
+// schema of the database, so that typos on the names of the tables and
+// columns are caught before the code reaches production.
+//
+// It type checks the Go packages looking for calls to Query, QueryOne,
+// QueryMaps, QueryIter and Exec whose queries are constant strings, and
+// for each of them it verifies that:
+//
+//   - The tables used on the FROM, JOIN, UPDATE and INSERT INTO clauses exist
+//   - The columns referenced by the query exist on these tables
+//   - The columns selected by the query match the `ksql` tags of the
+//     destination struct, since the columns without a matching attribute
+//     are silently ignored by ksql
+//   - The attributes of the struct exist on the tables of queries
+//     starting with `FROM`, for which ksql builds the SELECT part
+//
+// The SQL is analyzed by a lightweight parser so only the names that can
+// be resolved unambiguously are checked, the parts it can't understand,
+// e.g. the columns of subqueries, are ignored instead of reported.
+//
+// The schema can be read from a dump with ParseSchema or from a live
+// database with LoadSchema, the latter can be used inside a test for
+// running the checks on CI, e.g.:
+//
+//	func TestQueries(t *testing.T) {
+//		schema, err := kvet.LoadSchema(ctx, db)
+//		...
+//		issues, err := kvet.Check(schema, "./...")
+//		...
+//		for _, issue := range issues {
+//			t.Error(issue)
+//		}
+//	}
+//
+// The `cmd/ksqlvet` command runs the same checks using a schema dump.
+package kvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const ksqlPackagePath = "github.com/vingarcia/ksql"
+
+// Issue describes a problem found on one of the queries
+type Issue struct {
+	Pos     token.Position
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Pos, i.Message)
+}
+
+// queryMethods maps the methods of ksql that receive queries
+// to the position of the query and destination arguments,
+// a negative position means the method has no destination.
+var queryMethods = map[string]struct {
+	queryArg int
+	destArg  int
+}{
+	"Query":     {queryArg: 2, destArg: 1},
+	"QueryOne":  {queryArg: 2, destArg: 1},
+	"QueryMaps": {queryArg: 2, destArg: -1},
+	"QueryIter": {queryArg: 1, destArg: -1},
+	"Exec":      {queryArg: 1, destArg: -1},
+}
+
+// Check analyzes the Go packages on the input directories, the
+// directories ending with `/...` are walked recursively, and
+// returns the issues found sorted by their positions.
+//
+// Only the non-test files of the packages are analyzed.
+func Check(schema Schema, dirs ...string) ([]Issue, error) {
+	var allDirs []string
+	for _, dir := range dirs {
+		if !strings.HasSuffix(dir, "...") {
+			allDirs = append(allDirs, dir)
+			continue
+		}
+
+		root := filepath.Clean(strings.TrimSuffix(dir, "..."))
+		err := filepath.Walk(root, func(path string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fileInfo.IsDir() {
+				return nil
+			}
+
+			name := fileInfo.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			allDirs = append(allDirs, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	var issues []Issue
+	for _, dir := range allDirs {
+		dirIssues, err := checkDir(fset, imp, schema, dir)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, dirIssues...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Pos.Filename != issues[j].Pos.Filename {
+			return issues[i].Pos.Filename < issues[j].Pos.Filename
+		}
+		return issues[i].Pos.Offset < issues[j].Pos.Offset
+	})
+
+	return issues, nil
+}
+
+func checkDir(fset *token.FileSet, imp types.Importer, schema Schema, dir string) ([]Issue, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if _, ok := err.(*build.NoGoError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, filename := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, filename), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Uses:  map[*ast.Ident]types.Object{},
+	}
+	conf := types.Config{
+		Importer: imp,
+		// The type errors are ignored so that the packages
+		// are checked even if some of their imports can't be
+		// resolved, in which case the calls are just skipped.
+		Error: func(error) {},
+	}
+	_, _ = conf.Check(pkg.ImportPath, fset, files, info)
+
+	var issues []Issue
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			query, dest, ok := getQueryCall(info, call)
+			if !ok {
+				return true
+			}
+
+			for _, msg := range checkQuery(schema, query, dest) {
+				issues = append(issues, Issue{
+					Pos:     fset.Position(call.Pos()),
+					Message: msg,
+				})
+			}
+			return true
+		})
+	}
+
+	return issues, nil
+}
+
+// getQueryCall returns the query and the destination type of
+// the calls to the ksql methods with a constant query.
+func getQueryCall(info *types.Info, call *ast.CallExpr) (query string, dest types.Type, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, false
+	}
+
+	method, found := queryMethods[sel.Sel.Name]
+	if !found || len(call.Args) <= method.queryArg {
+		return "", nil, false
+	}
+
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != ksqlPackagePath {
+		return "", nil, false
+	}
+
+	value := info.Types[call.Args[method.queryArg]].Value
+	if value == nil || value.Kind() != constant.String {
+		return "", nil, false
+	}
+
+	if method.destArg >= 0 {
+		dest = info.TypeOf(call.Args[method.destArg])
+	}
+
+	return constant.StringVal(value), dest, true
+}
+
+// checkQuery returns the messages describing the problems of the query,
+// the dest is the type of the destination argument of the call or nil.
+func checkQuery(schema Schema, query string, dest types.Type) []string {
+	info := parseQuery(query)
+
+	var msgs []string
+	tablesByAlias := map[string]map[string]bool{}
+	var sourceTables []map[string]bool
+	allSourcesKnown := true
+	for _, source := range info.sources {
+		if source.table == "" {
+			allSourcesKnown = false
+			tablesByAlias[source.alias] = nil
+			continue
+		}
+
+		columns, found := schema.columns(source.table)
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("table %q not found on the schema", source.table))
+			allSourcesKnown = false
+		}
+		tablesByAlias[source.alias] = columns
+		sourceTables = append(sourceTables, columns)
+	}
+
+	for _, ref := range info.qualifiedRefs {
+		columns, found := tablesByAlias[ref.qualifier]
+		if !found || columns == nil || ref.column == "*" {
+			continue
+		}
+		if !columns[ref.column] {
+			msgs = append(msgs, fmt.Sprintf("column %q not found on table %q", ref.column, tableNameOf(info, ref.qualifier)))
+		}
+	}
+
+	if allSourcesKnown && len(sourceTables) > 0 {
+		for _, ref := range info.unqualifiedRefs {
+			if !anyTableHasColumn(sourceTables, ref) {
+				msgs = append(msgs, fmt.Sprintf("column %q not found on %s", ref, describeSources(info)))
+			}
+		}
+	}
+
+	structType, structName := getDestStruct(dest)
+	if structType == nil {
+		return msgs
+	}
+
+	switch info.kind {
+	case "select":
+		attrs, isNested := getStructColumns(structType)
+		if isNested {
+			break
+		}
+		for _, output := range info.outputs {
+			if output == "*" {
+				continue
+			}
+			if !attrs[output] {
+				msgs = append(msgs, fmt.Sprintf("the selected column %q has no matching `ksql` tag on %s and would be ignored", output, structName))
+			}
+		}
+
+	case "from":
+		if !allSourcesKnown {
+			break
+		}
+		msgs = append(msgs, checkStructAttributes(structType, structName, info, tablesByAlias, sourceTables)...)
+	}
+
+	return msgs
+}
+
+// checkStructAttributes verifies that the attributes of the struct exist on the
+// tables of the query, for the queries where the SELECT part is built by ksql.
+func checkStructAttributes(
+	structType *types.Struct,
+	structName string,
+	info queryInfo,
+	tablesByAlias map[string]map[string]bool,
+	sourceTables []map[string]bool,
+) (msgs []string) {
+	attrs, isNested := getStructColumns(structType)
+	if !isNested {
+		for _, attr := range sortedKeys(attrs) {
+			if !anyTableHasColumn(sourceTables, attr) {
+				msgs = append(msgs, fmt.Sprintf("the column %q of %s not found on %s", attr, structName, describeSources(info)))
+			}
+		}
+		return msgs
+	}
+
+	for i := 0; i < structType.NumFields(); i++ {
+		tableName, found := reflect.StructTag(structType.Tag(i)).Lookup("tablename")
+		if !found {
+			continue
+		}
+		tableName = strings.ToLower(tableName)
+
+		columns, found := tablesByAlias[tableName]
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("the table %q of %s.%s is not used on the query", tableName, structName, structType.Field(i).Name()))
+			continue
+		}
+
+		nestedStruct, _ := derefStruct(structType.Field(i).Type())
+		if nestedStruct == nil {
+			continue
+		}
+		nestedAttrs, _ := getStructColumns(nestedStruct)
+		for _, attr := range sortedKeys(nestedAttrs) {
+			if !columns[attr] {
+				msgs = append(msgs, fmt.Sprintf("the column %q of %s.%s not found on table %q", attr, structName, structType.Field(i).Name(), tableNameOf(info, tableName)))
+			}
+		}
+	}
+
+	return msgs
+}
+
+// getDestStruct returns the struct of the destination
+// of the query, e.g. `*[]User`, `*[]*User` or `*User`
+func getDestStruct(dest types.Type) (*types.Struct, string) {
+	ptr, ok := dest.(*types.Pointer)
+	if !ok {
+		return nil, ""
+	}
+
+	t := ptr.Elem()
+	if slice, ok := t.Underlying().(*types.Slice); ok {
+		t = slice.Elem()
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	// Structs that are scanned as a single value, e.g. time.Time
+	// or the ones implementing sql.Scanner, are not records:
+	methods := types.NewMethodSet(types.NewPointer(t))
+	if methods.Lookup(nil, "Scan") != nil {
+		return nil, ""
+	}
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" {
+		return nil, ""
+	}
+
+	structType, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, ""
+	}
+	return structType, types.TypeString(t, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+}
+
+func derefStruct(t types.Type) (*types.Struct, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	structType, ok := t.Underlying().(*types.Struct)
+	return structType, ok
+}
+
+// getStructColumns returns the column names of the `ksql`
+// tags of the struct including the flattened attributes,
+// and whether it is a nested struct.
+func getStructColumns(structType *types.Struct) (columns map[string]bool, isNested bool) {
+	columns = map[string]bool{}
+	for i := 0; i < structType.NumFields(); i++ {
+		tag := reflect.StructTag(structType.Tag(i))
+		if _, found := tag.Lookup("tablename"); found {
+			isNested = true
+			continue
+		}
+
+		name := tag.Get("ksql")
+		if name == "" {
+			continue
+		}
+
+		tags := strings.Split(name, ",")
+		if hasModifier(tags[1:], "flatten") {
+			if embedded, ok := derefStruct(structType.Field(i).Type()); ok {
+				embeddedColumns, _ := getStructColumns(embedded)
+				for column := range embeddedColumns {
+					columns[column] = true
+				}
+			}
+			continue
+		}
+
+		columns[strings.ToLower(tags[0])] = true
+	}
+
+	return columns, isNested
+}
+
+func hasModifier(modifiers []string, name string) bool {
+	for _, modifier := range modifiers {
+		if modifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+func anyTableHasColumn(tables []map[string]bool, column string) bool {
+	for _, columns := range tables {
+		if columns[column] {
+			return true
+		}
+	}
+	return false
+}
+
+func tableNameOf(info queryInfo, alias string) string {
+	for _, source := range info.sources {
+		if source.alias == alias {
+			return source.table
+		}
+	}
+	return alias
+}
+
+func describeSources(info queryInfo) string {
+	if len(info.sources) == 1 {
+		return fmt.Sprintf("table %q", info.sources[0].table)
+	}
+
+	var tables []string
+	for _, source := range info.sources {
+		tables = append(tables, fmt.Sprintf("%q", source.table))
+	}
+	return "tables " + strings.Join(tables, ", ")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
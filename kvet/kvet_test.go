@@ -0,0 +1,120 @@
+package kvet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestCheck(t *testing.T) {
+	ddl, err := ioutil.ReadFile("testdata/schema.sql")
+	tt.AssertNoErr(t, err)
+
+	schema, err := ParseSchema(string(ddl))
+	tt.AssertNoErr(t, err)
+
+	issues, err := Check(schema, "testdata/queries")
+	tt.AssertNoErr(t, err)
+
+	var got []string
+	for _, issue := range issues {
+		tt.AssertEqual(t, filepath.Base(issue.Pos.Filename), "queries.go")
+		got = append(got, issue.Message)
+	}
+
+	tt.AssertEqual(t, got, []string{
+		`column "nmae" not found on table "users"`,
+		`the selected column "nmae" has no matching ` + "`ksql`" + ` tag on queries.User and would be ignored`,
+		`the selected column "age" has no matching ` + "`ksql`" + ` tag on queries.User and would be ignored`,
+		`the column "titel" of queries.UserWithPosts.Post not found on table "posts"`,
+		`table "userz" not found on the schema`,
+		`column "nome" not found on table "users"`,
+	})
+}
+
+func TestCheckQuery(t *testing.T) {
+	schema := NewSchema(map[string][]string{
+		"users": {"id", "name", "age"},
+		"posts": {"id", "user_id", "title"},
+	})
+
+	tests := []struct {
+		desc         string
+		query        string
+		expectedMsgs []string
+	}{
+		{
+			desc:  "should accept valid queries",
+			query: `SELECT u.id, count(p.id) AS total FROM users u LEFT JOIN posts AS p ON p.user_id = u.id GROUP BY u.id`,
+		},
+		{
+			desc:  "should ignore literals, strings and comments",
+			query: "SELECT 1, true, 'x.y' -- u.foo\nFROM users /* u.bar */",
+		},
+		{
+			desc:  "should ignore the columns of subqueries and CTEs",
+			query: `WITH recent AS (SELECT * FROM posts) SELECT r.anything, s.other FROM recent r JOIN (SELECT 1 AS other) s ON true`,
+		},
+		{
+			desc:         "should check the tables inside subqueries",
+			query:        `SELECT id FROM users WHERE id IN (SELECT user_id FROM comments)`,
+			expectedMsgs: []string{`table "comments" not found on the schema`},
+		},
+		{
+			desc:         "should check qualified references",
+			query:        `SELECT u.id FROM users u WHERE u.email = $1`,
+			expectedMsgs: []string{`column "email" not found on table "users"`},
+		},
+		{
+			desc:         "should check the selected columns on all the tables of the query",
+			query:        `SELECT title, email FROM users, posts`,
+			expectedMsgs: []string{`column "email" not found on tables "users", "posts"`},
+		},
+		{
+			desc:         "should check the columns of updates",
+			query:        `UPDATE users SET name = ?, email = ? WHERE id = ?`,
+			expectedMsgs: []string{`column "email" not found on table "users"`},
+		},
+		{
+			desc:  "should not confuse upserts and locks with updates",
+			query: `INSERT INTO users (id, name) VALUES (1, 'a') ON CONFLICT (id) DO UPDATE SET name = excluded.name`,
+		},
+		{
+			desc:  "should accept quoted identifiers",
+			query: "SELECT \"Name\", `age`, [id] FROM \"USERS\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			msgs := checkQuery(schema, test.query, nil)
+			tt.AssertEqual(t, msgs, test.expectedMsgs)
+		})
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	t.Run("should parse the tables and columns of the dump", func(t *testing.T) {
+		ddl, err := ioutil.ReadFile("testdata/schema.sql")
+		tt.AssertNoErr(t, err)
+
+		schema, err := ParseSchema(string(ddl))
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, schema.Tables(), []string{"posts", "public.users"})
+
+		columns, found := schema.columns("posts")
+		tt.AssertEqual(t, found, true)
+		tt.AssertEqual(t, sortedKeys(columns), []string{"author_id", "deleted_at", "id", "title"})
+
+		columns, found = schema.columns("users")
+		tt.AssertEqual(t, found, true)
+		tt.AssertEqual(t, sortedKeys(columns), []string{"age", "id", "name"})
+	})
+
+	t.Run("should report schemas without tables", func(t *testing.T) {
+		_, err := ParseSchema("CREATE INDEX foo ON bar (id);")
+		tt.AssertErrContains(t, err, "no CREATE TABLE")
+	})
+}
@@ -0,0 +1,247 @@
+package kvet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vingarcia/ksql"
+)
+
+// Schema contains the columns of each table of a database,
+// all the names are compared in a case insensitive way.
+type Schema struct {
+	tables map[string]map[string]bool
+}
+
+// NewSchema creates a Schema from a map with the
+// table names as keys and their columns as values
+func NewSchema(tables map[string][]string) Schema {
+	schema := Schema{tables: map[string]map[string]bool{}}
+	for table, columns := range tables {
+		for _, column := range columns {
+			schema.addColumn(table, column)
+		}
+	}
+	return schema
+}
+
+func (s Schema) addColumn(table string, column string) {
+	table = strings.ToLower(table)
+	if s.tables[table] == nil {
+		s.tables[table] = map[string]bool{}
+	}
+	s.tables[table][strings.ToLower(column)] = true
+}
+
+// columns returns the columns of the table, the tables qualified
+// with the name of their schemas, e.g. `public.users`, are also
+// found by their unqualified names.
+func (s Schema) columns(table string) (map[string]bool, bool) {
+	name, found := s.lookup(table)
+	return s.tables[name], found
+}
+
+// lookup returns the name the table is stored with on the schema
+func (s Schema) lookup(table string) (string, bool) {
+	if _, found := s.tables[table]; found {
+		return table, true
+	}
+
+	parts := strings.Split(table, ".")
+	if _, found := s.tables[parts[len(parts)-1]]; found {
+		return parts[len(parts)-1], true
+	}
+
+	for name := range s.tables {
+		if strings.HasSuffix(name, "."+parts[len(parts)-1]) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// Tables returns the names of the tables of the schema in alphabetical order
+func (s Schema) Tables() []string {
+	tables := make([]string, 0, len(s.tables))
+	for table := range s.tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// ParseSchema reads the CREATE TABLE statements of a schema dump, such as
+// the ones generated by `pg_dump --schema-only`, `mysqldump --no-data`
+// or the `.schema` command of sqlite3, the other statements are ignored.
+//
+// Columns added with `ALTER TABLE ... ADD [COLUMN]` are also supported.
+func ParseSchema(ddl string) (Schema, error) {
+	schema := Schema{tables: map[string]map[string]bool{}}
+	tokens := tokenize(ddl)
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].depth != 0 || !tokens[i].isKeyword("create", "alter") {
+			continue
+		}
+
+		var err error
+		if tokens[i].text == "create" {
+			i, err = parseCreateTable(tokens, i+1, schema)
+		} else {
+			i, err = parseAlterTable(tokens, i+1, schema)
+		}
+		if err != nil {
+			return Schema{}, err
+		}
+	}
+
+	if len(schema.tables) == 0 {
+		return Schema{}, fmt.Errorf("kvet: no CREATE TABLE statements found on the schema")
+	}
+
+	return schema, nil
+}
+
+func parseCreateTable(tokens []sqlToken, i int, schema Schema) (int, error) {
+	// Skip modifiers such as `CREATE UNLOGGED TABLE` or `CREATE TEMPORARY TABLE`:
+	for i < len(tokens) && tokens[i].kind == identToken && !tokens[i].isKeyword("table") {
+		if tokens[i].isKeyword("index", "view", "function", "trigger", "sequence", "type", "schema", "extension") {
+			return i, nil
+		}
+		i++
+	}
+	i++
+
+	if i+2 < len(tokens) && tokens[i].isKeyword("if") {
+		i += 3
+	}
+
+	table, i := parseQualifiedName(tokens, i)
+	if table == "" || i >= len(tokens) || !tokens[i].is(symbolToken, "(") {
+		// e.g. `CREATE TABLE t AS SELECT ...`
+		return i, nil
+	}
+
+	isItemStart := true
+	for i++; i < len(tokens) && tokens[i].depth > 0; i++ {
+		token := tokens[i]
+		if token.depth > 1 {
+			continue
+		}
+		if token.is(symbolToken, ",") {
+			isItemStart = true
+			continue
+		}
+		if !isItemStart {
+			continue
+		}
+		isItemStart = false
+
+		if token.kind != identToken {
+			return i, fmt.Errorf("kvet: unexpected token '%s' on the definition of table %s", token.text, table)
+		}
+		if isTableConstraint(token.text) {
+			continue
+		}
+		schema.addColumn(table, token.text)
+	}
+
+	if _, found := schema.tables[table]; !found {
+		schema.tables[table] = map[string]bool{}
+	}
+
+	return i, nil
+}
+
+func parseAlterTable(tokens []sqlToken, i int, schema Schema) (int, error) {
+	if i >= len(tokens) || !tokens[i].isKeyword("table") {
+		return i, nil
+	}
+	i++
+
+	for i < len(tokens) && tokens[i].isKeyword("if", "exists", "only") {
+		i++
+	}
+
+	table, i := parseQualifiedName(tokens, i)
+	if name, found := schema.lookup(table); found {
+		table = name
+	}
+
+	for ; i < len(tokens) && !tokens[i].is(symbolToken, ";"); i++ {
+		if tokens[i].depth != 0 || !tokens[i].isKeyword("add") {
+			continue
+		}
+
+		i++
+		if i < len(tokens) && tokens[i].isKeyword("column") {
+			i++
+		}
+		for i+2 < len(tokens) && tokens[i].isKeyword("if") {
+			i += 3
+		}
+		if i < len(tokens) && tokens[i].kind == identToken && !isTableConstraint(tokens[i].text) {
+			schema.addColumn(table, tokens[i].text)
+		}
+	}
+
+	return i, nil
+}
+
+func parseQualifiedName(tokens []sqlToken, i int) (string, int) {
+	if i >= len(tokens) || tokens[i].kind != identToken {
+		return "", i
+	}
+
+	name := tokens[i].text
+	for i+2 < len(tokens) && tokens[i+1].is(symbolToken, ".") && tokens[i+2].kind == identToken {
+		name += "." + tokens[i+2].text
+		i += 2
+	}
+	return name, i + 1
+}
+
+func isTableConstraint(word string) bool {
+	switch word {
+	case "constraint", "primary", "unique", "foreign", "check", "key",
+		"index", "exclude", "fulltext", "spatial", "period":
+		return true
+	}
+	return false
+}
+
+// LoadSchema reads the schema from a live database using the
+// `information_schema` views, or the equivalent catalogs on
+// the databases that don't support them, e.g. sqlite3 and oracle.
+func LoadSchema(ctx context.Context, db *ksql.DB) (Schema, error) {
+	var query string
+	switch db.Dialect().DriverName() {
+	case "sqlite3":
+		query = `SELECT m.name AS table_name, p.name AS column_name
+			FROM sqlite_master m JOIN pragma_table_info(m.name) p
+			WHERE m.type IN ('table', 'view')`
+	case "oracle":
+		query = `SELECT table_name AS "table_name", column_name AS "column_name" FROM user_tab_columns`
+	default:
+		query = `SELECT table_name AS table_name, column_name AS column_name
+			FROM information_schema.columns
+			WHERE table_schema NOT IN ('information_schema', 'pg_catalog', 'mysql', 'performance_schema', 'sys')`
+	}
+
+	var columns []struct {
+		Table  string `ksql:"table_name"`
+		Column string `ksql:"column_name"`
+	}
+	err := db.Query(ctx, &columns, query)
+	if err != nil {
+		return Schema{}, fmt.Errorf("kvet: unable to load the schema: %s", err)
+	}
+
+	schema := Schema{tables: map[string]map[string]bool{}}
+	for _, c := range columns {
+		schema.addColumn(c.Table, c.Column)
+	}
+	return schema, nil
+}
@@ -0,0 +1,439 @@
+package kvet
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	stringToken
+	numberToken
+	paramToken
+	symbolToken
+)
+
+// sqlToken is a token of a query, the identifiers are
+// lowercased and their quotes are removed, since the
+// comparisons with the schema are case insensitive.
+type sqlToken struct {
+	kind tokenKind
+	text string
+
+	// depth is the number of parentheses around the token
+	depth int
+}
+
+func (t sqlToken) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t sqlToken) isKeyword(keywords ...string) bool {
+	if t.kind != identToken {
+		return false
+	}
+	for _, keyword := range keywords {
+		if t.text == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedWords are the keywords that might follow a table name
+// or an expression, so they can't be mistaken for aliases.
+var reservedWords = map[string]bool{
+	"as": true, "on": true, "using": true, "where": true, "join": true,
+	"inner": true, "left": true, "right": true, "full": true, "outer": true,
+	"cross": true, "natural": true, "lateral": true, "group": true,
+	"order": true, "having": true, "limit": true, "offset": true,
+	"fetch": true, "for": true, "union": true, "except": true,
+	"intersect": true, "window": true, "returning": true, "set": true,
+	"values": true, "from": true, "select": true, "output": true,
+	"default": true, "with": true, "into": true, "and": true, "or": true, "not": true,
+}
+
+// literalWords are the keywords that can be selected as values
+var literalWords = map[string]bool{
+	"true": true, "false": true, "null": true, "current_date": true,
+	"current_time": true, "current_timestamp": true, "current_user": true,
+	"localtime": true, "localtimestamp": true, "sysdate": true, "systimestamp": true,
+}
+
+func tokenize(query string) []sqlToken {
+	var tokens []sqlToken
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return tokens
+			}
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end == -1 {
+				return tokens
+			}
+			i += end + 1
+
+		case c == '\'':
+			start := i
+			for i++; i < len(query); i++ {
+				if query[i] != '\'' {
+					continue
+				}
+				if i+1 < len(query) && query[i+1] == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, sqlToken{kind: stringToken, text: query[start:min(i+1, len(query))], depth: depth})
+
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(query[i+1:], closing)
+			if end == -1 {
+				return tokens
+			}
+			tokens = append(tokens, sqlToken{kind: identToken, text: strings.ToLower(query[i+1 : i+1+end]), depth: depth})
+			i += end + 1
+
+		case c == '?' || (c == '$' || c == '@' || c == ':') && i+1 < len(query) && isWordChar(query[i+1]):
+			start := i
+			for i+1 < len(query) && isWordChar(query[i+1]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: paramToken, text: query[start : i+1], depth: depth})
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i+1 < len(query) && (isWordChar(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: numberToken, text: query[start : i+1], depth: depth})
+
+		case isWordChar(c):
+			start := i
+			for i+1 < len(query) && (isWordChar(query[i+1]) || query[i+1] == '$') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: identToken, text: strings.ToLower(query[start : i+1]), depth: depth})
+
+		case c == '(':
+			tokens = append(tokens, sqlToken{kind: symbolToken, text: "(", depth: depth})
+			depth++
+
+		case c == ')':
+			depth--
+			tokens = append(tokens, sqlToken{kind: symbolToken, text: ")", depth: depth})
+
+		default:
+			tokens = append(tokens, sqlToken{kind: symbolToken, text: string(c), depth: depth})
+		}
+	}
+
+	return tokens
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' ||
+		c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c >= 0x80
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// querySource is a table, subquery or function used on the FROM,
+// JOIN, UPDATE or INSERT INTO clauses, the table is empty for the
+// sources whose columns are unknown, e.g. subqueries.
+type querySource struct {
+	table string
+	alias string
+}
+
+// queryInfo contains the parts of a query relevant for the checks
+type queryInfo struct {
+	// kind is the first keyword of the query, e.g. "select" or "from"
+	kind string
+
+	sources []querySource
+
+	// outputs are the names of the columns returned by a SELECT,
+	// `*` is used for the items whose names can't be determined.
+	outputs []string
+
+	// unqualifiedRefs are the columns referenced without a table name
+	// on the parts of the query where the names are not ambiguous,
+	// i.e. the SELECT list, the INSERT column list and the SET clause.
+	unqualifiedRefs []string
+
+	// qualifiedRefs are the column references prefixed with
+	// the name or alias of a table, e.g. `u.name`
+	qualifiedRefs []qualifiedRef
+}
+
+type qualifiedRef struct {
+	qualifier string
+	column    string
+}
+
+func parseQuery(query string) queryInfo {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return queryInfo{}
+	}
+
+	var info queryInfo
+	cteNames := map[string]bool{}
+	start := 0
+	if tokens[0].isKeyword("with") {
+		start = skipCTEs(tokens, cteNames)
+	}
+	if start < len(tokens) {
+		info.kind = tokens[start].text
+	}
+
+	visitTokens(tokens, 0, len(tokens), cteNames, &info)
+
+	switch info.kind {
+	case "select":
+		parseSelectList(tokens[start:], &info)
+	case "insert":
+		parseInsertColumns(tokens[start:], &info)
+	case "update":
+		parseSetClause(tokens[start:], &info)
+	}
+
+	return info
+}
+
+// visitTokens reads the sources and the qualified column
+// references of the tokens between the positions start and end.
+func visitTokens(tokens []sqlToken, start int, end int, cteNames map[string]bool, info *queryInfo) {
+	for i := start; i < end; i++ {
+		token := tokens[i]
+		switch {
+		case token.isKeyword("from", "join", "update", "into"):
+			// Skip the `FOR UPDATE` locks and the upserts, e.g. `ON CONFLICT DO UPDATE`:
+			if token.text == "update" && i > 0 && tokens[i-1].isKeyword("key", "for", "do") {
+				continue
+			}
+			i = parseSources(tokens, i+1, token.text, cteNames, info)
+
+		case token.kind == identToken && i+2 < end &&
+			tokens[i+1].is(symbolToken, ".") && tokens[i+2].kind == identToken &&
+			(i == 0 || !tokens[i-1].is(symbolToken, ".")) &&
+			(i+3 >= end || !tokens[i+3].is(symbolToken, ".") && !tokens[i+3].is(symbolToken, "(")):
+			info.qualifiedRefs = append(info.qualifiedRefs, qualifiedRef{
+				qualifier: token.text,
+				column:    tokens[i+2].text,
+			})
+			i += 2
+		}
+	}
+}
+
+// skipCTEs records the names of the common table expressions
+// and returns the position of the main statement of the query.
+func skipCTEs(tokens []sqlToken, cteNames map[string]bool) int {
+	i := 1
+	for i < len(tokens) {
+		if tokens[i].isKeyword("recursive") || tokens[i].is(symbolToken, ",") {
+			i++
+			continue
+		}
+		if tokens[i].kind != identToken || tokens[i].depth > 0 || reservedWords[tokens[i].text] && tokens[i].text != "as" {
+			return i
+		}
+
+		if tokens[i].text != "as" {
+			cteNames[tokens[i].text] = true
+		}
+
+		// Skip everything until the next top level token:
+		for i++; i < len(tokens) && (tokens[i].depth > 0 || tokens[i].is(symbolToken, "(") || tokens[i].is(symbolToken, ")") || tokens[i].isKeyword("as")); i++ {
+		}
+	}
+	return i
+}
+
+// parseSources reads the sources following the keyword, which starts on the
+// position i, and returns the position of the last token it consumed.
+func parseSources(tokens []sqlToken, i int, keyword string, cteNames map[string]bool, info *queryInfo) int {
+	isFrom := keyword == "from" || keyword == "join"
+	for i < len(tokens) {
+		var source querySource
+		switch {
+		case tokens[i].is(symbolToken, "("):
+			// Subqueries have unknown columns:
+			start := i + 1
+			depth := tokens[i].depth
+			for i++; i < len(tokens) && tokens[i].depth > depth; i++ {
+			}
+			visitTokens(tokens, start, i, cteNames, info)
+
+		case tokens[i].kind == identToken && !reservedWords[tokens[i].text]:
+			source.table = tokens[i].text
+			for i+2 < len(tokens) && tokens[i+1].is(symbolToken, ".") && tokens[i+2].kind == identToken {
+				source.table += "." + tokens[i+2].text
+				i += 2
+			}
+			if cteNames[source.table] {
+				source.alias = source.table
+				source.table = ""
+			}
+
+			// Table functions, e.g. `generate_series(1, 10)`:
+			if isFrom && i+1 < len(tokens) && tokens[i+1].is(symbolToken, "(") {
+				source.table = ""
+				depth := tokens[i+1].depth
+				for i += 2; i < len(tokens) && tokens[i].depth > depth; i++ {
+				}
+			}
+
+		default:
+			return i - 1
+		}
+
+		if i+1 < len(tokens) && tokens[i+1].isKeyword("as") {
+			i++
+		}
+		if i+1 < len(tokens) && tokens[i+1].kind == identToken && !reservedWords[tokens[i+1].text] {
+			source.alias = tokens[i+1].text
+			i++
+		}
+
+		if source.alias == "" && source.table != "" {
+			parts := strings.Split(source.table, ".")
+			source.alias = parts[len(parts)-1]
+		}
+		info.sources = append(info.sources, source)
+
+		if keyword != "from" || i+1 >= len(tokens) || !tokens[i+1].is(symbolToken, ",") {
+			return i
+		}
+		i += 2
+	}
+	return i
+}
+
+// parseSelectList reads the names of the columns
+// returned by the top level SELECT of the query
+func parseSelectList(tokens []sqlToken, info *queryInfo) {
+	var item []sqlToken
+	depth := tokens[0].depth
+	for _, token := range tokens[1:] {
+		if token.depth == depth && (token.is(symbolToken, ",") || token.isKeyword("from")) {
+			addSelectItem(item, info)
+			item = nil
+			if token.isKeyword("from") {
+				return
+			}
+			continue
+		}
+		if len(item) == 0 && token.isKeyword("distinct", "all") {
+			continue
+		}
+		item = append(item, token)
+	}
+	addSelectItem(item, info)
+}
+
+func addSelectItem(item []sqlToken, info *queryInfo) {
+	n := len(item)
+	if n == 0 {
+		return
+	}
+
+	last := item[n-1]
+	switch {
+	case last.is(symbolToken, "*") || last.kind != identToken || n == 1 && literalWords[last.text]:
+		info.outputs = append(info.outputs, "*")
+
+	case n == 1:
+		info.outputs = append(info.outputs, last.text)
+		info.unqualifiedRefs = append(info.unqualifiedRefs, last.text)
+
+	case n == 3 && item[1].is(symbolToken, ".") && item[0].kind == identToken:
+		// The column is already listed on the qualifiedRefs:
+		info.outputs = append(info.outputs, last.text)
+
+	case item[n-2].isKeyword("as"):
+		info.outputs = append(info.outputs, last.text)
+		if n == 3 && item[0].kind == identToken {
+			info.unqualifiedRefs = append(info.unqualifiedRefs, item[0].text)
+		}
+
+	case item[n-2].is(symbolToken, ")") || item[n-2].kind == identToken || item[n-2].kind == numberToken || item[n-2].kind == stringToken:
+		// Implicit aliases, e.g. `count(*) total`:
+		info.outputs = append(info.outputs, last.text)
+
+	default:
+		info.outputs = append(info.outputs, "*")
+	}
+}
+
+// parseInsertColumns reads the list of columns of `INSERT INTO t (a, b)`
+func parseInsertColumns(tokens []sqlToken, info *queryInfo) {
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].is(symbolToken, "(") {
+			continue
+		}
+		if i == 0 || tokens[i-1].kind != identToken || reservedWords[tokens[i-1].text] {
+			return
+		}
+
+		depth := tokens[i].depth + 1
+		for i++; i < len(tokens) && tokens[i].depth >= depth; i++ {
+			if tokens[i].kind == identToken && tokens[i].depth == depth {
+				info.unqualifiedRefs = append(info.unqualifiedRefs, tokens[i].text)
+			}
+		}
+		return
+	}
+}
+
+// parseSetClause reads the columns assigned on `UPDATE t SET a = 1, b = 2`
+func parseSetClause(tokens []sqlToken, info *queryInfo) {
+	depth := tokens[0].depth
+	inSet := false
+	for i, token := range tokens {
+		if token.depth != depth {
+			continue
+		}
+		if token.isKeyword("set") {
+			inSet = true
+			continue
+		}
+		if !inSet {
+			continue
+		}
+		if token.isKeyword("where", "from", "returning", "output") {
+			return
+		}
+
+		isAssignment := token.kind == identToken && i+1 < len(tokens) && tokens[i+1].is(symbolToken, "=")
+		isFirstOfItem := tokens[i-1].isKeyword("set") || tokens[i-1].is(symbolToken, ",")
+		if isAssignment && isFirstOfItem {
+			info.unqualifiedRefs = append(info.unqualifiedRefs, token.text)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/vingarcia/ksql"
+)
+
+type User struct {
+	ID   int    `ksql:"id"`
+	Name string `ksql:"name"`
+}
+
+type Post struct {
+	ID    int    `ksql:"id"`
+	Title string `ksql:"titel"`
+}
+
+type UserWithPosts struct {
+	User User `tablename:"u"`
+	Post Post `tablename:"p"`
+}
+
+const byIDQuery = "SELECT id, name FROM users WHERE id = $1"
+
+func queries(ctx context.Context, db ksql.Provider) {
+	var users []User
+	_ = db.Query(ctx, &users, "SELECT id, nmae FROM users")
+	_ = db.Query(ctx, &users, "FROM users")
+
+	var user User
+	_ = db.QueryOne(ctx, &user, byIDQuery, 1)
+	_ = db.QueryOne(ctx, &user, "SELECT id, name, age FROM "+"users")
+
+	var rows []UserWithPosts
+	_ = db.Query(ctx, &rows, "FROM users u JOIN posts p ON p.author_id = u.id")
+
+	var posts []*Post
+	_ = db.Query(ctx, &posts, "SELECT p.id, p.title AS titel FROM posts p WHERE p.deleted_at IS NULL")
+
+	_, _ = db.Exec(ctx, "UPDATE userz SET name = $1", "Ana")
+	_, _ = db.Exec(ctx, "INSERT INTO users (id, nome) VALUES ($1, $2)", 1, "Ana")
+
+	// Queries built at runtime are not checked:
+	table := "users"
+	_ = db.Query(ctx, &users, "SELECT * FROM "+table)
+}
@@ -0,0 +1,20 @@
+-- A schema dump with the statements usually generated by pg_dump:
+SET statement_timeout = 0;
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    name text DEFAULT ''::text,
+    age integer,
+    CONSTRAINT users_age_check CHECK ((age > 0))
+);
+
+CREATE TABLE IF NOT EXISTS "posts" (
+    "id" serial PRIMARY KEY,
+    "author_id" integer REFERENCES users(id),
+    "title" varchar(255) NOT NULL,
+    UNIQUE (author_id, title)
+);
+
+ALTER TABLE ONLY public.posts ADD COLUMN deleted_at timestamp;
+
+CREATE INDEX posts_author_idx ON posts (author_id);
@@ -0,0 +1,15 @@
+module github.com/vingarcia/ksql/kyaml
+
+go 1.20
+
+require (
+	github.com/vingarcia/ksql v1.4.7
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.7.0 // indirect
+)
@@ -0,0 +1,31 @@
+// Package kyaml adds support for YAML fixture files to ksql.LoadFixtures,
+// it only needs to be imported for registering the decoder, e.g.:
+//
+//	import _ "github.com/vingarcia/ksql/kyaml"
+//
+// The files with the `.yml` and `.yaml` extensions are then decoded
+// as lists of objects mapping the column names to their values:
+//
+//	# users.yml
+//	- id: 1
+//	  name: Ana
+//	- id: 2
+//	  name: Bia
+package kyaml
+
+import (
+	"github.com/vingarcia/ksql"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	ksql.RegisterFixtureDecoder(".yml", DecodeFixture)
+	ksql.RegisterFixtureDecoder(".yaml", DecodeFixture)
+}
+
+// DecodeFixture decodes the rows of a YAML fixture file,
+// it implements the ksql.FixtureDecoder type
+func DecodeFixture(content []byte) (rows []map[string]interface{}, err error) {
+	err = yaml.Unmarshal(content, &rows)
+	return rows, err
+}
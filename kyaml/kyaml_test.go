@@ -0,0 +1,70 @@
+package kyaml
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vingarcia/ksql"
+)
+
+func TestDecodeFixture(t *testing.T) {
+	rows, err := DecodeFixture([]byte(`
+- id: 1
+  name: Ana
+  address:
+    city: Rio
+- id: 2
+  name: Bia
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": 1, "name": "Ana", "address": map[string]interface{}{"city": "Rio"}},
+		{"id": 2, "name": "Bia"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, but got %v", expected, rows)
+	}
+
+	_, err = DecodeFixture([]byte("- id: [1"))
+	if err == nil {
+		t.Fatalf("expected an error for the invalid YAML")
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kyaml-fixtures")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "users.yaml"), []byte("- {id: 1, name: Ana}"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	dryRun, err := ksql.NewDryRun("duckdb", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = ksql.LoadFixtures(context.Background(), dryRun.DB, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	statements := dryRun.Statements()
+	if len(statements) != 2 {
+		t.Fatalf("expected a DELETE and an INSERT statement, but got: %v", statements)
+	}
+	if fmt.Sprint(statements[1].Params) != "[1 Ana]" {
+		t.Fatalf("unexpected params for the INSERT statement: %v", statements[1].Params)
+	}
+}
@@ -0,0 +1,81 @@
+package ksql
+
+import "context"
+
+// BeforeInsertHook can be implemented by the records passed to Insert
+// and Upsert, the hook is called before building the query, so the
+// changes it makes on the record are saved on the database, e.g.:
+//
+//	func (u *User) BeforeInsert(ctx context.Context) error {
+//		u.Email = strings.ToLower(u.Email)
+//		return nil
+//	}
+//
+// If it returns an error the record is not inserted.
+type BeforeInsertHook interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInsertHook can be implemented by the records passed to Insert and
+// Upsert, the hook is called after the record is written and its ID is set.
+type AfterInsertHook interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdateHook can be implemented by the records passed to Patch,
+// Update and UpdateReturning, the hook is called before building the query, so the
+// changes it makes on the record are saved on the database.
+//
+// If it returns an error the record is not updated.
+type BeforeUpdateHook interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterUpdateHook can be implemented by the records passed to Patch,
+// Update and UpdateReturning, the hook is called only if a row was actually updated.
+type AfterUpdateHook interface {
+	AfterUpdate(ctx context.Context) error
+}
+
+// AfterQueryHook can be implemented by the records loaded by
+// Query, QueryOne, QueryChunks and QueryIter, the hook is
+// called after each record is scanned, which is useful
+// for filling computed attributes.
+type AfterQueryHook interface {
+	AfterQuery(ctx context.Context) error
+}
+
+func callBeforeInsert(ctx context.Context, record interface{}) error {
+	if hook, ok := record.(BeforeInsertHook); ok {
+		return hook.BeforeInsert(ctx)
+	}
+	return nil
+}
+
+func callAfterInsert(ctx context.Context, record interface{}) error {
+	if hook, ok := record.(AfterInsertHook); ok {
+		return hook.AfterInsert(ctx)
+	}
+	return nil
+}
+
+func callBeforeUpdate(ctx context.Context, record interface{}) error {
+	if hook, ok := record.(BeforeUpdateHook); ok {
+		return hook.BeforeUpdate(ctx)
+	}
+	return nil
+}
+
+func callAfterUpdate(ctx context.Context, record interface{}) error {
+	if hook, ok := record.(AfterUpdateHook); ok {
+		return hook.AfterUpdate(ctx)
+	}
+	return nil
+}
+
+func callAfterQuery(ctx context.Context, record interface{}) error {
+	if hook, ok := record.(AfterQueryHook); ok {
+		return hook.AfterQuery(ctx)
+	}
+	return nil
+}
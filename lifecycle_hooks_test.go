@@ -0,0 +1,172 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type hookedUser struct {
+	ID    uint   `ksql:"id"`
+	Email string `ksql:"email"`
+
+	Domain string
+	Calls  []string
+}
+
+func (u *hookedUser) BeforeInsert(ctx context.Context) error {
+	u.Calls = append(u.Calls, "BeforeInsert")
+	u.Email = strings.ToLower(u.Email)
+	if u.Email == "" {
+		return errors.New("fake error: missing email")
+	}
+	return nil
+}
+
+func (u *hookedUser) AfterInsert(ctx context.Context) error {
+	u.Calls = append(u.Calls, "AfterInsert")
+	return nil
+}
+
+func (u *hookedUser) BeforeUpdate(ctx context.Context) error {
+	u.Calls = append(u.Calls, "BeforeUpdate")
+	u.Email = strings.ToLower(u.Email)
+	return nil
+}
+
+func (u *hookedUser) AfterUpdate(ctx context.Context) error {
+	u.Calls = append(u.Calls, "AfterUpdate")
+	return nil
+}
+
+func (u *hookedUser) AfterQuery(ctx context.Context) error {
+	u.Domain = u.Email[strings.Index(u.Email, "@")+1:]
+	return nil
+}
+
+type fakeUserRows struct {
+	Rows
+
+	emails []string
+}
+
+func (f *fakeUserRows) Next() bool {
+	return len(f.emails) > 0
+}
+
+func (f *fakeUserRows) Scan(args ...interface{}) error {
+	*(args[0].(*uint)) = 1
+	*(args[1].(*string)) = f.emails[0]
+	f.emails = f.emails[1:]
+	return nil
+}
+
+func (f *fakeUserRows) Columns() ([]string, error) {
+	return []string{"id", "email"}, nil
+}
+
+func (f *fakeUserRows) Err() error {
+	return nil
+}
+
+func (f *fakeUserRows) Close() error {
+	return nil
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	hookedUsersTable := NewTable("users")
+
+	t.Run("should call the insert hooks", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		u := hookedUser{Email: "Fake@Example.com"}
+		err = dryRun.Insert(context.Background(), hookedUsersTable, &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.Calls, []string{"BeforeInsert", "AfterInsert"})
+		tt.AssertEqual(t, dryRun.Statements()[0].Params, []interface{}{"fake@example.com"})
+	})
+
+	t.Run("should not insert the record if the BeforeInsert hook fails", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		u := hookedUser{}
+		err = dryRun.Insert(context.Background(), hookedUsersTable, &u)
+		tt.AssertErrContains(t, err, "fake error", "missing email")
+
+		tt.AssertEqual(t, u.Calls, []string{"BeforeInsert"})
+		tt.AssertEqual(t, len(dryRun.Statements()), 0)
+	})
+
+	t.Run("should call the update hooks", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", nil)
+		tt.AssertNoErr(t, err)
+
+		u := hookedUser{ID: 42, Email: "Fake@Example.com"}
+		err = dryRun.Patch(context.Background(), hookedUsersTable, &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.Calls, []string{"BeforeUpdate", "AfterUpdate"})
+		tt.AssertEqual(t, dryRun.Statements()[0].Params, []interface{}{"fake@example.com", uint(42)})
+	})
+
+	t.Run("should call the insert hooks on upserts", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		u := hookedUser{ID: 42, Email: "Fake@Example.com"}
+		err = dryRun.Upsert(context.Background(), hookedUsersTable, &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.Calls, []string{"BeforeInsert", "AfterInsert"})
+		// The order of the columns of the upserts is not deterministic:
+		params := dryRun.Statements()[0].Params
+		tt.AssertEqual(t, len(params), 2)
+		tt.AssertEqual(t, params[0] == "fake@example.com" || params[1] == "fake@example.com", true)
+	})
+
+	t.Run("should call the update hooks on UpdateReturning", func(t *testing.T) {
+		var params []interface{}
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				params = args
+				return &fakeUserRows{emails: []string{"fake@example.com"}}, nil
+			},
+		}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		u := hookedUser{ID: 42, Email: "Fake@Example.com"}
+		err = db.UpdateReturning(context.Background(), hookedUsersTable, &u)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.Calls, []string{"BeforeUpdate", "AfterUpdate"})
+		tt.AssertEqual(t, params, []interface{}{"fake@example.com", uint(42)})
+	})
+
+	t.Run("should call the AfterQuery hook for each record", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return &fakeUserRows{emails: []string{"a@foo.com", "b@bar.com"}}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		var users []hookedUser
+		err = db.Query(context.Background(), &users, "FROM users")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(users), 2)
+		tt.AssertEqual(t, users[0].Domain, "foo.com")
+		tt.AssertEqual(t, users[1].Domain, "bar.com")
+
+		var u hookedUser
+		err = db.QueryOne(context.Background(), &u, "FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, u.Domain, "foo.com")
+	})
+}
@@ -0,0 +1,401 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoadFixtures replaces the contents of the tables with the rows
+// described on the fixture files of the input directory, which is
+// useful for preparing the database for integration tests, e.g.:
+//
+//	err := ksql.LoadFixtures(ctx, db, "testdata/fixtures/")
+//
+// Each file contains the rows of the table with the same name as
+// the file, e.g. `users.json`, as a list of objects mapping
+// the column names to their values:
+//
+//	[
+//		{"id": 1, "name": "Ana"},
+//		{"id": 2, "name": "Bia"}
+//	]
+//
+// Other formats can be supported with `ksql.RegisterFixtureDecoder()`,
+// e.g. the `kyaml` module registers a decoder for the `.yml` and
+// `.yaml` files, so the core module doesn't depend on a YAML parser:
+//
+//	import _ "github.com/vingarcia/ksql/kyaml"
+//
+// All rows of these tables are deleted before loading the fixtures,
+// and both operations happen in the order required by the foreign keys
+// between the tables, falling back to the alphabetical order for
+// tables that don't depend on each other, all inside a transaction.
+//
+// If one of the input tables was created with `Table.WithStruct()` its
+// rows are decoded into this struct and inserted with `DB.Insert()`, so
+// the columns are mapped using the ksql tags, including the modifiers,
+// e.g. `ksql:"address,json"`, otherwise the values are inserted as
+// they are, with nested objects and lists encoded as JSON.
+//
+// On Postgres the sequences of the `id` columns are updated after
+// loading the rows, so that new records don't conflict with the fixtures.
+// On SQL Server the fixtures can't set the values of IDENTITY columns,
+// and on DuckDB the foreign keys are not detected, so the files are
+// loaded in alphabetical order.
+func LoadFixtures(ctx context.Context, db DB, dir string, tables ...Table) error {
+	fixtures, err := readFixtures(dir)
+	if err != nil {
+		return err
+	}
+
+	tableNames := make([]string, 0, len(fixtures))
+	for name := range fixtures {
+		tableNames = append(tableNames, name)
+	}
+
+	deps, err := db.foreignKeyDependencies(ctx, tableNames)
+	if err != nil {
+		return err
+	}
+
+	tableNames, err = sortTablesByDependencies(tableNames, deps)
+	if err != nil {
+		return err
+	}
+
+	tablesByName := map[string]Table{}
+	for _, table := range tables {
+		tablesByName[table.name] = table
+	}
+
+	return db.Transaction(ctx, func(p Provider) error {
+		tx := p.(DB)
+		for i := len(tableNames) - 1; i >= 0; i-- {
+			_, err := tx.Exec(ctx, "DELETE FROM "+tx.dialect.Escape(tableNames[i]))
+			if err != nil {
+				return fmt.Errorf("ksql: error deleting the rows of table `%s`: %s", tableNames[i], err)
+			}
+		}
+
+		for _, tableName := range tableNames {
+			for i, row := range fixtures[tableName] {
+				var err error
+				if table, ok := tablesByName[tableName]; ok && table.structType != nil {
+					err = tx.insertFixtureRecord(ctx, table, row)
+				} else {
+					err = tx.insertFixtureRow(ctx, tableName, row)
+				}
+				if err != nil {
+					return fmt.Errorf("ksql: error inserting row %d of the fixtures of table `%s`: %s", i+1, tableName, err)
+				}
+			}
+
+			if tx.dialect.DriverName() == "postgres" && len(fixtures[tableName]) > 0 {
+				if _, ok := fixtures[tableName][0]["id"]; ok {
+					err := tx.resetIDSequence(ctx, tableName)
+					if err != nil {
+						return fmt.Errorf("ksql: error updating the sequence of table `%s`: %s", tableName, err)
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// FixtureDecoder decodes the content of a fixture file into
+// the rows of the table, see `ksql.RegisterFixtureDecoder()`
+type FixtureDecoder func(content []byte) ([]map[string]interface{}, error)
+
+var fixtureDecoders = struct {
+	sync.RWMutex
+	byExt map[string]FixtureDecoder
+}{
+	byExt: map[string]FixtureDecoder{
+		".json": decodeJSONFixture,
+	},
+}
+
+// RegisterFixtureDecoder registers the decoder used by LoadFixtures for
+// the files with the input extension, e.g. ".yml", replacing the decoder
+// already registered for it, if any.
+//
+// The numbers might be decoded as json.Number values, and the nested
+// objects as maps with keys of any type, since both are normalized
+// before the rows are inserted.
+func RegisterFixtureDecoder(ext string, decoder FixtureDecoder) {
+	fixtureDecoders.Lock()
+	defer fixtureDecoders.Unlock()
+	fixtureDecoders.byExt[ext] = decoder
+}
+
+func getFixtureDecoder(ext string) FixtureDecoder {
+	fixtureDecoders.RLock()
+	defer fixtureDecoders.RUnlock()
+	return fixtureDecoders.byExt[ext]
+}
+
+func decodeJSONFixture(content []byte) (rows []map[string]interface{}, err error) {
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.UseNumber()
+	err = decoder.Decode(&rows)
+	return rows, err
+}
+
+// resetIDSequence updates the sequence of the `id` column of a Postgres
+// table to its largest value, the IDs without sequences are ignored,
+// e.g. UUIDs, since there is nothing to update and they have no MAX()
+func (c DB) resetIDSequence(ctx context.Context, tableName string) error {
+	var sequence sql.NullString
+	err := c.QueryOne(ctx, &sequence, "SELECT pg_get_serial_sequence($1, 'id')", tableName)
+	if err != nil || !sequence.Valid {
+		return err
+	}
+
+	_, err = c.Exec(ctx, fmt.Sprintf(
+		"SELECT setval($1, (SELECT MAX(id) FROM %s))",
+		c.dialect.Escape(tableName),
+	), sequence.String)
+	return err
+}
+
+// readFixtures returns the rows of each fixture file of dir by table name
+func readFixtures(dir string) (map[string][]map[string]interface{}, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: unable to read the fixtures directory: %s", err)
+	}
+
+	fixtures := map[string][]map[string]interface{}{}
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		decode := getFixtureDecoder(ext)
+		if file.IsDir() || decode == nil {
+			continue
+		}
+
+		tableName := strings.TrimSuffix(file.Name(), ext)
+		if _, found := fixtures[tableName]; found {
+			return nil, fmt.Errorf("ksql: found more than one fixture file for table `%s`", tableName)
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("ksql: unable to read the fixture file '%s': %s", file.Name(), err)
+		}
+
+		rows, err := decode(content)
+		if err != nil {
+			return nil, fmt.Errorf("ksql: unable to parse the fixture file '%s': %s", file.Name(), err)
+		}
+
+		fixtures[tableName] = rows
+	}
+
+	return fixtures, nil
+}
+
+// insertFixtureRecord decodes the row into the struct registered on
+// the table and inserts it, so the columns are mapped using the tags
+func (c DB) insertFixtureRecord(ctx context.Context, table Table, row map[string]interface{}) error {
+	info, err := c.naming.GetTagInfo(table.structType)
+	if err != nil {
+		return err
+	}
+
+	record := reflect.New(table.structType)
+	for column, value := range row {
+		field := info.ByName(column)
+		if !field.Valid {
+			return fmt.Errorf("the struct %v has no attribute for the column `%s`", table.structType, column)
+		}
+
+		// Encoding the value as JSON allows decoding it into
+		// any type supported by encoding/json, e.g. time.Time:
+		rawValue, err := json.Marshal(normalizeFixtureValue(value))
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(rawValue, record.Elem().FieldByIndex(field.Path).Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("unable to decode the value of column `%s`: %s", column, err)
+		}
+	}
+
+	return c.Insert(ctx, table, record.Interface())
+}
+
+func (c DB) insertFixtureRow(ctx context.Context, tableName string, row map[string]interface{}) error {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	escapedColumns := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	params := make([]interface{}, len(columns))
+	for i, column := range columns {
+		escapedColumns[i] = c.dialect.Escape(column)
+		placeholders[i] = c.dialect.Placeholder(i)
+
+		value := normalizeFixtureValue(row[column])
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			rawValue, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			value = string(rawValue)
+		}
+		params[i] = value
+	}
+
+	_, err := c.Exec(ctx, fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		c.dialect.Escape(tableName),
+		strings.Join(escapedColumns, ", "),
+		strings.Join(placeholders, ", "),
+	), params...)
+	return err
+}
+
+// normalizeFixtureValue converts the json.Number values to int64 or float64
+// and the maps decoded with keys of other types, e.g. by YAML parsers,
+// to maps with string keys
+func normalizeFixtureValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = normalizeFixtureValue(item)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = normalizeFixtureValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = normalizeFixtureValue(item)
+		}
+		return s
+	}
+	return value
+}
+
+// foreignKeyDependencies returns the tables referenced by the foreign
+// keys of each of the input tables, the tables referenced by themselves
+// and the tables not included on the input are ignored.
+func (c DB) foreignKeyDependencies(ctx context.Context, tableNames []string) (map[string][]string, error) {
+	type foreignKey struct {
+		Child  string `ksql:"child"`
+		Parent string `ksql:"parent"`
+	}
+
+	var fks []foreignKey
+	var err error
+	switch c.dialect.DriverName() {
+	case "sqlite3":
+		for _, tableName := range tableNames {
+			var tableFKs []foreignKey
+			err = c.Query(ctx, &tableFKs, `SELECT ? AS child, "table" AS parent FROM pragma_foreign_key_list(?)`, tableName, tableName)
+			if err != nil {
+				break
+			}
+			fks = append(fks, tableFKs...)
+		}
+	case "postgres":
+		err = c.Query(ctx, &fks, `SELECT DISTINCT tc.table_name AS child, ccu.table_name AS parent
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.constraint_column_usage ccu
+				ON ccu.constraint_name = tc.constraint_name AND ccu.constraint_schema = tc.constraint_schema
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()`)
+	case "mysql", "mariadb":
+		err = c.Query(ctx, &fks, `SELECT DISTINCT table_name AS child, referenced_table_name AS parent
+			FROM information_schema.key_column_usage
+			WHERE referenced_table_name IS NOT NULL AND table_schema = DATABASE()`)
+	case "sqlserver":
+		err = c.Query(ctx, &fks, `SELECT DISTINCT OBJECT_NAME(parent_object_id) AS child, OBJECT_NAME(referenced_object_id) AS parent
+			FROM sys.foreign_keys`)
+	case "oracle":
+		err = c.Query(ctx, &fks, `SELECT DISTINCT LOWER(a.table_name) AS child, LOWER(r.table_name) AS parent
+			FROM user_constraints a
+			JOIN user_constraints r ON a.r_constraint_name = r.constraint_name
+			WHERE a.constraint_type = 'R'`)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ksql: unable to load the foreign keys of the fixture tables: %s", err)
+	}
+
+	isInput := map[string]bool{}
+	for _, tableName := range tableNames {
+		isInput[tableName] = true
+	}
+
+	deps := map[string][]string{}
+	for _, fk := range fks {
+		if fk.Child == fk.Parent || !isInput[fk.Child] || !isInput[fk.Parent] {
+			continue
+		}
+		deps[fk.Child] = append(deps[fk.Child], fk.Parent)
+	}
+
+	return deps, nil
+}
+
+// sortTablesByDependencies sorts the tables so that each table comes
+// after the tables it depends on, tables without dependencies between
+// them are sorted alphabetically so that the order is deterministic.
+func sortTablesByDependencies(tableNames []string, deps map[string][]string) ([]string, error) {
+	pending := append([]string{}, tableNames...)
+	sort.Strings(pending)
+
+	sorted := make([]string, 0, len(pending))
+	done := map[string]bool{}
+	for len(pending) > 0 {
+		next := -1
+		for i, tableName := range pending {
+			ready := true
+			for _, dep := range deps[tableName] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			return nil, fmt.Errorf("ksql: can't sort the fixture tables due to circular foreign keys between the tables: %v", pending)
+		}
+
+		done[pending[next]] = true
+		sorted = append(sorted, pending[next])
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,87 @@
+package ksql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestSortTablesByDependencies(t *testing.T) {
+	t.Run("should sort the tables after their dependencies", func(t *testing.T) {
+		sorted, err := sortTablesByDependencies(
+			[]string{"comments", "users", "posts", "tags"},
+			map[string][]string{
+				"comments": {"posts", "users"},
+				"posts":    {"users"},
+			},
+		)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, sorted, []string{"tags", "users", "posts", "comments"})
+	})
+
+	t.Run("should report circular dependencies", func(t *testing.T) {
+		_, err := sortTablesByDependencies(
+			[]string{"a", "b", "c"},
+			map[string][]string{
+				"a": {"b"},
+				"b": {"a"},
+			},
+		)
+		tt.AssertErrContains(t, err, "circular foreign keys", "[a b]")
+	})
+}
+
+func TestReadFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ksql-fixtures")
+	tt.AssertNoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name string, content string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		tt.AssertNoErr(t, err)
+	}
+
+	writeFile("users.fake", "Ana,Bia")
+	writeFile("posts.json", `[{"id": 1, "user_id": 2, "score": 4.5, "tags": {"a": [1]}}]`)
+	writeFile("README.md", "ignored")
+
+	// Without a decoder for the extension the file is ignored:
+	fixtures, err := readFixtures(dir)
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, len(fixtures), 1)
+	tt.AssertEqual(t, len(fixtures["posts"]), 1)
+	tt.AssertEqual(t, normalizeFixtureValue(fixtures["posts"][0]["user_id"]), int64(2))
+	tt.AssertEqual(t, normalizeFixtureValue(fixtures["posts"][0]["score"]), 4.5)
+	tt.AssertEqual(t, normalizeFixtureValue(fixtures["posts"][0]["tags"]), map[string]interface{}{
+		"a": []interface{}{int64(1)},
+	})
+
+	RegisterFixtureDecoder(".fake", func(content []byte) ([]map[string]interface{}, error) {
+		var rows []map[string]interface{}
+		for _, name := range strings.Split(string(content), ",") {
+			rows = append(rows, map[string]interface{}{"name": name})
+		}
+		return rows, nil
+	})
+	defer func() {
+		fixtureDecoders.Lock()
+		delete(fixtureDecoders.byExt, ".fake")
+		fixtureDecoders.Unlock()
+	}()
+
+	fixtures, err = readFixtures(dir)
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, len(fixtures), 2)
+	tt.AssertEqual(t, fixtures["users"], []map[string]interface{}{
+		{"name": "Ana"},
+		{"name": "Bia"},
+	})
+
+	writeFile("users.json", `[]`)
+	_, err = readFixtures(dir)
+	tt.AssertErrContains(t, err, "more than one fixture file", "users")
+}
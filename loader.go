@@ -0,0 +1,227 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// LoaderConfig configures the batches of a ksql.Loader
+type LoaderConfig struct {
+	// Wait is how long a batch waits for more IDs
+	// before running its query, it defaults to 1ms
+	Wait time.Duration
+
+	// MaxBatchSize is the maximum number of IDs of a batch, the batches
+	// reaching it run immediately, it defaults to 100
+	MaxBatchSize int
+}
+
+// Loader batches the calls to its Load method made within a short time
+// window into a single `WHERE id IN (...)` query, which is useful for
+// avoiding the N+1 queries problem on GraphQL resolvers.
+//
+// A Loader also keeps the records it loads, so it should be created for
+// each request, see `ksql.NewLoader()` for more details.
+type Loader struct {
+	db         DB
+	table      Table
+	structType reflect.Type
+	idField    *structs.FieldInfo
+	config     LoaderConfig
+
+	mu      sync.Mutex
+	batch   *loaderBatch
+	records map[string]reflect.Value
+}
+
+type loaderBatch struct {
+	ctx     context.Context
+	ids     []interface{}
+	keys    map[string]bool
+	started bool
+	done    chan struct{}
+
+	records map[string]reflect.Value
+	err     error
+}
+
+// NewLoader returns a ksql.Loader for the records of the input table,
+// which must have a single ID column and a struct registered with
+// `ksql.NewTable().WithStruct()`, e.g.:
+//
+//	var UsersTable = ksql.NewTable("users").WithStruct(User{})
+//
+//	// On each request:
+//	loader, err := ksql.NewLoader(db, UsersTable, ksql.LoaderConfig{})
+//
+//	// On each resolver:
+//	var author User
+//	err := loader.Load(ctx, post.AuthorID, &author)
+//
+// The query of each batch runs with the context of the first call to Load
+// of the batch, and the records are read from the table with the same
+// filters of the Query method, e.g. for soft deleted records and tenants.
+//
+// The records loaded are kept until the Loader is discarded, so it
+// should not be reused between requests, otherwise it might return
+// outdated records.
+func NewLoader(db DB, table Table, config LoaderConfig) (*Loader, error) {
+	if err := table.validate(); err != nil {
+		return nil, fmt.Errorf("can't create a loader for ksql.Table: %s", err)
+	}
+
+	if table.structType == nil {
+		return nil, fmt.Errorf(
+			"ksql: NewLoader requires a struct registered on the ksql.Table, please create it with: ksql.NewTable(%q).WithStruct(MyStruct{})",
+			table.name,
+		)
+	}
+
+	if len(table.idColumns) != 1 {
+		return nil, fmt.Errorf("ksql: NewLoader doesn't support tables with composite keys, but got: %v", table.idColumns)
+	}
+
+	info, err := db.naming.GetTagInfo(table.structType)
+	if err != nil {
+		return nil, err
+	}
+
+	idField := info.ByName(table.idColumns[0])
+	if !idField.Valid {
+		return nil, fmt.Errorf(
+			"ksql: the struct %v has no attribute for the ID column `%s`",
+			table.structType, table.idColumns[0],
+		)
+	}
+
+	if config.Wait == 0 {
+		config.Wait = time.Millisecond
+	}
+	if config.MaxBatchSize == 0 {
+		config.MaxBatchSize = 100
+	}
+
+	return &Loader{
+		db:         db,
+		table:      table,
+		structType: table.structType,
+		idField:    idField,
+		config:     config,
+		records:    map[string]reflect.Value{},
+	}, nil
+}
+
+// Load saves the record with the input ID on the record argument, which
+// must be a pointer to the struct registered on the table, or returns
+// ksql.ErrRecordNotFound if there is no such record.
+func (l *Loader) Load(ctx context.Context, id interface{}, record interface{}) error {
+	v := reflect.ValueOf(record)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Type().Elem() != l.structType {
+		return fmt.Errorf("ksql: expected record to be a non-nil *%v, but got: %T", l.structType, record)
+	}
+
+	key := loaderKey(id)
+
+	l.mu.Lock()
+	if found, ok := l.records[key]; ok {
+		l.mu.Unlock()
+		v.Elem().Set(found)
+		return nil
+	}
+
+	batch := l.batch
+	if batch == nil {
+		batch = &loaderBatch{
+			ctx:  ctx,
+			keys: map[string]bool{},
+			done: make(chan struct{}),
+		}
+		l.batch = batch
+		time.AfterFunc(l.config.Wait, func() {
+			l.dispatch(batch)
+		})
+	}
+
+	if !batch.keys[key] {
+		batch.keys[key] = true
+		batch.ids = append(batch.ids, id)
+	}
+	full := len(batch.ids) >= l.config.MaxBatchSize
+	l.mu.Unlock()
+
+	if full {
+		go l.dispatch(batch)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if batch.err != nil {
+		return batch.err
+	}
+
+	found, ok := batch.records[key]
+	if !ok {
+		return notFoundError(ctx, NotFoundError{Table: l.table.name})
+	}
+
+	v.Elem().Set(found)
+	return nil
+}
+
+// dispatch runs the query of the batch, unless it is already running
+func (l *Loader) dispatch(batch *loaderBatch) {
+	l.mu.Lock()
+	if batch.started {
+		l.mu.Unlock()
+		return
+	}
+	batch.started = true
+	if l.batch == batch {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	defer close(batch.done)
+
+	query := fmt.Sprintf(
+		"FROM %s WHERE %s IN (%s)",
+		l.db.dialect.Escape(l.table.name),
+		l.db.dialect.Escape(l.table.idColumns[0]),
+		l.db.dialect.Placeholder(0),
+	)
+
+	slicePtr := reflect.New(reflect.SliceOf(l.structType))
+	batch.err = l.db.Query(batch.ctx, slicePtr.Interface(), query, batch.ids)
+	if batch.err != nil {
+		return
+	}
+
+	slice := slicePtr.Elem()
+	batch.records = make(map[string]reflect.Value, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		record := slice.Index(i)
+		batch.records[loaderKey(record.FieldByIndex(l.idField.Path).Interface())] = record
+	}
+
+	l.mu.Lock()
+	for key, record := range batch.records {
+		l.records[key] = record
+	}
+	l.mu.Unlock()
+}
+
+// loaderKey returns a key for the ID that is the same for the
+// IDs received by Load and the IDs read from the database,
+// even if they are pointers or have different int types.
+func loaderKey(id interface{}) string {
+	return fmt.Sprint(reflect.Indirect(reflect.ValueOf(id)))
+}
@@ -0,0 +1,27 @@
+package ksql
+
+import (
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestNewLoader(t *testing.T) {
+	db, err := NewWithAdapter(mockDBAdapter{}, "sqlite3")
+	tt.AssertNoErr(t, err)
+
+	t.Run("should report tables without a struct", func(t *testing.T) {
+		_, err := NewLoader(db, NewTable("users"), LoaderConfig{})
+		tt.AssertErrContains(t, err, "NewLoader", "WithStruct")
+	})
+
+	t.Run("should report tables with composite keys", func(t *testing.T) {
+		_, err := NewLoader(db, NewTable("user_permissions", "user_id", "perm_id").WithStruct(user{}), LoaderConfig{})
+		tt.AssertErrContains(t, err, "composite keys")
+	})
+
+	t.Run("should report structs without the ID column", func(t *testing.T) {
+		_, err := NewLoader(db, NewTable("users", "user_id").WithStruct(user{}), LoaderConfig{})
+		tt.AssertErrContains(t, err, "user_id")
+	})
+}
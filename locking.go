@@ -0,0 +1,163 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LockMode describes the row locks acquired by a query, it is
+// used with `ksql.WithLock()` and its possible values are
+// ksql.ForUpdate and ksql.ForShare, optionally combined
+// with the SkipLocked or NoWait methods.
+type LockMode struct {
+	exclusive  bool
+	skipLocked bool
+	noWait     bool
+}
+
+var (
+	// ForUpdate locks the selected rows for writing,
+	// i.e. `SELECT ... FOR UPDATE` on Postgres
+	ForUpdate = LockMode{exclusive: true}
+
+	// ForShare locks the selected rows for reading,
+	// i.e. `SELECT ... FOR SHARE` on Postgres
+	ForShare = LockMode{}
+)
+
+// SkipLocked returns a copy of the LockMode that skips the rows
+// locked by other transactions instead of waiting for them,
+// which is useful for implementing job queues.
+func (l LockMode) SkipLocked() LockMode {
+	l.skipLocked = true
+	return l
+}
+
+// NoWait returns a copy of the LockMode that fails immediately
+// if the rows are locked by other transactions
+// instead of waiting for them.
+func (l LockMode) NoWait() LockMode {
+	l.noWait = true
+	return l
+}
+
+type lockKey struct{}
+
+// WithLock returns a copy of the input context that makes the Query,
+// QueryOne, QueryIter and QueryChunks methods lock the selected rows, e.g.:
+//
+//	err := db.Transaction(ctx, func(db ksql.Provider) error {
+//		var job Job
+//		err := db.QueryOne(ksql.WithLock(ctx, ksql.ForUpdate.SkipLocked()), &job,
+//			"FROM jobs WHERE status = 'pending' ORDER BY id LIMIT 1",
+//		)
+//		// ...
+//	})
+//
+// The locking clause is written using the syntax of each dialect, i.e. the
+// `FOR UPDATE` family of clauses is appended to the query on Postgres,
+// MySQL, MariaDB and Oracle and the `WITH (UPDLOCK, ROWLOCK)` family of
+// table hints is added after the first table of the FROM clause on
+// SQL Server, for the queries with joins the other tables are not locked.
+//
+// Oracle doesn't support ForShare and SQLite and DuckDB don't have row
+// locks, in these cases the queries return an error. Also note that
+// the locks are only held until the end of the current transaction.
+func WithLock(ctx context.Context, mode LockMode) context.Context {
+	return context.WithValue(ctx, lockKey{}, mode)
+}
+
+// addLockClause adds the locking clause of the LockMode
+// saved on the context to the query, if there is one
+func addLockClause(ctx context.Context, dialect Dialect, query string) (string, error) {
+	mode, ok := ctx.Value(lockKey{}).(LockMode)
+	if !ok {
+		return query, nil
+	}
+
+	if mode.skipLocked && mode.noWait {
+		return "", fmt.Errorf("ksql: a LockMode can't use both SkipLocked and NoWait")
+	}
+
+	driver := dialect.DriverName()
+	if driver == "sqlserver" {
+		return addLockHint(query, mode)
+	}
+
+	var clause string
+	switch driver {
+	case "postgres", "mysql":
+		clause = "FOR SHARE"
+		if mode.exclusive {
+			clause = "FOR UPDATE"
+		}
+	case "mariadb":
+		clause = "LOCK IN SHARE MODE"
+		if mode.exclusive {
+			clause = "FOR UPDATE"
+		}
+	case "oracle":
+		if !mode.exclusive {
+			return "", fmt.Errorf("ksql: the oracle dialect doesn't support ksql.ForShare")
+		}
+		clause = "FOR UPDATE"
+	default:
+		return "", fmt.Errorf("ksql: the %s dialect doesn't support row locks", driver)
+	}
+
+	if mode.skipLocked {
+		clause += " SKIP LOCKED"
+	}
+	if mode.noWait {
+		clause += " NOWAIT"
+	}
+
+	end := scanTopLevelWords(query, func(word string, start int) bool {
+		return false
+	})
+
+	rest := query[end:]
+	return strings.TrimRight(query[:end], " \t\r\n") + " " + clause + rest, nil
+}
+
+// addLockHint adds the SQL Server table hints equivalent to the
+// LockMode after the first table of the top level FROM clause
+func addLockHint(query string, mode LockMode) (string, error) {
+	hints := []string{"HOLDLOCK", "ROWLOCK"}
+	if mode.exclusive {
+		hints = []string{"UPDLOCK", "ROWLOCK"}
+	}
+	if mode.skipLocked {
+		hints = append(hints, "READPAST")
+	}
+	if mode.noWait {
+		hints = append(hints, "NOWAIT")
+	}
+
+	foundFrom := false
+	end := scanTopLevelWords(query, func(word string, start int) bool {
+		word = strings.ToUpper(word)
+		if !foundFrom {
+			foundFrom = word == "FROM"
+			return false
+		}
+
+		switch word {
+		case "WHERE", "GROUP", "HAVING", "ORDER", "OPTION", "FOR", "UNION", "INTERSECT", "EXCEPT",
+			"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "OUTER":
+			return true
+		}
+		return false
+	})
+	if !foundFrom {
+		return "", fmt.Errorf("ksql: can't add the lock hints to a query without a FROM clause: '%s'", query)
+	}
+
+	rest := query[end:]
+	if rest != "" {
+		rest = " " + rest
+	}
+
+	return strings.TrimRight(query[:end], " \t\r\n") + " WITH (" + strings.Join(hints, ", ") + ")" + rest, nil
+}
@@ -0,0 +1,195 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAddLockClause(t *testing.T) {
+	tests := []struct {
+		desc          string
+		driver        string
+		mode          LockMode
+		query         string
+		expectedQuery string
+		expectedErr   []string
+	}{
+		{
+			desc:          "should append FOR UPDATE on postgres",
+			driver:        "postgres",
+			mode:          ForUpdate,
+			query:         "SELECT * FROM jobs WHERE status = $1 ORDER BY id LIMIT 1",
+			expectedQuery: "SELECT * FROM jobs WHERE status = $1 ORDER BY id LIMIT 1 FOR UPDATE",
+		},
+		{
+			desc:          "should append FOR SHARE SKIP LOCKED on mysql",
+			driver:        "mysql",
+			mode:          ForShare.SkipLocked(),
+			query:         "SELECT * FROM jobs",
+			expectedQuery: "SELECT * FROM jobs FOR SHARE SKIP LOCKED",
+		},
+		{
+			desc:          "should use LOCK IN SHARE MODE on mariadb",
+			driver:        "mariadb",
+			mode:          ForShare,
+			query:         "SELECT * FROM jobs",
+			expectedQuery: "SELECT * FROM jobs LOCK IN SHARE MODE",
+		},
+		{
+			desc:          "should append FOR UPDATE NOWAIT on oracle",
+			driver:        "oracle",
+			mode:          ForUpdate.NoWait(),
+			query:         "SELECT * FROM jobs",
+			expectedQuery: "SELECT * FROM jobs FOR UPDATE NOWAIT",
+		},
+		{
+			desc:          "should add the clause before the trailing semicolon",
+			driver:        "postgres",
+			mode:          ForUpdate,
+			query:         "SELECT * FROM jobs WHERE name = ';' ;",
+			expectedQuery: "SELECT * FROM jobs WHERE name = ';' FOR UPDATE;",
+		},
+		{
+			desc:          "should add the lock hints after the first table on sqlserver",
+			driver:        "sqlserver",
+			mode:          ForUpdate.SkipLocked(),
+			query:         "SELECT * FROM jobs j JOIN users u ON u.id = j.user_id WHERE j.status = @p1",
+			expectedQuery: "SELECT * FROM jobs j WITH (UPDLOCK, ROWLOCK, READPAST) JOIN users u ON u.id = j.user_id WHERE j.status = @p1",
+		},
+		{
+			desc:          "should ignore subqueries on sqlserver",
+			driver:        "sqlserver",
+			mode:          ForShare,
+			query:         "SELECT (SELECT 1 FROM users WHERE id = 1) AS one FROM [jobs]",
+			expectedQuery: "SELECT (SELECT 1 FROM users WHERE id = 1) AS one FROM [jobs] WITH (HOLDLOCK, ROWLOCK)",
+		},
+		{
+			desc:        "should report queries without FROM on sqlserver",
+			driver:      "sqlserver",
+			mode:        ForUpdate,
+			query:       "SELECT 1",
+			expectedErr: []string{"FROM clause"},
+		},
+		{
+			desc:        "should report ForShare on oracle",
+			driver:      "oracle",
+			mode:        ForShare,
+			query:       "SELECT * FROM jobs",
+			expectedErr: []string{"oracle", "ForShare"},
+		},
+		{
+			desc:        "should report dialects without row locks",
+			driver:      "sqlite3",
+			mode:        ForUpdate,
+			query:       "SELECT * FROM jobs",
+			expectedErr: []string{"sqlite3", "doesn't support row locks"},
+		},
+		{
+			desc:        "should report SkipLocked combined with NoWait",
+			driver:      "postgres",
+			mode:        ForUpdate.SkipLocked().NoWait(),
+			query:       "SELECT * FROM jobs",
+			expectedErr: []string{"SkipLocked", "NoWait"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dialect, err := GetDriverDialect(test.driver)
+			tt.AssertNoErr(t, err)
+
+			query, err := addLockClause(WithLock(context.Background(), test.mode), dialect, test.query)
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+		})
+	}
+
+	t.Run("should not change the query if the context has no lock", func(t *testing.T) {
+		query, err := addLockClause(context.Background(), supportedDialects["postgres"], "SELECT * FROM jobs")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, "SELECT * FROM jobs")
+	})
+
+	t.Run("should be used by QueryOne", func(t *testing.T) {
+		var queries []string
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				queries = append(queries, query)
+				return &fakeScalarRows{values: []interface{}{42}}, nil
+			},
+		}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		var id int
+		err = db.QueryOne(WithLock(context.Background(), ForUpdate.SkipLocked()), &id, "SELECT id FROM jobs LIMIT 1")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, id, 42)
+		tt.AssertEqual(t, queries, []string{"SELECT id FROM jobs LIMIT 1 FOR UPDATE SKIP LOCKED"})
+	})
+
+	t.Run("should be used by QueryIter and QueryChunks", func(t *testing.T) {
+		var queries []string
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				queries = append(queries, query)
+				return &fakeUserRows{emails: []string{"fake@example.com"}}, nil
+			},
+		}, "postgres")
+		tt.AssertNoErr(t, err)
+
+		type job struct {
+			ID    uint   `ksql:"id"`
+			Email string `ksql:"email"`
+		}
+
+		ctx := WithLock(context.Background(), ForUpdate)
+		cursor := db.QueryIter(ctx, "SELECT id, email FROM jobs")
+		var j job
+		tt.AssertEqual(t, cursor.Next(&j), true)
+		tt.AssertNoErr(t, cursor.Close())
+
+		err = db.QueryChunks(ctx, ChunkParser{
+			Query:     "SELECT id, email FROM jobs",
+			ChunkSize: 10,
+			ForEachChunk: func(jobs []job) error {
+				return nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, queries, []string{
+			"SELECT id, email FROM jobs FOR UPDATE",
+			"SELECT id, email FROM jobs FOR UPDATE",
+		})
+	})
+
+	t.Run("should report the unsupported locks on QueryIter and QueryChunks", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		type job struct {
+			ID uint `ksql:"id"`
+		}
+
+		ctx := WithLock(context.Background(), ForUpdate)
+		cursor := db.QueryIter(ctx, "SELECT id FROM jobs")
+		var j job
+		tt.AssertEqual(t, cursor.Next(&j), false)
+		tt.AssertErrContains(t, cursor.Err(), "sqlite3", "row locks")
+
+		err = db.QueryChunks(ctx, ChunkParser{
+			Query:     "SELECT id FROM jobs",
+			ChunkSize: 10,
+			ForEachChunk: func(jobs []job) error {
+				return nil
+			},
+		})
+		tt.AssertErrContains(t, err, "sqlite3", "row locks")
+	})
+}
@@ -0,0 +1,317 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LogLevel is the severity of the messages sent to the ksql.Logger,
+// its values are the same used by the `log/slog` package, so
+// it can be converted with `slog.Level(level)`.
+type LogLevel int
+
+// The log levels used by KSQL
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Logger receives the logs of the statements sent to the database, the
+// keyvals are alternating keys and values just like on the `log/slog`
+// package, and the `ksql.NewSlogLogger()` function adapts a *slog.Logger
+// to this interface.
+//
+// The successful statements are logged with LogLevelDebug, the ones
+// slower than the `Config.SlowQueryThreshold` with LogLevelWarn and
+// the failed ones with LogLevelError, with the following keys:
+//
+//   - "query": the query with its whitespace normalized
+//   - "params": the params, redacted if `Config.RedactLogParams` is set
+//   - "duration": the time.Duration of the statement
+//   - "rows_affected" or "rows": the number of rows affected by the
+//     statement or returned by the query, when available
+//   - "params_digest", "caller" and "plan": only on slow statements,
+//     see `ksql.SlowQuery` for more details
+//   - "error": the error returned by the statement, if any
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc allows the use of ordinary functions as loggers
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+// Log implements the Logger interface
+func (l LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	l(ctx, level, msg, keyvals...)
+}
+
+// statementLogger logs the statements sent to the database
+// and reports the ones slower than the slowThreshold
+type statementLogger struct {
+	logger       Logger
+	redactParams bool
+
+	slowThreshold time.Duration
+	onSlowQuery   func(ctx context.Context, q SlowQuery)
+
+	// explainSlow enables the EXPLAIN of the slow statements,
+	// which requires the driverName for choosing the syntax
+	explainSlow bool
+	driverName  string
+}
+
+func (l statementLogger) log(
+	ctx context.Context,
+	db DBAdapter,
+	query string,
+	params []interface{},
+	duration time.Duration,
+	rowsKey string,
+	rows int64,
+	err error,
+) {
+	isSlow := l.slowThreshold > 0 && duration >= l.slowThreshold
+
+	var slow SlowQuery
+	if isSlow {
+		slow = SlowQuery{
+			Query:        normalizeQuery(query),
+			ParamsDigest: paramsDigest(params),
+			Duration:     duration,
+			Caller:       callerLocation(),
+			Err:          err,
+		}
+		if l.explainSlow && err == nil {
+			slow.Plan, slow.PlanErr = explainQuery(ctx, db, l.driverName, false, query, params)
+		}
+		if l.onSlowQuery != nil {
+			l.onSlowQuery(ctx, slow)
+		}
+	}
+
+	if l.logger == nil {
+		return
+	}
+
+	if l.redactParams {
+		redacted := make([]interface{}, len(params))
+		for i := range redacted {
+			redacted[i] = "[REDACTED]"
+		}
+		params = redacted
+	}
+
+	keyvals := []interface{}{
+		"query", normalizeQuery(query),
+		"params", params,
+		"duration", duration,
+	}
+	if rows >= 0 {
+		keyvals = append(keyvals, rowsKey, rows)
+	}
+	if isSlow {
+		keyvals = append(keyvals, "params_digest", slow.ParamsDigest, "caller", slow.Caller)
+		if slow.Plan != "" {
+			keyvals = append(keyvals, "plan", slow.Plan)
+		}
+	}
+
+	if err != nil {
+		l.logger.Log(ctx, LogLevelError, "ksql: statement failed", append(keyvals, "error", err)...)
+		return
+	}
+
+	if isSlow {
+		l.logger.Log(ctx, LogLevelWarn, "ksql: slow statement", keyvals...)
+		return
+	}
+
+	l.logger.Log(ctx, LogLevelDebug, "ksql: statement executed", keyvals...)
+}
+
+// normalizeQuery collapses all the whitespace of the query, so
+// that queries written on multiple lines fit on a single log line.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// loggingAdapter wraps a DBAdapter logging all the statements sent to it
+type loggingAdapter struct {
+	DBAdapter
+
+	logger statementLogger
+}
+
+// ExecContext implements the DBAdapter interface
+func (l loggingAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return logExec(ctx, l.logger, l.DBAdapter, query, args)
+}
+
+// QueryContext implements the DBAdapter interface
+func (l loggingAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return logQuery(ctx, l.logger, l.DBAdapter, query, args)
+}
+
+// BeginTx implements the TxBeginner interface
+func (l loggingAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := l.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return loggingTx{Tx: tx, logger: l.logger}, nil
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (l loggingAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := l.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTxWithOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return loggingTx{Tx: tx, logger: l.logger}, nil
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (l loggingAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := l.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (l loggingAdapter) Close() error {
+	closer, ok := l.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func (l loggingAdapter) unwrapAdapter() DBAdapter {
+	return l.DBAdapter
+}
+
+// loggingTx wraps a Tx logging all the statements sent to it
+type loggingTx struct {
+	Tx
+
+	logger statementLogger
+}
+
+func (l loggingTx) unwrapAdapter() DBAdapter {
+	return l.Tx
+}
+
+// ExecContext implements the Tx interface
+func (l loggingTx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return logExec(ctx, l.logger, l.Tx, query, args)
+}
+
+// QueryContext implements the Tx interface
+func (l loggingTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return logQuery(ctx, l.logger, l.Tx, query, args)
+}
+
+func logExec(ctx context.Context, logger statementLogger, db DBAdapter, query string, args []interface{}) (Result, error) {
+	start := time.Now()
+	result, err := db.ExecContext(ctx, query, args...)
+	duration := time.Since(start)
+
+	rowsAffected := int64(-1)
+	if err == nil && result != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			rowsAffected = n
+		}
+	}
+
+	logger.log(ctx, db, query, args, duration, "rows_affected", rowsAffected, err)
+	return result, err
+}
+
+func logQuery(ctx context.Context, logger statementLogger, db DBAdapter, query string, args []interface{}) (Rows, error) {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.log(ctx, db, query, args, time.Since(start), "rows", -1, err)
+		return rows, err
+	}
+
+	return &loggingRows{
+		Rows:   rows,
+		ctx:    ctx,
+		db:     db,
+		logger: logger,
+		query:  query,
+		params: args,
+		start:  start,
+	}, nil
+}
+
+// loggingRows logs the query when the rows are closed, so that
+// the log contains the number of rows read and the errors that
+// only happen while iterating over the results.
+type loggingRows struct {
+	Rows
+
+	ctx    context.Context
+	db     DBAdapter
+	logger statementLogger
+	query  string
+	params []interface{}
+	start  time.Time
+
+	count  int64
+	logged bool
+}
+
+// Next implements the Rows interface
+func (l *loggingRows) Next() bool {
+	hasNext := l.Rows.Next()
+	if hasNext {
+		l.count++
+	}
+	return hasNext
+}
+
+// Close implements the Rows interface
+func (l *loggingRows) Close() error {
+	err := l.Rows.Close()
+	if !l.logged {
+		l.logged = true
+		l.logger.log(l.ctx, l.db, l.query, l.params, time.Since(l.start), "rows", l.count, l.Rows.Err())
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+//go:build go1.21
+// +build go1.21
+
+package ksql
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger adapts a *slog.Logger to the ksql.Logger interface, e.g.:
+//
+//	db, err := ksqlite3.New(ctx, "/tmp/db.sqlite", ksql.Config{
+//		Logger: ksql.NewSlogLogger(slog.Default()),
+//	})
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		logger.Log(ctx, slog.Level(level), msg, keyvals...)
+	})
+}
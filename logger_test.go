@@ -0,0 +1,137 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type logEntry struct {
+	level   LogLevel
+	msg     string
+	keyvals map[string]interface{}
+}
+
+func newRecordingLogger(entries *[]logEntry) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		m := map[string]interface{}{}
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			m[keyvals[i].(string)] = keyvals[i+1]
+		}
+		*entries = append(*entries, logEntry{level: level, msg: msg, keyvals: m})
+	})
+}
+
+type fakeRows struct {
+	Rows
+
+	remaining int
+	closeErr  error
+}
+
+func (f *fakeRows) Next() bool {
+	if f.remaining == 0 {
+		return false
+	}
+	f.remaining--
+	return true
+}
+
+func (f *fakeRows) Err() error {
+	return nil
+}
+
+func (f *fakeRows) Close() error {
+	return f.closeErr
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("should log successful statements with debug level", func(t *testing.T) {
+		var entries []logEntry
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				return NewMockResult(0, 3), nil
+			},
+		}, "sqlite3", Config{
+			Logger: newRecordingLogger(&entries),
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "UPDATE users\n\tSET age = ?\n\tWHERE name = ?", 42, "fake-name")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(entries), 1)
+		tt.AssertEqual(t, entries[0].level, LogLevelDebug)
+		tt.AssertEqual(t, entries[0].keyvals["query"], "UPDATE users SET age = ? WHERE name = ?")
+		tt.AssertEqual(t, entries[0].keyvals["params"], []interface{}{42, "fake-name"})
+		tt.AssertEqual(t, entries[0].keyvals["rows_affected"], int64(3))
+		tt.AssertEqual(t, entries[0].keyvals["error"], nil)
+	})
+
+	t.Run("should log failed statements with error level", func(t *testing.T) {
+		var entries []logEntry
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				return nil, errors.New("fake error")
+			},
+		}, "sqlite3", Config{
+			Logger: newRecordingLogger(&entries),
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "DELETE FROM users")
+		tt.AssertErrContains(t, err, "fake error")
+
+		tt.AssertEqual(t, len(entries), 1)
+		tt.AssertEqual(t, entries[0].level, LogLevelError)
+		tt.AssertEqual(t, entries[0].keyvals["error"], errors.New("fake error"))
+		_, hasRows := entries[0].keyvals["rows_affected"]
+		tt.AssertEqual(t, hasRows, false)
+	})
+
+	t.Run("should redact the params when RedactLogParams is set", func(t *testing.T) {
+		var entries []logEntry
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3", Config{
+			Logger:          newRecordingLogger(&entries),
+			RedactLogParams: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "UPDATE users SET password = ?", "secret")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(entries), 1)
+		tt.AssertEqual(t, entries[0].keyvals["params"], []interface{}{"[REDACTED]"})
+	})
+
+	t.Run("should log queries once with the number of rows read", func(t *testing.T) {
+		var entries []logEntry
+		rows := &fakeRows{remaining: 2}
+		adapter := loggingAdapter{
+			DBAdapter: mockDBAdapter{
+				QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+					return rows, nil
+				},
+			},
+			logger: statementLogger{logger: newRecordingLogger(&entries)},
+		}
+
+		r, err := adapter.QueryContext(context.Background(), "SELECT id FROM users")
+		tt.AssertNoErr(t, err)
+		for r.Next() {
+		}
+		tt.AssertEqual(t, len(entries), 0)
+
+		tt.AssertNoErr(t, r.Close())
+		tt.AssertNoErr(t, r.Close())
+
+		tt.AssertEqual(t, len(entries), 1)
+		tt.AssertEqual(t, entries[0].keyvals["rows"], int64(2))
+	})
+}
@@ -0,0 +1,106 @@
+package ksql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MetricsHook is called before and after each operation of
+// a ksql.DB, it can be set with the `ksql.Config.MetricsHook`
+// attribute or with `db.With(ksql.NewMetricsMiddleware(hook))`.
+//
+// The `kprometheus` module contains a ready-made
+// implementation for Prometheus.
+type MetricsHook interface {
+	// OnQueryStart is called before the operation starts, the
+	// returned context is passed to the OnQueryEnd method, which
+	// is useful for storing values, e.g. tracing spans.
+	OnQueryStart(ctx context.Context, op Operation) context.Context
+
+	// OnQueryEnd is called after the operation finishes
+	OnQueryEnd(ctx context.Context, op Operation, metrics QueryMetrics)
+}
+
+// QueryMetrics contains the measurements of an
+// operation that are sent to the MetricsHook
+type QueryMetrics struct {
+	Duration time.Duration
+
+	// Err is the error returned by the operation, if any
+	Err error
+
+	// ErrorClass is the result of `ksql.ErrorClass(Err)`
+	ErrorClass string
+}
+
+// The error classes returned by `ksql.ErrorClass()`
+const (
+	ErrorClassNone                = ""
+	ErrorClassNotFound            = "not_found"
+	ErrorClassUniqueViolation     = "unique_violation"
+	ErrorClassForeignKeyViolation = "foreign_key_violation"
+	ErrorClassCheckViolation      = "check_violation"
+	ErrorClassNotNullViolation    = "not_null_violation"
+	ErrorClassVersionConflict     = "version_conflict"
+	ErrorClassValidation          = "validation"
+	ErrorClassTimeout             = "timeout"
+	ErrorClassCanceled            = "canceled"
+	ErrorClassCircuitOpen         = "circuit_open"
+	ErrorClassOther               = "other"
+)
+
+// ErrorClass classifies the errors returned by KSQL into a small set
+// of values, which is useful as a label on metrics, e.g. "not_found"
+// or "unique_violation", it returns an empty string for nil errors.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ErrorClassNone
+	}
+
+	switch {
+	case IsNotFound(err):
+		return ErrorClassNotFound
+	case errors.As(err, &ErrUniqueViolation{}):
+		return ErrorClassUniqueViolation
+	case errors.As(err, &ErrForeignKeyViolation{}):
+		return ErrorClassForeignKeyViolation
+	case errors.As(err, &ErrCheckViolation{}):
+		return ErrorClassCheckViolation
+	case errors.As(err, &ErrNotNullViolation{}):
+		return ErrorClassNotNullViolation
+	case errors.Is(err, ErrVersionConflict):
+		return ErrorClassVersionConflict
+	case errors.As(err, &ErrValidation{}):
+		return ErrorClassValidation
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), context.DeadlineExceeded.Error()):
+		// Some errors are formatted as strings, so we also check the message:
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled) || strings.Contains(err.Error(), context.Canceled.Error()):
+		return ErrorClassCanceled
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorClassCircuitOpen
+	}
+
+	return ErrorClassOther
+}
+
+// NewMetricsMiddleware returns a Middleware that calls
+// the input MetricsHook before and after each operation.
+func NewMetricsMiddleware(hook MetricsHook) Middleware {
+	return MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+		ctx = hook.OnQueryStart(ctx, op)
+
+		start := time.Now()
+		err := next(ctx, op)
+
+		hook.OnQueryEnd(ctx, op, QueryMetrics{
+			Duration:   time.Since(start),
+			Err:        err,
+			ErrorClass: ErrorClass(err),
+		})
+		return err
+	})
+}
@@ -0,0 +1,84 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type mockMetricsHook struct {
+	OnQueryStartFn func(ctx context.Context, op Operation) context.Context
+	OnQueryEndFn   func(ctx context.Context, op Operation, metrics QueryMetrics)
+}
+
+func (m mockMetricsHook) OnQueryStart(ctx context.Context, op Operation) context.Context {
+	return m.OnQueryStartFn(ctx, op)
+}
+
+func (m mockMetricsHook) OnQueryEnd(ctx context.Context, op Operation, metrics QueryMetrics) {
+	m.OnQueryEndFn(ctx, op, metrics)
+}
+
+func TestMetricsHook(t *testing.T) {
+	type ctxKey struct{}
+
+	var startOps []Operation
+	var endOps []Operation
+	var endMetrics []QueryMetrics
+	var endCtxValues []interface{}
+	db, err := NewWithConfig(mockDBAdapter{
+		ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+			return NewMockResult(0, 0), nil
+		},
+	}, "sqlite3", Config{
+		MetricsHook: mockMetricsHook{
+			OnQueryStartFn: func(ctx context.Context, op Operation) context.Context {
+				startOps = append(startOps, op)
+				return context.WithValue(ctx, ctxKey{}, "fake-value")
+			},
+			OnQueryEndFn: func(ctx context.Context, op Operation, metrics QueryMetrics) {
+				endOps = append(endOps, op)
+				endMetrics = append(endMetrics, metrics)
+				endCtxValues = append(endCtxValues, ctx.Value(ctxKey{}))
+			},
+		},
+	})
+	tt.AssertNoErr(t, err)
+
+	err = db.Delete(context.Background(), usersTable, 42)
+	tt.AssertEqual(t, IsNotFound(err), true)
+
+	expectedOp := Operation{Method: "Delete", Table: "users", Record: 42}
+	tt.AssertEqual(t, startOps, []Operation{expectedOp})
+	tt.AssertEqual(t, endOps, []Operation{expectedOp})
+	tt.AssertEqual(t, endCtxValues, []interface{}{"fake-value"})
+	tt.AssertEqual(t, endMetrics[0].Err, err)
+	tt.AssertEqual(t, endMetrics[0].ErrorClass, ErrorClassNotFound)
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		desc     string
+		err      error
+		expected string
+	}{
+		{desc: "nil errors", err: nil, expected: ErrorClassNone},
+		{desc: "not found errors", err: NotFoundError{Table: "users"}, expected: ErrorClassNotFound},
+		{desc: "unique violations", err: ErrUniqueViolation{Err: errors.New("fake")}, expected: ErrorClassUniqueViolation},
+		{desc: "wrapped violations", err: fmt.Errorf("fake: %w", ErrCheckViolation{Err: errors.New("fake")}), expected: ErrorClassCheckViolation},
+		{desc: "version conflicts", err: ErrVersionConflict, expected: ErrorClassVersionConflict},
+		{desc: "validation errors", err: ErrValidation{Err: errors.New("fake")}, expected: ErrorClassValidation},
+		{desc: "timeouts", err: context.DeadlineExceeded, expected: ErrorClassTimeout},
+		{desc: "timeouts formatted as strings", err: fmt.Errorf("error running query: %s", context.DeadlineExceeded), expected: ErrorClassTimeout},
+		{desc: "open circuits", err: ErrCircuitOpen, expected: ErrorClassCircuitOpen},
+		{desc: "other errors", err: errors.New("fake error"), expected: ErrorClassOther},
+	}
+	for _, test := range tests {
+		t.Run("should classify "+test.desc, func(t *testing.T) {
+			tt.AssertEqual(t, ErrorClass(test.err), test.expected)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package ksql
+
+import "context"
+
+// Operation describes a call to one of the ksql.DB methods
+// and is received by the middlewares intercepting it.
+type Operation struct {
+	// Method is the name of the ksql.DB method, e.g. "Insert" or "Query"
+	Method string
+
+	// Table is the name of the table for the methods receiving a ksql.Table
+	Table string
+
+	// Query and Params are only set for the methods receiving a query,
+	// e.g. Query, QueryOne and Exec, on these methods a middleware can
+	// also rewrite them by passing a modified Operation to the next function.
+	Query  string
+	Params []interface{}
+
+	// Record is the record, ID or map received by the method, if any
+	Record interface{}
+}
+
+// NextFn runs the next middleware of the chain, or the
+// operation itself if there are no more middlewares.
+type NextFn func(ctx context.Context, op Operation) error
+
+// Middleware intercepts all the operations of a ksql.DB, which is useful
+// for implementing cross-cutting features like logging and metrics.
+//
+// Each middleware must call the next function, unless it wants to
+// abort the operation, and return the error it returns, or a
+// different error if the middleware wants to replace it.
+//
+// See `DB.With()` for more details.
+type Middleware interface {
+	Intercept(ctx context.Context, op Operation, next NextFn) error
+}
+
+// MiddlewareFunc allows the use of ordinary functions as middlewares
+type MiddlewareFunc func(ctx context.Context, op Operation, next NextFn) error
+
+// Intercept implements the Middleware interface
+func (m MiddlewareFunc) Intercept(ctx context.Context, op Operation, next NextFn) error {
+	return m(ctx, op, next)
+}
+
+// With returns a copy of the DB that runs all its operations through the
+// input middlewares, the first middleware being the outermost one, e.g.:
+//
+//	db = db.With(ksql.MiddlewareFunc(func(ctx context.Context, op ksql.Operation, next ksql.NextFn) error {
+//		start := time.Now()
+//		err := next(ctx, op)
+//		log.Printf("%s on table '%s' took %s", op.Method, op.Table, time.Since(start))
+//		return err
+//	}))
+//
+// The middlewares are appended to the ones already registered on the DB,
+// and they also intercept the operations made inside transactions.
+//
+// On QueryIter the operation runs on the first call to `Cursor.Next()`,
+// when the query is sent to the database, and on Begin it only covers
+// the start of the transaction, Commit and Rollback are not intercepted.
+//
+// HealthCheck and Close are not intercepted either.
+func (c DB) With(middlewares ...Middleware) DB {
+	dbCopy := c
+	dbCopy.middlewares = append(append([]Middleware{}, c.middlewares...), middlewares...)
+	return dbCopy
+}
+
+// intercept runs the input operation through the middlewares of the DB
+func (c DB) intercept(ctx context.Context, op Operation, fn NextFn) error {
+	ctx = c.withEncryptor(ctx)
+	ctx = c.withTimeLocation(ctx)
+
+	if c.rebindPlaceholders && op.Query != "" {
+		op.Query = Rebind(c.dialect, op.Query)
+	}
+
+	next := fn
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		middleware, nextFn := c.middlewares[i], next
+		next = func(ctx context.Context, op Operation) error {
+			return middleware.Intercept(ctx, op, nextFn)
+		}
+	}
+
+	err := next(ctx, op)
+
+	// The writes are invalidated even on errors since
+	// some of them might have written part of the rows:
+	c.invalidateCache(ctx, op)
+
+	return err
+}
@@ -0,0 +1,140 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestMiddlewares(t *testing.T) {
+	newDB := func(t *testing.T, queries *[]string) DB {
+		db, err := NewWithAdapter(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				*queries = append(*queries, query)
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	recorder := func(name string, calls *[]string) Middleware {
+		return MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+			*calls = append(*calls, name+" before "+op.Method)
+			err := next(ctx, op)
+			*calls = append(*calls, name+" after "+op.Method)
+			return err
+		})
+	}
+
+	t.Run("should run the middlewares in order", func(t *testing.T) {
+		var queries, calls []string
+		db := newDB(t, &queries).With(recorder("mw1", &calls)).With(recorder("mw2", &calls))
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, []string{
+			"mw1 before Exec",
+			"mw2 before Exec",
+			"mw2 after Exec",
+			"mw1 after Exec",
+		})
+		tt.AssertEqual(t, queries, []string{"fake query"})
+	})
+
+	t.Run("should not affect the original DB", func(t *testing.T) {
+		var queries, calls []string
+		db := newDB(t, &queries)
+		_ = db.With(recorder("mw1", &calls))
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(calls), 0)
+	})
+
+	t.Run("should describe the operation", func(t *testing.T) {
+		var queries []string
+		var ops []Operation
+		db := newDB(t, &queries).With(MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+			ops = append(ops, op)
+			return next(ctx, op)
+		}))
+
+		record := &user{ID: 42, Name: "fake-name"}
+		err := db.Patch(context.Background(), usersTable, record)
+		tt.AssertNoErr(t, err)
+		_, err = db.Exec(context.Background(), "fake query", 42)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, ops, []Operation{
+			{Method: "Patch", Table: "users", Record: record},
+			{Method: "Exec", Query: "fake query", Params: []interface{}{42}},
+		})
+	})
+
+	t.Run("should intercept QueryIter and Begin", func(t *testing.T) {
+		var queries []string
+		var ops []Operation
+		db, err := NewWithAdapter(mockTxBeginner{
+			DBAdapter: mockDBAdapter{
+				QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+					queries = append(queries, query)
+					return &fakeUserRows{emails: []string{"fake@example.com"}}, nil
+				},
+			},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{DBAdapter: mockDBAdapter{}}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		db = db.With(MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+			ops = append(ops, op)
+			op.Query = "/* fake comment */ " + op.Query
+			return next(ctx, op)
+		}))
+
+		cursor := db.QueryIter(context.Background(), "SELECT id, email FROM users WHERE id = ?", 42)
+		var u struct {
+			ID    uint   `ksql:"id"`
+			Email string `ksql:"email"`
+		}
+		tt.AssertEqual(t, cursor.Next(&u), true)
+		tt.AssertNoErr(t, cursor.Close())
+		tt.AssertEqual(t, u.Email, "fake@example.com")
+
+		_, err = db.Begin(context.Background())
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, ops, []Operation{
+			{Method: "QueryIter", Query: "SELECT id, email FROM users WHERE id = ?", Params: []interface{}{42}},
+			{Method: "Begin"},
+		})
+		tt.AssertEqual(t, queries, []string{"/* fake comment */ SELECT id, email FROM users WHERE id = ?"})
+	})
+
+	t.Run("should allow the middlewares to rewrite the query", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries).With(MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+			op.Query = "/* fake comment */ " + op.Query
+			return next(ctx, op)
+		}))
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"/* fake comment */ fake query"})
+	})
+
+	t.Run("should allow the middlewares to abort the operation", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries).With(MiddlewareFunc(func(ctx context.Context, op Operation, next NextFn) error {
+			return errors.New("fake-middleware-error")
+		}))
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertErrContains(t, err, "fake-middleware-error")
+		tt.AssertEqual(t, len(queries), 0)
+	})
+}
@@ -0,0 +1,430 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MockT is the subset of the testing.TB interface used by the ExpectationMock
+type MockT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// MockAnyArg can be passed to WithArgs for matching any value on its position
+var MockAnyArg interface{} = mockAnyArg{}
+
+type mockAnyArg struct{}
+
+func (mockAnyArg) String() string {
+	return "<any>"
+}
+
+// ExpectationMock is a ksql.Mock that checks the calls it receives
+// against a list of expectations, which makes it easier to write tests
+// that use several operations of the database, e.g.:
+//
+//	mock := ksql.NewExpectationMock(t)
+//	mock.ExpectQuery("FROM users WHERE age > $1").WithArgs(18).Return([]User{
+//		{ID: 1, Name: "Ana", Age: 20},
+//	})
+//	mock.ExpectPatch(UsersTable).WithArgs(&User{ID: 1, Name: "Ana", Age: 21})
+//
+//	service := myservice.New(mock)
+//
+// By default the calls must happen in the same order as the expectations,
+// use the InAnyOrder method for accepting them on any order.
+//
+// Unexpected calls fail the test and return an error, and the expectations
+// not met when the test ends also fail the test, this check can also be
+// made explicitly with the ExpectationsWereMet method.
+//
+// Since the ExpectationMock embeds a ksql.Mock its Fn attributes can
+// still be overwritten for customizing the behavior of some operations.
+type ExpectationMock struct {
+	Mock
+
+	// QueryMatcher compares the expected and the received queries,
+	// by default the queries are compared ignoring the differences
+	// on the whitespace, e.g. indentation and line breaks.
+	QueryMatcher func(expected string, actual string) bool
+
+	t       MockT
+	mu      sync.Mutex
+	ordered bool
+
+	expectations []*MockExpectation
+}
+
+// MockExpectation describes a single call expected by an ExpectationMock
+type MockExpectation struct {
+	method string
+	query  string
+	table  string
+
+	args    []interface{}
+	hasArgs bool
+
+	value    interface{}
+	hasValue bool
+	err      error
+
+	met bool
+}
+
+// NewExpectationMock creates an ExpectationMock that reports
+// its failures on t, including the expectations that were
+// not met when the test ends.
+func NewExpectationMock(t MockT) *ExpectationMock {
+	m := &ExpectationMock{
+		QueryMatcher: matchQueriesIgnoringSpaces,
+		t:            t,
+		ordered:      true,
+	}
+
+	m.Mock = Mock{
+		InsertFn: func(ctx context.Context, table Table, record interface{}) error {
+			_, err := m.call("Insert", table.name, "", []interface{}{record}, record)
+			return err
+		},
+		PatchFn: func(ctx context.Context, table Table, record interface{}) error {
+			_, err := m.call("Patch", table.name, "", []interface{}{record}, nil)
+			return err
+		},
+		DeleteFn: func(ctx context.Context, table Table, idOrRecord interface{}) error {
+			_, err := m.call("Delete", table.name, "", []interface{}{idOrRecord}, nil)
+			return err
+		},
+		UpdateFn: func(ctx context.Context, table Table, record interface{}) error {
+			_, err := m.call("Update", table.name, "", []interface{}{record}, nil)
+			return err
+		},
+		QueryFn: func(ctx context.Context, records interface{}, query string, params ...interface{}) error {
+			_, err := m.call("Query", "", query, params, records)
+			return err
+		},
+		QueryOneFn: func(ctx context.Context, record interface{}, query string, params ...interface{}) error {
+			_, err := m.call("QueryOne", "", query, params, record)
+			return err
+		},
+		QueryChunksFn: func(ctx context.Context, parser ChunkParser) error {
+			e, err := m.call("QueryChunks", "", parser.Query, parser.Params, nil)
+			if err != nil {
+				return err
+			}
+			return mockQueryChunks(parser, e.value)
+		},
+		ExecFn: func(ctx context.Context, query string, params ...interface{}) (Result, error) {
+			e, err := m.call("Exec", "", query, params, nil)
+			if err != nil {
+				return nil, err
+			}
+			if result, ok := e.value.(Result); ok {
+				return result, nil
+			}
+			return NewMockResult(0, 0), nil
+		},
+		TransactionFn: func(ctx context.Context, fn func(db Provider) error) error {
+			e, err := m.call("Transaction", "", "", nil, nil)
+			if err != nil {
+				return err
+			}
+			// The error of the expectation simulates a failure on the commit:
+			err = fn(m)
+			if err != nil {
+				return err
+			}
+			return e.err
+		},
+	}
+
+	t.Cleanup(func() {
+		t.Helper()
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s", err)
+		}
+	})
+
+	return m
+}
+
+// InAnyOrder allows the expectations to be met in any order
+func (m *ExpectationMock) InAnyOrder() *ExpectationMock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ordered = false
+	return m
+}
+
+// ExpectInsert expects a call to Insert on the table, the inserted record can
+// be checked with WithArgs, and the value informed to Return is copied into
+// the input record, which is useful for simulating the generated IDs.
+func (m *ExpectationMock) ExpectInsert(table Table) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Insert", table: table.name})
+}
+
+// ExpectPatch expects a call to Patch on the table, the
+// updated record can be checked with WithArgs
+func (m *ExpectationMock) ExpectPatch(table Table) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Patch", table: table.name})
+}
+
+// ExpectUpdate expects a call to Update on the table, the
+// updated record can be checked with WithArgs
+func (m *ExpectationMock) ExpectUpdate(table Table) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Update", table: table.name})
+}
+
+// ExpectDelete expects a call to Delete on the table, the
+// ID or record being deleted can be checked with WithArgs
+func (m *ExpectationMock) ExpectDelete(table Table) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Delete", table: table.name})
+}
+
+// ExpectQuery expects a call to Query, the value informed to
+// Return must be a slice of the same type of the records
+func (m *ExpectationMock) ExpectQuery(query string) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Query", query: query})
+}
+
+// ExpectQueryOne expects a call to QueryOne, the value informed
+// to Return must be of the same type of the record
+func (m *ExpectationMock) ExpectQueryOne(query string) *MockExpectation {
+	return m.expect(&MockExpectation{method: "QueryOne", query: query})
+}
+
+// ExpectQueryChunks expects a call to QueryChunks, the value informed to
+// Return must be a slice of records, which is split in chunks of the
+// ChunkSize of the parser before being passed to ForEachChunk.
+func (m *ExpectationMock) ExpectQueryChunks(query string) *MockExpectation {
+	return m.expect(&MockExpectation{method: "QueryChunks", query: query})
+}
+
+// ExpectExec expects a call to Exec, the value informed to Return
+// must be a ksql.Result, e.g. `ksql.NewMockResult(0, 1)`
+func (m *ExpectationMock) ExpectExec(query string) *MockExpectation {
+	return m.expect(&MockExpectation{method: "Exec", query: query})
+}
+
+// ExpectTransaction expects a call to Transaction, the calls made inside
+// the transaction are checked against the expectations that follow it,
+// and the error informed to ReturnError is returned after the input
+// function succeeds, simulating an error on the commit.
+func (m *ExpectationMock) ExpectTransaction() *MockExpectation {
+	return m.expect(&MockExpectation{method: "Transaction"})
+}
+
+func (m *ExpectationMock) expect(e *MockExpectation) *MockExpectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// WithArgs sets the arguments expected by the call, i.e. the params of
+// the queries or the record of the write operations, which are compared
+// using reflect.DeepEqual, MockAnyArg can be used for accepting any value.
+func (e *MockExpectation) WithArgs(args ...interface{}) *MockExpectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// Return sets the value returned by the call, see the
+// documentation of each of the Expect methods for details
+func (e *MockExpectation) Return(value interface{}) *MockExpectation {
+	e.value = value
+	e.hasValue = true
+	return e
+}
+
+// ReturnError sets the error returned by the call
+func (e *MockExpectation) ReturnError(err error) *MockExpectation {
+	e.err = err
+	return e
+}
+
+// ExpectationsWereMet returns an error describing
+// the expectations that were not met so far.
+func (m *ExpectationMock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.met {
+			unmet = append(unmet, "\n\t- "+e.String())
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("ksql.ExpectationMock: there are %d unmet expectations:%s", len(unmet), strings.Join(unmet, ""))
+	}
+
+	return nil
+}
+
+func (e *MockExpectation) String() string {
+	target := fmt.Sprintf("%q", e.query)
+	if e.table != "" {
+		target = fmt.Sprintf("table %q", e.table)
+	}
+	if e.method == "Transaction" {
+		target = ""
+	}
+
+	if !e.hasArgs {
+		return fmt.Sprintf("%s(%s)", e.method, target)
+	}
+	return fmt.Sprintf("%s(%s) with args %s", e.method, target, formatMockArgs(e.args))
+}
+
+func (m *ExpectationMock) call(method string, table string, query string, args []interface{}, dest interface{}) (*MockExpectation, error) {
+	m.t.Helper()
+
+	e, err := m.findExpectation(method, table, query, args)
+	if err != nil {
+		m.t.Errorf("%s", err)
+		return nil, err
+	}
+
+	if e.err != nil && method != "Transaction" {
+		return e, e.err
+	}
+
+	if dest != nil && e.hasValue {
+		err = copyMockValue(dest, e.value)
+		if err != nil {
+			err = fmt.Errorf("ksql.ExpectationMock: unable to return the value of %s: %s", e, err)
+			m.t.Errorf("%s", err)
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func (m *ExpectationMock) findExpectation(method string, table string, query string, args []interface{}) (*MockExpectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	received := &MockExpectation{method: method, table: table, query: query, args: args, hasArgs: len(args) > 0}
+	for _, e := range m.expectations {
+		if e.met {
+			continue
+		}
+
+		if m.matches(e, method, table, query, args) {
+			e.met = true
+			return e, nil
+		}
+
+		if m.ordered {
+			return nil, fmt.Errorf("ksql.ExpectationMock: received %s but the next expectation is %s", received, e)
+		}
+	}
+
+	return nil, fmt.Errorf("ksql.ExpectationMock: received unexpected call %s", received)
+}
+
+func (m *ExpectationMock) matches(e *MockExpectation, method string, table string, query string, args []interface{}) bool {
+	if e.method != method || e.table != table {
+		return false
+	}
+	if e.query != "" && !m.QueryMatcher(e.query, query) {
+		return false
+	}
+	if !e.hasArgs {
+		return true
+	}
+
+	if len(e.args) != len(args) {
+		return false
+	}
+	for i := range args {
+		if e.args[i] == MockAnyArg {
+			continue
+		}
+		if !reflect.DeepEqual(e.args[i], args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchQueriesIgnoringSpaces(expected string, actual string) bool {
+	return strings.Join(strings.Fields(expected), " ") == strings.Join(strings.Fields(actual), " ")
+}
+
+func formatMockArgs(args []interface{}) string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		v := reflect.ValueOf(arg)
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			arg = v.Elem().Interface()
+		}
+		strs[i] = fmt.Sprintf("%+v", arg)
+	}
+	return "[" + strings.Join(strs, ", ") + "]"
+}
+
+// copyMockValue copies the value into the pointer dest,
+// the value can be either of the type of the dest or a
+// pointer to it.
+func copyMockValue(dest interface{}, value interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("expected a non nil pointer as the destination, but got: %T", dest)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.Type() != destValue.Elem().Type() {
+		v = v.Elem()
+	}
+	if v.Type() != destValue.Elem().Type() {
+		return fmt.Errorf("can't assign %T to %T", value, dest)
+	}
+
+	destValue.Elem().Set(v)
+	return nil
+}
+
+func mockQueryChunks(parser ChunkParser, records interface{}) error {
+	fn := reflect.ValueOf(parser.ForEachChunk)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 || fn.Type().In(0).Kind() != reflect.Slice {
+		return fmt.Errorf("ksql.ExpectationMock: the ForEachChunk attribute must be a function receiving a slice, but got: %T", parser.ForEachChunk)
+	}
+
+	chunkType := fn.Type().In(0)
+	v := reflect.ValueOf(records)
+	if records == nil {
+		v = reflect.MakeSlice(chunkType, 0, 0)
+	}
+	if v.Type() != chunkType {
+		return fmt.Errorf("ksql.ExpectationMock: can't pass the records of type %T to ForEachChunk(%s)", records, chunkType)
+	}
+
+	chunkSize := parser.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = v.Len()
+	}
+	for start := 0; start < v.Len(); start += chunkSize {
+		end := start + chunkSize
+		if end > v.Len() {
+			end = v.Len()
+		}
+
+		err, _ := fn.Call([]reflect.Value{v.Slice(start, end)})[0].Interface().(error)
+		if err == ErrAbortIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package ksql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vingarcia/ksql"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+var _ ksql.MockT = &testing.T{}
+
+// fakeMockT records the failures reported by the ExpectationMock
+type fakeMockT struct {
+	errs     []string
+	cleanups []func()
+}
+
+func (f *fakeMockT) Helper() {}
+
+func (f *fakeMockT) Errorf(format string, args ...interface{}) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeMockT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeMockT) finish() {
+	for _, fn := range f.cleanups {
+		fn()
+	}
+}
+
+func TestExpectationMock(t *testing.T) {
+	ctx := context.Background()
+	usersTable := ksql.NewTable("users", "id")
+	type User struct {
+		ID   int    `ksql:"id"`
+		Name string `ksql:"name"`
+		Age  int    `ksql:"age"`
+	}
+
+	t.Run("should return the values of the expectations in order", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT)
+		mock.ExpectQuery("FROM users WHERE age > $1").WithArgs(18).Return([]User{
+			{ID: 1, Name: "Ana", Age: 20},
+		})
+		mock.ExpectQueryOne("FROM users WHERE id = $1").WithArgs(1).Return(&User{ID: 1, Name: "Ana"})
+		mock.ExpectInsert(usersTable).WithArgs(&User{Name: "Bia"}).Return(User{ID: 2, Name: "Bia"})
+		mock.ExpectExec("DELETE FROM users").Return(ksql.NewMockResult(0, 2))
+
+		var db ksql.Provider = mock
+		var users []User
+		err := db.Query(ctx, &users, "FROM users\n\tWHERE age > $1", 18)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, users, []User{{ID: 1, Name: "Ana", Age: 20}})
+
+		var user User
+		err = db.QueryOne(ctx, &user, "FROM users WHERE id = $1", 1)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, user, User{ID: 1, Name: "Ana"})
+
+		newUser := User{Name: "Bia"}
+		err = db.Insert(ctx, usersTable, &newUser)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, newUser.ID, 2)
+
+		result, err := db.Exec(ctx, "DELETE FROM users")
+		tt.AssertNoErr(t, err)
+		rowsAffected, err := result.RowsAffected()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, rowsAffected, int64(2))
+
+		tt.AssertNoErr(t, mock.ExpectationsWereMet())
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+
+	t.Run("should fail on calls out of order", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT)
+		mock.ExpectDelete(usersTable).WithArgs(1)
+		mock.ExpectDelete(usersTable).WithArgs(2)
+
+		err := mock.Delete(ctx, usersTable, 2)
+		tt.AssertErrContains(t, err, "received Delete(table \"users\") with args [2]", "next expectation is Delete(table \"users\") with args [1]")
+		tt.AssertEqual(t, len(fakeT.errs), 1)
+	})
+
+	t.Run("should accept calls in any order if requested", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT).InAnyOrder()
+		mock.ExpectDelete(usersTable).WithArgs(1)
+		mock.ExpectDelete(usersTable).WithArgs(ksql.MockAnyArg)
+
+		tt.AssertNoErr(t, mock.Delete(ctx, usersTable, 2))
+		tt.AssertNoErr(t, mock.Delete(ctx, usersTable, 1))
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+
+	t.Run("should fail on unexpected calls and unmet expectations", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT).InAnyOrder()
+		mock.ExpectPatch(usersTable).WithArgs(&User{ID: 1, Name: "Ana"})
+
+		err := mock.Patch(ctx, usersTable, &User{ID: 1, Name: "Bia"})
+		tt.AssertErrContains(t, err, "unexpected call Patch(table \"users\") with args [{ID:1 Name:Bia Age:0}]")
+
+		_, err = mock.Exec(ctx, "DELETE FROM users")
+		tt.AssertErrContains(t, err, "unexpected call Exec(\"DELETE FROM users\")")
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 3)
+		tt.AssertErrContains(t, errors.New(fakeT.errs[2]), "1 unmet expectations", "Patch(table \"users\") with args [{ID:1 Name:Ana Age:0}]")
+	})
+
+	t.Run("should return the errors of the expectations", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT)
+		mock.ExpectQueryOne("FROM users WHERE id = $1").ReturnError(ksql.ErrRecordNotFound)
+
+		var user User
+		err := mock.QueryOne(ctx, &user, "FROM users WHERE id = $1", 42)
+		tt.AssertEqual(t, err, ksql.ErrRecordNotFound)
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+
+	t.Run("should check the calls made inside transactions", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT)
+		mock.ExpectTransaction().ReturnError(errors.New("fake commit error"))
+		mock.ExpectUpdate(usersTable).WithArgs(&User{ID: 1, Name: "Ana"})
+
+		err := mock.Transaction(ctx, func(db ksql.Provider) error {
+			return db.Update(ctx, usersTable, &User{ID: 1, Name: "Ana"})
+		})
+		tt.AssertErrContains(t, err, "fake commit error")
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+
+	t.Run("should split the records of QueryChunks in chunks", func(t *testing.T) {
+		fakeT := &fakeMockT{}
+		mock := ksql.NewExpectationMock(fakeT)
+		mock.ExpectQueryChunks("FROM users").Return([]User{{ID: 1}, {ID: 2}, {ID: 3}})
+
+		var chunks [][]User
+		err := mock.QueryChunks(ctx, ksql.ChunkParser{
+			Query:     "FROM users",
+			ChunkSize: 2,
+			ForEachChunk: func(users []User) error {
+				chunks = append(chunks, users)
+				return nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, chunks, [][]User{{{ID: 1}, {ID: 2}}, {{ID: 3}}})
+
+		fakeT.finish()
+		tt.AssertEqual(t, len(fakeT.errs), 0)
+	})
+}
@@ -0,0 +1,97 @@
+package ksql
+
+import (
+	"context"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+// AttrModifier describes a custom modifier that can be used
+// on the `ksql` tags, see the ksqlmodifiers package for details.
+type AttrModifier = ksqlmodifiers.AttrModifier
+
+func init() {
+	structs.RegisterAttrModifier("json", jsonModifier)
+	structs.RegisterAttrModifier("jsonNullable", jsonNullableModifier)
+}
+
+// RegisterModifier makes a custom modifier available to be used
+// on the `ksql` tags after the column name, e.g.:
+//
+//	ksql.RegisterModifier("csv", ksql.AttrModifier{
+//		Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+//			// decode dbValue into attrPtr
+//		},
+//		Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+//			// encode inputValue
+//		},
+//	})
+//
+//	type Post struct {
+//		ID   int      `ksql:"id"`
+//		Tags []string `ksql:"tags,csv"`
+//	}
+//
+// It should be called before the modifier is used for the first time,
+// usually on an `init()` function, and it panics if the name is already
+// in use, including the names of the modifiers built into KSQL.
+func RegisterModifier(name string, modifier AttrModifier) {
+	structs.RegisterAttrModifier(name, modifier)
+}
+
+// modifierScanner adapts the Scan function of
+// a modifier to the sql.Scanner interface.
+type modifierScanner struct {
+	ctx     context.Context
+	opInfo  ksqlmodifiers.OpInfo
+	attrPtr interface{}
+	scan    ksqlmodifiers.AttrScanner
+}
+
+func (m modifierScanner) Scan(dbValue interface{}) error {
+	return m.scan(m.ctx, m.opInfo, m.attrPtr, dbValue)
+}
+
+// getScanValue returns the value that should be passed to rows.Scan()
+// for reading the attribute, applying its modifier if there is one.
+func getScanValue(ctx context.Context, dialect Dialect, fieldInfo *structs.FieldInfo, attrPtr interface{}) interface{} {
+	if fieldInfo.Modifier == nil || fieldInfo.Modifier.Scan == nil {
+		return attrPtr
+	}
+
+	return modifierScanner{
+		ctx: ctx,
+		opInfo: ksqlmodifiers.OpInfo{
+			DriverName: dialect.DriverName(),
+			Method:     "Query",
+		},
+		attrPtr: attrPtr,
+		scan:    fieldInfo.Modifier.Scan,
+	}
+}
+
+// applyValueModifier returns the value that should be sent
+// to the database for the attribute, applying its
+// modifier if there is one.
+func applyValueModifier(
+	ctx context.Context,
+	dialect Dialect,
+	method string,
+	fieldInfo *structs.FieldInfo,
+	value interface{},
+) (interface{}, error) {
+	if fieldInfo.Modifier == nil || fieldInfo.Modifier.Value == nil {
+		return normalizeTimeValue(ctx, value), nil
+	}
+
+	// Expressions are written on the query as they are:
+	if _, isExpr := value.(Expr); isExpr {
+		return value, nil
+	}
+
+	return fieldInfo.Modifier.Value(ctx, ksqlmodifiers.OpInfo{
+		DriverName: dialect.DriverName(),
+		Method:     method,
+	}, value)
+}
@@ -0,0 +1,114 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+func TestRegisterModifier(t *testing.T) {
+	t.Run("should make the modifier available on the ksql tags", func(t *testing.T) {
+		RegisterModifier("fakeModifierForTests", AttrModifier{
+			SkipOnUpdate: true,
+			Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+				return inputValue, nil
+			},
+		})
+
+		info, err := structs.GetTagInfo(reflect.TypeOf(struct {
+			Name string `ksql:"name,fakeModifierForTests"`
+		}{}))
+		tt.AssertNoErr(t, err)
+
+		field := info.ByName("name")
+		tt.AssertNotEqual(t, field.Modifier, nil)
+		tt.AssertEqual(t, field.Modifier.Value != nil, true)
+		tt.AssertEqual(t, field.SkipOnUpdate, true)
+		tt.AssertEqual(t, field.SkipOnInsert, false)
+	})
+
+	t.Run("should panic for names already in use", func(t *testing.T) {
+		for _, name := range []string{"json", "softDelete", "timeNowUTC", ""} {
+			panicPayload := tt.PanicHandler(func() {
+				RegisterModifier(name, AttrModifier{})
+			})
+
+			err, ok := panicPayload.(error)
+			tt.AssertEqual(t, ok, true)
+			tt.AssertErrContains(t, err, "ksql", "cannot register modifier", name)
+		}
+	})
+
+	t.Run("should report error for unknown modifiers", func(t *testing.T) {
+		_, err := structs.GetTagInfo(reflect.TypeOf(struct {
+			Name string `ksql:"name,notRegisteredModifier"`
+		}{}))
+		tt.AssertErrContains(t, err, "name", "unknown modifier", "notRegisteredModifier")
+	})
+
+	t.Run("should report error for attributes with more than one registered modifier", func(t *testing.T) {
+		_, err := structs.GetTagInfo(reflect.TypeOf(struct {
+			Name string `ksql:"name,json,fakeModifierForTests"`
+		}{}))
+		tt.AssertErrContains(t, err, "name", "more than one", "modifier")
+	})
+}
+
+func TestReadOnlyAttributes(t *testing.T) {
+	ctx := context.Background()
+
+	type generatedUser struct {
+		ID     int    `ksql:"id"`
+		Name   string `ksql:"name"`
+		Search string `ksql:"search,readOnly"`
+	}
+
+	t.Run("should not insert read only attributes", func(t *testing.T) {
+		dryRun, err := NewDryRun("mysql", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Insert(ctx, NewTable("users"), &generatedUser{Name: "Jane", Search: "jane"})
+		tt.AssertNoErr(t, err)
+
+		_, err = dryRun.BulkInsert(ctx, NewTable("users"), []generatedUser{{Name: "Jane", Search: "jane"}})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{
+			{
+				Query:  "INSERT INTO `users` (`name`) VALUES (?)",
+				Params: []interface{}{"Jane"},
+			},
+			{
+				Query:  "INSERT INTO `users` (`name`) VALUES (?)",
+				Params: []interface{}{"Jane"},
+			},
+		})
+	})
+
+	t.Run("should not update read only attributes", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Patch(ctx, NewTable("users"), generatedUser{ID: 1, Name: "Jane", Search: "jane"})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{{
+			Query:  `UPDATE "users" SET "name" = $1 WHERE "id" = $2`,
+			Params: []interface{}{"Jane", 1},
+		}})
+	})
+
+	t.Run("should not allow registering modifiers named readOnly", func(t *testing.T) {
+		panicPayload := tt.PanicHandler(func() {
+			RegisterModifier("readOnly", AttrModifier{})
+		})
+
+		err, ok := panicPayload.(error)
+		tt.AssertEqual(t, ok, true)
+		tt.AssertErrContains(t, err, "readOnly", "reserved")
+	})
+}
@@ -0,0 +1,36 @@
+package ksql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase converts the name of a struct attribute to snake case,
+// keeping the acronyms together, e.g. `UserID` is converted to
+// `user_id` and `HTTPStatus` to `http_status`.
+//
+// It is the default `ksql.Config.NamingStrategy`.
+func SnakeCase(fieldName string) string {
+	runes := []rune(fieldName)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// LowerCase converts the name of a struct attribute to lower
+// case, e.g. `UserID` is converted to `userid`, it can be used
+// as the `ksql.Config.NamingStrategy` for case-insensitive columns.
+func LowerCase(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
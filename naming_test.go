@@ -0,0 +1,100 @@
+package ksql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{input: "Name", expectedOutput: "name"},
+		{input: "CreatedAt", expectedOutput: "created_at"},
+		{input: "UserID", expectedOutput: "user_id"},
+		{input: "HTTPStatus", expectedOutput: "http_status"},
+		{input: "Address2", expectedOutput: "address2"},
+		{input: "Line2Text", expectedOutput: "line2_text"},
+		{input: "ID", expectedOutput: "id"},
+		{input: "already_snake", expectedOutput: "already_snake"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tt.AssertEqual(t, SnakeCase(test.input), test.expectedOutput)
+		})
+	}
+}
+
+func TestMapUntaggedFields(t *testing.T) {
+	type untaggedUser struct {
+		ID        uint
+		FullName  string
+		Nickname  string `ksql:"nick"`
+		Ignored   string `ksql:"-"`
+		UpdatedAt string
+	}
+
+	ctx := context.Background()
+
+	t.Run("should map the untagged attributes with snake case by default", func(t *testing.T) {
+		var query string
+		var params []interface{}
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, q string, p ...interface{}) (Result, error) {
+				query, params = q, p
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3", Config{
+			MapUntaggedFields: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		err = db.Patch(ctx, NewTable("users"), &untaggedUser{
+			ID:        42,
+			FullName:  "Jane Doe",
+			Nickname:  "jane",
+			Ignored:   "ignored",
+			UpdatedAt: "today",
+		})
+		tt.AssertNoErr(t, err)
+
+		for _, substr := range []string{"UPDATE `users` SET", "`full_name` = ?", "`nick` = ?", "`updated_at` = ?", "WHERE `id` = ?"} {
+			tt.AssertEqual(t, strings.Contains(query, substr), true, query)
+		}
+		tt.AssertEqual(t, strings.Contains(strings.ToLower(query), "ignored"), false, query)
+		tt.AssertEqual(t, len(params), 4)
+	})
+
+	t.Run("should use the configured naming strategy", func(t *testing.T) {
+		var query string
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, q string, p ...interface{}) (Result, error) {
+				query = q
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3", Config{
+			MapUntaggedFields: true,
+			NamingStrategy:    LowerCase,
+		})
+		tt.AssertNoErr(t, err)
+
+		err = db.Patch(ctx, NewTable("users"), &untaggedUser{ID: 42, FullName: "Jane Doe"})
+		tt.AssertNoErr(t, err)
+
+		for _, substr := range []string{"`fullname` = ?", "WHERE `id` = ?"} {
+			tt.AssertEqual(t, strings.Contains(query, substr), true, query)
+		}
+	})
+
+	t.Run("should not map the untagged attributes when disabled", func(t *testing.T) {
+		db, err := NewWithConfig(mockDBAdapter{}, "sqlite3", Config{})
+		tt.AssertNoErr(t, err)
+
+		err = db.Patch(ctx, NewTable("users"), &untaggedUser{ID: 42, Nickname: "jane"})
+		tt.AssertErrContains(t, err, "id")
+	})
+}
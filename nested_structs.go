@@ -0,0 +1,97 @@
+package ksql
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// nestedTable describes one of the structs tagged with `tablename`
+// that are nested inside the struct used for scanning a JOIN.
+type nestedTable struct {
+	// alias is the name of this table on the query, for tables
+	// nested in more than one level it is built by joining the
+	// `tablename` of each level with their separators, e.g.:
+	// `author_publisher`
+	alias string
+
+	// path is the sequence of indexes for reaching the
+	// struct using `reflect.Value.FieldByIndex()`
+	path []int
+
+	info structs.StructInfo
+
+	// isPtr is true for the tables declared as pointers to structs,
+	// which are set to nil when all of their columns are NULL,
+	// e.g. when a LEFT JOIN finds no rows for them.
+	isPtr      bool
+	structType reflect.Type
+}
+
+// getNestedTables returns all the tables of a nested struct in the
+// same order their columns are selected by ksql, the tables declared
+// inside other tables are listed right after their parents.
+func getNestedTables(structType reflect.Type, info structs.StructInfo) ([]nestedTable, error) {
+	return appendNestedTables(nil, structType, info, nil, "", "")
+}
+
+func appendNestedTables(
+	tables []nestedTable,
+	structType reflect.Type,
+	info structs.StructInfo,
+	parentPath []int,
+	parentAlias string,
+	separator string,
+) ([]nestedTable, error) {
+	for _, table := range info.Tables() {
+		alias := table.Name
+		if parentAlias != "" {
+			alias = parentAlias + separator + table.Name
+		}
+
+		tableType := structType.Field(table.Index).Type
+		isPtr := tableType.Kind() == reflect.Ptr
+		if isPtr {
+			tableType = tableType.Elem()
+		}
+		if tableType.Kind() != reflect.Struct {
+			return nil, fmt.Errorf(
+				"expected nested struct with `tablename:\"%s\"` to be a kind of Struct or a pointer to Struct, but got %v",
+				table.Name, structType.Field(table.Index).Type,
+			)
+		}
+
+		tableInfo, err := info.Naming.GetTagInfo(tableType)
+		if err != nil {
+			return nil, err
+		}
+
+		path := append(append([]int{}, parentPath...), table.Index)
+
+		if isPtr && tableInfo.IsNestedStruct {
+			return nil, fmt.Errorf(
+				"the nested struct with `tablename:\"%s\"` is only used for grouping other tables, so it can't be a pointer: %v",
+				table.Name, structType.Field(table.Index).Type,
+			)
+		}
+
+		// Structs with no `ksql` tags are only used for grouping other tables:
+		if !tableInfo.IsNestedStruct {
+			tables = append(tables, nestedTable{
+				alias:      alias,
+				path:       path,
+				info:       tableInfo,
+				isPtr:      isPtr,
+				structType: tableType,
+			})
+		}
+
+		tables, err = appendNestedTables(tables, tableType, tableInfo, path, alias, table.Separator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tables, nil
+}
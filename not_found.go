@@ -0,0 +1,105 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// NotFoundError describes a query that returned no results or an
+// operation that changed no rows, it wraps the ErrRecordNotFound error
+// and adds the table or the query that caused it to the message.
+//
+// It is only returned instead of ErrRecordNotFound itself when the
+// context was created with `ksql.WithNotFoundDetails()`, so that
+// comparing the errors with `err == ksql.ErrRecordNotFound` keeps working.
+//
+// The simplest way of checking for both errors is using `ksql.IsNotFound()`.
+type NotFoundError struct {
+	// Table is the name of the table when the error
+	// is returned by one of the functions using a ksql.Table
+	Table string
+
+	// Query is the query that returned no results
+	// when the error is returned by QueryOne
+	Query string
+}
+
+func (e NotFoundError) Error() string {
+	if e.Table != "" {
+		return fmt.Sprintf("%s: on table `%s`", ErrRecordNotFound, e.Table)
+	}
+	return fmt.Sprintf("%s: on query `%s`", ErrRecordNotFound, e.Query)
+}
+
+// Unwrap returns ErrRecordNotFound, so that `errors.Is(err, ksql.ErrRecordNotFound)`
+// and `errors.Is(err, sql.ErrNoRows)` work with this error.
+func (e NotFoundError) Unwrap() error {
+	return ErrRecordNotFound
+}
+
+type notFoundDetailsKey struct{}
+
+// WithNotFoundDetails returns a copy of the input context that makes
+// the KSQL methods return a ksql.NotFoundError, which includes the
+// table or the query on the error message, instead of returning
+// the ErrRecordNotFound error itself, e.g.:
+//
+//	err := db.QueryOne(ksql.WithNotFoundDetails(ctx), &user, "FROM users WHERE id = ?", id)
+//	if ksql.IsNotFound(err) {
+//		// The error message includes the query:
+//		log.Println(err)
+//	}
+func WithNotFoundDetails(ctx context.Context) context.Context {
+	return context.WithValue(ctx, notFoundDetailsKey{}, true)
+}
+
+// notFoundError returns the input error if the details were requested
+// with `ksql.WithNotFoundDetails()` and ErrRecordNotFound otherwise.
+func notFoundError(ctx context.Context, err NotFoundError) error {
+	if withDetails, _ := ctx.Value(notFoundDetailsKey{}).(bool); withDetails {
+		return err
+	}
+	return ErrRecordNotFound
+}
+
+// IsNotFound checks if the error was caused by a
+// query returning no results or by an operation that
+// changed no rows, e.g. deleting a record that doesn't exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrRecordNotFound)
+}
+
+// QueryOneOrZero works like QueryOne, but instead of returning
+// an error when the query returns no results it returns found
+// as false, leaving the input record unchanged, e.g.:
+//
+//	var user User
+//	found, err := ksql.QueryOneOrZero(ctx, db, &user, "FROM users WHERE id = $1", id)
+//	if err != nil {
+//		return err
+//	}
+//	if !found {
+//		// ...
+//	}
+//
+// It is a function instead of a method so that it works
+// with any ksql.Provider, including the ones used on tests.
+func QueryOneOrZero(
+	ctx context.Context,
+	db Provider,
+	record interface{},
+	query string,
+	params ...interface{},
+) (found bool, err error) {
+	err = db.QueryOne(ctx, record, query, params...)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
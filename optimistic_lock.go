@@ -0,0 +1,83 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// checkVersionConflict should be called when an update using the
+// optimistic lock affected no rows, it returns ErrVersionConflict
+// if the record still exists on the database or ErrRecordNotFound
+// if it doesn't.
+func (c DB) checkVersionConflict(ctx context.Context, table Table, idOrRecord interface{}) error {
+	idMap, err := normalizeIDsAsMap(c.naming, table.idColumns, idOrRecord)
+	if err != nil {
+		return err
+	}
+
+	tenantField, err := getTenantField(c.naming, table, idOrRecord)
+	if err != nil {
+		return err
+	}
+
+	table, idMap, err = scopeToTenant(ctx, table, tenantField, idMap)
+	if err != nil {
+		return err
+	}
+
+	whereQuery := []string{}
+	params := []interface{}{}
+	for i, idName := range table.idColumns {
+		whereQuery = append(whereQuery, fmt.Sprintf(
+			"%s = %s", c.dialect.Escape(idName), c.dialect.Placeholder(i),
+		))
+		params = append(params, idMap[idName])
+	}
+
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE %s",
+		c.dialect.Escape(table.name),
+		strings.Join(whereQuery, " AND "),
+	), params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		return notFoundError(ctx, NotFoundError{Table: table.name})
+	}
+
+	return ErrVersionConflict
+}
+
+// incrementVersion increments the attribute with the optimisticLock
+// modifier so that it matches the version saved on the database
+// after a successful update.
+func incrementVersion(structValue reflect.Value, lockField *structs.FieldInfo) {
+	if lockField == nil {
+		return
+	}
+
+	field := structValue.FieldByIndex(lockField.Path)
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(field.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(field.Uint() + 1)
+	}
+}
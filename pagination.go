@@ -0,0 +1,309 @@
+package ksql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// PageRequest describes the page requested to ksql.Paginate.
+//
+// The type is not called Cursor because this name
+// is already used by the ksql.QueryIter function.
+type PageRequest struct {
+	// After is the opaque token returned on the NextCursor attribute of
+	// the previous page, if it is empty the first page is returned.
+	After string
+
+	// Limit is the maximum number of records on the page.
+	Limit int
+
+	// OrderBy lists the columns used for sorting the records, as
+	// written on the ksql tags, optionally followed by ASC or DESC,
+	// e.g. `[]string{"created_at DESC", "id DESC"}`.
+	//
+	// The last column should be unique, e.g. the ID, so that
+	// the records with the same values are not skipped.
+	OrderBy []string
+}
+
+// PageInfo describes the page returned by ksql.Paginate
+type PageInfo struct {
+	// HasNext is true if there are more records after this page.
+	HasNext bool
+
+	// NextCursor is the token that should be sent on the After
+	// attribute of the PageRequest for loading the next page,
+	// it is empty if HasNext is false.
+	NextCursor string
+}
+
+// Paginate loads a page of records using keyset pagination, i.e. instead
+// of using OFFSET, which gets slower as the offset grows, it filters the
+// records that come after the last record of the previous page, e.g.:
+//
+//	var users []User
+//	page, err := ksql.Paginate(ctx, db, &users, "FROM users WHERE age > ?", ksql.PageRequest{
+//		After:   token,
+//		Limit:   50,
+//		OrderBy: []string{"created_at DESC", "id DESC"},
+//	}, 18)
+//
+// The input query is used as a subquery, so it should not contain
+// ORDER BY or LIMIT clauses, and the SELECT part of the query
+// can be omitted just like on the Query method.
+//
+// The values of the OrderBy columns are saved on the cursor tokens,
+// so these columns should not be NULL and their types should be
+// serializable as JSON, e.g. numbers, strings and time.Time.
+//
+// The db argument must be a ksql.DB or a Provider that
+// returns its dialect on a `Dialect() ksql.Dialect` method.
+func Paginate(
+	ctx context.Context,
+	db Provider,
+	records interface{},
+	query string,
+	req PageRequest,
+	params ...interface{},
+) (PageInfo, error) {
+	dialectProvider, ok := db.(interface{ Dialect() Dialect })
+	if !ok {
+		return PageInfo{}, fmt.Errorf("ksql: Paginate requires a Provider with a `Dialect() ksql.Dialect` method, but got: %T", db)
+	}
+	dialect := dialectProvider.Dialect()
+
+	if req.Limit <= 0 {
+		return PageInfo{}, fmt.Errorf("ksql: the page limit must be greater than 0, but got: %d", req.Limit)
+	}
+	if len(req.OrderBy) == 0 {
+		return PageInfo{}, fmt.Errorf("ksql: Paginate requires at least one OrderBy column")
+	}
+
+	slicePtr := reflect.ValueOf(records)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.Elem().Kind() != reflect.Slice {
+		return PageInfo{}, fmt.Errorf("ksql: expected to receive a pointer to slice of structs, but got: %T", records)
+	}
+
+	structType, isSliceOfPtrs, err := structs.DecodeAsSliceOfStructs(slicePtr.Elem().Type())
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	// The ksql.DB might map the untagged attributes:
+	var naming *structs.Naming
+	if ksqlDB, ok := db.(DB); ok {
+		naming = ksqlDB.naming
+	}
+
+	info, err := naming.GetTagInfo(structType)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	if info.IsNestedStruct {
+		return PageInfo{}, fmt.Errorf("ksql: Paginate doesn't support nested structs")
+	}
+
+	orderColumns, err := parseOrderColumns(req.OrderBy, info)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	if strings.ToUpper(getFirstToken(query)) == "FROM" {
+		selectPrefix, err := buildSelectQuery(dialect, structType, info, selectQueryCache[dialect.DriverName()])
+		if err != nil {
+			return PageInfo{}, err
+		}
+		query = selectPrefix + query
+	}
+
+	var orderBy []string
+	for _, col := range orderColumns {
+		direction := ""
+		if col.desc {
+			direction = " DESC"
+		}
+		orderBy = append(orderBy, dialect.Escape(col.name)+direction)
+	}
+
+	clauses := Clauses{}.OrderBy(strings.Join(orderBy, ", ")).Limit(req.Limit + 1)
+	if req.After != "" {
+		values, err := decodePageCursor(req.After, orderColumns, structType, info)
+		if err != nil {
+			return PageInfo{}, err
+		}
+
+		cond, condParams := buildKeysetCondition(dialect, orderColumns, values)
+		clauses = clauses.And(cond, condParams...)
+	}
+
+	// The placeholders of the clauses must start
+	// after the ones of the input query:
+	clausesQuery, clausesParams, err := clauses.buildWithOffset(dialect, len(params))
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	// The Query method reuses the elements of slices of structs, so we
+	// truncate it for not leaving records of a previous page on it:
+	slicePtr.Elem().Set(slicePtr.Elem().Slice(0, 0))
+
+	query = "SELECT * FROM (" + query + ") ksql_page " + clausesQuery
+	err = db.Query(ctx, records, query, append(append([]interface{}{}, params...), clausesParams...)...)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	slice := slicePtr.Elem()
+	if slice.Len() <= req.Limit {
+		return PageInfo{}, nil
+	}
+
+	slice = slice.Slice(0, req.Limit)
+	slicePtr.Elem().Set(slice)
+
+	last := slice.Index(req.Limit - 1)
+	if isSliceOfPtrs {
+		last = last.Elem()
+	}
+
+	cursor, err := encodePageCursor(last, orderColumns, info)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	return PageInfo{
+		HasNext:    true,
+		NextCursor: cursor,
+	}, nil
+}
+
+type orderColumn struct {
+	name string
+	desc bool
+}
+
+func parseOrderColumns(orderBy []string, info structs.StructInfo) ([]orderColumn, error) {
+	var columns []orderColumn
+	for _, entry := range orderBy {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("ksql: invalid OrderBy entry: '%s'", entry)
+		}
+
+		col := orderColumn{name: fields[0]}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				col.desc = true
+			default:
+				return nil, fmt.Errorf("ksql: invalid OrderBy direction on entry: '%s'", entry)
+			}
+		}
+
+		if !info.ByName(col.name).Valid {
+			return nil, fmt.Errorf("ksql: the OrderBy column `%s` has no matching attribute on the struct", col.name)
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, nil
+}
+
+// buildKeysetCondition builds the condition that selects the records
+// after the input values, e.g. for `ORDER BY a, b DESC` it renders:
+//
+//	a > ? OR (a = ? AND b < ?)
+func buildKeysetCondition(dialect Dialect, columns []orderColumn, values []interface{}) (string, []interface{}) {
+	var alternatives []string
+	var params []interface{}
+	for i, col := range columns {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, dialect.Escape(columns[j].name)+" = ?")
+			params = append(params, values[j])
+		}
+
+		operator := " > ?"
+		if col.desc {
+			operator = " < ?"
+		}
+		conds = append(conds, dialect.Escape(col.name)+operator)
+		params = append(params, values[i])
+
+		alternative := strings.Join(conds, " AND ")
+		if len(conds) > 1 {
+			alternative = "(" + alternative + ")"
+		}
+		alternatives = append(alternatives, alternative)
+	}
+
+	return strings.Join(alternatives, " OR "), params
+}
+
+type pageCursor struct {
+	Columns []string          `json:"c"`
+	Values  []json.RawMessage `json:"v"`
+}
+
+func encodePageCursor(record reflect.Value, columns []orderColumn, info structs.StructInfo) (string, error) {
+	var cursor pageCursor
+	for _, col := range columns {
+		value, err := json.Marshal(record.FieldByIndex(info.ByName(col.name).Path).Interface())
+		if err != nil {
+			return "", fmt.Errorf("ksql: error encoding the value of column `%s` on the cursor: %s", col.name, err)
+		}
+
+		cursor.Columns = append(cursor.Columns, col.name)
+		cursor.Values = append(cursor.Values, value)
+	}
+
+	rawJSON, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("ksql: error encoding cursor: %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(rawJSON), nil
+}
+
+func decodePageCursor(token string, columns []orderColumn, structType reflect.Type, info structs.StructInfo) ([]interface{}, error) {
+	rawJSON, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: invalid cursor token: %s", err)
+	}
+
+	var cursor pageCursor
+	err = json.Unmarshal(rawJSON, &cursor)
+	if err != nil {
+		return nil, fmt.Errorf("ksql: invalid cursor token: %s", err)
+	}
+
+	if len(cursor.Columns) != len(columns) || len(cursor.Values) != len(columns) {
+		return nil, fmt.Errorf("ksql: the cursor token doesn't match the OrderBy columns of the request")
+	}
+
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		if cursor.Columns[i] != col.name {
+			return nil, fmt.Errorf("ksql: the cursor token doesn't match the OrderBy columns of the request")
+		}
+
+		// Decoding into the type of the attribute preserves
+		// types like time.Time that JSON can't represent:
+		valuePtr := reflect.New(structType.FieldByIndex(info.ByName(col.name).Path).Type)
+		err := json.Unmarshal(cursor.Values[i], valuePtr.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("ksql: invalid value for column `%s` on the cursor token: %s", col.name, err)
+		}
+		values[i] = valuePtr.Elem().Interface()
+	}
+
+	return values, nil
+}
@@ -0,0 +1,253 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+type pageUser struct {
+	ID   int    `ksql:"id"`
+	Name string `ksql:"name"`
+}
+
+type fakePageRows struct {
+	Rows
+
+	users []pageUser
+}
+
+func (f *fakePageRows) Next() bool {
+	return len(f.users) > 0
+}
+
+func (f *fakePageRows) Scan(args ...interface{}) error {
+	*(args[0].(*int)) = f.users[0].ID
+	*(args[1].(*string)) = f.users[0].Name
+	f.users = f.users[1:]
+	return nil
+}
+
+func (f *fakePageRows) Columns() ([]string, error) {
+	return []string{"id", "name"}, nil
+}
+
+func (f *fakePageRows) Err() error {
+	return nil
+}
+
+func (f *fakePageRows) Close() error {
+	return nil
+}
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+
+	newDB := func(t *testing.T, driver string, queries *[]string, params *[][]interface{}, users []pageUser) DB {
+		db, err := NewWithAdapter(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				*queries = append(*queries, query)
+				*params = append(*params, args)
+				return &fakePageRows{users: users}, nil
+			},
+		}, driver)
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should load the first page and return the cursor for the next one", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		db := newDB(t, "postgres", &queries, &params, []pageUser{
+			{ID: 3, Name: "Bia"},
+			{ID: 2, Name: "Bia"},
+			{ID: 1, Name: "Ana"},
+		})
+
+		var users []pageUser
+		page, err := Paginate(ctx, db, &users, "FROM users WHERE age > $1", PageRequest{
+			Limit:   2,
+			OrderBy: []string{"name DESC", "id"},
+		}, 18)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, queries, []string{
+			`SELECT * FROM (SELECT "id", "name" FROM users WHERE age > $1) ksql_page ORDER BY "name" DESC, "id" LIMIT 3`,
+		})
+		tt.AssertEqual(t, params, [][]interface{}{{18}})
+		tt.AssertEqual(t, users, []pageUser{{ID: 3, Name: "Bia"}, {ID: 2, Name: "Bia"}})
+		tt.AssertEqual(t, page.HasNext, true)
+
+		info, err := structs.GetTagInfo(reflect.TypeOf(pageUser{}))
+		tt.AssertNoErr(t, err)
+
+		values, err := decodePageCursor(
+			page.NextCursor,
+			[]orderColumn{{name: "name", desc: true}, {name: "id"}},
+			reflect.TypeOf(pageUser{}),
+			info,
+		)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, values, []interface{}{"Bia", 2})
+	})
+
+	t.Run("should filter the records after the cursor", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		db := newDB(t, "postgres", &queries, &params, nil)
+
+		cursor := mustEncodePageCursor(t,
+			pageUser{ID: 2, Name: "Bia"},
+			[]orderColumn{{name: "name", desc: true}, {name: "id"}},
+		)
+
+		var users []pageUser
+		page, err := Paginate(ctx, db, &users, "FROM users WHERE age > $1", PageRequest{
+			After:   cursor,
+			Limit:   2,
+			OrderBy: []string{"name DESC", "id"},
+		}, 18)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, queries[0],
+			`SELECT * FROM (SELECT "id", "name" FROM users WHERE age > $1) ksql_page`+
+				` WHERE ("name" < $2 OR ("name" = $3 AND "id" > $4)) ORDER BY "name" DESC, "id" LIMIT 3`,
+		)
+		tt.AssertEqual(t, params[0], []interface{}{18, "Bia", "Bia", 2})
+		tt.AssertEqual(t, page, PageInfo{})
+	})
+
+	t.Run("should not keep the records of the previous page on the slice", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		db := newDB(t, "sqlite3", &queries, &params, []pageUser{
+			{ID: 3, Name: "Cid"},
+		})
+
+		users := []pageUser{{ID: 1, Name: "Ana"}, {ID: 2, Name: "Bia"}}
+		page, err := Paginate(ctx, db, &users, "FROM users", PageRequest{
+			After:   mustEncodePageCursor(t, pageUser{ID: 2}, []orderColumn{{name: "id"}}),
+			Limit:   2,
+			OrderBy: []string{"id"},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, users, []pageUser{{ID: 3, Name: "Cid"}})
+		tt.AssertEqual(t, page.HasNext, false)
+	})
+
+	t.Run("should use the FETCH syntax for sqlserver", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		db := newDB(t, "sqlserver", &queries, &params, nil)
+
+		var users []*pageUser
+		_, err := Paginate(ctx, db, &users, "SELECT id, name FROM users", PageRequest{
+			Limit:   10,
+			OrderBy: []string{"id"},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, queries, []string{
+			`SELECT * FROM (SELECT id, name FROM users) ksql_page ORDER BY [id] OFFSET 0 ROWS FETCH NEXT 11 ROWS ONLY`,
+		})
+	})
+
+	t.Run("should work with slices of pointers", func(t *testing.T) {
+		var queries []string
+		var params [][]interface{}
+		db := newDB(t, "sqlite3", &queries, &params, []pageUser{
+			{ID: 1, Name: "Ana"},
+			{ID: 2, Name: "Bia"},
+		})
+
+		var users []*pageUser
+		page, err := Paginate(ctx, db, &users, "FROM users", PageRequest{
+			Limit:   1,
+			OrderBy: []string{"id"},
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, users, []*pageUser{{ID: 1, Name: "Ana"}})
+		tt.AssertEqual(t, page.HasNext, true)
+	})
+
+	t.Run("should report errors", func(t *testing.T) {
+		tests := []struct {
+			desc        string
+			records     interface{}
+			req         PageRequest
+			expectedErr []string
+		}{
+			{
+				desc:        "invalid limit",
+				records:     &[]pageUser{},
+				req:         PageRequest{OrderBy: []string{"id"}},
+				expectedErr: []string{"limit", "0"},
+			},
+			{
+				desc:        "missing OrderBy",
+				records:     &[]pageUser{},
+				req:         PageRequest{Limit: 10},
+				expectedErr: []string{"OrderBy"},
+			},
+			{
+				desc:        "unknown OrderBy column",
+				records:     &[]pageUser{},
+				req:         PageRequest{Limit: 10, OrderBy: []string{"age"}},
+				expectedErr: []string{"age", "no matching attribute"},
+			},
+			{
+				desc:        "invalid OrderBy direction",
+				records:     &[]pageUser{},
+				req:         PageRequest{Limit: 10, OrderBy: []string{"id DOWN"}},
+				expectedErr: []string{"invalid OrderBy direction", "id DOWN"},
+			},
+			{
+				desc:        "records is not a pointer to slice",
+				records:     []pageUser{},
+				req:         PageRequest{Limit: 10, OrderBy: []string{"id"}},
+				expectedErr: []string{"pointer to slice"},
+			},
+			{
+				desc:        "invalid cursor",
+				records:     &[]pageUser{},
+				req:         PageRequest{After: "not a cursor", Limit: 10, OrderBy: []string{"id"}},
+				expectedErr: []string{"invalid cursor token"},
+			},
+			{
+				desc:    "cursor with other OrderBy columns",
+				records: &[]pageUser{},
+				req: PageRequest{
+					After:   mustEncodePageCursor(t, pageUser{ID: 1, Name: "Ana"}, []orderColumn{{name: "name"}}),
+					Limit:   10,
+					OrderBy: []string{"id"},
+				},
+				expectedErr: []string{"cursor token doesn't match the OrderBy columns"},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				var queries []string
+				var params [][]interface{}
+				db := newDB(t, "postgres", &queries, &params, nil)
+
+				_, err := Paginate(ctx, db, test.records, "FROM users", test.req)
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				tt.AssertEqual(t, len(queries), 0)
+			})
+		}
+	})
+}
+
+func mustEncodePageCursor(t *testing.T, record pageUser, columns []orderColumn) string {
+	info, err := structs.GetTagInfo(reflect.TypeOf(record))
+	tt.AssertNoErr(t, err)
+
+	cursor, err := encodePageCursor(reflect.ValueOf(record), columns, info)
+	tt.AssertNoErr(t, err)
+	return cursor
+}
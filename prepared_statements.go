@@ -0,0 +1,384 @@
+package ksql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// preparedStatementsCacheSize is the maximum number of statements kept
+// prepared by the cache, it matches the default of pgx's statement cache.
+const preparedStatementsCacheSize = 512
+
+// sqlPreparer is implemented by the adapters based on database/sql,
+// since they embed the *sql.DB instance.
+type sqlPreparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// sqlTxPreparer is implemented by the Tx of the adapters
+// based on database/sql, since they embed the *sql.Tx instance.
+type sqlTxPreparer interface {
+	sqlPreparer
+	StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt
+}
+
+// stmtCache keeps the most recently used prepared statements, evicting
+// the least recently used ones when it is full.
+//
+// The *sql.Stmt takes care of preparing the statement again on each
+// connection of the pool, so the same instance is shared by all of them.
+type stmtCache struct {
+	preparer sqlPreparer
+
+	mu       sync.Mutex
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List
+	isClosed bool
+}
+
+// stmtCacheEntry counts the users of the statement so that
+// an evicted statement is only closed after its last use.
+type stmtCacheEntry struct {
+	key     string
+	stmt    *sql.Stmt
+	users   int
+	evicted bool
+}
+
+func newStmtCache(preparer sqlPreparer, size int) *stmtCache {
+	return &stmtCache{
+		preparer: preparer,
+		size:     size,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// acquire returns the prepared statement for the query, preparing it if
+// necessary, or nil if the query should not be prepared, in which case
+// the caller should send it to the database directly.
+//
+// The entry returned must be released after its use.
+func (s *stmtCache) acquire(ctx context.Context, query string) *stmtCacheEntry {
+	if !isPreparable(query) {
+		return nil
+	}
+
+	if entry := s.lookup(query); entry != nil {
+		return entry
+	}
+
+	// Preparing without holding the lock so other queries are not blocked,
+	// if it fails the query runs directly so the caller gets the real error,
+	// e.g. for the statements not supported by the prepared protocol of MySQL:
+	stmt, err := s.preparer.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isClosed {
+		stmt.Close()
+		return nil
+	}
+
+	if elem, found := s.entries[query]; found {
+		// Another goroutine prepared the same query concurrently:
+		stmt.Close()
+		s.lru.MoveToFront(elem)
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.users++
+		return entry
+	}
+
+	entry := &stmtCacheEntry{key: query, stmt: stmt, users: 1}
+	s.entries[query] = s.lru.PushFront(entry)
+
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+
+		evicted := oldest.Value.(*stmtCacheEntry)
+		delete(s.entries, evicted.key)
+		evicted.evicted = true
+		if evicted.users == 0 {
+			evicted.stmt.Close()
+		}
+	}
+
+	return entry
+}
+
+// lookup works like acquire but only returns the statements already
+// on the cache, returning nil if the query was not prepared yet.
+func (s *stmtCache) lookup(query string) *stmtCacheEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, found := s.entries[query]
+	if !found {
+		return nil
+	}
+
+	s.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtCacheEntry)
+	entry.users++
+	return entry
+}
+
+func (s *stmtCache) release(entry *stmtCacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.users--
+	if entry.evicted && entry.users == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// close closes all the cached statements, the statements
+// still in use are closed after their last use.
+func (s *stmtCache) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.isClosed = true
+	for _, elem := range s.entries {
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.evicted = true
+		if entry.users == 0 {
+			entry.stmt.Close()
+		}
+	}
+	s.entries = map[string]*list.Element{}
+	s.lru.Init()
+}
+
+// isPreparable reports whether the query contains a single statement,
+// since most drivers only prepare the first statement of the query.
+func isPreparable(query string) bool {
+	end := scanTopLevelWords(query, func(string, int) bool { return false })
+	return end == len(query) || strings.TrimSpace(query[end+1:]) == ""
+}
+
+// preparedStatementsAdapter wraps a DBAdapter based on database/sql
+// running all the statements as prepared statements kept on a cache
+type preparedStatementsAdapter struct {
+	DBAdapter
+
+	cache *stmtCache
+}
+
+// newPreparedStatementsAdapter returns the input adapter unchanged if
+// it is not based on database/sql, e.g. kpgx, whose drivers have their
+// own prepared statement caches.
+func newPreparedStatementsAdapter(db DBAdapter) DBAdapter {
+	preparer, ok := db.(sqlPreparer)
+	if !ok {
+		return db
+	}
+
+	return preparedStatementsAdapter{
+		DBAdapter: db,
+		cache:     newStmtCache(preparer, preparedStatementsCacheSize),
+	}
+}
+
+// ExecContext implements the DBAdapter interface
+func (p preparedStatementsAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	entry := p.cache.acquire(ctx, query)
+	if entry == nil {
+		return p.DBAdapter.ExecContext(ctx, query, args...)
+	}
+	defer p.cache.release(entry)
+
+	return entry.stmt.ExecContext(ctx, args...)
+}
+
+// QueryContext implements the DBAdapter interface
+func (p preparedStatementsAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	entry := p.cache.acquire(ctx, query)
+	if entry == nil {
+		return p.DBAdapter.QueryContext(ctx, query, args...)
+	}
+	// The *sql.Rows keep the statement open until they are closed,
+	// so it is safe to release it before reading them:
+	defer p.cache.release(entry)
+
+	return entry.stmt.QueryContext(ctx, args...)
+}
+
+// BeginTx implements the TxBeginner interface
+func (p preparedStatementsAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := p.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedTx(tx, p.cache), nil
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (p preparedStatementsAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := p.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTxWithOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedTx(tx, p.cache), nil
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (p preparedStatementsAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := p.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (p preparedStatementsAdapter) Close() error {
+	p.cache.close()
+
+	closer, ok := p.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func (p preparedStatementsAdapter) unwrapAdapter() DBAdapter {
+	return p.DBAdapter
+}
+
+// preparedTx wraps a Tx running the statements with the prepared
+// statements of the cache bound to the transaction.
+//
+// The statements are only released when the transaction ends, since
+// the statements bound to it are closed by database/sql at that time.
+//
+// The queries missing from the cache are prepared on the transaction
+// instead of on the database, since the database would wait for a free
+// connection, which never happens when the transaction holds the last one.
+type preparedTx struct {
+	Tx
+
+	cache *stmtCache
+
+	mu      *sync.Mutex
+	entries map[string]*stmtCacheEntry
+	stmts   map[string]*sql.Stmt
+}
+
+func newPreparedTx(tx Tx, cache *stmtCache) Tx {
+	if _, ok := tx.(sqlTxPreparer); !ok {
+		return tx
+	}
+
+	return preparedTx{
+		Tx:      tx,
+		cache:   cache,
+		mu:      &sync.Mutex{},
+		entries: map[string]*stmtCacheEntry{},
+		stmts:   map[string]*sql.Stmt{},
+	}
+}
+
+func (p preparedTx) unwrapAdapter() DBAdapter {
+	return p.Tx
+}
+
+// ExecContext implements the Tx interface
+func (p preparedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	stmt := p.getStmt(ctx, query)
+	if stmt == nil {
+		return p.Tx.ExecContext(ctx, query, args...)
+	}
+
+	return stmt.ExecContext(ctx, args...)
+}
+
+// QueryContext implements the Tx interface
+func (p preparedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	stmt := p.getStmt(ctx, query)
+	if stmt == nil {
+		return p.Tx.QueryContext(ctx, query, args...)
+	}
+
+	return stmt.QueryContext(ctx, args...)
+}
+
+// Rollback implements the Tx interface
+func (p preparedTx) Rollback(ctx context.Context) error {
+	defer p.releaseAll()
+	return p.Tx.Rollback(ctx)
+}
+
+// Commit implements the Tx interface
+func (p preparedTx) Commit(ctx context.Context) error {
+	defer p.releaseAll()
+	return p.Tx.Commit(ctx)
+}
+
+// getStmt returns the prepared statement bound to the transaction
+// or nil if the query should not be prepared.
+func (p preparedTx) getStmt(ctx context.Context, query string) *sql.Stmt {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if stmt, found := p.stmts[query]; found {
+		return stmt
+	}
+
+	if !isPreparable(query) {
+		return nil
+	}
+
+	tx := p.Tx.(sqlTxPreparer)
+	if entry := p.cache.lookup(query); entry != nil {
+		stmt := tx.StmtContext(ctx, entry.stmt)
+		p.entries[query] = entry
+		p.stmts[query] = stmt
+		return stmt
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	p.stmts[query] = stmt
+	return stmt
+}
+
+func (p preparedTx) releaseAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for query, entry := range p.entries {
+		p.cache.release(entry)
+		delete(p.entries, query)
+	}
+	for query := range p.stmts {
+		delete(p.stmts, query)
+	}
+}
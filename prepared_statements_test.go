@@ -0,0 +1,315 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestPreparedStatements(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should prepare each query only once", func(t *testing.T) {
+		conn := &fakeSQLConn{}
+		db, err := NewWithConfig(newFakeSQLAdapter(conn), "sqlite3", Config{
+			PreparedStatements: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err = db.Exec(ctx, "UPDATE users SET age = ?", i)
+			tt.AssertNoErr(t, err)
+
+			var count int
+			err = db.QueryOne(ctx, &count, "SELECT count(*) FROM users")
+			tt.AssertNoErr(t, err)
+		}
+
+		tt.AssertEqual(t, conn.calls(), []string{
+			"prepare: UPDATE users SET age = ?",
+			"stmt exec: UPDATE users SET age = ?",
+			"prepare: SELECT count(*) FROM users",
+			"stmt query: SELECT count(*) FROM users",
+			"stmt exec: UPDATE users SET age = ?",
+			"stmt query: SELECT count(*) FROM users",
+			"stmt exec: UPDATE users SET age = ?",
+			"stmt query: SELECT count(*) FROM users",
+		})
+	})
+
+	t.Run("should not prepare queries with multiple statements", func(t *testing.T) {
+		conn := &fakeSQLConn{}
+		db, err := NewWithConfig(newFakeSQLAdapter(conn), "sqlite3", Config{
+			PreparedStatements: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(ctx, "UPDATE users SET name = 'a;b'; DELETE FROM users")
+		tt.AssertNoErr(t, err)
+		_, err = db.Exec(ctx, "DELETE FROM users; ")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, conn.calls(), []string{
+			"exec: UPDATE users SET name = 'a;b'; DELETE FROM users",
+			"prepare: DELETE FROM users; ",
+			"stmt exec: DELETE FROM users; ",
+		})
+	})
+
+	t.Run("should close the statements evicted from the cache", func(t *testing.T) {
+		conn := &fakeSQLConn{}
+		adapter := newFakeSQLAdapter(conn)
+		cache := newStmtCache(adapter, 1)
+
+		first := cache.acquire(ctx, "SELECT 1")
+		cache.release(first)
+
+		second := cache.acquire(ctx, "SELECT 2")
+		tt.AssertEqual(t, conn.calls(), []string{
+			"prepare: SELECT 1",
+			"prepare: SELECT 2",
+			"stmt close: SELECT 1",
+		})
+
+		// The statements in use are only closed after their last use:
+		third := cache.acquire(ctx, "SELECT 3")
+		tt.AssertEqual(t, len(conn.calls()), 4)
+		cache.release(second)
+		tt.AssertEqual(t, conn.calls()[4:], []string{"stmt close: SELECT 2"})
+
+		cache.release(third)
+		cache.close()
+		tt.AssertEqual(t, conn.calls()[5:], []string{"stmt close: SELECT 3"})
+	})
+
+	t.Run("should reuse the statements inside transactions", func(t *testing.T) {
+		conn := &fakeSQLConn{}
+		db, err := NewWithConfig(newFakeSQLAdapter(conn), "sqlite3", Config{
+			PreparedStatements: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(ctx, "DELETE FROM users WHERE id = ?", 1)
+		tt.AssertNoErr(t, err)
+
+		err = db.Transaction(ctx, func(db Provider) error {
+			for i := 2; i < 4; i++ {
+				_, err := db.Exec(ctx, "DELETE FROM users WHERE id = ?", i)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, conn.calls(), []string{
+			"prepare: DELETE FROM users WHERE id = ?",
+			"stmt exec: DELETE FROM users WHERE id = ?",
+			"begin",
+			"stmt exec: DELETE FROM users WHERE id = ?",
+			"stmt exec: DELETE FROM users WHERE id = ?",
+			"commit",
+		})
+	})
+
+	t.Run("should prepare the queries missing from the cache on the transaction", func(t *testing.T) {
+		conn := &fakeSQLConn{}
+		adapter := newFakeSQLAdapter(conn)
+		// Preparing on the database would wait for the connection held by the transaction:
+		adapter.SetMaxOpenConns(1)
+
+		db, err := NewWithConfig(adapter, "sqlite3", Config{
+			PreparedStatements: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		err = db.Transaction(ctx, func(db Provider) error {
+			_, err := db.Exec(ctx, "DELETE FROM users WHERE id = ?", 1)
+			return err
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, conn.calls(), []string{
+			"begin",
+			"prepare: DELETE FROM users WHERE id = ?",
+			"stmt exec: DELETE FROM users WHERE id = ?",
+			"commit",
+			// Closed by database/sql when the transaction ends:
+			"stmt close: DELETE FROM users WHERE id = ?",
+		})
+	})
+
+	t.Run("should be ignored by adapters not based on database/sql", func(t *testing.T) {
+		db, err := NewWithConfig(mockDBAdapter{}, "postgres", Config{
+			PreparedStatements: true,
+		})
+		tt.AssertNoErr(t, err)
+
+		_, isPrepared := db.db.(commentAdapter).DBAdapter.(preparedStatementsAdapter)
+		tt.AssertEqual(t, isPrepared, false)
+	})
+}
+
+// fakeSQLAdapter is a DBAdapter based on database/sql
+// like the ones on the `adapters/` directory
+type fakeSQLAdapter struct {
+	*sql.DB
+}
+
+func newFakeSQLAdapter(conn *fakeSQLConn) fakeSQLAdapter {
+	return fakeSQLAdapter{sql.OpenDB(fakeSQLConnector{conn: conn})}
+}
+
+func (f fakeSQLAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return f.DB.ExecContext(ctx, query, args...)
+}
+
+func (f fakeSQLAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return f.DB.QueryContext(ctx, query, args...)
+}
+
+func (f fakeSQLAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := f.DB.BeginTx(ctx, nil)
+	return fakeSQLTx{tx}, err
+}
+
+type fakeSQLTx struct {
+	*sql.Tx
+}
+
+func (f fakeSQLTx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return f.Tx.ExecContext(ctx, query, args...)
+}
+
+func (f fakeSQLTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return f.Tx.QueryContext(ctx, query, args...)
+}
+
+func (f fakeSQLTx) Rollback(ctx context.Context) error {
+	return f.Tx.Rollback()
+}
+
+func (f fakeSQLTx) Commit(ctx context.Context) error {
+	return f.Tx.Commit()
+}
+
+type fakeSQLConnector struct {
+	conn *fakeSQLConn
+}
+
+func (f fakeSQLConnector) Connect(context.Context) (driver.Conn, error) {
+	return f.conn, nil
+}
+
+func (f fakeSQLConnector) Driver() driver.Driver {
+	return nil
+}
+
+// fakeSQLConn is a driver.Conn that records all the calls
+// it receives and returns a single row with the value 1
+// for all the queries.
+type fakeSQLConn struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (f *fakeSQLConn) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, call)
+}
+
+func (f *fakeSQLConn) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.log...)
+}
+
+func (f *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	f.record("prepare: " + query)
+	return fakeSQLStmt{conn: f, query: query}, nil
+}
+
+func (f *fakeSQLConn) Close() error {
+	return nil
+}
+
+func (f *fakeSQLConn) Begin() (driver.Tx, error) {
+	f.record("begin")
+	return fakeSQLDriverTx{conn: f}, nil
+}
+
+func (f *fakeSQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f.record("exec: " + query)
+	return driver.RowsAffected(1), nil
+}
+
+func (f *fakeSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	f.record("query: " + query)
+	return &fakeSQLRows{}, nil
+}
+
+type fakeSQLDriverTx struct {
+	conn *fakeSQLConn
+}
+
+func (f fakeSQLDriverTx) Commit() error {
+	f.conn.record("commit")
+	return nil
+}
+
+func (f fakeSQLDriverTx) Rollback() error {
+	f.conn.record("rollback")
+	return nil
+}
+
+type fakeSQLStmt struct {
+	conn  *fakeSQLConn
+	query string
+}
+
+func (f fakeSQLStmt) Close() error {
+	f.conn.record("stmt close: " + f.query)
+	return nil
+}
+
+func (f fakeSQLStmt) NumInput() int {
+	return -1
+}
+
+func (f fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	f.conn.record("stmt exec: " + f.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (f fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	f.conn.record("stmt query: " + f.query)
+	return &fakeSQLRows{}, nil
+}
+
+type fakeSQLRows struct {
+	done bool
+}
+
+func (f *fakeSQLRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (f *fakeSQLRows) Close() error {
+	return nil
+}
+
+func (f *fakeSQLRows) Next(dest []driver.Value) error {
+	if f.done {
+		return io.EOF
+	}
+	f.done = true
+	dest[0] = int64(1)
+	return nil
+}
@@ -0,0 +1,195 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type queryCommentsKey struct{}
+
+// WithQueryComments returns a context whose statements are annotated with
+// the input comments following the sqlcommenter format, e.g.:
+//
+//	ctx = ksql.WithQueryComments(ctx, map[string]string{
+//		"route":       "/users/{id}",
+//		"traceparent": traceparent,
+//	})
+//
+// produces statements such as:
+//
+//	SELECT * FROM users WHERE id = $1 /*route='%2Fusers%2F%7Bid%7D',traceparent='...'*/
+//
+// The comments are merged with the ones already present on the context and
+// with the `ksql.Config.QueryComments`, the latest ones taking precedence.
+//
+// The comments are only added to statements that don't contain comments,
+// as recommended by the sqlcommenter specification, and only by the DBs
+// created with `ksql.NewWithConfig()`, which includes all the adapters
+// maintained on this repository.
+func WithQueryComments(ctx context.Context, comments map[string]string) context.Context {
+	merged := map[string]string{}
+	if previous, ok := ctx.Value(queryCommentsKey{}).(map[string]string); ok {
+		for k, v := range previous {
+			merged[k] = v
+		}
+	}
+	for k, v := range comments {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, queryCommentsKey{}, merged)
+}
+
+// commenter appends the sqlcommenter comments to the queries
+type commenter struct {
+	globalComments map[string]string
+}
+
+func (c commenter) comment(ctx context.Context, query string) string {
+	ctxComments, _ := ctx.Value(queryCommentsKey{}).(map[string]string)
+	if len(c.globalComments) == 0 && len(ctxComments) == 0 {
+		return query
+	}
+
+	if strings.Contains(query, "/*") || strings.Contains(query, "--") {
+		return query
+	}
+
+	comments := map[string]string{}
+	for k, v := range c.globalComments {
+		comments[k] = v
+	}
+	for k, v := range ctxComments {
+		comments[k] = v
+	}
+
+	return appendQueryComment(query, comments)
+}
+
+// appendQueryComment serializes the comments as described by
+// the sqlcommenter specification and appends them to the query,
+// before the trailing semicolon if there is one.
+func appendQueryComment(query string, comments map[string]string) string {
+	keys := make([]string, 0, len(comments))
+	for k := range comments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s='%s'", sqlcommenterEscape(k), sqlcommenterEscape(comments[k]))
+	}
+	comment := "/*" + strings.Join(pairs, ",") + "*/"
+
+	query = strings.TrimRightFunc(query, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if strings.HasSuffix(query, ";") {
+		return strings.TrimSuffix(query, ";") + " " + comment + ";"
+	}
+	return query + " " + comment
+}
+
+func sqlcommenterEscape(s string) string {
+	// url.QueryEscape encodes spaces as "+", but the spec uses "%20":
+	s = strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	return strings.ReplaceAll(s, "'", `\'`)
+}
+
+// commentAdapter wraps a DBAdapter appending the
+// sqlcommenter comments to all the statements
+type commentAdapter struct {
+	DBAdapter
+
+	commenter commenter
+}
+
+// ExecContext implements the DBAdapter interface
+func (c commentAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return c.DBAdapter.ExecContext(ctx, c.commenter.comment(ctx, query), args...)
+}
+
+// QueryContext implements the DBAdapter interface
+func (c commentAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return c.DBAdapter.QueryContext(ctx, c.commenter.comment(ctx, query), args...)
+}
+
+// BeginTx implements the TxBeginner interface
+func (c commentAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := c.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return commentTx{Tx: tx, commenter: c.commenter}, nil
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (c commentAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := c.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTxWithOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return commentTx{Tx: tx, commenter: c.commenter}, nil
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (c commentAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := c.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (c commentAdapter) Close() error {
+	closer, ok := c.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func (c commentAdapter) unwrapAdapter() DBAdapter {
+	return c.DBAdapter
+}
+
+// commentTx wraps a Tx appending the sqlcommenter
+// comments to all the statements
+type commentTx struct {
+	Tx
+
+	commenter commenter
+}
+
+func (c commentTx) unwrapAdapter() DBAdapter {
+	return c.Tx
+}
+
+// ExecContext implements the Tx interface
+func (c commentTx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return c.Tx.ExecContext(ctx, c.commenter.comment(ctx, query), args...)
+}
+
+// QueryContext implements the Tx interface
+func (c commentTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return c.Tx.QueryContext(ctx, c.commenter.comment(ctx, query), args...)
+}
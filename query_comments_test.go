@@ -0,0 +1,93 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestQueryComments(t *testing.T) {
+	newDB := func(t *testing.T, receivedQueries *[]string, globalComments map[string]string) DB {
+		db, err := NewWithConfig(mockTxBeginner{
+			DBAdapter: mockDBAdapter{
+				ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+					*receivedQueries = append(*receivedQueries, query)
+					return NewMockResult(0, 1), nil
+				},
+			},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{
+					DBAdapter: mockDBAdapter{
+						ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+							*receivedQueries = append(*receivedQueries, query)
+							return NewMockResult(0, 1), nil
+						},
+					},
+					CommitFn: func(ctx context.Context) error {
+						return nil
+					},
+				}, nil
+			},
+		}, "postgres", Config{
+			QueryComments: globalComments,
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should not change the queries when there are no comments", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries, nil)
+
+		_, err := db.Exec(context.Background(), "DELETE FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"DELETE FROM users"})
+	})
+
+	t.Run("should merge the global comments with the context comments", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries, map[string]string{
+			"application": "fake-app",
+			"route":       "overridden",
+		})
+
+		ctx := WithQueryComments(context.Background(), map[string]string{"route": "/users/{id}"})
+		ctx = WithQueryComments(ctx, map[string]string{"controller": "users"})
+
+		_, err := db.Exec(ctx, "DELETE FROM users;")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{
+			"DELETE FROM users /*application='fake-app',controller='users',route='%2Fusers%2F%7Bid%7D'*/;",
+		})
+	})
+
+	t.Run("should comment the statements inside transactions", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries, nil)
+
+		ctx := WithQueryComments(context.Background(), map[string]string{"action": "delete"})
+		err := db.Transaction(ctx, func(db Provider) error {
+			_, err := db.Exec(ctx, "DELETE FROM users")
+			return err
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"DELETE FROM users /*action='delete'*/"})
+	})
+
+	t.Run("should not change queries that already contain comments", func(t *testing.T) {
+		var queries []string
+		db := newDB(t, &queries, map[string]string{"application": "fake-app"})
+
+		_, err := db.Exec(context.Background(), "DELETE FROM users /* manual */")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"DELETE FROM users /* manual */"})
+	})
+
+	t.Run("should escape the keys and values", func(t *testing.T) {
+		query := appendQueryComment("SELECT 1", map[string]string{
+			"name with space": "it's",
+		})
+		tt.AssertEqual(t, query, `SELECT 1 /*name%20with%20space='it%27s'*/`)
+	})
+}
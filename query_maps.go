@@ -0,0 +1,115 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QueryMaps queries several rows from the database and saves each
+// of them as a map from the column names to the values returned
+// by the driver, which is useful for ad-hoc queries where there
+// is no struct describing the results, e.g.:
+//
+//	var rows []map[string]interface{}
+//	err := db.QueryMaps(ctx, &rows, "SELECT id, name FROM users WHERE age > ?", 18)
+//
+// Since there is no struct for generating the SELECT part of
+// the query, the query must be written in full.
+//
+// The values are normalized so that they are safe to use after the
+// rows are closed, and for MySQL all the []byte values are converted
+// to strings since this driver returns text columns as []byte.
+func (c DB) QueryMaps(
+	ctx context.Context,
+	records *[]map[string]interface{},
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "QueryMaps", Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		return c.queryMaps(ctx, records, op.Query, op.Params...)
+	})
+}
+
+func (c DB) queryMaps(
+	ctx context.Context,
+	records *[]map[string]interface{},
+	query string,
+	params ...interface{},
+) error {
+	c = c.contextTx(ctx)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if records == nil {
+		return fmt.Errorf("ksql: expected to receive a pointer to a slice of maps, but got nil")
+	}
+
+	if strings.ToUpper(getFirstToken(query)) == "FROM" {
+		return fmt.Errorf("ksql: can't generate the SELECT part of the query for QueryMaps, please write the full query")
+	}
+
+	query, params, err := expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("error running query: %s", err)
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		scanArgs := make([]interface{}, len(names))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			return err
+		}
+
+		record := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			record[name] = normalizeMapValue(c.dialect, values[i])
+		}
+		results = append(results, record)
+	}
+
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	*records = results
+	return nil
+}
+
+func normalizeMapValue(dialect Dialect, value interface{}) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	switch dialect.DriverName() {
+	case "mysql", "mariadb":
+		return string(b)
+	}
+
+	// Some drivers reuse the same buffer when scanning
+	// the next rows, so it must be copied:
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,208 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// LoadRelated loads the records of the input table related to a slice of
+// records with a single `WHERE relatedKey IN (...)` query, saving them on
+// the related map keyed by the value of their relatedKey column.
+//
+// The IN list contains the distinct values of the key column of the
+// records, and the kind of the relationship is chosen by the type of the
+// related map, e.g. for belongs-to relationships:
+//
+//	var authorsByID map[int]User
+//	err := ksql.LoadRelated(ctx, db, posts, "author_id", UsersTable, "id", &authorsByID)
+//
+// And for has-many relationships the map values must be slices:
+//
+//	var commentsByPostID map[int][]Comment
+//	err := ksql.LoadRelated(ctx, db, posts, "id", CommentsTable, "post_id", &commentsByPostID)
+//
+// The records argument must be a slice of structs, or pointers to structs,
+// and the nil keys, e.g. of nullable foreign keys, are ignored. The results
+// are added to the map, which is created if nil, and nothing is attached to
+// the records themselves, so they can be matched with the map explicitly.
+//
+// For belongs-to relationships an error is returned if more
+// than one related record is found with the same key.
+func LoadRelated(
+	ctx context.Context,
+	db DB,
+	records interface{},
+	key string,
+	table Table,
+	relatedKey string,
+	related interface{},
+) error {
+	if err := table.validate(); err != nil {
+		return fmt.Errorf("can't load related records from ksql.Table: %s", err)
+	}
+
+	relatedPtr := reflect.ValueOf(related)
+	if relatedPtr.Kind() != reflect.Ptr || relatedPtr.IsNil() || relatedPtr.Elem().Kind() != reflect.Map {
+		return fmt.Errorf("ksql: expected related argument to be a non-nil pointer to a map, but got: %T", related)
+	}
+	relatedMap := relatedPtr.Elem()
+	mapType := relatedMap.Type()
+
+	hasMany := mapType.Elem().Kind() == reflect.Slice
+	recordType := mapType.Elem()
+	if hasMany {
+		recordType = recordType.Elem()
+	}
+	structType := recordType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf(
+			"ksql: expected the values of the related map to be structs, or slices of structs, but got: %v",
+			mapType.Elem(),
+		)
+	}
+
+	relatedInfo, err := db.naming.GetTagInfo(structType)
+	if err != nil {
+		return err
+	}
+	relatedField := relatedInfo.ByName(relatedKey)
+	if !relatedField.Valid {
+		return fmt.Errorf("ksql: the struct %v has no attribute for the column `%s`", structType, relatedKey)
+	}
+
+	ids, err := collectRelatedKeys(db.naming, records, key)
+	if err != nil {
+		return err
+	}
+
+	if relatedMap.IsNil() {
+		relatedMap.Set(reflect.MakeMap(mapType))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(
+		"FROM %s WHERE %s IN (%s)",
+		db.dialect.Escape(table.name),
+		db.dialect.Escape(relatedKey),
+		db.dialect.Placeholder(0),
+	)
+
+	slicePtr := reflect.New(reflect.SliceOf(recordType))
+	err = db.Query(ctx, slicePtr.Interface(), query, ids)
+	if err != nil {
+		return err
+	}
+
+	slice := slicePtr.Elem()
+	for i := 0; i < slice.Len(); i++ {
+		record := slice.Index(i)
+
+		keyValue := reflect.Indirect(record).FieldByIndex(relatedField.Path)
+		if keyValue.Kind() == reflect.Ptr {
+			if keyValue.IsNil() {
+				continue
+			}
+			keyValue = keyValue.Elem()
+		}
+
+		mapKey, err := convertRelatedKey(keyValue, mapType.Key())
+		if err != nil {
+			return err
+		}
+
+		if hasMany {
+			records := relatedMap.MapIndex(mapKey)
+			if !records.IsValid() {
+				records = reflect.Zero(mapType.Elem())
+			}
+			relatedMap.SetMapIndex(mapKey, reflect.Append(records, record))
+			continue
+		}
+
+		if relatedMap.MapIndex(mapKey).IsValid() {
+			return fmt.Errorf(
+				"ksql: LoadRelated found more than one record on `%s` with %s = %v, use a map of slices for has-many relationships",
+				table.name, relatedKey, mapKey,
+			)
+		}
+		relatedMap.SetMapIndex(mapKey, record)
+	}
+
+	return nil
+}
+
+// collectRelatedKeys returns the distinct values of the key column
+// of the input records, ignoring the nil ones
+func collectRelatedKeys(naming *structs.Naming, records interface{}, key string) ([]interface{}, error) {
+	slice := reflect.Indirect(reflect.ValueOf(records))
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ksql: expected records to be a slice of structs, but got: %T", records)
+	}
+
+	structType := slice.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ksql: expected records to be a slice of structs, but got: %T", records)
+	}
+
+	info, err := naming.GetTagInfo(structType)
+	if err != nil {
+		return nil, err
+	}
+	field := info.ByName(key)
+	if !field.Valid {
+		return nil, fmt.Errorf("ksql: the struct %v has no attribute for the column `%s`", structType, key)
+	}
+
+	ids := []interface{}{}
+	seen := map[string]bool{}
+	for i := 0; i < slice.Len(); i++ {
+		record := reflect.Indirect(slice.Index(i))
+		if !record.IsValid() {
+			continue
+		}
+
+		id := record.FieldByIndex(field.Path)
+		if id.Kind() == reflect.Ptr {
+			if id.IsNil() {
+				continue
+			}
+			id = id.Elem()
+		}
+
+		if seen[loaderKey(id.Interface())] {
+			continue
+		}
+		seen[loaderKey(id.Interface())] = true
+		ids = append(ids, id.Interface())
+	}
+
+	return ids, nil
+}
+
+// convertRelatedKey converts the value of the key column of a related
+// record to the key type of the map, allowing different numeric types
+func convertRelatedKey(value reflect.Value, keyType reflect.Type) (reflect.Value, error) {
+	if value.Type().AssignableTo(keyType) {
+		return value, nil
+	}
+
+	if isNumericKind(value.Kind()) && isNumericKind(keyType.Kind()) {
+		return value.Convert(keyType), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf(
+		"ksql: can't use the related key of type %v as a key of a map with keys of type %v",
+		value.Type(), keyType,
+	)
+}
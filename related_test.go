@@ -0,0 +1,79 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestLoadRelated(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewWithAdapter(mockDBAdapter{}, "sqlite3")
+	tt.AssertNoErr(t, err)
+
+	t.Run("should not run any query if there are no keys", func(t *testing.T) {
+		var authorsByID map[uint]user
+		err := LoadRelated(ctx, db, []post{}, "user_id", usersTable, "id", &authorsByID)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, authorsByID, map[uint]user{})
+	})
+
+	t.Run("should report invalid arguments", func(t *testing.T) {
+		tests := []struct {
+			desc               string
+			records            interface{}
+			key                string
+			relatedKey         string
+			related            interface{}
+			expectErrToContain []string
+		}{
+			{
+				desc:               "related is not a pointer",
+				records:            []post{},
+				key:                "user_id",
+				relatedKey:         "id",
+				related:            map[uint]user{},
+				expectErrToContain: []string{"pointer to a map", "map[uint]ksql.user"},
+			},
+			{
+				desc:               "related map has no struct values",
+				records:            []post{},
+				key:                "user_id",
+				relatedKey:         "id",
+				related:            &map[uint]string{},
+				expectErrToContain: []string{"structs", "string"},
+			},
+			{
+				desc:               "records is not a slice",
+				records:            post{},
+				key:                "user_id",
+				relatedKey:         "id",
+				related:            &map[uint]user{},
+				expectErrToContain: []string{"slice of structs", "ksql.post"},
+			},
+			{
+				desc:               "key is not a column of the records",
+				records:            []post{},
+				key:                "author_id",
+				relatedKey:         "id",
+				related:            &map[uint]user{},
+				expectErrToContain: []string{"ksql.post", "author_id"},
+			},
+			{
+				desc:               "related key is not a column of the related struct",
+				records:            []post{},
+				key:                "user_id",
+				relatedKey:         "user_id",
+				related:            &map[uint]user{},
+				expectErrToContain: []string{"ksql.user", "user_id"},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				err := LoadRelated(ctx, db, test.records, test.key, usersTable, test.relatedKey, test.related)
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+			})
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// replicaSet holds the adapters of the read replicas and
+// the counter used for distributing the reads between them.
+type replicaSet struct {
+	adapters []DBAdapter
+	next     uint64
+}
+
+func (r *replicaSet) pick() DBAdapter {
+	idx := atomic.AddUint64(&r.next, 1) - 1
+	return r.adapters[idx%uint64(len(r.adapters))]
+}
+
+// NewWithReplicas returns a copy of the primary DB that sends the
+// read-only operations, i.e. Query, QueryOne, QueryChunks, QueryIter
+// and QueryMaps, to the replicas in a round-robin fashion.
+//
+// All the other operations and everything running inside a
+// transaction are still sent to the primary database, e.g.:
+//
+//	primary, err := kpgx.New(ctx, os.Getenv("PRIMARY_URL"), ksql.Config{})
+//	replica, err := kpgx.New(ctx, os.Getenv("REPLICA_URL"), ksql.Config{})
+//
+//	db, err := ksql.NewWithReplicas(primary, replica)
+//
+// Reads that can't tolerate the replication lag can be sent
+// to the primary by using a context created with `ksql.UsePrimary(ctx)`.
+//
+// Calling Close() on the returned DB also closes the replicas.
+func NewWithReplicas(primary DB, replicas ...DB) (DB, error) {
+	if primary.db == nil {
+		return DB{}, fmt.Errorf("ksql: the primary DB must be a valid ksql.DB instance")
+	}
+
+	var adapters []DBAdapter
+	for i, replica := range replicas {
+		if replica.db == nil {
+			return DB{}, fmt.Errorf("ksql: replica %d must be a valid ksql.DB instance", i)
+		}
+
+		if replica.driver != primary.driver {
+			return DB{}, fmt.Errorf(
+				"ksql: all replicas must use the same dialect as the primary DB, expected `%s` but replica %d uses `%s`",
+				primary.driver, i, replica.driver,
+			)
+		}
+
+		adapters = append(adapters, replica.db)
+	}
+
+	if len(adapters) > 0 {
+		primary.replicas = &replicaSet{
+			adapters: adapters,
+		}
+	}
+
+	return primary, nil
+}
+
+type usePrimaryKey struct{}
+
+// UsePrimary returns a copy of the input context that makes
+// the read-only operations of a DB created with `ksql.NewWithReplicas()`
+// run on the primary database instead of on one of the replicas, e.g.:
+//
+//	err := db.QueryOne(ksql.UsePrimary(ctx), &user, "FROM users WHERE id = ?", id)
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+func isPrimaryForced(ctx context.Context) bool {
+	usePrimary, _ := ctx.Value(usePrimaryKey{}).(bool)
+	return usePrimary
+}
+
+// readDB returns the adapter that should be used for read-only operations.
+func (c DB) readDB(ctx context.Context) DBAdapter {
+	if c.replicas == nil || isPrimaryForced(ctx) {
+		return c.db
+	}
+
+	return c.replicas.pick()
+}
+
+// closeReplicas closes all the replicas that implement the io.Closer interface
+func (c DB) closeReplicas() error {
+	if c.replicas == nil {
+		return nil
+	}
+
+	var firstErr error
+	for _, replica := range c.replicas.adapters {
+		closer, ok := replica.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,250 @@
+package ksql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryPolicy describes how the operations that fail
+// with transient errors, e.g. connection resets, deadlocks
+// and serialization failures, should be retried.
+//
+// The statements other than SELECTs are only retried if
+// RetryWrites is true, since retrying them might not be safe,
+// e.g. an INSERT might have succeeded before the connection
+// was lost, and the statements running inside transactions
+// are never retried, for these see the `ksql.TxRetrier` interface.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation
+	// will run, including the first attempt, values lower
+	// than 2 disable the retries
+	MaxAttempts int
+
+	// InitialBackoff is the maximum duration to wait before the
+	// first retry, it doubles after each attempt and defaults to 10ms
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum duration to wait before
+	// each retry, it defaults to 1s
+	MaxBackoff time.Duration
+
+	// RetryWrites enables the retries for statements other than SELECTs
+	RetryWrites bool
+
+	// IsRetryable optionally replaces the default
+	// classification of which errors are transient
+	IsRetryable func(err error) bool
+}
+
+// SetDefaultValues sets the default values of the RetryPolicy if unset
+func (r *RetryPolicy) SetDefaultValues() {
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = 10 * time.Millisecond
+	}
+
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = time.Second
+	}
+}
+
+// backoff returns the duration to wait after the input attempt
+// using an exponential backoff with full jitter.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	max := r.MaxBackoff
+	if shift := uint(attempt - 1); shift < 32 && r.InitialBackoff<<shift < max {
+		max = r.InitialBackoff << shift
+	}
+
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// retryAdapter wraps a DBAdapter retrying the
+// queries that fail with transient errors.
+type retryAdapter struct {
+	DBAdapter
+
+	driverName string
+	policy     RetryPolicy
+}
+
+func newRetryAdapter(db DBAdapter, driverName string, policy RetryPolicy) retryAdapter {
+	policy.SetDefaultValues()
+	if policy.IsRetryable == nil {
+		policy.IsRetryable = func(err error) bool {
+			return isTransientError(driverName, err)
+		}
+	}
+
+	return retryAdapter{
+		DBAdapter:  db,
+		driverName: driverName,
+		policy:     policy,
+	}
+}
+
+// ExecContext implements the DBAdapter interface
+func (r retryAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (result Result, err error) {
+	err = r.retry(ctx, query, func() error {
+		result, err = r.DBAdapter.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext implements the DBAdapter interface
+func (r retryAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (rows Rows, err error) {
+	err = r.retry(ctx, query, func() error {
+		rows, err = r.DBAdapter.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// BeginTx implements the TxBeginner interface, the returned
+// Tx is not wrapped, so the statements inside it are never retried
+func (r retryAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := r.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	return txBeginner.BeginTx(ctx)
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (r retryAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := r.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	return txBeginner.BeginTxWithOptions(ctx, opts)
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (r retryAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := r.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (r retryAdapter) Close() error {
+	closer, ok := r.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func (r retryAdapter) unwrapAdapter() DBAdapter {
+	return r.DBAdapter
+}
+
+func (r retryAdapter) retry(ctx context.Context, query string, fn func() error) error {
+	if !r.policy.RetryWrites && !strings.EqualFold(getFirstToken(query), "SELECT") {
+		return fn()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.policy.IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(r.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// transientErrors contains the messages of the errors that are worth
+// retrying for each driver, the messages are used instead of the error
+// types since most adapters are on separate modules and some errors
+// are only available formatted as strings.
+var transientErrors = map[string][]string{
+	"postgres": {
+		"(SQLSTATE 40001)", "(SQLSTATE 40P01)",
+		"could not serialize access", "deadlock detected",
+	},
+	"mysql": {
+		"Error 1213", "Error 1205",
+	},
+	"mariadb": {
+		"Error 1213", "Error 1205",
+	},
+	"sqlserver": {
+		"was deadlocked",
+	},
+	"sqlite3": {
+		"database is locked", "database table is locked",
+	},
+	"oracle": {
+		"ORA-00060", "ORA-08177",
+	},
+}
+
+// connectionErrors contains the messages of the errors caused by
+// broken connections or unreachable databases, which are common
+// to all drivers.
+var connectionErrors = []string{
+	"connection reset by peer",
+	"connection refused",
+	"broken pipe",
+	"unexpected EOF",
+	"i/o timeout",
+}
+
+// isTransientError checks if the error is likely to go away
+// if the operation is retried, e.g. deadlocks or connection resets.
+func isTransientError(driverName string, err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+
+	return containsAny(err, transientErrors[driverName])
+}
+
+// isConnectionError checks if the error was caused by
+// a broken connection or by an unreachable database.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	return containsAny(err, connectionErrors)
+}
+
+func containsAny(err error, substrs []string) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+	for _, substr := range substrs {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+
+	return false
+}
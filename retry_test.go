@@ -0,0 +1,201 @@
+package ksql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	newDB := func(t *testing.T, policy RetryPolicy, errs []error, calls *int) DB {
+		next := func() error {
+			*calls++
+			if len(errs) == 0 {
+				return nil
+			}
+			return shiftErrSlice(&errs)
+		}
+
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				return nil, next()
+			},
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return nil, next()
+			},
+		}, "postgres", Config{
+			RetryPolicy: policy,
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	transientErr := fmt.Errorf("ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)")
+
+	t.Run("should retry SELECTs that fail with transient errors", func(t *testing.T) {
+		var calls int
+		db := newDB(t, RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Microsecond,
+		}, []error{transientErr, transientErr}, &calls)
+
+		_, err := db.readDB(context.Background()).QueryContext(context.Background(), "SELECT * FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, 3)
+	})
+
+	t.Run("should stop after the max number of attempts", func(t *testing.T) {
+		var calls int
+		db := newDB(t, RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Microsecond,
+		}, []error{transientErr, transientErr, transientErr}, &calls)
+
+		_, err := db.readDB(context.Background()).QueryContext(context.Background(), "SELECT * FROM users")
+		tt.AssertErrContains(t, err, "SQLSTATE 40001")
+		tt.AssertEqual(t, calls, 2)
+	})
+
+	t.Run("should not retry errors that are not transient", func(t *testing.T) {
+		var calls int
+		db := newDB(t, RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Microsecond,
+		}, []error{fmt.Errorf("fakeErrMsg")}, &calls)
+
+		_, err := db.readDB(context.Background()).QueryContext(context.Background(), "SELECT * FROM users")
+		tt.AssertErrContains(t, err, "fakeErrMsg")
+		tt.AssertEqual(t, calls, 1)
+	})
+
+	t.Run("should not retry writes unless RetryWrites is set", func(t *testing.T) {
+		var calls int
+		db := newDB(t, RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Microsecond,
+		}, []error{transientErr, transientErr}, &calls)
+
+		_, err := db.Exec(context.Background(), "UPDATE users SET age = 42")
+		tt.AssertErrContains(t, err, "SQLSTATE 40001")
+		tt.AssertEqual(t, calls, 1)
+
+		calls = 0
+		db = newDB(t, RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Microsecond,
+			RetryWrites:    true,
+		}, []error{transientErr, transientErr}, &calls)
+
+		_, err = db.Exec(context.Background(), "UPDATE users SET age = 42")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, 3)
+	})
+
+	t.Run("should use the custom IsRetryable function if set", func(t *testing.T) {
+		var calls int
+		db := newDB(t, RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Microsecond,
+			IsRetryable: func(err error) bool {
+				return err.Error() == "fakeRetryableErr"
+			},
+		}, []error{fmt.Errorf("fakeRetryableErr"), transientErr}, &calls)
+
+		_, err := db.readDB(context.Background()).QueryContext(context.Background(), "SELECT * FROM users")
+		tt.AssertErrContains(t, err, "SQLSTATE 40001")
+		tt.AssertEqual(t, calls, 2)
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{}
+	policy.SetDefaultValues()
+
+	for attempt, maxExpected := range map[int]time.Duration{
+		1:  10 * time.Millisecond,
+		2:  20 * time.Millisecond,
+		3:  40 * time.Millisecond,
+		10: time.Second,
+		80: time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			backoff := policy.backoff(attempt)
+			if backoff < 0 || backoff > maxExpected {
+				t.Fatalf("expected backoff for attempt %d to be between 0 and %v, but got %v", attempt, maxExpected, backoff)
+			}
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		desc       string
+		driverName string
+		err        error
+		expected   bool
+	}{
+		{
+			desc:       "should detect postgres deadlocks",
+			driverName: "postgres",
+			err:        fmt.Errorf("ERROR: deadlock detected (SQLSTATE 40P01)"),
+			expected:   true,
+		},
+		{
+			desc:       "should detect mysql deadlocks",
+			driverName: "mysql",
+			err:        fmt.Errorf("Error 1213: Deadlock found when trying to get lock"),
+			expected:   true,
+		},
+		{
+			desc:       "should detect sqlserver deadlocks",
+			driverName: "sqlserver",
+			err:        fmt.Errorf("mssql: Transaction (Process ID 52) was deadlocked on lock resources"),
+			expected:   true,
+		},
+		{
+			desc:       "should detect locked sqlite3 databases",
+			driverName: "sqlite3",
+			err:        fmt.Errorf("database is locked"),
+			expected:   true,
+		},
+		{
+			desc:       "should detect broken connections on any driver",
+			driverName: "sqlite3",
+			err:        fmt.Errorf("error running query: %w", driver.ErrBadConn),
+			expected:   true,
+		},
+		{
+			desc:       "should detect connection resets on any driver",
+			driverName: "mysql",
+			err:        fmt.Errorf("read tcp 127.0.0.1:3306: read: connection reset by peer"),
+			expected:   true,
+		},
+		{
+			desc:       "should not use the messages of other drivers",
+			driverName: "postgres",
+			err:        fmt.Errorf("Error 1213: Deadlock found when trying to get lock"),
+			expected:   false,
+		},
+		{
+			desc:       "should not retry other errors",
+			driverName: "postgres",
+			err:        fmt.Errorf(`ERROR: relation "users" does not exist (SQLSTATE 42P01)`),
+			expected:   false,
+		},
+		{
+			desc:       "should not retry nil errors",
+			driverName: "postgres",
+			err:        nil,
+			expected:   false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tt.AssertEqual(t, isTransientError(test.driverName, test.err), test.expected)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// RunInRollbackTx runs fn inside a transaction that is always rolled back
+// when fn returns, so that integration tests sharing the same database
+// don't see the changes made by each other, e.g.:
+//
+//	func TestUsersRepository(t *testing.T) {
+//		ksql.RunInRollbackTx(t, db, func(db ksql.Provider) {
+//			repo := NewUsersRepository(db)
+//			// ... no need to truncate the tables afterwards ...
+//		})
+//	}
+//
+// The transactions started by the code being tested, with the `Transaction()`
+// or the `Begin()` methods, use savepoints instead of real transactions, so
+// when they fail only their own changes are rolled back as usual, and their
+// OnCommit hooks run when the savepoint is released. On DuckDB, which has no
+// savepoints, starting these transactions returns an error instead.
+//
+// Since the test and the transaction share the same connection,
+// fn should not run queries concurrently on databases that don't
+// support it, e.g. on a single pgx connection.
+//
+// NOTE: This function lives in this package instead of the ksqltest
+// package because the ksql package imports ksqltest and not the other
+// way around.
+func RunInRollbackTx(t MockT, db DB, fn func(db Provider)) {
+	t.Helper()
+	ctx := context.Background()
+
+	txBeginner, ok := db.db.(TxBeginner)
+	if !ok {
+		t.Errorf("ksql.RunInRollbackTx: the DBAdapter doesn't implement the TxBeginner interface")
+		return
+	}
+	if _, ok := db.db.(Tx); ok {
+		t.Errorf("ksql.RunInRollbackTx: the input DB is already bound to a transaction")
+		return
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	if err != nil {
+		t.Errorf("ksql.RunInRollbackTx: error starting transaction: %s", err)
+		return
+	}
+
+	hooks := &txHooks{}
+	defer func() {
+		// The rollback is deferred so it also happens
+		// when the test fails with `t.FailNow()`:
+		if err := hooks.rollback(ctx, tx); err != nil {
+			t.Errorf("ksql.RunInRollbackTx: error rolling back the transaction: %s", err)
+		}
+	}()
+
+	dbCopy := db
+	dbCopy.db = tx
+
+	// All the queries of a transaction must run on the primary:
+	dbCopy.replicas = nil
+	dbCopy.txHooks = hooks
+	dbCopy.savepoints = new(int32)
+
+	fn(dbCopy)
+}
+
+// savepointTx implements the Tx interface on top of a savepoint,
+// so that it can be used for running nested transactions inside
+// the transaction started by `ksql.RunInRollbackTx()`.
+type savepointTx struct {
+	Tx
+
+	name    string
+	dialect Dialect
+}
+
+// beginSavepoint creates a new savepoint on the transaction the DB is bound to
+func (c DB) beginSavepoint(ctx context.Context) (Tx, error) {
+	tx, ok := c.db.(Tx)
+	if !ok {
+		return nil, fmt.Errorf("savepoints can only be created inside a transaction")
+	}
+
+	// DuckDB doesn't support savepoints, so the nested
+	// transactions can't be rolled back independently:
+	if c.dialect.DriverName() == "duckdb" {
+		return nil, fmt.Errorf("ksql: savepoints are not supported for the driver `%s`", c.dialect.DriverName())
+	}
+
+	sp := savepointTx{
+		Tx:      tx,
+		name:    fmt.Sprintf("ksql_savepoint_%d", atomic.AddInt32(c.savepoints, 1)),
+		dialect: c.dialect,
+	}
+
+	query := "SAVEPOINT " + sp.name
+	if c.dialect.DriverName() == "sqlserver" {
+		query = "SAVE TRANSACTION " + sp.name
+	}
+
+	_, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error creating savepoint: %s", err)
+	}
+
+	return sp, nil
+}
+
+// Commit releases the savepoint, keeping its changes on the outer transaction
+func (sp savepointTx) Commit(ctx context.Context) error {
+	switch sp.dialect.DriverName() {
+	case "sqlserver", "oracle":
+		// These databases release the savepoints with the outer transaction
+		return nil
+	}
+
+	_, err := sp.Tx.ExecContext(ctx, "RELEASE SAVEPOINT "+sp.name)
+	return err
+}
+
+// Rollback undoes the changes made after the savepoint was created
+func (sp savepointTx) Rollback(ctx context.Context) error {
+	query := "ROLLBACK TO SAVEPOINT " + sp.name
+	if sp.dialect.DriverName() == "sqlserver" {
+		query = "ROLLBACK TRANSACTION " + sp.name
+	}
+
+	_, err := sp.Tx.ExecContext(ctx, query)
+	return err
+}
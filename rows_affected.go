@@ -0,0 +1,29 @@
+package ksql
+
+import "fmt"
+
+// ExpectRowsAffected returns ErrNoRowsAffected if the input result
+// reports that no rows were affected, so it can wrap the calls to the
+// functions returning a ksql.Result, e.g.:
+//
+//	err := ksql.ExpectRowsAffected(db.Exec(ctx, "UPDATE users SET age = 42 WHERE id = $1", id))
+//	if err == ksql.ErrNoRowsAffected {
+//		// ...
+//	}
+//
+// If the input error is not nil it is returned unchanged.
+func ExpectRowsAffected(result Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ksql: unable to check how many rows were affected: %s", err)
+	}
+	if n == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
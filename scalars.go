@@ -0,0 +1,156 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// isScalarType checks if the input type should be scanned directly
+// from a single column instead of being decoded as a struct with
+// `ksql` tags, e.g. int, string, []byte and their pointers, as well
+// as time.Time and any type implementing the sql.Scanner interface,
+// e.g. sql.NullTime.
+func isScalarType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// queryScalars is the version of the Query method used for slices of
+// scalar values, e.g. []int, which are filled with the only column
+// returned by the query.
+func (c DB) queryScalars(
+	ctx context.Context,
+	slicePtr reflect.Value,
+	query string,
+	params []interface{},
+) error {
+	sliceType := slicePtr.Type().Elem()
+	if strings.ToUpper(getFirstToken(query)) == "FROM" {
+		return fmt.Errorf("ksql: can't generate the SELECT part of the query for scanning into %v, please write the full query", sliceType)
+	}
+
+	query, err := addLockClause(ctx, c.dialect, query)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("error running query: %s", err)
+	}
+	defer rows.Close()
+
+	slice := reflect.MakeSlice(sliceType, 0, 0)
+	for rows.Next() {
+		elemPtr := reflect.New(sliceType.Elem())
+		err = scanScalar(rows, elemPtr.Interface())
+		if err != nil {
+			return err
+		}
+		normalizeTime(elemPtr.Elem(), timeLocationFromContext(ctx))
+		slice = reflect.Append(slice, elemPtr.Elem())
+	}
+
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	slicePtr.Elem().Set(slice)
+	return nil
+}
+
+// queryOneScalar is the version of the QueryOne method used for
+// pointers to scalar values, e.g. *int, which are useful for
+// queries returning a single value, like COUNT(*) queries.
+func (c DB) queryOneScalar(
+	ctx context.Context,
+	record interface{},
+	query string,
+	params []interface{},
+) error {
+	if strings.ToUpper(getFirstToken(query)) == "FROM" {
+		return fmt.Errorf("ksql: can't generate the SELECT part of the query for scanning into %T, please write the full query", record)
+	}
+
+	query, err := addLockClause(ctx, c.dialect, query)
+	if err != nil {
+		return err
+	}
+
+	query, params, err = expandSliceParams(c.dialect, query, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.readDB(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("error running query: %s", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		return notFoundError(ctx, NotFoundError{Query: query})
+	}
+
+	err = scanScalar(rows, record)
+	if err != nil {
+		return err
+	}
+	normalizeTime(reflect.ValueOf(record).Elem(), timeLocationFromContext(ctx))
+
+	return rows.Close()
+}
+
+func scanScalar(rows Rows, valuePtr interface{}) error {
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if len(names) != 1 {
+		return fmt.Errorf(
+			"ksql: expected the query to return a single column for scanning into %T, but it returned %d columns",
+			valuePtr, len(names),
+		)
+	}
+
+	return rows.Scan(valuePtr)
+}
@@ -0,0 +1,362 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// scanPlanCache keeps the scanPlan of each struct type and set of
+// columns, so the mapping from the columns to the struct fields is
+// only built once instead of once for each row.
+var scanPlanCache = &sync.Map{}
+
+type scanPlanKey struct {
+	structType reflect.Type
+
+	// columns is empty for nested structs since
+	// their columns are always on the same order.
+	columns string
+
+	// naming is the Naming used for building the StructInfo
+	naming *structs.Naming
+}
+
+// scanPlan contains the struct field each column of the
+// results should be scanned into, in the order of the columns.
+type scanPlan struct {
+	targets []scanTarget
+
+	// ptrTables are the nested tables declared as pointers,
+	// listed in the same order of the tables, so the tables
+	// containing other tables come before them
+	ptrTables []ptrTable
+}
+
+// ptrTable describes a nested table declared as a pointer, which
+// is allocated before scanning each row and set back to nil if
+// all of its columns are NULL.
+type ptrTable struct {
+	path       []int
+	structType reflect.Type
+
+	// parent is the index of the closest ptrTable
+	// containing this table, or -1 if there is none
+	parent int
+
+	// hasValueTables is true if this table contains tables that are not
+	// pointers, in which case it is never set to nil, since their columns
+	// can't be checked for NULL values
+	hasValueTables bool
+}
+
+// scanTarget describes the field a column is scanned into,
+// the fieldInfo is nil for the columns missing from the struct.
+type scanTarget struct {
+	fieldInfo *structs.FieldInfo
+
+	// path is the index of the field relative to the root struct,
+	// which for nested structs includes the index of the table.
+	path []int
+
+	// ptrTable is the index of the ptrTable of
+	// the field, or -1 if its table is not a pointer
+	ptrTable int
+
+	// concreteType is the type registered with `ksql.RegisterConcreteType()`
+	// for the fields of interface types, or nil for the other fields
+	concreteType reflect.Type
+
+	// isTime is true for the time.Time and *time.Time fields,
+	// which are converted to the `ksql.Config.TimeLocation`
+	isTime bool
+}
+
+func getScanPlan(rows Rows, structType reflect.Type, info structs.StructInfo) (*scanPlan, error) {
+	var names []string
+	key := scanPlanKey{structType: structType, naming: info.Naming}
+	if !info.IsNestedStruct {
+		var err error
+		names, err = rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		key.columns = strings.Join(names, "\x00")
+	}
+
+	if plan, found := scanPlanCache.Load(key); found {
+		return plan.(*scanPlan), nil
+	}
+
+	plan := &scanPlan{}
+	if info.IsNestedStruct {
+		// This version is positional meaning that it expect the arguments
+		// to follow an specific order. It's ok because we don't allow the
+		// user to type the "SELECT" part of the query for nested structs.
+		tables, err := getNestedTables(structType, info)
+		if err != nil {
+			return nil, err
+		}
+
+		// The ptrTable of each table:
+		tablePtrIdx := make([]int, len(tables))
+		for i, table := range tables {
+			parent := closestPtrTable(plan.ptrTables, table.path)
+			if !table.isPtr {
+				tablePtrIdx[i] = -1
+				if parent != -1 {
+					plan.ptrTables[parent].hasValueTables = true
+				}
+				continue
+			}
+
+			tablePtrIdx[i] = len(plan.ptrTables)
+			plan.ptrTables = append(plan.ptrTables, ptrTable{
+				path:       table.path,
+				structType: table.structType,
+				parent:     parent,
+			})
+		}
+
+		for i, table := range tables {
+			for _, fieldInfo := range table.info.Fields() {
+				target := scanTarget{
+					fieldInfo: fieldInfo,
+					path:      append(append([]int{}, table.path...), fieldInfo.Path...),
+					ptrTable:  tablePtrIdx[i],
+				}
+
+				fieldType := structType.FieldByIndex(target.path).Type
+				target.concreteType, err = getConcreteType(fieldInfo.Name, fieldType)
+				if err != nil {
+					return nil, err
+				}
+				target.isTime = isTimeField(fieldInfo, fieldType)
+
+				plan.targets = append(plan.targets, target)
+			}
+		}
+	} else {
+		// Since this version uses the names of the columns it works
+		// with any order of attributes/columns.
+		plan.targets = make([]scanTarget, len(names))
+		for i, name := range names {
+			fieldInfo := info.ByName(name)
+			if fieldInfo.Valid {
+				fieldType := structType.FieldByIndex(fieldInfo.Path).Type
+				concreteType, err := getConcreteType(fieldInfo.Name, fieldType)
+				if err != nil {
+					return nil, err
+				}
+
+				plan.targets[i] = scanTarget{
+					fieldInfo:    fieldInfo,
+					path:         fieldInfo.Path,
+					ptrTable:     -1,
+					concreteType: concreteType,
+					isTime:       isTimeField(fieldInfo, fieldType),
+				}
+			}
+		}
+	}
+
+	scanPlanCache.Store(key, plan)
+	return plan, nil
+}
+
+// closestPtrTable returns the index of the last ptrTable whose
+// path is a prefix of the input path, or -1 if there is none
+func closestPtrTable(ptrTables []ptrTable, path []int) int {
+	for i := len(ptrTables) - 1; i >= 0; i-- {
+		tablePath := ptrTables[i].path
+		if len(tablePath) < len(path) && reflect.DeepEqual(tablePath, path[:len(tablePath)]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rowScanner scans the rows of a single query reusing the same
+// scan plan and the same slice of scan arguments for all the rows.
+type rowScanner struct {
+	ctx      context.Context
+	dialect  Dialect
+	rows     Rows
+	plan     *scanPlan
+	scanArgs []interface{}
+
+	// temps are the pointers used for scanning the fields that can't
+	// be scanned directly, and notNull records which ptrTables of the
+	// plan have columns that are not NULL, both are reset on each row
+	temps   []reflect.Value
+	notNull []bool
+
+	// isStatic is true if the records implement the StaticScanner interface
+	isStatic bool
+
+	// timeLocation is the `ksql.Config.TimeLocation`, or nil if
+	// the times should keep the zone returned by the driver
+	timeLocation *time.Location
+}
+
+func newRowScanner(
+	ctx context.Context,
+	dialect Dialect,
+	rows Rows,
+	structType reflect.Type,
+	info structs.StructInfo,
+) (*rowScanner, error) {
+	plan, err := getScanPlan(rows, structType, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rowScanner{
+		ctx:      ctx,
+		dialect:  dialect,
+		rows:     rows,
+		plan:     plan,
+		scanArgs: make([]interface{}, len(plan.targets)),
+		temps:    make([]reflect.Value, len(plan.targets)),
+		notNull:  make([]bool, len(plan.ptrTables)),
+		isStatic: !info.IsNestedStruct && reflect.PtrTo(structType).Implements(staticScannerType),
+
+		timeLocation: timeLocationFromContext(ctx),
+	}, nil
+}
+
+// scan reads the current row into the record, which
+// must be a pointer to the struct type of the plan.
+func (s *rowScanner) scan(record reflect.Value) error {
+	var staticScanner StaticScanner
+	if s.isStatic {
+		staticScanner = record.Interface().(StaticScanner)
+	}
+
+	v := record.Elem()
+
+	// The tables declared as pointers are allocated before
+	// scanning, and the tables containing them come first:
+	for _, table := range s.plan.ptrTables {
+		v.FieldByIndex(table.path).Set(reflect.New(table.structType))
+	}
+	for i := range s.notNull {
+		s.notNull[i] = false
+	}
+
+	for i, target := range s.plan.targets {
+		s.temps[i] = reflect.Value{}
+		if target.fieldInfo == nil {
+			s.scanArgs[i] = nopScannerValue
+			continue
+		}
+
+		var attrPtr interface{}
+		if staticScanner != nil {
+			attrPtr = staticScanner.KSQLScanTarget(target.fieldInfo.Name)
+		}
+		if attrPtr != nil {
+			s.scanArgs[i] = getScanValue(s.ctx, s.dialect, target.fieldInfo, attrPtr)
+			continue
+		}
+
+		field := v.FieldByIndex(target.path)
+		if target.isTime && s.timeLocation != nil {
+			s.scanArgs[i] = timeScanner{field: field, loc: s.timeLocation}
+			if target.ptrTable != -1 {
+				s.scanArgs[i] = nullTracker{
+					scanner: s.scanArgs[i],
+					notNull: &s.notNull[target.ptrTable],
+				}
+			}
+			continue
+		}
+
+		hasModifier := target.fieldInfo.Modifier != nil && target.fieldInfo.Modifier.Scan != nil
+		if hasModifier || (target.ptrTable == -1 && target.concreteType == nil) {
+			s.scanArgs[i] = getScanValue(s.ctx, s.dialect, target.fieldInfo, field.Addr().Interface())
+			if hasModifier && target.ptrTable != -1 {
+				s.scanArgs[i] = nullTracker{
+					scanner: s.scanArgs[i],
+					notNull: &s.notNull[target.ptrTable],
+				}
+			}
+			continue
+		}
+
+		// The other fields are scanned into a pointer to pointer,
+		// which is set to nil if the column is NULL:
+		baseType := field.Type()
+		if target.concreteType != nil {
+			baseType = target.concreteType
+			if baseType.Kind() == reflect.Ptr {
+				baseType = baseType.Elem()
+			}
+		}
+		s.temps[i] = reflect.New(reflect.PtrTo(baseType))
+		s.scanArgs[i] = s.temps[i].Interface()
+	}
+
+	err := s.rows.Scan(s.scanArgs...)
+	if err != nil {
+		return err
+	}
+
+	for i, temp := range s.temps {
+		if !temp.IsValid() {
+			continue
+		}
+
+		target := s.plan.targets[i]
+		if temp.Elem().IsNil() {
+			field := v.FieldByIndex(target.path)
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+
+		value := temp.Elem()
+		if target.concreteType == nil || target.concreteType.Kind() != reflect.Ptr {
+			value = value.Elem()
+		}
+		v.FieldByIndex(target.path).Set(value)
+
+		if target.ptrTable != -1 {
+			s.notNull[target.ptrTable] = true
+		}
+	}
+
+	// The tables contained by other tables come last, so the
+	// tables with columns are propagated to their parents:
+	for i := len(s.plan.ptrTables) - 1; i >= 0; i-- {
+		table := s.plan.ptrTables[i]
+		if s.notNull[i] || table.hasValueTables {
+			if table.parent != -1 {
+				s.notNull[table.parent] = true
+			}
+			continue
+		}
+
+		v.FieldByIndex(table.path).Set(reflect.Zero(reflect.PtrTo(table.structType)))
+	}
+
+	return callAfterQuery(s.ctx, record.Interface())
+}
+
+// nullTracker wraps the scan values of the modifiers used on the
+// tables declared as pointers, for checking if they are NULL.
+type nullTracker struct {
+	scanner interface{}
+	notNull *bool
+}
+
+func (n nullTracker) Scan(dbValue interface{}) error {
+	if dbValue != nil {
+		*n.notNull = true
+	}
+	return n.scanner.(sql.Scanner).Scan(dbValue)
+}
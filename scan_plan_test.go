@@ -0,0 +1,109 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+// fakeColumnRows returns the same row forever
+// and counts the calls to the Columns method
+type fakeColumnRows struct {
+	Rows
+
+	columns      []string
+	row          []interface{}
+	columnsCalls int
+}
+
+func (f *fakeColumnRows) Next() bool {
+	return true
+}
+
+func (f *fakeColumnRows) Scan(args ...interface{}) error {
+	for i, arg := range args {
+		if _, ok := arg.(*nopScanner); ok {
+			continue
+		}
+		reflect.ValueOf(arg).Elem().Set(reflect.ValueOf(f.row[i]))
+	}
+	return nil
+}
+
+func (f *fakeColumnRows) Columns() ([]string, error) {
+	f.columnsCalls++
+	return f.columns, nil
+}
+
+func TestRowScanner(t *testing.T) {
+	ctx := context.Background()
+
+	type user struct {
+		ID   int    `ksql:"id"`
+		Name string `ksql:"name"`
+		Age  int    `ksql:"age"`
+	}
+
+	t.Run("should map the columns to the fields by name", func(t *testing.T) {
+		rows := &fakeColumnRows{
+			columns: []string{"age", "unknown_column", "id"},
+			row:     []interface{}{42, "ignored", 1},
+		}
+
+		info, err := structs.GetTagInfo(reflect.TypeOf(user{}))
+		tt.AssertNoErr(t, err)
+
+		scanner, err := newRowScanner(ctx, supportedDialects["postgres"], rows, reflect.TypeOf(user{}), info)
+		tt.AssertNoErr(t, err)
+
+		users := make([]user, 3)
+		for i := range users {
+			err = scanner.scan(reflect.ValueOf(&users[i]))
+			tt.AssertNoErr(t, err)
+		}
+
+		tt.AssertEqual(t, users, []user{{ID: 1, Age: 42}, {ID: 1, Age: 42}, {ID: 1, Age: 42}})
+		tt.AssertEqual(t, rows.columnsCalls, 1)
+	})
+
+	t.Run("should cache the plans per struct type and set of columns", func(t *testing.T) {
+		info, err := structs.GetTagInfo(reflect.TypeOf(user{}))
+		tt.AssertNoErr(t, err)
+
+		plan1, err := getScanPlan(&fakeColumnRows{columns: []string{"id", "name"}}, reflect.TypeOf(user{}), info)
+		tt.AssertNoErr(t, err)
+		plan2, err := getScanPlan(&fakeColumnRows{columns: []string{"id", "name"}}, reflect.TypeOf(user{}), info)
+		tt.AssertNoErr(t, err)
+		plan3, err := getScanPlan(&fakeColumnRows{columns: []string{"name", "id"}}, reflect.TypeOf(user{}), info)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, plan1 == plan2, true)
+		tt.AssertEqual(t, plan1 == plan3, false)
+		tt.AssertEqual(t, plan3.targets[0].fieldInfo.Name, "name")
+	})
+
+	t.Run("should not allocate memory for scanning each row", func(t *testing.T) {
+		rows := &fakeColumnRows{
+			columns: []string{"id", "name", "age"},
+			row:     []interface{}{1, "Ana", 42},
+		}
+
+		info, err := structs.GetTagInfo(reflect.TypeOf(user{}))
+		tt.AssertNoErr(t, err)
+
+		scanner, err := newRowScanner(ctx, supportedDialects["postgres"], rows, reflect.TypeOf(user{}), info)
+		tt.AssertNoErr(t, err)
+
+		var u user
+		record := reflect.ValueOf(&u)
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = scanner.scan(record)
+		})
+
+		tt.AssertEqual(t, allocs, float64(0))
+		tt.AssertEqual(t, u, user{ID: 1, Name: "Ana", Age: 42})
+	})
+}
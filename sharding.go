@@ -0,0 +1,267 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardKeyFunc returns the name of the shard that should run an operation,
+// extracted from the context or from the record, which is the record or
+// ID received by the Insert, Patch, Update and Delete methods and nil for
+// the other methods.
+//
+// For reads it can return an empty string for querying all the shards.
+type ShardKeyFunc func(ctx context.Context, record interface{}) (shard string, err error)
+
+// ShardedDB routes the operations of the ksql.Provider interface to one
+// of many databases, e.g. for applications that store each group of
+// customers on a different database, see `ksql.NewSharded()` for
+// more details.
+type ShardedDB struct {
+	shardKey ShardKeyFunc
+	shards   map[string]DB
+	names    []string
+}
+
+var _ Provider = ShardedDB{}
+
+// NewSharded returns a ksql.Provider that runs each operation on the shard
+// whose name is returned by the shardKey function, e.g.:
+//
+//	db, err := ksql.NewSharded(func(ctx context.Context, record interface{}) (string, error) {
+//		if user, ok := record.(*User); ok {
+//			return user.Region, nil
+//		}
+//		region, _ := ctx.Value(regionKey{}).(string)
+//		return region, nil
+//	}, map[string]ksql.DB{
+//		"us": usDB,
+//		"eu": euDB,
+//	})
+//
+// The writes, Exec and Transaction must always resolve to a shard, since
+// there are no transactions across shards, but when the shard of Query,
+// QueryOne or QueryChunks is empty the query runs on all the shards:
+//
+//   - Query runs concurrently on all the shards and appends the results in
+//     the order of the shard names, so ORDER BY and LIMIT clauses only apply
+//     to the rows of each shard.
+//   - QueryOne returns the record found on the first shard
+//     in the order of their names, or a ksql.NotFoundError.
+//   - QueryChunks runs on one shard at a time, in the order of their
+//     names, so the chunks never contain rows of different shards.
+//
+// For running operations on a specific shard, e.g. migrations, use
+// the `ShardedDB.Shard()` method.
+func NewSharded(shardKey ShardKeyFunc, shards map[string]DB) (ShardedDB, error) {
+	if shardKey == nil {
+		return ShardedDB{}, fmt.Errorf("ksql: the shardKey function of NewSharded can't be nil")
+	}
+
+	if len(shards) == 0 {
+		return ShardedDB{}, fmt.Errorf("ksql: NewSharded requires at least one shard")
+	}
+
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return ShardedDB{
+		shardKey: shardKey,
+		shards:   shards,
+		names:    names,
+	}, nil
+}
+
+// Shard returns the DB of the shard with the input name
+// and true, or a zero DB and false if there is no such shard
+func (s ShardedDB) Shard(name string) (DB, bool) {
+	db, found := s.shards[name]
+	return db, found
+}
+
+// resolve returns the DB of the shard with the name returned by the
+// shardKey function, or an empty name and a zero DB if it returned
+// an empty name and allowAll is true, i.e. for querying all shards.
+func (s ShardedDB) resolve(ctx context.Context, record interface{}, allowAll bool) (string, DB, error) {
+	name, err := s.shardKey(ctx, record)
+	if err != nil {
+		return "", DB{}, err
+	}
+
+	if name == "" {
+		if allowAll {
+			return "", DB{}, nil
+		}
+		return "", DB{}, fmt.Errorf("ksql: the shardKey function returned no shard for an operation that requires one")
+	}
+
+	db, found := s.shards[name]
+	if !found {
+		return "", DB{}, fmt.Errorf("ksql: there is no shard named `%s`", name)
+	}
+
+	return name, db, nil
+}
+
+// Insert runs the Insert method on the shard of the record
+func (s ShardedDB) Insert(ctx context.Context, table Table, record interface{}) error {
+	_, db, err := s.resolve(ctx, record, false)
+	if err != nil {
+		return err
+	}
+	return db.Insert(ctx, table, record)
+}
+
+// Patch runs the Patch method on the shard of the record
+func (s ShardedDB) Patch(ctx context.Context, table Table, record interface{}) error {
+	_, db, err := s.resolve(ctx, record, false)
+	if err != nil {
+		return err
+	}
+	return db.Patch(ctx, table, record)
+}
+
+// Update runs the Update method on the shard of the record
+//
+// Deprecated: Use the Patch method instead
+func (s ShardedDB) Update(ctx context.Context, table Table, record interface{}) error {
+	return s.Patch(ctx, table, record)
+}
+
+// Delete runs the Delete method on the shard of the ID or record
+func (s ShardedDB) Delete(ctx context.Context, table Table, idOrRecord interface{}) error {
+	_, db, err := s.resolve(ctx, idOrRecord, false)
+	if err != nil {
+		return err
+	}
+	return db.Delete(ctx, table, idOrRecord)
+}
+
+// Query runs the Query method on the shard of the context
+// or, if there is none, on all the shards merging the results
+func (s ShardedDB) Query(ctx context.Context, records interface{}, query string, params ...interface{}) error {
+	name, db, err := s.resolve(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		return db.Query(ctx, records, query, params...)
+	}
+
+	slicePtr := reflect.ValueOf(records)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.IsNil() || slicePtr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ksql: expected records to be a pointer to a slice, but got: %T", records)
+	}
+	sliceType := slicePtr.Elem().Type()
+
+	results := make([]reflect.Value, len(s.names))
+	errs := make([]error, len(s.names))
+	var wg sync.WaitGroup
+	for i, name := range s.names {
+		results[i] = reflect.New(sliceType)
+
+		wg.Add(1)
+		go func(i int, db DB) {
+			defer wg.Done()
+			errs[i] = db.Query(ctx, results[i].Interface(), query, params...)
+		}(i, s.shards[name])
+	}
+	wg.Wait()
+
+	merged := reflect.MakeSlice(sliceType, 0, 0)
+	for i, name := range s.names {
+		if errs[i] != nil {
+			return fmt.Errorf("ksql: error querying shard `%s`: %s", name, errs[i])
+		}
+		merged = reflect.AppendSlice(merged, results[i].Elem())
+	}
+	slicePtr.Elem().Set(merged)
+
+	return nil
+}
+
+// QueryOne runs the QueryOne method on the shard of the context or, if
+// there is none, returns the record found on the first shard by name
+func (s ShardedDB) QueryOne(ctx context.Context, record interface{}, query string, params ...interface{}) error {
+	name, db, err := s.resolve(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		return db.QueryOne(ctx, record, query, params...)
+	}
+
+	for _, name := range s.names {
+		err := s.shards[name].QueryOne(ctx, record, query, params...)
+		if IsNotFound(err) {
+			continue
+		}
+		return err
+	}
+
+	return notFoundError(ctx, NotFoundError{Query: query})
+}
+
+// QueryChunks runs the QueryChunks method on the shard of the
+// context or, if there is none, on each of the shards by name
+func (s ShardedDB) QueryChunks(ctx context.Context, parser ChunkParser) error {
+	name, db, err := s.resolve(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		return db.QueryChunks(ctx, parser)
+	}
+
+	// QueryChunks returns nil when the iteration is aborted,
+	// so we need to intercept the callback for detecting it:
+	var aborted int32
+	fn := reflect.ValueOf(parser.ForEachChunk)
+	if fn.Kind() == reflect.Func {
+		parser.ForEachChunk = reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+			results := fn.Call(args)
+			if len(results) == 1 && results[0].Interface() == ErrAbortIteration {
+				atomic.StoreInt32(&aborted, 1)
+			}
+			return results
+		}).Interface()
+	}
+
+	for _, name := range s.names {
+		err := s.shards[name].QueryChunks(ctx, parser)
+		if err != nil {
+			return err
+		}
+		if atomic.LoadInt32(&aborted) == 1 {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Exec runs the Exec method on the shard of the context
+func (s ShardedDB) Exec(ctx context.Context, query string, params ...interface{}) (Result, error) {
+	_, db, err := s.resolve(ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(ctx, query, params...)
+}
+
+// Transaction runs the Transaction method on the shard of the
+// context, so all the operations of the transaction run on it
+func (s ShardedDB) Transaction(ctx context.Context, fn func(Provider) error) error {
+	_, db, err := s.resolve(ctx, nil, false)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(ctx, fn)
+}
@@ -0,0 +1,139 @@
+package ksql
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestShardedDB(t *testing.T) {
+	type shardKey struct{}
+
+	type account struct {
+		ID     int    `ksql:"id"`
+		Region string `ksql:"region"`
+	}
+
+	// The queries on all shards run concurrently:
+	var mutex sync.Mutex
+	newShard := func(t *testing.T, name string, queries *[]string, ids ...interface{}) DB {
+		record := func(query string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			*queries = append(*queries, name+": "+query)
+		}
+
+		db, err := NewWithAdapter(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, params ...interface{}) (Result, error) {
+				record(query)
+				return NewMockResult(1, 1), nil
+			},
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				record(query)
+				return &fakeScalarRows{values: ids}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	newSharded := func(t *testing.T, queries *[]string) ShardedDB {
+		db, err := NewSharded(func(ctx context.Context, record interface{}) (string, error) {
+			if acc, ok := record.(*account); ok {
+				return acc.Region, nil
+			}
+			region, _ := ctx.Value(shardKey{}).(string)
+			return region, nil
+		}, map[string]DB{
+			"us": newShard(t, "us", queries, 1, 2),
+			"eu": newShard(t, "eu", queries, 3),
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should route the writes to the shard of the record", func(t *testing.T) {
+		var queries []string
+		db := newSharded(t, &queries)
+
+		err := db.Insert(context.Background(), NewTable("accounts"), &account{Region: "eu"})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, queries, []string{"eu: INSERT INTO `accounts` (`region`) VALUES (?)"})
+	})
+
+	t.Run("should route the queries to the shard of the context", func(t *testing.T) {
+		var queries []string
+		db := newSharded(t, &queries)
+
+		var ids []int
+		ctx := context.WithValue(context.Background(), shardKey{}, "us")
+		err := db.Query(ctx, &ids, "SELECT id FROM accounts")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, ids, []int{1, 2})
+		tt.AssertEqual(t, queries, []string{"us: SELECT id FROM accounts"})
+	})
+
+	t.Run("should merge the results of all shards in the order of their names", func(t *testing.T) {
+		var queries []string
+		db := newSharded(t, &queries)
+
+		var ids []int
+		err := db.Query(context.Background(), &ids, "SELECT id FROM accounts")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, ids, []int{3, 1, 2})
+		tt.AssertEqual(t, len(queries), 2)
+	})
+
+	t.Run("should return the first record found when querying all shards", func(t *testing.T) {
+		var queries []string
+		db := newSharded(t, &queries)
+
+		var id int
+		err := db.QueryOne(context.Background(), &id, "SELECT id FROM accounts")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, id, 3)
+		tt.AssertEqual(t, queries, []string{"eu: SELECT id FROM accounts"})
+	})
+
+	t.Run("should report records not found on any shard", func(t *testing.T) {
+		db, err := NewSharded(func(ctx context.Context, record interface{}) (string, error) {
+			return "", nil
+		}, map[string]DB{
+			"us": newShard(t, "us", &[]string{}),
+			"eu": newShard(t, "eu", &[]string{}),
+		})
+		tt.AssertNoErr(t, err)
+
+		var id int
+		err = db.QueryOne(context.Background(), &id, "SELECT id FROM accounts")
+		tt.AssertEqual(t, err, ErrRecordNotFound)
+
+		err = db.QueryOne(WithNotFoundDetails(context.Background()), &id, "SELECT id FROM accounts")
+		tt.AssertEqual(t, err, NotFoundError{Query: "SELECT id FROM accounts"})
+	})
+
+	t.Run("should report operations without a shard", func(t *testing.T) {
+		var queries []string
+		db := newSharded(t, &queries)
+
+		_, err := db.Exec(context.Background(), "DELETE FROM accounts")
+		tt.AssertErrContains(t, err, "no shard")
+
+		err = db.Insert(context.Background(), NewTable("accounts"), &account{Region: "asia"})
+		tt.AssertErrContains(t, err, "no shard", "asia")
+
+		tt.AssertEqual(t, len(queries), 0)
+	})
+
+	t.Run("should report invalid arguments", func(t *testing.T) {
+		_, err := NewSharded(nil, map[string]DB{"us": {}})
+		tt.AssertErrContains(t, err, "shardKey", "nil")
+
+		_, err = NewSharded(func(ctx context.Context, record interface{}) (string, error) {
+			return "", nil
+		}, nil)
+		tt.AssertErrContains(t, err, "at least one shard")
+	})
+}
@@ -0,0 +1,119 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// flightGroup deduplicates the identical queries running concurrently,
+// it is enabled with the `ksql.Config.SingleFlight` option.
+//
+// The first caller of a query runs it and the callers arriving while it
+// runs wait for its result, which is copied to each of their destinations
+// by serializing it with encoding/gob, so the callers never share the
+// same slices, maps or pointers.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+
+	// result is nil if the result couldn't be
+	// serialized, in which case it is not shared
+	result []byte
+	err    error
+}
+
+type skipFlightKey struct{}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		flights: map[string]*flight{},
+	}
+}
+
+// useSingleFlight checks if a query can be deduplicated
+func (c DB) useSingleFlight(ctx context.Context) bool {
+	if c.flights == nil {
+		return false
+	}
+
+	skipped, _ := ctx.Value(skipFlightKey{}).(bool)
+	return !skipped && c.isShareableRead(ctx)
+}
+
+// do runs the query or, if an identical query is already running,
+// waits for it and copies its result to the dest argument.
+func (g *flightGroup) do(
+	ctx context.Context,
+	method string,
+	dest interface{},
+	query string,
+	params []interface{},
+	runQuery func(ctx context.Context) error,
+) error {
+	ctx = context.WithValue(ctx, skipFlightKey{}, true)
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return runQuery(ctx)
+	}
+
+	key, ok := buildReadKey(ctx, method, dest, query, params)
+	if !ok {
+		return runQuery(ctx)
+	}
+
+	g.mu.Lock()
+	if f, found := g.flights[key]; found {
+		g.mu.Unlock()
+		return f.wait(ctx, destValue, runQuery)
+	}
+
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.err = runQuery(ctx)
+	if f.err == nil {
+		// The result is not shared if it can't be serialized:
+		f.result, _ = encodeReadResult(destValue)
+	}
+
+	return f.err
+}
+
+// wait waits for the result of the flight and copies it to the
+// destination, if the flight failed due to its own context or
+// if its result can't be shared the query runs again instead.
+func (f *flight) wait(ctx context.Context, destValue reflect.Value, runQuery func(ctx context.Context) error) error {
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	switch ErrorClass(f.err) {
+	case ErrorClassTimeout, ErrorClassCanceled:
+		return runQuery(ctx)
+	}
+	if f.err != nil {
+		return f.err
+	}
+
+	if f.result == nil || decodeReadResult(f.result, destValue) != nil {
+		return runQuery(ctx)
+	}
+
+	return nil
+}
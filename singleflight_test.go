@@ -0,0 +1,93 @@
+package ksql
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestSingleFlight(t *testing.T) {
+	ctx := context.Background()
+
+	newDB := func(t *testing.T, numQueries *int32, started chan<- struct{}, release <-chan struct{}) DB {
+		db, err := NewWithConfig(mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+				if atomic.AddInt32(numQueries, 1) == 1 {
+					close(started)
+				}
+				<-release
+				return &fakeScalarRows{values: []interface{}{1, 2}}, nil
+			},
+		}, "sqlite3", Config{
+			SingleFlight: true,
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should share the result of identical concurrent queries", func(t *testing.T) {
+		var numQueries int32
+		started, release := make(chan struct{}), make(chan struct{})
+		db := newDB(t, &numQueries, started, release)
+
+		results := make([][]int, 5)
+		errs := make([]error, 5)
+		var wg sync.WaitGroup
+		query := func(i int) {
+			defer wg.Done()
+			errs[i] = db.Query(ctx, &results[i], "SELECT id FROM users WHERE age > ?", 18)
+		}
+
+		wg.Add(1)
+		go query(0)
+		<-started
+
+		for i := 1; i < len(results); i++ {
+			wg.Add(1)
+			go query(i)
+		}
+
+		// Give the other callers time to join the running query:
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		tt.AssertEqual(t, atomic.LoadInt32(&numQueries), int32(1))
+		for i := range results {
+			tt.AssertNoErr(t, errs[i])
+			tt.AssertEqual(t, results[i], []int{1, 2})
+		}
+
+		// Each caller must receive its own copy of the result:
+		results[0][0] = 42
+		tt.AssertEqual(t, results[1], []int{1, 2})
+	})
+
+	t.Run("should not share queries with different params", func(t *testing.T) {
+		var numQueries int32
+		started, release := make(chan struct{}), make(chan struct{})
+		db := newDB(t, &numQueries, started, release)
+		close(release)
+
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var ids []int
+				errs[i] = db.Query(ctx, &ids, "SELECT id FROM users WHERE age > ?", i)
+			}(i)
+		}
+		wg.Wait()
+
+		tt.AssertNoErr(t, errs[0])
+		tt.AssertNoErr(t, errs[1])
+
+		tt.AssertEqual(t, atomic.LoadInt32(&numQueries), int32(2))
+	})
+}
@@ -0,0 +1,69 @@
+package ksql
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowQuery describes a statement that took longer than
+// the `ksql.Config.SlowQueryThreshold` to finish, it is
+// passed to the `ksql.Config.OnSlowQuery` callback.
+type SlowQuery struct {
+	// Query is the query with its whitespace normalized
+	Query string
+
+	// ParamsDigest is a hash of the params of the query, which
+	// allows telling apart calls with different params without
+	// exposing their values
+	ParamsDigest string
+
+	Duration time.Duration
+
+	// Caller is the location of the code that sent the statement
+	// in the format "file:line", or an empty string if unknown
+	Caller string
+
+	// Err is the error returned by the statement, if any
+	Err error
+
+	// Plan is the result of running EXPLAIN on the statement, it is only
+	// set if `ksql.Config.ExplainSlowQueries` is enabled, see the
+	// `ksql.QueryPlan` type for more details on its format.
+	Plan string
+
+	// PlanErr is the error returned by the EXPLAIN, if any
+	PlanErr error
+}
+
+// paramsDigest returns a short hash of the params
+func paramsDigest(params []interface{}) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%#v", params)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+const ksqlPackagePrefix = "github.com/vingarcia/ksql."
+
+// callerLocation returns the location of the first function on
+// the stack that is not part of KSQL itself, i.e. the code of
+// the user that started the operation.
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		isKSQL := strings.HasPrefix(frame.Function, ksqlPackagePrefix) &&
+			!strings.HasSuffix(frame.File, "_test.go")
+		isRuntime := strings.HasPrefix(frame.Function, "runtime.")
+		if !isKSQL && !isRuntime && frame.File != "" {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
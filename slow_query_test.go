@@ -0,0 +1,68 @@
+package ksql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestSlowQuery(t *testing.T) {
+	t.Run("should report statements slower than the threshold", func(t *testing.T) {
+		var entries []logEntry
+		var slowQueries []SlowQuery
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				time.Sleep(5 * time.Millisecond)
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3", Config{
+			Logger:             newRecordingLogger(&entries),
+			SlowQueryThreshold: time.Millisecond,
+			OnSlowQuery: func(ctx context.Context, q SlowQuery) {
+				slowQueries = append(slowQueries, q)
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "UPDATE users SET age = ?", 42)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(slowQueries), 1)
+		tt.AssertEqual(t, slowQueries[0].Query, "UPDATE users SET age = ?")
+		tt.AssertEqual(t, slowQueries[0].ParamsDigest, paramsDigest([]interface{}{42}))
+		tt.AssertEqual(t, slowQueries[0].Duration >= 5*time.Millisecond, true)
+		tt.AssertEqual(t, strings.Contains(slowQueries[0].Caller, "slow_query_test.go:"), true)
+
+		tt.AssertEqual(t, len(entries), 1)
+		tt.AssertEqual(t, entries[0].level, LogLevelWarn)
+		tt.AssertEqual(t, entries[0].keyvals["caller"], slowQueries[0].Caller)
+	})
+
+	t.Run("should not report statements faster than the threshold", func(t *testing.T) {
+		var slowQueries []SlowQuery
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				return NewMockResult(0, 1), nil
+			},
+		}, "sqlite3", Config{
+			SlowQueryThreshold: time.Hour,
+			OnSlowQuery: func(ctx context.Context, q SlowQuery) {
+				slowQueries = append(slowQueries, q)
+			},
+		})
+		tt.AssertNoErr(t, err)
+
+		_, err = db.Exec(context.Background(), "UPDATE users SET age = ?", 42)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(slowQueries), 0)
+	})
+
+	t.Run("should produce different digests for different params", func(t *testing.T) {
+		tt.AssertNotEqual(t, paramsDigest([]interface{}{1}), paramsDigest([]interface{}{2}))
+		tt.AssertEqual(t, paramsDigest([]interface{}{"a", 1}), paramsDigest([]interface{}{"a", 1}))
+	})
+}
@@ -0,0 +1,249 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+type unscopedKey struct{}
+
+// Unscoped returns a copy of the input context that disables
+// the soft delete behavior of the `softDelete` modifier, e.g.:
+//
+//	err := db.Query(ksql.Unscoped(ctx), &users, "FROM users")
+//
+// When using this context the queries will also return the
+// soft deleted rows, and the Delete method will actually
+// remove the rows from the database.
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey{}, true)
+}
+
+func isUnscoped(ctx context.Context) bool {
+	unscoped, _ := ctx.Value(unscopedKey{}).(bool)
+	return unscoped
+}
+
+// addSoftDeleteFilter adds a `deleted_at IS NULL` condition to the
+// WHERE clause of the query for each struct declaring the `softDelete`
+// modifier, i.e. the destination struct itself or, for nested structs,
+// each of the structs representing a joined table.
+func addSoftDeleteFilter(
+	ctx context.Context,
+	dialect Dialect,
+	query string,
+	structType reflect.Type,
+	info structs.StructInfo,
+) (string, error) {
+	if isUnscoped(ctx) {
+		return query, nil
+	}
+
+	var conditions []string
+	if !info.IsNestedStruct {
+		if info.SoftDeleteField != nil {
+			conditions = append(conditions, escapeColumn(dialect, info, info.SoftDeleteField.Name)+" IS NULL")
+		}
+	} else {
+		tables, err := getNestedTables(structType, info)
+		if err != nil {
+			return "", err
+		}
+
+		for _, table := range tables {
+			if table.info.SoftDeleteField != nil {
+				conditions = append(conditions,
+					dialect.Escape(table.alias)+"."+escapeColumn(dialect, table.info, table.info.SoftDeleteField.Name)+" IS NULL",
+				)
+			}
+		}
+	}
+
+	if len(conditions) == 0 {
+		return query, nil
+	}
+
+	return addWhereCondition(
+		query,
+		strings.Join(conditions, " AND "),
+		"soft delete filter",
+		"use ksql.Unscoped(ctx) and filter the deleted rows manually",
+	)
+}
+
+// addWhereCondition adds the input condition to the top level WHERE
+// clause of the query, creating this clause if it doesn't exist yet.
+//
+// The filterName and the hint are only used for explaining why
+// the condition can't be added to queries using set operations.
+func addWhereCondition(query string, condition string, filterName string, hint string) (string, error) {
+	whereStart, whereEnd := -1, -1
+
+	var err error
+	clauseEnd := scanTopLevelWords(query, func(word string, start int) (stop bool) {
+		switch strings.ToUpper(word) {
+		case "WHERE":
+			if whereStart == -1 {
+				whereStart, whereEnd = start, start+len(word)
+			}
+		case "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET", "FETCH", "FOR":
+			return true
+		case "UNION", "INTERSECT", "EXCEPT":
+			err = fmt.Errorf(
+				"ksql: can't add the %s to queries using %s, %s",
+				filterName, strings.ToUpper(word), hint,
+			)
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return "", err
+	}
+
+	rest := query[clauseEnd:]
+	if rest != "" {
+		rest = " " + rest
+	}
+
+	if whereStart == -1 {
+		return strings.TrimRight(query[:clauseEnd], " \t\r\n") + " WHERE " + condition + rest, nil
+	}
+
+	originalCondition := strings.TrimSpace(query[whereEnd:clauseEnd])
+
+	// Line comments would otherwise comment out the closing parenthesis:
+	closing := ")"
+	if strings.Contains(originalCondition, "--") {
+		closing = "\n)"
+	}
+
+	return query[:whereStart] + "WHERE " + condition + " AND (" + originalCondition + closing + rest, nil
+}
+
+// scanTopLevelWords calls fn for each word of the query that is not inside
+// parentheses, quotes or comments, and it returns the position where the
+// scan stopped, i.e. the start of the word for which fn returned true,
+// the position of the first top level `;` or the end of the query.
+func scanTopLevelWords(query string, fn func(word string, start int) (stop bool)) int {
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end == -1 {
+				return len(query)
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return len(query)
+			}
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end == -1 {
+				return len(query)
+			}
+			i += end + 1
+
+		case c == '(':
+			depth++
+
+		case c == ')':
+			depth--
+
+		case c == ';' && depth == 0:
+			return i
+
+		case isIdentifierChar(c):
+			start := i
+			for i+1 < len(query) && isIdentifierChar(query[i+1]) {
+				i++
+			}
+			if depth > 0 || start > 0 && (query[start-1] == '.' || query[start-1] == '$' || query[start-1] == '@') {
+				continue
+			}
+
+			if fn(query[start:i+1], start) {
+				return start
+			}
+		}
+	}
+
+	return len(query)
+}
+
+// getSoftDeleteField returns the field with the softDelete modifier
+// of the struct registered for the table or, if there is none, of the
+// record received as argument, if no such field exists it returns nil.
+func getSoftDeleteField(ctx context.Context, naming *structs.Naming, table Table, idOrRecord interface{}) (*structs.FieldInfo, error) {
+	if isUnscoped(ctx) {
+		return nil, nil
+	}
+
+	info, err := getRecordInfo(naming, table, idOrRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return info.SoftDeleteField, nil
+}
+
+// getRecordInfo returns the info of the struct registered for the table
+// or, if there is none, of the record received as argument, if the
+// argument is just an ID it returns an empty StructInfo.
+func getRecordInfo(naming *structs.Naming, table Table, idOrRecord interface{}) (structs.StructInfo, error) {
+	t := table.structType
+	if t == nil {
+		t = reflect.TypeOf(idOrRecord)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return structs.StructInfo{}, nil
+		}
+	}
+
+	return naming.GetTagInfo(t)
+}
+
+func buildSoftDeleteQuery(
+	dialect Dialect,
+	table Table,
+	info structs.StructInfo,
+	idMap map[string]interface{},
+	softDeleteColumn string,
+	deletedAt time.Time,
+	outputQuery string,
+	returningQuery string,
+) (query string, params []interface{}) {
+	params = append(params, deletedAt)
+
+	whereQuery := []string{}
+	for i, idName := range table.idColumns {
+		whereQuery = append(whereQuery, fmt.Sprintf(
+			"%s = %s", escapeColumn(dialect, info, idName), dialect.Placeholder(i+1),
+		))
+		params = append(params, idMap[idName])
+	}
+	whereQuery = append(whereQuery, escapeColumn(dialect, info, softDeleteColumn)+" IS NULL")
+
+	return fmt.Sprintf(
+		"UPDATE %s SET %s = %s%s WHERE %s%s",
+		dialect.Escape(table.name),
+		escapeColumn(dialect, info, softDeleteColumn),
+		dialect.Placeholder(0),
+		outputQuery,
+		strings.Join(whereQuery, " AND "),
+		returningQuery,
+	), params
+}
@@ -0,0 +1,77 @@
+package ksql
+
+import (
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAddWhereCondition(t *testing.T) {
+	tests := []struct {
+		desc          string
+		query         string
+		expectedQuery string
+		expectedErr   []string
+	}{
+		{
+			desc:          "should add a WHERE clause to queries without one",
+			query:         `SELECT * FROM users`,
+			expectedQuery: `SELECT * FROM users WHERE deleted_at IS NULL`,
+		},
+		{
+			desc:          "should add the condition to existing WHERE clauses",
+			query:         `SELECT * FROM users WHERE name = $1 OR age = $2`,
+			expectedQuery: `SELECT * FROM users WHERE deleted_at IS NULL AND (name = $1 OR age = $2)`,
+		},
+		{
+			desc:          "should add the WHERE clause before other clauses",
+			query:         `SELECT * FROM users ORDER BY id LIMIT 10`,
+			expectedQuery: `SELECT * FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT 10`,
+		},
+		{
+			desc:          "should stop the WHERE clause before other clauses",
+			query:         `select * from users where name = ? group by age having count(*) > 1`,
+			expectedQuery: `select * from users WHERE deleted_at IS NULL AND (name = ?) group by age having count(*) > 1`,
+		},
+		{
+			desc:          "should ignore keywords inside subqueries",
+			query:         `SELECT * FROM users WHERE id IN (SELECT user_id FROM posts WHERE title = 'foo' ORDER BY id)`,
+			expectedQuery: `SELECT * FROM users WHERE deleted_at IS NULL AND (id IN (SELECT user_id FROM posts WHERE title = 'foo' ORDER BY id))`,
+		},
+		{
+			desc:          "should ignore keywords inside strings, quoted names and comments",
+			query:         `SELECT * FROM "order" /* WHERE */ WHERE name = 'ORDER BY'`,
+			expectedQuery: `SELECT * FROM "order" /* WHERE */ WHERE deleted_at IS NULL AND (name = 'ORDER BY')`,
+		},
+		{
+			desc:          "should not let line comments hide the closing parenthesis",
+			query:         "SELECT * FROM users WHERE id = 1 -- some comment",
+			expectedQuery: "SELECT * FROM users WHERE deleted_at IS NULL AND (id = 1 -- some comment\n)",
+		},
+		{
+			desc:          "should keep the trailing semicolon",
+			query:         `SELECT * FROM users;`,
+			expectedQuery: `SELECT * FROM users WHERE deleted_at IS NULL ;`,
+		},
+
+		/* * * * * Testing error cases: * * * * */
+		{
+			desc:        "should report error for queries with UNION",
+			query:       `SELECT * FROM users UNION SELECT * FROM old_users`,
+			expectedErr: []string{"ksql", "UNION", "Unscoped"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			query, err := addWhereCondition(test.query, "deleted_at IS NULL", "soft delete filter", "use ksql.Unscoped(ctx) and filter the deleted rows manually")
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package ksql
+
+import "reflect"
+
+// StaticScanner can be implemented by the records for loading the
+// query results into their attributes without using reflection.
+//
+// It is meant to be generated by the `ksqlgen` command, i.e.:
+//
+//	//go:generate go run github.com/vingarcia/ksql/cmd/ksqlgen -type User -table users
+//
+// The modifiers of the attributes, e.g. `ksql:"address,json"`,
+// are still applied when scanning the values.
+type StaticScanner interface {
+	// KSQLScanTarget returns a pointer to the attribute of the
+	// column or nil if the record has no attribute for it.
+	KSQLScanTarget(column string) interface{}
+}
+
+// StaticValuer can be implemented by the records for reading the values
+// of their attributes without using reflection on the write operations,
+// e.g. Insert and Patch, and on the `ksqltest.StructToMap()` function.
+//
+// Just like StaticScanner it is meant to be generated by `ksqlgen`.
+type StaticValuer interface {
+	// KSQLValues returns a map with the column names as keys and
+	// the values of the attributes, where the nil pointers are
+	// omitted and the other pointers are dereferenced.
+	KSQLValues() map[string]interface{}
+}
+
+var staticScannerType = reflect.TypeOf((*StaticScanner)(nil)).Elem()
@@ -0,0 +1,86 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+// staticUser implements the methods the `ksqlgen`
+// command would generate for it
+type staticUser struct {
+	ID   int     `ksql:"id"`
+	Name *string `ksql:"name"`
+	Age  int     `ksql:"age"`
+
+	ScanTargetCalls int
+}
+
+func (u *staticUser) KSQLScanTarget(column string) interface{} {
+	u.ScanTargetCalls++
+	switch column {
+	case "id":
+		return &u.ID
+	case "name":
+		return &u.Name
+	}
+	// Age is omitted for testing the fallback to reflection
+	return nil
+}
+
+func (u staticUser) KSQLValues() map[string]interface{} {
+	values := map[string]interface{}{
+		"id": u.ID,
+	}
+	if u.Name != nil {
+		values["name"] = *u.Name
+	}
+	return values
+}
+
+func TestStaticRecords(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should scan the rows using the StaticScanner", func(t *testing.T) {
+		name := "Ana"
+		rows := &fakeColumnRows{
+			columns: []string{"id", "name", "age", "unknown_column"},
+			row:     []interface{}{1, &name, 42, "ignored"},
+		}
+
+		info, err := structs.GetTagInfo(reflect.TypeOf(staticUser{}))
+		tt.AssertNoErr(t, err)
+
+		scanner, err := newRowScanner(ctx, supportedDialects["postgres"], rows, reflect.TypeOf(staticUser{}), info)
+		tt.AssertNoErr(t, err)
+
+		var u staticUser
+		err = scanner.scan(reflect.ValueOf(&u))
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, u.ID, 1)
+		tt.AssertEqual(t, *u.Name, "Ana")
+		tt.AssertEqual(t, u.Age, 42)
+		tt.AssertEqual(t, u.ScanTargetCalls, 3)
+	})
+
+	t.Run("should read the values of the records using the StaticValuer", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		// The Age attribute is omitted by KSQLValues:
+		name := "Bia"
+		err = dryRun.Insert(ctx, usersTable, &staticUser{Name: &name, Age: 42})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, dryRun.Statements(), []DryRunStatement{
+			{
+				Query:  `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`,
+				Params: []interface{}{"Bia"},
+			},
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The convention used by `DB.QueryAsOf()` for the history tables on Postgres
+const (
+	historyTableSuffix  = "_history"
+	historyPeriodColumn = "sys_period"
+)
+
+// QueryAsOf works like the Query method but reads the table of the FROM
+// clause as it was at the input point in time, e.g.:
+//
+//	var users []User
+//	err := db.QueryAsOf(ctx, &users, lastMonth, "FROM users WHERE age > ?", 18)
+//
+// On SQL Server the table must be a system-versioned temporal table, and
+// a `FOR SYSTEM_TIME AS OF` clause is added right after its name.
+//
+// Postgres has no built-in temporal tables, so the convention of the
+// temporal_tables extension is used instead: the past versions of the rows
+// of the table `users` are kept on the table `users_history`, which has the
+// same columns, and both tables have a `sys_period tstzrange` column with
+// the period in which each version was valid. The table is then replaced
+// by a `UNION ALL` of both tables filtered by this period, aliased with
+// the name of the table unless the query declares an alias.
+//
+// Only the first table of the FROM clause is read as of the input time,
+// so on queries with joins the other tables are read at their current
+// state. The other dialects are not supported.
+func (c DB) QueryAsOf(
+	ctx context.Context,
+	records interface{},
+	asOf time.Time,
+	query string,
+	params ...interface{},
+) error {
+	op := Operation{Method: "QueryAsOf", Query: query, Params: params}
+	return c.intercept(ctx, op, func(ctx context.Context, op Operation) error {
+		query, params, err := addAsOfClause(c.dialect, op.Query, op.Params, asOf)
+		if err != nil {
+			return err
+		}
+
+		return c.query(ctx, records, query, params...)
+	})
+}
+
+// addAsOfClause rewrites the first table of the top level FROM clause of
+// the query so that it is read as of the input time, adding the time to
+// the end of the params, which only works for numbered placeholders.
+func addAsOfClause(
+	dialect Dialect,
+	query string,
+	params []interface{},
+	asOf time.Time,
+) (string, []interface{}, error) {
+	driver := dialect.DriverName()
+	if driver != "postgres" && driver != "sqlserver" {
+		return "", nil, fmt.Errorf("ksql: QueryAsOf is not supported on driver `%s`", driver)
+	}
+
+	fromEnd := -1
+	scanTopLevelWords(query, func(word string, start int) bool {
+		if strings.ToUpper(word) == "FROM" {
+			fromEnd = start + len(word)
+			return true
+		}
+		return false
+	})
+	if fromEnd == -1 {
+		return "", nil, fmt.Errorf("ksql: QueryAsOf requires a query with a FROM clause, but got: '%s'", query)
+	}
+
+	tableStart, tableEnd, err := parseTableName(query, fromEnd)
+	if err != nil {
+		return "", nil, err
+	}
+	table := query[tableStart:tableEnd]
+
+	placeholder := dialect.Placeholder(len(params))
+	params = append(append([]interface{}{}, params...), asOf)
+
+	var versioned string
+	switch driver {
+	case "sqlserver":
+		versioned = table + " FOR SYSTEM_TIME AS OF " + placeholder
+	case "postgres":
+		condition := dialect.Escape(historyPeriodColumn) + " @> " + placeholder + "::timestamptz"
+		versioned = fmt.Sprintf(
+			"(SELECT * FROM %s WHERE %s UNION ALL SELECT * FROM %s WHERE %s)",
+			table, condition, historyTableName(table), condition,
+		)
+		if !hasTableAlias(query[tableEnd:]) {
+			versioned += " AS " + table[strings.LastIndex(table, ".")+1:]
+		}
+	}
+
+	return query[:tableStart] + versioned + query[tableEnd:], params, nil
+}
+
+// parseTableName returns the position of the table name starting after
+// the input position, which can be quoted and prefixed by a schema.
+func parseTableName(query string, pos int) (start int, end int, err error) {
+	start = pos
+	for start < len(query) && isSpace(query[start]) {
+		start++
+	}
+
+	end = start
+	for end < len(query) {
+		switch c := query[end]; {
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			i := strings.IndexByte(query[end+1:], closing)
+			if i == -1 {
+				return 0, 0, fmt.Errorf("ksql: unterminated quoted table name on query: '%s'", query)
+			}
+			end += i + 2
+		case isIdentifierChar(c) || c == '.':
+			end++
+		default:
+			if end == start {
+				return 0, 0, fmt.Errorf("ksql: expected a table name after the FROM clause on query: '%s'", query)
+			}
+			return start, end, nil
+		}
+	}
+
+	if end == start {
+		return 0, 0, fmt.Errorf("ksql: expected a table name after the FROM clause on query: '%s'", query)
+	}
+	return start, end, nil
+}
+
+// historyTableName adds the history suffix to the table name,
+// keeping it inside the quotes if the name is quoted.
+func historyTableName(table string) string {
+	last := table[len(table)-1]
+	if last == '"' || last == '`' || last == ']' {
+		return table[:len(table)-1] + historyTableSuffix + string(last)
+	}
+	return table + historyTableSuffix
+}
+
+// hasTableAlias checks if the rest of the query
+// after a table name starts with an alias.
+func hasTableAlias(rest string) bool {
+	rest = strings.TrimLeft(rest, " \t\r\n")
+
+	end := 0
+	for end < len(rest) && isIdentifierChar(rest[end]) {
+		end++
+	}
+	if end == 0 {
+		return rest != "" && rest[0] == '"'
+	}
+
+	switch strings.ToUpper(rest[:end]) {
+	case "WHERE", "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET", "FETCH", "FOR",
+		"UNION", "INTERSECT", "EXCEPT", "JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "NATURAL":
+		return false
+	}
+	return true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,87 @@
+package ksql
+
+import (
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAddAsOfClause(t *testing.T) {
+	asOf := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		desc           string
+		driver         string
+		query          string
+		params         []interface{}
+		expectedQuery  string
+		expectedParams []interface{}
+		expectedErr    []string
+	}{
+		{
+			desc:           "should add the FOR SYSTEM_TIME clause on sqlserver",
+			driver:         "sqlserver",
+			query:          "FROM users WHERE age > @p1",
+			params:         []interface{}{18},
+			expectedQuery:  "FROM users FOR SYSTEM_TIME AS OF @p2 WHERE age > @p1",
+			expectedParams: []interface{}{18, asOf},
+		},
+		{
+			desc:           "should add the FOR SYSTEM_TIME clause before the alias",
+			driver:         "sqlserver",
+			query:          "SELECT u.id FROM [dbo].[users] AS u JOIN posts p ON p.user_id = u.id",
+			expectedQuery:  "SELECT u.id FROM [dbo].[users] FOR SYSTEM_TIME AS OF @p1 AS u JOIN posts p ON p.user_id = u.id",
+			expectedParams: []interface{}{asOf},
+		},
+		{
+			desc:   "should union the history table on postgres",
+			driver: "postgres",
+			query:  "FROM users WHERE age > $1",
+			params: []interface{}{18},
+			expectedQuery: `FROM (SELECT * FROM users WHERE "sys_period" @> $2::timestamptz` +
+				` UNION ALL SELECT * FROM users_history WHERE "sys_period" @> $2::timestamptz) AS users WHERE age > $1`,
+			expectedParams: []interface{}{18, asOf},
+		},
+		{
+			desc:   "should keep the quotes and the alias of the table on postgres",
+			driver: "postgres",
+			query:  `SELECT u.name FROM public."users" u ORDER BY u.name`,
+			expectedQuery: `SELECT u.name FROM (SELECT * FROM public."users" WHERE "sys_period" @> $1::timestamptz` +
+				` UNION ALL SELECT * FROM public."users_history" WHERE "sys_period" @> $1::timestamptz) u ORDER BY u.name`,
+			expectedParams: []interface{}{asOf},
+		},
+		{
+			desc:        "should report unsupported drivers",
+			driver:      "sqlite3",
+			query:       "FROM users",
+			expectedErr: []string{"QueryAsOf", "sqlite3"},
+		},
+		{
+			desc:        "should report queries without a FROM clause",
+			driver:      "postgres",
+			query:       "SELECT 1",
+			expectedErr: []string{"FROM clause"},
+		},
+		{
+			desc:        "should report subqueries on the FROM clause",
+			driver:      "postgres",
+			query:       "FROM (SELECT * FROM users) u",
+			expectedErr: []string{"table name"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			query, params, err := addAsOfClause(supportedDialects[test.driver], test.query, test.params, asOf)
+			if test.expectedErr != nil {
+				tt.AssertErrContains(t, err, test.expectedErr...)
+				return
+			}
+
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+			tt.AssertEqual(t, params, test.expectedParams)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+type tenantKey struct{}
+
+type crossTenantKey struct{}
+
+// WithTenant returns a copy of the input context scoped to the input tenant,
+// which restricts all operations on structs with an attribute using the
+// `tenant` modifier, e.g. `ksql:"tenant_id,tenant"`, to the rows of this
+// tenant:
+//
+//	ctx = ksql.WithTenant(ctx, tenantID)
+//
+//	// Only the users of the tenant are returned:
+//	err := db.Query(ctx, &users, "FROM users WHERE age > ?", 42)
+//
+// The queries of the Query, QueryOne, QueryChunks, QueryIter, Count,
+// Exists, Sum, Min and Max methods get a `tenant_id = ?` condition for
+// each of these structs, including the ones of nested structs.
+//
+// The Insert, Upsert and BulkInsert methods save the tenant on the
+// records, and the Patch, UpdateReturning, PatchMap, UpdateMany, Delete,
+// DeleteReturning and DeleteMany methods only change the rows of the
+// tenant. The records are never moved between tenants, so if they
+// already have a different tenant an error is returned instead.
+//
+// Exec and QueryMaps don't use any struct, so their queries are never
+// changed, and Delete, DeleteWithResult and DeleteMany can only find the
+// tenant column if they receive a record or if the struct was registered
+// with `Table.WithStruct()`.
+//
+// Using a tenant-scoped struct with a context that has no tenant returns
+// ksql.ErrMissingTenant, so a missing tenant never exposes the rows of
+// the other tenants. For intentionally accessing all tenants, e.g. on
+// administrative tasks, use `ksql.CrossTenant(ctx)` instead.
+func WithTenant(ctx context.Context, tenantID interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// CrossTenant returns a copy of the input context that disables the tenant
+// filters added by `ksql.WithTenant()`, allowing the operations to read
+// and write the rows of all the tenants, e.g.:
+//
+//	err := db.Query(ksql.CrossTenant(ctx), &users, "FROM users")
+func CrossTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, crossTenantKey{}, true)
+}
+
+// TenantFromContext returns the tenant saved on the context by
+// `ksql.WithTenant()` and true, or nil and false if there is none.
+func TenantFromContext(ctx context.Context) (tenantID interface{}, found bool) {
+	tenantID = ctx.Value(tenantKey{})
+	return tenantID, tenantID != nil
+}
+
+func isCrossTenant(ctx context.Context) bool {
+	crossTenant, _ := ctx.Value(crossTenantKey{}).(bool)
+	return crossTenant
+}
+
+// getTenantID returns the tenant that should be used for scoping the
+// operations on a struct with the input tenant field, or false if they
+// are not scoped, i.e. if there is no such field or the context was
+// created with `ksql.CrossTenant()`.
+func getTenantID(ctx context.Context, tenantField *structs.FieldInfo) (tenantID interface{}, scoped bool, err error) {
+	if tenantField == nil || isCrossTenant(ctx) {
+		return nil, false, nil
+	}
+
+	tenantID, found := TenantFromContext(ctx)
+	if !found {
+		return nil, false, ErrMissingTenant
+	}
+
+	return tenantID, true, nil
+}
+
+// addTenantFilter adds a `tenant_id = ?` condition to the WHERE clause
+// of the query for each struct declaring the `tenant` modifier, i.e. the
+// destination struct itself or, for nested structs, each of the structs
+// representing a joined table, and it adds the tenant to the params.
+func addTenantFilter(
+	ctx context.Context,
+	dialect Dialect,
+	query string,
+	params []interface{},
+	structType reflect.Type,
+	info structs.StructInfo,
+) (string, []interface{}, error) {
+	if isCrossTenant(ctx) {
+		return query, params, nil
+	}
+
+	var columns []string
+	var tenantFields []*structs.FieldInfo
+	if !info.IsNestedStruct {
+		if info.TenantField != nil {
+			columns = append(columns, dialect.Escape(info.TenantField.Name))
+			tenantFields = append(tenantFields, info.TenantField)
+		}
+	} else {
+		tables, err := getNestedTables(structType, info)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, table := range tables {
+			if table.info.TenantField != nil {
+				columns = append(columns, dialect.Escape(table.alias)+"."+dialect.Escape(table.info.TenantField.Name))
+				tenantFields = append(tenantFields, table.info.TenantField)
+			}
+		}
+	}
+
+	if len(columns) == 0 {
+		return query, params, nil
+	}
+
+	tenantID, _, err := getTenantID(ctx, tenantFields[0])
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The placeholders are only known after the condition is added to
+	// the query, since on dialects with positional placeholders, e.g. `?`,
+	// the order of the params must follow their position on the query:
+	const marker = "\x00ksql_tenant\x00"
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " = " + marker
+	}
+
+	query, err = addWhereCondition(
+		query,
+		strings.Join(conditions, " AND "),
+		"tenant filter",
+		"use ksql.CrossTenant(ctx) and filter the rows of the tenant manually",
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	newParams := make([]interface{}, 0, len(params)+len(columns))
+	isPositional := dialect.Placeholder(0) == dialect.Placeholder(1)
+	if isPositional {
+		numParamsBefore := len(findPlaceholders(dialect, query[:strings.Index(query, marker)]))
+		if numParamsBefore > len(params) {
+			numParamsBefore = len(params)
+		}
+
+		newParams = append(newParams, params[:numParamsBefore]...)
+		for range columns {
+			newParams = append(newParams, tenantID)
+		}
+		newParams = append(newParams, params[numParamsBefore:]...)
+		query = strings.Replace(query, marker, dialect.Placeholder(0), -1)
+	} else {
+		newParams = append(newParams, params...)
+		for range columns {
+			query = strings.Replace(query, marker, dialect.Placeholder(len(newParams)), 1)
+			newParams = append(newParams, tenantID)
+		}
+	}
+
+	return query, newParams, nil
+}
+
+// getTenantField returns the field with the tenant modifier of the
+// struct registered for the table or, if there is none, of the record
+// received as argument, if no such field exists it returns nil.
+func getTenantField(naming *structs.Naming, table Table, idOrRecord interface{}) (*structs.FieldInfo, error) {
+	t := table.structType
+	if t == nil {
+		t = reflect.TypeOf(idOrRecord)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, nil
+		}
+	}
+
+	info, err := naming.GetTagInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return info.TenantField, nil
+}
+
+// scopeToTenant adds the tenant column to the ID columns of the table and
+// the tenant of the context to a copy of the input map, so that the writes
+// using them only affect the rows of the tenant.
+//
+// If the map already has a different tenant an error is returned, and if
+// the operation is not scoped to a tenant the inputs are returned unchanged.
+func scopeToTenant(
+	ctx context.Context,
+	table Table,
+	tenantField *structs.FieldInfo,
+	recordMap map[string]interface{},
+) (Table, map[string]interface{}, error) {
+	tenantID, scoped, err := getTenantID(ctx, tenantField)
+	if err != nil || !scoped {
+		return table, recordMap, err
+	}
+
+	column := tenantField.Name
+	if value, found := recordMap[column]; found && !isZeroTenant(value) && !isSameTenant(value, tenantID) {
+		return table, nil, fmt.Errorf(
+			"ksql: the record belongs to tenant `%v` but the context is scoped to tenant `%v`",
+			derefTenant(value), tenantID,
+		)
+	}
+
+	scopedMap := make(map[string]interface{}, len(recordMap)+1)
+	for k, v := range recordMap {
+		scopedMap[k] = v
+	}
+	scopedMap[column] = tenantID
+
+	if _, isID := findString(table.idColumns, column); !isID {
+		table.idColumns = append(append([]string{}, table.idColumns...), column)
+	}
+
+	return table, scopedMap, nil
+}
+
+// setTenantOnInsert saves the tenant of the context on the attribute with
+// the tenant modifier, or returns an error if the attribute already
+// contains a different tenant.
+func setTenantOnInsert(ctx context.Context, structValue reflect.Value, info structs.StructInfo) error {
+	tenantID, scoped, err := getTenantID(ctx, info.TenantField)
+	if err != nil || !scoped {
+		return err
+	}
+
+	attr := structValue.FieldByIndex(info.TenantField.Path)
+	if !isZeroTenant(attr.Interface()) {
+		if !isSameTenant(attr.Interface(), tenantID) {
+			return fmt.Errorf(
+				"ksql: the record belongs to tenant `%v` but the context is scoped to tenant `%v`",
+				derefTenant(attr.Interface()), tenantID,
+			)
+		}
+		return nil
+	}
+
+	attrType := attr.Type()
+	if attrType.Kind() == reflect.Ptr {
+		attrType = attrType.Elem()
+	}
+
+	value := reflect.ValueOf(tenantID)
+	switch {
+	case value.Type().AssignableTo(attrType):
+	case isNumericKind(value.Kind()) && isNumericKind(attrType.Kind()),
+		value.Kind() == reflect.String && attrType.Kind() == reflect.String:
+		value = value.Convert(attrType)
+	default:
+		return fmt.Errorf(
+			"ksql: the tenant of type %T can't be saved on the attribute '%s' of type %v",
+			tenantID, info.TenantField.Name, attr.Type(),
+		)
+	}
+
+	if attr.Kind() == reflect.Ptr {
+		ptr := reflect.New(attrType)
+		ptr.Elem().Set(value)
+		value = ptr
+	}
+	attr.Set(value)
+
+	return nil
+}
+
+// assertTenantInConflictColumns makes sure upserts on tenant-scoped tables
+// can't overwrite the rows of other tenants, which requires the tenant
+// column to be part of the columns used for detecting the conflicts.
+func assertTenantInConflictColumns(ctx context.Context, table Table, info structs.StructInfo) error {
+	_, scoped, err := getTenantID(ctx, info.TenantField)
+	if err != nil || !scoped {
+		return err
+	}
+
+	if _, found := findString(table.getConflictColumns(), info.TenantField.Name); !found {
+		return fmt.Errorf(
+			"ksql: can't upsert on tenant-scoped table `%s` because the tenant column `%s` is not one of its conflict columns",
+			table.name, info.TenantField.Name,
+		)
+	}
+
+	return nil
+}
+
+func isZeroTenant(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
+
+// isSameTenant compares the tenants by their textual representation so
+// that tenants of different types, e.g. int and int64, can be compared.
+func isSameTenant(value interface{}, tenantID interface{}) bool {
+	return fmt.Sprint(derefTenant(value)) == fmt.Sprint(tenantID)
+}
+
+func derefTenant(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		return v.Elem().Interface()
+	}
+	return value
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package ksql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestAddTenantFilter(t *testing.T) {
+	type tenantUser struct {
+		ID       int    `ksql:"id"`
+		TenantID int    `ksql:"tenant_id,tenant"`
+		Name     string `ksql:"name"`
+	}
+
+	ctx := WithTenant(context.Background(), 42)
+
+	tests := []struct {
+		desc           string
+		driver         string
+		query          string
+		params         []interface{}
+		expectedQuery  string
+		expectedParams []interface{}
+	}{
+		{
+			desc:           "should add the tenant as the last param on numbered dialects",
+			driver:         "postgres",
+			query:          `SELECT * FROM users WHERE name = $1 ORDER BY id LIMIT $2`,
+			params:         []interface{}{"Ana", 10},
+			expectedQuery:  `SELECT * FROM users WHERE "tenant_id" = $3 AND (name = $1) ORDER BY id LIMIT $2`,
+			expectedParams: []interface{}{"Ana", 10, 42},
+		},
+		{
+			desc:           "should insert the tenant in the position of its placeholder on positional dialects",
+			driver:         "sqlite3",
+			query:          `SELECT * FROM users WHERE id IN (SELECT user_id FROM posts WHERE title = ?) AND name = ? LIMIT ?`,
+			params:         []interface{}{"foo", "Ana", 10},
+			expectedQuery:  "SELECT * FROM users WHERE `tenant_id` = ? AND (id IN (SELECT user_id FROM posts WHERE title = ?) AND name = ?) LIMIT ?",
+			expectedParams: []interface{}{42, "foo", "Ana", 10},
+		},
+		{
+			desc:           "should insert the tenant after the params of the FROM clause on positional dialects",
+			driver:         "mysql",
+			query:          `SELECT * FROM (SELECT * FROM users WHERE age > ?) u LIMIT ?`,
+			params:         []interface{}{18, 10},
+			expectedQuery:  "SELECT * FROM (SELECT * FROM users WHERE age > ?) u WHERE `tenant_id` = ? LIMIT ?",
+			expectedParams: []interface{}{18, 42, 10},
+		},
+	}
+
+	info, err := structs.GetTagInfo(reflect.TypeOf(tenantUser{}))
+	tt.AssertNoErr(t, err)
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			query, params, err := addTenantFilter(ctx, supportedDialects[test.driver], test.query, test.params, reflect.TypeOf(tenantUser{}), info)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, query, test.expectedQuery)
+			tt.AssertEqual(t, params, test.expectedParams)
+		})
+	}
+
+	t.Run("should not change the query on cross tenant contexts", func(t *testing.T) {
+		query, params, err := addTenantFilter(CrossTenant(ctx), supportedDialects["postgres"], `SELECT * FROM users`, nil, reflect.TypeOf(tenantUser{}), info)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, `SELECT * FROM users`)
+		tt.AssertEqual(t, len(params), 0)
+	})
+
+	t.Run("should report missing tenants", func(t *testing.T) {
+		_, _, err := addTenantFilter(context.Background(), supportedDialects["postgres"], `SELECT * FROM users`, nil, reflect.TypeOf(tenantUser{}), info)
+		tt.AssertEqual(t, err, ErrMissingTenant)
+	})
+}
+
+func TestSetTenantOnInsert(t *testing.T) {
+	type tenantUser struct {
+		ID       int    `ksql:"id"`
+		TenantID *int64 `ksql:"tenant_id,tenant"`
+		Name     string `ksql:"name"`
+	}
+
+	info, err := structs.GetTagInfo(reflect.TypeOf(tenantUser{}))
+	tt.AssertNoErr(t, err)
+
+	t.Run("should convert the tenant to the type of the attribute", func(t *testing.T) {
+		var u tenantUser
+		err := setTenantOnInsert(WithTenant(context.Background(), 42), reflect.ValueOf(&u).Elem(), info)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, *u.TenantID, int64(42))
+	})
+
+	t.Run("should reject records of other tenants", func(t *testing.T) {
+		otherTenant := int64(7)
+		u := tenantUser{TenantID: &otherTenant}
+		err := setTenantOnInsert(WithTenant(context.Background(), 42), reflect.ValueOf(&u).Elem(), info)
+		tt.AssertErrContains(t, err, "tenant `7`", "tenant `42`")
+	})
+
+	t.Run("should reject tenants of incompatible types", func(t *testing.T) {
+		var u tenantUser
+		err := setTenantOnInsert(WithTenant(context.Background(), "acme"), reflect.ValueOf(&u).Elem(), info)
+		tt.AssertErrContains(t, err, "string", "tenant_id")
+	})
+}
@@ -0,0 +1,176 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// tenantSessionAdapter wraps a DBAdapter setting the Postgres setting
+// configured on `ksql.Config.TenantSessionVariable` to the tenant of the
+// context before each statement, so it can be used by Row Level Security
+// policies, e.g.:
+//
+//	CREATE POLICY tenant_isolation ON users
+//		USING (tenant_id = current_setting('app.tenant_id')::bigint);
+//
+// Since the connections are shared by the pool the setting is always set
+// with `set_config(name, value, true)`, which is the same as `SET LOCAL`
+// but accepts params, so it only lasts until the end of the transaction.
+// For this reason the statements running outside transactions run on a
+// transaction of their own, and the transactions started with a tenant on
+// the context set it once right after they start.
+type tenantSessionAdapter struct {
+	DBAdapter
+
+	variable string
+}
+
+func newTenantSessionAdapter(db DBAdapter, variable string) tenantSessionAdapter {
+	return tenantSessionAdapter{
+		DBAdapter: db,
+		variable:  variable,
+	}
+}
+
+// ExecContext implements the DBAdapter interface
+func (a tenantSessionAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	if _, found := TenantFromContext(ctx); !found {
+		return a.DBAdapter.ExecContext(ctx, query, args...)
+	}
+
+	tx, err := a.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return result, tx.Commit(ctx)
+}
+
+// QueryContext implements the DBAdapter interface
+func (a tenantSessionAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	if _, found := TenantFromContext(ctx); !found {
+		return a.DBAdapter.QueryContext(ctx, query, args...)
+	}
+
+	tx, err := a.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+
+	// The transaction can only finish after the rows are read:
+	return &tenantSessionRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+// BeginTx implements the TxBeginner interface
+func (a tenantSessionAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	txBeginner, ok := a.DBAdapter.(TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, a.setTenant(ctx, tx)
+}
+
+// BeginTxWithOptions implements the TxOptionsBeginner interface
+func (a tenantSessionAdapter) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	txBeginner, ok := a.DBAdapter.(TxOptionsBeginner)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	tx, err := txBeginner.BeginTxWithOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tx, a.setTenant(ctx, tx)
+}
+
+// setTenant sets the session variable to the tenant of the context
+// for the rest of the transaction, or rolls it back on errors.
+func (a tenantSessionAdapter) setTenant(ctx context.Context, tx Tx) error {
+	tenantID, found := TenantFromContext(ctx)
+	if !found {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", a.variable, fmt.Sprint(tenantID))
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("ksql: unable to set the session variable `%s` to the tenant of the context: %s", a.variable, err)
+	}
+
+	return nil
+}
+
+// RetryTx implements the TxRetrier interface by
+// delegating to the wrapped adapter if it implements it
+func (a tenantSessionAdapter) RetryTx(ctx context.Context, attempt int, err error) bool {
+	retrier, ok := a.DBAdapter.(TxRetrier)
+	if !ok {
+		return false
+	}
+
+	return retrier.RetryTx(ctx, attempt, err)
+}
+
+// Close implements the io.Closer interface
+func (a tenantSessionAdapter) Close() error {
+	closer, ok := a.DBAdapter.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+// unwrapAdapter is used for reaching the pool stats of the adapter,
+// the BulkCopier is not used though, see `getBulkCopier()`.
+func (a tenantSessionAdapter) unwrapAdapter() DBAdapter {
+	return a.DBAdapter
+}
+
+// tenantSessionRows commits the transaction started for
+// running a query once its rows are closed
+type tenantSessionRows struct {
+	Rows
+
+	ctx    context.Context
+	tx     Tx
+	closed bool
+}
+
+// Close implements the Rows interface
+func (r *tenantSessionRows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.Rows.Close()
+	if err == nil {
+		err = r.Rows.Err()
+	}
+	if err != nil {
+		_ = r.tx.Rollback(r.ctx)
+		return err
+	}
+
+	return r.tx.Commit(r.ctx)
+}
@@ -0,0 +1,127 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestTenantSessionVariable(t *testing.T) {
+	newDB := func(t *testing.T, statements *[]string) DB {
+		record := func(statement string) {
+			*statements = append(*statements, statement)
+		}
+
+		db, err := NewWithConfig(mockTxBeginner{
+			DBAdapter: mockDBAdapter{
+				ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+					record(query)
+					return NewMockResult(0, 1), nil
+				},
+			},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				record("BEGIN")
+				return mockTx{
+					DBAdapter: mockDBAdapter{
+						ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+							record(query)
+							if len(args) == 2 {
+								record(args[0].(string) + "=" + args[1].(string))
+							}
+							return NewMockResult(0, 1), nil
+						},
+						QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+							record(query)
+							return &fakeScalarRows{values: []interface{}{42}}, nil
+						},
+					},
+					CommitFn: func(ctx context.Context) error {
+						record("COMMIT")
+						return nil
+					},
+					RollbackFn: func(ctx context.Context) error {
+						record("ROLLBACK")
+						return nil
+					},
+				}, nil
+			},
+		}, "postgres", Config{
+			TenantSessionVariable: "app.tenant_id",
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should run the statements on a transaction setting the tenant", func(t *testing.T) {
+		var statements []string
+		db := newDB(t, &statements)
+
+		_, err := db.Exec(WithTenant(context.Background(), 7), "DELETE FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, statements, []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true)",
+			"app.tenant_id=7",
+			"DELETE FROM users",
+			"COMMIT",
+		})
+	})
+
+	t.Run("should only commit the queries after closing the rows", func(t *testing.T) {
+		var statements []string
+		db := newDB(t, &statements)
+
+		var count int
+		err := db.QueryOne(WithTenant(context.Background(), "acme"), &count, "SELECT count(*) FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, count, 42)
+		tt.AssertEqual(t, statements, []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true)",
+			"app.tenant_id=acme",
+			"SELECT count(*) FROM users",
+			"COMMIT",
+		})
+	})
+
+	t.Run("should set the tenant once per transaction", func(t *testing.T) {
+		var statements []string
+		db := newDB(t, &statements)
+
+		ctx := WithTenant(context.Background(), 7)
+		err := db.Transaction(ctx, func(db Provider) error {
+			_, err := db.Exec(ctx, "DELETE FROM users")
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "DELETE FROM posts")
+			return err
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, statements, []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true)",
+			"app.tenant_id=7",
+			"DELETE FROM users",
+			"DELETE FROM posts",
+			"COMMIT",
+		})
+	})
+
+	t.Run("should not change the statements without a tenant", func(t *testing.T) {
+		var statements []string
+		db := newDB(t, &statements)
+
+		_, err := db.Exec(context.Background(), "DELETE FROM users")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, statements, []string{"DELETE FROM users"})
+	})
+
+	t.Run("should report unsupported drivers", func(t *testing.T) {
+		_, err := NewWithConfig(mockDBAdapter{}, "mysql", Config{
+			TenantSessionVariable: "app.tenant_id",
+		})
+		tt.AssertErrContains(t, err, "TenantSessionVariable", "mysql")
+	})
+}
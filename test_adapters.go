@@ -1,15 +1,27 @@
 package ksql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/kdecimal"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
 	"github.com/vingarcia/ksql/nullable"
 )
 
@@ -43,6 +55,36 @@ type post struct {
 
 var userPermissionsTable = NewTable("user_permissions", "user_id", "perm_id")
 
+type document struct {
+	ID        uint       `ksql:"id"`
+	UserID    uint       `ksql:"user_id"`
+	Title     string     `ksql:"title"`
+	Version   int        `ksql:"version,optimisticLock"`
+	CreatedAt time.Time  `ksql:"created_at,timeNowUTCSkipOnUpdate"`
+	UpdatedAt *time.Time `ksql:"updated_at,timeNowUTC"`
+	DeletedAt *time.Time `ksql:"deleted_at,softDelete"`
+}
+
+var documentsTable = NewTable("documents")
+
+// upperCaseScanner is a sql.Scanner that saves
+// text values converted to upper case
+type upperCaseScanner struct {
+	value string
+}
+
+func (u *upperCaseScanner) Scan(dbValue interface{}) error {
+	switch v := dbValue.(type) {
+	case string:
+		u.value = strings.ToUpper(v)
+	case []byte:
+		u.value = strings.ToUpper(string(v))
+	default:
+		return fmt.Errorf("unexpected type %T for upperCaseScanner", dbValue)
+	}
+	return nil
+}
+
 type userPermission struct {
 	ID     int    `ksql:"id"`
 	UserID int    `ksql:"user_id"`
@@ -66,11 +108,41 @@ func RunTestsForAdapter(
 	t.Run(adapterName+"."+driver, func(t *testing.T) {
 		QueryTest(t, driver, connStr, newDBAdapter)
 		QueryOneTest(t, driver, connStr, newDBAdapter)
+		QueryMapsTest(t, driver, connStr, newDBAdapter)
+		ReplicasTest(t, driver, connStr, newDBAdapter)
+		HealthCheckTest(t, driver, connStr, newDBAdapter)
 		InsertTest(t, driver, connStr, newDBAdapter)
+		UpsertTest(t, driver, connStr, newDBAdapter)
 		DeleteTest(t, driver, connStr, newDBAdapter)
 		PatchTest(t, driver, connStr, newDBAdapter)
+		PatchMapTest(t, driver, connStr, newDBAdapter)
+		UpdateReturningTest(t, driver, connStr, newDBAdapter)
+		DeleteReturningTest(t, driver, connStr, newDBAdapter)
 		QueryChunksTest(t, driver, connStr, newDBAdapter)
+		QueryIterTest(t, driver, connStr, newDBAdapter)
+		SoftDeleteTest(t, driver, connStr, newDBAdapter)
+		OptimisticLockTest(t, driver, connStr, newDBAdapter)
+		TimeNowUTCTest(t, driver, connStr, newDBAdapter)
+		FlattenTest(t, driver, connStr, newDBAdapter)
+		ModifiersTest(t, driver, connStr, newDBAdapter)
 		TransactionTest(t, driver, connStr, newDBAdapter)
+		RollbackTxTest(t, driver, connStr, newDBAdapter)
+		LoadFixturesTest(t, driver, connStr, newDBAdapter)
+		EnsureTableTest(t, driver, connStr, newDBAdapter)
+		DescribeTableTest(t, driver, connStr, newDBAdapter)
+		ValidateSchemaTest(t, driver, connStr, newDBAdapter)
+		TenancyTest(t, driver, connStr, newDBAdapter)
+		AuditTest(t, driver, connStr, newDBAdapter)
+		LoaderTest(t, driver, connStr, newDBAdapter)
+		LoadRelatedTest(t, driver, connStr, newDBAdapter)
+		MapUntaggedFieldsTest(t, driver, connStr, newDBAdapter)
+		PointerAndInterfaceFieldsTest(t, driver, connStr, newDBAdapter)
+		FieldMasksTest(t, driver, connStr, newDBAdapter)
+		BlobsTest(t, driver, connStr, newDBAdapter)
+		DecimalsTest(t, driver, connStr, newDBAdapter)
+		TimeLocationTest(t, driver, connStr, newDBAdapter)
+		EnumsTest(t, driver, connStr, newDBAdapter)
+		VectorsTest(t, driver, connStr, newDBAdapter)
 		ScanRowsTest(t, driver, connStr, newDBAdapter)
 	})
 }
@@ -168,6 +240,31 @@ func QueryTest(
 						tt.AssertEqual(t, users[1].Address.Country, "BR")
 					})
 
+					t.Run("should expand slices used inside IN clauses", func(t *testing.T) {
+						db, closer := newDBAdapter(t)
+						defer closer.Close()
+
+						_, err := db.ExecContext(context.TODO(), `INSERT INTO users (name, age, address) VALUES ('Ana In', 0, '{"country":"US"}')`)
+						tt.AssertNoErr(t, err)
+						_, err = db.ExecContext(context.TODO(), `INSERT INTO users (name, age, address) VALUES ('Bia In', 0, '{"country":"BR"}')`)
+						tt.AssertNoErr(t, err)
+						_, err = db.ExecContext(context.TODO(), `INSERT INTO users (name, age, address) VALUES ('Caio In', 0, '{"country":"BR"}')`)
+						tt.AssertNoErr(t, err)
+
+						ctx := context.Background()
+						c := newTestDB(db, driver)
+						var users []user
+						err = c.Query(ctx, &users,
+							variation.queryPrefix+`FROM users WHERE name IN (`+c.dialect.Placeholder(0)+`) AND age = `+c.dialect.Placeholder(1)+` ORDER BY id`,
+							[]string{"Ana In", "Caio In"}, 0,
+						)
+
+						tt.AssertNoErr(t, err)
+						tt.AssertEqual(t, len(users), 2)
+						tt.AssertEqual(t, users[0].Name, "Ana In")
+						tt.AssertEqual(t, users[1].Name, "Caio In")
+					})
+
 					t.Run("should query joined tables correctly", func(t *testing.T) {
 						db, closer := newDBAdapter(t)
 						defer closer.Close()
@@ -358,6 +455,180 @@ func QueryTest(
 			})
 		}
 
+		t.Run("using multi-level nested structs", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			joao := user{Name: "João Ribeiro", Age: 30}
+			err = c.Insert(ctx, usersTable, &joao)
+			tt.AssertNoErr(t, err)
+
+			bia := user{Name: "Bia Ribeiro", Age: 30}
+			err = c.Insert(ctx, usersTable, &bia)
+			tt.AssertNoErr(t, err)
+
+			joaoPost := post{UserID: joao.ID, Title: "João Post"}
+			err = c.Insert(ctx, NewTable("posts"), &joaoPost)
+			tt.AssertNoErr(t, err)
+
+			joaoDoc := document{UserID: joao.ID, Title: "João Doc"}
+			err = c.Insert(ctx, documentsTable, &joaoDoc)
+			tt.AssertNoErr(t, err)
+
+			deletedDoc := document{UserID: joao.ID, Title: "Deleted Doc"}
+			err = c.Insert(ctx, documentsTable, &deletedDoc)
+			tt.AssertNoErr(t, err)
+			err = c.Delete(ctx, documentsTable, &deletedDoc)
+			tt.AssertNoErr(t, err)
+
+			t.Run("should scan tables nested inside other tables", func(t *testing.T) {
+				type authorWithDocument struct {
+					ID       uint     `ksql:"id"`
+					Name     string   `ksql:"name"`
+					Document document `tablename:"doc"`
+				}
+
+				var rows []struct {
+					Post   post               `tablename:"p"`
+					Author authorWithDocument `tablename:"author"`
+				}
+				err := c.Query(ctx, &rows, fmt.Sprint(
+					`FROM posts p`,
+					` JOIN users author ON author.id = p.user_id`,
+					` JOIN documents author_doc ON author_doc.user_id = author.id`,
+					` WHERE p.id = `, c.dialect.Placeholder(0),
+				), joaoPost.ID)
+				tt.AssertNoErr(t, err)
+
+				// The soft deleted document should be ignored:
+				tt.AssertEqual(t, len(rows), 1)
+				tt.AssertEqual(t, rows[0].Post.Title, "João Post")
+				tt.AssertEqual(t, rows[0].Author.ID, joao.ID)
+				tt.AssertEqual(t, rows[0].Author.Name, "João Ribeiro")
+				tt.AssertEqual(t, rows[0].Author.Document.ID, joaoDoc.ID)
+				tt.AssertEqual(t, rows[0].Author.Document.Title, "João Doc")
+			})
+
+			t.Run("should use the separator configured on the tablename tag", func(t *testing.T) {
+				var row struct {
+					Post   post `tablename:"p"`
+					Author struct {
+						User     user     `tablename:"user"`
+						Document document `tablename:"doc"`
+					} `tablename:"author,separator=__"`
+				}
+				err := c.QueryOne(ctx, &row, fmt.Sprint(
+					`FROM posts p`,
+					` JOIN users author__user ON author__user.id = p.user_id`,
+					` JOIN documents author__doc ON author__doc.user_id = author__user.id`,
+					` WHERE p.id = `, c.dialect.Placeholder(0),
+				), joaoPost.ID)
+				tt.AssertNoErr(t, err)
+
+				tt.AssertEqual(t, row.Post.Title, "João Post")
+				tt.AssertEqual(t, row.Author.User.ID, joao.ID)
+				tt.AssertEqual(t, row.Author.User.Name, "João Ribeiro")
+				tt.AssertEqual(t, row.Author.Document.Title, "João Doc")
+			})
+
+			t.Run("should scan the same table under different aliases", func(t *testing.T) {
+				var rows []struct {
+					User   user `tablename:"u"`
+					Friend user `tablename:"friend"`
+				}
+				err := c.Query(ctx, &rows, fmt.Sprint(
+					`FROM users u JOIN users friend ON friend.age = u.age AND friend.id <> u.id`,
+					` WHERE u.id = `, c.dialect.Placeholder(0),
+				), joao.ID)
+				tt.AssertNoErr(t, err)
+
+				tt.AssertEqual(t, len(rows), 1)
+				tt.AssertEqual(t, rows[0].User.ID, joao.ID)
+				tt.AssertEqual(t, rows[0].User.Name, "João Ribeiro")
+				tt.AssertEqual(t, rows[0].Friend.ID, bia.ID)
+				tt.AssertEqual(t, rows[0].Friend.Name, "Bia Ribeiro")
+			})
+
+			t.Run("should report error for unknown options on the tablename tag", func(t *testing.T) {
+				var rows []struct {
+					User user `tablename:"u,fakeOption"`
+					Post post `tablename:"p"`
+				}
+				err := c.Query(ctx, &rows, `FROM users u JOIN posts p ON p.user_id = u.id`)
+				tt.AssertErrContains(t, err, "tablename", "unknown option", "fakeOption")
+			})
+		})
+
+		t.Run("using slices of scalar values", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var ids []uint
+			for _, name := range []string{"Scalar User 1", "Scalar User 2", "Scalar User 3"} {
+				u := user{Name: name, Age: 42}
+				err := c.Insert(ctx, usersTable, &u)
+				tt.AssertNoErr(t, err)
+				ids = append(ids, u.ID)
+			}
+
+			t.Run("should scan the only column of each row", func(t *testing.T) {
+				var names []string
+				err := c.Query(ctx, &names, `SELECT name FROM users WHERE name LIKE `+c.dialect.Placeholder(0)+` ORDER BY id`, "Scalar User%")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, names, []string{"Scalar User 1", "Scalar User 2", "Scalar User 3"})
+
+				var resultIDs []uint
+				err = c.Query(ctx, &resultIDs, `SELECT id FROM users WHERE name LIKE `+c.dialect.Placeholder(0)+` ORDER BY id`, "Scalar User%")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, resultIDs, ids)
+
+				var agePtrs []*int
+				err = c.Query(ctx, &agePtrs, `SELECT age FROM users WHERE name LIKE `+c.dialect.Placeholder(0)+` ORDER BY id`, "Scalar User%")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, len(agePtrs), 3)
+				tt.AssertEqual(t, *agePtrs[0], 42)
+			})
+
+			t.Run("should replace the previous contents of the slice", func(t *testing.T) {
+				names := []string{"fake-name-1", "fake-name-2", "fake-name-3", "fake-name-4"}
+				err := c.Query(ctx, &names, `SELECT name FROM users WHERE name = `+c.dialect.Placeholder(0), "Scalar User 2")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, names, []string{"Scalar User 2"})
+
+				err = c.Query(ctx, &names, `SELECT name FROM users WHERE id = `+c.dialect.Placeholder(0), 4200)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, len(names), 0)
+			})
+
+			t.Run("should report error if the query returns more than one column", func(t *testing.T) {
+				var names []string
+				err := c.Query(ctx, &names, `SELECT name, age FROM users WHERE name LIKE 'Scalar User%'`)
+				tt.AssertErrContains(t, err, "ksql", "single column", "2 columns")
+			})
+
+			t.Run("should report error if the query starts with FROM", func(t *testing.T) {
+				var names []string
+				err := c.Query(ctx, &names, `FROM users`)
+				tt.AssertErrContains(t, err, "ksql", "SELECT", "[]string")
+			})
+		})
+
 		t.Run("testing error cases", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
@@ -387,8 +658,8 @@ func QueryTest(
 				err = c.Query(ctx, &i, `SELECT * FROM users WHERE name like `+c.dialect.Placeholder(0), "% Sá")
 				tt.AssertErrContains(t, err, "expected", "to be a slice", "int")
 
-				err = c.Query(ctx, &[]int{}, `SELECT * FROM users WHERE name like `+c.dialect.Placeholder(0), "% Sá")
-				tt.AssertErrContains(t, err, "expected", "slice of structs", "[]int")
+				err = c.Query(ctx, &[]map[string]interface{}{}, `SELECT * FROM users WHERE name like `+c.dialect.Placeholder(0), "% Sá")
+				tt.AssertErrContains(t, err, "expected", "slice of structs", "[]map[string]interface {}")
 			})
 
 			t.Run("should report error if the query is not valid", func(t *testing.T) {
@@ -435,14 +706,34 @@ func QueryTest(
 					tt.AssertErrContains(t, err, "foo", "int")
 				})
 
-				t.Run("*struct", func(t *testing.T) {
+				t.Run("**struct", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+					var rows []struct {
+						Foo **user `tablename:"foo"`
+					}
+					err := c.Query(ctx, &rows, fmt.Sprint(
+						`FROM users u JOIN posts p ON p.user_id = u.id`,
+						` WHERE u.name like `, c.dialect.Placeholder(0),
+						` ORDER BY u.id, p.id`,
+					), "% Ribeiro")
+
+					tt.AssertErrContains(t, err, "foo", "**ksql.user")
+				})
+
+				t.Run("pointer to a struct used for grouping tables", func(t *testing.T) {
 					db, closer := newDBAdapter(t)
 					defer closer.Close()
 
 					ctx := context.Background()
 					c := newTestDB(db, driver)
 					var rows []struct {
-						Foo *user `tablename:"foo"`
+						Foo *struct {
+							User user `tablename:"u"`
+						} `tablename:"foo"`
 					}
 					err := c.Query(ctx, &rows, fmt.Sprint(
 						`FROM users u JOIN posts p ON p.user_id = u.id`,
@@ -450,7 +741,7 @@ func QueryTest(
 						` ORDER BY u.id, p.id`,
 					), "% Ribeiro")
 
-					tt.AssertErrContains(t, err, "foo", "*ksql.user")
+					tt.AssertErrContains(t, err, "foo", "grouping", "pointer")
 				})
 			})
 
@@ -615,6 +906,137 @@ func QueryOneTest(
 			})
 		}
 
+		t.Run("using pointers to scalar values", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			t.Run("should scan the only column of the result", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				for _, name := range []string{"Scalar Count 1", "Scalar Count 2"} {
+					err := c.Insert(ctx, usersTable, &user{Name: name, Age: 42})
+					tt.AssertNoErr(t, err)
+				}
+
+				var count int
+				err := c.QueryOne(ctx, &count, `SELECT count(*) FROM users WHERE name LIKE `+c.dialect.Placeholder(0), "Scalar Count%")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, count, 2)
+
+				var name string
+				err = c.QueryOne(ctx, &name, `SELECT name FROM users WHERE name = `+c.dialect.Placeholder(0), "Scalar Count 1")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, name, "Scalar Count 1")
+			})
+
+			t.Run("should scan NULL values into pointers", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				_, err := db.ExecContext(ctx, `INSERT INTO users (name) VALUES ('Scalar With Null Age')`)
+				tt.AssertNoErr(t, err)
+
+				age := new(int)
+				err = c.QueryOne(ctx, &age, `SELECT age FROM users WHERE name = 'Scalar With Null Age'`)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, age, (*int)(nil))
+			})
+
+			t.Run("should scan into types implementing sql.Scanner", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				var name sql.NullString
+				err := c.QueryOne(ctx, &name, `SELECT name FROM users WHERE name = 'Scalar Count 1'`)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, name, sql.NullString{String: "Scalar Count 1", Valid: true})
+
+				var age sql.NullInt64
+				err = c.QueryOne(ctx, &age, `SELECT age FROM users WHERE name = 'Scalar With Null Age'`)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, age, sql.NullInt64{})
+
+				var upperName upperCaseScanner
+				err = c.QueryOne(ctx, &upperName, `SELECT name FROM users WHERE name = 'Scalar Count 1'`)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, upperName.value, "SCALAR COUNT 1")
+
+				var names []sql.NullString
+				err = c.Query(ctx, &names, `SELECT name FROM users WHERE name LIKE 'Scalar Count%' ORDER BY id`)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, names, []sql.NullString{
+					{String: "Scalar Count 1", Valid: true},
+					{String: "Scalar Count 2", Valid: true},
+				})
+			})
+
+			t.Run("should return ErrRecordNotFound if there are no results", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				var name string
+				err := c.QueryOne(ctx, &name, `SELECT name FROM users WHERE id = `+c.dialect.Placeholder(0), 4200)
+				tt.AssertEqual(t, err, ErrRecordNotFound)
+			})
+
+			t.Run("should include the query on the error when requested", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				var name string
+				err := c.QueryOne(WithNotFoundDetails(ctx), &name, `SELECT name FROM users WHERE id = `+c.dialect.Placeholder(0), 4200)
+				tt.AssertEqual(t, IsNotFound(err), true)
+				tt.AssertErrContains(t, err, "SELECT name FROM users")
+
+				found, err := QueryOneOrZero(ctx, c, &name, `SELECT name FROM users WHERE id = `+c.dialect.Placeholder(0), 4200)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, found, false)
+				tt.AssertEqual(t, name, "")
+			})
+
+			t.Run("should report error if the query returns more than one column", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				var name string
+				err := c.QueryOne(ctx, &name, `SELECT name, age FROM users WHERE name = 'Scalar Count 1'`)
+				tt.AssertErrContains(t, err, "ksql", "single column", "2 columns")
+			})
+
+			t.Run("should report error if the query starts with FROM", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				var name string
+				err := c.QueryOne(ctx, &name, `FROM users`)
+				tt.AssertErrContains(t, err, "ksql", "SELECT", "*string")
+			})
+		})
+
 		t.Run("should report error if input is not a pointer to struct", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
@@ -859,7 +1281,7 @@ func InsertTest(
 						tt.AssertEqual(t, len(userPerms), 1)
 						tt.AssertEqual(t, userPerms[0].UserID, 2)
 						tt.AssertEqual(t, userPerms[0].PermID, 42)
-					case insertWithReturning, insertWithOutput:
+					case insertWithReturning, insertWithOutput, insertWithReturningInto:
 						tt.AssertNotEqual(t, permission.ID, 0)
 						tt.AssertEqual(t, len(userPerms), 1)
 						tt.AssertEqual(t, userPerms[0].ID, permission.ID)
@@ -907,6 +1329,22 @@ func InsertTest(
 				tt.AssertNotEqual(t, err, nil)
 			})
 
+			t.Run("should report ErrUniqueViolation when inserting duplicated keys", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				table := NewTable("user_permissions")
+				err := c.Insert(ctx, table, &userPermission{UserID: 1, PermID: 42})
+				tt.AssertNoErr(t, err)
+
+				err = c.Insert(ctx, table, &userPermission{UserID: 1, PermID: 42})
+				var uniqueErr ErrUniqueViolation
+				tt.AssertEqual(t, errors.As(err, &uniqueErr), true)
+			})
+
 			t.Run("should report error if for some reason the insertMethod is invalid", func(t *testing.T) {
 				db, closer := newDBAdapter(t)
 				defer closer.Close()
@@ -1010,64 +1448,231 @@ func InsertTest(
 	})
 }
 
-type brokenDialect struct{}
-
-func (brokenDialect) InsertMethod() insertMethod {
-	return insertMethod(42)
-}
-
-func (brokenDialect) Escape(str string) string {
-	return str
-}
-
-func (brokenDialect) Placeholder(idx int) string {
-	return "?"
-}
-
-func (brokenDialect) DriverName() string {
-	return "fake-driver-name"
-}
-
-// DeleteTest runs all tests for making sure the Delete function is
+// UpsertTest runs all tests for making sure the Upsert function is
 // working for a given adapter and driver.
-func DeleteTest(
+func UpsertTest(
 	t *testing.T,
 	driver string,
 	connStr string,
 	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
 ) {
-	t.Run("Delete", func(t *testing.T) {
+	t.Run("Upsert", func(t *testing.T) {
 		err := createTables(driver, connStr)
 		if err != nil {
 			t.Fatal("could not create test table!, reason:", err.Error())
 		}
 
-		t.Run("should delete from tables with a single primary key correctly", func(t *testing.T) {
-			tests := []struct {
-				desc               string
-				deletionKeyForUser func(u user) interface{}
-			}{
-				{
-					desc: "passing only the ID as key",
-					deletionKeyForUser: func(u user) interface{} {
-						return u.ID
-					},
-				},
-				{
-					desc: "passing only the entire user",
-					deletionKeyForUser: func(u user) interface{} {
-						return u
-					},
-				},
-				{
-					desc: "passing the address of the user",
-					deletionKeyForUser: func(u user) interface{} {
-						return &u
-					},
-				},
-			}
+		t.Run("should insert a new record correctly", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-			for _, test := range tests {
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{
+				Name: "Upserted User",
+				Age:  22,
+				Address: address{
+					Country: "Brazil",
+				},
+			}
+			err := c.Upsert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			var result user
+			err = getUserByName(db, driver, &result, "Upserted User")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, result.ID, uint(0))
+			tt.AssertEqual(t, result.Age, 22)
+			tt.AssertEqual(t, result.Address.Country, "Brazil")
+
+			switch c.dialect.InsertMethod() {
+			case insertWithReturning, insertWithOutput, insertWithReturningInto:
+				tt.AssertEqual(t, u.ID, result.ID)
+			}
+		})
+
+		t.Run("should update an existing record correctly", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{
+				Name: "User Before Upsert",
+				Age:  22,
+			}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
+
+			err = c.Upsert(ctx, usersTable, &user{
+				ID:   u.ID,
+				Name: "User After Upsert",
+				Age:  23,
+			})
+			tt.AssertNoErr(t, err)
+
+			var result user
+			err = getUserByID(db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "User After Upsert")
+			tt.AssertEqual(t, result.Age, 23)
+		})
+
+		t.Run("should ignore nil pointers when updating", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{
+				Name: "Upserted With Nil Pointers",
+				Age:  22,
+			}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			err = c.Upsert(ctx, usersTable, &struct {
+				ID   uint    `ksql:"id"`
+				Name *string `ksql:"name"`
+				Age  int     `ksql:"age"`
+			}{
+				ID:  u.ID,
+				Age: 42,
+			})
+			tt.AssertNoErr(t, err)
+
+			var result user
+			err = getUserByID(db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "Upserted With Nil Pointers")
+			tt.AssertEqual(t, result.Age, 42)
+		})
+
+		t.Run("should use the conflict columns when they are set", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			table := NewTable("user_permissions").WithConflictColumns("user_id", "perm_id")
+
+			err := c.Upsert(ctx, table, &userPermission{
+				UserID: 1,
+				PermID: 42,
+				Type:   "read",
+			})
+			tt.AssertNoErr(t, err)
+
+			err = c.Upsert(ctx, table, &userPermission{
+				UserID: 1,
+				PermID: 42,
+				Type:   "write",
+			})
+			tt.AssertNoErr(t, err)
+
+			userPerm, err := getUserPermissionBySecondaryKeys(db, c.dialect, 1, 42)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, userPerm.Type, "write")
+
+			userPerms, err := getUserPermissionsByUser(db, driver, 1)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(userPerms), 1)
+		})
+
+		t.Run("should report error for invalid input types", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Upsert(ctx, usersTable, "foo")
+			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct")
+
+			err = c.Upsert(ctx, usersTable, user{Name: "not a ptr to user"})
+			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct")
+
+			var u *user
+			err = c.Upsert(ctx, usersTable, u)
+			tt.AssertErrContains(t, err, "ksql", "nil pointer")
+		})
+
+		t.Run("should report error if the conflict columns contain an empty string", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Upsert(ctx, usersTable.WithConflictColumns(""), &user{Name: "fake-name"})
+			tt.AssertErrContains(t, err, "ksql.Table", "conflict columns", "empty string")
+		})
+	})
+}
+
+type brokenDialect struct{}
+
+func (brokenDialect) InsertMethod() insertMethod {
+	return insertMethod(42)
+}
+
+func (brokenDialect) Escape(str string) string {
+	return str
+}
+
+func (brokenDialect) Placeholder(idx int) string {
+	return "?"
+}
+
+func (brokenDialect) DriverName() string {
+	return "fake-driver-name"
+}
+
+// DeleteTest runs all tests for making sure the Delete function is
+// working for a given adapter and driver.
+func DeleteTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Delete", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("should delete from tables with a single primary key correctly", func(t *testing.T) {
+			tests := []struct {
+				desc               string
+				deletionKeyForUser func(u user) interface{}
+			}{
+				{
+					desc: "passing only the ID as key",
+					deletionKeyForUser: func(u user) interface{} {
+						return u.ID
+					},
+				},
+				{
+					desc: "passing only the entire user",
+					deletionKeyForUser: func(u user) interface{} {
+						return u
+					},
+				},
+				{
+					desc: "passing the address of the user",
+					deletionKeyForUser: func(u user) interface{} {
+						return &u
+					},
+				},
+			}
+
+			for _, test := range tests {
 				t.Run(test.desc, func(t *testing.T) {
 					db, closer := newDBAdapter(t)
 					defer closer.Close()
@@ -1206,6 +1811,46 @@ func DeleteTest(
 			tt.AssertEqual(t, err, ErrRecordNotFound)
 		})
 
+		t.Run("should include the table on the error when requested", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.Delete(WithNotFoundDetails(ctx), usersTable, 4200)
+			tt.AssertEqual(t, err, NotFoundError{Table: "users"})
+			tt.AssertEqual(t, IsNotFound(err), true)
+			tt.AssertEqual(t, errors.Is(err, sql.ErrNoRows), true)
+		})
+
+		t.Run("DeleteWithResult should report the number of deleted rows", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Won't be deleted twice"}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			result, err := c.DeleteWithResult(ctx, usersTable, u.ID)
+			tt.AssertNoErr(t, err)
+			n, err := result.RowsAffected()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, n, int64(1))
+
+			result, err = c.DeleteWithResult(ctx, usersTable, u.ID)
+			tt.AssertNoErr(t, err)
+			n, err = result.RowsAffected()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, n, int64(0))
+
+			err = ExpectRowsAffected(c.DeleteWithResult(ctx, usersTable, u.ID))
+			tt.AssertEqual(t, err, ErrNoRowsAffected)
+		})
+
 		t.Run("should report error if it receives a nil pointer to a struct", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
@@ -1370,6 +2015,17 @@ func DeleteTest(
 					})
 					tt.AssertErrContains(t, err, "invalid value", "0", "perm_id")
 				})
+
+				t.Run("single value instead of struct or map", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					err := c.Delete(ctx, userPermissionsTable, 42)
+					tt.AssertErrContains(t, err, "ksql", "composite keys", "user_id", "perm_id", "int")
+				})
 			})
 		})
 
@@ -1506,6 +2162,44 @@ func PatchTest(
 			})
 		})
 
+		t.Run("should update tables with composite keys that don't include the id column", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = createUserPermission(db, c.dialect, userPermission{
+				UserID: 44,
+				PermID: 45,
+				Type:   "existingFakeType",
+			})
+			tt.AssertNoErr(t, err)
+
+			existingPerm, err := getUserPermissionBySecondaryKeys(db, c.dialect, 44, 45)
+			tt.AssertNoErr(t, err)
+
+			err = c.Patch(ctx, userPermissionsTable, &struct {
+				UserID int    `ksql:"user_id"`
+				PermID int    `ksql:"perm_id"`
+				Type   string `ksql:"type"`
+			}{
+				UserID: 44,
+				PermID: 45,
+				Type:   "newFakeType",
+			})
+			tt.AssertNoErr(t, err)
+
+			newPerm, err := getUserPermissionBySecondaryKeys(db, c.dialect, 44, 45)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, newPerm, userPermission{
+				ID:     existingPerm.ID,
+				UserID: 44,
+				PermID: 45,
+				Type:   "newFakeType",
+			})
+		})
+
 		t.Run("should ignore null pointers on partial updates", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
@@ -1543,6 +2237,43 @@ func PatchTest(
 			tt.AssertEqual(t, result.Age, 22)
 		})
 
+		t.Run("should ignore zero values with the omitempty option on partial updates", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			type partialUser struct {
+				ID   uint   `ksql:"id"`
+				Name string `ksql:"name,omitempty"`
+				Age  int    `ksql:"age,omitempty"`
+			}
+
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name, age) VALUES ('Letícia', 22)`)
+			tt.AssertNoErr(t, err)
+
+			var u user
+			err = getUserByName(db, driver, &u, "Letícia")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
+
+			err = c.Patch(ctx, usersTable, partialUser{
+				ID: u.ID,
+				// Should not be updated because it is empty:
+				Name: "",
+				// Should be updated because it is not empty:
+				Age: 23,
+			})
+			tt.AssertNoErr(t, err)
+
+			var result user
+			err = getUserByID(c.db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "Letícia")
+			tt.AssertEqual(t, result.Age, 23)
+		})
+
 		t.Run("should update valid pointers on partial updates", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
@@ -1594,6 +2325,33 @@ func PatchTest(
 			tt.AssertEqual(t, err, ErrRecordNotFound)
 		})
 
+		t.Run("PatchWithResult should report the number of updated rows", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Letícia"}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			err = ExpectRowsAffected(c.PatchWithResult(ctx, usersTable, user{
+				ID:   u.ID,
+				Name: "Letícia Updated",
+			}))
+			tt.AssertNoErr(t, err)
+
+			result, err := c.PatchWithResult(ctx, usersTable, user{
+				ID:   4200,
+				Name: "Thayane",
+			})
+			tt.AssertNoErr(t, err)
+			n, err := result.RowsAffected()
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, n, int64(0))
+		})
+
 		t.Run("should report database errors correctly", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
@@ -1649,534 +2407,3822 @@ func PatchTest(
 				})
 				tt.AssertErrContains(t, err, "invalid value", "0", "'user_id'")
 			})
-		})
-	})
-}
 
-// QueryChunksTest runs all tests for making sure the QueryChunks function is
+			t.Run("with composite keys and fewer attributes than ID columns", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				err := c.Patch(ctx, NewTable("user_permissions", "id", "user_id", "perm_id"), &struct {
+					ID   int    `ksql:"id"`
+					Type string `ksql:"type"`
+				}{
+					ID:   1,
+					Type: "fakeType",
+				})
+				tt.AssertErrContains(t, err, "missing required id field", "user_id")
+			})
+		})
+
+		t.Run("should report error if there are no attributes to update", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Patch(ctx, userPermissionsTable, &struct {
+				UserID int `ksql:"user_id"`
+				PermID int `ksql:"perm_id"`
+			}{
+				UserID: 1,
+				PermID: 42,
+			})
+			tt.AssertErrContains(t, err, "ksql", "no attributes to update", "user_id", "perm_id")
+		})
+
+		t.Run("should report error if table contains an empty ID name", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Patch(ctx, NewTable("users", ""), &user{ID: 1, Name: "fake-name"})
+			tt.AssertErrContains(t, err, "ksql.Table", "ID", "empty string")
+		})
+
+		t.Run("should report error if ksql.Table.name is empty", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Patch(ctx, NewTable("", "id"), &user{ID: 1, Name: "fake-name"})
+			tt.AssertErrContains(t, err, "ksql.Table", "table name", "empty string")
+		})
+	})
+}
+
+// PatchMapTest runs all tests for making sure the PatchMap function is
 // working for a given adapter and driver.
-func QueryChunksTest(
+func PatchMapTest(
 	t *testing.T,
 	driver string,
 	connStr string,
 	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
 ) {
-	t.Run("QueryChunks", func(t *testing.T) {
-		variations := []struct {
-			desc        string
-			queryPrefix string
-		}{
-			{
-				desc:        "with select *",
-				queryPrefix: "SELECT * ",
-			},
-			{
-				desc:        "building the SELECT part of the query internally",
-				queryPrefix: "",
-			},
+	t.Run("PatchMap", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
 		}
-		for _, variation := range variations {
-			t.Run(variation.desc, func(t *testing.T) {
-				t.Run("should query a single row correctly", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
-
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+		usersTable := usersTable.WithStruct(user{})
 
-					_ = c.Insert(ctx, usersTable, &user{
-						Name:    "User1",
-						Address: address{Country: "BR"},
-					})
+		t.Run("should update only the columns present on the map", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					var length int
-					var u user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `FROM users WHERE name = ` + c.dialect.Placeholder(0),
-						Params: []interface{}{"User1"},
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-						ChunkSize: 100,
-						ForEachChunk: func(users []user) error {
-							length = len(users)
-							if length > 0 {
-								u = users[0]
-							}
-							return nil
-						},
-					})
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name, age, address) VALUES ('Letícia', 22, '{"country":"BR"}')`)
+			tt.AssertNoErr(t, err)
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, length, 1)
-					tt.AssertNotEqual(t, u.ID, uint(0))
-					tt.AssertEqual(t, u.Name, "User1")
-					tt.AssertEqual(t, u.Address.Country, "BR")
-				})
+			var u user
+			err = getUserByName(db, driver, &u, "Letícia")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
 
-				t.Run("should query one chunk correctly", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			err = c.PatchMap(ctx, usersTable, u.ID, map[string]interface{}{
+				"name":    "Thayane",
+				"address": address{Country: "US"},
+			})
+			tt.AssertNoErr(t, err)
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			var result user
+			err = getUserByID(c.db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "Thayane")
+			tt.AssertEqual(t, result.Age, 22)
+			tt.AssertEqual(t, result.Address.Country, "US")
+		})
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+		t.Run("should write the expressions directly on the query", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Address: address{Country: "US"}})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Address: address{Country: "BR"}})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name, age) VALUES ('Expr User', 22)`)
+			tt.AssertNoErr(t, err)
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							users = append(users, buffer...)
-							lengths = append(lengths, len(buffer))
-							return nil
-						},
-					})
+			var u user
+			err = getUserByName(db, driver, &u, "Expr User")
+			tt.AssertNoErr(t, err)
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(lengths), 1)
-					tt.AssertEqual(t, lengths[0], 2)
+			err = c.PatchMap(ctx, usersTable, u.ID, map[string]interface{}{
+				"name": "Expr User 2",
+				"age":  Expr("age + 1"),
+			})
+			tt.AssertNoErr(t, err)
 
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertEqual(t, users[0].Address.Country, "US")
+			var result user
+			err = getUserByID(c.db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "Expr User 2")
+			tt.AssertEqual(t, result.Age, 23)
+		})
 
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertEqual(t, users[1].Address.Country, "BR")
-				})
+		t.Run("should update tables with composite keys correctly", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-				t.Run("should query chunks of 1 correctly", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			err = createUserPermission(db, c.dialect, userPermission{
+				UserID: 42,
+				PermID: 43,
+				Type:   "existingFakeType",
+			})
+			tt.AssertNoErr(t, err)
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			err = c.PatchMap(ctx, userPermissionsTable.WithStruct(&userPermission{}), map[string]interface{}{
+				"user_id": 42,
+				"perm_id": 43,
+			}, map[string]interface{}{
+				"type": "newFakeType",
+			})
+			tt.AssertNoErr(t, err)
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Address: address{Country: "US"}})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Address: address{Country: "BR"}})
+			existingPerm, err := getUserPermissionBySecondaryKeys(db, c.dialect, 42, 43)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, existingPerm.Type, "newFakeType")
+		})
 
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+		t.Run("should return ErrRecordNotFound when asked to update an inexistent user", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-						ChunkSize: 1,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
-							return nil
-						},
-					})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(users), 2)
-					tt.AssertEqual(t, lengths, []int{1, 1})
+			err = c.PatchMap(ctx, usersTable, 4200, map[string]interface{}{
+				"name": "Thayane",
+			})
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
 
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertEqual(t, users[0].Address.Country, "US")
+		t.Run("should report error if the table has no registered struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertEqual(t, users[1].Address.Country, "BR")
-				})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-				t.Run("should load partially filled chunks correctly", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			err = c.PatchMap(ctx, NewTable("users"), 1, map[string]interface{}{
+				"name": "Thayane",
+			})
+			tt.AssertErrContains(t, err, "ksql", "PatchMap", "WithStruct")
+		})
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+		t.Run("should report error if the registered struct is not a struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+			err = c.PatchMap(ctx, NewTable("users").WithStruct(42), 1, map[string]interface{}{
+				"name": "Thayane",
+			})
+			tt.AssertErrContains(t, err, "ksql.Table", "registered struct", "int")
+		})
 
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+		t.Run("should report error for columns not declared on the struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
-							return nil
-						},
-					})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
+			err = c.PatchMap(ctx, usersTable, 1, map[string]interface{}{
+				"name":         "Thayane",
+				"not_a_column": "fake-value",
+			})
+			tt.AssertErrContains(t, err, "ksql", "not_a_column", "not declared", "users")
+		})
+
+		t.Run("should report error when trying to update an ID column", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.PatchMap(ctx, usersTable, 1, map[string]interface{}{
+				"id": 2,
+			})
+			tt.AssertErrContains(t, err, "ksql", "can't update", "ID column", "id")
+		})
+
+		t.Run("should report error if there is nothing to update", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.PatchMap(ctx, usersTable, 1, map[string]interface{}{})
+			tt.AssertErrContains(t, err, "ksql", "no attributes to update")
+		})
+
+		t.Run("should report error if the ID is missing", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.PatchMap(ctx, userPermissionsTable.WithStruct(userPermission{}), 42, map[string]interface{}{
+				"type": "newFakeType",
+			})
+			tt.AssertErrContains(t, err, "ksql", "composite keys", "struct or a map")
+		})
+	})
+}
+
+// UpdateReturningTest runs all tests for making sure the UpdateReturning function is
+// working for a given adapter and driver.
+func UpdateReturningTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("UpdateReturning", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		if driver == "mysql" || driver == "mariadb" || driver == "duckdb" {
+			t.Run("should report error for unsupported drivers", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				err := c.UpdateReturning(ctx, usersTable, &user{ID: 1, Name: "fake-name"})
+				tt.AssertErrContains(t, err, "ksql", "not supported", driver)
+			})
+			return
+		}
+
+		t.Run("should update the record and fill it with the saved values", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name, age, address) VALUES ('Letícia', 22, '{"country":"BR"}')`)
+			tt.AssertNoErr(t, err)
+
+			var u user
+			err = getUserByName(db, driver, &u, "Letícia")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
+
+			type partialUser struct {
+				ID      uint     `ksql:"id"`
+				Name    string   `ksql:"name"`
+				Age     *int     `ksql:"age"`
+				Address *address `ksql:"address,json"`
+			}
+
+			record := partialUser{
+				ID:   u.ID,
+				Name: "Thayane",
+			}
+			err = c.UpdateReturning(ctx, usersTable, &record)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, record.ID, u.ID)
+			tt.AssertEqual(t, record.Name, "Thayane")
+			tt.AssertEqual(t, record.Age, nullable.Int(22))
+			tt.AssertEqual(t, record.Address, &address{Country: "BR"})
+
+			var result user
+			err = getUserByID(c.db, c.dialect, &result, u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name, "Thayane")
+			tt.AssertEqual(t, result.Age, 22)
+		})
+
+		t.Run("should update tables with composite keys correctly", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = createUserPermission(db, c.dialect, userPermission{
+				UserID: 42,
+				PermID: 43,
+				Type:   "existingFakeType",
+			})
+			tt.AssertNoErr(t, err)
+
+			// The ID is a pointer so it is not updated:
+			type partialPermission struct {
+				ID     *int   `ksql:"id"`
+				UserID int    `ksql:"user_id"`
+				PermID int    `ksql:"perm_id"`
+				Type   string `ksql:"type"`
+			}
+
+			perm := partialPermission{
+				UserID: 42,
+				PermID: 43,
+				Type:   "newFakeType",
+			}
+			err = c.UpdateReturning(ctx, userPermissionsTable, &perm)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, perm.ID, nil)
+			tt.AssertEqual(t, perm.Type, "newFakeType")
+
+			existingPerm, err := getUserPermissionBySecondaryKeys(db, c.dialect, 42, 43)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, existingPerm.ID, *perm.ID)
+			tt.AssertEqual(t, existingPerm.Type, "newFakeType")
+		})
+
+		t.Run("should return ErrRecordNotFound when asked to update an inexistent user", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.UpdateReturning(ctx, usersTable, &user{
+				ID:   4200,
+				Name: "Thayane",
+			})
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("should report error if the record is not a pointer to struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.UpdateReturning(ctx, usersTable, user{
+				ID:   1,
+				Name: "Thayane",
+			})
+			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct", "ksql.user")
+		})
+
+		t.Run("should report error if the record is a nil pointer", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var u *user
+			err = c.UpdateReturning(ctx, usersTable, u)
+			tt.AssertErrContains(t, err, "ksql", "expected", "valid pointer", "nil pointer")
+		})
+	})
+}
+
+// DeleteReturningTest runs all tests for making sure the DeleteReturning function is
+// working for a given adapter and driver.
+func DeleteReturningTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("DeleteReturning", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		if driver == "mysql" || driver == "mariadb" {
+			t.Run("should report error for unsupported drivers", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				err := c.DeleteReturning(ctx, usersTable, &user{ID: 1})
+				tt.AssertErrContains(t, err, "ksql", "not supported", driver)
+			})
+			return
+		}
+
+		t.Run("should delete the record and fill it with the deleted values", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name, age, address) VALUES ('Fernanda', 33, '{"country":"US"}')`)
+			tt.AssertNoErr(t, err)
+
+			var u user
+			err = getUserByName(db, driver, &u, "Fernanda")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
+
+			record := user{ID: u.ID}
+			err = c.DeleteReturning(ctx, usersTable, &record)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, record.ID, u.ID)
+			tt.AssertEqual(t, record.Name, "Fernanda")
+			tt.AssertEqual(t, record.Age, 33)
+			tt.AssertEqual(t, record.Address.Country, "US")
+
+			var result user
+			err = getUserByID(c.db, c.dialect, &result, u.ID)
+			tt.AssertEqual(t, err, sql.ErrNoRows)
+		})
+
+		t.Run("should delete from tables with composite keys correctly", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = createUserPermission(db, c.dialect, userPermission{
+				UserID: 1,
+				PermID: 2,
+				Type:   "fakeType",
+			})
+			tt.AssertNoErr(t, err)
+
+			perm := userPermission{
+				UserID: 1,
+				PermID: 2,
+			}
+			err = c.DeleteReturning(ctx, userPermissionsTable, &perm)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, perm.ID, 0)
+			tt.AssertEqual(t, perm.Type, "fakeType")
+
+			userPerms, err := getUserPermissionsByUser(db, driver, 1)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(userPerms), 0)
+		})
+
+		t.Run("should return ErrRecordNotFound if no rows were deleted", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.DeleteReturning(ctx, usersTable, &user{ID: 4200})
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("should report error if the record is not a pointer to struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.DeleteReturning(ctx, usersTable, uint(42))
+			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct", "uint")
+		})
+
+		t.Run("should report error if the record has no ID", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.DeleteReturning(ctx, usersTable, &user{Name: "fake-name"})
+			tt.AssertErrContains(t, err, "invalid value", "id")
+		})
+	})
+}
+
+// SoftDeleteTest runs all tests for making sure the softDelete
+// modifier is working for a given adapter and driver.
+func SoftDeleteTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("SoftDelete", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("Delete should only mark the record as deleted", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "soft deleted doc"}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, doc.ID, uint(0))
+
+			err = c.Delete(ctx, documentsTable, doc)
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+
+			err = c.QueryOne(Unscoped(ctx), &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "soft deleted doc")
+			tt.AssertNotEqual(t, result.DeletedAt, nil)
+		})
+
+		t.Run("Delete should use the struct registered on the table when receiving only the ID", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "soft deleted by id"}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(ctx, documentsTable.WithStruct(document{}), doc.ID)
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(Unscoped(ctx), &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, result.DeletedAt, nil)
+		})
+
+		t.Run("Delete should return ErrRecordNotFound for records already deleted", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "deleted twice"}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(ctx, documentsTable, &doc)
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("Delete should remove the record when using an unscoped context", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "hard deleted doc"}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(Unscoped(ctx), documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(Unscoped(ctx), &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("queries should ignore soft deleted records", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := c.Exec(ctx, `DELETE FROM documents`)
+			tt.AssertNoErr(t, err)
+
+			for _, title := range []string{"doc1", "doc2", "doc3"} {
+				err := c.Insert(ctx, documentsTable, &document{UserID: 42, Title: title})
+				tt.AssertNoErr(t, err)
+			}
+
+			var doc2 document
+			err = c.QueryOne(ctx, &doc2, `FROM documents WHERE title = `+c.dialect.Placeholder(0), "doc2")
+			tt.AssertNoErr(t, err)
+			err = c.Delete(ctx, documentsTable, &doc2)
+			tt.AssertNoErr(t, err)
+
+			var docs []document
+			err = c.Query(ctx, &docs, `FROM documents WHERE user_id = `+c.dialect.Placeholder(0)+` ORDER BY id`, 42)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(docs), 2)
+			tt.AssertEqual(t, docs[0].Title, "doc1")
+			tt.AssertEqual(t, docs[1].Title, "doc3")
+
+			docs = nil
+			err = c.Query(ctx, &docs, `SELECT * FROM documents ORDER BY id`)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(docs), 2)
+
+			docs = nil
+			err = c.Query(Unscoped(ctx), &docs, `FROM documents ORDER BY id`)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(docs), 3)
+
+			var chunkTitles []string
+			err = c.QueryChunks(ctx, ChunkParser{
+				Query:     `FROM documents ORDER BY id`,
+				ChunkSize: 10,
+				ForEachChunk: func(docs []document) error {
+					for _, doc := range docs {
+						chunkTitles = append(chunkTitles, doc.Title)
+					}
+					return nil
+				},
+			})
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, chunkTitles, []string{"doc1", "doc3"})
+
+			var iterTitles []string
+			cursor := c.QueryIter(ctx, `FROM documents ORDER BY id`)
+			var doc document
+			for cursor.Next(&doc) {
+				iterTitles = append(iterTitles, doc.Title)
+			}
+			tt.AssertNoErr(t, cursor.Err())
+			tt.AssertEqual(t, iterTitles, []string{"doc1", "doc3"})
+		})
+
+		t.Run("queries with nested structs should ignore soft deleted records", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Soft Delete Owner"}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			keptDoc := document{UserID: u.ID, Title: "kept doc"}
+			err = c.Insert(ctx, documentsTable, &keptDoc)
+			tt.AssertNoErr(t, err)
+
+			deletedDoc := document{UserID: u.ID, Title: "deleted doc"}
+			err = c.Insert(ctx, documentsTable, &deletedDoc)
+			tt.AssertNoErr(t, err)
+
+			err = c.Delete(ctx, documentsTable, &deletedDoc)
+			tt.AssertNoErr(t, err)
+
+			var rows []struct {
+				User     user     `tablename:"u"`
+				Document document `tablename:"documents"`
+			}
+			err = c.Query(ctx, &rows,
+				`FROM users u JOIN documents ON documents.user_id = u.id WHERE u.id = `+c.dialect.Placeholder(0),
+				u.ID,
+			)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 1)
+			tt.AssertEqual(t, rows[0].Document.Title, "kept doc")
+		})
+
+		if driver != "mysql" && driver != "mariadb" && driver != "duckdb" {
+			t.Run("DeleteReturning should only mark the record as deleted", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				doc := document{Title: "soft deleted with returning"}
+				err := c.Insert(ctx, documentsTable, &doc)
+				tt.AssertNoErr(t, err)
+
+				result := document{ID: doc.ID}
+				err = c.DeleteReturning(ctx, documentsTable, &result)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, result.Title, "soft deleted with returning")
+				tt.AssertNotEqual(t, result.DeletedAt, nil)
+
+				err = c.QueryOne(Unscoped(ctx), &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+				tt.AssertNoErr(t, err)
+			})
+		}
+
+		t.Run("should report error for queries with UNION", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var docs []document
+			err := c.Query(ctx, &docs, `SELECT * FROM documents UNION SELECT * FROM documents`)
+			tt.AssertErrContains(t, err, "ksql", "soft delete", "UNION")
+		})
+	})
+}
+
+// OptimisticLockTest runs all tests for making sure the optimisticLock
+// modifier is working for a given adapter and driver.
+func OptimisticLockTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("OptimisticLock", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("Patch should increment the version on the database and on the record", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "original title", Version: 1}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			doc.Title = "new title"
+			err = c.Patch(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, doc.Version, 2)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "new title")
+			tt.AssertEqual(t, result.Version, 2)
+		})
+
+		t.Run("Patch should return ErrVersionConflict for outdated records", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "original title", Version: 1}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			firstCopy := doc
+			secondCopy := doc
+
+			firstCopy.Title = "first title"
+			err = c.Patch(ctx, documentsTable, &firstCopy)
+			tt.AssertNoErr(t, err)
+
+			secondCopy.Title = "second title"
+			err = c.Patch(ctx, documentsTable, &secondCopy)
+			tt.AssertEqual(t, err, ErrVersionConflict)
+			tt.AssertEqual(t, secondCopy.Version, 1)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "first title")
+			tt.AssertEqual(t, result.Version, 2)
+		})
+
+		t.Run("Patch should return ErrRecordNotFound for inexistent records", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Patch(ctx, documentsTable, &document{ID: 4200, Title: "fake title", Version: 1})
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("Patch should not check the version if the attribute is a nil pointer", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "original title", Version: 5}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			type partialDocument struct {
+				ID      uint   `ksql:"id"`
+				Title   string `ksql:"title"`
+				Version *int   `ksql:"version,optimisticLock"`
+			}
+			err = c.Patch(ctx, documentsTable, partialDocument{
+				ID:    doc.ID,
+				Title: "new title",
+			})
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "new title")
+			tt.AssertEqual(t, result.Version, 6)
+		})
+
+		t.Run("PatchMap should check the version if it is present on the map", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "original title", Version: 1}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			table := documentsTable.WithStruct(document{})
+			err = c.PatchMap(ctx, table, doc.ID, map[string]interface{}{
+				"title":   "outdated title",
+				"version": 0,
+			})
+			tt.AssertEqual(t, err, ErrVersionConflict)
+
+			err = c.PatchMap(ctx, table, doc.ID, map[string]interface{}{
+				"title":   "new title",
+				"version": 1,
+			})
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "new title")
+			tt.AssertEqual(t, result.Version, 2)
+		})
+
+		if driver != "mysql" && driver != "mariadb" && driver != "duckdb" {
+			t.Run("UpdateReturning should return the new version", func(t *testing.T) {
+				db, closer := newDBAdapter(t)
+				defer closer.Close()
+
+				ctx := context.Background()
+				c := newTestDB(db, driver)
+
+				doc := document{Title: "original title", Version: 1}
+				err := c.Insert(ctx, documentsTable, &doc)
+				tt.AssertNoErr(t, err)
+
+				outdatedDoc := doc
+
+				doc.Title = "new title"
+				err = c.UpdateReturning(ctx, documentsTable, &doc)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, doc.Version, 2)
+
+				outdatedDoc.Title = "outdated title"
+				err = c.UpdateReturning(ctx, documentsTable, &outdatedDoc)
+				tt.AssertEqual(t, err, ErrVersionConflict)
+			})
+		}
+	})
+}
+
+// TimeNowUTCTest runs all tests for making sure the timeNowUTC
+// modifiers are working for a given adapter and driver.
+func TimeNowUTCTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("TimeNowUTC", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("Insert should set the timestamps on the database and on the record", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			before := time.Now().UTC()
+
+			doc := document{Title: "doc with timestamps"}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			assertTimeNear(t, doc.CreatedAt, before)
+			tt.AssertNotEqual(t, doc.UpdatedAt, nil)
+			assertTimeNear(t, *doc.UpdatedAt, before)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			assertTimeNear(t, result.CreatedAt, before)
+			tt.AssertNotEqual(t, result.UpdatedAt, nil)
+			assertTimeNear(t, *result.UpdatedAt, before)
+		})
+
+		t.Run("Patch should only update the timeNowUTC attributes", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			oldTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			_, err := db.ExecContext(ctx,
+				`INSERT INTO documents (user_id, title, version, created_at, updated_at) VALUES (0, 'old doc', 1, `+
+					c.dialect.Placeholder(0)+`, `+c.dialect.Placeholder(1)+`)`,
+				oldTime, oldTime,
+			)
+			tt.AssertNoErr(t, err)
+
+			var doc document
+			err = c.QueryOne(ctx, &doc, `FROM documents WHERE title = 'old doc'`)
+			tt.AssertNoErr(t, err)
+
+			before := time.Now().UTC()
+
+			// Even if the attributes are changed on the struct
+			// they should be ignored:
+			doc.Title = "updated doc"
+			doc.CreatedAt = time.Now()
+			doc.UpdatedAt = nil
+			err = c.Patch(ctx, documentsTable, doc)
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "updated doc")
+			tt.AssertEqual(t, result.CreatedAt.Equal(oldTime), true)
+			tt.AssertNotEqual(t, result.UpdatedAt, nil)
+			assertTimeNear(t, *result.UpdatedAt, before)
+		})
+
+		t.Run("PatchMap should update the timeNowUTC attributes", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := document{Title: "doc with timestamps", Version: 1}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			oldTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			err = c.PatchMap(ctx, documentsTable.WithStruct(document{}), doc.ID, map[string]interface{}{
+				"title":      "updated doc",
+				"created_at": oldTime,
+				"updated_at": oldTime,
+			})
+			tt.AssertNoErr(t, err)
+
+			var result document
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, "updated doc")
+			assertTimeNear(t, result.CreatedAt, doc.CreatedAt)
+			tt.AssertNotEqual(t, result.UpdatedAt, nil)
+			assertTimeNear(t, *result.UpdatedAt, *doc.UpdatedAt)
+		})
+
+		t.Run("should report error for attributes that are not of type time.Time", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Insert(ctx, documentsTable, &struct {
+				ID        uint   `ksql:"id"`
+				CreatedAt string `ksql:"created_at,timeNowUTC"`
+			}{})
+			tt.AssertErrContains(t, err, "timeNowUTC", "time.Time", "created_at", "string")
+		})
+	})
+}
+
+// assertTimeNear checks that the input time is within a few
+// seconds after the expected time, since some databases
+// don't store the fractions of a second.
+func assertTimeNear(t *testing.T, value time.Time, expected time.Time) {
+	diff := value.Sub(expected)
+	if diff < -time.Second || diff > 5*time.Second {
+		t.Fatalf("expected time %v to be near %v", value, expected)
+	}
+}
+
+var registerTestModifierOnce sync.Once
+
+// These variables record the methods that used the test modifier:
+var testModifierMutex sync.Mutex
+var testModifierMethods []string
+
+// FlattenTest runs all tests for making sure the attributes of
+// flattened structs are working for a given adapter and driver.
+func FlattenTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	type documentOwner struct {
+		ID uint `ksql:"id"`
+	}
+
+	type documentTimestamps struct {
+		CreatedAt time.Time  `ksql:"created_at,timeNowUTCSkipOnUpdate"`
+		UpdatedAt *time.Time `ksql:"updated_at,timeNowUTC"`
+		DeletedAt *time.Time `ksql:"deleted_at,softDelete"`
+	}
+
+	type flattenedDocument struct {
+		documentTimestamps
+
+		ID      uint          `ksql:"id"`
+		Owner   documentOwner `ksql:"user_,flatten"`
+		Title   string        `ksql:"title"`
+		Version int           `ksql:"version,optimisticLock"`
+	}
+
+	t.Run("Flatten", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("should insert and query structs with flattened attributes", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			before := time.Now().UTC()
+
+			doc := flattenedDocument{
+				Owner: documentOwner{ID: 42},
+				Title: "flattened doc",
+			}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, doc.ID, uint(0))
+			assertTimeNear(t, doc.CreatedAt, before)
+
+			var result flattenedDocument
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.ID, doc.ID)
+			tt.AssertEqual(t, result.Owner.ID, uint(42))
+			tt.AssertEqual(t, result.Title, "flattened doc")
+			assertTimeNear(t, result.CreatedAt, before)
+
+			// The same row read with the plain struct:
+			var plainDoc document
+			err = c.QueryOne(ctx, &plainDoc, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, plainDoc.UserID, uint(42))
+			tt.AssertEqual(t, plainDoc.Title, "flattened doc")
+		})
+
+		t.Run("should patch and delete structs with flattened attributes", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			doc := flattenedDocument{
+				Owner: documentOwner{ID: 42},
+				Title: "doc to patch",
+			}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			doc.Owner.ID = 43
+			doc.Title = "patched doc"
+			err = c.Patch(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, doc.Version, 1)
+
+			var result flattenedDocument
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Owner.ID, uint(43))
+			tt.AssertEqual(t, result.Title, "patched doc")
+			tt.AssertEqual(t, result.Version, 1)
+			tt.AssertNotEqual(t, result.UpdatedAt, nil)
+
+			err = c.Delete(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertEqual(t, err, ErrRecordNotFound)
+		})
+
+		t.Run("should scan flattened attributes of nested structs", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Flattened Owner"}
+			err := c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			doc := flattenedDocument{
+				Owner: documentOwner{ID: u.ID},
+				Title: "joined doc",
+			}
+			err = c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			var rows []struct {
+				User user              `tablename:"u"`
+				Doc  flattenedDocument `tablename:"d"`
+			}
+			err = c.Query(ctx, &rows,
+				`FROM users u JOIN documents d ON d.user_id = u.id WHERE u.id = `+c.dialect.Placeholder(0),
+				u.ID,
+			)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 1)
+			tt.AssertEqual(t, rows[0].User.Name, "Flattened Owner")
+			tt.AssertEqual(t, rows[0].Doc.ID, doc.ID)
+			tt.AssertEqual(t, rows[0].Doc.Owner.ID, u.ID)
+			tt.AssertEqual(t, rows[0].Doc.Title, "joined doc")
+		})
+	})
+}
+
+// ModifiersTest runs all tests for making sure the custom attribute
+// modifiers are working for a given adapter and driver.
+func ModifiersTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Modifiers", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		registerTestModifierOnce.Do(func() {
+			RegisterModifier("ksqlTestCSV", AttrModifier{
+				Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+					testModifierMutex.Lock()
+					testModifierMethods = append(testModifierMethods, opInfo.Method)
+					testModifierMutex.Unlock()
+
+					var s string
+					switch v := dbValue.(type) {
+					case string:
+						s = v
+					case []byte:
+						s = string(v)
+					}
+					*attrPtr.(*[]string) = strings.Split(s, ",")
+					return nil
+				},
+				Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+					testModifierMutex.Lock()
+					testModifierMethods = append(testModifierMethods, opInfo.Method)
+					testModifierMutex.Unlock()
+
+					return strings.Join(inputValue.([]string), ","), nil
+				},
+			})
+		})
+
+		type taggedDocument struct {
+			ID    uint     `ksql:"id"`
+			Title []string `ksql:"title,ksqlTestCSV"`
+		}
+
+		t.Run("should use the custom modifier for writing and reading attributes", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			testModifierMutex.Lock()
+			testModifierMethods = nil
+			testModifierMutex.Unlock()
+
+			doc := taggedDocument{Title: []string{"foo", "bar"}}
+			err := c.Insert(ctx, documentsTable, &doc)
+			tt.AssertNoErr(t, err)
+
+			var rawDoc struct {
+				Title string `ksql:"title"`
+			}
+			err = c.QueryOne(ctx, &rawDoc, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, rawDoc.Title, "foo,bar")
+
+			doc.Title = []string{"foo", "bar", "baz"}
+			err = c.Patch(ctx, documentsTable, doc)
+			tt.AssertNoErr(t, err)
+
+			var result taggedDocument
+			err = c.QueryOne(ctx, &result, `FROM documents WHERE id = `+c.dialect.Placeholder(0), doc.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Title, []string{"foo", "bar", "baz"})
+
+			tt.AssertEqual(t, testModifierMethods, []string{"Insert", "Update", "Query"})
+		})
+
+		t.Run("jsonNullable should save zero values as NULL", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			type nullableAddressUser struct {
+				ID      uint    `ksql:"id"`
+				Name    string  `ksql:"name"`
+				Age     int     `ksql:"age"`
+				Address address `ksql:"address,jsonNullable"`
+			}
+
+			emptyUser := nullableAddressUser{Name: "jsonNullable empty"}
+			err := c.Insert(ctx, usersTable, &emptyUser)
+			tt.AssertNoErr(t, err)
+
+			filledUser := nullableAddressUser{Name: "jsonNullable filled", Address: address{Country: "BR"}}
+			err = c.Insert(ctx, usersTable, &filledUser)
+			tt.AssertNoErr(t, err)
+
+			var users []nullableAddressUser
+			err = c.Query(ctx, &users, `FROM users WHERE address IS NULL AND name LIKE 'jsonNullable%'`)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 1)
+			tt.AssertEqual(t, users[0].ID, emptyUser.ID)
+			tt.AssertEqual(t, users[0].Address, address{})
+
+			var result nullableAddressUser
+			err = c.QueryOne(ctx, &result, `FROM users WHERE id = `+c.dialect.Placeholder(0), filledUser.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Address.Country, "BR")
+		})
+	})
+}
+
+// QueryMapsTest runs all tests for making sure the QueryMaps function is
+// working for a given adapter and driver.
+func QueryMapsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("QueryMaps", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("should return an empty slice if there are no results", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			rows := []map[string]interface{}{{"fake": "map"}}
+			err := c.QueryMaps(ctx, &rows, `SELECT id, name FROM users WHERE id = `+c.dialect.Placeholder(0), 4200)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 0)
+		})
+
+		t.Run("should scan each row into a map", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err := c.Insert(ctx, usersTable, &user{Name: "Maps User 1", Age: 21})
+			tt.AssertNoErr(t, err)
+			err = c.Insert(ctx, usersTable, &user{Name: "Maps User 2", Age: 22})
+			tt.AssertNoErr(t, err)
+
+			var rows []map[string]interface{}
+			err = c.QueryMaps(ctx, &rows, `SELECT name, age, address FROM users WHERE name LIKE `+c.dialect.Placeholder(0)+` ORDER BY id`, "Maps User%")
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, len(rows), 2)
+			tt.AssertEqual(t, rows[0]["name"], "Maps User 1")
+			tt.AssertEqual(t, fmt.Sprint(rows[0]["age"]), "21")
+			tt.AssertEqual(t, rows[1]["name"], "Maps User 2")
+			tt.AssertEqual(t, fmt.Sprint(rows[1]["age"]), "22")
+
+			// NULL values should be returned as nil:
+			_, err = db.ExecContext(ctx, `INSERT INTO users (name) VALUES ('Maps User With Nulls')`)
+			tt.AssertNoErr(t, err)
+
+			err = c.QueryMaps(ctx, &rows, `SELECT name, age FROM users WHERE name = 'Maps User With Nulls'`)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, rows, []map[string]interface{}{
+				{"name": "Maps User With Nulls", "age": nil},
+			})
+		})
+
+		t.Run("should expand slices used inside IN clauses", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var rows []map[string]interface{}
+			err := c.QueryMaps(ctx, &rows,
+				`SELECT name FROM users WHERE name IN (`+c.dialect.Placeholder(0)+`) ORDER BY name`,
+				[]string{"Maps User 1", "Maps User 2"},
+			)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, rows, []map[string]interface{}{
+				{"name": "Maps User 1"},
+				{"name": "Maps User 2"},
+			})
+		})
+
+		t.Run("should report error if the query starts with FROM", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var rows []map[string]interface{}
+			err := c.QueryMaps(ctx, &rows, `FROM users`)
+			tt.AssertErrContains(t, err, "QueryMaps", "SELECT")
+		})
+
+		t.Run("should report error if the query is invalid", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var rows []map[string]interface{}
+			err := c.QueryMaps(ctx, &rows, `SELECT * FROM not_a_table`)
+			tt.AssertErrContains(t, err, "error running query")
+		})
+	})
+}
+
+// ReplicasTest runs all tests for making sure the read-only operations
+// are routed to the replicas when using `ksql.NewWithReplicas()`.
+func ReplicasTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Replicas", func(t *testing.T) {
+		err := createTables(driver, connStr)
+		if err != nil {
+			t.Fatal("could not create test table!, reason:", err.Error())
+		}
+
+		t.Run("should send the reads to the replicas in a round-robin fashion", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+
+			primary := &countingAdapter{DBAdapter: db}
+			replica1 := &countingAdapter{DBAdapter: db}
+			replica2 := &countingAdapter{DBAdapter: db}
+
+			c, err := NewWithReplicas(
+				newTestDB(primary, driver),
+				newTestDB(replica1, driver),
+				newTestDB(replica2, driver),
+			)
+			tt.AssertNoErr(t, err)
+
+			err = c.Insert(ctx, usersTable, &user{Name: "Replicas User", Age: 22})
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, replica1.count+replica2.count, 0)
+
+			var users []user
+			err = c.Query(ctx, &users, `FROM users WHERE name = `+c.dialect.Placeholder(0), "Replicas User")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 1)
+
+			var u user
+			err = c.QueryOne(ctx, &u, `FROM users WHERE name = `+c.dialect.Placeholder(0), "Replicas User")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, u.Age, 22)
+
+			var rows []map[string]interface{}
+			err = c.QueryMaps(ctx, &rows, `SELECT name FROM users WHERE name = `+c.dialect.Placeholder(0), "Replicas User")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 1)
+
+			tt.AssertEqual(t, replica1.count, 2)
+			tt.AssertEqual(t, replica2.count, 1)
+		})
+
+		t.Run("should send the reads to the primary when using UsePrimary()", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+
+			primary := &countingAdapter{DBAdapter: db}
+			replica := &countingAdapter{DBAdapter: db}
+
+			c, err := NewWithReplicas(newTestDB(primary, driver), newTestDB(replica, driver))
+			tt.AssertNoErr(t, err)
+
+			var users []user
+			err = c.Query(UsePrimary(ctx), &users, `FROM users`)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, primary.count, 1)
+			tt.AssertEqual(t, replica.count, 0)
+		})
+
+		t.Run("should send the reads inside transactions to the primary", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+
+			replica := &countingAdapter{DBAdapter: db}
+
+			c, err := NewWithReplicas(newTestDB(db, driver), newTestDB(replica, driver))
+			tt.AssertNoErr(t, err)
+
+			err = c.Transaction(ctx, func(db Provider) error {
+				var users []user
+				return db.Query(ctx, &users, `FROM users`)
+			})
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, replica.count, 0)
+		})
+
+		t.Run("should report error if the replicas use a different dialect", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			otherDriver := "postgres"
+			if driver == "postgres" {
+				otherDriver = "mysql"
+			}
+
+			_, err := NewWithReplicas(newTestDB(db, driver), newTestDB(db, otherDriver))
+			tt.AssertErrContains(t, err, "ksql", "same dialect", driver, otherDriver)
+		})
+	})
+}
+
+// countingAdapter counts the number of queries
+// sent to the wrapped adapter.
+type countingAdapter struct {
+	DBAdapter
+	count int
+}
+
+func (c *countingAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	c.count++
+	return c.DBAdapter.QueryContext(ctx, query, args...)
+}
+
+// HealthCheckTest runs all tests for making sure the HealthCheck
+// method is working for a given adapter and driver.
+func HealthCheckTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("HealthCheck", func(t *testing.T) {
+		t.Run("should report the database as healthy", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c, err := NewWithConfig(db, driver, Config{
+				CircuitBreaker: CircuitBreakerConfig{
+					FailureThreshold: 3,
+				},
+			})
+			tt.AssertNoErr(t, err)
+
+			status, err := c.HealthCheck(ctx)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, status.CircuitState, CircuitClosed)
+			tt.AssertNotEqual(t, status.PoolStats, (*PoolStats)(nil))
+		})
+
+		t.Run("should report errors from the ping query", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := c.HealthCheck(ctx)
+			tt.AssertNotEqual(t, err, nil)
+		})
+	})
+}
+
+// QueryChunksTest runs all tests for making sure the QueryChunks function is
+// working for a given adapter and driver.
+func QueryChunksTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("QueryChunks", func(t *testing.T) {
+		variations := []struct {
+			desc        string
+			queryPrefix string
+		}{
+			{
+				desc:        "with select *",
+				queryPrefix: "SELECT * ",
+			},
+			{
+				desc:        "building the SELECT part of the query internally",
+				queryPrefix: "",
+			},
+		}
+		for _, variation := range variations {
+			t.Run(variation.desc, func(t *testing.T) {
+				t.Run("should query a single row correctly", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{
+						Name:    "User1",
+						Address: address{Country: "BR"},
+					})
+
+					var length int
+					var u user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `FROM users WHERE name = ` + c.dialect.Placeholder(0),
+						Params: []interface{}{"User1"},
+
+						ChunkSize: 100,
+						ForEachChunk: func(users []user) error {
+							length = len(users)
+							if length > 0 {
+								u = users[0]
+							}
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, length, 1)
+					tt.AssertNotEqual(t, u.ID, uint(0))
+					tt.AssertEqual(t, u.Name, "User1")
+					tt.AssertEqual(t, u.Address.Country, "BR")
+				})
+
+				t.Run("should query one chunk correctly", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Address: address{Country: "US"}})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Address: address{Country: "BR"}})
+
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							users = append(users, buffer...)
+							lengths = append(lengths, len(buffer))
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(lengths), 1)
+					tt.AssertEqual(t, lengths[0], 2)
+
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertEqual(t, users[0].Address.Country, "US")
+
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, users[1].Address.Country, "BR")
+				})
+
+				t.Run("should query chunks of 1 correctly", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Address: address{Country: "US"}})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Address: address{Country: "BR"}})
+
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 1,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(users), 2)
+					tt.AssertEqual(t, lengths, []int{1, 1})
+
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertEqual(t, users[0].Address.Country, "US")
+
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, users[1].Address.Country, "BR")
+				})
+
+				t.Run("should load partially filled chunks correctly", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(users), 3)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertNotEqual(t, users[2].ID, uint(0))
+					tt.AssertEqual(t, users[2].Name, "User3")
+					tt.AssertEqual(t, lengths, []int{2, 1})
+				})
+
+				t.Run("should reuse the same records for all the chunks", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Age: 22})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Age: 0})
+
+					var pointers []*user
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 1,
+						ForEachChunk: func(buffer []user) error {
+							pointers = append(pointers, &buffer[0])
+							users = append(users, buffer...)
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(users), 2)
+					tt.AssertEqual(t, pointers[0] == pointers[1], true)
+
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertEqual(t, users[0].Age, 22)
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, users[1].Age, 0)
+				})
+
+				t.Run("should process the chunks concurrently when Workers is set", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User4"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User5"})
+
+					var mutex sync.Mutex
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						Workers:   3,
+						ForEachChunk: func(buffer []user) error {
+							mutex.Lock()
+							defer mutex.Unlock()
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+							return nil
+						},
+					})
+					tt.AssertNoErr(t, err)
+
+					// The chunks might be processed in any order:
+					sort.Ints(lengths)
+					sort.Slice(users, func(i, j int) bool {
+						return users[i].Name < users[j].Name
+					})
+
+					tt.AssertEqual(t, lengths, []int{1, 2, 2})
+					tt.AssertEqual(t, len(users), 5)
+					for i, u := range users {
+						tt.AssertNotEqual(t, u.ID, uint(0))
+						tt.AssertEqual(t, u.Name, fmt.Sprint("User", i+1))
+					}
+				})
+
+				t.Run("should return the error returned by one of the Workers", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 1,
+						Workers:   2,
+						ForEachChunk: func(buffer []user) error {
+							return errors.New("fakeWorkerErrMsg")
+						},
+					})
+					tt.AssertErrContains(t, err, "fakeWorkerErrMsg")
+				})
+
+				t.Run("should stop with no errors when one of the Workers returns ErrAbortIteration", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 1,
+						Workers:   2,
+						ForEachChunk: func(buffer []user) error {
+							return ErrAbortIteration
+						},
+					})
+					tt.AssertNoErr(t, err)
+				})
+
+				t.Run("should report error if Workers is negative", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					err := c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `FROM users`,
+						Params: []interface{}{},
+
+						ChunkSize: 2,
+						Workers:   -1,
+						ForEachChunk: func(buffer []user) error {
+							return nil
+						},
+					})
+					tt.AssertErrContains(t, err, "ksql", "Workers", "negative")
+				})
+
+				// xxx
+				t.Run("should query joined tables correctly", func(t *testing.T) {
+					// This test only makes sense with no query prefix
+					if variation.queryPrefix != "" {
+						return
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					joao := user{
+						Name: "Thiago Ribeiro",
+						Age:  24,
+					}
+					thatiana := user{
+						Name: "Thatiana Ribeiro",
+						Age:  20,
+					}
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+					_ = c.Insert(ctx, usersTable, &joao)
+					_ = c.Insert(ctx, usersTable, &thatiana)
+
+					_, err := db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, thatiana.ID, `, 'Thatiana Post1')`))
+					tt.AssertNoErr(t, err)
+					_, err = db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, thatiana.ID, `, 'Thatiana Post2')`))
+					tt.AssertNoErr(t, err)
+					_, err = db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, joao.ID, `, 'Thiago Post1')`))
+					tt.AssertNoErr(t, err)
+
+					var lengths []int
+					var users []user
+					var posts []post
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query: fmt.Sprint(
+							`FROM users u JOIN posts p ON p.user_id = u.id`,
+							` WHERE u.name like `, c.dialect.Placeholder(0),
+							` ORDER BY u.id, p.id`,
+						),
+						Params: []interface{}{"% Ribeiro"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(chunk []struct {
+							User user `tablename:"u"`
+							Post post `tablename:"p"`
+						}) error {
+							lengths = append(lengths, len(chunk))
+							for _, row := range chunk {
+								users = append(users, row.User)
+								posts = append(posts, row.Post)
+							}
+							return nil
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(posts), 3)
+
+					tt.AssertEqual(t, users[0].ID, joao.ID)
+					tt.AssertEqual(t, users[0].Name, "Thiago Ribeiro")
+					tt.AssertEqual(t, posts[0].Title, "Thiago Post1")
+
+					tt.AssertEqual(t, users[1].ID, thatiana.ID)
+					tt.AssertEqual(t, users[1].Name, "Thatiana Ribeiro")
+					tt.AssertEqual(t, posts[1].Title, "Thatiana Post1")
+
+					tt.AssertEqual(t, users[2].ID, thatiana.ID)
+					tt.AssertEqual(t, users[2].Name, "Thatiana Ribeiro")
+					tt.AssertEqual(t, posts[2].Title, "Thatiana Post2")
+				})
+
+				t.Run("should abort the first iteration when the callback returns an ErrAbortIteration", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+							return ErrAbortIteration
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(users), 2)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, lengths, []int{2})
+				})
+
+				t.Run("should abort the last iteration when the callback returns an ErrAbortIteration", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					returnVals := []error{nil, ErrAbortIteration}
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+
+							return shiftErrSlice(&returnVals)
+						},
+					})
+
+					tt.AssertNoErr(t, err)
+					tt.AssertEqual(t, len(users), 3)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertNotEqual(t, users[2].ID, uint(0))
+					tt.AssertEqual(t, users[2].Name, "User3")
+					tt.AssertEqual(t, lengths, []int{2, 1})
+				})
+
+				t.Run("should return error if the callback returns an error in the first iteration", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+							return errors.New("fake error msg")
+						},
+					})
+
+					tt.AssertNotEqual(t, err, nil)
+					tt.AssertEqual(t, len(users), 2)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, lengths, []int{2})
+				})
+
+				t.Run("should return error if the callback returns an error in the last iteration", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+
+					returnVals := []error{nil, errors.New("fake error msg")}
+					var lengths []int
+					var users []user
+					err = c.QueryChunks(ctx, ChunkParser{
+						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
+						Params: []interface{}{"User%"},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							lengths = append(lengths, len(buffer))
+							users = append(users, buffer...)
+
+							return shiftErrSlice(&returnVals)
+						},
+					})
+
+					tt.AssertNotEqual(t, err, nil)
+					tt.AssertEqual(t, len(users), 3)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertNotEqual(t, users[2].ID, uint(0))
+					tt.AssertEqual(t, users[2].Name, "User3")
+					tt.AssertEqual(t, lengths, []int{2, 1})
+				})
+
+				t.Run("should report error if the input function is invalid", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					funcs := []interface{}{
+						nil,
+						"not a function",
+						func() error {
+							return nil
+						},
+						func(extraInputValue []user, extra []user) error {
+							return nil
+						},
+						func(invalidArgType string) error {
+							return nil
+						},
+						func(missingReturnType []user) {
+						},
+						func(users []user) string {
+							return ""
+						},
+						func(extraReturnValue []user) ([]user, error) {
+							return nil, nil
+						},
+						func(notSliceOfStructs []string) error {
+							return nil
+						},
+					}
+
+					for _, fn := range funcs {
+						err := c.QueryChunks(ctx, ChunkParser{
+							Query:  variation.queryPrefix + `FROM users`,
+							Params: []interface{}{},
+
+							ChunkSize:    2,
+							ForEachChunk: fn,
+						})
+						tt.AssertNotEqual(t, err, nil)
+					}
+				})
+
+				t.Run("should report error if the query is not valid", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+					err := c.QueryChunks(ctx, ChunkParser{
+						Query:  `SELECT * FROM not a valid query`,
+						Params: []interface{}{},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []user) error {
+							return nil
+						},
+					})
+					tt.AssertNotEqual(t, err, nil)
+				})
+
+				t.Run("should report error if using nested struct and the query starts with SELECT", func(t *testing.T) {
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					err := c.QueryChunks(ctx, ChunkParser{
+						Query:  `SELECT * FROM users u JOIN posts p ON u.id = p.user_id`,
+						Params: []interface{}{},
+
+						ChunkSize: 2,
+						ForEachChunk: func(buffer []struct {
+							User user `tablename:"users"`
+							Post post `tablename:"posts"`
+						}) error {
+							return nil
+						},
+					})
+
+					tt.AssertErrContains(t, err, "nested struct", "feature")
+				})
+			})
+		}
+	})
+}
+
+// QueryIterTest runs all tests for making sure the QueryIter function is
+// working for a given adapter and driver.
+func QueryIterTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("QueryIter", func(t *testing.T) {
+		variations := []struct {
+			desc        string
+			queryPrefix string
+		}{
+			{
+				desc:        "with select *",
+				queryPrefix: "SELECT * ",
+			},
+			{
+				desc:        "building the SELECT part of the query internally",
+				queryPrefix: "",
+			},
+		}
+		for _, variation := range variations {
+			t.Run(variation.desc, func(t *testing.T) {
+				t.Run("should iterate over all the results correctly", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1", Address: address{Country: "BR"}})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2", Address: address{Country: "US"}})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User3", Address: address{Country: "UK"}})
+
+					cursor := c.QueryIter(ctx, variation.queryPrefix+`FROM users WHERE name like `+c.dialect.Placeholder(0)+` ORDER BY name ASC`, "User%")
+					defer cursor.Close()
+
+					var users []user
+					var u user
+					for cursor.Next(&u) {
+						users = append(users, u)
+					}
+					tt.AssertNoErr(t, cursor.Err())
+
+					tt.AssertEqual(t, len(users), 3)
+					tt.AssertNotEqual(t, users[0].ID, uint(0))
+					tt.AssertEqual(t, users[0].Name, "User1")
+					tt.AssertEqual(t, users[0].Address.Country, "BR")
+					tt.AssertNotEqual(t, users[1].ID, uint(0))
+					tt.AssertEqual(t, users[1].Name, "User2")
+					tt.AssertEqual(t, users[1].Address.Country, "US")
+					tt.AssertNotEqual(t, users[2].ID, uint(0))
+					tt.AssertEqual(t, users[2].Name, "User3")
+					tt.AssertEqual(t, users[2].Address.Country, "UK")
+				})
+
+				t.Run("should return false on the first call if there are no results", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					cursor := c.QueryIter(ctx, variation.queryPrefix+`FROM users`)
+					defer cursor.Close()
+
+					var u user
+					tt.AssertEqual(t, cursor.Next(&u), false)
+					tt.AssertNoErr(t, cursor.Err())
+				})
+
+				t.Run("should stop iterating after Close is called", func(t *testing.T) {
+					err := createTables(driver, connStr)
+					if err != nil {
+						t.Fatal("could not create test table!, reason:", err.Error())
+					}
+
+					db, closer := newDBAdapter(t)
+					defer closer.Close()
+
+					ctx := context.Background()
+					c := newTestDB(db, driver)
+
+					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+
+					cursor := c.QueryIter(ctx, variation.queryPrefix+`FROM users ORDER BY name ASC`)
+
+					var u user
+					tt.AssertEqual(t, cursor.Next(&u), true)
+					tt.AssertEqual(t, u.Name, "User1")
+
+					tt.AssertNoErr(t, cursor.Close())
+					tt.AssertEqual(t, cursor.Next(&u), false)
+					tt.AssertNoErr(t, cursor.Err())
+
+					// Closing it twice should cause no errors:
+					tt.AssertNoErr(t, cursor.Close())
+				})
+			})
+		}
+
+		t.Run("should query joined tables correctly", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			joao := user{Name: "Thiago Ribeiro"}
+			_ = c.Insert(ctx, usersTable, &joao)
+			_, err = db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, joao.ID, `, 'Thiago Post1')`))
+			tt.AssertNoErr(t, err)
+
+			cursor := c.QueryIter(ctx, fmt.Sprint(
+				`FROM users u JOIN posts p ON p.user_id = u.id`,
+				` WHERE u.name like `, c.dialect.Placeholder(0),
+			), "% Ribeiro")
+			defer cursor.Close()
+
+			var row struct {
+				User user `tablename:"u"`
+				Post post `tablename:"p"`
+			}
+			tt.AssertEqual(t, cursor.Next(&row), true)
+			tt.AssertEqual(t, row.User.Name, "Thiago Ribeiro")
+			tt.AssertEqual(t, row.Post.Title, "Thiago Post1")
+			tt.AssertEqual(t, cursor.Next(&row), false)
+			tt.AssertNoErr(t, cursor.Err())
+		})
+
+		t.Run("should report error if the query is not valid", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			cursor := c.QueryIter(ctx, `SELECT * FROM not a valid query`)
+			defer cursor.Close()
+
+			var u user
+			tt.AssertEqual(t, cursor.Next(&u), false)
+			tt.AssertNotEqual(t, cursor.Err(), nil)
+		})
+
+		t.Run("should report error if the record is not a pointer to struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			cursor := c.QueryIter(ctx, `FROM users`)
+			defer cursor.Close()
+
+			var u user
+			tt.AssertEqual(t, cursor.Next(u), false)
+			tt.AssertErrContains(t, cursor.Err(), "ksql", "expected", "pointer to struct", "user")
+		})
+
+		t.Run("should report error if the record type changes between calls", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+			_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+
+			cursor := c.QueryIter(ctx, `FROM users`)
+			defer cursor.Close()
+
+			var u user
+			tt.AssertEqual(t, cursor.Next(&u), true)
+
+			var p post
+			tt.AssertEqual(t, cursor.Next(&p), false)
+			tt.AssertErrContains(t, cursor.Err(), "ksql", "same record type")
+		})
+	})
+}
+
+// TransactionTest runs all tests for making sure the Transaction function is
+// working for a given adapter and driver.
+func TransactionTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Transaction", func(t *testing.T) {
+		t.Run("should query a single row correctly", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
+			_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
+
+			var users []user
+			err = c.Transaction(ctx, func(db Provider) error {
+				db.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
+				return nil
+			})
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, len(users), 2)
+			tt.AssertEqual(t, users[0].Name, "User1")
+			tt.AssertEqual(t, users[1].Name, "User2")
+		})
+
+		t.Run("should work normally in nested transactions", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{
+				Name: "User1",
+			}
+			err = c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, 0)
+
+			var updatedUser user
+			err = c.Transaction(ctx, func(db Provider) error {
+				u.Age = 42
+				err = db.Patch(ctx, usersTable, &u)
+				if err != nil {
+					return err
+				}
+
+				return db.Transaction(ctx, func(db Provider) error {
+					return db.QueryOne(ctx, &updatedUser, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
+				})
+			})
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, updatedUser.ID, u.ID)
+			tt.AssertEqual(t, updatedUser.Age, 42)
+		})
+
+		t.Run("should rollback when there are errors", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u1 := user{Name: "User1", Age: 42}
+			u2 := user{Name: "User2", Age: 42}
+			_ = c.Insert(ctx, usersTable, &u1)
+			_ = c.Insert(ctx, usersTable, &u2)
+
+			err = c.Transaction(ctx, func(db Provider) error {
+				err = db.Insert(ctx, usersTable, &user{Name: "User3"})
+				tt.AssertNoErr(t, err)
+				err = db.Insert(ctx, usersTable, &user{Name: "User4"})
+				tt.AssertNoErr(t, err)
+				_, err = db.Exec(ctx, "UPDATE users SET age = 22")
+				tt.AssertNoErr(t, err)
+
+				return errors.New("fake-error")
+			})
+			tt.AssertNotEqual(t, err, nil)
+			tt.AssertEqual(t, err.Error(), "fake-error")
+
+			var users []user
+			err = c.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, users, []user{u1, u2})
+		})
+
+		t.Run("should retry the transaction if the DBAdapter implements TxRetrier", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			retryableErr := errors.New("fake-retryable-error")
+			var retriedAttempts []int
+			c.db = mockTxRetrier{
+				mockTxBeginner: mockTxBeginner{
+					DBAdapter: db,
+					BeginTxFn: db.(TxBeginner).BeginTx,
+				},
+				RetryTxFn: func(ctx context.Context, attempt int, err error) bool {
+					retriedAttempts = append(retriedAttempts, attempt)
+					return err == retryableErr
+				},
+			}
+
+			numCalls := 0
+			err = c.Transaction(ctx, func(db Provider) error {
+				numCalls++
+				err := db.Insert(ctx, usersTable, &user{Name: fmt.Sprint("Retried User ", numCalls)})
+				tt.AssertNoErr(t, err)
+
+				if numCalls < 3 {
+					return retryableErr
+				}
+				return nil
+			})
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, numCalls, 3)
+			tt.AssertEqual(t, retriedAttempts, []int{1, 2})
+
+			// Only the last attempt should have been committed:
+			var users []user
+			err = c.Query(ctx, &users, "SELECT * FROM users WHERE name LIKE 'Retried User%'")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 1)
+			tt.AssertEqual(t, users[0].Name, "Retried User 3")
+
+			// Errors that shouldn't be retried are returned immediately:
+			numCalls = 0
+			retriedAttempts = nil
+			err = c.Transaction(ctx, func(db Provider) error {
+				numCalls++
+				return errors.New("fake-error")
+			})
+			tt.AssertErrContains(t, err, "fake-error")
+			tt.AssertEqual(t, numCalls, 1)
+			tt.AssertEqual(t, retriedAttempts, []int{1})
+		})
+
+		t.Run("should start the transaction with the input options", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			isolation := Serializable
+
+			// The duckdb driver only supports the default isolation level:
+			if driver == "duckdb" {
+				err = c.TransactionWithOptions(ctx, TxOptions{
+					Isolation: Serializable,
+				}, func(db Provider) error {
+					return nil
+				})
+				tt.AssertErrContains(t, err, "isolation")
+
+				isolation = DefaultIsolation
+			}
+
+			err = c.TransactionWithOptions(ctx, TxOptions{
+				Isolation: isolation,
+			}, func(db Provider) error {
+				return db.Insert(ctx, usersTable, &user{Name: "User1", Age: 42})
+			})
+			tt.AssertNoErr(t, err)
+
+			var u user
+			err = c.QueryOne(ctx, &u, "FROM users WHERE name = 'User1'")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, u.Age, 42)
+
+			// The sqlite3 and sqlserver drivers ignore the ReadOnly option:
+			if driver == "sqlite3" || driver == "sqlserver" {
+				return
+			}
+
+			err = c.TransactionWithOptions(ctx, TxOptions{
+				ReadOnly: true,
+			}, func(db Provider) error {
+				return db.Insert(ctx, usersTable, &user{Name: "User2", Age: 42})
+			})
+			tt.AssertNotEqual(t, err, nil)
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users WHERE name = 'User2'")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 0)
+		})
+
+		t.Run("should commit and rollback transactions started with Begin", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			tx, err := c.Begin(ctx)
+			tt.AssertNoErr(t, err)
+			err = tx.Insert(ctx, usersTable, &user{Name: "User1", Age: 42})
+			tt.AssertNoErr(t, err)
+			err = tx.Commit(ctx)
+			tt.AssertNoErr(t, err)
+
+			// Rolling back after the commit should have no effect:
+			err = tx.Rollback(ctx)
+			tt.AssertNoErr(t, err)
+
+			tx, err = c.Begin(ctx)
+			tt.AssertNoErr(t, err)
+			err = tx.Insert(ctx, usersTable, &user{Name: "User2", Age: 42})
+			tt.AssertNoErr(t, err)
+			err = tx.Rollback(ctx)
+			tt.AssertNoErr(t, err)
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users ORDER BY id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 1)
+			tt.AssertEqual(t, users[0].Name, "User1")
+
+			// Nested transactions are not supported:
+			tx, err = c.Begin(ctx)
+			tt.AssertNoErr(t, err)
+			defer tx.Rollback(ctx)
+
+			_, err = tx.Begin(ctx)
+			tt.AssertErrContains(t, err, "nested transactions")
+		})
+
+		t.Run("should use the transaction injected on the context", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.Transaction(ctx, func(tx Provider) error {
+				ctx := InjectTx(ctx, tx)
+				tt.AssertEqual(t, ProviderFromContext(ctx, c), tx)
+
+				err := c.Insert(ctx, usersTable, &user{Name: "User1", Age: 42})
+				tt.AssertNoErr(t, err)
+
+				var u user
+				err = c.QueryOne(ctx, &u, "FROM users WHERE name = 'User1'")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, u.Age, 42)
+
+				// Nested transactions should also reuse the injected transaction:
+				err = c.Transaction(ctx, func(nestedTx Provider) error {
+					return nestedTx.Insert(ctx, usersTable, &user{Name: "User2", Age: 42})
+				})
+				tt.AssertNoErr(t, err)
+
+				return errors.New("fake-error")
+			})
+			tt.AssertErrContains(t, err, "fake-error")
+
+			tt.AssertEqual(t, ProviderFromContext(ctx, c), c)
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 0)
+		})
+
+		t.Run("should rollback when the fn call panics", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u1 := user{Name: "User1", Age: 42}
+			u2 := user{Name: "User2", Age: 42}
+			_ = c.Insert(ctx, usersTable, &u1)
+			_ = c.Insert(ctx, usersTable, &u2)
+
+			panicPayload := tt.PanicHandler(func() {
+				c.Transaction(ctx, func(db Provider) error {
+					err = db.Insert(ctx, usersTable, &user{Name: "User3"})
+					tt.AssertNoErr(t, err)
+					err = db.Insert(ctx, usersTable, &user{Name: "User4"})
+					tt.AssertNoErr(t, err)
+					_, err = db.Exec(ctx, "UPDATE users SET age = 22")
+					tt.AssertNoErr(t, err)
+
+					panic("fakePanicPayload")
+				})
+			})
+			tt.AssertEqual(t, panicPayload, "fakePanicPayload")
+
+			var users []user
+			err = c.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, users, []user{u1, u2})
+		})
+
+		t.Run("should handle rollback errors when the fn call panics", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			cMock := mockTxBeginner{
+				DBAdapter: c.db,
+				BeginTxFn: func(ctx context.Context) (Tx, error) {
+					return mockTx{
+						DBAdapter: c.db,
+						RollbackFn: func(ctx context.Context) error {
+							return fmt.Errorf("fakeRollbackErrMsg")
+						},
+					}, nil
+				},
+			}
+			c.db = cMock
+
+			panicPayload := tt.PanicHandler(func() {
+				c.Transaction(ctx, func(db Provider) error {
+					panic("fakePanicPayload")
+				})
+			})
+			err, ok := panicPayload.(error)
+			tt.AssertEqual(t, ok, true)
+			tt.AssertErrContains(t, err, "fakePanicPayload", "fakeRollbackErrMsg")
+		})
+
+		t.Run("should handle rollback errors when fn returns an error", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			cMock := mockTxBeginner{
+				DBAdapter: c.db,
+				BeginTxFn: func(ctx context.Context) (Tx, error) {
+					return mockTx{
+						DBAdapter: c.db,
+						RollbackFn: func(ctx context.Context) error {
+							return fmt.Errorf("fakeRollbackErrMsg")
+						},
+					}, nil
+				},
+			}
+			c.db = cMock
+
+			err = c.Transaction(ctx, func(db Provider) error {
+				return fmt.Errorf("fakeTransactionErrMsg")
+			})
+			tt.AssertErrContains(t, err, "fakeTransactionErrMsg", "fakeRollbackErrMsg")
+		})
+
+		t.Run("should report error when BeginTx() fails", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			cMock := mockTxBeginner{
+				DBAdapter: c.db,
+				BeginTxFn: func(ctx context.Context) (Tx, error) {
+					return nil, fmt.Errorf("fakeErrMsg")
+				},
+			}
+			c.db = cMock
+
+			err := c.Transaction(ctx, func(db Provider) error {
+				return nil
+			})
+			tt.AssertErrContains(t, err, "KSQL", "fakeErrMsg")
+		})
+
+		t.Run("should report error if DBAdapter can't create transactions", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			c.db = mockDBAdapter{}
+
+			err = c.Transaction(ctx, func(db Provider) error {
+				return nil
+			})
+			tt.AssertErrContains(t, err, "KSQL", "can't start transaction", "DBAdapter", "TxBeginner")
+		})
+	})
+}
+
+// RollbackTxTest runs all tests for making sure the RunInRollbackTx
+// function is working for a given adapter and driver.
+func RollbackTxTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("RunInRollbackTx", func(t *testing.T) {
+		t.Run("should rollback all the changes at the end", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u1 := user{Name: "User1", Age: 42}
+			_ = c.Insert(ctx, usersTable, &u1)
+
+			RunInRollbackTx(t, c, func(db Provider) {
+				err := db.Insert(ctx, usersTable, &user{Name: "User2"})
+				tt.AssertNoErr(t, err)
+
+				err = db.Delete(ctx, usersTable, u1.ID)
+				tt.AssertNoErr(t, err)
+
+				var users []user
+				err = db.Query(ctx, &users, "FROM users")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, len(users), 1)
+				tt.AssertEqual(t, users[0].Name, "User2")
+			})
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, users, []user{u1})
+		})
+
+		t.Run("should use savepoints for the nested transactions", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			if driver == "duckdb" {
+				RunInRollbackTx(t, c, func(db Provider) {
+					err := db.Transaction(ctx, func(db Provider) error {
+						return db.Insert(ctx, usersTable, &user{Name: "User1"})
+					})
+					tt.AssertErrContains(t, err, "savepoints", "not supported", driver)
+
+					_, err = db.(DB).Begin(ctx)
+					tt.AssertErrContains(t, err, "savepoints", "not supported", driver)
+				})
+				return
+			}
+
+			RunInRollbackTx(t, c, func(db Provider) {
+				var committed bool
+				err := db.Transaction(ctx, func(db Provider) error {
+					err := OnCommit(ctx, db, func(ctx context.Context) {
+						committed = true
+					})
 					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(users), 3)
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertNotEqual(t, users[2].ID, uint(0))
-					tt.AssertEqual(t, users[2].Name, "User3")
-					tt.AssertEqual(t, lengths, []int{2, 1})
+					return db.Insert(ctx, usersTable, &user{Name: "User1"})
+				})
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, committed, true)
+
+				err = db.Transaction(ctx, func(db Provider) error {
+					err := db.Insert(ctx, usersTable, &user{Name: "User2"})
+					tt.AssertNoErr(t, err)
+
+					return db.Transaction(ctx, func(db Provider) error {
+						return errors.New("fake-error")
+					})
 				})
+				tt.AssertErrContains(t, err, "fake-error")
+
+				tx, err := db.(DB).Begin(ctx)
+				tt.AssertNoErr(t, err)
+				err = tx.Insert(ctx, usersTable, &user{Name: "User3"})
+				tt.AssertNoErr(t, err)
+				tt.AssertNoErr(t, tx.Rollback(ctx))
+
+				var users []user
+				err = db.Query(ctx, &users, "FROM users")
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, len(users), 1)
+				tt.AssertEqual(t, users[0].Name, "User1")
+			})
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 0)
+		})
+	})
+}
+
+// LoadFixturesTest runs all tests for making sure the LoadFixtures
+// function is working for a given adapter and driver.
+func LoadFixturesTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("LoadFixtures", func(t *testing.T) {
+		writeFixtures := func(t *testing.T, files map[string]string) (dir string) {
+			dir, err := ioutil.TempDir("", "ksql-fixtures")
+			tt.AssertNoErr(t, err)
+			for name, content := range files {
+				err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+				tt.AssertNoErr(t, err)
+			}
+			return dir
+		}
+
+		t.Run("should replace the rows of the tables with the fixtures", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.Insert(ctx, usersTable, &user{Name: "Old User"})
+			tt.AssertNoErr(t, err)
+
+			dir := writeFixtures(t, map[string]string{
+				"users.json": `[
+					{"name": "Ana", "age": 20, "address": {"city": "Rio"}},
+					{"name": "Bia", "age": 30}
+				]`,
+				"posts.json": `[{"user_id": 42, "title": "Fixture Post"}]`,
+			})
+			defer os.RemoveAll(dir)
+
+			err = LoadFixtures(ctx, c, dir, usersTable.WithStruct(user{}))
+			tt.AssertNoErr(t, err)
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users ORDER BY name")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 2)
+			tt.AssertEqual(t, users[0].Name, "Ana")
+			tt.AssertEqual(t, users[0].Age, 20)
+			tt.AssertEqual(t, users[0].Address, address{City: "Rio"})
+			tt.AssertEqual(t, users[1].Name, "Bia")
+
+			var posts []post
+			err = c.Query(ctx, &posts, "FROM posts")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(posts), 1)
+			tt.AssertEqual(t, posts[0].UserID, uint(42))
+			tt.AssertEqual(t, posts[0].Title, "Fixture Post")
+		})
+
+		t.Run("should load the tables in the order of their foreign keys", func(t *testing.T) {
+			if driver == "duckdb" {
+				t.Skip("the foreign keys are not detected on DuckDB")
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			for _, query := range []string{
+				"DROP TABLE IF EXISTS fixture_articles",
+				"DROP TABLE IF EXISTS fixture_writers",
+				"CREATE TABLE fixture_writers (id INT PRIMARY KEY, name VARCHAR(50))",
+				`CREATE TABLE fixture_articles (
+					id INT PRIMARY KEY,
+					writer_id INT,
+					FOREIGN KEY (writer_id) REFERENCES fixture_writers(id)
+				)`,
+			} {
+				_, err := c.Exec(ctx, query)
+				tt.AssertNoErr(t, err)
+			}
+
+			dir := writeFixtures(t, map[string]string{
+				"fixture_writers.json":  `[{"id": 1, "name": "Ana"}]`,
+				"fixture_articles.json": `[{"id": 1, "writer_id": 1}]`,
+			})
+			defer os.RemoveAll(dir)
+
+			// Loading twice ensures the rows are also deleted in the right order:
+			for i := 0; i < 2; i++ {
+				err := LoadFixtures(ctx, c, dir)
+				tt.AssertNoErr(t, err)
+			}
+
+			var rows []map[string]interface{}
+			err := c.QueryMaps(ctx, &rows, "SELECT writer_id FROM fixture_articles")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 1)
+		})
+
+		t.Run("should load tables whose IDs have no sequence", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			// On Postgres there is no MAX() for UUIDs, so the
+			// sequence of these IDs must not be updated:
+			idType := "VARCHAR(36)"
+			if driver == "postgres" {
+				idType = "UUID"
+			}
+
+			for _, query := range []string{
+				"DROP TABLE IF EXISTS fixture_tokens",
+				"CREATE TABLE fixture_tokens (id " + idType + " PRIMARY KEY, name VARCHAR(50))",
+			} {
+				_, err := c.Exec(ctx, query)
+				tt.AssertNoErr(t, err)
+			}
+
+			dir := writeFixtures(t, map[string]string{
+				"fixture_tokens.json": `[{"id": "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "name": "Ana"}]`,
+			})
+			defer os.RemoveAll(dir)
+
+			err := LoadFixtures(ctx, c, dir)
+			tt.AssertNoErr(t, err)
+
+			var rows []map[string]interface{}
+			err = c.QueryMaps(ctx, &rows, "SELECT name FROM fixture_tokens")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(rows), 1)
+		})
+	})
+}
+
+// EnsureTableTest runs the tests for the EnsureTable function
+func EnsureTableTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("EnsureTable", func(t *testing.T) {
+		type record struct {
+			ID        int       `ksql:"id"`
+			Name      string    `ksql:"name,type=varchar(50),notnull"`
+			Email     string    `ksql:"email,type=varchar(100),unique"`
+			Age       *int      `ksql:"age"`
+			Score     float64   `ksql:"score"`
+			Active    bool      `ksql:"active"`
+			Address   address   `ksql:"address,json"`
+			CreatedAt time.Time `ksql:"created_at,timeNowUTC"`
+		}
+
+		t.Run("should create a table that can store the struct", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-				// xxx
-				t.Run("should query joined tables correctly", func(t *testing.T) {
-					// This test only makes sense with no query prefix
-					if variation.queryPrefix != "" {
-						return
-					}
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			c.Exec(ctx, "DROP TABLE ensured_records")
 
-					joao := user{
-						Name: "Thiago Ribeiro",
-						Age:  24,
-					}
-					thatiana := user{
-						Name: "Thatiana Ribeiro",
-						Age:  20,
-					}
+			table := NewTable("ensured_records")
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
-					_ = c.Insert(ctx, usersTable, &joao)
-					_ = c.Insert(ctx, usersTable, &thatiana)
+			// Running it twice ensures existing tables are ignored:
+			for i := 0; i < 2; i++ {
+				err := EnsureTable(ctx, c, table, &record{})
+				tt.AssertNoErr(t, err)
+			}
 
-					_, err := db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, thatiana.ID, `, 'Thatiana Post1')`))
-					tt.AssertNoErr(t, err)
-					_, err = db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, thatiana.ID, `, 'Thatiana Post2')`))
-					tt.AssertNoErr(t, err)
-					_, err = db.ExecContext(ctx, fmt.Sprint(`INSERT INTO posts (user_id, title) VALUES (`, joao.ID, `, 'Thiago Post1')`))
-					tt.AssertNoErr(t, err)
+			age := 22
+			err := c.Insert(ctx, table, &record{
+				Name:    "Ana",
+				Email:   "ana@example.com",
+				Age:     &age,
+				Score:   7.5,
+				Active:  true,
+				Address: address{City: "Rio"},
+			})
+			tt.AssertNoErr(t, err)
 
-					var lengths []int
-					var users []user
-					var posts []post
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query: fmt.Sprint(
-							`FROM users u JOIN posts p ON p.user_id = u.id`,
-							` WHERE u.name like `, c.dialect.Placeholder(0),
-							` ORDER BY u.id, p.id`,
-						),
-						Params: []interface{}{"% Ribeiro"},
+			var ana record
+			err = c.QueryOne(ctx, &ana, "FROM ensured_records WHERE name = "+c.dialect.Placeholder(0), "Ana")
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, ana.ID, 0)
+			tt.AssertEqual(t, ana.Email, "ana@example.com")
+			tt.AssertEqual(t, *ana.Age, 22)
+			tt.AssertEqual(t, ana.Score, 7.5)
+			tt.AssertEqual(t, ana.Active, true)
+			tt.AssertEqual(t, ana.Address.City, "Rio")
+			tt.AssertNotEqual(t, ana.CreatedAt, time.Time{})
+
+			err = c.Insert(ctx, table, &record{Name: "Bia", Email: "ana@example.com"})
+			var uniqueErr ErrUniqueViolation
+			tt.AssertEqual(t, errors.As(err, &uniqueErr), true)
+		})
+	})
+}
 
-						ChunkSize: 2,
-						ForEachChunk: func(chunk []struct {
-							User user `tablename:"u"`
-							Post post `tablename:"p"`
-						}) error {
-							lengths = append(lengths, len(chunk))
-							for _, row := range chunk {
-								users = append(users, row.User)
-								posts = append(posts, row.Post)
-							}
-							return nil
-						},
-					})
+// AuditTest runs all tests for making sure the audited tables
+// record their writes on the audit table
+func AuditTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	auditedUsersTable := usersTable.WithAudit("audit_log")
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(posts), 3)
+	t.Run("Audit", func(t *testing.T) {
+		t.Run("should record the inserts, updates and deletes", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-					tt.AssertEqual(t, users[0].ID, joao.ID)
-					tt.AssertEqual(t, users[0].Name, "Thiago Ribeiro")
-					tt.AssertEqual(t, posts[0].Title, "Thiago Post1")
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					tt.AssertEqual(t, users[1].ID, thatiana.ID)
-					tt.AssertEqual(t, users[1].Name, "Thatiana Ribeiro")
-					tt.AssertEqual(t, posts[1].Title, "Thatiana Post1")
+			ctx := WithActor(context.Background(), "admin:1")
+			c := newTestDB(db, driver)
 
-					tt.AssertEqual(t, users[2].ID, thatiana.ID)
-					tt.AssertEqual(t, users[2].Name, "Thatiana Ribeiro")
-					tt.AssertEqual(t, posts[2].Title, "Thatiana Post2")
-				})
+			u := user{Name: "Jane", Age: 42}
+			err = c.Insert(ctx, auditedUsersTable, &u)
+			tt.AssertNoErr(t, err)
 
-				t.Run("should abort the first iteration when the callback returns an ErrAbortIteration", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			err = c.Patch(ctx, auditedUsersTable, struct {
+				ID   uint   `ksql:"id"`
+				Name string `ksql:"name"`
+			}{ID: u.ID, Name: "Janet"})
+			tt.AssertNoErr(t, err)
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			err = c.Delete(ctx, auditedUsersTable, u.ID)
+			tt.AssertNoErr(t, err)
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			var entries []AuditEntry
+			err = c.Query(ctx, &entries, "FROM audit_log ORDER BY id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(entries), 3)
+
+			recordID := fmt.Sprintf(`{"id":%d}`, u.ID)
+			for i, operation := range []string{AuditInsert, AuditUpdate, AuditDelete} {
+				tt.AssertEqual(t, entries[i].TableName, "users")
+				tt.AssertEqual(t, entries[i].Operation, operation)
+				tt.AssertEqual(t, entries[i].RecordID, recordID)
+				tt.AssertEqual(t, entries[i].Actor, "admin:1")
+				tt.AssertEqual(t, entries[i].CreatedAt.IsZero(), false)
+			}
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+			tt.AssertEqual(t, entries[0].Before == nil, true)
+			tt.AssertEqual(t, entries[0].After["name"], "Jane")
+			tt.AssertEqual(t, entries[0].After["age"], float64(42))
 
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+			tt.AssertEqual(t, entries[1].Before["name"], "Jane")
+			tt.AssertEqual(t, entries[1].After["name"], "Janet")
+			tt.AssertEqual(t, entries[1].After["age"], float64(42))
+
+			tt.AssertEqual(t, entries[2].Before["name"], "Janet")
+			tt.AssertEqual(t, entries[2].After == nil, true)
+		})
+
+		t.Run("should not record the writes that don't find the row", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.Delete(ctx, auditedUsersTable, 4200)
+			tt.AssertEqual(t, IsNotFound(err), true)
+
+			var entries []AuditEntry
+			err = c.Query(ctx, &entries, "FROM audit_log")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(entries), 0)
+		})
+
+		t.Run("should roll back the write if the audit entry fails", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Jane", Age: 42}
+			err = c.Insert(ctx, usersTable.WithAudit("non_existing_table"), &u)
+			tt.AssertNotEqual(t, err, nil)
+
+			var users []user
+			err = c.Query(ctx, &users, "FROM users")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(users), 0)
+		})
+
+		t.Run("should report the methods that don't support audited tables", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := c.DeleteMany(ctx, auditedUsersTable, []uint{1, 2})
+			tt.AssertErrContains(t, err, "DeleteMany", "audited", "audit_log")
+		})
+	})
+}
+
+// LoaderTest runs all tests for making sure the ksql.Loader
+// batches the records it loads for a given adapter and driver.
+func LoaderTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	loaderUsersTable := NewTable("users").WithStruct(user{})
+
+	t.Run("Loader", func(t *testing.T) {
+		t.Run("should load the records of concurrent calls with a single query", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var ids []uint
+			for _, name := range []string{"Jane", "John", "Bia"} {
+				u := user{Name: name}
+				err = c.Insert(ctx, usersTable, &u)
+				tt.AssertNoErr(t, err)
+				ids = append(ids, u.ID)
+			}
+
+			var numQueries int32
+			c = newTestDB(mockDBAdapter{
+				ExecContextFn: db.ExecContext,
+				QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+					atomic.AddInt32(&numQueries, 1)
+					return db.QueryContext(ctx, query, params...)
+				},
+			}, driver)
+
+			loader, err := NewLoader(c, loaderUsersTable, LoaderConfig{Wait: 50 * time.Millisecond})
+			tt.AssertNoErr(t, err)
+
+			loadIDs := []uint{ids[0], ids[2], ids[0], 4200}
+			users := make([]user, len(loadIDs))
+			errs := make([]error, len(loadIDs))
+			var wg sync.WaitGroup
+			for i, id := range loadIDs {
+				wg.Add(1)
+				go func(i int, id uint) {
+					defer wg.Done()
+					errs[i] = loader.Load(ctx, id, &users[i])
+				}(i, id)
+			}
+			wg.Wait()
+
+			tt.AssertEqual(t, atomic.LoadInt32(&numQueries), int32(1))
+
+			tt.AssertNoErr(t, errs[0])
+			tt.AssertEqual(t, users[0].Name, "Jane")
+			tt.AssertNoErr(t, errs[1])
+			tt.AssertEqual(t, users[1].Name, "Bia")
+			tt.AssertNoErr(t, errs[2])
+			tt.AssertEqual(t, users[2].Name, "Jane")
+			tt.AssertEqual(t, IsNotFound(errs[3]), true)
+
+			// The records already loaded are not queried again:
+			var u user
+			err = loader.Load(ctx, ids[2], &u)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, u.Name, "Bia")
+			tt.AssertEqual(t, atomic.LoadInt32(&numQueries), int32(1))
+		})
+
+		t.Run("should run the batches reaching the max size immediately", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			u := user{Name: "Jane"}
+			err = c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+
+			loader, err := NewLoader(c, loaderUsersTable, LoaderConfig{Wait: time.Hour, MaxBatchSize: 1})
+			tt.AssertNoErr(t, err)
+
+			var loaded user
+			err = loader.Load(ctx, u.ID, &loaded)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, loaded.Name, "Jane")
+		})
+	})
+}
+
+// LoadRelatedTest runs all tests for making sure the LoadRelated
+// function is working for a given adapter and driver.
+func LoadRelatedTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	postsTable := NewTable("posts")
+
+	t.Run("LoadRelated", func(t *testing.T) {
+		t.Run("should load belongs-to relationships", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			jane := user{Name: "Jane"}
+			err = c.Insert(ctx, usersTable, &jane)
+			tt.AssertNoErr(t, err)
+			john := user{Name: "John"}
+			err = c.Insert(ctx, usersTable, &john)
+			tt.AssertNoErr(t, err)
+			_ = c.Insert(ctx, usersTable, &user{Name: "Bia"})
+
+			posts := []*post{
+				{UserID: jane.ID, Title: "post1"},
+				{UserID: john.ID, Title: "post2"},
+				{UserID: jane.ID, Title: "post3"},
+			}
+
+			var numQueries int32
+			c = newTestDB(mockDBAdapter{
+				ExecContextFn: db.ExecContext,
+				QueryContextFn: func(ctx context.Context, query string, params ...interface{}) (Rows, error) {
+					atomic.AddInt32(&numQueries, 1)
+					return db.QueryContext(ctx, query, params...)
+				},
+			}, driver)
+
+			var authorsByID map[int]user
+			err = LoadRelated(ctx, c, posts, "user_id", usersTable, "id", &authorsByID)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, atomic.LoadInt32(&numQueries), int32(1))
+			tt.AssertEqual(t, len(authorsByID), 2)
+			tt.AssertEqual(t, authorsByID[int(jane.ID)].Name, "Jane")
+			tt.AssertEqual(t, authorsByID[int(john.ID)].Name, "John")
+		})
+
+		t.Run("should load has-many relationships", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			jane := user{Name: "Jane"}
+			err = c.Insert(ctx, usersTable, &jane)
+			tt.AssertNoErr(t, err)
+			john := user{Name: "John"}
+			err = c.Insert(ctx, usersTable, &john)
+			tt.AssertNoErr(t, err)
+			bia := user{Name: "Bia"}
+			err = c.Insert(ctx, usersTable, &bia)
+			tt.AssertNoErr(t, err)
+
+			for _, p := range []post{
+				{UserID: jane.ID, Title: "post1"},
+				{UserID: john.ID, Title: "post2"},
+				{UserID: jane.ID, Title: "post3"},
+				{UserID: bia.ID, Title: "post4"},
+			} {
+				err = c.Insert(ctx, postsTable, &p)
+				tt.AssertNoErr(t, err)
+			}
+
+			var postsByUserID map[uint][]post
+			err = LoadRelated(ctx, c, []user{jane, john}, "id", postsTable, "user_id", &postsByUserID)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, len(postsByUserID), 2)
+			titles := []string{}
+			for _, p := range postsByUserID[jane.ID] {
+				titles = append(titles, p.Title)
+			}
+			sort.Strings(titles)
+			tt.AssertEqual(t, titles, []string{"post1", "post3"})
+			tt.AssertEqual(t, len(postsByUserID[john.ID]), 1)
+			tt.AssertEqual(t, postsByUserID[john.ID][0].Title, "post2")
+		})
+
+		t.Run("should report duplicated keys on belongs-to relationships", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
-							return ErrAbortIteration
-						},
-					})
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(users), 2)
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertEqual(t, lengths, []int{2})
-				})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-				t.Run("should abort the last iteration when the callback returns an ErrAbortIteration", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			jane := user{Name: "Jane"}
+			err = c.Insert(ctx, usersTable, &jane)
+			tt.AssertNoErr(t, err)
+			_ = c.Insert(ctx, postsTable, &post{UserID: jane.ID, Title: "post1"})
+			_ = c.Insert(ctx, postsTable, &post{UserID: jane.ID, Title: "post2"})
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			var postByUserID map[uint]post
+			err = LoadRelated(ctx, c, []user{jane}, "id", postsTable, "user_id", &postByUserID)
+			tt.AssertErrContains(t, err, "more than one record", "posts", "has-many")
+		})
+	})
+}
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+// MapUntaggedFieldsTest runs all tests for making sure the MapUntaggedFields
+// option is working for a given adapter and driver.
+func MapUntaggedFieldsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	type untaggedUser struct {
+		ID      uint
+		Name    string
+		Age     int
+		Address address `ksql:"address,json"`
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+		Ignored string `ksql:"-"`
+	}
 
-					returnVals := []error{nil, ErrAbortIteration}
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+	type untaggedPost struct {
+		ID     int
+		UserID uint
+		Title  string
+	}
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
+	t.Run("MapUntaggedFields", func(t *testing.T) {
+		t.Run("should insert, patch and query structs without tags", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-							return shiftErrSlice(&returnVals)
-						},
-					})
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					tt.AssertNoErr(t, err)
-					tt.AssertEqual(t, len(users), 3)
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertNotEqual(t, users[2].ID, uint(0))
-					tt.AssertEqual(t, users[2].Name, "User3")
-					tt.AssertEqual(t, lengths, []int{2, 1})
-				})
+			ctx := context.Background()
+			c, err := NewWithConfig(db, driver, Config{
+				MapUntaggedFields: true,
+			})
+			tt.AssertNoErr(t, err)
 
-				t.Run("should return error if the callback returns an error in the first iteration", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+			u := untaggedUser{
+				Name:    "Jane",
+				Age:     22,
+				Address: address{City: "Rio"},
+				Ignored: "ignored",
+			}
+			err = c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			err = c.Patch(ctx, usersTable, &untaggedUser{ID: u.ID, Name: "Jane Doe", Age: 23})
+			tt.AssertNoErr(t, err)
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			var loaded untaggedUser
+			err = c.QueryOne(ctx, &loaded, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, loaded, untaggedUser{
+				ID:   u.ID,
+				Name: "Jane Doe",
+				Age:  23,
+			})
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+			post := untaggedPost{UserID: u.ID, Title: "post1"}
+			err = c.Insert(ctx, NewTable("posts"), &post)
+			tt.AssertNoErr(t, err)
 
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+			var posts []untaggedPost
+			err = c.Query(ctx, &posts, "FROM posts WHERE user_id = "+c.dialect.Placeholder(0), u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, posts, []untaggedPost{post})
+		})
+	})
+}
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
-							return errors.New("fake error msg")
-						},
-					})
+// displayName is used for testing the attributes
+// declared with interfaces by PointerAndInterfaceFieldsTest
+type displayName interface {
+	Display() string
+}
 
-					tt.AssertNotEqual(t, err, nil)
-					tt.AssertEqual(t, len(users), 2)
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertEqual(t, lengths, []int{2})
-				})
+// upperCaseName is the concrete type of
+// the displayName interface on the tests
+type upperCaseName struct {
+	upperCaseScanner
+}
 
-				t.Run("should return error if the callback returns an error in the last iteration", func(t *testing.T) {
-					err := createTables(driver, connStr)
-					if err != nil {
-						t.Fatal("could not create test table!, reason:", err.Error())
-					}
+func (u upperCaseName) Display() string {
+	return u.value
+}
 
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+func (u upperCaseName) Value() (driver.Value, error) {
+	return u.value, nil
+}
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+var registerDisplayNameOnce sync.Once
 
-					_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-					_ = c.Insert(ctx, usersTable, &user{Name: "User3"})
+// PointerAndInterfaceFieldsTest runs all tests for making sure the nested
+// structs declared as pointers and the attributes declared as interfaces
+// are working for a given adapter and driver.
+func PointerAndInterfaceFieldsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	registerDisplayNameOnce.Do(func() {
+		RegisterConcreteType((*displayName)(nil), upperCaseName{})
+	})
 
-					returnVals := []error{nil, errors.New("fake error msg")}
-					var lengths []int
-					var users []user
-					err = c.QueryChunks(ctx, ChunkParser{
-						Query:  variation.queryPrefix + `from users where name like ` + c.dialect.Placeholder(0) + ` order by name asc;`,
-						Params: []interface{}{"User%"},
+	t.Run("PointerAndInterfaceFields", func(t *testing.T) {
+		t.Run("should set the nested pointers to nil when all their columns are NULL", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							lengths = append(lengths, len(buffer))
-							users = append(users, buffer...)
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-							return shiftErrSlice(&returnVals)
-						},
-					})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-					tt.AssertNotEqual(t, err, nil)
-					tt.AssertEqual(t, len(users), 3)
-					tt.AssertNotEqual(t, users[0].ID, uint(0))
-					tt.AssertEqual(t, users[0].Name, "User1")
-					tt.AssertNotEqual(t, users[1].ID, uint(0))
-					tt.AssertEqual(t, users[1].Name, "User2")
-					tt.AssertNotEqual(t, users[2].ID, uint(0))
-					tt.AssertEqual(t, users[2].Name, "User3")
-					tt.AssertEqual(t, lengths, []int{2, 1})
-				})
+			jane := user{Name: "Jane", Address: address{City: "Rio"}}
+			err = c.Insert(ctx, usersTable, &jane)
+			tt.AssertNoErr(t, err)
+			john := user{Name: "John"}
+			err = c.Insert(ctx, usersTable, &john)
+			tt.AssertNoErr(t, err)
 
-				t.Run("should report error if the input function is invalid", func(t *testing.T) {
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			janePost := post{UserID: jane.ID, Title: "Jane Post"}
+			err = c.Insert(ctx, NewTable("posts"), &janePost)
+			tt.AssertNoErr(t, err)
+			orphanPost := post{UserID: 4200, Title: "Orphan Post"}
+			err = c.Insert(ctx, NewTable("posts"), &orphanPost)
+			tt.AssertNoErr(t, err)
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			var usersPosts []struct {
+				User user  `tablename:"u"`
+				Post *post `tablename:"p"`
+			}
+			err = c.Query(ctx, &usersPosts, "FROM users u LEFT JOIN posts p ON p.user_id = u.id ORDER BY u.id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(usersPosts), 2)
+			tt.AssertEqual(t, usersPosts[0].User.Name, "Jane")
+			tt.AssertEqual(t, usersPosts[0].Post, &janePost)
+			tt.AssertEqual(t, usersPosts[1].User.Name, "John")
+			tt.AssertEqual(t, usersPosts[1].Post == nil, true)
+
+			// With modifiers on the pointer:
+			var postsUsers []struct {
+				Post post  `tablename:"p"`
+				User *user `tablename:"u"`
+			}
+			err = c.Query(ctx, &postsUsers, "FROM posts p LEFT JOIN users u ON p.user_id = u.id ORDER BY p.id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(postsUsers), 2)
+			tt.AssertEqual(t, postsUsers[0].Post.Title, "Jane Post")
+			tt.AssertNotEqual(t, postsUsers[0].User, (*user)(nil))
+			tt.AssertEqual(t, postsUsers[0].User.Name, "Jane")
+			tt.AssertEqual(t, postsUsers[0].User.Address.City, "Rio")
+			tt.AssertEqual(t, postsUsers[1].Post.Title, "Orphan Post")
+			tt.AssertEqual(t, postsUsers[1].User == nil, true)
+		})
 
-					funcs := []interface{}{
-						nil,
-						"not a function",
-						func() error {
-							return nil
-						},
-						func(extraInputValue []user, extra []user) error {
-							return nil
-						},
-						func(invalidArgType string) error {
-							return nil
-						},
-						func(missingReturnType []user) {
-						},
-						func(users []user) string {
-							return ""
-						},
-						func(extraReturnValue []user) ([]user, error) {
-							return nil, nil
-						},
-						func(notSliceOfStructs []string) error {
-							return nil
-						},
-					}
+		t.Run("should scan and write the attributes declared as interfaces", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-					for _, fn := range funcs {
-						err := c.QueryChunks(ctx, ChunkParser{
-							Query:  variation.queryPrefix + `FROM users`,
-							Params: []interface{}{},
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-							ChunkSize:    2,
-							ForEachChunk: fn,
-						})
-						tt.AssertNotEqual(t, err, nil)
-					}
-				})
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-				t.Run("should report error if the query is not valid", func(t *testing.T) {
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+			type userWithInterface struct {
+				ID   uint        `ksql:"id"`
+				Name displayName `ksql:"name"`
+			}
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
-					err := c.QueryChunks(ctx, ChunkParser{
-						Query:  `SELECT * FROM not a valid query`,
-						Params: []interface{}{},
+			u := userWithInterface{Name: upperCaseName{upperCaseScanner{value: "Jane"}}}
+			err = c.Insert(ctx, usersTable, &u)
+			tt.AssertNoErr(t, err)
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []user) error {
-							return nil
-						},
-					})
-					tt.AssertNotEqual(t, err, nil)
-				})
+			var loaded userWithInterface
+			err = c.QueryOne(ctx, &loaded, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, loaded.Name, nil)
+			tt.AssertEqual(t, loaded.Name.Display(), "JANE")
+		})
 
-				t.Run("should report error if using nested struct and the query starts with SELECT", func(t *testing.T) {
-					db, closer := newDBAdapter(t)
-					defer closer.Close()
+		t.Run("should report interfaces without a registered concrete type", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
 
-					ctx := context.Background()
-					c := newTestDB(db, driver)
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
 
-					err := c.QueryChunks(ctx, ChunkParser{
-						Query:  `SELECT * FROM users u JOIN posts p ON u.id = p.user_id`,
-						Params: []interface{}{},
+			ctx := context.Background()
+			c := newTestDB(db, driver)
 
-						ChunkSize: 2,
-						ForEachChunk: func(buffer []struct {
-							User user `tablename:"users"`
-							Post post `tablename:"posts"`
-						}) error {
-							return nil
-						},
-					})
+			err = c.Insert(ctx, usersTable, &user{Name: "Jane"})
+			tt.AssertNoErr(t, err)
 
-					tt.AssertErrContains(t, err, "nested struct", "feature")
-				})
-			})
-		}
+			var users []struct {
+				ID   uint         `ksql:"id"`
+				Name fmt.Stringer `ksql:"name"`
+			}
+			err = c.Query(ctx, &users, "FROM users")
+			tt.AssertErrContains(t, err, "name", "fmt.Stringer", "RegisterConcreteType")
+		})
 	})
 }
 
-// TransactionTest runs all tests for making sure the Transaction function is
-// working for a given adapter and driver.
-func TransactionTest(
+// FieldMasksTest runs all tests for making sure the InsertFields
+// and UpdateFields methods are working for a given adapter and driver.
+func FieldMasksTest(
 	t *testing.T,
 	driver string,
 	connStr string,
 	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
 ) {
-	t.Run("Transaction", func(t *testing.T) {
-		t.Run("should query a single row correctly", func(t *testing.T) {
+	t.Run("FieldMasks", func(t *testing.T) {
+		t.Run("should only insert the listed columns", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2188,22 +6234,20 @@ func TransactionTest(
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			_ = c.Insert(ctx, usersTable, &user{Name: "User1"})
-			_ = c.Insert(ctx, usersTable, &user{Name: "User2"})
-
-			var users []user
-			err = c.Transaction(ctx, func(db Provider) error {
-				db.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
-				return nil
-			})
+			u := user{Name: "Jane", Age: 42, Address: address{City: "Rio"}}
+			err = c.InsertFields(ctx, usersTable, &u, "name", "age")
 			tt.AssertNoErr(t, err)
+			tt.AssertNotEqual(t, u.ID, uint(0))
 
-			tt.AssertEqual(t, len(users), 2)
-			tt.AssertEqual(t, users[0].Name, "User1")
-			tt.AssertEqual(t, users[1].Name, "User2")
+			var dbUser user
+			err = c.QueryOne(ctx, &dbUser, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, dbUser.Name, "Jane")
+			tt.AssertEqual(t, dbUser.Age, 42)
+			tt.AssertEqual(t, dbUser.Address, address{})
 		})
 
-		t.Run("should work normally in nested transactions", func(t *testing.T) {
+		t.Run("should only update the listed columns", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2215,32 +6259,60 @@ func TransactionTest(
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			u := user{
-				Name: "User1",
-			}
+			u := user{Name: "Jane", Age: 42}
 			err = c.Insert(ctx, usersTable, &u)
 			tt.AssertNoErr(t, err)
-			tt.AssertNotEqual(t, u.ID, 0)
 
-			var updatedUser user
-			err = c.Transaction(ctx, func(db Provider) error {
-				u.Age = 42
-				err = db.Patch(ctx, usersTable, &u)
-				if err != nil {
-					return err
-				}
+			err = c.UpdateFields(ctx, usersTable, &user{ID: u.ID, Name: "Jane Doe", Age: 0}, "name")
+			tt.AssertNoErr(t, err)
 
-				return db.Transaction(ctx, func(db Provider) error {
-					return db.QueryOne(ctx, &updatedUser, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
-				})
-			})
+			var dbUser user
+			err = c.QueryOne(ctx, &dbUser, "FROM users WHERE id = "+c.dialect.Placeholder(0), u.ID)
 			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, dbUser.Name, "Jane Doe")
+			tt.AssertEqual(t, dbUser.Age, 42)
+		})
 
-			tt.AssertEqual(t, updatedUser.ID, u.ID)
-			tt.AssertEqual(t, updatedUser.Age, 42)
+		t.Run("should return ErrRecordNotFound when updating a missing record", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.UpdateFields(ctx, usersTable, &user{ID: 4200, Name: "Jane"}, "name")
+			tt.AssertEqual(t, IsNotFound(err), true)
 		})
+	})
+}
 
-		t.Run("should rollback when there are errors", func(t *testing.T) {
+// BlobsTest runs all tests for making sure the methods that
+// stream blobs are working for a given adapter and driver.
+func BlobsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	switch driver {
+	case "postgres", "sqlite3", "mysql", "mariadb", "sqlserver":
+	default:
+		return
+	}
+
+	type file struct {
+		ID   uint   `ksql:"id"`
+		Name string `ksql:"name"`
+	}
+	filesTable := NewTable("files")
+
+	t.Run("Blobs", func(t *testing.T) {
+		t.Run("should write and read the blobs in chunks", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2252,32 +6324,38 @@ func TransactionTest(
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			u1 := user{Name: "User1", Age: 42}
-			u2 := user{Name: "User2", Age: 42}
-			_ = c.Insert(ctx, usersTable, &u1)
-			_ = c.Insert(ctx, usersTable, &u2)
+			defer func(size int) { blobChunkSize = size }(blobChunkSize)
+			blobChunkSize = 16
 
-			err = c.Transaction(ctx, func(db Provider) error {
-				err = db.Insert(ctx, usersTable, &user{Name: "User3"})
-				tt.AssertNoErr(t, err)
-				err = db.Insert(ctx, usersTable, &user{Name: "User4"})
-				tt.AssertNoErr(t, err)
-				_, err = db.Exec(ctx, "UPDATE users SET age = 22")
-				tt.AssertNoErr(t, err)
+			f := file{Name: "data.bin"}
+			err = c.Insert(ctx, filesTable, &f)
+			tt.AssertNoErr(t, err)
 
-				return errors.New("fake-error")
-			})
-			tt.AssertNotEqual(t, err, nil)
-			tt.AssertEqual(t, err.Error(), "fake-error")
+			content := make([]byte, 100)
+			for i := range content {
+				content[i] = byte(i)
+			}
 
-			var users []user
-			err = c.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
+			err = c.WriteBlob(ctx, filesTable, f.ID, "content", bytes.NewReader(content))
 			tt.AssertNoErr(t, err)
 
-			tt.AssertEqual(t, users, []user{u1, u2})
+			var buf bytes.Buffer
+			n, err := c.ReadBlob(ctx, filesTable, f.ID, "content", &buf)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, n, int64(len(content)))
+			tt.AssertEqual(t, buf.Bytes(), content)
+
+			// Writing again should replace the previous content:
+			err = c.WriteBlob(ctx, filesTable, f.ID, "content", bytes.NewReader([]byte("short")))
+			tt.AssertNoErr(t, err)
+
+			buf.Reset()
+			_, err = c.ReadBlob(ctx, filesTable, f.ID, "content", &buf)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, buf.String(), "short")
 		})
 
-		t.Run("should rollback when the fn call panics", func(t *testing.T) {
+		t.Run("should return ErrRecordNotFound for missing records", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2289,33 +6367,51 @@ func TransactionTest(
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			u1 := user{Name: "User1", Age: 42}
-			u2 := user{Name: "User2", Age: 42}
-			_ = c.Insert(ctx, usersTable, &u1)
-			_ = c.Insert(ctx, usersTable, &u2)
+			err = c.WriteBlob(ctx, filesTable, 4200, "content", bytes.NewReader([]byte("content")))
+			tt.AssertEqual(t, IsNotFound(err), true)
 
-			panicPayload := tt.PanicHandler(func() {
-				c.Transaction(ctx, func(db Provider) error {
-					err = db.Insert(ctx, usersTable, &user{Name: "User3"})
-					tt.AssertNoErr(t, err)
-					err = db.Insert(ctx, usersTable, &user{Name: "User4"})
-					tt.AssertNoErr(t, err)
-					_, err = db.Exec(ctx, "UPDATE users SET age = 22")
-					tt.AssertNoErr(t, err)
+			_, err = c.ReadBlob(ctx, filesTable, 4200, "content", &bytes.Buffer{})
+			tt.AssertEqual(t, IsNotFound(err), true)
+		})
 
-					panic("fakePanicPayload")
-				})
-			})
-			tt.AssertEqual(t, panicPayload, "fakePanicPayload")
+		if driver != "postgres" {
+			return
+		}
 
-			var users []user
-			err = c.Query(ctx, &users, "SELECT * FROM users ORDER BY id ASC")
+		t.Run("should write and read large objects in chunks", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			defer func(size int) { blobChunkSize = size }(blobChunkSize)
+			blobChunkSize = 16
+
+			content := []byte(strings.Repeat("large object ", 10))
+			oid, err := c.WriteLargeObject(ctx, bytes.NewReader(content))
 			tt.AssertNoErr(t, err)
+			defer c.Exec(ctx, "SELECT lo_unlink($1)", oid)
 
-			tt.AssertEqual(t, users, []user{u1, u2})
+			var buf bytes.Buffer
+			n, err := c.ReadLargeObject(ctx, oid, &buf)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, n, int64(len(content)))
+			tt.AssertEqual(t, buf.Bytes(), content)
 		})
+	})
+}
 
-		t.Run("should handle rollback errors when the fn call panics", func(t *testing.T) {
+// DecimalsTest runs all tests for making sure the kdecimal.Decimal
+// type is working for a given adapter and driver.
+func DecimalsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Decimals", func(t *testing.T) {
+		t.Run("should write and scan decimals without losing precision", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2327,30 +6423,60 @@ func TransactionTest(
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			cMock := mockTxBeginner{
-				DBAdapter: c.db,
-				BeginTxFn: func(ctx context.Context) (Tx, error) {
-					return mockTx{
-						DBAdapter: c.db,
-						RollbackFn: func(ctx context.Context) error {
-							return fmt.Errorf("fakeRollbackErrMsg")
-						},
-					}, nil
-				},
+			type fileWithDecimal struct {
+				ID      uint              `ksql:"id"`
+				Name    kdecimal.Decimal  `ksql:"name"`
+				Content *kdecimal.Decimal `ksql:"content"`
 			}
-			c.db = cMock
 
-			panicPayload := tt.PanicHandler(func() {
-				c.Transaction(ctx, func(db Provider) error {
-					panic("fakePanicPayload")
-				})
-			})
-			err, ok := panicPayload.(error)
-			tt.AssertEqual(t, ok, true)
-			tt.AssertErrContains(t, err, "fakePanicPayload", "fakeRollbackErrMsg")
+			f := fileWithDecimal{Name: kdecimal.RequireFromString("123456789012.345678901")}
+			err = c.Insert(ctx, NewTable("files"), &f)
+			tt.AssertNoErr(t, err)
+
+			var result fileWithDecimal
+			err = c.QueryOne(ctx, &result, "FROM files WHERE id = "+c.dialect.Placeholder(0), f.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Name.String(), "123456789012.345678901")
+			tt.AssertEqual(t, result.Content == nil, true)
 		})
 
-		t.Run("should handle rollback errors when fn returns an error", func(t *testing.T) {
+		t.Run("should scan decimal columns", func(t *testing.T) {
+			if driver == "oracle" {
+				t.Skip("oracle requires a FROM clause")
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			var values []kdecimal.Decimal
+			err := c.Query(ctx, &values, "SELECT CAST(12.5 AS DECIMAL(10,2))")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(values), 1)
+			tt.AssertEqual(t, values[0].Equal(kdecimal.New(1250, 2)), true)
+		})
+	})
+}
+
+// TimeLocationTest runs all tests for making sure the times are
+// normalized to the TimeLocation and the dates to midnight UTC
+func TimeLocationTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("TimeLocation", func(t *testing.T) {
+		type event struct {
+			ID        uint       `ksql:"id"`
+			StartsAt  time.Time  `ksql:"starts_at"`
+			EndsAt    *time.Time `ksql:"ends_at"`
+			StartDate time.Time  `ksql:"start_date,date"`
+		}
+
+		t.Run("should read the same times and dates that were written", func(t *testing.T) {
 			err := createTables(driver, connStr)
 			if err != nil {
 				t.Fatal("could not create test table!, reason:", err.Error())
@@ -2361,65 +6487,192 @@ func TransactionTest(
 
 			ctx := context.Background()
 			c := newTestDB(db, driver)
+			c.timeLocation = time.UTC
+
+			// Close to midnight so the date would
+			// change if it was converted to UTC:
+			saoPaulo := time.FixedZone("BRT", -3*60*60)
+			startsAt := time.Date(2024, 1, 2, 22, 30, 0, 0, saoPaulo)
+			endsAt := startsAt.Add(time.Hour)
+
+			e := event{
+				StartsAt:  startsAt,
+				EndsAt:    &endsAt,
+				StartDate: startsAt,
+			}
+			err = c.Insert(ctx, NewTable("events"), &e)
+			tt.AssertNoErr(t, err)
 
-			cMock := mockTxBeginner{
-				DBAdapter: c.db,
-				BeginTxFn: func(ctx context.Context) (Tx, error) {
-					return mockTx{
-						DBAdapter: c.db,
-						RollbackFn: func(ctx context.Context) error {
-							return fmt.Errorf("fakeRollbackErrMsg")
-						},
-					}, nil
-				},
+			var result event
+			err = c.QueryOne(ctx, &result, "FROM events WHERE id = "+c.dialect.Placeholder(0), e.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.StartsAt, time.Date(2024, 1, 3, 1, 30, 0, 0, time.UTC))
+			tt.AssertNotEqual(t, result.EndsAt, nil)
+			tt.AssertEqual(t, *result.EndsAt, time.Date(2024, 1, 3, 2, 30, 0, 0, time.UTC))
+			tt.AssertEqual(t, result.StartDate, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+		})
+
+		t.Run("should scan NULL times as nil", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
 			}
-			c.db = cMock
 
-			err = c.Transaction(ctx, func(db Provider) error {
-				return fmt.Errorf("fakeTransactionErrMsg")
-			})
-			tt.AssertErrContains(t, err, "fakeTransactionErrMsg", "fakeRollbackErrMsg")
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+			c.timeLocation = time.UTC
+
+			e := event{
+				StartsAt:  time.Date(2024, 1, 2, 22, 30, 0, 0, time.UTC),
+				StartDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			}
+			err = c.Insert(ctx, NewTable("events"), &e)
+			tt.AssertNoErr(t, err)
+
+			var result event
+			err = c.QueryOne(ctx, &result, "FROM events WHERE id = "+c.dialect.Placeholder(0), e.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.EndsAt == nil, true)
+		})
+	})
+}
+
+type ticketStatus string
+
+type ticketPriority int
+
+var registerTicketEnumsOnce sync.Once
+
+// EnumsTest runs all tests for making sure the attributes
+// using the `enum` modifier are working for a given adapter
+// and driver.
+func EnumsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	registerTicketEnumsOnce.Do(func() {
+		RegisterEnum("ticket_status", []ticketStatus{"open", "closed"})
+		RegisterEnum("ticket_priority", []ticketPriority{1, 2, 3})
+	})
+
+	t.Run("Enums", func(t *testing.T) {
+		type ticket struct {
+			ID       int             `ksql:"id"`
+			Status   ticketStatus    `ksql:"status,enum,notnull"`
+			Priority *ticketPriority `ksql:"priority,enum"`
+		}
+
+		table := NewTable("tickets")
+		setup := func(t *testing.T, c DB) {
+			ctx := context.Background()
+			c.Exec(ctx, "DROP TABLE tickets")
+			if driver == "postgres" {
+				c.Exec(ctx, "DROP TYPE ticket_status")
+			}
+
+			err := EnsureTable(ctx, c, table, &ticket{})
+			tt.AssertNoErr(t, err)
+		}
+
+		t.Run("should write and read the registered values", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+			setup(t, c)
+
+			priority := ticketPriority(2)
+			err := c.Insert(ctx, table, &ticket{Status: "open", Priority: &priority})
+			tt.AssertNoErr(t, err)
+			err = c.Insert(ctx, table, &ticket{Status: "closed"})
+			tt.AssertNoErr(t, err)
+
+			var tickets []ticket
+			err = c.Query(ctx, &tickets, "FROM tickets ORDER BY id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(tickets), 2)
+			tt.AssertEqual(t, tickets[0].Status, ticketStatus("open"))
+			tt.AssertEqual(t, *tickets[0].Priority, ticketPriority(2))
+			tt.AssertEqual(t, tickets[1].Status, ticketStatus("closed"))
+			tt.AssertEqual(t, tickets[1].Priority == nil, true)
 		})
 
-		t.Run("should report error when BeginTx() fails", func(t *testing.T) {
+		t.Run("should reject the values that were not registered", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
 
 			ctx := context.Background()
 			c := newTestDB(db, driver)
+			setup(t, c)
 
-			cMock := mockTxBeginner{
-				DBAdapter: c.db,
-				BeginTxFn: func(ctx context.Context) (Tx, error) {
-					return nil, fmt.Errorf("fakeErrMsg")
-				},
-			}
-			c.db = cMock
+			err := c.Insert(ctx, table, &ticket{Status: "lost"})
+			tt.AssertErrContains(t, err, "lost", "ticket_status")
 
-			err := c.Transaction(ctx, func(db Provider) error {
-				return nil
-			})
-			tt.AssertErrContains(t, err, "KSQL", "fakeErrMsg")
+			var count int
+			err = c.QueryOne(ctx, &count, "SELECT count(*) FROM tickets")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, count, 0)
 		})
+	})
+}
 
-		t.Run("should report error if DBAdapter can't create transactions", func(t *testing.T) {
-			err := createTables(driver, connStr)
-			if err != nil {
-				t.Fatal("could not create test table!, reason:", err.Error())
-			}
+// VectorsTest runs all tests for making sure the vectors
+// are written and read back for a given adapter and driver.
+//
+// On Postgres the columns require the pgvector extension, so on
+// the other databases the vectors are saved on text columns.
+func VectorsTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("Vectors", func(t *testing.T) {
+		if driver == "postgres" {
+			t.Skip("the test images don't have the pgvector extension")
+		}
 
+		t.Run("should write and read back the vectors", func(t *testing.T) {
 			db, closer := newDBAdapter(t)
 			defer closer.Close()
 
 			ctx := context.Background()
 			c := newTestDB(db, driver)
 
-			c.db = mockDBAdapter{}
+			type embedding struct {
+				ID     int       `ksql:"id"`
+				Values []float32 `ksql:"vals,vector"`
+				Raw    Vector    `ksql:"raw"`
+			}
 
-			err = c.Transaction(ctx, func(db Provider) error {
-				return nil
-			})
-			tt.AssertErrContains(t, err, "KSQL", "can't start transaction", "DBAdapter", "TxBeginner")
+			table := NewTable("embeddings")
+			c.Exec(ctx, "DROP TABLE embeddings")
+			err := EnsureTable(ctx, c, table, &embedding{})
+			tt.AssertNoErr(t, err)
+
+			e := embedding{
+				Values: []float32{0.25, -1, 3.5e-6},
+				Raw:    Vector{1, 2},
+			}
+			err = c.Insert(ctx, table, &e)
+			tt.AssertNoErr(t, err)
+			err = c.Insert(ctx, table, &embedding{})
+			tt.AssertNoErr(t, err)
+
+			var embeddings []embedding
+			err = c.Query(ctx, &embeddings, "FROM embeddings ORDER BY id")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(embeddings), 2)
+			tt.AssertEqual(t, embeddings[0].Values, []float32{0.25, -1, 3.5e-6})
+			tt.AssertEqual(t, embeddings[0].Raw, Vector{1, 2})
+			tt.AssertEqual(t, embeddings[1].Values == nil, true)
+			tt.AssertEqual(t, embeddings[1].Raw == nil, true)
 		})
 	})
 }
@@ -2455,7 +6708,7 @@ func ScanRowsTest(
 			tt.AssertEqual(t, rows.Next(), true)
 
 			var u user
-			err = scanRows(dialect, rows, &u)
+			err = scanRows(ctx, dialect, rows, &u)
 			tt.AssertNoErr(t, err)
 
 			tt.AssertEqual(t, u.Name, "User2")
@@ -2488,7 +6741,7 @@ func ScanRowsTest(
 				// Omitted for testing purposes:
 				// Name string `ksql:"name"`
 			}
-			err = scanRows(dialect, rows, &u)
+			err = scanRows(ctx, dialect, rows, &u)
 			tt.AssertNoErr(t, err)
 
 			tt.AssertEqual(t, u.Age, 22)
@@ -2511,7 +6764,7 @@ func ScanRowsTest(
 			var u user
 			err = rows.Close()
 			tt.AssertNoErr(t, err)
-			err = scanRows(dialect, rows, &u)
+			err = scanRows(ctx, dialect, rows, &u)
 			tt.AssertNotEqual(t, err, nil)
 		})
 
@@ -2531,7 +6784,7 @@ func ScanRowsTest(
 			defer rows.Close()
 
 			var u user
-			err = scanRows(dialect, rows, u)
+			err = scanRows(ctx, dialect, rows, u)
 			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct", "user")
 		})
 
@@ -2551,18 +6804,287 @@ func ScanRowsTest(
 			defer rows.Close()
 
 			var u map[string]interface{}
-			err = scanRows(dialect, rows, &u)
+			err = scanRows(ctx, dialect, rows, &u)
 			tt.AssertErrContains(t, err, "ksql", "expected", "pointer to struct", "map[string]interface")
 		})
 	})
 }
 
+// DescribeTableTest runs the tests for the DescribeTable method
+func DescribeTableTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("DescribeTable", func(t *testing.T) {
+		t.Run("should describe the columns and indexes of the table", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			schema, err := c.DescribeTable(ctx, "user_permissions")
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, schema.Name, "user_permissions")
+			tt.AssertEqual(t, len(schema.Columns), 4)
+
+			var names []string
+			for _, column := range schema.Columns {
+				names = append(names, column.Name)
+			}
+			tt.AssertEqual(t, names, []string{"id", "user_id", "perm_id", "type"})
+			tt.AssertEqual(t, schema.PrimaryKey, []string{"id"})
+
+			id, found := schema.Column("id")
+			tt.AssertEqual(t, found, true)
+			tt.AssertEqual(t, id.PrimaryKey, true)
+
+			typ, found := schema.Column("type")
+			tt.AssertEqual(t, found, true)
+			tt.AssertEqual(t, typ.Nullable, true)
+			tt.AssertEqual(t, typ.PrimaryKey, false)
+
+			if driver != "duckdb" {
+				tt.AssertEqual(t, len(schema.Indexes), 1)
+				tt.AssertEqual(t, schema.Indexes[0].Unique, true)
+				tt.AssertEqual(t, schema.Indexes[0].Columns, []string{"user_id", "perm_id"})
+			}
+		})
+
+		t.Run("should report missing tables", func(t *testing.T) {
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			_, err := c.DescribeTable(ctx, "non_existing_table")
+			tt.AssertEqual(t, IsNotFound(err), true)
+		})
+	})
+}
+
+// ValidateSchemaTest runs the tests for the ValidateSchema function
+func ValidateSchemaTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	t.Run("ValidateSchema", func(t *testing.T) {
+		t.Run("should accept structs matching the tables", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			report, err := ValidateSchema(ctx, c,
+				NewTable("users").WithStruct(user{}),
+				NewTable("posts").WithStruct(post{}),
+				NewTable("documents").WithStruct(document{}),
+			)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(report.Tables), 3)
+			tt.AssertEqual(t, report.HasMismatches(), false)
+		})
+
+		t.Run("should report the differences between the structs and the tables", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			type outdatedPost struct {
+				ID        int       `ksql:"id"`
+				Title     string    `ksql:"title"`
+				CreatedAt time.Time `ksql:"user_id"`
+				Slug      string    `ksql:"slug"`
+			}
+
+			report, err := ValidateSchema(ctx, c,
+				NewTable("posts").WithStruct(outdatedPost{}),
+				NewTable("non_existing_table").WithStruct(post{}),
+			)
+
+			var mismatchErr ErrSchemaMismatch
+			tt.AssertEqual(t, errors.As(err, &mismatchErr), true)
+			tt.AssertEqual(t, mismatchErr.Report, report)
+			tt.AssertErrContains(t, err, "posts.slug", "posts.user_id", "non_existing_table")
+
+			tt.AssertEqual(t, len(report.Tables), 2)
+			tt.AssertEqual(t, report.Tables[0].MissingColumns, []string{"slug"})
+			tt.AssertEqual(t, len(report.Tables[0].TypeMismatches), 1)
+			tt.AssertEqual(t, report.Tables[0].TypeMismatches[0].Column, "user_id")
+			tt.AssertEqual(t, report.Tables[1].MissingTable, true)
+		})
+	})
+}
+
+// TenancyTest runs all tests for making sure the tenant
+// modifier is working for a given adapter and driver.
+func TenancyTest(
+	t *testing.T,
+	driver string,
+	connStr string,
+	newDBAdapter func(t *testing.T) (DBAdapter, io.Closer),
+) {
+	type tenantPermission struct {
+		ID     int    `ksql:"id"`
+		UserID int    `ksql:"user_id,tenant"`
+		PermID int    `ksql:"perm_id"`
+		Type   string `ksql:"type"`
+	}
+
+	permissionsTable := NewTable("user_permissions").WithStruct(tenantPermission{})
+
+	t.Run("Tenancy", func(t *testing.T) {
+		t.Run("should only read the rows of the tenant", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			tenantA := WithTenant(ctx, 1)
+			tenantB := WithTenant(ctx, 2)
+
+			permA := tenantPermission{PermID: 10, Type: "read"}
+			err = c.Insert(tenantA, permissionsTable, &permA)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, permA.UserID, 1)
+
+			permB := tenantPermission{PermID: 10, Type: "write"}
+			err = c.Insert(tenantB, permissionsTable, &permB)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, permB.UserID, 2)
+
+			var perms []tenantPermission
+			err = c.Query(tenantA, &perms, `FROM user_permissions WHERE perm_id = `+c.dialect.Placeholder(0), 10)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, len(perms), 1)
+			tt.AssertEqual(t, perms[0].Type, "read")
+
+			var perm tenantPermission
+			err = c.QueryOne(tenantB, &perm, `FROM user_permissions WHERE id = `+c.dialect.Placeholder(0), permA.ID)
+			tt.AssertEqual(t, IsNotFound(err), true)
+
+			count, err := c.Count(tenantB, permissionsTable, "")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, count, int64(1))
+
+			count, err = c.Count(CrossTenant(ctx), permissionsTable, "")
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, count, int64(2))
+
+			err = c.Query(ctx, &perms, `FROM user_permissions`)
+			tt.AssertEqual(t, err, ErrMissingTenant)
+		})
+
+		t.Run("should only write the rows of the tenant", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			tenantA := WithTenant(ctx, 1)
+			tenantB := WithTenant(ctx, 2)
+
+			perm := tenantPermission{PermID: 10, Type: "read"}
+			err = c.Insert(tenantA, permissionsTable, &perm)
+			tt.AssertNoErr(t, err)
+
+			// The perm_id is left out of the updates since DuckDB rejects
+			// the updates on the columns of a unique index, even when
+			// their values don't change:
+			type permissionType struct {
+				ID     int    `ksql:"id"`
+				UserID int    `ksql:"user_id,tenant"`
+				Type   string `ksql:"type"`
+			}
+
+			err = c.Patch(tenantB, permissionsTable, &permissionType{ID: perm.ID, Type: "write"})
+			tt.AssertEqual(t, IsNotFound(err), true)
+
+			err = c.Delete(tenantB, permissionsTable, perm.ID)
+			tt.AssertEqual(t, IsNotFound(err), true)
+
+			err = c.Patch(tenantA, permissionsTable, &permissionType{ID: perm.ID, Type: "write"})
+			tt.AssertNoErr(t, err)
+
+			var result tenantPermission
+			err = c.QueryOne(tenantA, &result, `FROM user_permissions WHERE id = `+c.dialect.Placeholder(0), perm.ID)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, result.Type, "write")
+			tt.AssertEqual(t, result.UserID, 1)
+
+			err = c.Delete(tenantA, permissionsTable, perm.ID)
+			tt.AssertNoErr(t, err)
+		})
+
+		t.Run("should not move records between tenants", func(t *testing.T) {
+			err := createTables(driver, connStr)
+			if err != nil {
+				t.Fatal("could not create test table!, reason:", err.Error())
+			}
+
+			db, closer := newDBAdapter(t)
+			defer closer.Close()
+
+			ctx := context.Background()
+			c := newTestDB(db, driver)
+
+			err = c.Insert(WithTenant(ctx, 1), permissionsTable, &tenantPermission{UserID: 2, PermID: 10, Type: "read"})
+			tt.AssertErrContains(t, err, "tenant `2`", "tenant `1`")
+
+			err = c.Upsert(WithTenant(ctx, 1), permissionsTable, &tenantPermission{PermID: 10, Type: "read"})
+			tt.AssertErrContains(t, err, "upsert", "user_id", "conflict columns")
+		})
+	})
+}
+
 func createTables(driver string, connStr string) error {
 	if connStr == "" {
 		return fmt.Errorf("unsupported driver: '%s'", driver)
 	}
 
-	db, err := sql.Open(driver, connStr)
+	sqlDriver := driver
+	if driver == "mariadb" {
+		// MariaDB uses the same driver as MySQL:
+		sqlDriver = "mysql"
+	}
+
+	db, err := sql.Open(sqlDriver, connStr)
 	if err != nil {
 		return err
 	}
@@ -2585,7 +7107,7 @@ func createTables(driver string, connStr string) error {
 			name VARCHAR(50),
 			address jsonb
 		)`)
-	case "mysql":
+	case "mysql", "mariadb":
 		_, err = db.Exec(`CREATE TABLE users (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			age INT,
@@ -2599,6 +7121,14 @@ func createTables(driver string, connStr string) error {
 			name VARCHAR(50),
 			address NVARCHAR(4000)
 		)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS users_id_seq;
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY DEFAULT nextval('users_id_seq'),
+			age INTEGER,
+			name VARCHAR,
+			address VARCHAR
+		)`)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to create new users table: %s", err.Error())
@@ -2619,7 +7149,7 @@ func createTables(driver string, connStr string) error {
 			user_id INT,
 			title VARCHAR(50)
 		)`)
-	case "mysql":
+	case "mysql", "mariadb":
 		_, err = db.Exec(`CREATE TABLE posts (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id INT,
@@ -2631,6 +7161,13 @@ func createTables(driver string, connStr string) error {
 			user_id INT,
 			title VARCHAR(50)
 		)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS posts_id_seq;
+		CREATE TABLE posts (
+			id INTEGER PRIMARY KEY DEFAULT nextval('posts_id_seq'),
+			user_id INTEGER,
+			title VARCHAR
+		)`)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to create new posts table: %s", err.Error())
@@ -2655,7 +7192,7 @@ func createTables(driver string, connStr string) error {
 			type VARCHAR(50),
 			UNIQUE (user_id, perm_id)
 		)`)
-	case "mysql":
+	case "mysql", "mariadb":
 		_, err = db.Exec(`CREATE TABLE user_permissions (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id INT,
@@ -2671,11 +7208,181 @@ func createTables(driver string, connStr string) error {
 			type VARCHAR(50),
 			CONSTRAINT unique_1 UNIQUE (user_id, perm_id)
 		)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS user_permissions_id_seq;
+		CREATE TABLE user_permissions (
+			id INTEGER PRIMARY KEY DEFAULT nextval('user_permissions_id_seq'),
+			user_id INTEGER,
+			perm_id INTEGER,
+			type VARCHAR,
+			UNIQUE (user_id, perm_id)
+		)`)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to create new user_permissions table: %s", err.Error())
 	}
 
+	db.Exec(`DROP TABLE documents`)
+
+	switch driver {
+	case "sqlite3":
+		_, err = db.Exec(`CREATE TABLE documents (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			title TEXT,
+			version INTEGER,
+			created_at DATETIME,
+			updated_at DATETIME,
+			deleted_at DATETIME
+		)`)
+	case "postgres":
+		_, err = db.Exec(`CREATE TABLE documents (
+			id serial PRIMARY KEY,
+			user_id INT,
+			title VARCHAR(50),
+			version INT,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP,
+			deleted_at TIMESTAMP
+		)`)
+	case "mysql", "mariadb":
+		_, err = db.Exec(`CREATE TABLE documents (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT,
+			title VARCHAR(50),
+			version INT,
+			created_at DATETIME,
+			updated_at DATETIME,
+			deleted_at DATETIME
+		)`)
+	case "sqlserver":
+		_, err = db.Exec(`CREATE TABLE documents (
+			id INT IDENTITY(1,1) PRIMARY KEY,
+			user_id INT,
+			title VARCHAR(50),
+			version INT,
+			created_at DATETIME2,
+			updated_at DATETIME2,
+			deleted_at DATETIME2
+		)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS documents_id_seq;
+		CREATE TABLE documents (
+			id INTEGER PRIMARY KEY DEFAULT nextval('documents_id_seq'),
+			user_id INTEGER,
+			title VARCHAR,
+			version INTEGER,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP,
+			deleted_at TIMESTAMP
+		)`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create new documents table: %s", err.Error())
+	}
+
+	db.Exec(`DROP TABLE audit_log`)
+
+	switch driver {
+	case "sqlite3":
+		_, err = db.Exec(`CREATE TABLE audit_log (
+			id INTEGER PRIMARY KEY,
+			table_name TEXT,
+			operation TEXT,
+			record_id TEXT,
+			before_image TEXT,
+			after_image TEXT,
+			actor TEXT,
+			created_at DATETIME
+		)`)
+	case "postgres":
+		_, err = db.Exec(`CREATE TABLE audit_log (
+			id serial PRIMARY KEY,
+			table_name VARCHAR(50),
+			operation VARCHAR(10),
+			record_id VARCHAR(100),
+			before_image JSONB,
+			after_image JSONB,
+			actor VARCHAR(50),
+			created_at TIMESTAMP
+		)`)
+	case "mysql", "mariadb":
+		_, err = db.Exec(`CREATE TABLE audit_log (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			table_name VARCHAR(50),
+			operation VARCHAR(10),
+			record_id VARCHAR(100),
+			before_image JSON,
+			after_image JSON,
+			actor VARCHAR(50),
+			created_at DATETIME
+		)`)
+	case "sqlserver":
+		_, err = db.Exec(`CREATE TABLE audit_log (
+			id INT IDENTITY(1,1) PRIMARY KEY,
+			table_name VARCHAR(50),
+			operation VARCHAR(10),
+			record_id VARCHAR(100),
+			before_image NVARCHAR(MAX),
+			after_image NVARCHAR(MAX),
+			actor VARCHAR(50),
+			created_at DATETIME2
+		)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS audit_log_id_seq;
+		CREATE TABLE audit_log (
+			id INTEGER PRIMARY KEY DEFAULT nextval('audit_log_id_seq'),
+			table_name VARCHAR,
+			operation VARCHAR,
+			record_id VARCHAR,
+			before_image VARCHAR,
+			after_image VARCHAR,
+			actor VARCHAR,
+			created_at TIMESTAMP
+		)`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create new audit_log table: %s", err.Error())
+	}
+
+	db.Exec(`DROP TABLE files`)
+
+	switch driver {
+	case "sqlite3":
+		_, err = db.Exec(`CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, content BLOB)`)
+	case "postgres":
+		_, err = db.Exec(`CREATE TABLE files (id serial PRIMARY KEY, name VARCHAR(50), content BYTEA)`)
+	case "mysql", "mariadb":
+		_, err = db.Exec(`CREATE TABLE files (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(50), content LONGBLOB)`)
+	case "sqlserver":
+		_, err = db.Exec(`CREATE TABLE files (id INT IDENTITY(1,1) PRIMARY KEY, name VARCHAR(50), content VARBINARY(MAX))`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS files_id_seq;
+		CREATE TABLE files (id INTEGER PRIMARY KEY DEFAULT nextval('files_id_seq'), name VARCHAR, content BLOB)`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create new files table: %s", err.Error())
+	}
+
+	db.Exec(`DROP TABLE events`)
+
+	switch driver {
+	case "sqlite3":
+		_, err = db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, starts_at DATETIME, ends_at INTEGER, start_date DATE)`)
+	case "postgres":
+		_, err = db.Exec(`CREATE TABLE events (id serial PRIMARY KEY, starts_at TIMESTAMPTZ, ends_at TIMESTAMPTZ, start_date DATE)`)
+	case "mysql", "mariadb":
+		_, err = db.Exec(`CREATE TABLE events (id INT AUTO_INCREMENT PRIMARY KEY, starts_at DATETIME(6), ends_at DATETIME(6), start_date DATE)`)
+	case "sqlserver":
+		_, err = db.Exec(`CREATE TABLE events (id INT IDENTITY(1,1) PRIMARY KEY, starts_at DATETIMEOFFSET, ends_at DATETIMEOFFSET, start_date DATE)`)
+	case "duckdb":
+		_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS events_id_seq;
+		CREATE TABLE events (id INTEGER PRIMARY KEY DEFAULT nextval('events_id_seq'), starts_at TIMESTAMPTZ, ends_at TIMESTAMPTZ, start_date DATE)`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create new events table: %s", err.Error())
+	}
+
 	return nil
 }
 
@@ -0,0 +1,38 @@
+package ksql
+
+import (
+	"context"
+	"time"
+)
+
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of the input context that makes the
+// KSQL operations using it fail if they take longer than the input
+// timeout, overriding the `ksql.Config.DefaultQueryTimeout`, e.g.:
+//
+//	err := db.Query(ksql.WithTimeout(ctx, 5*time.Second), &users, "FROM users")
+//
+// Unlike `context.WithTimeout()` the timeout is applied to each
+// operation separately, starting when the operation starts, so
+// there is no cancel function to be called by the user.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// withTimeout returns the context that should be used by an operation
+// along with the cancel function that must be called when it completes.
+//
+// The timeout set with `ksql.WithTimeout()` is always applied, while the
+// default timeout is only applied if the context has no deadline.
+func (c DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	if !ok {
+		if _, hasDeadline := ctx.Deadline(); hasDeadline || c.defaultTimeout == 0 {
+			return ctx, func() {}
+		}
+		timeout = c.defaultTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,71 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestTimeouts(t *testing.T) {
+	newDB := func(t *testing.T, defaultTimeout time.Duration, deadline *time.Time, hasDeadline *bool) DB {
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				*deadline, *hasDeadline = ctx.Deadline()
+				return nil, nil
+			},
+		}, "sqlite3", Config{
+			DefaultQueryTimeout: defaultTimeout,
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should not set a deadline if no timeout is configured", func(t *testing.T) {
+		var deadline time.Time
+		var hasDeadline bool
+		db := newDB(t, 0, &deadline, &hasDeadline)
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, hasDeadline, false)
+	})
+
+	t.Run("should apply the default timeout", func(t *testing.T) {
+		var deadline time.Time
+		var hasDeadline bool
+		db := newDB(t, time.Minute, &deadline, &hasDeadline)
+
+		_, err := db.Exec(context.Background(), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, hasDeadline, true)
+		tt.AssertApproxTime(t, 2*time.Second, deadline, time.Now().Add(time.Minute), "deadline")
+	})
+
+	t.Run("should not override deadlines set by the caller", func(t *testing.T) {
+		var deadline time.Time
+		var hasDeadline bool
+		db := newDB(t, time.Minute, &deadline, &hasDeadline)
+
+		expectedDeadline := time.Now().Add(time.Hour)
+		ctx, cancel := context.WithDeadline(context.Background(), expectedDeadline)
+		defer cancel()
+
+		_, err := db.Exec(ctx, "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, hasDeadline, true)
+		tt.AssertEqual(t, deadline, expectedDeadline)
+	})
+
+	t.Run("should prefer the timeout set with WithTimeout", func(t *testing.T) {
+		var deadline time.Time
+		var hasDeadline bool
+		db := newDB(t, time.Minute, &deadline, &hasDeadline)
+
+		_, err := db.Exec(WithTimeout(context.Background(), time.Hour), "fake query")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, hasDeadline, true)
+		tt.AssertApproxTime(t, 2*time.Second, deadline, time.Now().Add(time.Hour), "deadline")
+	})
+}
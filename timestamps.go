@@ -0,0 +1,43 @@
+package ksql
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// setTimeNowOnInsert sets all the attributes with the timeNowUTC
+// modifiers of the record to the input time, so that the user has
+// access to the saved values after the record is inserted.
+func setTimeNowOnInsert(structValue reflect.Value, info structs.StructInfo, now time.Time) {
+	for _, field := range info.Fields() {
+		if !field.TimeNowUTC {
+			continue
+		}
+
+		attr := structValue.FieldByIndex(field.Path)
+		if attr.Kind() == reflect.Ptr {
+			attr.Set(reflect.New(attr.Type().Elem()))
+			attr = attr.Elem()
+		}
+		attr.Set(reflect.ValueOf(now))
+	}
+}
+
+// setTimeNowOnUpdate updates the map of attributes that will be
+// written on an UPDATE so that the attributes with the timeNowUTC
+// modifier are set to the input time and the ones that should
+// not be updated are removed.
+func setTimeNowOnUpdate(recordMap map[string]interface{}, info structs.StructInfo, now time.Time) {
+	for _, field := range info.Fields() {
+		if field.SkipOnUpdate {
+			delete(recordMap, field.Name)
+			continue
+		}
+
+		if field.TimeNowUTC {
+			recordMap[field.Name] = now
+		}
+	}
+}
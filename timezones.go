@@ -0,0 +1,200 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+type timeLocationKey struct{}
+
+// withTimeLocation adds the `ksql.Config.TimeLocation`
+// of the DB to the context if it is set
+func (c DB) withTimeLocation(ctx context.Context) context.Context {
+	if c.timeLocation == nil || ctx.Value(timeLocationKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, timeLocationKey{}, c.timeLocation)
+}
+
+// timeLocationFromContext returns the configured TimeLocation,
+// or nil if the times should keep the zone used by the driver
+func timeLocationFromContext(ctx context.Context) *time.Location {
+	loc, _ := ctx.Value(timeLocationKey{}).(*time.Location)
+	return loc
+}
+
+var timePtrType = reflect.PtrTo(timeType)
+
+// isTimeField checks if the attribute should be normalized
+// to the TimeLocation, the attributes with modifiers are
+// ignored since the modifiers decide how they are decoded.
+func isTimeField(fieldInfo *structs.FieldInfo, fieldType reflect.Type) bool {
+	if fieldInfo.Modifier != nil && fieldInfo.Modifier.Scan != nil {
+		return false
+	}
+	return fieldType == timeType || fieldType == timePtrType
+}
+
+// normalizeTimeValue converts the time.Time and *time.Time
+// values to the TimeLocation saved on the context before
+// they are written to the database
+func normalizeTimeValue(ctx context.Context, value interface{}) interface{} {
+	loc := timeLocationFromContext(ctx)
+	if loc == nil {
+		return value
+	}
+
+	switch t := value.(type) {
+	case time.Time:
+		return t.In(loc)
+	case *time.Time:
+		if t != nil {
+			return t.In(loc)
+		}
+	}
+	return value
+}
+
+// normalizeTime converts the time.Time or *time.Time
+// stored on the input value to the input location
+func normalizeTime(v reflect.Value, loc *time.Location) {
+	if loc == nil {
+		return
+	}
+
+	switch t := v.Interface().(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(t.In(loc)))
+	case *time.Time:
+		if t != nil {
+			*t = t.In(loc)
+		}
+	}
+}
+
+// timeScanner scans the time.Time and *time.Time attributes when the
+// TimeLocation is set, so that the times saved as text or as unix
+// timestamps, e.g. on SQLite, are decoded the same way as the
+// times returned by the drivers as time.Time.
+type timeScanner struct {
+	field reflect.Value
+	loc   *time.Location
+}
+
+func (s timeScanner) Scan(dbValue interface{}) error {
+	if dbValue == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+
+	t, err := parseDBTime(dbValue)
+	if err != nil {
+		return err
+	}
+	t = t.In(s.loc)
+
+	if s.field.Kind() == reflect.Ptr {
+		s.field.Set(reflect.ValueOf(&t))
+		return nil
+	}
+	s.field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// These are the formats used by the drivers for saving times as
+// text, the ones without zones are interpreted as UTC:
+var dbTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseDBTime decodes the times returned by the drivers,
+// which might be a time.Time, a text or a unix timestamp
+func parseDBTime(dbValue interface{}) (time.Time, error) {
+	var str string
+	switch v := dbValue.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	default:
+		return time.Time{}, fmt.Errorf("ksql: unexpected type %T for a time column", dbValue)
+	}
+
+	str = strings.TrimSpace(str)
+	for _, layout := range dbTimeLayouts {
+		t, err := time.Parse(layout, str)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("ksql: can't parse '%s' as a time", str)
+}
+
+func init() {
+	structs.RegisterAttrModifier("date", dateModifier)
+}
+
+// dateModifier is used for DATE columns, it writes the time.Time and
+// *time.Time attributes as "YYYY-MM-DD" strings and reads them as the
+// midnight in UTC of the same date, regardless of the zone used by the
+// driver or of the `ksql.Config.TimeLocation`, so that a date never
+// changes to the previous or to the next day when it is converted.
+var dateModifier = ksqlmodifiers.AttrModifier{
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		switch t := inputValue.(type) {
+		case time.Time:
+			return t.Format("2006-01-02"), nil
+		case *time.Time:
+			if t == nil {
+				return nil, nil
+			}
+			return t.Format("2006-01-02"), nil
+		}
+
+		return nil, fmt.Errorf("ksql: the date modifier only supports time.Time attributes, but got: %T", inputValue)
+	},
+
+	Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+		v := reflect.ValueOf(attrPtr).Elem()
+		if v.Type() != timeType && v.Type() != timePtrType {
+			return fmt.Errorf("ksql: the date modifier only supports time.Time attributes, but got: %T", attrPtr)
+		}
+
+		if dbValue == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		t, err := parseDBTime(dbValue)
+		if err != nil {
+			return err
+		}
+
+		// The date is read on the zone returned by the
+		// driver, which is the zone it was written on:
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.ValueOf(&date))
+			return nil
+		}
+		v.Set(reflect.ValueOf(date))
+		return nil
+	},
+}
@@ -0,0 +1,231 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+type event struct {
+	ID        int        `ksql:"id"`
+	StartsAt  time.Time  `ksql:"starts_at"`
+	EndsAt    *time.Time `ksql:"ends_at"`
+	StartDate time.Time  `ksql:"start_date,date"`
+}
+
+// fakeTimeRows returns a single row with the input values,
+// which are scanned like database/sql would scan them
+type fakeTimeRows struct {
+	Rows
+
+	columns []string
+	values  []interface{}
+	done    bool
+}
+
+func (f *fakeTimeRows) Next() bool {
+	if f.done {
+		return false
+	}
+	f.done = true
+	return true
+}
+
+func (f *fakeTimeRows) Scan(args ...interface{}) error {
+	for i, value := range f.values {
+		if scanner, ok := args[i].(sql.Scanner); ok {
+			if err := scanner.Scan(value); err != nil {
+				return err
+			}
+			continue
+		}
+		reflect.ValueOf(args[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (f *fakeTimeRows) Columns() ([]string, error) {
+	return f.columns, nil
+}
+
+func (f *fakeTimeRows) Err() error {
+	return nil
+}
+
+func (f *fakeTimeRows) Close() error {
+	return nil
+}
+
+// paramsByColumn returns the params of the columns of a Postgres
+// statement, since the order of the columns is not deterministic
+func paramsByColumn(statement DryRunStatement) map[string]interface{} {
+	params := map[string]interface{}{}
+	for _, match := range regexp.MustCompile(`"(\w+)" = \$(\d+)`).FindAllStringSubmatch(statement.Query, -1) {
+		i, _ := strconv.Atoi(match[2])
+		params[match[1]] = statement.Params[i-1]
+	}
+	return params
+}
+
+func TestTimeLocation(t *testing.T) {
+	ctx := context.Background()
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	t.Run("should convert the times before writing them", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+		dryRun.timeLocation = time.UTC
+
+		startsAt := time.Date(2024, 1, 2, 21, 30, 0, 0, saoPaulo)
+		endsAt := startsAt.Add(time.Hour)
+		err = dryRun.Patch(ctx, NewTable("events"), &event{
+			ID:        1,
+			StartsAt:  startsAt,
+			EndsAt:    &endsAt,
+			StartDate: startsAt,
+		})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, paramsByColumn(dryRun.Statements()[0]), map[string]interface{}{
+			"starts_at":  time.Date(2024, 1, 3, 0, 30, 0, 0, time.UTC),
+			"ends_at":    time.Date(2024, 1, 3, 1, 30, 0, 0, time.UTC),
+			"start_date": "2024-01-02",
+			"id":         1,
+		})
+	})
+
+	t.Run("should keep the times unchanged if no location is set", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		startsAt := time.Date(2024, 1, 2, 21, 30, 0, 0, saoPaulo)
+		err = dryRun.Patch(ctx, NewTable("events"), &event{
+			ID:        1,
+			StartsAt:  startsAt,
+			StartDate: startsAt,
+		})
+		tt.AssertNoErr(t, err)
+
+		params := paramsByColumn(dryRun.Statements()[0])
+		tt.AssertEqual(t, params["starts_at"].(time.Time).Location(), saoPaulo)
+	})
+
+	t.Run("should decode and convert the times read from the database", func(t *testing.T) {
+		tests := []struct {
+			desc     string
+			startsAt interface{}
+		}{
+			{desc: "time.Time", startsAt: time.Date(2024, 1, 2, 21, 30, 0, 0, saoPaulo)},
+			{desc: "text with zone", startsAt: "2024-01-02 21:30:00-03:00"},
+			{desc: "ISO text", startsAt: []byte("2024-01-03T00:30:00Z")},
+			{desc: "text without zone", startsAt: "2024-01-03 00:30:00"},
+			{desc: "unix timestamp", startsAt: int64(1704241800)},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				dryRun, err := NewDryRun("sqlite3", mockDBAdapter{
+					QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+						return &fakeTimeRows{
+							columns: []string{"id", "starts_at", "ends_at", "start_date"},
+							values:  []interface{}{1, test.startsAt, nil, "2024-01-02"},
+						}, nil
+					},
+				})
+				tt.AssertNoErr(t, err)
+				dryRun.timeLocation = time.UTC
+
+				var e event
+				err = dryRun.QueryOne(ctx, &e, "FROM events WHERE id = ?", 1)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, e, event{
+					ID:        1,
+					StartsAt:  time.Date(2024, 1, 3, 0, 30, 0, 0, time.UTC),
+					StartDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				})
+			})
+		}
+	})
+
+	t.Run("should convert scalar times", func(t *testing.T) {
+		dryRun, err := NewDryRun("sqlite3", mockDBAdapter{
+			QueryContextFn: func(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+				return &fakeTimeRows{
+					columns: []string{"starts_at"},
+					values:  []interface{}{time.Date(2024, 1, 2, 21, 30, 0, 0, saoPaulo)},
+				}, nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+		dryRun.timeLocation = time.UTC
+
+		var startsAt time.Time
+		err = dryRun.QueryOne(ctx, &startsAt, "SELECT starts_at FROM events WHERE id = ?", 1)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, startsAt, time.Date(2024, 1, 3, 0, 30, 0, 0, time.UTC))
+	})
+}
+
+func TestDateModifier(t *testing.T) {
+	ctx := context.Background()
+	opInfo := ksqlmodifiers.OpInfo{DriverName: "postgres", Method: "Query"}
+
+	t.Run("should read dates as the midnight in UTC", func(t *testing.T) {
+		tests := []struct {
+			desc    string
+			dbValue interface{}
+		}{
+			{desc: "time.Time on another zone", dbValue: time.Date(2024, 1, 2, 0, 0, 0, 0, time.FixedZone("BRT", -3*60*60))},
+			{desc: "text", dbValue: "2024-01-02"},
+			{desc: "bytes", dbValue: []byte("2024-01-02")},
+			{desc: "text with time", dbValue: "2024-01-02 00:00:00+00:00"},
+		}
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				var date time.Time
+				err := dateModifier.Scan(ctx, opInfo, &date, test.dbValue)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, date, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+
+				var datePtr *time.Time
+				err = dateModifier.Scan(ctx, opInfo, &datePtr, test.dbValue)
+				tt.AssertNoErr(t, err)
+				tt.AssertEqual(t, *datePtr, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+			})
+		}
+	})
+
+	t.Run("should write dates as text", func(t *testing.T) {
+		date := time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC)
+		value, err := dateModifier.Value(ctx, opInfo, date)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, "2024-01-02")
+
+		value, err = dateModifier.Value(ctx, opInfo, &date)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, "2024-01-02")
+
+		value, err = dateModifier.Value(ctx, opInfo, (*time.Time)(nil))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, nil)
+	})
+
+	t.Run("should report invalid attributes", func(t *testing.T) {
+		var notADate string
+		err := dateModifier.Scan(ctx, opInfo, &notADate, "2024-01-02")
+		tt.AssertErrContains(t, err, "date modifier", "*string")
+
+		_, err = dateModifier.Value(ctx, opInfo, notADate)
+		tt.AssertErrContains(t, err, "date modifier", "string")
+
+		var date time.Time
+		err = dateModifier.Scan(ctx, opInfo, &date, "yesterday")
+		tt.AssertErrContains(t, err, "yesterday")
+	})
+}
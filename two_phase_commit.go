@@ -0,0 +1,133 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PrepareTransaction prepares the transaction for a two-phase commit
+// using the Postgres `PREPARE TRANSACTION` statement, after which the
+// transaction is no longer associated with the TxHandle and can only
+// be finished by calling `DB.CommitPrepared()` or `DB.RollbackPrepared()`
+// with the same id, possibly from another connection or process.
+//
+// The hooks registered on the transaction don't run for prepared
+// transactions and the Postgres server must be configured with
+// a `max_prepared_transactions` greater than zero.
+func (t *TxHandle) PrepareTransaction(ctx context.Context, id string) error {
+	if t.dialect.DriverName() != "postgres" {
+		return fmt.Errorf("KSQL: prepared transactions are only supported on postgres, not on %s", t.dialect.DriverName())
+	}
+
+	_, err := t.tx.ExecContext(ctx, "PREPARE TRANSACTION "+quoteLiteral(id))
+	if err != nil {
+		return fmt.Errorf("KSQL: error preparing transaction '%s': %s", id, err)
+	}
+
+	// Marking the transaction as finished so the hooks
+	// don't run when the handle is released below:
+	t.txHooks.finish(ctx, func() []func(ctx context.Context) { return nil })
+
+	// The connection is no longer inside a transaction but it still needs
+	// to be released, the error is ignored because some drivers complain
+	// that there is no transaction in progress:
+	_ = t.tx.Rollback(ctx)
+
+	return nil
+}
+
+// CommitPrepared commits a transaction prepared
+// with `TxHandle.PrepareTransaction()`
+func (c DB) CommitPrepared(ctx context.Context, id string) error {
+	return c.finishPrepared(ctx, "COMMIT PREPARED", id)
+}
+
+// RollbackPrepared rolls back a transaction prepared
+// with `TxHandle.PrepareTransaction()`
+func (c DB) RollbackPrepared(ctx context.Context, id string) error {
+	return c.finishPrepared(ctx, "ROLLBACK PREPARED", id)
+}
+
+func (c DB) finishPrepared(ctx context.Context, statement string, id string) error {
+	if c.dialect.DriverName() != "postgres" {
+		return fmt.Errorf("KSQL: prepared transactions are only supported on postgres, not on %s", c.dialect.DriverName())
+	}
+
+	if _, ok := c.db.(Tx); ok {
+		return fmt.Errorf("KSQL: %s can't run inside a transaction", statement)
+	}
+
+	_, err := c.Exec(ctx, statement+" "+quoteLiteral(id))
+	if err != nil {
+		return fmt.Errorf("KSQL: error on %s '%s': %s", statement, id, err)
+	}
+	return nil
+}
+
+// TwoPhaseCommit runs the input callback with one transaction for
+// each of the input databases and commits all of them using a two-phase
+// commit, so either all the transactions are committed or none are.
+//
+// The transaction of the database at index i is prepared with the
+// id `<id>-<i>` so that the ids are unique even if the databases are on
+// the same server. If the process dies before all the transactions are
+// committed they remain prepared on the databases and need to be finished
+// manually with `DB.CommitPrepared()` or `DB.RollbackPrepared()`.
+func TwoPhaseCommit(ctx context.Context, id string, dbs []DB, fn func(txs []Provider) error) (err error) {
+	handles := make([]*TxHandle, 0, len(dbs))
+	defer func() {
+		if err != nil {
+			for _, tx := range handles {
+				_ = tx.Rollback(ctx)
+			}
+		}
+	}()
+
+	txs := make([]Provider, 0, len(dbs))
+	for _, db := range dbs {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		handles = append(handles, tx)
+		txs = append(txs, tx)
+	}
+
+	err = fn(txs)
+	if err != nil {
+		return err
+	}
+
+	var preparedIDs []string
+	for i, tx := range handles {
+		txID := fmt.Sprintf("%s-%d", id, i)
+		err = tx.PrepareTransaction(ctx, txID)
+		if err != nil {
+			for j, preparedID := range preparedIDs {
+				_ = dbs[j].RollbackPrepared(ctx, preparedID)
+			}
+			return err
+		}
+		preparedIDs = append(preparedIDs, txID)
+	}
+
+	for i, txID := range preparedIDs {
+		err = dbs[i].CommitPrepared(ctx, txID)
+		if err != nil {
+			return fmt.Errorf(
+				"KSQL: the prepared transactions %s were not committed: %s",
+				strings.Join(preparedIDs[i:], ", "), err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// quoteLiteral quotes the input as an SQL string literal,
+// which is necessary for the statements that don't accept
+// arguments, e.g. `PREPARE TRANSACTION`.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
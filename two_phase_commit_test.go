@@ -0,0 +1,126 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestTwoPhaseCommit(t *testing.T) {
+	// newDB returns a DB that records the statements it runs and
+	// fails the statements starting with the input failPrefix:
+	newDB := func(t *testing.T, name string, failPrefix string, statements *[]string) DB {
+		exec := func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+			*statements = append(*statements, name+": "+query)
+			if failPrefix != "" && strings.HasPrefix(query, failPrefix) {
+				return nil, errors.New("fake-error")
+			}
+			return nil, nil
+		}
+
+		db, err := NewWithAdapter(mockTxBeginner{
+			DBAdapter: mockDBAdapter{ExecContextFn: exec},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{
+					DBAdapter: mockDBAdapter{ExecContextFn: exec},
+					CommitFn: func(ctx context.Context) error {
+						*statements = append(*statements, name+": COMMIT")
+						return nil
+					},
+					RollbackFn: func(ctx context.Context) error {
+						*statements = append(*statements, name+": ROLLBACK")
+						return nil
+					},
+				}, nil
+			},
+		}, "postgres")
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should prepare and commit all the transactions", func(t *testing.T) {
+		var statements []string
+		db1 := newDB(t, "db1", "", &statements)
+		db2 := newDB(t, "db2", "", &statements)
+
+		err := TwoPhaseCommit(context.Background(), "fake-id", []DB{db1, db2}, func(txs []Provider) error {
+			tt.AssertEqual(t, len(txs), 2)
+			for i, tx := range txs {
+				_, err := tx.Exec(context.Background(), fmt.Sprint("fake-query-", i))
+				tt.AssertNoErr(t, err)
+			}
+			return nil
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, statements, []string{
+			"db1: fake-query-0",
+			"db2: fake-query-1",
+			"db1: PREPARE TRANSACTION 'fake-id-0'",
+			"db1: ROLLBACK",
+			"db2: PREPARE TRANSACTION 'fake-id-1'",
+			"db2: ROLLBACK",
+			"db1: COMMIT PREPARED 'fake-id-0'",
+			"db2: COMMIT PREPARED 'fake-id-1'",
+		})
+	})
+
+	t.Run("should rollback the prepared transactions if a prepare fails", func(t *testing.T) {
+		var statements []string
+		db1 := newDB(t, "db1", "", &statements)
+		db2 := newDB(t, "db2", "PREPARE", &statements)
+
+		err := TwoPhaseCommit(context.Background(), "fake-id", []DB{db1, db2}, func(txs []Provider) error {
+			return nil
+		})
+		tt.AssertErrContains(t, err, "fake-error")
+		tt.AssertEqual(t, statements, []string{
+			"db1: PREPARE TRANSACTION 'fake-id-0'",
+			"db1: ROLLBACK",
+			"db2: PREPARE TRANSACTION 'fake-id-1'",
+			"db1: ROLLBACK PREPARED 'fake-id-0'",
+			"db2: ROLLBACK",
+		})
+	})
+
+	t.Run("should rollback all the transactions if the callback fails", func(t *testing.T) {
+		var statements []string
+		db1 := newDB(t, "db1", "", &statements)
+		db2 := newDB(t, "db2", "", &statements)
+
+		err := TwoPhaseCommit(context.Background(), "fake-id", []DB{db1, db2}, func(txs []Provider) error {
+			return errors.New("fake-callback-error")
+		})
+		tt.AssertErrContains(t, err, "fake-callback-error")
+		tt.AssertEqual(t, statements, []string{
+			"db1: ROLLBACK",
+			"db2: ROLLBACK",
+		})
+	})
+
+	t.Run("should report the transactions left prepared if a commit fails", func(t *testing.T) {
+		var statements []string
+		db1 := newDB(t, "db1", "", &statements)
+		db2 := newDB(t, "db2", "COMMIT PREPARED", &statements)
+
+		err := TwoPhaseCommit(context.Background(), "fake-id", []DB{db1, db2}, func(txs []Provider) error {
+			return nil
+		})
+		tt.AssertErrContains(t, err, "fake-id-1", "were not committed", "fake-error")
+	})
+
+	t.Run("should escape the transaction id", func(t *testing.T) {
+		tt.AssertEqual(t, quoteLiteral("it's"), "'it''s'")
+	})
+
+	t.Run("should report an error for databases other than postgres", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		err = db.CommitPrepared(context.Background(), "fake-id")
+		tt.AssertErrContains(t, err, "only supported on postgres")
+	})
+}
@@ -0,0 +1,91 @@
+package ksql
+
+import "context"
+
+// txKey is keyed by the identity of the DB that started the
+// transaction so that other DBs, e.g. other shards or replicas,
+// ignore the transactions injected on the context.
+type txKey struct {
+	db *dbIdentity
+}
+
+// dbIdentity is never read, its address is what identifies each DB,
+// and it is not empty since pointers to distinct zero-size
+// values are not guaranteed to be different.
+type dbIdentity struct {
+	_ byte
+}
+
+// InjectTx returns a copy of the input context carrying the
+// input transaction, which is usually the Provider received
+// by the `DB.Transaction()` callback or a `*ksql.TxHandle`.
+//
+// The KSQL methods called with this context will automatically run
+// inside the injected transaction, so the code that receives the
+// context doesn't need to know whether it is inside a transaction, e.g.:
+//
+//	err := db.Transaction(ctx, func(tx ksql.Provider) error {
+//		ctx := ksql.InjectTx(ctx, tx)
+//
+//		// This insert runs inside the transaction:
+//		return db.Insert(ctx, usersTable, &user)
+//	})
+//
+// The transaction is only used by the ksql.DB that started it and by
+// its copies, e.g. the ones created with `DB.With()`, the other
+// instances receiving the context will just ignore it.
+func InjectTx(ctx context.Context, tx Provider) context.Context {
+	return context.WithValue(ctx, txKey{db: identityOf(tx)}, tx)
+}
+
+// ProviderFromContext returns the transaction injected on the context
+// with `ksql.InjectTx()` or the input db if there is none.
+//
+// This is useful for working with Providers other than ksql.DB, e.g.
+// mocks, since only the ksql.DB uses the injected transaction automatically.
+func ProviderFromContext(ctx context.Context, db Provider) Provider {
+	if tx, ok := ctx.Value(txKey{db: identityOf(db)}).(Provider); ok {
+		return tx
+	}
+	return db
+}
+
+// identityOf returns the identity of the ksql.DB instances
+// or nil for the other implementations of the Provider interface.
+func identityOf(p Provider) *dbIdentity {
+	switch db := p.(type) {
+	case DB:
+		return db.identity
+	case *TxHandle:
+		return db.identity
+	}
+	return nil
+}
+
+// contextTx returns a copy of the DB bound to the transaction it
+// injected on the context, or the DB itself if there is no such
+// transaction or if it is already bound to a transaction.
+//
+// Only the transaction is taken from the injected Provider, so the
+// middlewares and the config of the receiver are still used.
+func (c DB) contextTx(ctx context.Context) DB {
+	if _, ok := c.db.(Tx); ok {
+		return c
+	}
+
+	var tx DB
+	switch injected := ctx.Value(txKey{db: c.identity}).(type) {
+	case DB:
+		tx = injected
+	case *TxHandle:
+		tx = injected.DB
+	default:
+		return c
+	}
+
+	c.db = tx.db
+	c.replicas = nil
+	c.txHooks = tx.txHooks
+	c.savepoints = tx.savepoints
+	return c
+}
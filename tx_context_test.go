@@ -0,0 +1,85 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestInjectTx(t *testing.T) {
+	newDB := func(t *testing.T, name string, queries *[]string) DB {
+		db, err := NewWithAdapter(mockTxBeginner{
+			DBAdapter: mockDBAdapter{
+				ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+					*queries = append(*queries, name+": "+query)
+					return nil, nil
+				},
+			},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{
+					DBAdapter: mockDBAdapter{
+						ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+							*queries = append(*queries, name+"-tx: "+query)
+							return nil, nil
+						},
+					},
+				}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should only use the injected tx on the DB that started it", func(t *testing.T) {
+		ctx := context.Background()
+
+		var queries []string
+		db1 := newDB(t, "db1", &queries)
+		db2 := newDB(t, "db2", &queries)
+
+		tx, err := db1.Begin(ctx)
+		tt.AssertNoErr(t, err)
+
+		ctx = InjectTx(ctx, tx)
+		tt.AssertEqual(t, ProviderFromContext(ctx, db1) == Provider(tx), true)
+		_, isTx := ProviderFromContext(ctx, db2).(*TxHandle)
+		tt.AssertEqual(t, isTx, false)
+
+		_, err = db1.Exec(ctx, "fake query 1")
+		tt.AssertNoErr(t, err)
+		_, err = db2.Exec(ctx, "fake query 2")
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, queries, []string{
+			"db1-tx: fake query 1",
+			"db2: fake query 2",
+		})
+	})
+
+	t.Run("should keep the config of the receiver when using the injected tx", func(t *testing.T) {
+		ctx := context.Background()
+
+		var queries []string
+		db := newDB(t, "db", &queries)
+
+		var validated []interface{}
+		tx, err := db.Begin(ctx)
+		tt.AssertNoErr(t, err)
+		ctx = InjectTx(ctx, tx)
+
+		db.validator = func(ctx context.Context, record interface{}) error {
+			validated = append(validated, record)
+			return nil
+		}
+
+		_, err = db.Exec(ctx, "fake query")
+		tt.AssertNoErr(t, err)
+
+		c := db.contextTx(ctx)
+		tt.AssertEqual(t, c.txHooks == tx.txHooks, true)
+		tt.AssertNoErr(t, c.validator(ctx, "fake-record"))
+		tt.AssertEqual(t, validated, []interface{}{"fake-record"})
+		tt.AssertEqual(t, queries, []string{"db-tx: fake query"})
+	})
+}
@@ -0,0 +1,91 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+)
+
+// TxHandle is a ksql.DB bound to a transaction started by
+// the `DB.Begin()` method, all the queries made with it
+// run inside the transaction until it is committed or rolled back.
+type TxHandle struct {
+	DB
+
+	tx Tx
+}
+
+// Begin starts a transaction and returns a handle for it, this is
+// useful when the lifetime of the transaction doesn't fit inside a
+// single callback, e.g. on HTTP middlewares or unit of work patterns:
+//
+//	tx, err := db.Begin(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer tx.Rollback(ctx)
+//
+//	// ... use tx as a regular ksql.Provider ...
+//
+//	return tx.Commit(ctx)
+//
+// Unlike the Transaction method the transaction is never retried
+// and it is the caller's responsibility to call either Commit or Rollback.
+// It can't be called inside another transaction, except inside
+// `ksql.RunInRollbackTx()` where it creates a savepoint instead.
+//
+// Calling Rollback after Commit does nothing and returns nil, so it
+// is safe to defer the Rollback right after starting the transaction.
+func (c DB) Begin(ctx context.Context) (*TxHandle, error) {
+	c = c.contextTx(ctx)
+	beginTx := c.beginSavepoint
+	if _, ok := c.db.(Tx); ok {
+		if c.savepoints == nil {
+			return nil, fmt.Errorf("KSQL: can't start transaction: nested transactions are not supported")
+		}
+	} else {
+		txBeginner, ok := c.db.(TxBeginner)
+		if !ok {
+			return nil, fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxBeginner interface")
+		}
+		beginTx = txBeginner.BeginTx
+	}
+
+	var tx Tx
+	err := c.intercept(ctx, Operation{Method: "Begin"}, func(ctx context.Context, op Operation) (err error) {
+		tx, err = beginTx(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KSQL: error starting transaction: %s", err)
+	}
+
+	dbCopy := c
+	dbCopy.db = tx
+
+	// All the queries of a transaction must run on the primary:
+	dbCopy.replicas = nil
+	dbCopy.txHooks = &txHooks{}
+
+	return &TxHandle{
+		DB: dbCopy,
+		tx: tx,
+	}, nil
+}
+
+// Commit commits the transaction and runs the hooks
+// registered with `BeforeCommit()` and `OnCommit()`
+func (t *TxHandle) Commit(ctx context.Context) error {
+	return t.txHooks.commit(ctx, t.tx)
+}
+
+// Rollback rolls back the transaction and runs
+// the hooks registered with `OnRollback()`
+//
+// If the transaction was already committed or rolled
+// back it does nothing and returns nil.
+func (t *TxHandle) Rollback(ctx context.Context) error {
+	if t.txHooks.isFinished() {
+		return nil
+	}
+	return t.txHooks.rollback(ctx, t.tx)
+}
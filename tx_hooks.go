@@ -0,0 +1,215 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// txHooks stores the callbacks registered on a transaction,
+// it is shared by all the copies of the ksql.DB bound to it.
+type txHooks struct {
+	mu            sync.Mutex
+	finished      bool
+	beforeCommit  []func(ctx context.Context) error
+	afterCommit   []func(ctx context.Context)
+	afterRollback []func(ctx context.Context)
+}
+
+// TxHooksProvider is implemented by the Providers that support
+// registering callbacks on their transactions, i.e. the ksql.DB and
+// the *ksql.TxHandle, and can also be implemented by their wrappers.
+//
+// Use the `ksql.BeforeCommit()`, `ksql.OnCommit()` and `ksql.OnRollback()`
+// functions for registering the callbacks on any ksql.Provider.
+type TxHooksProvider interface {
+	BeforeCommit(fn func(ctx context.Context) error) error
+	OnCommit(fn func(ctx context.Context)) error
+	OnRollback(fn func(ctx context.Context)) error
+}
+
+// BeforeCommit registers a callback on the transaction of the input
+// Provider, or on the transaction injected on the context with
+// `ksql.InjectTx()`, see the `DB.BeforeCommit()` method for details.
+//
+// Unlike the method it also works with the Providers that are not a
+// ksql.DB, returning an error if they don't implement TxHooksProvider.
+func BeforeCommit(ctx context.Context, db Provider, fn func(ctx context.Context) error) error {
+	hooks, err := getTxHooksProvider(ctx, db)
+	if err != nil {
+		return err
+	}
+	return hooks.BeforeCommit(fn)
+}
+
+// OnCommit registers a callback on the transaction of the input Provider,
+// or on the transaction injected on the context, that runs after the
+// transaction is successfully committed, e.g.:
+//
+//	err := db.Transaction(ctx, func(tx ksql.Provider) error {
+//		// ...
+//		return ksql.OnCommit(ctx, tx, func(ctx context.Context) {
+//			cache.Invalidate(user.ID)
+//		})
+//	})
+//
+// It returns an error if the Provider doesn't implement
+// TxHooksProvider or if it is not bound to a transaction.
+func OnCommit(ctx context.Context, db Provider, fn func(ctx context.Context)) error {
+	hooks, err := getTxHooksProvider(ctx, db)
+	if err != nil {
+		return err
+	}
+	return hooks.OnCommit(fn)
+}
+
+// OnRollback registers a callback on the transaction of the input
+// Provider, or on the transaction injected on the context, that runs
+// after the transaction is rolled back, see `DB.OnRollback()`.
+func OnRollback(ctx context.Context, db Provider, fn func(ctx context.Context)) error {
+	hooks, err := getTxHooksProvider(ctx, db)
+	if err != nil {
+		return err
+	}
+	return hooks.OnRollback(fn)
+}
+
+func getTxHooksProvider(ctx context.Context, db Provider) (TxHooksProvider, error) {
+	// The injected transaction is only used if the
+	// DB is not bound to a transaction already:
+	switch p := db.(type) {
+	case DB:
+		if p.txHooks != nil {
+			return p, nil
+		}
+	case *TxHandle:
+		return p, nil
+	}
+
+	db = ProviderFromContext(ctx, db)
+	hooks, ok := db.(TxHooksProvider)
+	if !ok {
+		return nil, fmt.Errorf("KSQL: transaction hooks are not supported by the Provider of type %T", db)
+	}
+	return hooks, nil
+}
+
+// BeforeCommit registers a callback that runs right before
+// the current transaction is committed, if it returns an
+// error the transaction is rolled back instead.
+//
+// It returns an error if the DB is not bound to a transaction, i.e. it
+// should be called on a `*ksql.TxHandle` or using `ksql.BeforeCommit()`
+// with the Provider received by the `DB.Transaction()` callback.
+func (c DB) BeforeCommit(fn func(ctx context.Context) error) error {
+	return c.addTxHook(func(h *txHooks) {
+		h.beforeCommit = append(h.beforeCommit, fn)
+	})
+}
+
+// OnCommit registers a callback that runs after the current
+// transaction is successfully committed, e.g.:
+//
+//	tx, err := db.Begin(ctx)
+//	// ...
+//	err = tx.OnCommit(func(ctx context.Context) {
+//		cache.Invalidate(user.ID)
+//	})
+//
+// It returns an error if the DB is not bound to a transaction, see
+// `ksql.OnCommit()` for registering it on the Providers received by
+// the `DB.Transaction()` callbacks.
+func (c DB) OnCommit(fn func(ctx context.Context)) error {
+	return c.addTxHook(func(h *txHooks) {
+		h.afterCommit = append(h.afterCommit, fn)
+	})
+}
+
+// OnRollback registers a callback that runs after the current
+// transaction is rolled back, including when the commit fails.
+//
+// It returns an error if the DB is not bound to a transaction.
+func (c DB) OnRollback(fn func(ctx context.Context)) error {
+	return c.addTxHook(func(h *txHooks) {
+		h.afterRollback = append(h.afterRollback, fn)
+	})
+}
+
+func (c DB) addTxHook(add func(h *txHooks)) error {
+	if c.txHooks == nil {
+		return fmt.Errorf("KSQL: transaction hooks can only be registered inside a transaction")
+	}
+
+	c.txHooks.mu.Lock()
+	defer c.txHooks.mu.Unlock()
+	if c.txHooks.finished {
+		return fmt.Errorf("KSQL: can't register hook: the transaction has already finished")
+	}
+
+	add(c.txHooks)
+	return nil
+}
+
+// commit runs the BeforeCommit hooks, commits the transaction
+// and then runs the OnCommit hooks, if the transaction
+// is rolled back the OnRollback hooks run instead.
+func (h *txHooks) commit(ctx context.Context, tx Tx) error {
+	h.mu.Lock()
+	beforeCommit := h.beforeCommit
+	h.mu.Unlock()
+
+	for _, fn := range beforeCommit {
+		if err := fn(ctx); err != nil {
+			rollbackErr := h.rollback(ctx, tx)
+			if rollbackErr != nil {
+				err = errors.Wrap(rollbackErr,
+					fmt.Sprintf("KSQL: unable to rollback after error: %s", err.Error()),
+				)
+			}
+			return err
+		}
+	}
+
+	err := tx.Commit(ctx)
+	if err != nil {
+		h.finish(ctx, func() []func(ctx context.Context) { return h.afterRollback })
+		return err
+	}
+
+	h.finish(ctx, func() []func(ctx context.Context) { return h.afterCommit })
+	return nil
+}
+
+// rollback rolls back the transaction and runs the OnRollback hooks,
+// unless the transaction has already finished, e.g. when the
+// rollback is deferred right after starting a `ksql.TxHandle`.
+func (h *txHooks) rollback(ctx context.Context, tx Tx) error {
+	err := tx.Rollback(ctx)
+	h.finish(ctx, func() []func(ctx context.Context) { return h.afterRollback })
+	return err
+}
+
+func (h *txHooks) isFinished() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.finished
+}
+
+// finish marks the transaction as finished and runs the hooks
+// returned by getHooks if it wasn't finished before.
+func (h *txHooks) finish(ctx context.Context, getHooks func() []func(ctx context.Context)) {
+	h.mu.Lock()
+	if h.finished {
+		h.mu.Unlock()
+		return
+	}
+	h.finished = true
+	hooks := getHooks()
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
@@ -0,0 +1,147 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestTxHooks(t *testing.T) {
+	newDB := func(t *testing.T, commitErr error, calls *[]string) DB {
+		db, err := NewWithAdapter(mockTxBeginner{
+			DBAdapter: mockDBAdapter{},
+			BeginTxFn: func(ctx context.Context) (Tx, error) {
+				return mockTx{
+					DBAdapter: mockDBAdapter{},
+					CommitFn: func(ctx context.Context) error {
+						*calls = append(*calls, "commit")
+						return commitErr
+					},
+					RollbackFn: func(ctx context.Context) error {
+						*calls = append(*calls, "rollback")
+						return nil
+					},
+				}, nil
+			},
+		}, "sqlite3")
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	registerHooks := func(t *testing.T, tx Provider, calls *[]string, beforeCommitErr error) {
+		ctx := context.Background()
+		tt.AssertNoErr(t, BeforeCommit(ctx, tx, func(ctx context.Context) error {
+			*calls = append(*calls, "before-commit")
+			return beforeCommitErr
+		}))
+		tt.AssertNoErr(t, OnCommit(ctx, tx, func(ctx context.Context) {
+			*calls = append(*calls, "on-commit")
+		}))
+		tt.AssertNoErr(t, OnRollback(ctx, tx, func(ctx context.Context) {
+			*calls = append(*calls, "on-rollback")
+		}))
+	}
+
+	t.Run("should run the commit hooks when the transaction succeeds", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+
+		err := c.Transaction(context.Background(), func(tx Provider) error {
+			registerHooks(t, tx, &calls, nil)
+			return nil
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, []string{"before-commit", "commit", "on-commit"})
+	})
+
+	t.Run("should run the rollback hooks when the callback fails", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+
+		err := c.Transaction(context.Background(), func(tx Provider) error {
+			registerHooks(t, tx, &calls, nil)
+			return errors.New("fake-error")
+		})
+		tt.AssertErrContains(t, err, "fake-error")
+		tt.AssertEqual(t, calls, []string{"rollback", "on-rollback"})
+	})
+
+	t.Run("should rollback if a BeforeCommit hook fails", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+
+		err := c.Transaction(context.Background(), func(tx Provider) error {
+			registerHooks(t, tx, &calls, errors.New("fake-hook-error"))
+			return nil
+		})
+		tt.AssertErrContains(t, err, "fake-hook-error")
+		tt.AssertEqual(t, calls, []string{"before-commit", "rollback", "on-rollback"})
+	})
+
+	t.Run("should run the rollback hooks when the commit fails", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, errors.New("fake-commit-error"), &calls)
+
+		err := c.Transaction(context.Background(), func(tx Provider) error {
+			registerHooks(t, tx, &calls, nil)
+			return nil
+		})
+		tt.AssertErrContains(t, err, "fake-commit-error")
+		tt.AssertEqual(t, calls, []string{"before-commit", "commit", "on-rollback"})
+	})
+
+	t.Run("should run the hooks only once when using a TxHandle", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+		ctx := context.Background()
+
+		tx, err := c.Begin(ctx)
+		tt.AssertNoErr(t, err)
+		registerHooks(t, tx, &calls, nil)
+
+		tt.AssertNoErr(t, tx.Commit(ctx))
+		tt.AssertNoErr(t, tx.Rollback(ctx))
+		tt.AssertEqual(t, calls, []string{"before-commit", "commit", "on-commit"})
+
+		err = tx.OnCommit(func(ctx context.Context) {})
+		tt.AssertErrContains(t, err, "already finished")
+	})
+
+	t.Run("should report an error when called outside of a transaction", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+
+		err := c.OnCommit(func(ctx context.Context) {})
+		tt.AssertErrContains(t, err, "inside a transaction")
+
+		err = OnCommit(context.Background(), c, func(ctx context.Context) {})
+		tt.AssertErrContains(t, err, "inside a transaction")
+	})
+
+	t.Run("should register the hooks on the transaction injected on the context", func(t *testing.T) {
+		var calls []string
+		c := newDB(t, nil, &calls)
+
+		err := c.Transaction(context.Background(), func(tx Provider) error {
+			ctx := InjectTx(context.Background(), tx)
+			return OnCommit(ctx, c, func(ctx context.Context) {
+				calls = append(calls, "on-commit")
+			})
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, calls, []string{"commit", "on-commit"})
+	})
+
+	t.Run("should report an error for Providers without support for the hooks", func(t *testing.T) {
+		err := OnCommit(context.Background(), Mock{}, func(ctx context.Context) {})
+		tt.AssertErrContains(t, err, "not supported", "ksql.Mock")
+
+		err = BeforeCommit(context.Background(), Mock{}, func(ctx context.Context) error { return nil })
+		tt.AssertErrContains(t, err, "not supported", "ksql.Mock")
+
+		err = OnRollback(context.Background(), Mock{}, func(ctx context.Context) {})
+		tt.AssertErrContains(t, err, "not supported", "ksql.Mock")
+	})
+}
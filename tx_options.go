@@ -0,0 +1,118 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// IsolationLevel is the isolation level of a transaction,
+// see `DB.TransactionWithOptions()` for more details
+type IsolationLevel int
+
+// The isolation levels supported by KSQL, not all
+// databases support all of them, e.g. SQLite only
+// supports Serializable transactions.
+const (
+	DefaultIsolation IsolationLevel = iota
+	ReadUncommitted
+	ReadCommitted
+	RepeatableRead
+	Serializable
+)
+
+func (i IsolationLevel) String() string {
+	switch i {
+	case DefaultIsolation:
+		return "Default"
+	case ReadUncommitted:
+		return "Read Uncommitted"
+	case ReadCommitted:
+		return "Read Committed"
+	case RepeatableRead:
+		return "Repeatable Read"
+	case Serializable:
+		return "Serializable"
+	}
+	return fmt.Sprintf("IsolationLevel(%d)", int(i))
+}
+
+// TxOptions contains the options for starting a transaction
+type TxOptions struct {
+	// Isolation defaults to the isolation level configured on the database
+	Isolation IsolationLevel
+
+	// ReadOnly makes the database reject any writes inside the transaction
+	ReadOnly bool
+
+	// Deferrable is only supported by Postgres and only has effect on
+	// read only serializable transactions, where it makes the transaction
+	// wait until it can run without the risk of serialization failures
+	Deferrable bool
+}
+
+var sqlIsolationLevels = map[IsolationLevel]sql.IsolationLevel{
+	DefaultIsolation: sql.LevelDefault,
+	ReadUncommitted:  sql.LevelReadUncommitted,
+	ReadCommitted:    sql.LevelReadCommitted,
+	RepeatableRead:   sql.LevelRepeatableRead,
+	Serializable:     sql.LevelSerializable,
+}
+
+// SQLTxOptions converts the options into the *sql.TxOptions
+// used by the adapters built on top of `database/sql`
+func (o TxOptions) SQLTxOptions() (*sql.TxOptions, error) {
+	if o.Deferrable {
+		return nil, fmt.Errorf("ksql: deferrable transactions are not supported by the database/sql package")
+	}
+
+	isolation, ok := sqlIsolationLevels[o.Isolation]
+	if !ok {
+		return nil, fmt.Errorf("ksql: unsupported isolation level: %s", o.Isolation)
+	}
+
+	return &sql.TxOptions{
+		Isolation: isolation,
+		ReadOnly:  o.ReadOnly,
+	}, nil
+}
+
+// TxOptionsBeginner needs to be implemented by the DBAdapter in order to
+// make it possible to use the `ksql.TransactionWithOptions()` method.
+type TxOptionsBeginner interface {
+	BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error)
+}
+
+// TransactionWithOptions works like the Transaction method
+// but also allows the user to configure the transaction, e.g.:
+//
+//	err := db.TransactionWithOptions(ctx, ksql.TxOptions{
+//		Isolation: ksql.RepeatableRead,
+//		ReadOnly:  true,
+//	}, func(db ksql.Provider) error {
+//		// ...
+//	})
+//
+// If it is called inside a transaction callback the same
+// transaction is reused and the options are ignored.
+func (c DB) TransactionWithOptions(ctx context.Context, opts TxOptions, fn func(Provider) error) error {
+	return c.intercept(ctx, Operation{Method: "TransactionWithOptions"}, func(ctx context.Context, op Operation) error {
+		return c.startTransactionWithOptions(ctx, opts, fn)
+	})
+}
+
+func (c DB) startTransactionWithOptions(ctx context.Context, opts TxOptions, fn func(Provider) error) error {
+	c = c.contextTx(ctx)
+	if _, ok := c.db.(Tx); ok {
+		return fn(c)
+	}
+
+	beginner, ok := c.db.(TxOptionsBeginner)
+	if !ok {
+		return fmt.Errorf("KSQL: can't start transaction: The DBAdapter doesn't implement the TxOptionsBeginner interface")
+	}
+
+	return c.transaction(ctx, func(ctx context.Context) (Tx, error) {
+		return beginner.BeginTxWithOptions(ctx, opts)
+	}, fn)
+}
@@ -0,0 +1,45 @@
+package ksql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestSQLTxOptions(t *testing.T) {
+	t.Run("should convert the options to the database/sql format", func(t *testing.T) {
+		opts, err := TxOptions{
+			Isolation: RepeatableRead,
+			ReadOnly:  true,
+		}.SQLTxOptions()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, opts, &sql.TxOptions{
+			Isolation: sql.LevelRepeatableRead,
+			ReadOnly:  true,
+		})
+	})
+
+	t.Run("should report an error for deferrable transactions", func(t *testing.T) {
+		_, err := TxOptions{Deferrable: true}.SQLTxOptions()
+		tt.AssertErrContains(t, err, "deferrable")
+	})
+
+	t.Run("should report an error for unknown isolation levels", func(t *testing.T) {
+		_, err := TxOptions{Isolation: IsolationLevel(42)}.SQLTxOptions()
+		tt.AssertErrContains(t, err, "IsolationLevel(42)")
+	})
+}
+
+func TestTransactionWithOptions(t *testing.T) {
+	t.Run("should report an error if the adapter doesn't implement TxOptionsBeginner", func(t *testing.T) {
+		db, err := NewWithAdapter(mockDBAdapter{}, "sqlite3")
+		tt.AssertNoErr(t, err)
+
+		err = db.TransactionWithOptions(context.Background(), TxOptions{}, func(Provider) error {
+			return nil
+		})
+		tt.AssertErrContains(t, err, "TxOptionsBeginner")
+	})
+}
@@ -0,0 +1,282 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+)
+
+// SchemaReport describes the differences between the structs
+// and the tables checked by `ksql.ValidateSchema()`
+type SchemaReport struct {
+	Tables []TableReport
+}
+
+// TableReport describes the differences between
+// a table and the struct registered for it
+type TableReport struct {
+	Table string
+
+	// MissingTable is true if the table doesn't exist,
+	// in this case the other attributes are left empty.
+	MissingTable bool
+
+	// MissingColumns are the columns tagged on the struct
+	// that don't exist on the table
+	MissingColumns []string
+
+	// ExtraColumns are the columns of the table that have no attribute
+	// on the struct, they are only informative since they might be
+	// intentional, e.g. columns with default values.
+	ExtraColumns []string
+
+	TypeMismatches []TypeMismatch
+}
+
+// TypeMismatch describes a column whose type
+// can't be used for storing its attribute
+type TypeMismatch struct {
+	Column     string
+	ColumnType string
+	AttrType   reflect.Type
+}
+
+// HasMismatches returns true if any of the tables are missing, or have
+// missing columns or type mismatches, the extra columns are ignored.
+func (r SchemaReport) HasMismatches() bool {
+	for _, table := range r.Tables {
+		if table.hasMismatches() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TableReport) hasMismatches() bool {
+	return t.MissingTable || len(t.MissingColumns) > 0 || len(t.TypeMismatches) > 0
+}
+
+// ErrSchemaMismatch is returned by `ksql.ValidateSchema()` when the
+// tables don't match their structs, the details are available on
+// the Report attribute and can be retrieved with `errors.As()`.
+type ErrSchemaMismatch struct {
+	Report SchemaReport
+}
+
+func (e ErrSchemaMismatch) Error() string {
+	var problems []string
+	for _, table := range e.Report.Tables {
+		if table.MissingTable {
+			problems = append(problems, fmt.Sprintf("table `%s` doesn't exist", table.Table))
+			continue
+		}
+
+		for _, column := range table.MissingColumns {
+			problems = append(problems, fmt.Sprintf("column `%s.%s` doesn't exist", table.Table, column))
+		}
+
+		for _, mismatch := range table.TypeMismatches {
+			problems = append(problems, fmt.Sprintf(
+				"column `%s.%s` of type `%s` can't be used with an attribute of type %v",
+				table.Table, mismatch.Column, mismatch.ColumnType, mismatch.AttrType,
+			))
+		}
+	}
+
+	return "ksql: the database schema doesn't match the structs: " + strings.Join(problems, "; ")
+}
+
+// ValidateSchema checks that the input tables exist and have a column for
+// each attribute of the struct registered with `Table.WithStruct()`, with
+// a type compatible with the attribute, which is useful for detecting
+// missing migrations when the application starts, e.g.:
+//
+//	var UsersTable = ksql.NewTable("users").WithStruct(User{})
+//	var PostsTable = ksql.NewTable("posts").WithStruct(Post{})
+//
+//	func main() {
+//		// ...
+//		_, err = ksql.ValidateSchema(ctx, db, UsersTable, PostsTable)
+//		if err != nil {
+//			log.Fatalf("invalid database schema: %s", err)
+//		}
+//	}
+//
+// The report is always returned, and if any differences besides extra
+// columns are found the error is an ErrSchemaMismatch with the same report.
+//
+// The type check is lenient, e.g. string attributes can be stored on numeric
+// columns and bool attributes on integer columns, and it is skipped for the
+// attributes using custom modifiers or declaring a `type=<sql type>` option, as
+// well as for the column types it can't recognize. The columns are described
+// with `DB.DescribeTable()` so the same limitations apply.
+func ValidateSchema(ctx context.Context, db DB, tables ...Table) (SchemaReport, error) {
+	var report SchemaReport
+	for _, table := range tables {
+		if table.structType == nil {
+			return report, fmt.Errorf("ksql: can't validate table `%s` because it has no struct registered with Table.WithStruct()", table.name)
+		}
+
+		info, err := db.naming.GetTagInfo(table.structType)
+		if err != nil {
+			return report, err
+		}
+
+		tableReport := TableReport{
+			Table: table.name,
+		}
+
+		schema, err := db.DescribeTable(ctx, table.name)
+		if IsNotFound(err) {
+			tableReport.MissingTable = true
+			report.Tables = append(report.Tables, tableReport)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		for _, field := range info.Fields() {
+			column, found := schema.Column(field.Name)
+			if !found {
+				// Some databases, e.g. Oracle, might return the names in another case:
+				column, found = schema.Column(strings.ToLower(field.Name))
+			}
+			if !found {
+				tableReport.MissingColumns = append(tableReport.MissingColumns, field.Name)
+				continue
+			}
+
+			attrType := table.structType.FieldByIndex(field.Path).Type
+			if !isCompatibleColumnType(attrType, field, column.Type) {
+				tableReport.TypeMismatches = append(tableReport.TypeMismatches, TypeMismatch{
+					Column:     field.Name,
+					ColumnType: column.Type,
+					AttrType:   attrType,
+				})
+			}
+		}
+
+		for _, column := range schema.Columns {
+			if !info.ByName(column.Name).Valid {
+				tableReport.ExtraColumns = append(tableReport.ExtraColumns, column.Name)
+			}
+		}
+
+		report.Tables = append(report.Tables, tableReport)
+	}
+
+	if report.HasMismatches() {
+		return report, ErrSchemaMismatch{Report: report}
+	}
+
+	return report, nil
+}
+
+type columnCategory string
+
+const (
+	unknownCategory columnCategory = ""
+	integerCategory columnCategory = "integer"
+	numericCategory columnCategory = "numeric"
+	boolCategory    columnCategory = "bool"
+	stringCategory  columnCategory = "string"
+	timeCategory    columnCategory = "time"
+	bytesCategory   columnCategory = "bytes"
+	jsonCategory    columnCategory = "json"
+)
+
+// compatibleCategories lists the categories of columns that
+// can be used for storing each kind of attribute
+var compatibleCategories = map[columnKindType][]columnCategory{
+	smallintColumn: {integerCategory, numericCategory},
+	intColumn:      {integerCategory, numericCategory},
+	bigintColumn:   {integerCategory, numericCategory},
+	floatColumn:    {numericCategory, integerCategory},
+	doubleColumn:   {numericCategory, integerCategory},
+	boolColumn:     {boolCategory, integerCategory, numericCategory},
+	stringColumn:   {stringCategory, jsonCategory, numericCategory},
+	timeColumn:     {timeCategory},
+	bytesColumn:    {bytesCategory, stringCategory, jsonCategory},
+	jsonColumn:     {jsonCategory, stringCategory, bytesCategory},
+}
+
+func isCompatibleColumnType(attrType reflect.Type, field *structs.FieldInfo, columnType string) bool {
+	if field.SQLType != "" {
+		return true
+	}
+
+	switch field.ModifierName {
+	case "", "json", "jsonNullable", "encrypted":
+	default:
+		// Custom modifiers might convert the values to any type
+		return true
+	}
+
+	kind, err := columnKind(attrType, field)
+	if err != nil {
+		// We don't know which columns can store this attribute
+		return true
+	}
+
+	category := classifyColumnType(columnType)
+	if category == unknownCategory {
+		return true
+	}
+
+	for _, compatible := range compatibleCategories[kind] {
+		if category == compatible {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyColumnType converts the types returned
+// by `DB.DescribeTable()` to a columnCategory
+func classifyColumnType(columnType string) columnCategory {
+	if strings.Contains(columnType, "[") {
+		// Postgres arrays and DuckDB lists, e.g. `integer[]`, and
+		// DuckDB arrays, e.g. `integer[3]`, can only be used with custom types
+		return unknownCategory
+	}
+
+	baseType := columnType
+	if i := strings.Index(baseType, "("); i >= 0 {
+		baseType = baseType[:i]
+	}
+	baseType = strings.TrimSpace(baseType)
+
+	contains := func(substrs ...string) bool {
+		for _, substr := range substrs {
+			if strings.Contains(baseType, substr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case contains("json"):
+		return jsonCategory
+	case contains("bool") || baseType == "bit":
+		return boolCategory
+	case contains("timestamp", "datetime") || baseType == "date" || strings.HasPrefix(baseType, "time"):
+		return timeCategory
+	case contains("blob", "bytea", "binary", "image") || baseType == "raw":
+		return bytesCategory
+	case contains("interval", "point"):
+		return unknownCategory
+	case contains("int", "serial"):
+		return integerCategory
+	case contains("numeric", "decimal", "real", "double", "float", "money", "number"):
+		return numericCategory
+	case contains("char", "text", "clob", "string", "uuid", "enum", "xml") || baseType == "name":
+		return stringCategory
+	}
+
+	return unknownCategory
+}
@@ -0,0 +1,54 @@
+package ksql
+
+import (
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestClassifyColumnType(t *testing.T) {
+	tests := []struct {
+		columnType       string
+		expectedCategory columnCategory
+	}{
+		{columnType: "integer", expectedCategory: integerCategory},
+		{columnType: "bigint unsigned", expectedCategory: integerCategory},
+		{columnType: "tinyint(1)", expectedCategory: integerCategory},
+		{columnType: "numeric(10,2)", expectedCategory: numericCategory},
+		{columnType: "double precision", expectedCategory: numericCategory},
+		{columnType: "number", expectedCategory: numericCategory},
+		{columnType: "boolean", expectedCategory: boolCategory},
+		{columnType: "bit", expectedCategory: boolCategory},
+		{columnType: "character varying(50)", expectedCategory: stringCategory},
+		{columnType: "nvarchar", expectedCategory: stringCategory},
+		{columnType: "text", expectedCategory: stringCategory},
+		{columnType: "timestamp without time zone", expectedCategory: timeCategory},
+		{columnType: "datetime(6)", expectedCategory: timeCategory},
+		{columnType: "date", expectedCategory: timeCategory},
+		{columnType: "bytea", expectedCategory: bytesCategory},
+		{columnType: "varbinary", expectedCategory: bytesCategory},
+		{columnType: "jsonb", expectedCategory: jsonCategory},
+		{columnType: "interval", expectedCategory: unknownCategory},
+		{columnType: "point", expectedCategory: unknownCategory},
+		{columnType: "integer[]", expectedCategory: unknownCategory},
+
+		// The types reported by DuckDB:
+		{columnType: "hugeint", expectedCategory: integerCategory},
+		{columnType: "ubigint", expectedCategory: integerCategory},
+		{columnType: "decimal(10,2)", expectedCategory: numericCategory},
+		{columnType: "varchar", expectedCategory: stringCategory},
+		{columnType: "uuid", expectedCategory: stringCategory},
+		{columnType: "timestamp with time zone", expectedCategory: timeCategory},
+		{columnType: "time", expectedCategory: timeCategory},
+		{columnType: "blob", expectedCategory: bytesCategory},
+		{columnType: "integer[3]", expectedCategory: unknownCategory},
+		{columnType: "struct(a integer)", expectedCategory: unknownCategory},
+		{columnType: "map(varchar, integer)", expectedCategory: unknownCategory},
+	}
+
+	for _, test := range tests {
+		t.Run(test.columnType, func(t *testing.T) {
+			tt.AssertEqual(t, classifyColumnType(test.columnType), test.expectedCategory)
+		})
+	}
+}
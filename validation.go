@@ -0,0 +1,51 @@
+package ksql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrValidation is returned by Insert, Upsert, Patch and Update when
+// the `ksql.Config.Validator` rejects the record, in this case no
+// statements are sent to the database.
+//
+// It can be detected with `errors.As()`, and the
+// original error is available on the Err attribute.
+type ErrValidation struct {
+	Table string
+	Err   error
+}
+
+func (e ErrValidation) Error() string {
+	return fmt.Sprintf("ksql: invalid record for table `%s`: %s", e.Table, e.Err)
+}
+
+// Unwrap allows the use of `errors.Is()` and `errors.As()`
+// on the error returned by the Validator
+func (e ErrValidation) Unwrap() error {
+	return e.Err
+}
+
+// validate calls the Validator configured for the DB, if any
+func (c DB) validate(ctx context.Context, table Table, record interface{}) error {
+	if c.validator == nil {
+		return nil
+	}
+
+	err := c.validator(ctx, record)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr ErrValidation
+	if errors.As(err, &validationErr) {
+		return err
+	}
+
+	return ErrValidation{
+		Table: table.name,
+		Err:   err,
+	}
+}
@@ -0,0 +1,73 @@
+package ksql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+)
+
+func TestValidator(t *testing.T) {
+	newDB := func(t *testing.T, statements *int) DB {
+		db, err := NewWithConfig(mockDBAdapter{
+			ExecContextFn: func(ctx context.Context, query string, args ...interface{}) (Result, error) {
+				*statements++
+				return NewMockResult(1, 1), nil
+			},
+		}, "mysql", Config{
+			Validator: func(ctx context.Context, record interface{}) error {
+				var u *user
+				switch r := record.(type) {
+				case *user:
+					u = r
+				case user:
+					u = &r
+				}
+				if u.Name == "" {
+					return errors.New("fake error: name is required")
+				}
+				return nil
+			},
+		})
+		tt.AssertNoErr(t, err)
+		return db
+	}
+
+	t.Run("should reject invalid records before sending any statements", func(t *testing.T) {
+		ctx := context.Background()
+		var statements int
+		db := newDB(t, &statements)
+
+		err := db.Insert(ctx, usersTable, &user{})
+		var validationErr ErrValidation
+		tt.AssertEqual(t, errors.As(err, &validationErr), true)
+		tt.AssertEqual(t, validationErr.Table, "users")
+		tt.AssertErrContains(t, err, "users", "name is required")
+
+		err = db.Patch(ctx, usersTable, user{ID: 42})
+		tt.AssertEqual(t, errors.As(err, &validationErr), true)
+
+		err = db.Upsert(ctx, usersTable, &user{ID: 42})
+		tt.AssertEqual(t, errors.As(err, &validationErr), true)
+
+		err = db.UpdateReturning(ctx, usersTable, &user{ID: 42})
+		tt.AssertEqual(t, errors.As(err, &validationErr), true)
+
+		tt.AssertEqual(t, statements, 0)
+	})
+
+	t.Run("should accept valid records", func(t *testing.T) {
+		ctx := context.Background()
+		var statements int
+		db := newDB(t, &statements)
+
+		err := db.Insert(ctx, usersTable, &user{Name: "Bia"})
+		tt.AssertNoErr(t, err)
+
+		err = db.Patch(ctx, usersTable, user{ID: 42, Name: "Bia"})
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, statements, 2)
+	})
+}
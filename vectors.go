@@ -0,0 +1,154 @@
+package ksql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vingarcia/ksql/internal/structs"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+// Vector is an embedding stored on a pgvector `vector` column, it is
+// written and read using the text format of pgvector, e.g. `[1,2.5,3]`,
+// so it works with both the pq and the pgx adapters.
+//
+// Attributes declared as []float32 can use the `vector`
+// modifier instead, e.g. `ksql:"embedding,vector"`.
+type Vector []float32
+
+// Scan implements the sql.Scanner interface
+func (v *Vector) Scan(value interface{}) error {
+	var str string
+	switch dbValue := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		str = dbValue
+	case []byte:
+		str = string(dbValue)
+	default:
+		return fmt.Errorf("ksql: can't scan value of type %T into a Vector", value)
+	}
+
+	vec, err := parseVector(str)
+	if err != nil {
+		return err
+	}
+	*v = vec
+	return nil
+}
+
+// Value implements the driver.Valuer interface,
+// nil vectors are written as NULL
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return formatVector(v), nil
+}
+
+func formatVector(vec []float32) string {
+	values := make([]string, len(vec))
+	for i, f := range vec {
+		values[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+func parseVector(str string) ([]float32, error) {
+	trimmed := strings.TrimSpace(str)
+	if !strings.HasPrefix(trimmed, "[") || !strings.HasSuffix(trimmed, "]") {
+		return nil, fmt.Errorf("ksql: can't parse '%s' as a vector, expected a value like `[1,2,3]`", str)
+	}
+
+	trimmed = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+	if trimmed == "" {
+		return []float32{}, nil
+	}
+
+	values := strings.Split(trimmed, ",")
+	vec := make([]float32, len(values))
+	for i, value := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+		if err != nil {
+			return nil, fmt.Errorf("ksql: can't parse '%s' as a vector: invalid number '%s'", str, value)
+		}
+		vec[i] = float32(f)
+	}
+	return vec, nil
+}
+
+// CosineDistance returns the SQL expression that computes the cosine
+// distance between a pgvector column and the input vector, which is
+// useful for similarity searches, e.g.:
+//
+//	err := db.Query(ctx, &docs,
+//		"FROM documents ORDER BY "+ksql.CosineDistance("embedding", queryVec)+" LIMIT 5",
+//	)
+//
+// The vector is written on the expression as a literal, so the query changes
+// for each vector, for reusing the same prepared statement the vector should
+// be passed as a param instead, e.g. `ORDER BY embedding <=> ?` with a
+// ksql.Vector param. The column is written as it is, so it should never
+// contain user input.
+func CosineDistance(column string, vec []float32) string {
+	return vectorDistance(column, "<=>", vec)
+}
+
+// L2Distance works like CosineDistance but computes the Euclidean distance
+func L2Distance(column string, vec []float32) string {
+	return vectorDistance(column, "<->", vec)
+}
+
+// InnerProduct works like CosineDistance but computes the negative inner
+// product, which is negative so that the closest vectors come first on
+// ascending orders, like the other distances
+func InnerProduct(column string, vec []float32) string {
+	return vectorDistance(column, "<#>", vec)
+}
+
+func vectorDistance(column string, operator string, vec []float32) string {
+	return "(" + column + " " + operator + " '" + formatVector(vec) + "')"
+}
+
+func init() {
+	structs.RegisterAttrModifier("vector", vectorModifier)
+}
+
+// vectorModifier writes the []float32 attributes as pgvector
+// vectors and reads them back, nil slices are written as NULL
+var vectorModifier = ksqlmodifiers.AttrModifier{
+	Value: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, inputValue interface{}) (interface{}, error) {
+		switch vec := inputValue.(type) {
+		case []float32:
+			return Vector(vec).Value()
+		case *[]float32:
+			if vec == nil {
+				return nil, nil
+			}
+			return Vector(*vec).Value()
+		}
+
+		return nil, fmt.Errorf("ksql: the vector modifier only supports []float32 attributes, but got: %T", inputValue)
+	},
+
+	Scan: func(ctx context.Context, opInfo ksqlmodifiers.OpInfo, attrPtr interface{}, dbValue interface{}) error {
+		switch vecPtr := attrPtr.(type) {
+		case *[]float32:
+			return (*Vector)(vecPtr).Scan(dbValue)
+		case **[]float32:
+			if dbValue == nil {
+				*vecPtr = nil
+				return nil
+			}
+			*vecPtr = new([]float32)
+			return (*Vector)(*vecPtr).Scan(dbValue)
+		}
+
+		return fmt.Errorf("ksql: the vector modifier only supports []float32 attributes, but got: %T", attrPtr)
+	},
+}
@@ -0,0 +1,112 @@
+package ksql
+
+import (
+	"context"
+	"testing"
+
+	tt "github.com/vingarcia/ksql/internal/testtools"
+	"github.com/vingarcia/ksql/ksqlmodifiers"
+)
+
+func TestVector(t *testing.T) {
+	t.Run("should scan the text format of pgvector", func(t *testing.T) {
+		var v Vector
+		err := v.Scan([]byte("[1,2.5,-3e-05]"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, v, Vector{1, 2.5, -3e-05})
+
+		err = v.Scan("[ ]")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, v, Vector{})
+
+		err = v.Scan(nil)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, v == nil, true)
+	})
+
+	t.Run("should report invalid vectors", func(t *testing.T) {
+		var v Vector
+		err := v.Scan("1,2,3")
+		tt.AssertErrContains(t, err, "1,2,3", "[1,2,3]")
+
+		err = v.Scan("[1,a]")
+		tt.AssertErrContains(t, err, "invalid number", "a")
+
+		err = v.Scan(42)
+		tt.AssertErrContains(t, err, "int", "Vector")
+	})
+
+	t.Run("should write the text format of pgvector", func(t *testing.T) {
+		value, err := Vector{1, 2.5, -3e-05}.Value()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, "[1,2.5,-3e-05]")
+
+		value, err = Vector(nil).Value()
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, value, nil)
+	})
+}
+
+func TestVectorModifier(t *testing.T) {
+	ctx := context.Background()
+
+	type document struct {
+		ID        int        `ksql:"id"`
+		Embedding []float32  `ksql:"embedding,vector"`
+		Summary   *[]float32 `ksql:"summary,vector"`
+	}
+
+	t.Run("should encode the vectors on writes", func(t *testing.T) {
+		dryRun, err := NewDryRun("postgres", nil)
+		tt.AssertNoErr(t, err)
+
+		err = dryRun.Patch(ctx, NewTable("documents"), &document{
+			ID:        1,
+			Embedding: []float32{0.5, 1},
+		})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, paramsByColumn(dryRun.Statements()[0]), map[string]interface{}{
+			"embedding": "[0.5,1]",
+			"id":        1,
+		})
+	})
+
+	t.Run("should decode the vectors on reads", func(t *testing.T) {
+		opInfo := ksqlmodifiers.OpInfo{DriverName: "postgres", Method: "Query"}
+
+		var embedding []float32
+		err := vectorModifier.Scan(ctx, opInfo, &embedding, "[0.5,1]")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, embedding, []float32{0.5, 1})
+
+		var summary *[]float32
+		err = vectorModifier.Scan(ctx, opInfo, &summary, []byte("[2]"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, *summary, []float32{2})
+
+		err = vectorModifier.Scan(ctx, opInfo, &summary, nil)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, summary == nil, true)
+
+		var notAVector []float64
+		err = vectorModifier.Scan(ctx, opInfo, &notAVector, "[1]")
+		tt.AssertErrContains(t, err, "vector modifier", "[]float64")
+	})
+
+	t.Run("should use the vector type on CreateTableSQL", func(t *testing.T) {
+		query, err := CreateTableSQL("postgres", NewTable("documents"), &document{})
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, query, `CREATE TABLE "documents" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"embedding" vector,
+	"summary" vector
+)`)
+	})
+}
+
+func TestVectorDistances(t *testing.T) {
+	vec := []float32{1, 0.5}
+	tt.AssertEqual(t, CosineDistance("embedding", vec), "(embedding <=> '[1,0.5]')")
+	tt.AssertEqual(t, L2Distance("d.embedding", vec), "(d.embedding <-> '[1,0.5]')")
+	tt.AssertEqual(t, InnerProduct("embedding", vec), "(embedding <#> '[1,0.5]')")
+}